@@ -0,0 +1,142 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package annotation
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// dateLayouts are the accepted timestamps with a date (tried in order)
+var dateLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02 15:04"}
+
+// dailyLayouts are the accepted timestamps without a date (tried in order)
+var dailyLayouts = []string{"15:04:05", "15:04"}
+
+// Annotation represents a note on a point in time (e.g. a configuration change)
+type Annotation struct {
+	Time  time.Time // time of the note (only the time of day is used if Daily is set)
+	Daily bool      // whether the timestamp has no date (the note applies to every day)
+	Text  string    // text of the note
+}
+
+// parseLine parses a single line of the form "<timestamp> <text>"
+func parseLine(line string) (Annotation, error) {
+	fields := strings.Fields(line)
+	for _, layout := range dateLayouts {
+		// layouts containing a space span two fields
+		n := strings.Count(layout, " ") + 1
+		if len(fields) <= n {
+			continue
+		}
+		t, err := time.ParseInLocation(layout, strings.Join(fields[:n], " "), time.Local)
+		if err == nil {
+			return Annotation{Time: t, Text: strings.Join(fields[n:], " ")}, nil
+		}
+	}
+	for _, layout := range dailyLayouts {
+		if len(fields) <= 1 {
+			break
+		}
+		t, err := time.Parse(layout, fields[0])
+		if err == nil {
+			return Annotation{Time: t, Daily: true, Text: strings.Join(fields[1:], " ")}, nil
+		}
+	}
+	if len(fields) == 1 {
+		return Annotation{}, fmt.Errorf("missing text after %q", fields[0])
+	}
+	return Annotation{}, fmt.Errorf("invalid timestamp %q", fields[0])
+}
+
+// public
+
+// At returns the time of the annotation at or before t (for daily annotations the same day as t in the
+// location of t, the day before if the time of day is later than t)
+func (a Annotation) At(t time.Time) time.Time {
+	if !a.Daily {
+		return a.Time
+	}
+	year, month, day := t.Date()
+	at := time.Date(year, month, day, a.Time.Hour(), a.Time.Minute(), a.Time.Second(), 0, t.Location())
+	if at.After(t) {
+		at = at.AddDate(0, 0, -1)
+	}
+	return at
+}
+
+// Between returns the annotations after from and at or before to (in chronological order,
+// daily annotations are returned with the time they occur at)
+func Between(annotations []Annotation, from, to time.Time) []Annotation {
+	var between []Annotation
+	for _, a := range annotations {
+		at := a.At(to)
+		if at.After(from) && !at.After(to) {
+			between = append(between, Annotation{Time: at, Text: a.Text})
+		}
+	}
+	slices.SortStableFunc(between, func(a, b Annotation) int {
+		return a.Time.Compare(b.Time)
+	})
+	return between
+}
+
+// Load reads the annotations file at path
+func Load(path string) ([]Annotation, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error(annotation): %w", err)
+	}
+	defer file.Close()
+	return Parse(file)
+}
+
+// Parse reads annotations, one per line in the form "<timestamp> <text>" (timestamps without a date apply to
+// every day, empty lines and lines starting with # are skipped)
+func Parse(r io.Reader) ([]Annotation, error) {
+	annotations := make([]Annotation, 0)
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		a, err := parseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("error(annotation): line %d: %w", lineNum, err)
+		}
+		annotations = append(annotations, a)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error(annotation): %w", err)
+	}
+	return annotations, nil
+}
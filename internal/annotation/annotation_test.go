@@ -0,0 +1,135 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package annotation
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []Annotation
+		wantErr bool
+	}{
+		{
+			name:  "daily",
+			input: "14:02 enabled new WAN rule\n",
+			want:  []Annotation{{Time: time.Date(0, 1, 1, 14, 2, 0, 0, time.UTC), Daily: true, Text: "enabled new WAN rule"}},
+		},
+		{
+			name:  "date and time",
+			input: "# changes\n\n2025-10-10 14:02 enabled  new WAN rule\n2025-10-10T15:00:30 disabled it\n",
+			want: []Annotation{
+				{Time: time.Date(2025, 10, 10, 14, 2, 0, 0, time.Local), Text: "enabled new WAN rule"},
+				{Time: time.Date(2025, 10, 10, 15, 0, 30, 0, time.Local), Text: "disabled it"},
+			},
+		},
+		{
+			name:  "rfc3339",
+			input: "2025-10-10T14:02:00+02:00 upgrade",
+			want:  []Annotation{{Time: time.Date(2025, 10, 10, 14, 2, 0, 0, time.FixedZone("", 2*60*60)), Text: "upgrade"}},
+		},
+		{
+			name:    "missing text",
+			input:   "14:02",
+			wantErr: true,
+		},
+		{
+			name:    "invalid timestamp",
+			input:   "yesterday enabled new WAN rule",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(strings.NewReader(tt.input))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %d annotations, got %d", len(tt.want), len(got))
+			}
+			for i := range got {
+				if !got[i].Time.Equal(tt.want[i].Time) || got[i].Daily != tt.want[i].Daily || got[i].Text != tt.want[i].Text {
+					t.Errorf("expected %v, got %v", tt.want[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBetween(t *testing.T) {
+	annotations := []Annotation{
+		{Time: time.Date(2025, 10, 10, 14, 2, 0, 0, time.UTC), Text: "dated"},
+		{Time: time.Date(0, 1, 1, 8, 0, 0, 0, time.UTC), Daily: true, Text: "daily"},
+	}
+	day := func(d, h, m int) time.Time { return time.Date(2025, 10, d, h, m, 0, 0, time.UTC) }
+	tests := []struct {
+		name     string
+		from, to time.Time
+		want     []Annotation
+	}{
+		{
+			name: "dated",
+			from: day(10, 14, 0),
+			to:   day(10, 14, 2),
+			want: []Annotation{{Time: day(10, 14, 2), Text: "dated"}},
+		},
+		{
+			name: "daily on another day",
+			from: day(11, 7, 59),
+			to:   day(11, 8, 1),
+			want: []Annotation{{Time: day(11, 8, 0), Text: "daily"}},
+		},
+		{
+			name: "daily across midnight",
+			from: day(10, 7, 0),
+			to:   day(11, 7, 0),
+			want: []Annotation{{Time: day(10, 8, 0), Text: "daily"}, {Time: day(10, 14, 2), Text: "dated"}},
+		},
+		{
+			name: "none",
+			from: day(10, 14, 2),
+			to:   day(10, 15, 0),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Between(annotations, tt.from, tt.to); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
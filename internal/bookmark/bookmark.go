@@ -0,0 +1,181 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package bookmark
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/meta"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+const fileName = "bookmarks.json"
+
+// Bookmark represents an entry of interest with a note
+type Bookmark struct {
+	File    string          `json:"file"`    // absolute path of the log file
+	Line    int             `json:"line"`    // line number in the log file (starting at 1)
+	Note    string          `json:"note"`    // note of the user (may be empty)
+	Created time.Time       `json:"created"` // time the bookmark was created or last changed
+	Entry   stream.LogEntry `json:"entry"`   // copy of the entry (kept when the log file is rotated away)
+}
+
+// Store holds all bookmarks and persists them in a file
+type Store struct {
+	path      string     // file the bookmarks are stored in
+	bookmarks []Bookmark // bookmarks ordered by entry time
+}
+
+// find returns the index of the bookmark of the given line (-1 if none)
+func (s *Store) find(file string, line int) int {
+	return slices.IndexFunc(s.bookmarks, func(b Bookmark) bool {
+		return b.File == file && b.Line == line
+	})
+}
+
+// save writes the bookmarks to a temporary file and renames it to the path of the store
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(s.bookmarks, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error(bookmark): could not encode bookmarks: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("error(bookmark): could not write bookmarks: %w", err)
+	}
+	file, err := os.CreateTemp(filepath.Dir(s.path), ".bookmarks-*")
+	if err != nil {
+		return fmt.Errorf("error(bookmark): could not write bookmarks: %w", err)
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return fmt.Errorf("error(bookmark): could not write bookmarks: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("error(bookmark): could not write bookmarks: %w", err)
+	}
+	if err := os.Rename(file.Name(), s.path); err != nil {
+		return fmt.Errorf("error(bookmark): could not write bookmarks: %w", err)
+	}
+	return nil
+}
+
+// sort orders the bookmarks by entry time (then file and line)
+func (s *Store) sort() {
+	slices.SortFunc(s.bookmarks, func(a, b Bookmark) int {
+		return cmp.Or(a.Entry.Time.Compare(b.Entry.Time), cmp.Compare(a.File, b.File), cmp.Compare(a.Line, b.Line))
+	})
+}
+
+// public
+
+// All returns all bookmarks ordered by entry time
+func (s *Store) All() []Bookmark {
+	return slices.Clone(s.bookmarks)
+}
+
+// DefaultPath returns the default bookmarks file path (empty if it can't be determined)
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, meta.Name, fileName)
+}
+
+// File returns the bookmarks of the given log file ordered by entry time
+func (s *Store) File(file string) []Bookmark {
+	bookmarks := make([]Bookmark, 0)
+	for _, b := range s.bookmarks {
+		if b.File == file {
+			bookmarks = append(bookmarks, b)
+		}
+	}
+	return bookmarks
+}
+
+// FilePath returns the path bookmarks of the log file are stored under (absolute path with symlinks
+// such as latest.log resolved, so that bookmarks stay with their file after rotation)
+func FilePath(path string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", fmt.Errorf("error(bookmark): %w", err)
+	}
+	return filepath.Abs(resolved)
+}
+
+// Get returns the bookmark of the given line
+func (s *Store) Get(file string, line int) (Bookmark, bool) {
+	i := s.find(file, line)
+	if i < 0 {
+		return Bookmark{}, false
+	}
+	return s.bookmarks[i], true
+}
+
+// Load reads the bookmarks file at the given path (the store is empty if it does not exist)
+func Load(path string) (*Store, error) {
+	s := &Store{path: path, bookmarks: make([]Bookmark, 0)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error(bookmark): %w", err)
+	}
+	if err := json.Unmarshal(data, &s.bookmarks); err != nil {
+		return nil, fmt.Errorf("error(bookmark): could not parse %s: %w", path, err)
+	}
+	s.sort()
+	return s, nil
+}
+
+// Remove deletes the bookmark of the given line and saves the store
+func (s *Store) Remove(file string, line int) error {
+	i := s.find(file, line)
+	if i < 0 {
+		return nil
+	}
+	s.bookmarks = slices.Delete(s.bookmarks, i, i+1)
+	return s.save()
+}
+
+// Set adds the bookmark (or replaces the bookmark of the same line) and saves the store
+func (s *Store) Set(b Bookmark) error {
+	if i := s.find(b.File, b.Line); i >= 0 {
+		s.bookmarks[i] = b
+	} else {
+		s.bookmarks = append(s.bookmarks, b)
+	}
+	s.sort()
+	return s.save()
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package bookmark
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "bookmarks.json")
+	s, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(s.All()) != 0 {
+		t.Fatalf("expected empty store, got %d bookmarks", len(s.All()))
+	}
+	base := time.Date(2025, 10, 10, 0, 0, 0, 0, time.UTC)
+	bookmarks := []Bookmark{
+		{File: "/var/log/filter/a.log", Line: 20, Note: "second", Entry: stream.LogEntry{Time: base.Add(time.Minute), Src: "192.0.2.1"}},
+		{File: "/var/log/filter/a.log", Line: 10, Note: "first", Entry: stream.LogEntry{Time: base, Src: "192.0.2.2"}},
+		{File: "/var/log/filter/b.log", Line: 5, Note: "other file", Entry: stream.LogEntry{Time: base.Add(time.Hour)}},
+	}
+	for _, b := range bookmarks {
+		if err := s.Set(b); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	// replace the note of an existing bookmark
+	replaced := bookmarks[0]
+	replaced.Note = "second (checked)"
+	if err := s.Set(replaced); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	all := loaded.All()
+	if len(all) != 3 {
+		t.Fatalf("expected 3 bookmarks, got %d", len(all))
+	}
+	for i, note := range []string{"first", "second (checked)", "other file"} {
+		if all[i].Note != note {
+			t.Errorf("expected bookmark %d to have note %q, got %q", i, note, all[i].Note)
+		}
+	}
+	if all[0].Entry.Src != "192.0.2.2" || !all[0].Entry.Time.Equal(base) {
+		t.Errorf("expected entry to be stored, got %+v", all[0].Entry)
+	}
+	if got := loaded.File("/var/log/filter/a.log"); len(got) != 2 {
+		t.Errorf("expected 2 bookmarks of a.log, got %d", len(got))
+	}
+	if _, ok := loaded.Get("/var/log/filter/b.log", 5); !ok {
+		t.Error("expected bookmark of b.log line 5")
+	}
+	if _, ok := loaded.Get("/var/log/filter/b.log", 6); ok {
+		t.Error("expected no bookmark of b.log line 6")
+	}
+
+	if err := loaded.Remove("/var/log/filter/a.log", 10); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := loaded.Remove("/var/log/filter/a.log", 10); err != nil {
+		t.Fatalf("unexpected error removing missing bookmark: %v", err)
+	}
+	reloaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(reloaded.All()) != 2 {
+		t.Errorf("expected 2 bookmarks after removal, got %d", len(reloaded.All()))
+	}
+}
+
+func TestFilePath(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := filepath.Join(dir, "filter_20251010.log")
+	if err := os.WriteFile(target, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "latest.log")
+	if err := os.Symlink("filter_20251010.log", link); err != nil {
+		t.Fatal(err)
+	}
+	path, err := FilePath(link)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if path != target {
+		t.Errorf("expected %q, got %q", target, path)
+	}
+	if _, err := FilePath(filepath.Join(dir, "missing.log")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bookmarks.json")
+	if err := os.WriteFile(path, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("expected error for invalid file")
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/filter"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+// benchStage represents the result of a single benchmark stage
+type benchStage struct {
+	name     string        // stage name
+	lines    int           // number of lines processed
+	matches  int           // number of matching entries (-1 if not filtered)
+	duration time.Duration // time taken
+	alloc    uint64        // bytes allocated
+}
+
+// benchRun measures the duration and allocations of fn
+func benchRun(name string, fn func() (int, int, error)) (benchStage, error) {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	lines, matches, err := fn()
+	duration := time.Since(start)
+	runtime.ReadMemStats(&after)
+	return benchStage{
+		name:     name,
+		lines:    lines,
+		matches:  matches,
+		duration: duration,
+		alloc:    after.TotalAlloc - before.TotalAlloc,
+	}, err
+}
+
+// writeBench writes benchmark stages as an aligned table
+func writeBench(w io.Writer, stages []benchStage) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Stage\tLines\tMatches\tTime\tLines/s\tAllocated")
+	for _, s := range stages {
+		rate := 0.0
+		if s.duration > 0 {
+			rate = float64(s.lines) / s.duration.Seconds()
+		}
+		matches := "-"
+		if s.matches >= 0 {
+			matches = strconv.Itoa(s.matches)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%.0f\t%.1f MB\n", s.name, s.lines, matches,
+			s.duration.Round(time.Microsecond), rate, float64(s.alloc)/1024/1024)
+	}
+	return tw.Flush()
+}
+
+// displayBench measures indexing, parsing and filtering of the whole file and writes the results to stdout
+func displayBench(s *stream.Stream, filterValue string, filterOpts filter.Options) error {
+	compiled, err := filter.CompileWith(filterValue, filterOpts)
+	if err != nil {
+		return err
+	}
+	var stages []benchStage
+	index, err := benchRun("index", func() (int, int, error) {
+		if err := s.BuildIndex(); err != nil {
+			return 0, 0, err
+		}
+		return max(s.TotalLines(), 0), -1, nil
+	})
+	if err != nil {
+		return err
+	}
+	stages = append(stages, index)
+	if index.lines == 0 {
+		return fmt.Errorf("error(bench): no valid entries found")
+	}
+	parse, err := benchRun("parse", func() (int, int, error) {
+		if err := s.SeekToLine(0); err != nil {
+			return 0, 0, err
+		}
+		lines := 0
+		for entry := s.Next(); entry != nil; entry = s.Next() {
+			lines++
+		}
+		return lines, -1, nil
+	})
+	if err != nil {
+		return err
+	}
+	stages = append(stages, parse)
+	if compiled != nil {
+		match, err := benchRun("filter", func() (int, int, error) {
+			if err := s.SeekToLine(0); err != nil {
+				return 0, 0, err
+			}
+			lines, matches := 0, 0
+			for entry := s.Next(); entry != nil; entry = s.Next() {
+				lines++
+				if compiled.Matches(entry) {
+					matches++
+				}
+			}
+			return lines, matches, nil
+		})
+		if err != nil {
+			return err
+		}
+		stages = append(stages, match)
+	}
+	if err := writeBench(os.Stdout, stages); err != nil {
+		return fmt.Errorf("error(bench): could not write results: %w", err)
+	}
+	return nil
+}
+
+// startProfile writes a cpu profile to dir until the returned function is called, which also writes a heap profile
+func startProfile(dir string) (func() error, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error(cli): could not create profile directory: %w", err)
+	}
+	cpu, err := os.Create(filepath.Join(dir, "cpu.pprof"))
+	if err != nil {
+		return nil, fmt.Errorf("error(cli): could not create cpu profile: %w", err)
+	}
+	if err := pprof.StartCPUProfile(cpu); err != nil {
+		cpu.Close()
+		return nil, fmt.Errorf("error(cli): could not start cpu profile: %w", err)
+	}
+	return func() error {
+		pprof.StopCPUProfile()
+		if err := cpu.Close(); err != nil {
+			return fmt.Errorf("error(cli): could not write cpu profile: %w", err)
+		}
+		heap, err := os.Create(filepath.Join(dir, "heap.pprof"))
+		if err != nil {
+			return fmt.Errorf("error(cli): could not create heap profile: %w", err)
+		}
+		defer heap.Close()
+		runtime.GC()
+		if err := pprof.WriteHeapProfile(heap); err != nil {
+			return fmt.Errorf("error(cli): could not write heap profile: %w", err)
+		}
+		return nil
+	}, nil
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/filter"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+func TestBench(t *testing.T) {
+	tests := []struct {
+		name         string
+		filter       string
+		expectStages []string
+		expectError  bool
+	}{
+		{
+			name:         "without filter",
+			expectStages: []string{"index 20 -", "parse 20 -"},
+		},
+		{
+			name:         "with filter",
+			filter:       "proto tcp",
+			expectStages: []string{"index 20 -", "parse 20 -", "filter 20 12"},
+		},
+		{
+			name:        "invalid filter",
+			filter:      "src and",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := stream.NewStream("../../tests/filter_valid.log")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+			stdout, _, err := captureOutput(func() error {
+				return displayBench(s, tc.filter, filter.Options{})
+			})
+			if tc.expectError {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			lines := strings.Split(strings.TrimSpace(string(stdout)), "\n")
+			if len(lines) != len(tc.expectStages)+1 {
+				t.Fatalf("expected header and %d stages, got %q", len(tc.expectStages), lines)
+			}
+			for i, expect := range tc.expectStages {
+				if fields := strings.Fields(lines[i+1]); strings.Join(fields[:3], " ") != expect {
+					t.Fatalf("expected stage %q, got %q", expect, lines[i+1])
+				}
+			}
+		})
+	}
+}
+
+func TestStartProfile(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "profile")
+	stop, err := startProfile(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stop(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, name := range []string{"cpu.pprof", "heap.pprof"} {
+		if info, err := os.Stat(filepath.Join(dir, name)); err != nil || info.Size() == 0 {
+			t.Fatalf("expected non-empty %s, got %v", name, err)
+		}
+	}
+}
@@ -24,38 +24,139 @@
 package cli
 
 import (
+	"cmp"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"reflect"
+	"runtime"
+	"slices"
 	"strconv"
+	"strings"
+	"sync/atomic"
 
+	"gitlab.com/allddd/opnsense-filterlog/internal/annotation"
+	"gitlab.com/allddd/opnsense-filterlog/internal/bookmark"
+	"gitlab.com/allddd/opnsense-filterlog/internal/config"
+	"gitlab.com/allddd/opnsense-filterlog/internal/debuglog"
+	"gitlab.com/allddd/opnsense-filterlog/internal/filter"
 	"gitlab.com/allddd/opnsense-filterlog/internal/meta"
+	"gitlab.com/allddd/opnsense-filterlog/internal/netclass"
+	"gitlab.com/allddd/opnsense-filterlog/internal/output"
+	"gitlab.com/allddd/opnsense-filterlog/internal/plugin"
+	"gitlab.com/allddd/opnsense-filterlog/internal/sandbox"
+	"gitlab.com/allddd/opnsense-filterlog/internal/severity"
 	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
 	"gitlab.com/allddd/opnsense-filterlog/internal/tui"
 )
 
-const defaultLogPath = "/var/log/filter/latest.log"
+const (
+	// commands
+	cmdConfig = "config"
+	cmdDaemon = "daemon"
+	cmdFetch  = "fetch"
+	cmdFilter = "filter"
+	cmdServe  = "serve"
+	cmdSetup  = "setup"
+	cmdStats  = "stats"
+
+	// -print-on-exit
+	printFiltered = "filtered"
+	printVisible  = "visible"
+)
+
+const remoteLogDir = "/var/log/filter" // directory of the filter logs on the firewall
 const usageText = `terminal-based viewer for OPNsense firewall logs
 
 Usage:
-  %s [flag]... [path]
+  %[1]s [flag]... [path]...
+  %[1]s <command> [flag]... [path]
+
+Commands:
+  config	check the configuration file for problems (see '%[1]s config -h')
+  daemon	follow log file and write periodic reports (see '%[1]s daemon -h')
+  fetch	download logs from firewalls over SFTP and open them (see '%[1]s fetch -h')
+  filter	test filter expressions against sample log lines (see '%[1]s filter test -h')
+  serve	serve entries over HTTP (see '%[1]s serve -h')
+  setup	create a starter config file interactively (see '%[1]s setup -h')
+  stats	display statistics and exit (see '%[1]s stats -h')
 
 Arguments:
-  path	filter log file to analyze, defaults to 'latest.log' if omitted
+  path	filter log file to analyze (several files are merged by time), defaults to 'path' of the config file (latest.log) if omitted
 
 Flags:
 `
 
 type flags struct {
-	Filter  string `name:"f" usage:"filter expression (requires -j)"`
-	Help    bool   `name:"h" usage:"display this help message and exit"`
-	Json    bool   `name:"j" usage:"display entries as JSON and exit"`
-	Version bool   `name:"V" usage:"display version information and exit"`
+	Accessible  bool   `name:"accessible" usage:"render the TUI for screen readers: no colors or animations, > marks the selected line (same as accessible in config)"`
+	API         bool   `name:"api" usage:"read the newest entries from the OPNsense API (see api in config) instead of a file"`
+	Annotations string `name:"annotations" usage:"file of timestamped notes (e.g. 14:02 enabled new WAN rule) shown as separator rows in the TUI"`
+	Bench       bool   `name:"bench" usage:"measure indexing, parsing and filtering of the file, display results and exit"`
+	Bookmarks   string `name:"bookmarks" usage:"file bookmarks are stored in (default bookmarks.json in the config directory)"`
+	ClauseStats bool   `name:"clause-stats" usage:"display how many entries each clause of -f was evaluated for and matched on stderr (requires -j, -plain or -format)"`
+	Columns     bool   `name:"index-fields" usage:"record action, interface, ip version and ports while indexing to speed up simple filters in the TUI"`
+	Compress    string `name:"compress" usage:"compress entries written to the -o file or tcp destination (gzip, none, zstd; default: detected from the extension, .gz or .zst)"`
+	Config      string `name:"c" usage:"path to config file"`
+	Debug       string `name:"debug" usage:"append internal events (index timings, seeks, TUI messages, sink retries) to file"`
+	Dedupe      bool   `name:"dedupe" usage:"skip entries identical to one logged up to a minute before, e.g. of overlapping rotated files or re-shipped syslog (requires -j, -plain or -format)"`
+	Detect      string `name:"detect" usage:"run analysis (bruteforce, nat), display report and exit"`
+	Fields      bool   `name:"dump-fields" usage:"display every csv position with sample values and whether it is parsed, and exit"`
+	Filter      string `name:"f" usage:"filter expression (requires -j, -plain, -format, -bench, -detect, -incident or -report)"`
+	Follow      bool   `name:"follow" usage:"keep running and display new entries as they are written (with -j, -plain or -format, or in the TUI, toggled with f)"`
+	Format      string `name:"format" usage:"display entries in format (cef, csv, json, logfmt, ndjson, plain, template) and exit"`
+	Help        bool   `name:"h" usage:"display this help message and exit"`
+	HideNDP     bool   `name:"hide-ndp" usage:"hide icmpv6 neighbor discovery (types 133-137) in the TUI, can be toggled with N"`
+	Incident    string `name:"incident" usage:"write entries, raw lines, filter, summary and bookmarks to directory (or .tar.gz) and exit"`
+	Inline      bool   `name:"inline" usage:"render the TUI in the terminal instead of the alternate screen, so the last view stays in the scrollback after quitting"`
+	Json        bool   `name:"j" usage:"display entries as JSON and exit"`
+	Manifest    bool   `name:"manifest" usage:"write a manifest with the SHA-256 checksums and counts of the exported files (requires -incident, or -o with -j, -plain or -format)"`
+	Memory      int    `name:"memory-limit" usage:"approximate memory in MB used to cache entries in the TUI (default 1000 entries)"`
+	Mmap        bool   `name:"mmap" usage:"map the file into memory to speed up scrolling through filter results (file must not be truncated meanwhile)"`
+	NoSandbox   bool   `name:"no-sandbox" usage:"don't restrict file access once the log is open (applies to -detect, -format, -incident, -j, -plain and -report)"`
+	NoSymlinks  bool   `name:"no-symlinks" usage:"refuse to open the log file if it is a symlink (e.g. latest.log)"`
+	Output      string `name:"o" usage:"write report or entries to file, or send entries to tcp://host:port or udp://host:port, instead of stdout (requires -report, -j, -plain or -format)"`
+	Plain       bool   `name:"plain" usage:"display entries as table rows and exit (same as -format plain)"`
+	Pprof       string `name:"pprof" usage:"write cpu and heap profiles to directory"`
+	Print       string `name:"print-on-exit" usage:"write the entries shown in the log view (visible) or all entries matching the filter (filtered) of the TUI to stdout after quitting"`
+	PrintFormat string `name:"print-format" usage:"format of the entries written by -print-on-exit (cef, csv, json, logfmt, ndjson, plain, template; default: ndjson)"`
+	Profile     string `name:"profile" usage:"open the log of the named firewall profile (see profiles in config)"`
+	Replay      bool   `name:"replay" usage:"write the entries at the pace they were logged at, like -follow would have written them (requires -j, -plain or -format, see -speed)"`
+	Report      string `name:"report" usage:"generate report (html, json, markdown) and exit"`
+	Speed       string `name:"speed" value:"1x" usage:"factor the pace of -replay is sped up by, e.g. 10x (or slowed down, e.g. 0.5x)"`
+	Schema      bool   `name:"schema" usage:"display the JSON schema of the entries and meta objects written by -j and exit"`
+	State       string `name:"state" usage:"resume -follow at the position saved in file after a restart, saved whenever new entries have been written (requires -follow and -j, -plain or -format)"`
+	Template    string `name:"template" usage:"line written per entry with {field} placeholders, e.g. \"{time} {src} -> {dst}:{dport}\" (requires -format template or -print-format template)"`
+	Terms       bool   `name:"index-terms" usage:"record which values occur in each block of entries while indexing to speed up searches in the TUI"`
+	Unordered   bool   `name:"unordered" usage:"write entries in the order the workers are done with them instead of the order of the file (requires -j, -plain or -format)"`
+	Version     bool   `name:"V" usage:"display version information and exit"`
+	Workers     int    `name:"workers" usage:"number of goroutines parsing and formatting entries with -j, -plain or -format, 1 to disable (default: number of cpus, ignored with -follow, -clause-stats, -dedupe and enrich)"`
+}
+
+// stringsValue collects the values of a flag that can be repeated
+type stringsValue []string
+
+func (v *stringsValue) String() string {
+	return strings.Join(*v, ",")
+}
+
+func (v *stringsValue) Set(s string) error {
+	*v = append(*v, s)
+	return nil
+}
+
+// usageError is an error about invalid flags (the usage is displayed after it)
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e)
 }
 
-// flagsDefine defines all flags set in the struct
-func (f *flags) flagsDefine() {
+// flagsDefine defines all flags set in the struct (f must be a pointer to a struct)
+func flagsDefine(fs *flag.FlagSet, f any) {
 	sv := reflect.ValueOf(f).Elem()
 	st := sv.Type()
 	for i := 0; i < st.NumField(); i++ {
@@ -67,68 +168,589 @@ func (f *flags) flagsDefine() {
 		switch fv.Kind() {
 		case reflect.Bool:
 			valueBool, _ := strconv.ParseBool(value)
-			flag.BoolVar(fv.Addr().Interface().(*bool), name, valueBool, usage)
+			fs.BoolVar(fv.Addr().Interface().(*bool), name, valueBool, usage)
+		case reflect.Int:
+			valueInt, _ := strconv.Atoi(value)
+			fs.IntVar(fv.Addr().Interface().(*int), name, valueInt, usage)
 		case reflect.String:
-			flag.StringVar(fv.Addr().Interface().(*string), name, value, usage)
+			fs.StringVar(fv.Addr().Interface().(*string), name, value, usage)
+		case reflect.Slice:
+			fs.Var((*stringsValue)(fv.Addr().Interface().(*[]string)), name, usage)
+		}
+	}
+}
+
+// loadConfig loads the config file from path (or the default path if empty)
+func loadConfig(path string) (*config.Config, error) {
+	if path != "" {
+		return config.Load(path)
+	}
+	return config.LoadDefault()
+}
+
+// reloadConfig reads the config file at path again (defaults if it has been removed)
+func reloadConfig(path string) (*config.Config, error) {
+	cfg, err := config.Load(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return config.New(), nil
+	}
+	return cfg, err
+}
+
+// configReloader returns a function that reloads the config file at path if it changed since the last call and
+// applies its networks and severity rules to c (the config is nil if the file did not change)
+func configReloader(path string, c *classifier) func() (*config.Config, error) {
+	w := config.NewWatcher(path)
+	return func() (*config.Config, error) {
+		if !w.Changed() {
+			return nil, nil
 		}
+		cfg, err := reloadConfig(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.reload(cfg); err != nil {
+			return nil, err
+		}
+		return cfg, nil
+	}
+}
+
+// loadBookmarks loads the bookmarks file at path (or the default path if empty, nil if it can't be determined)
+func loadBookmarks(path string) (*bookmark.Store, error) {
+	if path == "" {
+		path = bookmark.DefaultPath()
+	}
+	if path == "" {
+		return nil, nil
+	}
+	return bookmark.Load(path)
+}
+
+// fileBookmarks returns the bookmarks of the log file of s (nil if bookmarks is nil, resolving the path of the file
+// isn't possible in the sandbox)
+func fileBookmarks(bookmarks *bookmark.Store, s *stream.Stream) []bookmark.Bookmark {
+	if bookmarks == nil {
+		return nil
+	}
+	file, err := bookmark.FilePath(s.GetPathRel())
+	if err != nil {
+		return nil
+	}
+	return bookmarks.File(file)
+}
+
+// classifierRules are the networks and severity rules of a classifier
+type classifierRules struct {
+	networks *netclass.Classifier // address classes
+	severity *severity.Classifier // severity levels (nil if no rules are configured)
+}
+
+// classifier assigns address classes and severity levels to entries (its rules can be replaced while it is used)
+type classifier struct {
+	rules atomic.Pointer[classifierRules] // current rules
+}
+
+// newClassifierRules compiles the networks and severity rules in cfg
+func newClassifierRules(cfg *config.Config) (*classifierRules, error) {
+	networks, err := netclass.New(cfg.Networks)
+	if err != nil {
+		return nil, err
+	}
+	levels, err := severity.New(cfg.Severity, cfg.FilterOptions())
+	if err != nil {
+		return nil, err
 	}
+	return &classifierRules{networks: networks, severity: levels}, nil
+}
+
+// newClassifier creates the classifier for the networks and severity rules in cfg
+func newClassifier(cfg *config.Config) (*classifier, error) {
+	rules, err := newClassifierRules(cfg)
+	if err != nil {
+		return nil, err
+	}
+	c := &classifier{}
+	c.rules.Store(rules)
+	return c, nil
+}
+
+// reload replaces the rules by the networks and severity rules in cfg (entries classified afterwards use them)
+func (c *classifier) reload(cfg *config.Config) error {
+	rules, err := newClassifierRules(cfg)
+	if err != nil {
+		return err
+	}
+	c.rules.Store(rules)
+	return nil
+}
+
+// Classify assigns the address classes first, so severity rules can use them (only built-in classes if c is nil)
+func (c *classifier) Classify(entry *stream.LogEntry) {
+	var rules classifierRules
+	if c != nil {
+		rules = *c.rules.Load()
+	}
+	rules.networks.Classify(entry)
+	rules.severity.Classify(entry)
+}
+
+// classify enables classification of the entries of s
+func classify(s *stream.Stream, c *classifier) {
+	s.SetHook(c.Classify)
+}
+
+// followPaths returns the directories a follower of path reopens files in (including the symlink target's)
+func followPaths(path string) []string {
+	paths := []string{filepath.Dir(path)}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil && filepath.Dir(resolved) != paths[0] {
+		paths = append(paths, filepath.Dir(resolved))
+	}
+	return paths
+}
+
+// enterSandbox restricts file access to p unless disabled (platforms that can't restrict the process only print a
+// warning, like the daemon)
+func enterSandbox(disabled bool, p sandbox.Policy) error {
+	if disabled {
+		return nil
+	}
+	err := sandbox.Enter(p)
+	if errors.Is(err, sandbox.ErrUnsupported) {
+		fmt.Fprintf(os.Stderr, "warning(cli): running without sandbox: %v\n", err)
+		return nil
+	}
+	return err
+}
+
+// logPath returns the first path in args (or the newest log file matching the path in cfg if empty)
+func logPath(args []string, cfg *config.Config) (string, error) {
+	if len(args) == 0 {
+		return config.ResolvePath(cfg.Path)
+	}
+	return args[0], nil
+}
+
+// openStream opens the path in args, merges several paths by time (or opens the log file in cfg if empty)
+func openStream(args []string, cfg *config.Config) (*stream.Stream, error) {
+	if len(args) > 1 {
+		return stream.NewMergedStream(args)
+	}
+	path, err := logPath(args, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return stream.NewStream(path)
 }
 
 func Execute() {
+	// commands
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case cmdConfig:
+			executeConfig(os.Args[2:])
+			return
+		case cmdDaemon:
+			executeDaemon(os.Args[2:])
+			return
+		case cmdFetch:
+			executeFetch(os.Args[2:])
+			return
+		case cmdFilter:
+			executeFilter(os.Args[2:])
+			return
+		case cmdServe:
+			executeServe(os.Args[2:])
+			return
+		case cmdSetup:
+			executeSetup(os.Args[2:])
+			return
+		case cmdStats:
+			executeStats(os.Args[2:])
+			return
+		}
+	}
+
 	var f flags
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, usageText, meta.Name)
 		flag.PrintDefaults()
 	}
-	f.flagsDefine()
+	flagsDefine(flag.CommandLine, &f)
 	flag.Parse()
+	if err := run(&f); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		var usage usageError
+		if errors.As(err, &usage) {
+			flag.Usage()
+		}
+		os.Exit(1)
+	}
+}
+
+// run runs the mode selected by the flags in f (deferred cleanups run before the process exits)
+func run(f *flags) error {
 	// check mutually exclusive flags
 	count := 0
-	for _, provided := range []bool{f.Help, f.Json, f.Version} {
+	for _, provided := range []bool{f.Bench, f.Detect != "", f.Fields, f.Format != "", f.Help, f.Incident != "", f.Json, f.Plain, f.Report != "", f.Schema, f.Version} {
 		if provided {
 			if count++; count > 1 {
-				fmt.Fprintln(os.Stderr, "error(cli): mutually exclusive flags")
-				flag.Usage()
-				os.Exit(1)
+				return usageError("error(cli): mutually exclusive flags")
 			}
 		}
 	}
-	if !f.Json && f.Filter != "" {
-		fmt.Fprintln(os.Stderr, "error(cli): -f requires -j flag")
-		flag.Usage()
-		os.Exit(1)
+	if f.Json {
+		f.Format = output.FormatJSON
+	}
+	if f.Plain {
+		f.Format = output.FormatPlain
+	}
+	if f.Format != "" && !slices.Contains(output.Formats, f.Format) {
+		return usageError(fmt.Sprintf("error(cli): unknown format %q (available: %s)", f.Format, strings.Join(output.Formats, ", ")))
+	}
+	if f.Print != "" && f.Print != printVisible && f.Print != printFiltered {
+		return usageError(fmt.Sprintf("error(cli): unknown -print-on-exit value %q (available: %s, %s)", f.Print, printFiltered, printVisible))
+	}
+	if f.Print != "" && (f.API || f.Bench || f.Detect != "" || f.Format != "" || f.Incident != "" || f.Report != "") {
+		return usageError("error(cli): -print-on-exit requires the TUI")
+	}
+	if f.PrintFormat != "" && (f.Print == "" || !slices.Contains(output.Formats, f.PrintFormat)) {
+		return usageError(fmt.Sprintf("error(cli): -print-format requires -print-on-exit and a known format (available: %s)", strings.Join(output.Formats, ", ")))
+	}
+	if (f.Format == output.FormatTemplate || f.PrintFormat == output.FormatTemplate) != (f.Template != "") {
+		return usageError("error(cli): -format template and -print-format template require -template flag and vice versa")
+	}
+	if f.Format == "" && !f.Bench && f.Detect == "" && f.Incident == "" && f.Report == "" && f.Filter != "" {
+		return usageError("error(cli): -f requires -j, -plain, -format, -bench, -detect, -incident or -report flag")
+	}
+	if f.Follow && (f.API || f.Format == "" && (f.Bench || f.Detect != "" || f.Incident != "" || f.Report != "")) {
+		return usageError("error(cli): -follow requires -j, -plain, -format or the TUI and can't be used with -api")
+	}
+	if f.State != "" && (!f.Follow || f.Format == "") {
+		return usageError("error(cli): -state requires -follow and -j, -plain or -format flag")
+	}
+	if f.Replay && (f.Format == "" || f.Follow) {
+		return usageError("error(cli): -replay requires -j, -plain or -format flag and can't be used with -follow")
+	}
+	speed, err := parseSpeed(f.Speed)
+	if err != nil || f.Speed != speedDefault && !f.Replay {
+		return usageError("error(cli): -speed requires -replay and a positive factor (e.g. 10x)")
+	}
+	if f.ClauseStats && (f.Format == "" || f.Follow || f.Replay || f.Filter == "") {
+		return usageError("error(cli): -clause-stats requires -f and -j, -plain or -format flag and can't be used with -follow or -replay")
+	}
+	if f.Compress != "" && (f.Output == "" || f.Report != "") {
+		return usageError("error(cli): -compress requires -o and -j, -plain or -format flag")
+	}
+	if f.Manifest && f.Incident == "" && (f.Output == "" || f.Report != "" || f.Follow || f.Replay || output.IsNetwork(f.Output)) {
+		return usageError("error(cli): -manifest requires -incident, or -o file and -j, -plain or -format flag and can't be used with -follow or -replay")
+	}
+	if f.Workers < 0 {
+		return usageError("error(cli): -workers must not be negative")
+	}
+	if f.Dedupe && (f.Format == "" || f.Follow || f.Replay) {
+		return usageError("error(cli): -dedupe requires -j, -plain or -format flag and can't be used with -follow or -replay")
+	}
+	if f.Unordered && (f.Format == "" || f.Follow || f.Replay) {
+		return usageError("error(cli): -unordered requires -j, -plain or -format flag and can't be used with -follow or -replay")
+	}
+	if f.Memory < 0 {
+		return usageError("error(cli): -memory-limit must not be negative")
+	}
+	if f.Output != "" && ((f.Report == "" && f.Format == "") || (f.Report != "" && output.IsNetwork(f.Output))) {
+		return usageError("error(cli): -o requires -report, -j, -plain or -format flag (network destinations only with the latter)")
 	}
 	// -h
 	if f.Help {
 		flag.Usage()
-		os.Exit(0)
+		return nil
 	}
 	// -V
 	if f.Version {
 		fmt.Fprintln(os.Stdout, meta.Version)
-		os.Exit(0)
+		return nil
+	}
+	// -debug
+	if f.Debug != "" {
+		file, err := debuglog.Open(f.Debug)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
 	}
-	// args
+	// -schema
+	if f.Schema {
+		return writeSchema(os.Stdout)
+	}
+	// -no-symlinks
+	stream.SetNoSymlinks(f.NoSymlinks)
+	// -c
+	cfg, err := loadConfig(f.Config)
+	if err != nil {
+		return err
+	}
+	// -f
+	warnFilter(f.Filter, cfg.FilterOptions())
+	// -api, -profile, args
 	args := flag.Args()
-	if len(args) == 0 {
-		args = []string{defaultLogPath}
+	if f.Follow && len(args) > 1 {
+		return usageError("error(cli): -follow requires a single path")
 	}
-
-	s, err := stream.NewStream(args[0])
+	if f.Profile != "" {
+		if f.API || len(args) > 0 {
+			return usageError("error(cli): -profile is mutually exclusive with -api and path")
+		}
+		p, err := cfg.GetProfile(f.Profile)
+		if err != nil {
+			return err
+		}
+		path, err := profilePath(p)
+		if err != nil {
+			return err
+		}
+		args = []string{path}
+	} else if f.API {
+		if len(args) > 0 {
+			return usageError("error(cli): -api and path are mutually exclusive")
+		}
+		path, err := downloadAPI(cfg.API)
+		if err != nil {
+			return err
+		}
+		args = []string{path}
+	}
+	s, err := openStream(args, cfg)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		return err
 	}
-	// -j
-	if f.Json {
-		if err := displayJSON(s, f.Filter); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			os.Exit(1)
+	if f.Profile != "" {
+		s.SetOrigin(f.Profile)
+	}
+	c, err := newClassifier(cfg)
+	if err != nil {
+		return err
+	}
+	classify(s, c)
+	// -pprof
+	if f.Pprof != "" {
+		stop, err := startProfile(f.Pprof)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := stop(); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}()
+	}
+	// -bench
+	if f.Bench {
+		return displayBench(s, f.Filter, cfg.FilterOptions())
+	}
+	// -dump-fields
+	if f.Fields {
+		return displayDumpFields(s)
+	}
+	// -no-sandbox (profiles are written on exit)
+	noSandbox := f.NoSandbox || f.Pprof != ""
+	if f.Detect != "" || f.Incident != "" || f.Report != "" || f.Format != "" {
+		// these read the file from the open handle only, the decompressed copy of a compressed file isn't left
+		// behind if they exit early (or can't remove it in the sandbox)
+		if err := s.Unlink(); err != nil {
+			return err
+		}
+	}
+	// -detect
+	if f.Detect != "" {
+		if err := enterSandbox(noSandbox, sandbox.Policy{Read: []string{s.GetPathRel()}}); err != nil {
+			return err
+		}
+		return displayDetect(s, f.Detect, f.Filter, cfg)
+	}
+	// -bookmarks
+	bookmarks, err := loadBookmarks(f.Bookmarks)
+	if err != nil {
+		return err
+	}
+	// -incident
+	if f.Incident != "" {
+		// the path of the file can't be resolved in the sandbox
+		marked := fileBookmarks(bookmarks, s)
+		p := sandbox.Policy{Read: []string{s.GetPathRel()}, Write: []string{filepath.Dir(f.Incident)}}
+		if err := enterSandbox(noSandbox, p); err != nil {
+			return err
+		}
+		return displayIncident(s, f.Incident, f.Filter, cfg.FilterOptions(), marked, f.Manifest)
+	}
+	// -report
+	if f.Report != "" {
+		// the path of the file can't be resolved in the sandbox
+		marked := fileBookmarks(bookmarks, s)
+		p := sandbox.Policy{Read: []string{s.GetPathRel()}}
+		if f.Output != "" {
+			p.Write = []string{filepath.Dir(f.Output)}
+		}
+		if err := enterSandbox(noSandbox, p); err != nil {
+			return err
+		}
+		return displayReport(s, f.Report, f.Filter, cfg.FilterOptions(), f.Output, marked)
+	}
+	// -j, -format
+	if f.Format != "" {
+		var e *plugin.Enricher
+		if cfg.Enrich != "" {
+			if e, err = plugin.NewEnricher(cfg.Enrich); err != nil {
+				return err
+			}
+		}
+		p := sandbox.Policy{Read: []string{s.GetPathRel()}}
+		if f.Follow {
+			// rotated files are reopened by name
+			p.Read = followPaths(s.GetPathRel())
+		}
+		if f.State != "" {
+			p.Write = append(p.Write, filepath.Dir(f.State))
+		}
+		// the destination is opened before entering the sandbox
+		var w io.Writer = os.Stdout
+		var size int64
+		if !f.Follow && isTerminal(os.Stderr) && (f.Output != "" || !isTerminal(os.Stdout)) {
+			// the bar would be mixed with entries written to the terminal
+			size = progressSize(s.Source())
+		}
+		var dest io.WriteCloser
+		if f.Output != "" {
+			if f.Manifest {
+				// the manifest would only cover the entries of this run
+				if info, err := os.Stat(f.Output); err == nil && info.Size() > 0 {
+					return fmt.Errorf("error(cli): -manifest requires a new file, %s exists", f.Output)
+				}
+				// the file is read again and the manifest created next to it
+				p.Write = []string{filepath.Dir(f.Output)}
+			}
+			if dest, err = output.Open(f.Output, f.Compress); err != nil {
+				return err
+			}
+			// closed below once everything is written, only if writing fails before
+			defer func() {
+				if dest != nil {
+					dest.Close()
+				}
+			}()
+			w = dest
+			p.Network = output.IsNetwork(f.Output)
+		}
+		if err := enterSandbox(noSandbox, p); err != nil {
+			return err
+		}
+		// -workers, -unordered
+		opts := writeOptions{enricher: e, filter: f.Filter, filterOpts: cfg.FilterOptions(), format: f.Format, summary: newSummary(), template: f.Template, unordered: f.Unordered, workers: f.Workers}
+		if opts.workers == 0 {
+			opts.workers = runtime.NumCPU()
+		}
+		// -clause-stats
+		if f.ClauseStats {
+			opts.clauseStats = &filter.Stats{}
+		}
+		// -dedupe
+		if f.Dedupe {
+			opts.deduper = stream.NewDeduper()
+		}
+		display := func(errW io.Writer) error {
+			return displayEntries(w, errW, s, opts)
+		}
+		if f.Follow {
+			display = func(errW io.Writer) error {
+				var follower *stream.Follower
+				var source liveSource
+				var err error
+				// -state
+				if f.State != "" {
+					r, err := newResumable(s.GetPathRel(), f.State)
+					if err != nil {
+						return err
+					}
+					follower, source = r.Follower, r
+				} else {
+					if follower, err = stream.NewFollower(s.GetPathRel(), false); err != nil {
+						return err
+					}
+					source = follower
+				}
+				defer follower.Close()
+				if f.Profile != "" {
+					follower.SetOrigin(f.Profile)
+				}
+				follower.SetHook(c.Classify)
+				return displayFollow(w, errW, source, opts)
+			}
+		}
+		// -replay, -speed
+		if f.Replay {
+			display = func(errW io.Writer) error {
+				return displayFollow(w, errW, stream.NewReplayer(s, speed), opts)
+			}
+		}
+		var errW io.Writer = os.Stderr
+		if p := startProgress(os.Stderr, s, size); p != nil {
+			errW = p
+			defer p.Close()
+		}
+		err := display(errW)
+		if e != nil {
+			e.Close()
+		}
+		if err != nil {
+			return err
+		}
+		if opts.clauseStats != nil {
+			if err := writeClauseStats(errW, opts.clauseStats); err != nil {
+				return err
+			}
+		}
+		// -o (flushes buffered and compressed entries)
+		if dest != nil {
+			err := dest.Close()
+			dest = nil
+			if err != nil {
+				return err
+			}
+		}
+		// -manifest
+		if f.Manifest {
+			if err := writeManifest(f.Output, s, f.Filter, opts.summary); err != nil {
+				return err
+			}
 		}
 	} else {
-		if err := tui.Display(s); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			os.Exit(1)
+		var annotations []annotation.Annotation
+		if f.Annotations != "" {
+			if annotations, err = annotation.Load(f.Annotations); err != nil {
+				return err
+			}
+		}
+		// -c
+		var reload func() (*config.Config, error)
+		if path := cmp.Or(f.Config, config.DefaultPath()); path != "" {
+			reload = configReloader(path, c)
+		}
+		opts := tui.Options{Accessible: f.Accessible || cfg.Accessible, Annotations: annotations, Bookmarks: bookmarks, Columns: f.Columns, Follow: f.Follow, HideNDP: f.HideNDP, Inline: f.Inline, MemoryLimit: f.Memory, Mmap: f.Mmap, Open: profileOpener(cfg, c), PrintAll: f.Print == printFiltered, Profile: f.Profile, Reload: reload, Terms: f.Terms}
+		// -print-on-exit
+		var sink output.Sink
+		if f.Print != "" {
+			if sink, err = output.New(cmp.Or(f.PrintFormat, output.FormatNDJSON), os.Stdout, output.Options{Template: f.Template}); err != nil {
+				return err
+			}
+			opts.Print = sink.Write
+		}
+		if err := tui.Display(s, cfg, opts); err != nil {
+			return err
+		}
+		if sink != nil {
+			if err := sink.Close(); err != nil {
+				return err
+			}
 		}
 	}
+	return nil
 }
@@ -0,0 +1,114 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package cli
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/config"
+	"gitlab.com/allddd/opnsense-filterlog/internal/netclass"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+func TestFlagsDefine(t *testing.T) {
+	var f struct {
+		Bool    bool     `name:"b" usage:"bool"`
+		Int     int      `name:"n" value:"5" usage:"int"`
+		Repeat  []string `name:"r" usage:"repeatable"`
+		String  string   `name:"s" value:"default" usage:"string"`
+		Unused  string   `name:"u" value:"unused" usage:"string with default"`
+		Ignored float64  `name:"i" usage:"unsupported kind"`
+	}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flagsDefine(fs, &f)
+	if err := fs.Parse([]string{"-b", "-r", "a", "--r", "b", "-s", "value", "path"}); err != nil {
+		t.Fatal(err)
+	}
+	if !f.Bool || f.Int != 5 || f.String != "value" || f.Unused != "unused" {
+		t.Fatalf("unexpected values: %+v", f)
+	}
+	if !slices.Equal(f.Repeat, []string{"a", "b"}) {
+		t.Fatalf("expected repeated values [a b], got %v", f.Repeat)
+	}
+	if fs.Lookup("i") != nil {
+		t.Fatal("expected unsupported kind to be ignored")
+	}
+	if fs.NArg() != 1 || fs.Arg(0) != "path" {
+		t.Fatalf("unexpected args %v", fs.Args())
+	}
+}
+
+func TestConfigReloader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	write := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`{}`)
+	c, err := newClassifier(config.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	reload := configReloader(path, c)
+	classOf := func() string {
+		entry := stream.LogEntry{Src: "192.0.2.1", Dst: "192.0.2.2"}
+		c.Classify(&entry)
+		return entry.SrcClass + " " + entry.Severity
+	}
+
+	if cfg, err := reload(); cfg != nil || err != nil {
+		t.Fatalf("expected no reload of unchanged file, got %v, %v", cfg, err)
+	}
+	write(`{"networks": ["192.0.2.0/24"], "severity": [{"filter": "srcclass mine", "level": "warning"}]}`)
+	cfg, err := reload()
+	if err != nil || cfg == nil || len(cfg.Networks) != 1 {
+		t.Fatalf("expected reloaded config, got %v, %v", cfg, err)
+	}
+	if got := classOf(); got != netclass.ClassMine+" "+stream.SeverityWarning {
+		t.Fatalf("expected entry classified by reloaded rules, got %q", got)
+	}
+	// invalid files keep the previous rules
+	write(`{"networks": ["192.0.2.0"]}`)
+	if _, err := reload(); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if got := classOf(); got != netclass.ClassMine+" "+stream.SeverityWarning {
+		t.Fatalf("expected previous rules to be kept, got %q", got)
+	}
+	// removed files apply the defaults
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if cfg, err := reload(); err != nil || cfg == nil {
+		t.Fatalf("expected default config, got %v, %v", cfg, err)
+	}
+	if got := classOf(); got == netclass.ClassMine+" "+stream.SeverityWarning {
+		t.Fatalf("expected default rules, got %q", got)
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"strings"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/config"
+	"gitlab.com/allddd/opnsense-filterlog/internal/filter"
+	"gitlab.com/allddd/opnsense-filterlog/internal/meta"
+	"gitlab.com/allddd/opnsense-filterlog/internal/sink"
+)
+
+const configUsageText = `check the configuration file for problems
+
+Usage:
+  %s config check [-c path] [-export url]...
+
+Reports every problem of the config file (severity rules, interfaces, networks, profiles, api and auth) and of the
+given daemon export URLs, which are checked without connecting to them. Filter warnings and commands (enrich, open)
+that are not found are reported as warnings. Exits with status 1 if there are problems.
+
+Flags:
+`
+
+type configFlags struct {
+	Config string   `name:"c" usage:"path to config file (default: config.json in the config directory)"`
+	Export []string `name:"export" usage:"daemon export url to check (can be repeated)"`
+	Help   bool     `name:"h" usage:"display this help message and exit"`
+}
+
+// checkConfig checks the config file at path and the export urls, writes a report to w and returns the number of
+// problems (a missing config file is only a problem if required)
+func checkConfig(w io.Writer, path string, required bool, exports []string) int {
+	var problems, warnings []string
+	cfg, errs := config.Check(path)
+	if len(errs) == 1 && cfg == nil && errors.Is(errs[0], fs.ErrNotExist) && !required {
+		warnings = append(warnings, "file not found, defaults apply")
+		errs = nil
+	}
+	for _, err := range errs {
+		problems = append(problems, err.Error())
+	}
+	// filters of export urls are compiled like the filters of the daemon
+	var opts filter.Options
+	if cfg != nil {
+		opts = cfg.FilterOptions()
+		for i, rule := range cfg.Severity {
+			compiled, err := filter.CompileWith(rule.Filter, opts)
+			if err != nil {
+				continue
+			}
+			for _, warning := range filter.Warnings(compiled) {
+				warnings = append(warnings, fmt.Sprintf("severity[%d].filter: %s", i, warning))
+			}
+		}
+		for _, c := range []struct{ key, command string }{{"enrich", cfg.Enrich}, {"open", cfg.Open}} {
+			if args := strings.Fields(c.command); len(args) > 0 {
+				if _, err := exec.LookPath(args[0]); err != nil {
+					warnings = append(warnings, fmt.Sprintf("%s: command %q not found", c.key, args[0]))
+				}
+			}
+		}
+	}
+	for _, u := range exports {
+		s, err := sink.NewDryRun(u, opts, io.Discard)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		s.Close()
+	}
+	fmt.Fprintf(w, "%s:\n", path)
+	for _, problem := range problems {
+		fmt.Fprintf(w, "  problem: %s\n", problem)
+	}
+	for _, warning := range warnings {
+		fmt.Fprintf(w, "  warning: %s\n", warning)
+	}
+	fmt.Fprintf(w, "problems: %d, warnings: %d\n", len(problems), len(warnings))
+	return len(problems)
+}
+
+// executeConfig runs the config command
+func executeConfig(args []string) {
+	var f configFlags
+	fs := flag.NewFlagSet(cmdConfig, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, configUsageText, meta.Name)
+		fs.PrintDefaults()
+	}
+	flagsDefine(fs, &f)
+	if len(args) > 0 && args[0] == "check" {
+		args = args[1:]
+	} else if len(args) > 0 && args[0] != "-h" {
+		fmt.Fprintf(os.Stderr, "error(cli): unknown config command %q\n", args[0])
+		fs.Usage()
+		os.Exit(1)
+	}
+	fs.Parse(args)
+	// -h
+	if f.Help {
+		fs.Usage()
+		os.Exit(0)
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintln(os.Stderr, "error(cli): config check takes no arguments")
+		fs.Usage()
+		os.Exit(1)
+	}
+	// -c
+	path := f.Config
+	if path == "" {
+		if path = config.DefaultPath(); path == "" {
+			fmt.Fprintln(os.Stderr, "error(cli): could not determine the config directory (use -c)")
+			os.Exit(1)
+		}
+	}
+	if checkConfig(os.Stdout, path, f.Config != "", f.Export) > 0 {
+		os.Exit(1)
+	}
+}
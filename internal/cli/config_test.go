@@ -0,0 +1,99 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckConfig(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name           string
+		content        string // config file content (no file if empty)
+		required       bool
+		exports        []string
+		expectProblems int
+		expectOutput   []string
+	}{
+		{
+			name:         "valid",
+			content:      `{"networks": ["192.0.2.0/24"], "open": "sh -c true"}`,
+			exports:      []string{"mqtt://localhost/topic"},
+			expectOutput: []string{"problems: 0, warnings: 0"},
+		},
+		{
+			name:           "problems and warnings",
+			content:        `{"networks": ["192.0.2.0"], "severity": [{"filter": "src and", "level": "critical"}], "enrich": "/nonexistent/plugin", "open": "sh"}`,
+			exports:        []string{"ftp://localhost/topic", "https://example.com/hook?batch=0"},
+			expectProblems: 4,
+			expectOutput: []string{
+				"  problem: networks[0] must be a network in CIDR notation",
+				"  problem: severity[0].filter: ",
+				"  problem: error(sink): unknown scheme \"ftp\"",
+				"  warning: enrich: command \"/nonexistent/plugin\" not found",
+				"problems: 4, warnings: 1",
+			},
+		},
+		{
+			name:           "invalid json",
+			content:        `{"open": `,
+			expectProblems: 1,
+			expectOutput:   []string{"  problem: error(config): could not parse"},
+		},
+		{
+			name:         "missing default",
+			expectOutput: []string{"  warning: file not found, defaults apply"},
+		},
+		{
+			name:           "missing required",
+			required:       true,
+			expectProblems: 1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(dir, strings.ReplaceAll(tc.name, " ", "_")+".json")
+			if tc.content != "" {
+				if err := os.WriteFile(path, []byte(tc.content), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var buf bytes.Buffer
+			if got := checkConfig(&buf, path, tc.required, tc.exports); got != tc.expectProblems {
+				t.Fatalf("expected %d problems, got %d:\n%s", tc.expectProblems, got, buf.String())
+			}
+			for _, expected := range tc.expectOutput {
+				if !strings.Contains(buf.String(), expected) {
+					t.Errorf("expected %q in output:\n%s", expected, buf.String())
+				}
+			}
+		})
+	}
+}
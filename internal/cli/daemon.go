@@ -0,0 +1,433 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package cli
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/config"
+	"gitlab.com/allddd/opnsense-filterlog/internal/daemon"
+	"gitlab.com/allddd/opnsense-filterlog/internal/debuglog"
+	"gitlab.com/allddd/opnsense-filterlog/internal/filter"
+	"gitlab.com/allddd/opnsense-filterlog/internal/meta"
+	"gitlab.com/allddd/opnsense-filterlog/internal/netclass"
+	"gitlab.com/allddd/opnsense-filterlog/internal/opnsense"
+	"gitlab.com/allddd/opnsense-filterlog/internal/sandbox"
+	"gitlab.com/allddd/opnsense-filterlog/internal/server"
+	"gitlab.com/allddd/opnsense-filterlog/internal/severity"
+	"gitlab.com/allddd/opnsense-filterlog/internal/sink"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+	"gitlab.com/allddd/opnsense-filterlog/internal/syslog"
+)
+
+const daemonUsageText = `follow OPNsense firewall log, export entries and write a report for each completed interval
+
+Usage:
+  %s daemon [-o dir] [-export url]... [-listen url] [flag]... [path]
+
+Export URLs:
+  http[s]://host[:port][/path][?filter=expression&batch=n&retries=n&secret=key&template=name&template_file=path]
+  mqtt[s]://[user:password@]host[:port]/topic[?filter=expression&retain=1&client_id=id]
+  {file:///path,tcp://host:port,udp://host:port}[?filter=expression&format=name&template=line&compress=gzip] (format: cef, csv, logfmt, ndjson (default), plain, template)
+  all export URLs accept spool=dir&spool_size=n to queue entries on disk while the sink is unreachable
+
+Listen URLs:
+  {udp,tcp,tls}://host:port[?queue=n&policy=block|drop|drop-oldest&rate=n] (tls requires -tls-cert)
+
+Arguments:
+  path	filter log file to follow, defaults to 'path' of the config file (latest.log) if omitted (not allowed with -api or -listen)
+
+Flags:
+`
+
+type daemonFlags struct {
+	API         bool     `name:"api" usage:"poll the OPNsense API (see api in config) instead of following a file"`
+	Config      string   `name:"c" usage:"path to config file (metrics credentials are read from auth)"`
+	Debug       string   `name:"debug" usage:"append internal events (index timings, file rotations, sink retries) to file"`
+	DryRun      bool     `name:"dry-run" usage:"print what would be sent to each -export url on stdout instead of sending it (not allowed with -o and -state)"`
+	Export      []string `name:"export" usage:"publish matching entries to url (can be repeated)"`
+	Filter      string   `name:"f" usage:"filter expression"`
+	Help        bool     `name:"h" usage:"display this help message and exit"`
+	Interval    string   `name:"i" value:"hourly" usage:"report interval (hourly, daily)"`
+	Listen      string   `name:"listen" usage:"receive entries via syslog on url instead of following a file"`
+	Metrics     string   `name:"metrics" usage:"serve prometheus metrics on address (e.g. 127.0.0.1:9100)"`
+	NoSandbox   bool     `name:"no-sandbox" usage:"don't restrict file access to the log, report, state and spool paths once everything is open"`
+	NoSymlinks  bool     `name:"no-symlinks" usage:"refuse to open the log file if it is a symlink (e.g. latest.log)"`
+	Output      string   `name:"o" usage:"directory to write reports to"`
+	Profile     string   `name:"profile" usage:"follow the log of the named firewall profile (see profiles in config, api and path profiles only)"`
+	Report      string   `name:"r" value:"html" usage:"comma separated report formats (html, json, markdown)"`
+	Since       string   `name:"since" usage:"replay entries of the last duration (e.g. 24h) from the rotated log files before following"`
+	State       string   `name:"state" usage:"file to persist the position in, to resume after a restart"`
+	TLSCert     string   `name:"tls-cert" usage:"path to TLS certificate (enables TLS for -metrics and tls:// listeners)"`
+	TLSClientCA string   `name:"tls-client-ca" usage:"path to CA to verify client certificates against (requires -tls-cert)"`
+	TLSKey      string   `name:"tls-key" usage:"path to TLS private key"`
+	TrustPath   bool     `name:"trust-path" usage:"allow log, report, state and spool paths in directories writable by everyone (e.g. /tmp)"`
+	User        string   `name:"user" usage:"user to switch to once everything is open if started as root (report, state and spool paths must be writable by it)"`
+}
+
+// reloadDaemon applies the severity rules and networks of the config file at path to d (defaults if it has been removed)
+func reloadDaemon(d *daemon.Daemon, path string) error {
+	cfg, err := reloadConfig(path)
+	if err != nil {
+		return err
+	}
+	c, err := severity.New(cfg.Severity, cfg.FilterOptions())
+	if err != nil {
+		return err
+	}
+	n, err := netclass.New(cfg.Networks)
+	if err != nil {
+		return err
+	}
+	d.Reload(c, n)
+	return nil
+}
+
+// watchDaemonConfig reloads the config file at path into d on SIGHUP and whenever it changes until ctx is done
+func watchDaemonConfig(ctx context.Context, d *daemon.Daemon, path string) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	w := config.NewWatcher(path)
+	ticker := time.NewTicker(config.WatchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			w.Changed()
+		case <-ticker.C:
+			if !w.Changed() {
+				continue
+			}
+		}
+		if err := reloadDaemon(d, path); err != nil {
+			log.Printf("daemon: config not reloaded: %v", err)
+			continue
+		}
+		log.Printf("daemon: reloaded config %s", path)
+	}
+}
+
+// executeDaemon runs the daemon command
+func executeDaemon(args []string) {
+	var f daemonFlags
+	fs := flag.NewFlagSet(cmdDaemon, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, daemonUsageText, meta.Name)
+		fs.PrintDefaults()
+	}
+	flagsDefine(fs, &f)
+	fs.Parse(args)
+	// -h
+	if f.Help {
+		fs.Usage()
+		os.Exit(0)
+	}
+	// -debug
+	if f.Debug != "" {
+		file, err := debuglog.Open(f.Debug)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer file.Close()
+	}
+	if f.Output == "" && len(f.Export) == 0 {
+		fmt.Fprintln(os.Stderr, "error(cli): -o or -export is required")
+		fs.Usage()
+		os.Exit(1)
+	}
+	// -dry-run
+	if f.DryRun && (f.Output != "" || f.State != "") {
+		fmt.Fprintln(os.Stderr, "error(cli): -dry-run is mutually exclusive with -o and -state")
+		fs.Usage()
+		os.Exit(1)
+	}
+	// -user
+	if f.User != "" && f.NoSandbox {
+		fmt.Fprintln(os.Stderr, "error(cli): -user is mutually exclusive with -no-sandbox")
+		fs.Usage()
+		os.Exit(1)
+	}
+	// -c
+	cfg, err := loadConfig(f.Config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	configPath := cmp.Or(f.Config, config.DefaultPath())
+	// -f
+	warnFilter(f.Filter, cfg.FilterOptions())
+	// -tls-cert, -tls-key, -tls-client-ca
+	tlsConfig, err := server.TLS{Cert: f.TLSCert, ClientCA: f.TLSClientCA, Key: f.TLSKey}.Config()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	// -profile
+	api, path := cfg.API, cfg.Path
+	if fs.NArg() > 0 {
+		path = fs.Arg(0)
+	}
+	if f.Profile != "" {
+		if f.API || f.Listen != "" || fs.NArg() > 0 {
+			fmt.Fprintln(os.Stderr, "error(cli): -profile is mutually exclusive with path, -api and -listen")
+			fs.Usage()
+			os.Exit(1)
+		}
+		p, err := cfg.GetProfile(f.Profile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		switch {
+		case p.API.URL != "":
+			f.API, api = true, p.API
+		case p.Path != "":
+			path = p.Path
+		default:
+			fmt.Fprintf(os.Stderr, "error(cli): profile %q can't be followed (host profiles can only be opened)\n", f.Profile)
+			os.Exit(1)
+		}
+	}
+	// -trust-path
+	if !f.TrustPath {
+		paths := []string{f.Output, f.State}
+		if !f.API && f.Listen == "" {
+			paths = append(paths, path)
+		}
+		for _, u := range f.Export {
+			paths = append(paths, sink.SpoolDir(u))
+		}
+		for _, p := range paths {
+			if p == "" {
+				continue
+			}
+			if err := sandbox.CheckPath(p); err != nil {
+				fmt.Fprintf(os.Stderr, "%v (use -trust-path to allow)\n", err)
+				os.Exit(1)
+			}
+		}
+	}
+	// -no-symlinks
+	stream.SetNoSymlinks(f.NoSymlinks)
+	// -api, -listen, args
+	var source daemon.Source
+	if f.API {
+		if f.Listen != "" || fs.NArg() > 0 || f.State != "" || f.Since != "" {
+			fmt.Fprintln(os.Stderr, "error(cli): -api is mutually exclusive with path, -listen, -since and -state")
+			fs.Usage()
+			os.Exit(1)
+		}
+		client, err := opnsense.NewClient(api)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		apiSource := opnsense.NewSource(client, time.Duration(api.Interval), api.Limit)
+		if f.Profile != "" {
+			apiSource.SetOrigin(f.Profile)
+		}
+		source = apiSource
+	} else if f.Listen != "" {
+		if fs.NArg() > 0 || f.State != "" || f.Since != "" {
+			fmt.Fprintln(os.Stderr, "error(cli): -listen is mutually exclusive with path, -since and -state")
+			fs.Usage()
+			os.Exit(1)
+		}
+		listener, err := syslog.Listen(f.Listen, tlsConfig)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer listener.Close()
+		source = listener
+	} else {
+		if f.State != "" && f.Since != "" {
+			fmt.Fprintln(os.Stderr, "error(cli): -since is mutually exclusive with -state")
+			fs.Usage()
+			os.Exit(1)
+		}
+		// -state
+		var st *daemon.State
+		if f.State != "" {
+			if st, err = daemon.LoadState(f.State); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		}
+		if fs.NArg() == 0 {
+			if path, err = config.ResolvePath(path); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		}
+		// -since
+		var since time.Duration
+		if f.Since != "" {
+			if since, err = time.ParseDuration(f.Since); err != nil || since <= 0 {
+				fmt.Fprintf(os.Stderr, "error(cli): invalid -since duration %q\n", f.Since)
+				os.Exit(1)
+			}
+		}
+		var follower *stream.Follower
+		switch {
+		case since > 0:
+			var backfill *stream.Backfill
+			if backfill, err = stream.NewBackfill(path, time.Now().Add(-since)); err == nil {
+				defer backfill.Close()
+				follower, source = backfill.Follower, backfill
+			}
+		case st != nil:
+			follower, err = stream.NewFollowerAt(path, st.Resume)
+		default:
+			follower, err = stream.NewFollower(path, false)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if source == nil {
+			defer follower.Close()
+			source = follower
+		}
+		if f.Profile != "" {
+			follower.SetOrigin(f.Profile)
+		}
+	}
+	// -export
+	var sinks []sink.Sink
+	defer func() {
+		for _, s := range sinks {
+			s.Close()
+		}
+	}()
+	newSink := sink.New
+	if f.DryRun {
+		newSink = func(rawURL string, opts filter.Options) (sink.Sink, error) {
+			return sink.NewDryRun(rawURL, opts, os.Stdout)
+		}
+	}
+	for _, u := range f.Export {
+		s, err := newSink(u, cfg.FilterOptions())
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		sinks = append(sinks, s)
+	}
+	c, err := severity.New(cfg.Severity, cfg.FilterOptions())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	n, err := netclass.New(cfg.Networks)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	d, err := daemon.New(source, daemon.Options{
+		Classifier: c,
+		Dir:        f.Output,
+		Filter:     f.Filter,
+		FilterOpts: cfg.FilterOptions(),
+		Formats:    strings.Split(f.Report, ","),
+		Interval:   f.Interval,
+		Networks:   n,
+		Sinks:      sinks,
+		StateFile:  f.State,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	// -no-sandbox
+	if !f.NoSandbox {
+		// listeners (-listen, -metrics) only accept connections, which is possible in capability mode
+		p := sandbox.Policy{Network: f.API || (len(f.Export) > 0 && !f.DryRun), User: f.User}
+		if f.User != "" && os.Geteuid() != 0 {
+			log.Printf("daemon: not started as root, ignoring -user %s", f.User)
+		}
+		if !f.API && f.Listen == "" {
+			p.Read = followPaths(path)
+		}
+		if configPath != "" {
+			// the config file is reloaded when it changes
+			p.Read = append(p.Read, filepath.Dir(configPath))
+		}
+		if f.Output != "" {
+			p.Write = append(p.Write, f.Output)
+		}
+		if f.State != "" {
+			p.Write = append(p.Write, filepath.Dir(f.State))
+		}
+		for _, u := range f.Export {
+			if dir := sink.SpoolDir(u); dir != "" && !f.DryRun {
+				p.Write = append(p.Write, dir)
+			}
+		}
+		if err := sandbox.Enter(p); err != nil {
+			if !errors.Is(err, sandbox.ErrUnsupported) {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			log.Printf("daemon: running without sandbox: %v", err)
+		}
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if configPath != "" {
+		go watchDaemonConfig(ctx, d, configPath)
+	}
+	// -metrics
+	if f.Metrics != "" {
+		mux := http.NewServeMux()
+		mux.Handle("GET /healthz", d.Health())
+		mux.Handle("GET /metrics", d.Metrics())
+		go func() {
+			if err := server.Serve(ctx, f.Metrics, mux, tlsConfig, cfg.Auth); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		}()
+	}
+	if err := d.Run(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package cli
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/config"
+	"gitlab.com/allddd/opnsense-filterlog/internal/filter"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stats"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+const (
+	detectBruteforce = "bruteforce"
+	detectNAT        = "nat"
+)
+
+// displayDetect runs the given analysis over all entries matching the filter and writes a report to stdout
+func displayDetect(s *stream.Stream, detect string, filterValue string, cfg *config.Config) error {
+	if detect != detectBruteforce && detect != detectNAT {
+		return fmt.Errorf("error(detect): unknown analysis %q (available: %s, %s)", detect, detectBruteforce, detectNAT)
+	}
+	compiled, err := filter.CompileWith(filterValue, cfg.FilterOptions())
+	if err != nil {
+		return err
+	}
+	b := stats.NewBruteforce(cfg.Bruteforce.Threshold, time.Duration(cfg.Bruteforce.Window))
+	n := stats.NewNAT()
+	add, write := b.Add, func() error { return stats.WriteOffenders(os.Stdout, b.Offenders()) }
+	if detect == detectNAT {
+		add, write = n.Add, func() error { return stats.WriteNAT(os.Stdout, n.Summaries()) }
+	}
+	for entry := s.Next(); entry != nil; entry = s.Next() {
+		if compiled != nil && !compiled.Matches(entry) {
+			continue
+		}
+		add(entry)
+	}
+	if err := write(); err != nil {
+		return fmt.Errorf("error(detect): could not write report: %w", err)
+	}
+	if errors := s.GetErrors(); len(errors) > 0 {
+		for _, err := range errors {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		return fmt.Errorf("error(detect): could not process all entries: %d parse errors", len(errors))
+	}
+	return nil
+}
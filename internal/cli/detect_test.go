@@ -0,0 +1,124 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package cli
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/config"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+func TestDetectNAT(t *testing.T) {
+	s, err := stream.NewStream("../../tests/filter_nat.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	stdout, _, err := captureOutput(func() error {
+		return displayDetect(s, "nat", "", config.New())
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(stdout)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d: %q", len(lines), lines)
+	}
+	for i, expect := range [][]string{
+		{"rdr", "a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6", "203.0.113.1:8443", "-", "unmatched", "1"},
+		{"rdr", "a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6", "203.0.113.1:8443", "192.168.1.10:443", "translated", "1"},
+	} {
+		if fields := strings.Fields(lines[i+1]); !slices.Equal(fields[:len(expect)], expect) {
+			t.Fatalf("expected row %d to start with %q, got %q", i, expect, lines[i+1])
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name          string
+		detect        string
+		filter        string
+		expectSources []string
+		expectError   bool
+	}{
+		{
+			name:          "bruteforce",
+			detect:        "bruteforce",
+			expectSources: []string{"203.0.113.10"},
+		},
+		{
+			name:          "bruteforce with filter",
+			detect:        "bruteforce",
+			filter:        "dport 3389",
+			expectSources: []string{},
+		},
+		{
+			name:        "unknown analysis",
+			detect:      "portscan",
+			expectError: true,
+		},
+		{
+			name:        "invalid filter",
+			detect:      "bruteforce",
+			filter:      "src and",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := stream.NewStream("../../tests/filter_bruteforce.log")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+			stdout, _, err := captureOutput(func() error {
+				return displayDetect(s, tc.detect, tc.filter, config.New())
+			})
+			if tc.expectError {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			// skip header
+			lines := strings.Split(strings.TrimSpace(string(stdout)), "\n")[1:]
+			if len(lines) != len(tc.expectSources) {
+				t.Fatalf("expected %d offenders, got %d", len(tc.expectSources), len(lines))
+			}
+			for i, src := range tc.expectSources {
+				if !strings.HasPrefix(lines[i], src+" ") {
+					t.Fatalf("expected offender %d to be %s, got %q", i, src, lines[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package cli
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/meta"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+	"gitlab.com/allddd/opnsense-filterlog/internal/tui"
+)
+
+const fetchUsageText = `download current and rotated filter logs from OPNsense firewalls over SFTP
+
+The logs are downloaded into the cache directory using the system sftp client in batch
+mode (keys, agent and ~/.ssh/config are used, password prompts are not supported).
+If a single host is given, the downloaded logs are opened afterwards.
+
+Usage:
+  %s fetch [flag]... host...
+
+Arguments:
+  host	firewall to download logs from ([user@]host, ssh config alias or sftp://[user@]host[:port])
+
+Flags:
+`
+
+type fetchFlags struct {
+	Config string `name:"c" usage:"path to config file"`
+	Help   bool   `name:"h" usage:"display this help message and exit"`
+	NoOpen bool   `name:"n" usage:"only download logs and print their paths, do not open them"`
+	Remote string `name:"r" value:"/var/log/filter" usage:"directory of the filter logs on the firewall"`
+}
+
+// sftpCommand is the sftp client used to download logs
+var sftpCommand = "sftp"
+
+// sftpEscape escapes spaces, quotes and backslashes of an argument of an sftp batch command with backslashes, and
+// glob characters if the argument is expanded by sftp (quoted arguments can't be used, sftp doesn't expand globs
+// within quotes)
+func sftpEscape(arg string, globbed bool) string {
+	special := " \t\\'\""
+	if globbed {
+		special += "*?[]"
+	}
+	var b strings.Builder
+	for _, r := range arg {
+		if strings.ContainsRune(special, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// fetchName returns the name of the host without user and port (used for cache paths)
+func fetchName(host string) string {
+	if u, err := url.Parse(host); err == nil && u.Scheme == "sftp" {
+		return u.Hostname()
+	}
+	if i := strings.LastIndex(host, "@"); i >= 0 {
+		host = host[i+1:]
+	}
+	return host
+}
+
+// fetchHost downloads the filter logs of host into dir and returns the path of the combined log
+//
+// Logs are downloaded into a temporary directory first so that a failed download keeps the previous logs,
+// the combined log contains all downloaded logs in chronological order.
+func fetchHost(host, remote, dir string) (string, error) {
+	name := fetchName(host)
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("error(cli): invalid host %q", host)
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("error(cli): could not create cache directory: %w", err)
+	}
+	tmp, err := os.MkdirTemp(dir, name+"-")
+	if err != nil {
+		return "", fmt.Errorf("error(cli): could not create cache directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+	var output bytes.Buffer
+	cmd := exec.Command(sftpCommand, "-b", "-", "-q", host)
+	// only the glob of the file names is expanded
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("get -p %s/filter_*.log %s\n", sftpEscape(remote, true), sftpEscape(tmp+string(filepath.Separator), false)))
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("error(cli): could not download logs from %s: %w: %s", host, err, strings.TrimSpace(output.String()))
+	}
+	paths, err := filepath.Glob(filepath.Join(tmp, "filter_*.log"))
+	if err != nil || len(paths) == 0 {
+		return "", fmt.Errorf("error(cli): no logs found in %s on %s", remote, host)
+	}
+	// file names contain the date, sorting by name sorts chronologically
+	sort.Strings(paths)
+	path := filepath.Join(dir, name+".log")
+	combined, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return "", fmt.Errorf("error(cli): could not create %s: %w", path, err)
+	}
+	for _, p := range paths {
+		if err := appendFile(combined, p); err != nil {
+			combined.Close()
+			return "", err
+		}
+	}
+	if err := combined.Close(); err != nil {
+		return "", fmt.Errorf("error(cli): could not write %s: %w", path, err)
+	}
+	hostDir := filepath.Join(dir, name)
+	if err := os.RemoveAll(hostDir); err != nil {
+		return "", fmt.Errorf("error(cli): could not replace %s: %w", hostDir, err)
+	}
+	if err := os.Rename(tmp, hostDir); err != nil {
+		return "", fmt.Errorf("error(cli): could not replace %s: %w", hostDir, err)
+	}
+	return path, nil
+}
+
+// appendFile copies the file at path to w
+func appendFile(w io.Writer, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error(cli): %w", err)
+	}
+	defer file.Close()
+	if _, err := io.Copy(w, file); err != nil {
+		return fmt.Errorf("error(cli): could not copy %s: %w", path, err)
+	}
+	return nil
+}
+
+// executeFetch runs the fetch command
+func executeFetch(args []string) {
+	var f fetchFlags
+	fs := flag.NewFlagSet(cmdFetch, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, fetchUsageText, meta.Name)
+		fs.PrintDefaults()
+	}
+	flagsDefine(fs, &f)
+	fs.Parse(args)
+	// -h
+	if f.Help {
+		fs.Usage()
+		os.Exit(0)
+	}
+	// args
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "error(cli): at least one host is required")
+		os.Exit(1)
+	}
+	// -c
+	cfg, err := loadConfig(f.Config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	dir, err := cacheDir()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	dir = filepath.Join(dir, cmdFetch)
+	// -r
+	remote := strings.TrimSuffix(f.Remote, "/")
+	var paths []string
+	failed := false
+	for _, host := range fs.Args() {
+		path, err := fetchHost(host, remote, dir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = true
+			continue
+		}
+		paths = append(paths, path)
+	}
+	// -n
+	if f.NoOpen || fs.NArg() > 1 || failed {
+		for _, path := range paths {
+			fmt.Println(path)
+		}
+		if failed {
+			os.Exit(1)
+		}
+		return
+	}
+	c, err := newClassifier(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	s, err := stream.NewStream(paths[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	classify(s, c)
+	bookmarks, err := loadBookmarks("")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := tui.Display(s, cfg, tui.Options{Bookmarks: bookmarks, Open: profileOpener(cfg, c)}); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package cli
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// fakeSFTP installs a fake sftp client that copies files from the local filesystem instead of the host
+func fakeSFTP(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake sftp client requires a shell")
+	}
+	script := filepath.Join(t.TempDir(), "sftp")
+	content := "#!/bin/sh\n" +
+		"for host; do :; done\n" +
+		"[ \"$host\" = unreachable ] && { echo 'Connection refused' >&2; exit 255; }\n" +
+		"read -r cmd flag remote local\n" +
+		"eval \"cp $remote $local\"\n"
+	if err := os.WriteFile(script, []byte(content), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	orig := sftpCommand
+	sftpCommand = script
+	t.Cleanup(func() { sftpCommand = orig })
+}
+
+func TestFetchHost(t *testing.T) {
+	fakeSFTP(t)
+	remote := t.TempDir()
+	for name, content := range map[string]string{
+		"filter_20251010.log": "first\n",
+		"filter_20251011.log": "second\n",
+		"latest.log":          "ignored\n",
+	} {
+		if err := os.WriteFile(filepath.Join(remote, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dir := t.TempDir()
+
+	path, err := fetchHost("root@fw1", remote, dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if path != filepath.Join(dir, "fw1.log") {
+		t.Fatalf("expected combined log in cache directory, got %s", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "first\nsecond\n" {
+		t.Fatalf("expected logs in chronological order, got %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "fw1", "filter_20251011.log")); err != nil {
+		t.Fatalf("expected downloaded logs to be kept: %v", err)
+	}
+
+	// failed downloads keep the previous logs
+	if _, err := fetchHost("unreachable", remote, dir); err == nil {
+		t.Fatal("expected error for unreachable host, got nil")
+	}
+	if _, err := fetchHost("fw1", filepath.Join(remote, "missing"), dir); err == nil {
+		t.Fatal("expected error without logs, got nil")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "fw1", "filter_20251011.log")); err != nil {
+		t.Fatalf("expected previous logs to be kept: %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected temporary directories to be removed, got %d entries", len(entries))
+	}
+}
+
+func TestFetchHostSFTP(t *testing.T) {
+	client, err := exec.LookPath("sftp")
+	if err != nil || runtime.GOOS == "windows" {
+		t.Skip("sftp client not available")
+	}
+	server := ""
+	for _, path := range []string{"/usr/lib/openssh/sftp-server", "/usr/libexec/openssh/sftp-server", "/usr/libexec/sftp-server", "/usr/lib/ssh/sftp-server"} {
+		if _, err := os.Stat(path); err == nil {
+			server = path
+			break
+		}
+	}
+	if server == "" {
+		t.Skip("sftp-server not available")
+	}
+	// the real client parses the batch commands, the server is started directly instead of connecting to the host
+	script := filepath.Join(t.TempDir(), "sftp")
+	content := "#!/bin/sh\n" +
+		"n=$#; i=0; for arg; do i=$((i+1)); [ $i -lt $n ] && set -- \"$@\" \"$arg\"; done; shift $n\n" +
+		"exec " + client + " -D " + server + " \"$@\"\n"
+	if err := os.WriteFile(script, []byte(content), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	orig := sftpCommand
+	sftpCommand = script
+	t.Cleanup(func() { sftpCommand = orig })
+
+	// spaces must be escaped, the glob must not be
+	remote := filepath.Join(t.TempDir(), "filter logs")
+	if err := os.Mkdir(remote, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"filter_20251010.log": "first\n", "filter_20251011.log": "second\n"} {
+		if err := os.WriteFile(filepath.Join(remote, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path, err := fetchHost("fw1", remote, filepath.Join(t.TempDir(), "fetch cache"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "first\nsecond\n" {
+		t.Fatalf("expected logs in chronological order, got %q", data)
+	}
+}
+
+func TestSFTPEscape(t *testing.T) {
+	if got := sftpEscape(`/var/log/my "logs"/*`, true); got != `/var/log/my\ \"logs\"/\*` {
+		t.Errorf("unexpected escaped glob %q", got)
+	}
+	if got := sftpEscape(`/tmp/cache dir/[x]`, false); got != `/tmp/cache\ dir/[x]` {
+		t.Errorf("unexpected escaped path %q", got)
+	}
+}
+
+func TestFetchName(t *testing.T) {
+	tests := []struct {
+		host     string
+		expected string
+	}{
+		{host: "fw1", expected: "fw1"},
+		{host: "root@192.168.1.1", expected: "192.168.1.1"},
+		{host: "sftp://root@fw1.example.com:2222", expected: "fw1.example.com"},
+		{host: "sftp://[2001:db8::1]", expected: "2001:db8::1"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.host, func(t *testing.T) {
+			if name := fetchName(tc.host); name != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, name)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+// writeFieldLayouts writes one aligned table of csv positions per layout
+func writeFieldLayouts(w io.Writer, layouts []stream.FieldLayout) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for i, layout := range layouts {
+		if i > 0 {
+			fmt.Fprintln(tw)
+		}
+		fmt.Fprintf(tw, "%s: %d lines (%s format)\n", layout.Layout, layout.Lines, layout.Format)
+		fmt.Fprintln(tw, "Pos\tSeen\tParsed\tSamples")
+		for _, p := range layout.Positions {
+			parsed := "no"
+			if p.Consumed {
+				parsed = "yes"
+			}
+			fmt.Fprintf(tw, "%d\t%d\t%s\t%s\n", p.Position, p.Seen, parsed, strings.Join(p.Samples, " "))
+		}
+	}
+	return tw.Flush()
+}
+
+// displayDumpFields writes every csv position seen in the file with sample values and whether it is parsed to stdout
+func displayDumpFields(s *stream.Stream) error {
+	layouts, err := s.AuditFields()
+	if err != nil {
+		return err
+	}
+	if err := writeFieldLayouts(os.Stdout, layouts); err != nil {
+		return fmt.Errorf("error(cli): could not write fields: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+func TestDumpFields(t *testing.T) {
+	s, err := stream.NewStream("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	stdout, _, err := captureOutput(func() error {
+		return displayDumpFields(s)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(string(stdout), "\n")
+	if lines[0] != "ipv4/tcp: 12 lines (current format)" {
+		t.Fatalf("expected ipv4/tcp layout first, got %q", lines[0])
+	}
+	if fields := strings.Fields(lines[2]); len(fields) < 3 || fields[0] != "0" || fields[1] != "12" || fields[2] != "yes" {
+		t.Fatalf("unexpected row %q", lines[2])
+	}
+	if fields := strings.Fields(lines[3]); len(fields) != 3 || fields[2] != "yes" {
+		t.Fatalf("unexpected row %q", lines[3])
+	}
+	if fields := strings.Fields(lines[17]); len(fields) != 4 || fields[0] != "15" || fields[2] != "yes" || fields[3] != "6" {
+		t.Fatalf("unexpected row %q", lines[17])
+	}
+}
@@ -0,0 +1,230 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/filter"
+	"gitlab.com/allddd/opnsense-filterlog/internal/meta"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+const filterUsageText = `test a filter expression against sample OPNsense firewall log lines
+
+Usage:
+  %s filter test -f expression [flag]... [path]
+
+Shows for each line whether it matched and the result of every clause of the expression.
+Exits with status 1 if no line matched.
+
+Arguments:
+  path	file of sample log lines, read from stdin if omitted or -
+
+Flags:
+`
+
+type filterFlags struct {
+	Filter string `name:"f" usage:"filter expression to test"`
+	Help   bool   `name:"h" usage:"display this help message and exit"`
+	Json   bool   `name:"j" usage:"display the result of each line as JSON"`
+}
+
+// filterResult is the result of a filter for a sample line (json)
+type filterResult struct {
+	Line    int            `json:"line"`            // line number
+	Matched bool           `json:"matched"`         // whether the filter matched
+	Error   string         `json:"error,omitempty"` // parse error of the line
+	Trace   *filter.Clause `json:"trace,omitempty"` // result of every clause
+}
+
+// compileFilter compiles the filter expression with opts (instrumented to count the entries per clause in stats if it
+// is not nil)
+func compileFilter(expression string, opts filter.Options, stats *filter.Stats) (filter.FilterNode, error) {
+	compiled, err := filter.CompileWith(expression, opts)
+	if err != nil {
+		return nil, err
+	}
+	return stats.Instrument(compiled), nil
+}
+
+// warnFilter writes the warnings of the filter expression compiled with opts to stderr (errors are reported where it
+// is compiled)
+func warnFilter(expression string, opts filter.Options) {
+	compiled, err := filter.CompileWith(expression, opts)
+	if err != nil {
+		return
+	}
+	for _, warning := range filter.Warnings(compiled) {
+		fmt.Fprintf(os.Stderr, "warning(filter): %s\n", warning)
+	}
+}
+
+// writeClauseStats writes the number of entries each clause was evaluated for and matched as table
+func writeClauseStats(w io.Writer, s *filter.Stats) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Clause\tEvaluated\tMatched")
+	for _, c := range s.Clauses() {
+		fmt.Fprintf(tw, "%s%s\t%d\t%d\n", strings.Repeat("  ", c.Depth), c.Expression, c.Evaluated, c.Matched)
+	}
+	return tw.Flush()
+}
+
+// writeClause writes the clause and its operands indented by depth
+func writeClause(w io.Writer, c filter.Clause, depth int) {
+	result := "no match"
+	if c.Matched {
+		result = "match"
+	}
+	fmt.Fprintf(w, "%s%s: %s", strings.Repeat("  ", depth), c.Expression, result)
+	if c.Reason != "" {
+		fmt.Fprintf(w, " (%s)", c.Reason)
+	}
+	fmt.Fprintln(w)
+	for _, operand := range c.Clauses {
+		writeClause(w, operand, depth+1)
+	}
+}
+
+// testFilter evaluates the expression for every non-empty line of r and writes the results to w, returns the number
+// of matching lines
+func testFilter(r io.Reader, w io.Writer, expression string, opts filter.Options, c *classifier, asJSON bool) (int, error) {
+	compiled, err := filter.CompileWith(expression, opts)
+	if err != nil {
+		return 0, err
+	}
+	enc := json.NewEncoder(w)
+	matched := 0
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		result := filterResult{Line: lineNum}
+		if entry, err := stream.ParseLine(line); err != nil {
+			result.Error = err.Error()
+		} else {
+			c.Classify(entry)
+			trace := filter.Trace(compiled, entry)
+			result.Matched, result.Trace = trace.Matched, &trace
+		}
+		if result.Matched {
+			matched++
+		}
+		if asJSON {
+			if err := enc.Encode(result); err != nil {
+				return matched, fmt.Errorf("error(filter): could not encode result: %w", err)
+			}
+			continue
+		}
+		switch {
+		case result.Error != "":
+			fmt.Fprintf(w, "line %d: %s\n", lineNum, result.Error)
+		case result.Matched:
+			fmt.Fprintf(w, "line %d: match\n", lineNum)
+		default:
+			fmt.Fprintf(w, "line %d: no match\n", lineNum)
+		}
+		if result.Trace != nil && compiled != nil {
+			writeClause(w, *result.Trace, 1)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return matched, fmt.Errorf("error(filter): could not read lines: %w", err)
+	}
+	return matched, nil
+}
+
+// executeFilter runs the filter command
+func executeFilter(args []string) {
+	var f filterFlags
+	fs := flag.NewFlagSet(cmdFilter, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, filterUsageText, meta.Name)
+		fs.PrintDefaults()
+	}
+	flagsDefine(fs, &f)
+	if len(args) > 0 && args[0] == "test" {
+		args = args[1:]
+	} else if len(args) > 0 && args[0] != "-h" {
+		fmt.Fprintf(os.Stderr, "error(cli): unknown filter command %q\n", args[0])
+		fs.Usage()
+		os.Exit(1)
+	}
+	fs.Parse(args)
+	// -h
+	if f.Help {
+		fs.Usage()
+		os.Exit(0)
+	}
+	if f.Filter == "" {
+		fmt.Fprintln(os.Stderr, "error(cli): -f is required")
+		fs.Usage()
+		os.Exit(1)
+	}
+	cfg, err := loadConfig("")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	warnFilter(f.Filter, cfg.FilterOptions())
+	c, err := newClassifier(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	// args
+	in := io.Reader(os.Stdin)
+	if path := fs.Arg(0); path != "" && path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error(cli): %v\n", err)
+			os.Exit(1)
+		}
+		defer file.Close()
+		in = file
+	}
+	matched, err := testFilter(in, os.Stdout, f.Filter, cfg.FilterOptions(), c, f.Json)
+	if err != nil {
+		// show where the expression is invalid
+		if ferr := (*filter.Error)(nil); errors.As(err, &ferr) {
+			fmt.Fprintf(os.Stderr, "%s\n%s^\n", f.Filter, strings.Repeat(" ", utf8.RuneCountInString(f.Filter[:min(ferr.Pos, len(f.Filter))])))
+		}
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if matched == 0 {
+		os.Exit(1)
+	}
+}
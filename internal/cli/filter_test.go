@@ -0,0 +1,166 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/filter"
+	"gitlab.com/allddd/opnsense-filterlog/internal/output"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+func TestTestFilter(t *testing.T) {
+	data, err := os.ReadFile("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	input := lines[0] + "\n" + "not a log line\n" + lines[1]
+
+	tests := []struct {
+		name          string
+		filter        string
+		expectMatched int
+		expectOutput  []string
+		expectError   bool
+	}{
+		{
+			name:          "trace",
+			filter:        "ipversion 4 and (port 53 or not iface eth1)",
+			expectMatched: 1,
+			expectOutput: []string{
+				"line 1: no match",
+				"    ipversion 4: no match (ipversion is 6)",
+				"line 3: error(stream): invalid timestamp",
+				"line 4: match",
+				"      port 53: match (srcport is 12162, dstport is 53)",
+				"        iface eth1: match (interface is \"eth1\")",
+			},
+		},
+		{
+			name:          "value",
+			filter:        "192.168.1.1",
+			expectMatched: 1,
+			expectOutput:  []string{"  192.168.1.1: match (found in \"192.168.1.1\")\n"},
+		},
+		{
+			name:        "invalid filter",
+			filter:      "src and",
+			expectError: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			matched, err := testFilter(strings.NewReader(input), &out, tc.filter, filter.Options{}, nil, false)
+			if tc.expectError {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if matched != tc.expectMatched {
+				t.Errorf("expected %d matching lines, got %d", tc.expectMatched, matched)
+			}
+			for _, line := range tc.expectOutput {
+				if !strings.Contains(out.String(), line) {
+					t.Errorf("expected output to contain %q, got:\n%s", line, out.String())
+				}
+			}
+		})
+	}
+
+	t.Run("json", func(t *testing.T) {
+		var out bytes.Buffer
+		if _, err := testFilter(strings.NewReader(input), &out, "action pass", filter.Options{}, nil, true); err != nil {
+			t.Fatal(err)
+		}
+		var results []filterResult
+		for line := range strings.SplitSeq(strings.TrimSpace(out.String()), "\n") {
+			var result filterResult
+			if err := json.Unmarshal([]byte(line), &result); err != nil {
+				t.Fatalf("could not parse json: %v", err)
+			}
+			results = append(results, result)
+		}
+		if len(results) != 3 || !results[0].Matched || results[1].Error == "" || results[2].Trace == nil {
+			t.Fatalf("unexpected results: %+v", results)
+		}
+	})
+}
+
+func TestClauseStats(t *testing.T) {
+	s, err := stream.NewStream("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	clauseStats := &filter.Stats{}
+	opts := writeOptions{clauseStats: clauseStats, filter: "action block or proto udp", format: output.FormatJSON}
+	if err := displayEntries(io.Discard, io.Discard, s, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var out bytes.Buffer
+	if err := writeClauseStats(&out, clauseStats); err != nil {
+		t.Fatal(err)
+	}
+	expected := "Clause          Evaluated  Matched\n" +
+		"or              20         9\n" +
+		"  action block  20         1\n" +
+		"  proto udp     19         8\n"
+	if out.String() != expected {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
+
+func TestWarnFilter(t *testing.T) {
+	tests := []struct {
+		filter string
+		expect string
+	}{
+		{"sr 10.0.0.1", `warning(filter): "sr" is not a field and is searched in all fields (did you mean "src"? quote it to search for the value)` + "\n"},
+		{"src 10.0.0.1", ""},
+		{"src and", ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.filter, func(t *testing.T) {
+			_, stderr, _ := captureOutput(func() error {
+				warnFilter(tc.filter, filter.Options{})
+				return nil
+			})
+			if string(stderr) != tc.expect {
+				t.Errorf("expected %q, got %q", tc.expect, stderr)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/daemon"
+	"gitlab.com/allddd/opnsense-filterlog/internal/filter"
+	"gitlab.com/allddd/opnsense-filterlog/internal/output"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+const (
+	// followInterval is how often the followed file is checked for new entries
+	followInterval = 250 * time.Millisecond
+
+	// speedDefault is the default replay speed (the pace the entries were logged at)
+	speedDefault = "1x"
+)
+
+// liveSource sends entries in batches as they are appended to a file (stream.Follower) or as they were logged
+// (stream.Replayer)
+type liveSource interface {
+	SetErrorHandler(handler func(err error))
+	Watch(ctx context.Context, interval time.Duration) <-chan []stream.LogEntry
+}
+
+// acknowledger is implemented by sources that are told when the entries of a batch have been written (e.g. to save
+// the position after them)
+type acknowledger interface {
+	Written() error
+}
+
+// resumable follows a file and saves the position after each batch to a state file once its entries have been written
+type resumable struct {
+	*stream.Follower
+	mu      sync.Mutex          // protects pending
+	pending []stream.Checkpoint // positions after the batches sent but not yet written (oldest first)
+	state   string              // state file
+}
+
+// Watch sends the batches of the follower (see stream.Follower.Watch)
+func (r *resumable) Watch(ctx context.Context, interval time.Duration) <-chan []stream.LogEntry {
+	ch := make(chan []stream.LogEntry)
+	go func() {
+		defer close(ch)
+		for batch := range r.WatchBatches(ctx, interval) {
+			r.mu.Lock()
+			r.pending = append(r.pending, batch.Checkpoint)
+			r.mu.Unlock()
+			select {
+			case ch <- batch.Entries:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// Written saves the position after the oldest batch not yet written
+func (r *resumable) Written() error {
+	r.mu.Lock()
+	if len(r.pending) == 0 {
+		r.mu.Unlock()
+		return nil
+	}
+	cp := r.pending[0]
+	r.pending = r.pending[1:]
+	r.mu.Unlock()
+	return daemon.WriteState(r.state, daemon.State{Resume: cp, Sent: cp})
+}
+
+// newResumable follows path at the position saved in state (at the end of the file if there is none)
+func newResumable(path, state string) (*resumable, error) {
+	st, err := daemon.LoadState(state)
+	if err != nil {
+		return nil, err
+	}
+	var f *stream.Follower
+	if st != nil {
+		f, err = stream.NewFollowerAt(path, st.Resume)
+	} else {
+		f, err = stream.NewFollower(path, false)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &resumable{Follower: f, state: state}, nil
+}
+
+// parseSpeed returns the factor of a replay speed (e.g. 10x or 0.5)
+func parseSpeed(s string) (float64, error) {
+	speed, err := strconv.ParseFloat(strings.TrimSuffix(s, "x"), 64)
+	if err != nil || speed <= 0 || math.IsInf(speed, 0) {
+		return 0, fmt.Errorf("error(cli): invalid speed %q (e.g. 10x or 0.5x)", s)
+	}
+	return speed, nil
+}
+
+// writeFollow writes every entry sent by f matching opts.filter to sink until ctx is done or f is done (the sink is
+// flushed whenever all entries of a batch have been written, entries are enriched if opts.enricher is not nil)
+func writeFollow(ctx context.Context, sink output.Sink, f liveSource, opts writeOptions) error {
+	e := opts.enricher
+	compiled, err := filter.CompileWith(opts.filter, opts.filterOpts)
+	if err != nil {
+		return err
+	}
+	// write the header (if any) right away
+	if err := sink.Flush(); err != nil {
+		return fmt.Errorf("error(cli): could not write header: %w", err)
+	}
+	// stops watching if writing fails
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	for batch := range f.Watch(ctx, followInterval) {
+		for i := range batch {
+			entry := &batch[i]
+			if compiled != nil && !compiled.Matches(entry) {
+				continue
+			}
+			if e != nil {
+				if err := e.Enrich(entry); err != nil {
+					return err
+				}
+			}
+			if err := sink.Write(entry); err != nil {
+				return fmt.Errorf("error(cli): could not write entry: %w", err)
+			}
+		}
+		if err := sink.Flush(); err != nil {
+			return fmt.Errorf("error(cli): could not write entry: %w", err)
+		}
+		if a, ok := f.(acknowledger); ok {
+			if err := a.Written(); err != nil {
+				return err
+			}
+		}
+	}
+	return sink.Close()
+}
+
+// displayFollow writes every entry sent by f (appended to the followed file or replayed) matching opts.filter in
+// opts.format to w until interrupted (one JSON object per line for json, parse errors are written to errW as they
+// occur, entries are enriched if opts.enricher is not nil)
+func displayFollow(w, errW io.Writer, f liveSource, opts writeOptions) error {
+	// the json document would only be complete once interrupted
+	format := opts.format
+	if format == output.FormatJSON {
+		format = output.FormatNDJSON
+	}
+	sink, err := output.New(format, w, output.Options{Template: opts.template})
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	f.SetErrorHandler(func(err error) {
+		fmt.Fprintln(errW, err)
+	})
+	return writeFollow(ctx, sink, f, opts)
+}
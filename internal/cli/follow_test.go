@@ -0,0 +1,276 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/daemon"
+	"gitlab.com/allddd/opnsense-filterlog/internal/output"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+// syncBuffer is a bytes.Buffer that can be written and read concurrently
+type syncBuffer struct {
+	b  bytes.Buffer
+	mu sync.Mutex
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.String()
+}
+
+func TestWriteFollow(t *testing.T) {
+	data, err := os.ReadFile("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(strings.TrimSuffix(string(data), "\n"), "\n")
+	path := filepath.Join(t.TempDir(), "filter.log")
+	if err := os.WriteFile(path, []byte(lines[0]), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// entries written after following started that match the filter
+	expect := 0
+	for _, line := range lines[1:] {
+		entry, err := stream.ParseLine(strings.TrimSuffix(line, "\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry.DstPort == 53 {
+			expect++
+		}
+	}
+
+	for _, format := range []string{output.FormatLogfmt, output.FormatNDJSON, output.FormatPlain} {
+		t.Run(format, func(t *testing.T) {
+			want := expect
+			if format == output.FormatPlain {
+				// header
+				want++
+			}
+			f, err := stream.NewFollower(path, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			ctx, cancel := context.WithCancel(context.Background())
+			var out syncBuffer
+			sink, err := output.New(format, &out, output.Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			done := make(chan error)
+			go func() {
+				done <- writeFollow(ctx, sink, f, writeOptions{filter: "dport 53"})
+			}()
+			file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, line := range lines[1:] {
+				file.WriteString(strings.TrimSuffix(line, "\n") + "\n")
+			}
+			file.Close()
+			deadline := time.Now().Add(5 * time.Second)
+			for strings.Count(out.String(), "\n") < want && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+			}
+			cancel()
+			if err := <-done; err != nil {
+				t.Fatal(err)
+			}
+			got := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+			if len(got) != want {
+				t.Fatalf("expected %d lines, got %d: %q", want, len(got), got)
+			}
+			if format == output.FormatPlain {
+				if !strings.HasPrefix(got[0], "Time") {
+					t.Errorf("expected header, got %q", got[0])
+				}
+				got = got[1:]
+			}
+			for _, line := range got {
+				if format == output.FormatPlain {
+					if fields := strings.Fields(line); len(fields) < 10 || !slices.Contains(fields, "53") {
+						t.Errorf("expected row with dport 53, got %q", line)
+					}
+					continue
+				}
+				if format == output.FormatLogfmt {
+					if !strings.Contains(line, " dport=53") {
+						t.Errorf("expected entry with dport 53, got %q", line)
+					}
+					continue
+				}
+				var entry stream.LogEntry
+				if err := json.Unmarshal([]byte(line), &entry); err != nil || entry.DstPort != 53 {
+					t.Errorf("expected JSON entry with dport 53, got %q (%v)", line, err)
+				}
+			}
+		})
+		// follow the lines appended by the next run only
+		os.WriteFile(path, []byte(lines[0]), 0o644)
+	}
+}
+
+func TestWriteFollowState(t *testing.T) {
+	data, err := os.ReadFile("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(strings.TrimSuffix(string(data), "\n"), "\n")
+	dir := t.TempDir()
+	path := filepath.Join(dir, "filter.log")
+	state := filepath.Join(dir, "state.json")
+	if err := os.WriteFile(path, []byte(lines[0]), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// follow writes the lines appended while it runs and returns once they have been written
+	follow := func(appended []string) []string {
+		t.Helper()
+		r, err := newResumable(path, state)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		ctx, cancel := context.WithCancel(context.Background())
+		var out syncBuffer
+		sink, err := output.New(output.FormatNDJSON, &out, output.Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		done := make(chan error)
+		go func() {
+			done <- writeFollow(ctx, sink, r, writeOptions{})
+		}()
+		file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file.WriteString(strings.Join(appended, ""))
+		file.Close()
+		deadline := time.Now().Add(5 * time.Second)
+		for strings.Count(out.String(), "\n") < len(appended) && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		// the position is saved once the entries have been written
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for time.Now().Before(deadline) {
+			if st, err := daemon.LoadState(state); err == nil && st != nil && st.Resume.Offset == info.Size() {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		cancel()
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+		return strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	}
+
+	if got := follow(lines[1:3]); len(got) != 2 {
+		t.Fatalf("expected 2 entries, got %d: %q", len(got), got)
+	}
+	// entries logged while not running are written after a restart
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(lines[3])
+	file.Close()
+	if got := follow(lines[4:5]); len(got) != 2 {
+		t.Fatalf("expected the entry logged meanwhile and the appended entry, got %d: %q", len(got), got)
+	}
+}
+
+func TestWriteFollowInvalidFilter(t *testing.T) {
+	f, err := stream.NewFollower("../../tests/filter_valid.log", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	sink, _ := output.New(output.FormatNDJSON, &syncBuffer{}, output.Options{})
+	if err := writeFollow(context.Background(), sink, f, writeOptions{filter: "src"}); err == nil {
+		t.Error("expected error for invalid filter")
+	}
+}
+
+func TestWriteReplay(t *testing.T) {
+	s, err := stream.NewStream("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	var out syncBuffer
+	sink, err := output.New(output.FormatNDJSON, &out, output.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 2 seconds of log in 20 milliseconds, done once all entries have been written
+	start := time.Now()
+	if err := writeFollow(context.Background(), sink, stream.NewReplayer(s, 100), writeOptions{filter: "dport 53"}); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("expected the replay to take at least 20ms, took %s", elapsed)
+	}
+	if got := strings.Count(out.String(), "\n"); got != 8 {
+		t.Errorf("expected 8 dns entries, got %d", got)
+	}
+}
+
+func TestParseSpeed(t *testing.T) {
+	for _, tc := range []struct {
+		value  string
+		expect float64
+	}{
+		{"1x", 1}, {"10x", 10}, {"0.5x", 0.5}, {"60", 60}, {"0x", 0}, {"-2x", 0}, {"fast", 0}, {"x", 0}, {"Infx", 0},
+	} {
+		speed, err := parseSpeed(tc.value)
+		if speed != tc.expect || (err == nil) != (tc.expect > 0) {
+			t.Errorf("%q: expected %v, got %v (%v)", tc.value, tc.expect, speed, err)
+		}
+	}
+}
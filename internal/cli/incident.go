@@ -0,0 +1,52 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package cli
+
+import (
+	"fmt"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/bookmark"
+	"gitlab.com/allddd/opnsense-filterlog/internal/filter"
+	"gitlab.com/allddd/opnsense-filterlog/internal/incident"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+// displayIncident writes an incident bundle of all entries matching the filter to path (bookmarks of the file are
+// included, a manifest if withManifest is set) and displays the number of entries
+func displayIncident(s *stream.Stream, path string, filterValue string, filterOpts filter.Options, bookmarks []bookmark.Bookmark, withManifest bool) error {
+	compiled, err := filter.CompileWith(filterValue, filterOpts)
+	if err != nil {
+		return err
+	}
+	b := incident.Bundle{Bookmarks: bookmarks, Filter: filterValue, Manifest: withManifest}
+	if compiled != nil {
+		b.Match = compiled.Matches
+	}
+	count, err := b.Write(path, s)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s: %d entries\n", path, count)
+	return nil
+}
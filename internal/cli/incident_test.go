@@ -0,0 +1,90 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/filter"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+func TestIncident(t *testing.T) {
+	tests := []struct {
+		name        string
+		filter      string
+		expect      string
+		expectError bool
+	}{
+		{
+			name:   "all entries",
+			expect: ": 24 entries\n",
+		},
+		{
+			name:   "filter",
+			filter: "dport 3389",
+			expect: ": 4 entries\n",
+		},
+		{
+			name:        "invalid filter",
+			filter:      "src and",
+			expectError: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := stream.NewStream("../../tests/filter_bruteforce.log")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+			dir := filepath.Join(t.TempDir(), "incident")
+			stdout, _, err := captureOutput(func() error {
+				return displayIncident(s, dir, tc.filter, filter.Options{}, nil, false)
+			})
+			if tc.expectError {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(stdout) != dir+tc.expect {
+				t.Fatalf("expected %q, got %q", dir+tc.expect, stdout)
+			}
+			filterFile, err := os.ReadFile(filepath.Join(dir, "filter.txt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.TrimSpace(string(filterFile)) != tc.filter {
+				t.Fatalf("expected filter %q, got %q", tc.filter, filterFile)
+			}
+		})
+	}
+}
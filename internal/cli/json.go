@@ -24,19 +24,19 @@
 package cli
 
 import (
-	"encoding/json"
-	"fmt"
-	"os"
+	"io"
 
-	"gitlab.com/allddd/opnsense-filterlog/internal/filter"
+	"gitlab.com/allddd/opnsense-filterlog/internal/output"
 	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
 )
 
 type jsonObjMeta struct {
-	Entries int    `json:"entries"`          // count of entries in entries array
-	Errors  int    `json:"errors,omitempty"` // number of parse errors
-	Filter  string `json:"filter,omitempty"` // filter expression
-	Source  string `json:"source"`           // file path (absolute if possible)
+	Duplicates int    `json:"duplicates,omitempty"` // number of duplicate entries skipped (-dedupe)
+	Entries    int    `json:"entries"`              // count of entries in entries array
+	Errors     int    `json:"errors,omitempty"`     // number of parse errors
+	Filter     string `json:"filter,omitempty"`     // filter expression
+	Next       string `json:"next,omitempty"`       // cursor of the next page (serve only, omitted on the last page)
+	Source     string `json:"source"`               // file path (absolute if possible)
 }
 
 // jsonObj represents the complete JSON output structure (used only for tests and docs)
@@ -45,61 +45,28 @@ type jsonObj struct {
 	Meta    jsonObjMeta        `json:"meta"`    // meta object
 }
 
-// displayJSON writes the jsonObj to stdout
-func displayJSON(s *stream.Stream, filterValue string) error {
-	// compile filter expression (if any)
-	var compiled filter.FilterNode
-	if filterValue != "" {
-		var err error
-		compiled, err = filter.Compile(filterValue)
-		if err != nil {
-			return err
-		}
-	}
-	// open object and entries array
-	fmt.Fprint(os.Stdout, `{"entries":[`)
-	// stream entries and count
-	entries := 0
-	for entry := s.Next(); entry != nil; entry = s.Next() {
-		// skip entries that don't match filter
-		if compiled != nil && !compiled.Matches(entry) {
-			continue
-		}
-		jsonEntry, err := json.Marshal(entry)
-		if err != nil {
-			return fmt.Errorf("error(json): could not encode entry: %w", err)
-		}
-		if entries > 0 {
-			fmt.Fprint(os.Stdout, ",")
-		}
-		fmt.Fprint(os.Stdout, string(jsonEntry))
-		entries++
-	}
-	// close entries and open meta
-	fmt.Fprint(os.Stdout, `],"meta":`)
-	// build and write meta object
-	errors := s.GetErrors()
+// jsonSource returns the path of s as shown in meta.source (absolute if possible)
+func jsonSource(s *stream.Stream) string {
 	source, err := s.GetPathAbs()
 	if err != nil {
-		source = s.GetPathRel()
-	}
-	meta := jsonObjMeta{
-		Entries: entries,
-		Errors:  len(errors),
-		Filter:  filterValue,
-		Source:  source,
+		return s.GetPathRel()
 	}
-	jsonMeta, err := json.Marshal(meta)
-	if err != nil {
-		return fmt.Errorf("error(json): could not encode meta: %w", err)
-	}
-	fmt.Fprintln(os.Stdout, string(jsonMeta)+"}")
-	// print errors to stderr (if any)
-	if len(errors) > 0 {
-		for _, err := range errors {
-			fmt.Fprintln(os.Stderr, err)
+	return source
+}
+
+// jsonMeta returns the meta object of the json document of the entries of s matching the filter (parse errors and
+// duplicates skipped by d, if it is not nil, are counted once the document is closed)
+func jsonMeta(s *stream.Stream, filterValue string, d *stream.Deduper) func(entries int) any {
+	return func(entries int) any {
+		meta := jsonObjMeta{Entries: entries, Errors: len(s.GetErrors()), Filter: filterValue, Source: jsonSource(s)}
+		if d != nil {
+			meta.Duplicates = d.Duplicates()
 		}
-		return fmt.Errorf("error(json): could not process all entries: %d parse errors", len(errors))
+		return meta
 	}
-	return nil
+}
+
+// writeJSON writes the jsonObj to w and returns the parse errors (entries are enriched if e is not nil)
+func writeJSON(w io.Writer, s *stream.Stream, opts writeOptions) ([]string, error) {
+	return writeEntries(output.NewJSON(w, jsonMeta(s, opts.filter, nil)), s, opts)
 }
@@ -24,11 +24,13 @@
 package cli
 
 import (
+	"bytes"
 	"encoding/json"
 	"io"
 	"os"
 	"testing"
 
+	"gitlab.com/allddd/opnsense-filterlog/internal/output"
 	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
 )
 
@@ -52,15 +54,14 @@ func TestValidLog(t *testing.T) {
 		t.Fatal(err)
 	}
 	defer s.Close()
-	stdout, _, err := captureOutput(func() error {
-		return displayJSON(s, "")
-	})
+	var stdout bytes.Buffer
+	err = displayEntries(&stdout, io.Discard, s, writeOptions{format: output.FormatJSON})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	// parse json
 	var obj jsonObj
-	if err := json.Unmarshal(stdout, &obj); err != nil {
+	if err := json.Unmarshal(stdout.Bytes(), &obj); err != nil {
 		t.Fatalf("could not parse json: %v", err)
 	}
 	// check entries
@@ -88,15 +89,14 @@ func TestMixedLog(t *testing.T) {
 		t.Fatal(err)
 	}
 	defer s.Close()
-	stdout, stderr, err := captureOutput(func() error {
-		return displayJSON(s, "")
-	})
+	var stdout, stderr bytes.Buffer
+	err = displayEntries(&stdout, &stderr, s, writeOptions{format: output.FormatJSON})
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
 	// parse json
 	var obj jsonObj
-	if err := json.Unmarshal(stdout, &obj); err != nil {
+	if err := json.Unmarshal(stdout.Bytes(), &obj); err != nil {
 		t.Fatalf("could not parse json: %v", err)
 	}
 	// check entries
@@ -111,7 +111,7 @@ func TestMixedLog(t *testing.T) {
 		t.Fatalf("expected 30 errors in meta, got %d", obj.Meta.Errors)
 	}
 	// check stderr
-	if len(stderr) == 0 {
+	if stderr.Len() == 0 {
 		t.Fatal("expected errors to be written to stderr")
 	}
 }
@@ -122,15 +122,14 @@ func TestCorruptLog(t *testing.T) {
 		t.Fatal(err)
 	}
 	defer s.Close()
-	stdout, _, err := captureOutput(func() error {
-		return displayJSON(s, "")
-	})
+	var stdout bytes.Buffer
+	err = displayEntries(&stdout, io.Discard, s, writeOptions{format: output.FormatJSON})
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
 	// parse json
 	var obj jsonObj
-	if err := json.Unmarshal(stdout, &obj); err != nil {
+	if err := json.Unmarshal(stdout.Bytes(), &obj); err != nil {
 		t.Fatalf("could not parse json: %v", err)
 	}
 	// check entries
@@ -180,9 +179,8 @@ func TestWithFilter(t *testing.T) {
 				t.Fatal(err)
 			}
 			defer s.Close()
-			stdout, _, err := captureOutput(func() error {
-				return displayJSON(s, tc.filter)
-			})
+			var stdout bytes.Buffer
+			err = displayEntries(&stdout, io.Discard, s, writeOptions{filter: tc.filter, format: output.FormatJSON})
 			if tc.expectError {
 				if err == nil {
 					t.Fatal("expected error, got nil")
@@ -194,7 +192,7 @@ func TestWithFilter(t *testing.T) {
 			}
 			// parse json
 			var obj jsonObj
-			if err := json.Unmarshal(stdout, &obj); err != nil {
+			if err := json.Unmarshal(stdout.Bytes(), &obj); err != nil {
 				t.Fatalf("could not parse json: %v", err)
 			}
 			// check entries
@@ -218,15 +216,14 @@ func TestEmpty(t *testing.T) {
 		t.Fatal(err)
 	}
 	defer s.Close()
-	stdout, _, err := captureOutput(func() error {
-		return displayJSON(s, "src 1.2.3.4") // use filter that matches nothing
-	})
+	var stdout bytes.Buffer
+	err = displayEntries(&stdout, io.Discard, s, writeOptions{filter: "src 1.2.3.4", format: output.FormatJSON}) // use filter that matches nothing
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	// parse json
 	var obj jsonObj
-	if err := json.Unmarshal(stdout, &obj); err != nil {
+	if err := json.Unmarshal(stdout.Bytes(), &obj); err != nil {
 		t.Fatalf("could not parse json: %v", err)
 	}
 	// check entries
@@ -245,15 +242,14 @@ func TestStructure(t *testing.T) {
 		t.Fatal(err)
 	}
 	defer s.Close()
-	stdout, _, err := captureOutput(func() error {
-		return displayJSON(s, "")
-	})
+	var stdout bytes.Buffer
+	err = displayEntries(&stdout, io.Discard, s, writeOptions{format: output.FormatJSON})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	// parse json
 	var raw map[string]any
-	if err := json.Unmarshal(stdout, &raw); err != nil {
+	if err := json.Unmarshal(stdout.Bytes(), &raw); err != nil {
 		t.Fatalf("could not parse json: %v", err)
 	}
 	// check top level structure
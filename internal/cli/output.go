@@ -0,0 +1,137 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package cli
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/filter"
+	"gitlab.com/allddd/opnsense-filterlog/internal/manifest"
+	"gitlab.com/allddd/opnsense-filterlog/internal/output"
+	"gitlab.com/allddd/opnsense-filterlog/internal/plugin"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+// writeOptions are the options of batch runs writing entries (see writeEntries)
+type writeOptions struct {
+	clauseStats *filter.Stats    // counts the entries per clause of the filter (-clause-stats, nil if not set)
+	deduper     *stream.Deduper  // skips entries identical to one written before (-dedupe, nil if not set)
+	enricher    *plugin.Enricher // enriches entries (enrich in config, nil if not set)
+	filter      string           // expression entries have to match (-f, all entries if empty)
+	filterOpts  filter.Options   // options the filter is compiled with (interfaces and strict_filter of the config)
+	format      string           // format entries are displayed in (-format)
+	summary     *summary         // counts the entries of the run (nil if not needed)
+	template    string           // line of the template format (-template)
+	unordered   bool             // write batches in the order they are done instead of the order of the file (-unordered)
+	workers     int              // goroutines parsing and formatting entries (-workers, entries are written sequentially if <= 1)
+}
+
+// writeManifest writes the manifest of the file at path, which the entries counted in sum have been written to, next
+// to it (path.manifest.json)
+func writeManifest(path string, s *stream.Stream, filterValue string, sum *summary) error {
+	m := manifest.New(jsonSource(s), filterValue, sum.written, len(s.GetErrors()))
+	if err := m.Add(path, filepath.Base(path)); err != nil {
+		return err
+	}
+	return m.Write(path + ".manifest.json")
+}
+
+// newSink creates the sink writing entries of s matching opts.filter in opts.format to w (duplicates skipped by
+// opts.deduper are counted in the meta object)
+func newSink(w io.Writer, s *stream.Stream, opts writeOptions) (output.Sink, error) {
+	return output.New(opts.format, w, output.Options{Meta: jsonMeta(s, opts.filter, opts.deduper), Template: opts.template})
+}
+
+// writeEntries writes the entries of s matching opts.filter to sink, closes it and returns the parse errors (entries
+// are enriched if opts.enricher is not nil, counted in opts.summary if it is not nil)
+func writeEntries(sink output.Sink, s *stream.Stream, opts writeOptions) ([]string, error) {
+	compiled, err := compileFilter(opts.filter, opts.filterOpts, opts.clauseStats)
+	if err != nil {
+		return nil, err
+	}
+	if opts.summary == nil {
+		opts.summary = &summary{}
+	}
+	sum, e := opts.summary, opts.enricher
+	// enrichment and clause statistics aren't safe for concurrent use, duplicates are detected in the order of the file
+	if enc, ok := sink.(output.Encoder); ok && opts.workers > 1 && e == nil && opts.clauseStats == nil && opts.deduper == nil {
+		if err := writeParallel(enc, s, compiled, opts); err != nil {
+			return nil, err
+		}
+	} else {
+		for entry := s.Next(); entry != nil; entry = s.Next() {
+			sum.total++
+			// skip entries that don't match filter
+			if compiled != nil && !compiled.Matches(entry) {
+				continue
+			}
+			sum.matched++
+			if opts.deduper != nil && opts.deduper.Duplicate(entry) {
+				sum.duplicates++
+				continue
+			}
+			if e != nil {
+				if err := e.Enrich(entry); err != nil {
+					return nil, err
+				}
+			}
+			if err := sink.Write(entry); err != nil {
+				return nil, err
+			}
+			sum.written++
+		}
+	}
+	if err := sink.Close(); err != nil {
+		return nil, err
+	}
+	return s.GetErrors(), nil
+}
+
+// displayEntries writes the entries matching opts.filter in opts.format to w, and parse errors and the summary of the
+// run to errW (entries are enriched if opts.enricher is not nil, counted in opts.summary if it is not nil)
+func displayEntries(w, errW io.Writer, s *stream.Stream, opts writeOptions) error {
+	sink, err := newSink(w, s, opts)
+	if err != nil {
+		return err
+	}
+	if opts.summary == nil {
+		opts.summary = newSummary()
+	}
+	errors, err := writeEntries(sink, s, opts)
+	if err != nil {
+		return err
+	}
+	// print errors (if any)
+	for _, err := range errors {
+		fmt.Fprintln(errW, err)
+	}
+	writeSummary(errW, opts.summary, len(errors), time.Since(opts.summary.start))
+	if len(errors) > 0 {
+		return fmt.Errorf("error(%s): could not process all entries: %d parse errors", opts.format, len(errors))
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/manifest"
+	"gitlab.com/allddd/opnsense-filterlog/internal/output"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+func TestWriteManifest(t *testing.T) {
+	s, err := stream.NewStream("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	path := filepath.Join(t.TempDir(), "entries.csv.gz")
+	dest, err := output.Open(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := newSummary()
+	if err := displayEntries(dest, &syncBuffer{}, s, writeOptions{filter: "proto udp", format: output.FormatCSV, summary: sum}); err != nil {
+		t.Fatal(err)
+	}
+	if err := dest.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeManifest(path, s, "proto udp", sum); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path + ".manifest.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m manifest.Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Entries != sum.written || m.Entries == 0 || m.Filter != "proto udp" {
+		t.Errorf("expected %d entries and filter, got %+v", sum.written, m)
+	}
+	if len(m.Files) != 1 || m.Files[0].Name != "entries.csv.gz" || m.Files[0].Size != info.Size() {
+		t.Errorf("expected compressed file, got %+v", m.Files)
+	}
+}
+
+func TestWriteEntriesDedupe(t *testing.T) {
+	// the same file twice, like overlapping rotations
+	s, err := stream.NewMergedStream([]string{"../../tests/filter_valid.log", "../../tests/filter_valid.log"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	d := stream.NewDeduper()
+	var b bytes.Buffer
+	sum := newSummary()
+	if _, err := writeEntries(output.NewJSON(&b, jsonMeta(s, "", d)), s, writeOptions{deduper: d, summary: sum, workers: 4}); err != nil {
+		t.Fatal(err)
+	}
+	var result jsonObj
+	if err := json.Unmarshal(b.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Entries) != 20 || result.Meta.Entries != 20 || result.Meta.Duplicates != 20 {
+		t.Errorf("expected 20 entries and 20 duplicates, got %d entries and meta %+v", len(result.Entries), result.Meta)
+	}
+	if sum.total != 40 || sum.matched != 40 || sum.written != 20 || sum.duplicates != 20 {
+		t.Errorf("expected 20 of 40 entries written, got %+v", *sum)
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package cli
+
+import (
+	"sync"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/filter"
+	"gitlab.com/allddd/opnsense-filterlog/internal/output"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+// parallelBatch is the number of lines a worker parses and formats at once
+const parallelBatch = 1024
+
+// batch is a block of consecutive lines of the file
+type batch struct {
+	encoded [][]byte // formatted entries matching the filter
+	err     error    // error formatting an entry (if any)
+	first   int      // line number of the first line
+	lines   []string // raw lines
+	matched int      // entries matching the filter
+	seq     int      // position of the batch in the file
+	total   int      // valid entries
+}
+
+// encode parses the lines of the batch and formats the entries matching the filter
+func (b *batch) encode(parse func(line string, lineNum int) *stream.LogEntry, compiled filter.FilterNode, enc output.Encoder) {
+	for i, line := range b.lines {
+		entry := parse(line, b.first+i)
+		if entry == nil {
+			continue
+		}
+		b.total++
+		if compiled != nil && !compiled.Matches(entry) {
+			continue
+		}
+		b.matched++
+		encoded, err := enc.Encode(entry)
+		if err != nil {
+			b.err = err
+			return
+		}
+		b.encoded = append(b.encoded, encoded)
+	}
+	b.lines = nil
+}
+
+// writeParallel parses and formats the entries of s matching the filter in batches using opts.workers goroutines and
+// writes them to enc (in the order of the file unless opts.unordered is set, counted in opts.summary)
+func writeParallel(enc output.Encoder, s *stream.Stream, compiled filter.FilterNode, opts writeOptions) error {
+	sum := opts.summary
+	jobs := make(chan *batch)
+	done := make(chan *batch)
+	stop := make(chan struct{})
+	// limits the batches kept in memory while waiting for an earlier one
+	tokens := make(chan struct{}, 4*opts.workers)
+	go func() {
+		defer close(jobs)
+		for seq := 0; ; seq++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			b := &batch{lines: make([]string, 0, parallelBatch), seq: seq}
+			for len(b.lines) < parallelBatch {
+				line, lineNum, ok := s.ReadLine()
+				if !ok {
+					break
+				}
+				if len(b.lines) == 0 {
+					b.first = lineNum
+				}
+				b.lines = append(b.lines, line)
+			}
+			if len(b.lines) == 0 {
+				return
+			}
+			select {
+			case tokens <- struct{}{}:
+			case <-stop:
+				return
+			}
+			jobs <- b
+		}
+	}()
+	var wg sync.WaitGroup
+	for range opts.workers {
+		wg.Go(func() {
+			parse := s.Parser()
+			for b := range jobs {
+				b.encode(parse, compiled, enc)
+				done <- b
+			}
+		})
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	var err error
+	write := func(b *batch) {
+		<-tokens
+		if err != nil {
+			return
+		}
+		if err = b.err; err != nil {
+			close(stop)
+			return
+		}
+		sum.total += b.total
+		sum.matched += b.matched
+		for _, encoded := range b.encoded {
+			if err = enc.WriteEncoded(encoded); err != nil {
+				close(stop)
+				return
+			}
+			sum.written++
+		}
+	}
+	pending, next := make(map[int]*batch), 0
+	for b := range done {
+		if opts.unordered {
+			write(b)
+			continue
+		}
+		pending[b.seq] = b
+		for b, ok := pending[next]; ok; b, ok = pending[next] {
+			delete(pending, next)
+			write(b)
+			next++
+		}
+	}
+	return err
+}
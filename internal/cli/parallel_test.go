@@ -0,0 +1,126 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package cli
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/output"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+// writeLargeLog writes the lines of filter_mixed.log repeated to span several batches and returns the path
+func writeLargeLog(t *testing.T) string {
+	t.Helper()
+	content, err := os.ReadFile("../../tests/filter_mixed.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "filter.log")
+	if err := os.WriteFile(path, bytes.Repeat(content, 3*parallelBatch/strings.Count(string(content), "\n")+1), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// convert writes the entries of the file at path matching the filter in format using workers goroutines
+func convert(t *testing.T, path, format, filterValue string, workers int, unordered bool) (string, []string, summary) {
+	t.Helper()
+	s, err := stream.NewStream(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	var buf bytes.Buffer
+	sink, err := output.New(format, &buf, output.Options{Template: "{action} {src}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sum summary
+	errors, err := writeEntries(sink, s, writeOptions{filter: filterValue, summary: &sum, unordered: unordered, workers: workers})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.String(), errors, sum
+}
+
+func TestWriteParallel(t *testing.T) {
+	path := writeLargeLog(t)
+	for _, format := range output.Formats {
+		t.Run(format, func(t *testing.T) {
+			expect, expectErrors, expectSum := convert(t, path, format, "proto udp or action block", 1, false)
+			got, errors, sum := convert(t, path, format, "proto udp or action block", 4, false)
+			if got != expect {
+				t.Errorf("expected output of sequential run, got %d bytes instead of %d", len(got), len(expect))
+			}
+			if len(errors) != len(expectErrors) {
+				t.Errorf("expected %d parse errors, got %d", len(expectErrors), len(errors))
+			}
+			if sum != expectSum {
+				t.Errorf("expected summary %+v, got %+v", expectSum, sum)
+			}
+		})
+	}
+}
+
+func TestWriteParallelUnordered(t *testing.T) {
+	path := writeLargeLog(t)
+	expect, _, _ := convert(t, path, output.FormatNDJSON, "", 1, false)
+	got, _, _ := convert(t, path, output.FormatNDJSON, "", 4, true)
+	expectLines, gotLines := strings.Split(expect, "\n"), strings.Split(got, "\n")
+	slices.Sort(expectLines)
+	slices.Sort(gotLines)
+	if !slices.Equal(gotLines, expectLines) {
+		t.Errorf("expected the lines of the sequential run in any order")
+	}
+}
+
+func TestWriteParallelError(t *testing.T) {
+	path := writeLargeLog(t)
+	s, err := stream.NewStream(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	sink, err := output.New(output.FormatNDJSON, failingWriter{}, output.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := writeEntries(sink, s, writeOptions{workers: 4}); err == nil {
+		t.Error("expected write error")
+	}
+}
+
+// failingWriter fails every write
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, io.ErrClosedPipe
+}
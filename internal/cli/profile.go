@@ -0,0 +1,69 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package cli
+
+import (
+	"path/filepath"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/config"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+	"gitlab.com/allddd/opnsense-filterlog/internal/tui"
+)
+
+// profilePath returns the path of the log of the profile (api and host profiles are downloaded into the cache directory first)
+func profilePath(p config.Profile) (string, error) {
+	switch {
+	case p.API.URL != "":
+		return downloadAPI(p.API)
+	case p.Host != "":
+		dir, err := cacheDir()
+		if err != nil {
+			return "", err
+		}
+		return fetchHost(p.Host, remoteLogDir, filepath.Join(dir, cmdFetch))
+	default:
+		return config.ResolvePath(p.Path)
+	}
+}
+
+// profileOpener returns the function the TUI uses to switch between the profiles in cfg (entries are classified using c)
+func profileOpener(cfg *config.Config, c *classifier) tui.Opener {
+	return func(name string) (*stream.Stream, error) {
+		p, err := cfg.GetProfile(name)
+		if err != nil {
+			return nil, err
+		}
+		path, err := profilePath(p)
+		if err != nil {
+			return nil, err
+		}
+		s, err := stream.NewStream(path)
+		if err != nil {
+			return nil, err
+		}
+		s.SetOrigin(name)
+		classify(s, c)
+		return s, nil
+	}
+}
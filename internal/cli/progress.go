@@ -0,0 +1,157 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+const (
+	progressInterval = 200 * time.Millisecond // time between redraws of the progress bar
+	progressWidth    = 30                     // width of the bar in characters
+)
+
+// progress draws a progress bar of the bytes read by a stream on a terminal until something else is written to it
+type progress struct {
+	done    chan struct{}  // closed to stop drawing
+	mu      sync.Mutex     // guards stopped and writes to w
+	s       *stream.Stream // stream being read
+	size    int64          // size of the file
+	start   time.Time      // start of the run
+	stopped bool           // whether the bar has been erased
+	w       io.Writer      // terminal
+	wg      sync.WaitGroup // waits for the drawing goroutine
+}
+
+// isTerminal returns true if f is a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// formatBytes formats n bytes with a binary unit (e.g. 1.5 GiB)
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// progressLine returns the progress bar of read of size bytes after elapsed (the remaining time is extrapolated from
+// the rate so far)
+func progressLine(read, size int64, elapsed time.Duration) string {
+	share := 1.0
+	if size > 0 {
+		share = min(float64(read)/float64(size), 1)
+	}
+	filled := int(share * progressWidth)
+	eta := "-"
+	if read > 0 && share < 1 {
+		eta = time.Duration(float64(elapsed) * (1 - share) / share).Round(time.Second).String()
+	} else if share >= 1 {
+		eta = "0s"
+	}
+	return fmt.Sprintf("[%s%s] %3d%% %s / %s, ETA %s", strings.Repeat("#", filled), strings.Repeat(".", progressWidth-filled),
+		int(share*100), formatBytes(min(read, size)), formatBytes(size), eta)
+}
+
+// draw redraws the bar (must hold mu)
+func (p *progress) draw() {
+	fmt.Fprintf(p.w, "\r\033[K%s", progressLine(p.s.Offset(), p.size, time.Since(p.start)))
+}
+
+// stop erases the bar (must hold mu)
+func (p *progress) stop() {
+	if p.stopped {
+		return
+	}
+	p.stopped = true
+	close(p.done)
+	fmt.Fprint(p.w, "\r\033[K")
+}
+
+// progressSize returns the size of the file at path for the progress bar (0 if it's not a regular file)
+func progressSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return 0
+	}
+	return info.Size()
+}
+
+// startProgress draws the progress bar of the size bytes read by s to w, which should be a terminal (returns nil if
+// size isn't known)
+func startProgress(w io.Writer, s *stream.Stream, size int64) *progress {
+	if size <= 0 {
+		return nil
+	}
+	p := &progress{done: make(chan struct{}), s: s, size: size, start: time.Now(), w: w}
+	p.wg.Go(func() {
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.done:
+				return
+			case <-ticker.C:
+			}
+			p.mu.Lock()
+			if !p.stopped {
+				p.draw()
+			}
+			p.mu.Unlock()
+		}
+	})
+	return p
+}
+
+// public
+
+// Write erases the bar for good and writes b (errors and the summary of the run aren't mixed with the bar)
+func (p *progress) Write(b []byte) (int, error) {
+	p.Close()
+	return p.w.Write(b)
+}
+
+// Close erases the bar
+func (p *progress) Close() error {
+	p.mu.Lock()
+	p.stop()
+	p.mu.Unlock()
+	p.wg.Wait()
+	return nil
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n      int64
+		expect string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1536, "1.5 KiB"},
+		{5 << 30, "5.0 GiB"},
+	}
+
+	for _, tc := range tests {
+		if got := formatBytes(tc.n); got != tc.expect {
+			t.Errorf("%d: expected %q, got %q", tc.n, tc.expect, got)
+		}
+	}
+}
+
+func TestProgressLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		read    int64
+		size    int64
+		elapsed time.Duration
+		expect  string
+	}{
+		{
+			name:   "start",
+			size:   2048,
+			expect: "[..............................]   0% 0 B / 2.0 KiB, ETA -",
+		},
+		{
+			name:    "quarter",
+			read:    512,
+			size:    2048,
+			elapsed: 10 * time.Second,
+			expect:  "[#######.......................]  25% 512 B / 2.0 KiB, ETA 30s",
+		},
+		{
+			name:    "grown file",
+			read:    4096,
+			size:    2048,
+			elapsed: time.Second,
+			expect:  "[##############################] 100% 2.0 KiB / 2.0 KiB, ETA 0s",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := progressLine(tc.read, tc.size, tc.elapsed); got != tc.expect {
+				t.Errorf("expected %q, got %q", tc.expect, got)
+			}
+		})
+	}
+}
+
+func TestProgressWrite(t *testing.T) {
+	s, err := stream.NewStream("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if p := startProgress(&bytes.Buffer{}, s, 0); p != nil {
+		t.Fatal("expected no progress bar for unknown size")
+	}
+	var buf bytes.Buffer
+	p := startProgress(&buf, s, progressSize("../../tests/filter_valid.log"))
+	if p == nil {
+		t.Fatal("expected progress bar")
+	}
+	for entry := s.Next(); entry != nil; entry = s.Next() {
+	}
+	time.Sleep(2 * progressInterval)
+	// writes erase the bar for good
+	p.Write([]byte("summary\n"))
+	time.Sleep(2 * progressInterval)
+	p.Close()
+	out := buf.String()
+	if !strings.Contains(out, "100%") {
+		t.Errorf("expected complete bar, got %q", out)
+	}
+	if !strings.HasSuffix(out, "\r\033[Ksummary\n") {
+		t.Errorf("expected bar to be erased before the write, got %q", out)
+	}
+}
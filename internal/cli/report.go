@@ -0,0 +1,82 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/bookmark"
+	"gitlab.com/allddd/opnsense-filterlog/internal/filter"
+	"gitlab.com/allddd/opnsense-filterlog/internal/report"
+	"gitlab.com/allddd/opnsense-filterlog/internal/sandbox"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+// displayReport generates the given report over all entries matching the filter and writes it to output (stdout if empty),
+// bookmarks of the file matching the filter are included
+func displayReport(s *stream.Stream, format string, filterValue string, filterOpts filter.Options, output string, bookmarks []bookmark.Bookmark) error {
+	if err := report.CheckFormat(format); err != nil {
+		return err
+	}
+	compiled, err := filter.CompileWith(filterValue, filterOpts)
+	if err != nil {
+		return err
+	}
+	r := report.New(s.GetPathRel(), filterValue)
+	for entry := s.Next(); entry != nil; entry = s.Next() {
+		if compiled != nil && !compiled.Matches(entry) {
+			continue
+		}
+		r.Add(entry)
+	}
+	errors := s.GetErrors()
+	r.Errors = len(errors)
+	for _, b := range bookmarks {
+		if compiled == nil || compiled.Matches(&b.Entry) {
+			r.Bookmarks = append(r.Bookmarks, b)
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if output != "" {
+		file, err := sandbox.Create(output)
+		if err != nil {
+			return fmt.Errorf("error(report): could not create output file: %w", err)
+		}
+		defer file.Close()
+		w = file
+	}
+	if err := r.Write(w, format); err != nil {
+		return err
+	}
+	if len(errors) > 0 {
+		for _, err := range errors {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		return fmt.Errorf("error(report): could not process all entries: %d parse errors", len(errors))
+	}
+	return nil
+}
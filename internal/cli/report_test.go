@@ -0,0 +1,154 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/bookmark"
+	"gitlab.com/allddd/opnsense-filterlog/internal/filter"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+func TestReport(t *testing.T) {
+	tests := []struct {
+		name        string
+		report      string
+		filter      string
+		output      bool
+		contains    []string
+		expectError bool
+	}{
+		{
+			name:     "html to stdout",
+			report:   "html",
+			contains: []string{"<!DOCTYPE html>", "<td>203.0.113.10</td><td class=\"num\">12</td>"},
+		},
+		{
+			name:     "html to file with filter",
+			report:   "html",
+			filter:   "dport 3389",
+			output:   true,
+			contains: []string{"<code>dport 3389</code>", "<td class=\"num\">3389</td><td class=\"num\">3</td>"},
+		},
+		{
+			name:     "markdown",
+			report:   "markdown",
+			contains: []string{"# Firewall log report", "| 203.0.113.10 | 12 | 12 |"},
+		},
+		{
+			name:        "unknown report",
+			report:      "pdf",
+			expectError: true,
+		},
+		{
+			name:        "invalid filter",
+			report:      "html",
+			filter:      "src and",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := stream.NewStream("../../tests/filter_bruteforce.log")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+			output := ""
+			if tc.output {
+				output = filepath.Join(t.TempDir(), "report.html")
+			}
+			stdout, _, err := captureOutput(func() error {
+				return displayReport(s, tc.report, tc.filter, filter.Options{}, output, nil)
+			})
+			if tc.expectError {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.output {
+				if len(stdout) != 0 {
+					t.Fatalf("expected empty stdout, got %q", stdout)
+				}
+				if stdout, err = os.ReadFile(output); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for _, s := range tc.contains {
+				if !strings.Contains(string(stdout), s) {
+					t.Fatalf("expected report to contain %q", s)
+				}
+			}
+		})
+	}
+}
+
+func TestReportBookmarks(t *testing.T) {
+	const log = "../../tests/filter_bruteforce.log"
+	file, err := bookmark.FilePath(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bookmarks, err := bookmark.Load(filepath.Join(t.TempDir(), "bookmarks.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range []bookmark.Bookmark{
+		{File: file, Line: 1, Note: "first attempt", Entry: stream.LogEntry{Action: stream.ActionBlock, DstPort: 22}},
+		{File: file, Line: 2, Note: "other port", Entry: stream.LogEntry{Action: stream.ActionBlock, DstPort: 3389}},
+		{File: "/var/log/filter/other.log", Line: 1, Note: "other file"},
+	} {
+		if err := bookmarks.Set(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s, err := stream.NewStream(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	stdout, _, err := captureOutput(func() error {
+		return displayReport(s, "markdown", "dport 22", filter.Options{}, "", fileBookmarks(bookmarks, s))
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(stdout), "first attempt") {
+		t.Fatal("expected report to contain bookmark of matching entry")
+	}
+	for _, note := range []string{"other port", "other file"} {
+		if strings.Contains(string(stdout), note) {
+			t.Fatalf("expected report not to contain bookmark %q", note)
+		}
+	}
+}
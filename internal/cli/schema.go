@@ -0,0 +1,119 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/meta"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+// schemaDialect is the json schema version the schema is written in
+const schemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// schemaType returns the json schema of values of type t as encoded by encoding/json
+func schemaType(t reflect.Type) map[string]any {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == reflect.TypeFor[time.Time]() {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Uint8:
+		return map[string]any{"type": "integer", "minimum": 0, "maximum": math.MaxUint8}
+	case reflect.Uint16:
+		return map[string]any{"type": "integer", "minimum": 0, "maximum": math.MaxUint16}
+	case reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaType(t.Elem())}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": schemaType(t.Elem())}
+	case reflect.Struct:
+		properties := make(map[string]any)
+		required := make([]string, 0)
+		for i := range t.NumField() {
+			field := t.Field(i)
+			name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if !field.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			properties[name] = schemaType(field.Type)
+			if !strings.Contains(opts, "omitempty") {
+				required = append(required, name)
+			}
+		}
+		return map[string]any{"type": "object", "properties": properties, "required": required, "additionalProperties": false}
+	default:
+		return map[string]any{"type": "string"}
+	}
+}
+
+// jsonSchema returns the json schema of the output of -j (an entry of -follow -j is #/$defs/entry)
+func jsonSchema() map[string]any {
+	return map[string]any{
+		"$schema": schemaDialect,
+		"$id":     fmt.Sprintf("urn:%s:schema:%s", meta.Name, meta.Version),
+		"title":   meta.Name + " JSON output",
+		"version": meta.Version,
+		"type":    "object",
+		"properties": map[string]any{
+			"entries": map[string]any{"type": "array", "items": map[string]any{"$ref": "#/$defs/entry"}},
+			"meta":    map[string]any{"$ref": "#/$defs/meta"},
+		},
+		"required":             []string{"entries", "meta"},
+		"additionalProperties": false,
+		"$defs": map[string]any{
+			"entry": schemaType(reflect.TypeFor[stream.LogEntry]()),
+			"meta":  schemaType(reflect.TypeFor[jsonObjMeta]()),
+		},
+	}
+}
+
+// writeSchema writes the json schema of the output of -j to w
+func writeSchema(w io.Writer) error {
+	data, err := json.MarshalIndent(jsonSchema(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("error(cli): could not encode schema: %w", err)
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
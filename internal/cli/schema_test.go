@@ -0,0 +1,107 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/filter"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+// checkObject reports keys of obj that are not in the schema and required keys that are missing
+func checkObject(t *testing.T, name string, obj map[string]any, schema map[string]any) {
+	properties := schema["properties"].(map[string]any)
+	for key := range obj {
+		if _, ok := properties[key]; !ok {
+			t.Errorf("%s: key %q not in schema", name, key)
+		}
+	}
+	for _, key := range schema["required"].([]string) {
+		if _, ok := obj[key]; !ok {
+			t.Errorf("%s: required key %q missing", name, key)
+		}
+	}
+}
+
+func TestSchema(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeSchema(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !json.Valid(buf.Bytes()) {
+		t.Fatal("schema is not valid json")
+	}
+	schema := jsonSchema()
+	defs := schema["$defs"].(map[string]any)
+	entrySchema, metaSchema := defs["entry"].(map[string]any), defs["meta"].(map[string]any)
+	for _, name := range stream.FieldNames {
+		if _, ok := entrySchema["properties"].(map[string]any)[name]; !ok {
+			t.Errorf("field %q not in entry schema", name)
+		}
+	}
+
+	// the output of -j matches the schema
+	s, err := stream.NewStream("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	buf.Reset()
+	if _, err := writeJSON(&buf, s, writeOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	var obj struct {
+		Entries []map[string]any `json:"entries"`
+		Meta    map[string]any   `json:"meta"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &obj); err != nil {
+		t.Fatal(err)
+	}
+	if len(obj.Entries) == 0 {
+		t.Fatal("expected entries")
+	}
+	for i, entry := range obj.Entries {
+		checkObject(t, "entry", entry, entrySchema)
+		if t.Failed() {
+			t.Fatalf("entry %d: %v", i, entry)
+		}
+	}
+	checkObject(t, "meta", obj.Meta, metaSchema)
+
+	// served as is
+	buf.Reset()
+	if err := writeSchema(&buf); err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	serveHandler("../../tests/filter_valid.log", nil, filter.Options{}).ServeHTTP(rec, httptest.NewRequest("GET", "/schema", nil))
+	if rec.Code != http.StatusOK || !bytes.Equal(rec.Body.Bytes(), buf.Bytes()) {
+		t.Fatalf("expected schema with status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body)
+	}
+}
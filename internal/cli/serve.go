@@ -0,0 +1,358 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package cli
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"sync"
+	"syscall"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/filter"
+	"gitlab.com/allddd/opnsense-filterlog/internal/meta"
+	"gitlab.com/allddd/opnsense-filterlog/internal/output"
+	"gitlab.com/allddd/opnsense-filterlog/internal/server"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+const serveUsageText = `serve OPNsense firewall log entries over HTTP
+
+Usage:
+  %s serve [flag]... [path]
+
+Endpoints:
+  GET /entries[?filter=expression]	entries as JSON (same format as -j)
+  GET /entries?limit=n[&cursor=c][&filter=expression]	page of n entries (default 1000, max 10000), meta.next is the cursor of the next page
+  GET /live[?filter=expression]	websocket, each entry appended to the file is sent as a JSON text message
+  GET /schema	JSON schema of the entries and meta objects (same as -schema)
+
+Arguments:
+  path	filter log file to serve, defaults to 'path' of the config file (latest.log) if omitted
+
+Flags:
+`
+
+const (
+	serveFilters  = 64    // number of compiled filter expressions kept (see filter.Cache)
+	serveLimit    = 1000  // default number of entries per page
+	serveLimitMax = 10000 // maximum number of entries per page
+)
+
+type serveFlags struct {
+	Config      string `name:"c" usage:"path to config file (credentials are read from auth)"`
+	Help        bool   `name:"h" usage:"display this help message and exit"`
+	Listen      string `name:"l" value:"127.0.0.1:8080" usage:"address to listen on"`
+	TLSCert     string `name:"tls-cert" usage:"path to TLS certificate (enables TLS)"`
+	TLSClientCA string `name:"tls-client-ca" usage:"path to CA to verify client certificates against (requires -tls-cert)"`
+	TLSKey      string `name:"tls-key" usage:"path to TLS private key"`
+}
+
+// errCursorExpired is returned for cursors of a file that has been replaced since
+var errCursorExpired = errors.New("error(cli): cursor expired, the file has been replaced")
+
+// pager serves pages of entries using an index of the log file that is kept between requests
+type pager struct {
+	c       *classifier    // classifies entries (nil if no rules)
+	filters *filter.Cache  // compiled filter expressions and their matching lines
+	mu      sync.RWMutex   // guards s (held for writing while indexing, for reading while serving a page)
+	path    string         // log file path
+	s       *stream.Stream // indexed stream (nil until the first page is requested)
+}
+
+// index indexes entries appended since the last request (starts over if the file has been replaced)
+func (p *pager) index() error {
+	if p.s == nil {
+		s, err := stream.NewStream(p.path)
+		if err != nil {
+			return err
+		}
+		classify(s, p.c)
+		if err := s.BuildIndex(); err != nil {
+			s.Close()
+			return err
+		}
+		p.s = s
+		return nil
+	}
+	return p.s.ExtendIndex()
+}
+
+// cursor encodes the position of the next entry in the file with the given identity
+func cursor(dev, ino uint64, pos int) string {
+	return base64.RawURLEncoding.EncodeToString(fmt.Appendf(nil, "%d.%d.%d", dev, ino, pos))
+}
+
+// parseCursor returns the position encoded in value (errCursorExpired if it belongs to another file)
+func parseCursor(value string, dev, ino uint64) (int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return 0, fmt.Errorf("error(cli): invalid cursor %q", value)
+	}
+	var cursorDev, cursorIno uint64
+	var pos int
+	if _, err := fmt.Sscanf(string(data), "%d.%d.%d", &cursorDev, &cursorIno, &pos); err != nil || pos < 0 {
+		return 0, fmt.Errorf("error(cli): invalid cursor %q", value)
+	}
+	if cursorDev != dev || cursorIno != ino {
+		return 0, errCursorExpired
+	}
+	return pos, nil
+}
+
+// ServeHTTP writes up to limit entries matching the filter starting at the cursor (same format as -j)
+func (p *pager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filterValue := query.Get("filter")
+	limit := serveLimit
+	if value := query.Get("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 || limit > serveLimitMax {
+			http.Error(w, fmt.Sprintf("error(cli): limit must be between 1 and %d", serveLimitMax), http.StatusBadRequest)
+			return
+		}
+	}
+	compiled, err := p.filters.Compile(filterValue)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	p.mu.Lock()
+	if err := p.index(); err != nil {
+		p.mu.Unlock()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// pages are read concurrently, indexing waits for them
+	p.mu.Unlock()
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	dev, ino := p.s.IndexIdentity()
+	fingerprint := p.s.CheckpointAt(-1)
+	pos := 0
+	if value := query.Get("cursor"); value != "" {
+		if pos, err = parseCursor(value, dev, ino); err != nil {
+			status := http.StatusBadRequest
+			if errors.Is(err, errCursorExpired) {
+				status = http.StatusGone
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+	}
+	s, err := p.s.Clone()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer s.Close()
+	total := max(s.TotalLines(), 0)
+	if pos < total {
+		if err := s.SeekToLine(pos); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	// the meta object is only encoded once the page is complete
+	meta := jsonObjMeta{Filter: filterValue, Source: jsonSource(s)}
+	j := output.NewJSON(w, func(entries int) any {
+		meta.Entries, meta.Errors = entries, len(s.GetErrors())
+		return meta
+	})
+	if lines, ok := p.filters.Lines(filterValue, fingerprint); ok && compiled != nil {
+		// only read the lines the filter matched before
+		i := sort.SearchInts(lines, pos)
+		for ; i < len(lines) && j.Entries() < limit; i++ {
+			if err := s.SeekToLine(lines[i]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return
+			}
+			entry := s.Next()
+			if entry == nil {
+				break
+			}
+			if err := j.Write(entry); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return
+			}
+		}
+		pos = total
+		if i < len(lines) {
+			pos = lines[i]
+		}
+	} else {
+		// the matching lines are cached once all lines have been read in one request
+		start, matched := pos, []int{}
+		for ; pos < total && j.Entries() < limit; pos++ {
+			entry := s.Next()
+			if entry == nil {
+				break
+			}
+			if compiled != nil && !compiled.Matches(entry) {
+				continue
+			}
+			if err := j.Write(entry); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return
+			}
+			matched = append(matched, pos)
+		}
+		if compiled != nil && start == 0 && pos >= total {
+			p.filters.SetLines(filterValue, fingerprint, matched)
+		}
+	}
+	if pos < total {
+		meta.Next = cursor(dev, ino, pos)
+	}
+	if err := j.Close(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+// serveHandler returns the handler serving the entries of the log file at path (classified using c, filters compiled
+// with opts)
+func serveHandler(path string, c *classifier, opts filter.Options) http.Handler {
+	p := &pager{c: c, filters: filter.NewCache(serveFilters, opts), path: path}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /entries", func(w http.ResponseWriter, r *http.Request) {
+		// -j semantics unless a page is requested
+		if query := r.URL.Query(); query.Has("limit") || query.Has("cursor") {
+			p.ServeHTTP(w, r)
+			return
+		}
+		filterValue := r.URL.Query().Get("filter")
+		if _, err := p.filters.Compile(filterValue); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s, err := stream.NewStream(path)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer s.Close()
+		classify(s, c)
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := writeJSON(w, s, writeOptions{filter: filterValue, filterOpts: opts}); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	})
+	mux.HandleFunc("GET /schema", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/schema+json")
+		if err := writeSchema(w); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	})
+	mux.HandleFunc("GET /live", func(w http.ResponseWriter, r *http.Request) {
+		filterValue := r.URL.Query().Get("filter")
+		if _, err := p.filters.Compile(filterValue); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f, err := stream.NewFollower(path, false)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer f.Close()
+		f.SetHook(c.Classify)
+		conn, err := server.Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		// the request context isn't canceled once the connection has been taken over
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		go func() {
+			select {
+			case <-conn.Done():
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		// write errors mean the client is gone
+		sink, _ := output.New(output.FormatNDJSON, conn, output.Options{})
+		writeFollow(ctx, sink, f, writeOptions{filter: filterValue, filterOpts: opts})
+	})
+	return mux
+}
+
+// executeServe runs the serve command
+func executeServe(args []string) {
+	var f serveFlags
+	fs := flag.NewFlagSet(cmdServe, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, serveUsageText, meta.Name)
+		fs.PrintDefaults()
+	}
+	flagsDefine(fs, &f)
+	fs.Parse(args)
+	// -h
+	if f.Help {
+		fs.Usage()
+		os.Exit(0)
+	}
+	// -c
+	cfg, err := loadConfig(f.Config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	// -tls-cert, -tls-key, -tls-client-ca
+	tlsConfig, err := server.TLS{Cert: f.TLSCert, ClientCA: f.TLSClientCA, Key: f.TLSKey}.Config()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	c, err := newClassifier(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	// args
+	path, err := logPath(fs.Args(), cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if _, err := os.Stat(path); err != nil {
+		fmt.Fprintf(os.Stderr, "error(cli): %v\n", err)
+		os.Exit(1)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := server.Serve(ctx, f.Listen, serveHandler(path, c, cfg.FilterOptions()), tlsConfig, cfg.Auth); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package cli
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/filter"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+func TestServeHandler(t *testing.T) {
+	h := serveHandler("../../tests/filter_valid.log", nil, filter.Options{})
+	tests := []struct {
+		name            string
+		target          string
+		expectedStatus  int
+		expectedEntries int
+	}{
+		{name: "all entries", target: "/entries", expectedStatus: http.StatusOK, expectedEntries: 20},
+		{name: "filtered", target: "/entries?filter=proto+tcp", expectedStatus: http.StatusOK, expectedEntries: 12},
+		{name: "invalid filter", target: "/entries?filter=src+and", expectedStatus: http.StatusBadRequest},
+		{name: "unknown path", target: "/", expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest("GET", tc.target, nil))
+			if rec.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tc.expectedStatus, rec.Code)
+			}
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+			var obj jsonObj
+			if err := json.Unmarshal(rec.Body.Bytes(), &obj); err != nil {
+				t.Fatalf("could not parse json: %v", err)
+			}
+			if obj.Meta.Entries != tc.expectedEntries {
+				t.Fatalf("expected %d entries, got %d", tc.expectedEntries, obj.Meta.Entries)
+			}
+		})
+	}
+}
+
+func TestServePages(t *testing.T) {
+	h := serveHandler("../../tests/filter_valid.log", nil, filter.Options{})
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+		return rec
+	}
+
+	tests := []struct {
+		name            string
+		query           string
+		expectedPages   int
+		expectedEntries int
+	}{
+		{name: "all entries", query: "limit=6", expectedPages: 4, expectedEntries: 20},
+		{name: "single page", query: "limit=20", expectedPages: 1, expectedEntries: 20},
+		{name: "filtered", query: "limit=5&filter=proto+tcp", expectedPages: 3, expectedEntries: 12},
+		{name: "no matches", query: "limit=5&filter=dport+1", expectedPages: 1, expectedEntries: 0},
+		// reading all lines in one request caches the matching lines for the following pages
+		{name: "filtered single page", query: "limit=20&filter=proto+tcp", expectedPages: 1, expectedEntries: 12},
+		{name: "filtered from cache", query: "limit=5&filter=proto+tcp", expectedPages: 3, expectedEntries: 12},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pages, entries := 0, 0
+			for next := ""; ; {
+				target := "/entries?" + tc.query
+				if next != "" {
+					target += "&cursor=" + next
+				}
+				rec := get(target)
+				if rec.Code != http.StatusOK {
+					t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body)
+				}
+				var obj jsonObj
+				if err := json.Unmarshal(rec.Body.Bytes(), &obj); err != nil {
+					t.Fatalf("could not parse json: %v", err)
+				}
+				if obj.Meta.Entries != len(obj.Entries) {
+					t.Fatalf("expected meta entries %d, got %d", len(obj.Entries), obj.Meta.Entries)
+				}
+				pages++
+				entries += obj.Meta.Entries
+				if next = obj.Meta.Next; next == "" {
+					break
+				}
+			}
+			if pages != tc.expectedPages || entries != tc.expectedEntries {
+				t.Fatalf("expected %d entries on %d pages, got %d on %d", tc.expectedEntries, tc.expectedPages, entries, pages)
+			}
+		})
+	}
+
+	for _, tc := range []struct {
+		name           string
+		target         string
+		expectedStatus int
+	}{
+		{name: "invalid limit", target: "/entries?limit=0", expectedStatus: http.StatusBadRequest},
+		{name: "limit too large", target: "/entries?limit=10001", expectedStatus: http.StatusBadRequest},
+		{name: "invalid cursor", target: "/entries?cursor=x", expectedStatus: http.StatusBadRequest},
+		{name: "invalid filter", target: "/entries?limit=5&filter=src+and", expectedStatus: http.StatusBadRequest},
+		{name: "cursor of another file", target: "/entries?cursor=" + cursor(1, 2, 5), expectedStatus: http.StatusGone},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if rec := get(tc.target); rec.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tc.expectedStatus, rec.Code)
+			}
+		})
+	}
+}
+
+func TestServeLive(t *testing.T) {
+	data, err := os.ReadFile("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	path := filepath.Join(t.TempDir(), "filter.log")
+	if err := os.WriteFile(path, []byte(lines[0]), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(serveHandler(path, nil, filter.Options{}))
+	defer srv.Close()
+
+	rec := httptest.NewRecorder()
+	serveHandler(path, nil, filter.Options{}).ServeHTTP(rec, httptest.NewRequest("GET", "/live?filter=src+and", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("invalid filter: expected status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	req := "GET /live?filter=proto+tcp HTTP/1.1\r\nHost: " + srv.Listener.Addr().String() + "\r\nConnection: Upgrade\r\n" +
+		"Upgrade: websocket\r\nSec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n"
+	if _, err := conn.Write([]byte(req)); err != nil {
+		t.Fatal(err)
+	}
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("expected status %d, got %d", http.StatusSwitchingProtocols, resp.StatusCode)
+	}
+
+	// only appended entries matching the filter are sent
+	var expected []*stream.LogEntry
+	for _, line := range lines[1:] {
+		entry, err := stream.ParseLine(strings.TrimSuffix(line, "\n"))
+		if err == nil && entry.ProtoName == "tcp" {
+			expected = append(expected, entry)
+		}
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(strings.Join(lines[1:], "")); err != nil {
+		t.Fatal(err)
+	}
+	for i := range expected {
+		var header [2]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			t.Fatal(err)
+		}
+		n := int(header[1])
+		if n == 126 {
+			var ext [2]byte
+			if _, err := io.ReadFull(r, ext[:]); err != nil {
+				t.Fatal(err)
+			}
+			n = int(binary.BigEndian.Uint16(ext[:]))
+		}
+		payload := make([]byte, n)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			t.Fatal(err)
+		}
+		var entry stream.LogEntry
+		if err := json.Unmarshal(payload, &entry); err != nil {
+			t.Fatalf("message %d: could not parse json: %v", i, err)
+		}
+		if entry.ProtoName != "tcp" || !entry.Time.Equal(expected[i].Time) || entry.Src != expected[i].Src {
+			t.Fatalf("message %d: expected %+v, got %+v", i, expected[i], entry)
+		}
+	}
+}
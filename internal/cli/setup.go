@@ -0,0 +1,279 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/x/term"
+	"gitlab.com/allddd/opnsense-filterlog/internal/config"
+	"gitlab.com/allddd/opnsense-filterlog/internal/meta"
+)
+
+const setupUsageText = `create a starter config file by answering a few questions
+
+Usage:
+  %s setup [flag]...
+
+Asks where the logs are read from (local file, firewall over SSH or OPNsense API), the
+credentials needed to access them and your own networks, and writes the config file.
+
+Flags:
+`
+
+type setupFlags struct {
+	Config string `name:"c" usage:"path of the config file to write (default: config.json in the config directory)"`
+	Force  bool   `name:"force" usage:"overwrite an existing config file"`
+	Help   bool   `name:"h" usage:"display this help message and exit"`
+}
+
+const (
+	// log sources
+	setupSourceFile = iota + 1
+	setupSourceSSH
+	setupSourceAPI
+)
+
+// prompter asks questions on w and reads the answers from r
+type prompter struct {
+	r      *bufio.Reader          // answers
+	secret func() (string, error) // reads an answer without echoing it (nil reads it from r)
+	w      io.Writer              // questions
+}
+
+// line reads an answer (an error if the input ended before it)
+func (p *prompter) line() (string, error) {
+	line, err := p.r.ReadString('\n')
+	if err != nil && (line == "" || !errors.Is(err, io.EOF)) {
+		return "", fmt.Errorf("error(cli): setup aborted: %w", err)
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// ask asks the question until check accepts the answer (def is the answer if it is empty, an empty answer is only
+// accepted without a default if required is false)
+func (p *prompter) ask(question, def string, required bool, check func(string) error) (string, error) {
+	for {
+		if def != "" {
+			fmt.Fprintf(p.w, "%s [%s]: ", question, def)
+		} else {
+			fmt.Fprintf(p.w, "%s: ", question)
+		}
+		answer, err := p.line()
+		if err != nil {
+			return "", err
+		}
+		if answer == "" {
+			answer = def
+		}
+		switch {
+		case answer == "" && required:
+			fmt.Fprintln(p.w, "  an answer is required")
+		case answer != "" && check != nil && check(answer) != nil:
+			fmt.Fprintf(p.w, "  %v\n", check(answer))
+		default:
+			return answer, nil
+		}
+	}
+}
+
+// askSecret asks the question until the answer is not empty (without echoing it if possible)
+func (p *prompter) askSecret(question string) (string, error) {
+	if p.secret == nil {
+		return p.ask(question, "", true, nil)
+	}
+	for {
+		fmt.Fprintf(p.w, "%s (not shown): ", question)
+		answer, err := p.secret()
+		fmt.Fprintln(p.w)
+		if err != nil {
+			return "", fmt.Errorf("error(cli): setup aborted: %w", err)
+		}
+		if answer = strings.TrimSpace(answer); answer != "" {
+			return answer, nil
+		}
+		fmt.Fprintln(p.w, "  an answer is required")
+	}
+}
+
+// choose asks to choose one of the options and returns its number (starting at 1)
+func (p *prompter) choose(question string, options []string, def int) (int, error) {
+	fmt.Fprintln(p.w, question)
+	for i, option := range options {
+		fmt.Fprintf(p.w, "  %d) %s\n", i+1, option)
+	}
+	answer, err := p.ask("Choice", strconv.Itoa(def), true, func(s string) error {
+		if n, err := strconv.Atoi(s); err != nil || n < 1 || n > len(options) {
+			return fmt.Errorf("choose a number from 1 to %d", len(options))
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	n, _ := strconv.Atoi(answer)
+	return n, nil
+}
+
+// checkNetworks checks a comma separated list of networks in CIDR notation
+func checkNetworks(s string) error {
+	for _, network := range strings.Split(s, ",") {
+		if _, err := netip.ParsePrefix(strings.TrimSpace(network)); err != nil {
+			return fmt.Errorf("%q is not a network in CIDR notation (e.g. 192.0.2.0/24)", strings.TrimSpace(network))
+		}
+	}
+	return nil
+}
+
+// runSetup asks for the settings of a starter config with p and returns it
+func runSetup(p *prompter) (*config.Config, error) {
+	cfg := config.New()
+	cfg.Interfaces = map[string][]string{}
+	cfg.Networks = []string{}
+	cfg.Profiles = map[string]config.Profile{}
+	cfg.Severity = []config.SeverityRule{}
+	source, err := p.choose("Where should the filter logs be read from?", []string{
+		"local file (e.g. on the firewall itself or a syslog server)",
+		"firewall over SSH (downloaded with sftp, keys or agent required)",
+		"OPNsense API (newest entries, polled)",
+	}, setupSourceFile)
+	if err != nil {
+		return nil, err
+	}
+	switch source {
+	case setupSourceFile:
+		if cfg.Path, err = p.ask("Path of the log file (may contain date verbs like %Y%m%d)", cfg.Path, true, nil); err != nil {
+			return nil, err
+		}
+	case setupSourceSSH:
+		host, err := p.ask("SSH host ([user@]host, ssh config alias or sftp://[user@]host[:port])", "", true, nil)
+		if err != nil {
+			return nil, err
+		}
+		name, err := p.ask("Profile name", "firewall", true, func(s string) error {
+			if strings.ContainsAny(s, " \t") {
+				return fmt.Errorf("the name must not contain spaces")
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		cfg.Profiles[name] = config.Profile{API: config.New().API, Host: host}
+		fmt.Fprintf(p.w, "  open the logs with: %s -profile %s\n", meta.Name, name)
+	case setupSourceAPI:
+		if cfg.API.URL, err = p.ask("API URL (e.g. https://192.168.1.1)", "", true, func(s string) error {
+			if !strings.HasPrefix(s, "https://") && !strings.HasPrefix(s, "http://") {
+				return fmt.Errorf("the url must start with https://")
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+		fmt.Fprintln(p.w, "  keys are created per user under System > Access > Users")
+		if cfg.API.Key, err = p.askSecret("API key"); err != nil {
+			return nil, err
+		}
+		if cfg.API.Secret, err = p.askSecret("API secret"); err != nil {
+			return nil, err
+		}
+		if cfg.API.CA, err = p.ask("CA file to verify the certificate (empty for the system roots)", "", false, func(s string) error {
+			_, err := os.Stat(s)
+			return err
+		}); err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(p.w, "  read the logs with: %s -api\n", meta.Name)
+	}
+	networks, err := p.ask("Your own networks in CIDR notation, comma separated (empty to skip)", "", false, checkNetworks)
+	if err != nil {
+		return nil, err
+	}
+	for network := range strings.SplitSeq(networks, ",") {
+		if network = strings.TrimSpace(network); network != "" {
+			cfg.Networks = append(cfg.Networks, network)
+		}
+	}
+	return cfg, nil
+}
+
+// executeSetup runs the setup command
+func executeSetup(args []string) {
+	var f setupFlags
+	fs := flag.NewFlagSet(cmdSetup, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, setupUsageText, meta.Name)
+		fs.PrintDefaults()
+	}
+	flagsDefine(fs, &f)
+	fs.Parse(args)
+	// -h
+	if f.Help {
+		fs.Usage()
+		os.Exit(0)
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintln(os.Stderr, "error(cli): setup takes no arguments")
+		fs.Usage()
+		os.Exit(1)
+	}
+	// -c
+	path := f.Config
+	if path == "" {
+		if path = config.DefaultPath(); path == "" {
+			fmt.Fprintln(os.Stderr, "error(cli): could not determine the config directory (use -c)")
+			os.Exit(1)
+		}
+	}
+	// -force
+	if _, err := os.Stat(path); !f.Force && !errors.Is(err, os.ErrNotExist) {
+		fmt.Fprintf(os.Stderr, "error(cli): %s already exists (use -force to overwrite it)\n", path)
+		os.Exit(1)
+	}
+	p := &prompter{r: bufio.NewReader(os.Stdin), w: os.Stdout}
+	if term.IsTerminal(os.Stdin.Fd()) {
+		p.secret = func() (string, error) {
+			b, err := term.ReadPassword(os.Stdin.Fd())
+			return string(b), err
+		}
+	}
+	cfg, err := runSetup(p)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := cfg.Save(path); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Printf("wrote %s (check it with: %s config check)\n", path, meta.Name)
+}
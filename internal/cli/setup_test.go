@@ -0,0 +1,101 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package cli
+
+import (
+	"bufio"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/config"
+)
+
+func TestRunSetup(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		check       func(*config.Config) bool
+		expectError bool
+	}{
+		{
+			name:  "defaults",
+			input: "\n\n\n",
+			check: func(cfg *config.Config) bool {
+				return cfg.Path == config.New().Path && len(cfg.Networks) == 0 && cfg.API.URL == ""
+			},
+		},
+		{
+			name:  "local file with networks",
+			input: "1\n/var/log/filter/filter_%Y%m%d.log\n192.0.2.0/24, 2001:db8::/32\n",
+			check: func(cfg *config.Config) bool {
+				return cfg.Path == "/var/log/filter/filter_%Y%m%d.log" && slices.Equal(cfg.Networks, []string{"192.0.2.0/24", "2001:db8::/32"})
+			},
+		},
+		{
+			name:  "ssh with invalid answers",
+			input: "4\n2\n\nadmin@fw1\nmy fw\nfw1\n192.0.2.0\n192.0.2.0/24\n",
+			check: func(cfg *config.Config) bool {
+				p, ok := cfg.Profiles["fw1"]
+				return ok && p.Host == "admin@fw1" && len(cfg.Networks) == 1
+			},
+		},
+		{
+			name:  "api",
+			input: "3\n192.168.1.1\nhttps://192.168.1.1\nkey\nsecret\n\n\n",
+			check: func(cfg *config.Config) bool {
+				return cfg.API.URL == "https://192.168.1.1" && cfg.API.Key == "key" && cfg.API.Secret == "secret" && cfg.API.CA == ""
+			},
+		},
+		{
+			name:        "aborted",
+			input:       "3\nhttps://192.168.1.1\n",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			p := &prompter{r: bufio.NewReader(strings.NewReader(tc.input)), w: io.Discard}
+			cfg, err := runSetup(p)
+			if tc.expectError {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tc.check(cfg) {
+				t.Fatalf("unexpected config: %+v", cfg)
+			}
+			// the starter config is valid
+			if err := cfg.Save(t.TempDir() + "/config.json"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
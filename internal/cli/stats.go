@@ -0,0 +1,189 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/filter"
+	"gitlab.com/allddd/opnsense-filterlog/internal/meta"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stats"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+const (
+	// reports
+	reportGaps  = "gaps"
+	reportPorts = "ports"
+	reportRules = "rules"
+)
+
+const statsUsageText = `display statistics for OPNsense firewall logs
+
+Usage:
+  %s stats [flag]... [path]...
+
+Reports:
+  gaps	min/median/p95/max time between consecutive entries and the largest silent gaps
+  ports	distinct sources, entries and first/last seen per destination port
+  rules	entries, pass/block split and first/last seen per firewall rule
+
+Arguments:
+  path	filter log file to analyze (several files are merged by time), defaults to 'path' of the config file (latest.log) if omitted
+
+Flags:
+`
+
+type statsFlags struct {
+	ClauseStats bool   `name:"clause-stats" usage:"display how many entries each clause of -f was evaluated for and matched on stderr"`
+	Filter      string `name:"f" usage:"filter expression"`
+	Help        bool   `name:"h" usage:"display this help message and exit"`
+	Json        bool   `name:"j" usage:"display report as JSON"`
+	Report      string `name:"r" value:"ports" usage:"report to display (gaps, ports, rules)"`
+}
+
+// executeStats runs the stats command
+func executeStats(args []string) {
+	var f statsFlags
+	fs := flag.NewFlagSet(cmdStats, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, statsUsageText, meta.Name)
+		fs.PrintDefaults()
+	}
+	flagsDefine(fs, &f)
+	fs.Parse(args)
+	// -h
+	if f.Help {
+		fs.Usage()
+		os.Exit(0)
+	}
+	cfg, err := loadConfig("")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	// -f
+	warnFilter(f.Filter, cfg.FilterOptions())
+	// args
+	s, err := openStream(fs.Args(), cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer s.Close()
+	// -clause-stats
+	var clauseStats *filter.Stats
+	if f.ClauseStats {
+		if f.Filter == "" {
+			fmt.Fprintln(os.Stderr, "error(cli): -clause-stats requires -f")
+			fs.Usage()
+			os.Exit(1)
+		}
+		clauseStats = &filter.Stats{}
+	}
+	var errW io.Writer = os.Stderr
+	if isTerminal(os.Stderr) && !isTerminal(os.Stdout) {
+		// the bar would be mixed with the report written to the terminal
+		if p := startProgress(os.Stderr, s, progressSize(s.Source())); p != nil {
+			errW = p
+			defer p.Close()
+		}
+	}
+	if err := displayStats(os.Stdout, errW, s, f.Report, f.Filter, cfg.FilterOptions(), f.Json, clauseStats); err != nil {
+		fmt.Fprintln(errW, err)
+		os.Exit(1)
+	}
+	if clauseStats != nil {
+		if err := writeClauseStats(errW, clauseStats); err != nil {
+			fmt.Fprintln(errW, err)
+			os.Exit(1)
+		}
+	}
+}
+
+// displayStats builds the given report over all entries matching the filter and writes it to w, and parse errors and
+// the summary of the run to errW (entries are counted per clause of the filter in clauseStats if it is not nil)
+func displayStats(w, errW io.Writer, s *stream.Stream, report string, filterValue string, filterOpts filter.Options, asJSON bool, clauseStats *filter.Stats) error {
+	var (
+		add   func(entry *stream.LogEntry) // adds an entry to the report
+		data  func() any                   // returns the report (json)
+		table func() error                 // writes the report as table
+	)
+	switch report {
+	case reportGaps:
+		g := stats.NewGaps()
+		add = g.Add
+		data = func() any { return g.Summary() }
+		table = func() error { return stats.WriteGaps(w, g.Summary()) }
+	case reportPorts:
+		p := stats.NewPorts()
+		add = p.Add
+		data = func() any { return p.Summaries() }
+		table = func() error { return stats.WritePorts(w, p.Summaries()) }
+	case reportRules:
+		r := stats.NewRules()
+		add = r.Add
+		data = func() any { return r.Summaries() }
+		table = func() error { return stats.WriteRules(w, r.Summaries()) }
+	default:
+		return fmt.Errorf("error(stats): unknown report %q (available: %s, %s, %s)", report, reportGaps, reportPorts,
+			reportRules)
+	}
+	compiled, err := compileFilter(filterValue, filterOpts, clauseStats)
+	if err != nil {
+		return err
+	}
+	sum := newSummary()
+	for entry := s.Next(); entry != nil; entry = s.Next() {
+		sum.total++
+		if compiled != nil && !compiled.Matches(entry) {
+			continue
+		}
+		sum.matched++
+		add(entry)
+	}
+	// every matching entry is added to the report
+	sum.written = sum.matched
+	if asJSON {
+		if err := json.NewEncoder(w).Encode(data()); err != nil {
+			return fmt.Errorf("error(stats): could not encode report: %w", err)
+		}
+	} else if err := table(); err != nil {
+		return fmt.Errorf("error(stats): could not write report: %w", err)
+	}
+	errors := s.GetErrors()
+	for _, err := range errors {
+		fmt.Fprintln(errW, err)
+	}
+	writeSummary(errW, sum, len(errors), time.Since(sum.start))
+	if len(errors) > 0 {
+		return fmt.Errorf("error(stats): could not process all entries: %d parse errors", len(errors))
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/filter"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stats"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+func TestStatsPorts(t *testing.T) {
+	tests := []struct {
+		name        string
+		filter      string
+		expectPorts []uint16
+		expectError bool
+	}{
+		{
+			name:        "all entries",
+			expectPorts: []uint16{3389, 22, 53},
+		},
+		{
+			name:        "with filter",
+			filter:      "action block",
+			expectPorts: []uint16{3389, 22},
+		},
+		{
+			name:        "invalid filter",
+			filter:      "src and",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := stream.NewStream("../../tests/filter_bruteforce.log")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+			stdout, _, err := captureOutput(func() error {
+				return displayStats(os.Stdout, os.Stderr, s, reportPorts, tc.filter, filter.Options{}, true, nil)
+			})
+			if tc.expectError {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var summaries []stats.PortSummary
+			if err := json.Unmarshal(stdout, &summaries); err != nil {
+				t.Fatalf("could not parse json: %v", err)
+			}
+			if len(summaries) != len(tc.expectPorts) {
+				t.Fatalf("expected %d ports, got %d", len(tc.expectPorts), len(summaries))
+			}
+			for i, port := range tc.expectPorts {
+				if summaries[i].DstPort != port {
+					t.Fatalf("expected port %d at %d, got %d", port, i, summaries[i].DstPort)
+				}
+			}
+		})
+	}
+}
+
+func TestStatsRules(t *testing.T) {
+	s, err := stream.NewStream("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	stdout, _, err := captureOutput(func() error {
+		return displayStats(os.Stdout, os.Stderr, s, reportRules, "", filter.Options{}, true, nil)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var summaries []stats.RuleSummary
+	if err := json.Unmarshal(stdout, &summaries); err != nil {
+		t.Fatalf("could not parse json: %v", err)
+	}
+	total := 0
+	for _, summary := range summaries {
+		if summary.Label == "" || summary.RuleNum == "" {
+			t.Fatalf("expected label and rule number, got %+v", summary)
+		}
+		if summary.Passed+summary.Blocked > summary.Entries {
+			t.Fatalf("expected at most %d passed and blocked entries, got %+v", summary.Entries, summary)
+		}
+		total += summary.Entries
+	}
+	if total != 20 {
+		t.Fatalf("expected 20 entries, got %d", total)
+	}
+}
+
+func TestStatsGaps(t *testing.T) {
+	s, err := stream.NewStream("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	stdout, _, err := captureOutput(func() error {
+		return displayStats(os.Stdout, os.Stderr, s, reportGaps, "", filter.Options{}, true, nil)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var summary stats.GapSummary
+	if err := json.Unmarshal(stdout, &summary); err != nil {
+		t.Fatalf("could not parse json: %v", err)
+	}
+	if summary.Entries != 20 {
+		t.Fatalf("expected 20 entries, got %d", summary.Entries)
+	}
+	if summary.Min > summary.Median || summary.Median > summary.P95 || summary.P95 > summary.Max {
+		t.Fatalf("expected ascending percentiles, got %+v", summary)
+	}
+	if len(summary.Largest) == 0 || summary.Largest[0].Duration != summary.Max {
+		t.Fatalf("expected the largest gap first, got %+v", summary.Largest)
+	}
+}
+
+func TestStatsTable(t *testing.T) {
+	s, err := stream.NewStream("../../tests/filter_bruteforce.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	stdout, _, err := captureOutput(func() error {
+		return displayStats(os.Stdout, os.Stderr, s, reportPorts, "dport 3389", filter.Options{}, false, nil)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(stdout)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected header and 1 row, got %d lines", len(lines))
+	}
+	if fields := strings.Fields(lines[1]); fields[0] != "3389" || fields[1] != "3" {
+		t.Fatalf("expected 3 sources for port 3389, got %q", lines[1])
+	}
+}
+
+func TestStatsUnknownReport(t *testing.T) {
+	s, err := stream.NewStream("../../tests/filter_bruteforce.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	_, _, err = captureOutput(func() error {
+		return displayStats(os.Stdout, os.Stderr, s, "talkers", "", filter.Options{}, false, nil)
+	})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
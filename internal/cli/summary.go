@@ -0,0 +1,59 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package cli
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// summary counts the entries of a run for the line written to stderr once it is done
+type summary struct {
+	duplicates int       // entries matching the filter skipped as duplicates (-dedupe)
+	matched    int       // entries matching the filter
+	start      time.Time // start of the run
+	total      int       // entries read
+	written    int       // entries written to the output
+}
+
+// newSummary returns the summary of a run starting now
+func newSummary() *summary {
+	return &summary{start: time.Now()}
+}
+
+// writeSummary writes the summary line of a run that took elapsed (errors is the number of parse errors)
+func writeSummary(w io.Writer, sum *summary, errors int, elapsed time.Duration) error {
+	rate := 0.0
+	if elapsed > 0 {
+		rate = float64(sum.total) / elapsed.Seconds()
+	}
+	duplicates := ""
+	if sum.duplicates > 0 {
+		duplicates = fmt.Sprintf(", %d duplicates skipped", sum.duplicates)
+	}
+	_, err := fmt.Fprintf(w, "%d entries written, %d/%d matched%s, %d errors in %s (%.0f entries/s)\n",
+		sum.written, sum.matched, sum.total, duplicates, errors, elapsed.Round(time.Millisecond), rate)
+	return err
+}
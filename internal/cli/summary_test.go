@@ -0,0 +1,101 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/output"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+func TestWriteSummary(t *testing.T) {
+	tests := []struct {
+		name    string
+		sum     summary
+		errors  int
+		elapsed time.Duration
+		expect  string
+	}{
+		{
+			name:    "filtered",
+			sum:     summary{matched: 12, total: 400, written: 12},
+			errors:  1,
+			elapsed: 2 * time.Second,
+			expect:  "12 entries written, 12/400 matched, 1 errors in 2s (200 entries/s)\n",
+		},
+		{
+			name:    "deduplicated",
+			sum:     summary{duplicates: 20, matched: 40, total: 40, written: 20},
+			elapsed: time.Second,
+			expect:  "20 entries written, 40/40 matched, 20 duplicates skipped, 0 errors in 1s (40 entries/s)\n",
+		},
+		{
+			name:   "instant",
+			expect: "0 entries written, 0/0 matched, 0 errors in 0s (0 entries/s)\n",
+		},
+		{
+			name:    "rounded",
+			sum:     summary{matched: 3, total: 3, written: 3},
+			elapsed: 1500 * time.Microsecond,
+			expect:  "3 entries written, 3/3 matched, 0 errors in 2ms (2000 entries/s)\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeSummary(&buf, &tc.sum, tc.errors, tc.elapsed); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != tc.expect {
+				t.Errorf("expected %q, got %q", tc.expect, buf.String())
+			}
+		})
+	}
+}
+
+func TestDisplayEntriesSummary(t *testing.T) {
+	s, err := stream.NewStream("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	var stdout, stderr bytes.Buffer
+	if err := displayEntries(&stdout, &stderr, s, writeOptions{filter: "proto udp", format: output.FormatCSV}); err != nil {
+		t.Fatal(err)
+	}
+	// the header isn't counted
+	rows := strings.Count(stdout.String(), "\n") - 1
+	if !strings.HasPrefix(stderr.String(), fmt.Sprintf("%d entries written, %d/", rows, rows)) {
+		t.Errorf("expected summary of %d entries, got %q", rows, stderr.String())
+	}
+	if strings.Count(stderr.String(), "\n") != 1 {
+		t.Errorf("expected single summary line, got %q", stderr.String())
+	}
+}
@@ -0,0 +1,359 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/filter"
+	"gitlab.com/allddd/opnsense-filterlog/internal/sandbox"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+const (
+	dirName  = "opnsense-filterlog"
+	fileName = "config.json"
+
+	// defaults
+	defaultAPIInterval         = Duration(10 * time.Second)
+	defaultAPILimit            = 1000
+	defaultBruteforceThreshold = 10
+	defaultBruteforceWindow    = Duration(time.Minute)
+	defaultOpen                = "whois {src}"
+	defaultPath                = "/var/log/filter/latest.log"
+)
+
+// Duration is a time.Duration that is represented as a string (e.g. "5m") in the config file
+type Duration time.Duration
+
+// API represents the connection to the OPNsense API
+type API struct {
+	CA       string   `json:"ca"`       // ca file to verify the certificate against (system roots if empty)
+	Interval Duration `json:"interval"` // polling interval
+	Key      string   `json:"key"`      // api key
+	Limit    int      `json:"limit"`    // maximum number of entries per request
+	Secret   string   `json:"secret"`   // api secret
+	URL      string   `json:"url"`      // base url (e.g. https://192.168.1.1)
+}
+
+// Auth represents the credentials required by network services (metrics, serve)
+type Auth struct {
+	Password string `json:"password"` // basic auth password
+	Token    string `json:"token"`    // bearer token
+	Username string `json:"username"` // basic auth username
+}
+
+// Bruteforce represents the brute-force detection settings
+type Bruteforce struct {
+	Threshold int      `json:"threshold"` // blocked attempts within window that must be exceeded
+	Window    Duration `json:"window"`    // sliding window size
+}
+
+// Profile represents a firewall and how its logs are accessed (exactly one of api.url, host and path is set)
+type Profile struct {
+	API  API    `json:"api"`  // connection to the OPNsense API
+	Host string `json:"host"` // ssh host the logs are fetched from over sftp
+	Path string `json:"path"` // local log file (may contain date verbs)
+}
+
+// SeverityRule represents a classification rule (entries matching filter are assigned level)
+type SeverityRule struct {
+	Filter string `json:"filter"` // filter expression
+	Level  string `json:"level"`  // severity level (info, notice, warning, critical)
+}
+
+// Config represents the user configuration file
+type Config struct {
+	Accessible   bool                `json:"accessible"`    // render the tui for screen readers (see -accessible)
+	API          API                 `json:"api"`           // connection to the OPNsense API
+	Auth         Auth                `json:"auth"`          // credentials required by network services
+	Bruteforce   Bruteforce          `json:"bruteforce"`    // brute-force detection settings
+	Enrich       string              `json:"enrich"`        // command line of the enrichment plugin
+	Interfaces   map[string][]string `json:"interfaces"`    // interfaces by name used in filters (e.g. wan: [igb0])
+	Networks     []string            `json:"networks"`      // own networks in CIDR notation (address class mine)
+	Open         string              `json:"open"`          // command template run by the open action (tui)
+	Path         string              `json:"path"`          // log file used if no path is given (may contain date verbs)
+	Profiles     map[string]Profile  `json:"profiles"`      // named firewalls
+	Severity     []SeverityRule      `json:"severity"`      // classification rules (first matching rule wins)
+	StrictFilter bool                `json:"strict_filter"` // require operators between filter terms (no implicit and)
+}
+
+// MarshalJSON encodes the duration as a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON decodes the duration from a string
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string: %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// UnmarshalJSON decodes the profile (api defaults apply to profiles as well)
+func (p *Profile) UnmarshalJSON(data []byte) error {
+	type profile Profile // avoids recursion
+	v := profile{API: API{Interval: defaultAPIInterval, Limit: defaultAPILimit}}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&v); err != nil {
+		return err
+	}
+	*p = Profile(v)
+	return nil
+}
+
+// validate checks the api values (prefix is the key of the api object)
+func (a API) validate(prefix string) error {
+	if a.URL != "" && (a.Key == "" || a.Secret == "") {
+		return fmt.Errorf("%[1]s.url requires %[1]s.key and %[1]s.secret", prefix)
+	}
+	if a.Interval <= 0 {
+		return fmt.Errorf("%s.interval must be positive", prefix)
+	}
+	if a.Limit < 1 {
+		return fmt.Errorf("%s.limit must be at least 1", prefix)
+	}
+	return nil
+}
+
+// validate checks the profile values (prefix is the key of the profile object)
+func (p Profile) validate(prefix string) error {
+	sources := 0
+	for _, v := range []string{p.API.URL, p.Host, p.Path} {
+		if v != "" {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return fmt.Errorf("%s requires exactly one of api.url, host and path", prefix)
+	}
+	if _, err := parsePathTemplate(p.Path); err != nil {
+		return fmt.Errorf("%s.path: %w", prefix, err)
+	}
+	return p.API.validate(prefix + ".api")
+}
+
+// problems checks the config values and returns all problems found (nil if there are none)
+func (c *Config) problems() []error {
+	var problems []error
+	if c.Bruteforce.Threshold < 1 {
+		problems = append(problems, fmt.Errorf("bruteforce.threshold must be at least 1"))
+	}
+	if c.Bruteforce.Window <= 0 {
+		problems = append(problems, fmt.Errorf("bruteforce.window must be positive"))
+	}
+	if err := c.API.validate("api"); err != nil {
+		problems = append(problems, err)
+	}
+	if (c.Auth.Username == "") != (c.Auth.Password == "") {
+		problems = append(problems, fmt.Errorf("auth.username and auth.password must be set together"))
+	}
+	if c.Path == "" {
+		problems = append(problems, fmt.Errorf("path must not be empty"))
+	} else if _, err := parsePathTemplate(c.Path); err != nil {
+		problems = append(problems, fmt.Errorf("path: %w", err))
+	}
+	for _, name := range c.ProfileNames() {
+		if err := c.Profiles[name].validate("profiles." + name); err != nil {
+			problems = append(problems, err)
+		}
+	}
+	for i, network := range c.Networks {
+		if _, err := netip.ParsePrefix(network); err != nil {
+			problems = append(problems, fmt.Errorf("networks[%d] must be a network in CIDR notation (e.g. 192.0.2.0/24): %w", i, err))
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.Interfaces)) {
+		if name == "" || strings.ContainsAny(name, " ()\"\\") || filter.IsKeyword(name) {
+			problems = append(problems, fmt.Errorf("interfaces.%s must be a single word that is not an operator, field name or shorthand", name))
+		}
+		if len(c.Interfaces[name]) == 0 || slices.Contains(c.Interfaces[name], "") {
+			problems = append(problems, fmt.Errorf("interfaces.%s must be a list of interfaces (e.g. [\"igb0\"])", name))
+		}
+	}
+	for i, rule := range c.Severity {
+		if stream.SeverityLevel(rule.Level) < 0 {
+			problems = append(problems, fmt.Errorf("severity[%d].level must be one of %v", i, stream.Severities))
+		}
+		if rule.Filter == "" {
+			problems = append(problems, fmt.Errorf("severity[%d].filter must not be empty", i))
+		}
+		if _, err := filter.CompileWith(rule.Filter, c.FilterOptions()); err != nil {
+			problems = append(problems, fmt.Errorf("severity[%d].filter: %w", i, err))
+		}
+	}
+	return problems
+}
+
+// validate checks the config values and returns the first problem found
+func (c *Config) validate() error {
+	if problems := c.problems(); len(problems) > 0 {
+		return problems[0]
+	}
+	return nil
+}
+
+// parse reads and parses the config file at the given path without checking its values
+func parse(path string) (*Config, error) {
+	data, err := sandbox.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error(config): %w", err)
+	}
+	cfg := New()
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("error(config): could not parse %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// DefaultPath returns the default config file path (empty if it can't be determined)
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, dirName, fileName)
+}
+
+// FilterOptions returns the options filters are compiled with (interfaces and strict_filter)
+func (c *Config) FilterOptions() filter.Options {
+	return filter.Options{Interfaces: c.Interfaces, Strict: c.StrictFilter}
+}
+
+// GetProfile returns the profile with the given name
+func (c *Config) GetProfile(name string) (Profile, error) {
+	p, ok := c.Profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("error(config): unknown profile %q", name)
+	}
+	return p, nil
+}
+
+// New returns a config with default values
+func New() *Config {
+	return &Config{
+		API: API{
+			Interval: defaultAPIInterval,
+			Limit:    defaultAPILimit,
+		},
+		Bruteforce: Bruteforce{
+			Threshold: defaultBruteforceThreshold,
+			Window:    defaultBruteforceWindow,
+		},
+		Open: defaultOpen,
+		Path: defaultPath,
+	}
+}
+
+// Check reads and parses the config file at the given path like Load, but returns all problems of its values instead
+// of the first (the config is nil if the file can't be read or parsed)
+func Check(path string) (*Config, []error) {
+	cfg, err := parse(path)
+	if err != nil {
+		return nil, []error{err}
+	}
+	return cfg, cfg.problems()
+}
+
+// Load reads and parses the config file at the given path
+func Load(path string) (*Config, error) {
+	cfg, err := parse(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("error(config): invalid %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// LoadDefault reads the config file from the default path and falls back to defaults if it does not exist
+func LoadDefault() (*Config, error) {
+	path := DefaultPath()
+	if path == "" {
+		return New(), nil
+	}
+	cfg, err := Load(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return New(), nil
+	}
+	return cfg, err
+}
+
+// Save checks the config values and writes the config to path (readable by the owner only, it may contain credentials)
+func (c *Config) Save(path string) error {
+	if err := c.validate(); err != nil {
+		return fmt.Errorf("error(config): %w", err)
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error(config): %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("error(config): %w", err)
+	}
+	// write to a temporary file first so an existing config is never left half-written
+	file, err := os.CreateTemp(filepath.Dir(path), ".config-*")
+	if err != nil {
+		return fmt.Errorf("error(config): %w", err)
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return fmt.Errorf("error(config): %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("error(config): %w", err)
+	}
+	if err := os.Rename(file.Name(), path); err != nil {
+		return fmt.Errorf("error(config): %w", err)
+	}
+	return nil
+}
+
+// ProfileNames returns the names of all profiles in sorted order
+func (c *Config) ProfileNames() []string {
+	return slices.Sorted(maps.Keys(c.Profiles))
+}
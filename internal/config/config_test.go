@@ -0,0 +1,316 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		expectOpen  string
+		expectError bool
+	}{
+		{
+			name:       "empty object keeps defaults",
+			content:    `{}`,
+			expectOpen: defaultOpen,
+		},
+		{
+			name:       "override open command",
+			content:    `{"open": "xdg-open https://ipinfo.io/{src}"}`,
+			expectOpen: "xdg-open https://ipinfo.io/{src}",
+		},
+		{
+			name:       "bruteforce settings",
+			content:    `{"bruteforce": {"threshold": 5, "window": "10m"}}`,
+			expectOpen: defaultOpen,
+		},
+		{
+			name:        "invalid bruteforce window",
+			content:     `{"bruteforce": {"threshold": 5, "window": "10"}}`,
+			expectError: true,
+		},
+		{
+			name:        "invalid bruteforce threshold",
+			content:     `{"bruteforce": {"threshold": 0, "window": "10m"}}`,
+			expectError: true,
+		},
+		{
+			name:       "api settings",
+			content:    `{"api": {"url": "https://192.168.1.1", "key": "key", "secret": "secret", "interval": "30s"}}`,
+			expectOpen: defaultOpen,
+		},
+		{
+			name:        "api url without key",
+			content:     `{"api": {"url": "https://192.168.1.1"}}`,
+			expectError: true,
+		},
+		{
+			name:        "invalid api limit",
+			content:     `{"api": {"limit": 0}}`,
+			expectError: true,
+		},
+		{
+			name:       "auth settings",
+			content:    `{"auth": {"token": "secret", "username": "admin", "password": "pass"}}`,
+			expectOpen: defaultOpen,
+		},
+		{
+			name:        "auth username without password",
+			content:     `{"auth": {"username": "admin"}}`,
+			expectError: true,
+		},
+		{
+			name:       "profiles",
+			content:    `{"profiles": {"fw1": {"host": "root@fw1"}, "fw2": {"api": {"url": "https://fw2", "key": "key", "secret": "secret"}}}}`,
+			expectOpen: defaultOpen,
+		},
+		{
+			name:        "profile without source",
+			content:     `{"profiles": {"fw1": {}}}`,
+			expectError: true,
+		},
+		{
+			name:        "profile with multiple sources",
+			content:     `{"profiles": {"fw1": {"host": "fw1", "path": "/var/log/fw1.log"}}}`,
+			expectError: true,
+		},
+		{
+			name:        "profile api url without key",
+			content:     `{"profiles": {"fw1": {"api": {"url": "https://fw1"}}}}`,
+			expectError: true,
+		},
+		{
+			name:        "unknown profile key",
+			content:     `{"profiles": {"fw1": {"hots": "fw1"}}}`,
+			expectError: true,
+		},
+		{
+			name:       "path template",
+			content:    `{"path": "/var/log/filter/filter_%Y%m%d.log"}`,
+			expectOpen: defaultOpen,
+		},
+		{
+			name:        "path with unsupported verb",
+			content:     `{"path": "/var/log/filter/filter_%s.log"}`,
+			expectError: true,
+		},
+		{
+			name:        "empty path",
+			content:     `{"path": ""}`,
+			expectError: true,
+		},
+		{
+			name:       "networks",
+			content:    `{"networks": ["192.0.2.0/24", "2001:db8::/32"]}`,
+			expectOpen: defaultOpen,
+		},
+		{
+			name:        "network without prefix length",
+			content:     `{"networks": ["192.0.2.1"]}`,
+			expectError: true,
+		},
+		{
+			name:       "severity rules",
+			content:    `{"severity": [{"filter": "action block and dport 22", "level": "critical"}]}`,
+			expectOpen: defaultOpen,
+		},
+		{
+			name:        "unknown severity level",
+			content:     `{"severity": [{"filter": "action block", "level": "error"}]}`,
+			expectError: true,
+		},
+		{
+			name:        "invalid severity filter",
+			content:     `{"severity": [{"filter": "src and", "level": "warning"}]}`,
+			expectError: true,
+		},
+		{
+			name:        "unknown key",
+			content:     `{"opne": "whois {dst}"}`,
+			expectError: true,
+		},
+		{
+			name:       "implicit and in severity filter",
+			content:    `{"severity": [{"filter": "action block dport 22", "level": "critical"}]}`,
+			expectOpen: defaultOpen,
+		},
+		{
+			name:        "strict filter in severity filter",
+			content:     `{"severity": [{"filter": "action block dport 22", "level": "critical"}], "strict_filter": true}`,
+			expectError: true,
+		},
+		{
+			name:       "interfaces in severity filter",
+			content:    `{"interfaces": {"wan": ["igb0", "pppoe0"]}, "severity": [{"filter": "blocked inbound wan", "level": "warning"}]}`,
+			expectOpen: defaultOpen,
+		},
+		{
+			name:        "interface name is a field",
+			content:     `{"interfaces": {"src": ["igb0"]}}`,
+			expectError: true,
+		},
+		{
+			name:        "interface name is a shorthand",
+			content:     `{"interfaces": {"blocked": ["igb0"]}}`,
+			expectError: true,
+		},
+		{
+			name:        "interface name with space",
+			content:     `{"interfaces": {"my wan": ["igb0"]}}`,
+			expectError: true,
+		},
+		{
+			name:        "empty interface list",
+			content:     `{"interfaces": {"wan": []}}`,
+			expectError: true,
+		},
+		{
+			name:        "invalid json",
+			content:     `{"open": `,
+			expectError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := Load(writeConfig(t, tc.content))
+			if tc.expectError {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cfg.Open != tc.expectOpen {
+				t.Fatalf("expected open %q, got %q", tc.expectOpen, cfg.Open)
+			}
+		})
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	cfg, err := Load(writeConfig(t, `{"profiles": {"fw2": {"path": "/var/log/fw2.log"}, "fw1": {"api": {"url": "https://fw1", "key": "key", "secret": "secret"}}}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if names := cfg.ProfileNames(); len(names) != 2 || names[0] != "fw1" || names[1] != "fw2" {
+		t.Fatalf("expected sorted profile names, got %v", names)
+	}
+	p, err := cfg.GetProfile("fw1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.API.Interval != defaultAPIInterval || p.API.Limit != defaultAPILimit {
+		t.Fatalf("expected api defaults, got interval %v and limit %d", p.API.Interval, p.API.Limit)
+	}
+	if _, err := cfg.GetProfile("fw3"); err == nil {
+		t.Fatal("expected error for unknown profile, got nil")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name           string
+		content        string
+		expectConfig   bool
+		expectProblems int
+	}{
+		{name: "valid", content: `{"networks": ["192.0.2.0/24"]}`, expectConfig: true},
+		{
+			name:           "all problems",
+			content:        `{"networks": ["192.0.2.0"], "severity": [{"filter": "src and", "level": "urgent"}], "auth": {"username": "admin"}}`,
+			expectConfig:   true,
+			expectProblems: 4,
+		},
+		{name: "invalid json", content: `{"networks": `, expectProblems: 1},
+		{name: "unknown field", content: `{"highlight": []}`, expectProblems: 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, problems := Check(writeConfig(t, tc.content))
+			if (cfg != nil) != tc.expectConfig {
+				t.Fatalf("expected config %v, got %v", tc.expectConfig, cfg != nil)
+			}
+			if len(problems) != tc.expectProblems {
+				t.Fatalf("expected %d problems, got %v", tc.expectProblems, problems)
+			}
+		})
+	}
+}
+
+func TestSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "opnsense-filterlog", "config.json")
+	cfg := New()
+	cfg.Networks = []string{"192.0.2.0/24"}
+	cfg.Profiles = map[string]Profile{"fw1": {API: New().API, Host: "admin@fw1"}}
+	if err := cfg.Save(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Fatalf("expected mode 0600, got %v", info.Mode().Perm())
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if loaded.Networks[0] != "192.0.2.0/24" || loaded.Profiles["fw1"].Host != "admin@fw1" || loaded.Bruteforce != cfg.Bruteforce {
+		t.Fatalf("expected saved values, got %+v", loaded)
+	}
+
+	cfg.Networks = []string{"192.0.2.0"}
+	if err := cfg.Save(path); err == nil {
+		t.Fatal("expected error for invalid config, got nil")
+	}
+	if loaded, err := Load(path); err != nil || loaded.Networks[0] != "192.0.2.0/24" {
+		t.Fatalf("expected previous file to be kept, got %v, %v", loaded, err)
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Fatal("expected error loading missing file, got nil")
+	}
+}
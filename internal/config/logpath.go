@@ -0,0 +1,143 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// pathVerbs are the date verbs supported in log path templates (in order of significance) and their width
+var pathVerbs = []struct {
+	verb  byte
+	width int
+}{
+	{'Y', 4}, {'m', 2}, {'d', 2}, {'H', 2}, {'M', 2}, {'S', 2},
+}
+
+// pathTemplate is a parsed log path template
+type pathTemplate struct {
+	glob    string         // pattern matching candidate files
+	literal string         // path without verbs ('%%' unescaped)
+	pattern *regexp.Regexp // matches candidate files and captures verb values (nil if there are no verbs)
+	verbs   []byte         // verbs in order of capture groups
+}
+
+// parsePathTemplate parses a log path containing date verbs (%Y, %m, %d, %H, %M, %S and %% for a literal %)
+func parsePathTemplate(template string) (pathTemplate, error) {
+	var (
+		glob, literal, pattern strings.Builder
+		t                      pathTemplate
+	)
+	pattern.WriteString("^")
+	for i := 0; i < len(template); i++ {
+		c := template[i]
+		if c != '%' {
+			if strings.IndexByte(`*?[\`, c) >= 0 {
+				glob.WriteByte('\\')
+			}
+			glob.WriteByte(c)
+			literal.WriteByte(c)
+			pattern.WriteString(regexp.QuoteMeta(string(c)))
+			continue
+		}
+		if i++; i == len(template) {
+			return t, fmt.Errorf("%q ends with %%", template)
+		}
+		if template[i] == '%' {
+			glob.WriteByte('%')
+			literal.WriteByte('%')
+			pattern.WriteString("%")
+			continue
+		}
+		width := 0
+		for _, v := range pathVerbs {
+			if v.verb == template[i] {
+				width = v.width
+			}
+		}
+		if width == 0 {
+			return t, fmt.Errorf("%q contains unsupported verb %%%c (supported: %%Y, %%m, %%d, %%H, %%M, %%S)", template, template[i])
+		}
+		glob.WriteString(strings.Repeat("[0-9]", width))
+		fmt.Fprintf(&pattern, `(\d{%d})`, width)
+		t.verbs = append(t.verbs, template[i])
+	}
+	pattern.WriteString("$")
+	t.glob, t.literal = glob.String(), literal.String()
+	if len(t.verbs) > 0 {
+		t.pattern = regexp.MustCompile(pattern.String())
+	}
+	return t, nil
+}
+
+// key returns a sortable key of the date captured from path (empty if path does not match)
+func (t pathTemplate) key(path string) string {
+	values := t.pattern.FindStringSubmatch(path)
+	if values == nil {
+		return ""
+	}
+	var key strings.Builder
+	for _, v := range pathVerbs {
+		value := strings.Repeat("0", v.width)
+		for i, verb := range t.verbs {
+			if verb == v.verb {
+				value = values[i+1]
+				break
+			}
+		}
+		key.WriteString(value)
+	}
+	return key.String()
+}
+
+// public
+
+// ResolvePath returns the log file for the given path template, paths with date verbs (e.g. /var/log/filter/filter_%Y%m%d.log)
+// resolve to the matching file with the newest date
+func ResolvePath(template string) (string, error) {
+	t, err := parsePathTemplate(template)
+	if err != nil {
+		return "", fmt.Errorf("error(config): %w", err)
+	}
+	if t.pattern == nil {
+		return t.literal, nil
+	}
+	matches, err := filepath.Glob(t.glob)
+	if err != nil {
+		return "", fmt.Errorf("error(config): %w", err)
+	}
+	newest, newestKey := "", ""
+	for _, match := range matches {
+		if key := t.key(match); key > newestKey {
+			newest, newestKey = match, key
+		}
+	}
+	if newest == "" {
+		return "", fmt.Errorf("error(config): no file matches %s", template)
+	}
+	return newest, nil
+}
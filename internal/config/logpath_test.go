@@ -0,0 +1,106 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolvePath(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"filter_20251009.log",
+		"filter_20251010.log",
+		"filter_2025101.log",
+		"filter_latest.log",
+		"10-10-2025.log",
+		"09-11-2025.log",
+		"100%.log",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name        string
+		template    string
+		expect      string
+		expectError bool
+	}{
+		{
+			name:     "no verbs",
+			template: "/var/log/filter/latest.log",
+			expect:   "/var/log/filter/latest.log",
+		},
+		{
+			name:     "escaped percent",
+			template: filepath.Join(dir, "100%%.log"),
+			expect:   filepath.Join(dir, "100%.log"),
+		},
+		{
+			name:     "newest date",
+			template: filepath.Join(dir, "filter_%Y%m%d.log"),
+			expect:   filepath.Join(dir, "filter_20251010.log"),
+		},
+		{
+			name:     "date in other order",
+			template: filepath.Join(dir, "%d-%m-%Y.log"),
+			expect:   filepath.Join(dir, "09-11-2025.log"),
+		},
+		{
+			name:        "no matching file",
+			template:    filepath.Join(dir, "filter_%Y%m%d%H.log"),
+			expectError: true,
+		},
+		{
+			name:        "unsupported verb",
+			template:    filepath.Join(dir, "filter_%s.log"),
+			expectError: true,
+		},
+		{
+			name:        "trailing percent",
+			template:    filepath.Join(dir, "filter_%"),
+			expectError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, err := ResolvePath(tt.template)
+			if tt.expectError {
+				if err == nil {
+					t.Fatalf("expected error, got %q", path)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if path != tt.expect {
+				t.Errorf("expected %q, got %q", tt.expect, path)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package config
+
+import (
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/sandbox"
+)
+
+// WatchInterval is the interval in which watchers should be polled
+const WatchInterval = 2 * time.Second
+
+// Watcher detects changes of the config file by comparing its modification time and size
+type Watcher struct {
+	modTime time.Time // modification time of the file when last checked (zero if it did not exist)
+	path    string    // path of the config file
+	size    int64     // size of the file when last checked
+}
+
+// stat returns the modification time and size of the file (zero values if it does not exist)
+func (w *Watcher) stat() (time.Time, int64) {
+	info, err := sandbox.Stat(w.path)
+	if err != nil {
+		return time.Time{}, 0
+	}
+	return info.ModTime(), info.Size()
+}
+
+// public
+
+// NewWatcher creates a watcher of the config file at path (changes are detected from now on)
+func NewWatcher(path string) *Watcher {
+	w := &Watcher{path: path}
+	w.modTime, w.size = w.stat()
+	return w
+}
+
+// Changed returns true if the file has been written, created or removed since the last call
+func (w *Watcher) Changed() bool {
+	modTime, size := w.stat()
+	if modTime.Equal(w.modTime) && size == w.size {
+		return false
+	}
+	w.modTime, w.size = modTime, size
+	return true
+}
+
+// Path returns the path of the config file
+func (w *Watcher) Path() string {
+	return w.path
+}
@@ -0,0 +1,460 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/filter"
+	"gitlab.com/allddd/opnsense-filterlog/internal/netclass"
+	"gitlab.com/allddd/opnsense-filterlog/internal/report"
+	"gitlab.com/allddd/opnsense-filterlog/internal/sandbox"
+	"gitlab.com/allddd/opnsense-filterlog/internal/severity"
+	"gitlab.com/allddd/opnsense-filterlog/internal/sink"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+const (
+	// intervals
+	IntervalDaily  = "daily"
+	IntervalHourly = "hourly"
+
+	pollInterval    = time.Second
+	progressEntries = 10000       // entries after which progress is recorded while polling
+	reportDelay     = time.Minute // time to wait for late entries after the end of an interval
+)
+
+// Options configures the daemon
+type Options struct {
+	Classifier *severity.Classifier // assigns severity levels before filtering (nil disables classification)
+	Dir        string               // directory to write reports to (empty disables reports)
+	Filter     string               // filter expression
+	FilterOpts filter.Options       // options the filter expression is compiled with
+	Formats    []string             // report formats
+	Interval   string               // report interval (hourly or daily)
+	Networks   *netclass.Classifier // assigns address classes before filtering (built-in classes only if nil)
+	Sinks      []sink.Sink          // sinks to publish matching entries to
+	StateFile  string               // file to persist the position in (empty disables checkpointing)
+}
+
+// Source provides entries to the daemon (e.g. a log file follower or a syslog listener)
+type Source interface {
+	GetPathRel() string     // name of the source used in reports
+	Next() *stream.LogEntry // returns the next entry (nil if none is available yet)
+	TakeErrors() []string   // returns and clears parse errors
+}
+
+// errorReporter is implemented by sources that can report errors as they occur (e.g. a log file follower)
+type errorReporter interface {
+	SetErrorHandler(handler func(err error))
+}
+
+// dropper is implemented by sources that drop messages under load (e.g. a syslog listener)
+type dropper interface {
+	Dropped() int64
+}
+
+// lagger is implemented by sources that know how far they are behind (e.g. a log file follower)
+type lagger interface {
+	Lag() int64
+}
+
+// classifiers assign address classes and severity levels to entries
+type classifiers struct {
+	networks *netclass.Classifier // address classes (built-in classes only if nil)
+	severity *severity.Classifier // severity levels (nil disables classification)
+}
+
+// position is a checkpoint of the source and the number of entries read before it (orders checkpoints of different
+// files)
+type position struct {
+	checkpoint stream.Checkpoint
+	read       int64
+}
+
+// delivery tracks the entries written to a sink since it was last flushed and the entries it failed to deliver
+type delivery struct {
+	failed  bool               // whether the sink failed since it was last flushed
+	first   position           // position before the first entry written since the sink was last flushed
+	pending bool               // whether entries were written since
+	pinned  *position          // position before the first entry the sink failed to deliver (nil if none)
+	sent    *stream.Checkpoint // position up to which entries were published to the sink before a restart (nil if none)
+	skipped int64              // offset after the last entry skipped since it was published to the sink before
+}
+
+// Daemon follows a source, publishes matching entries to sinks and writes a report for each completed interval
+type Daemon struct {
+	before      position                    // position before the current entry
+	classifiers atomic.Pointer[classifiers] // classifiers of the options (replaced by Reload)
+	compiled    filter.FilterNode           // compiled filter expression
+	deliveries  []delivery                  // delivery of the entries to each sink (same order as the sinks)
+	dropped     int64                       // dropped messages already logged
+	errors      int                         // parse errors not yet attributed to a report
+	metrics     metrics                     // counters exposed by the metrics endpoint
+	opts        Options                     // daemon options
+	read        int64                       // entries read from the source
+	report      *report.Report              // report of the current interval (nil if none)
+	reportFirst position                    // position before the first entry of the current report
+	reportStart time.Time                   // start of the current (or last written) interval
+	reportEnd   time.Time                   // end of the current (or last written) interval
+	source      Source                      // entry source
+	state       State                       // last saved state
+}
+
+// interval
+
+// intervalStart returns the start of the interval containing t (in the time zone of t)
+func (d *Daemon) intervalStart(t time.Time) time.Time {
+	if d.opts.Interval == IntervalDaily {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+}
+
+// intervalEnd returns the end of the interval starting at start
+func (d *Daemon) intervalEnd(start time.Time) time.Time {
+	if d.opts.Interval == IntervalDaily {
+		return start.AddDate(0, 0, 1)
+	}
+	return start.Add(time.Hour)
+}
+
+// reportName returns the file name (without extension) of the report starting at start
+func (d *Daemon) reportName(start time.Time) string {
+	if d.opts.Interval == IntervalDaily {
+		return "report-" + start.Format(time.DateOnly)
+	}
+	return "report-" + start.Format("2006-01-02T15")
+}
+
+// reporting
+
+// handle publishes an entry to all sinks (if it matches the filter and hasn't been published to the sink before a
+// restart) and adds it to the current report
+func (d *Daemon) handle(entry *stream.LogEntry) {
+	d.metrics.entries.Add(1)
+	d.metrics.lastEntry.Store(max(d.metrics.lastEntry.Load(), entry.Time.Unix()))
+	c := d.classifiers.Load()
+	c.networks.Classify(entry)
+	c.severity.Classify(entry)
+	matches := d.compiled == nil || d.compiled.Matches(entry)
+	if matches {
+		d.metrics.matched.Add(1)
+	}
+	if matches {
+		for i, s := range d.opts.Sinks {
+			dl := &d.deliveries[i]
+			if d.published(dl) {
+				continue
+			}
+			if !dl.pending {
+				dl.first, dl.pending = d.before, true
+			}
+			if err := s.Write(entry); err != nil {
+				d.sinkFailed(i, err)
+			}
+		}
+	}
+	if d.opts.Dir != "" {
+		d.add(entry, matches)
+	}
+}
+
+// add adds an entry to the report of its interval (writing the previous report if the interval changed)
+func (d *Daemon) add(entry *stream.LogEntry, matches bool) {
+	start := d.intervalStart(entry.Time)
+	if start.Before(d.reportStart) || (d.report == nil && start.Before(d.reportEnd)) {
+		// late entry of an interval that has already been written
+		return
+	}
+	if d.report != nil && start.After(d.reportStart) {
+		d.flush()
+	}
+	if d.report == nil {
+		d.report = report.New(d.source.GetPathRel(), d.opts.Filter)
+		d.reportFirst = d.before
+		d.reportStart = start
+		d.reportEnd = d.intervalEnd(start)
+		d.report.From, d.report.To = d.reportStart, d.reportEnd
+	}
+	if matches {
+		d.report.Add(entry)
+	}
+}
+
+// flush writes the report of the current interval in all formats
+func (d *Daemon) flush() {
+	if d.report == nil {
+		return
+	}
+	d.report.Errors += d.errors
+	d.errors = 0
+	for _, format := range d.opts.Formats {
+		path := filepath.Join(d.opts.Dir, d.reportName(d.reportStart)+report.Extension(format))
+		if err := writeReport(d.report, format, path); err != nil {
+			log.Println(err)
+			continue
+		}
+		d.metrics.reports.Add(1)
+		log.Printf("daemon: wrote report %s", path)
+	}
+	d.report = nil
+}
+
+// writeReport writes the report to a temporary file and renames it to path (so readers never see partial reports)
+func writeReport(r *report.Report, format string, path string) error {
+	file, err := sandbox.CreateTemp(filepath.Dir(path), ".report-*")
+	if err != nil {
+		return fmt.Errorf("error(daemon): could not create report: %w", err)
+	}
+	defer sandbox.Remove(file.Name())
+	if err := r.Write(file, format); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("error(daemon): could not write report: %w", err)
+	}
+	if err := sandbox.Rename(file.Name(), path); err != nil {
+		return fmt.Errorf("error(daemon): could not write report: %w", err)
+	}
+	return nil
+}
+
+// sinkFailed logs and counts an error of a sink, the sink is pinned before the entries written to it since it was last
+// flushed so they are published to it again after a restart (unless it spooled or rejected them, entries the sink
+// retries itself are only logged once the retries failed)
+func (d *Daemon) sinkFailed(i int, err error) {
+	retrying := sink.Retrying(err)
+	if !retrying {
+		d.metrics.sinkErrors.Add(1)
+		log.Println(err)
+	}
+	dl := &d.deliveries[i]
+	if sink.Spooled(err) {
+		// the spool accepted the entries
+		dl.pinned = nil
+		return
+	}
+	if !dl.pending || sink.Rejected(err) {
+		return
+	}
+	dl.failed = true
+	if dl.pinned != nil {
+		return
+	}
+	if !retrying && d.opts.StateFile != "" {
+		log.Printf("daemon: %s failed, entries from line %d on are published to it again after a restart",
+			d.opts.Sinks[i], dl.first.checkpoint.LineNum+1)
+	}
+	first := dl.first
+	dl.pinned = &first
+}
+
+// sinkFlushed records the result of flushing a sink, its pin is cleared once it flushed successfully without failing
+// since the last flush
+func (d *Daemon) sinkFlushed(i int, err error) {
+	dl := &d.deliveries[i]
+	if err != nil {
+		d.sinkFailed(i, err)
+	} else if dl.pinned != nil && !dl.failed {
+		if d.opts.StateFile != "" {
+			log.Printf("daemon: %s recovered", d.opts.Sinks[i])
+		}
+		dl.pinned = nil
+	}
+	// entries the sink retries on the next flush are still undelivered
+	if !sink.Retrying(err) {
+		dl.failed, dl.pending = false, false
+	}
+}
+
+// logError logs and counts a parse error of the source
+func (d *Daemon) logError(msg string) {
+	log.Printf("daemon: %s", msg)
+	d.metrics.errors.Add(1)
+	d.errors++
+}
+
+// takeErrors logs and counts parse errors collected by the source
+func (d *Daemon) takeErrors() {
+	for _, err := range d.source.TakeErrors() {
+		d.logError(err)
+	}
+}
+
+// poll processes all entries appended since the last poll and writes the current report once its interval is over
+func (d *Daemon) poll(now time.Time) {
+	d.metrics.alive.Store(now.Unix())
+	for n := 1; ; n++ {
+		if n%progressEntries == 0 {
+			// catching up with a large file is progress as well
+			d.metrics.alive.Store(time.Now().Unix())
+		}
+		d.before = position{checkpoint: d.checkpoint(), read: d.read}
+		entry := d.source.Next()
+		if entry == nil {
+			break
+		}
+		d.read++
+		d.takeErrors() // attribute errors before entry to the current interval
+		d.handle(entry)
+	}
+	d.takeErrors()
+	if lagger, ok := d.source.(lagger); ok {
+		d.metrics.lag.Store(lagger.Lag())
+	}
+	if dropper, ok := d.source.(dropper); ok {
+		if dropped := dropper.Dropped(); dropped > d.dropped {
+			log.Printf("daemon: source dropped %d messages", dropped-d.dropped)
+			d.dropped = dropped
+		}
+	}
+	for i, s := range d.opts.Sinks {
+		d.sinkFlushed(i, s.Flush())
+	}
+	if d.report != nil && !now.Before(d.reportEnd.Add(reportDelay)) {
+		d.flush()
+	}
+	d.saveState()
+}
+
+// published returns true if the entry just read was published to the sink of dl before a restart, the sent position
+// is dropped once the source read past it, moved to another file or the file was truncated (entries read since are
+// new)
+func (d *Daemon) published(dl *delivery) bool {
+	if dl.sent == nil {
+		return false
+	}
+	cp := d.checkpoint()
+	if !cp.SameFile(*dl.sent) || d.before.checkpoint.Offset < dl.skipped || cp.Offset > dl.sent.Offset {
+		dl.sent = nil
+		return false
+	}
+	dl.skipped = cp.Offset
+	return true
+}
+
+// checkpoint returns the position of the source (zero if the source can't report it)
+func (d *Daemon) checkpoint() stream.Checkpoint {
+	if c, ok := d.source.(checkpointer); ok {
+		return c.Checkpoint()
+	}
+	return stream.Checkpoint{}
+}
+
+// public
+
+// New creates a new daemon reading from the given source
+func New(source Source, opts Options) (*Daemon, error) {
+	if opts.Interval != IntervalHourly && opts.Interval != IntervalDaily {
+		return nil, fmt.Errorf("error(daemon): unknown interval %q (available: %s, %s)", opts.Interval, IntervalHourly, IntervalDaily)
+	}
+	if opts.Dir == "" && len(opts.Sinks) == 0 {
+		return nil, fmt.Errorf("error(daemon): no report directory or sink")
+	}
+	if opts.Dir != "" {
+		if len(opts.Formats) == 0 {
+			return nil, fmt.Errorf("error(daemon): no report format")
+		}
+		for _, format := range opts.Formats {
+			if err := report.CheckFormat(format); err != nil {
+				return nil, err
+			}
+		}
+		if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+			return nil, fmt.Errorf("error(daemon): could not create report directory: %w", err)
+		}
+	}
+	compiled, err := filter.CompileWith(opts.Filter, opts.FilterOpts)
+	if err != nil {
+		return nil, err
+	}
+	deliveries := make([]delivery, len(opts.Sinks))
+	if opts.StateFile != "" {
+		c, ok := source.(checkpointer)
+		if !ok {
+			return nil, fmt.Errorf("error(daemon): state file requires a log file source")
+		}
+		st, err := LoadState(opts.StateFile)
+		if err != nil {
+			return nil, err
+		}
+		// entries up to the sent position are only skipped if the source resumed at the saved position, sinks that
+		// failed get the entries from their pinned position on again
+		if cp := c.Checkpoint(); st != nil && cp.SameFile(st.Resume) && cp.Offset == st.Resume.Offset {
+			for i, s := range opts.Sinks {
+				sent, ok := st.Pinned[s.String()]
+				if !ok {
+					sent = st.Sent
+				}
+				deliveries[i].sent, deliveries[i].skipped = &sent, cp.Offset
+			}
+		}
+	}
+	d := &Daemon{
+		compiled:   compiled,
+		deliveries: deliveries,
+		opts:       opts,
+		source:     source,
+	}
+	d.classifiers.Store(&classifiers{networks: opts.Networks, severity: opts.Classifier})
+	if dropper, ok := source.(dropper); ok {
+		d.metrics.dropped = dropper.Dropped
+	}
+	_, d.metrics.lagged = source.(lagger)
+	if reporter, ok := source.(errorReporter); ok {
+		// errors are reported while reading the entry, so they are attributed to the current interval
+		reporter.SetErrorHandler(func(err error) {
+			d.logError(err.Error())
+		})
+	}
+	return d, nil
+}
+
+// Reload replaces the classifiers of the options, entries handled afterwards are classified by them (safe to call
+// while Run is running)
+func (d *Daemon) Reload(classifier *severity.Classifier, networks *netclass.Classifier) {
+	d.classifiers.Store(&classifiers{networks: networks, severity: classifier})
+}
+
+// Run reads entries from the source until ctx is done (the report of the incomplete interval is discarded)
+func (d *Daemon) Run(ctx context.Context) error {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		d.poll(time.Now())
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
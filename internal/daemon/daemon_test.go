@@ -0,0 +1,271 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/config"
+	"gitlab.com/allddd/opnsense-filterlog/internal/filter"
+	"gitlab.com/allddd/opnsense-filterlog/internal/netclass"
+	"gitlab.com/allddd/opnsense-filterlog/internal/report"
+	"gitlab.com/allddd/opnsense-filterlog/internal/severity"
+	"gitlab.com/allddd/opnsense-filterlog/internal/sink"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+// logLine returns a blocked ssh attempt logged at the given time
+func logLine(timestamp string) string {
+	return fmt.Sprintf("<134>1 %s opnsense.filter.log filterlog 86605 - [meta sequenceId=\"1\"] "+
+		"68,,,2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e,eth1,match,block,in,4,0x0,,64,0,0,DF,6,tcp,60,203.0.113.10,198.51.100.1,51000,22,0\n", timestamp)
+}
+
+func TestDaemon(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "filter.log")
+	lines := logLine("2025-10-10T10:05:00+02:00") + logLine("2025-10-10T10:30:00+02:00") + "invalid\n" + logLine("2025-10-10T11:10:00+02:00")
+	if err := os.WriteFile(path, []byte(lines), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := stream.NewFollower(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	reports := filepath.Join(dir, "reports")
+	d, err := New(f, Options{Dir: reports, Formats: []string{report.FormatJSON, report.FormatMarkdown}, Interval: IntervalHourly})
+	if err != nil {
+		t.Fatal(err)
+	}
+	loc := time.FixedZone("", 2*60*60)
+	readSummary := func(name string) report.Summary {
+		data, err := os.ReadFile(filepath.Join(reports, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var s report.Summary
+		if err := json.Unmarshal(data, &s); err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	// first interval is written once an entry of the next interval arrives
+	d.poll(time.Date(2025, 10, 10, 11, 15, 0, 0, loc))
+	s := readSummary("report-2025-10-10T10.json")
+	if s.Entries != 2 || s.Errors != 1 {
+		t.Fatalf("expected 2 entries and 1 error, got %d and %d", s.Entries, s.Errors)
+	}
+	if _, err := os.Stat(filepath.Join(reports, "report-2025-10-10T10.md")); err != nil {
+		t.Fatalf("expected markdown report: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(reports, "report-2025-10-10T11.json")); err == nil {
+		t.Fatal("expected incomplete interval not to be written")
+	}
+
+	// second interval is written once it's over (after the delay for late entries)
+	d.poll(time.Date(2025, 10, 10, 12, 0, 30, 0, loc))
+	if _, err := os.Stat(filepath.Join(reports, "report-2025-10-10T11.json")); err == nil {
+		t.Fatal("expected interval not to be written before the delay")
+	}
+	d.poll(time.Date(2025, 10, 10, 12, 1, 0, 0, loc))
+	if s := readSummary("report-2025-10-10T11.json"); s.Entries != 1 || s.Errors != 0 {
+		t.Fatalf("expected 1 entry and 0 errors, got %d and %d", s.Entries, s.Errors)
+	}
+
+	// late entries of written intervals are ignored
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(logLine("2025-10-10T11:59:00+02:00"))
+	file.Close()
+	d.poll(time.Date(2025, 10, 10, 12, 2, 0, 0, loc))
+	if d.report != nil {
+		t.Fatal("expected late entry to be ignored")
+	}
+	entries, err := os.ReadDir(reports)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("expected 4 reports, got %d", len(entries))
+	}
+}
+
+// testSink records all entries it receives
+type testSink struct {
+	entries []stream.LogEntry
+}
+
+func (s *testSink) Write(entry *stream.LogEntry) error {
+	s.entries = append(s.entries, *entry)
+	return nil
+}
+
+func (s *testSink) Flush() error   { return nil }
+func (s *testSink) Close() error   { return nil }
+func (s *testSink) String() string { return "test" }
+
+func TestDaemonSinks(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "filter.log")
+	if err := os.WriteFile(path, []byte(logLine("2025-10-10T10:05:00+02:00")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := stream.NewFollower(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := New(f, Options{Interval: IntervalHourly}); err == nil {
+		t.Fatal("expected error without report directory and sinks, got nil")
+	}
+	matching, other := &testSink{}, &testSink{}
+	d, err := New(f, Options{Filter: "dport 22", Interval: IntervalHourly, Sinks: []sink.Sink{matching}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.poll(time.Now())
+	if len(matching.entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(matching.entries))
+	}
+	if d.report != nil {
+		t.Fatal("expected no report without report directory")
+	}
+
+	// entries not matching the filter are not published
+	f2, err := stream.NewFollower(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f2.Close()
+	d, err = New(f2, Options{Filter: "dport 443", Interval: IntervalHourly, Sinks: []sink.Sink{other}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.poll(time.Now())
+	if len(other.entries) != 0 {
+		t.Fatalf("expected 0 entries, got %d", len(other.entries))
+	}
+}
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "filter.log")
+	if err := os.WriteFile(path, []byte(logLine("2025-10-10T10:05:00+02:00")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := stream.NewFollower(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	s := &testSink{}
+	d, err := New(f, Options{Filter: "severity critical or srcclass mine", Interval: IntervalHourly, Sinks: []sink.Sink{s}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.poll(time.Now())
+	if len(s.entries) != 0 {
+		t.Fatalf("expected 0 entries before reload, got %d", len(s.entries))
+	}
+
+	c, err := severity.New([]config.SeverityRule{{Filter: "dport 22", Level: stream.SeverityCritical}}, filter.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := netclass.New([]string{"203.0.113.0/24"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Reload(c, n)
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(logLine("2025-10-10T10:06:00+02:00"))
+	file.Close()
+	d.poll(time.Now())
+	if len(s.entries) != 1 {
+		t.Fatalf("expected 1 entry after reload, got %d", len(s.entries))
+	}
+	if entry := s.entries[0]; entry.Severity != stream.SeverityCritical || entry.SrcClass != netclass.ClassMine {
+		t.Fatalf("expected critical entry from mine, got %q from %q", entry.Severity, entry.SrcClass)
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        Options
+		expectError string
+	}{
+		{
+			name: "valid",
+			opts: Options{Formats: []string{report.FormatHTML}, Interval: IntervalDaily},
+		},
+		{
+			name:        "unknown interval",
+			opts:        Options{Formats: []string{report.FormatHTML}, Interval: "weekly"},
+			expectError: "unknown interval",
+		},
+		{
+			name:        "unknown format",
+			opts:        Options{Formats: []string{"pdf"}, Interval: IntervalHourly},
+			expectError: "unknown format",
+		},
+		{
+			name:        "no format",
+			opts:        Options{Interval: IntervalHourly},
+			expectError: "no report format",
+		},
+		{
+			name:        "invalid filter",
+			opts:        Options{Filter: "src and", Formats: []string{report.FormatHTML}, Interval: IntervalHourly},
+			expectError: "error(filter)",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.opts.Dir = t.TempDir()
+			_, err := New(nil, tc.opts)
+			if tc.expectError == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.expectError) {
+				t.Fatalf("expected error containing %q, got %v", tc.expectError, err)
+			}
+		})
+	}
+}
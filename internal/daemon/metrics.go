@@ -0,0 +1,111 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package daemon
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// metric types
+	metricCounter = "counter"
+	metricGauge   = "gauge"
+
+	// healthTimeout is the time without progress after which the daemon is reported unhealthy
+	healthTimeout = 30 * time.Second
+)
+
+// metrics represents the counters of the daemon (safe for concurrent use)
+type metrics struct {
+	alive      atomic.Int64 // unix time the daemon last made progress (0 before the first poll)
+	dropped    func() int64 // messages dropped by the source (nil if the source never drops)
+	entries    atomic.Int64 // entries read from the source
+	errors     atomic.Int64 // parse errors
+	lag        atomic.Int64 // bytes of the log file not yet read
+	lagged     bool         // whether the source reports its lag
+	lastEntry  atomic.Int64 // unix time of the newest entry (0 if none)
+	matched    atomic.Int64 // entries matching the filter
+	reports    atomic.Int64 // reports written
+	sinkErrors atomic.Int64 // failed sends and flushes
+}
+
+// metric represents a single prometheus metric
+type metric struct {
+	name  string // metric name
+	kind  string // metric type (counter or gauge)
+	help  string // metric description
+	value int64  // current value
+}
+
+// ServeHTTP writes the metrics in the prometheus text format
+func (m *metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	entries, errors := m.entries.Load(), m.errors.Load()
+	metrics := []metric{
+		{"filterlog_entries_total", metricCounter, "Entries read from the source.", entries},
+		{"filterlog_lines_total", metricCounter, "Lines read from the source (entries and parse errors).", entries + errors},
+		{"filterlog_matched_total", metricCounter, "Entries matching the filter.", m.matched.Load()},
+		{"filterlog_parse_errors_total", metricCounter, "Lines that could not be parsed.", errors},
+		{"filterlog_reports_total", metricCounter, "Reports written.", m.reports.Load()},
+		{"filterlog_sink_errors_total", metricCounter, "Failed sends and flushes of sinks.", m.sinkErrors.Load()},
+		{"filterlog_last_entry_timestamp_seconds", metricGauge, "Time of the newest entry.", m.lastEntry.Load()},
+		{"filterlog_last_progress_timestamp_seconds", metricGauge, "Time the daemon last polled the source.", m.alive.Load()},
+	}
+	if m.dropped != nil {
+		metrics = append(metrics, metric{"filterlog_dropped_total", metricCounter, "Messages dropped by the source (queue full or rate exceeded).", m.dropped()})
+	}
+	if m.lagged {
+		metrics = append(metrics, metric{"filterlog_lag_bytes", metricGauge, "Bytes of the log file not yet read.", m.lag.Load()})
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	for _, c := range metrics {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %d\n", c.name, c.help, c.name, c.kind, c.name, c.value)
+	}
+}
+
+// public
+
+// Health returns a handler responding with 200 while the daemon makes progress
+// (503 if it hasn't polled the source within healthTimeout)
+func (d *Daemon) Health() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		alive := d.metrics.alive.Load()
+		if alive == 0 {
+			http.Error(w, "starting", http.StatusServiceUnavailable)
+			return
+		}
+		if since := time.Since(time.Unix(alive, 0)); since > healthTimeout {
+			http.Error(w, fmt.Sprintf("stalled: no progress for %s", since.Truncate(time.Second)), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+}
+
+// Metrics returns a handler exposing the metrics of the daemon in the prometheus text format
+func (d *Daemon) Metrics() http.Handler {
+	return &d.metrics
+}
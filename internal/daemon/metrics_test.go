@@ -0,0 +1,138 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package daemon
+
+import (
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/sink"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+func TestMetrics(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "filter.log")
+	lines := logLine("2025-10-10T10:05:00+02:00") + "invalid\n" + logLine("2025-10-10T10:06:00+02:00")
+	if err := os.WriteFile(path, []byte(lines), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := stream.NewFollower(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	d, err := New(f, Options{Filter: "dport 22", Interval: IntervalHourly, Sinks: []sink.Sink{&testSink{}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.poll(time.Now())
+
+	rec := httptest.NewRecorder()
+	d.Metrics().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	for _, expected := range []string{
+		"# TYPE filterlog_entries_total counter\n",
+		"filterlog_entries_total 2\n",
+		"filterlog_matched_total 2\n",
+		"filterlog_parse_errors_total 1\n",
+		"filterlog_reports_total 0\n",
+		"filterlog_lines_total 3\n",
+		"# TYPE filterlog_lag_bytes gauge\n",
+		"filterlog_lag_bytes 0\n",
+	} {
+		if !strings.Contains(string(body), expected) {
+			t.Errorf("expected metrics to contain %q, got:\n%s", expected, body)
+		}
+	}
+}
+
+// dropSource is a follower that reports dropped messages
+type dropSource struct {
+	*stream.Follower
+}
+
+func (s dropSource) Dropped() int64 { return 5 }
+
+func TestMetricsDropped(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "filter.log")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := stream.NewFollower(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	d, err := New(dropSource{f}, Options{Interval: IntervalHourly, Sinks: []sink.Sink{&testSink{}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.poll(time.Now())
+	if d.dropped != 5 {
+		t.Fatalf("expected 5 logged drops, got %d", d.dropped)
+	}
+	rec := httptest.NewRecorder()
+	d.Metrics().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if !strings.Contains(rec.Body.String(), "filterlog_dropped_total 5\n") {
+		t.Fatalf("expected dropped counter, got:\n%s", rec.Body.String())
+	}
+}
+
+func TestHealth(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "filter.log")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := stream.NewFollower(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	d, err := New(f, Options{Interval: IntervalHourly, Sinks: []sink.Sink{&testSink{}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	status := func() int {
+		rec := httptest.NewRecorder()
+		d.Health().ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+		return rec.Code
+	}
+	if code := status(); code != 503 {
+		t.Fatalf("expected 503 before the first poll, got %d", code)
+	}
+	d.poll(time.Now())
+	if code := status(); code != 200 {
+		t.Fatalf("expected 200 after poll, got %d", code)
+	}
+	d.poll(time.Now().Add(-2 * healthTimeout))
+	if code := status(); code != 503 {
+		t.Fatalf("expected 503 without progress, got %d", code)
+	}
+}
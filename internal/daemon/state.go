@@ -0,0 +1,137 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/sandbox"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+// checkpointer is implemented by sources that can report their position (e.g. a log file follower)
+type checkpointer interface {
+	Checkpoint() stream.Checkpoint
+}
+
+// State represents the position of the daemon in the log file (persisted in the state file)
+type State struct {
+	Pinned map[string]stream.Checkpoint `json:"pinned,omitempty"` // position before the first entry each failed sink didn't deliver (by sink)
+	Resume stream.Checkpoint            `json:"resume"`           // position to resume reading at (start of the unwritten report or first pinned entry)
+	Sent   stream.Checkpoint            `json:"sent"`             // position up to which entries have been published to the other sinks
+}
+
+// equal returns true if both states are the same
+func (s State) equal(other State) bool {
+	return s.Resume == other.Resume && s.Sent == other.Sent && maps.Equal(s.Pinned, other.Pinned)
+}
+
+// saveState writes the state atomically (if a state file is configured and the state has changed), while a sink
+// fails to deliver entries the state stays before them so they are published to it again after a restart
+func (d *Daemon) saveState() {
+	if d.opts.StateFile == "" {
+		return
+	}
+	current := d.checkpoint()
+	st := State{Resume: current, Sent: current}
+	var resume *position
+	if d.report != nil {
+		resume = &d.reportFirst
+	}
+	for i, dl := range d.deliveries {
+		if dl.pinned == nil {
+			continue
+		}
+		if st.Pinned == nil {
+			st.Pinned = make(map[string]stream.Checkpoint)
+		}
+		st.Pinned[d.opts.Sinks[i].String()] = dl.pinned.checkpoint
+		if resume == nil || dl.pinned.read < resume.read {
+			resume = dl.pinned
+		}
+	}
+	if resume != nil {
+		st.Resume = resume.checkpoint
+	}
+	if st.equal(d.state) {
+		return
+	}
+	if err := WriteState(d.opts.StateFile, st); err != nil {
+		log.Println(err)
+		return
+	}
+	d.state = st
+}
+
+// public
+
+// LoadState reads the state file (returns nil if it doesn't exist yet)
+func LoadState(path string) (*State, error) {
+	data, err := sandbox.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error(daemon): could not read state: %w", err)
+	}
+	var st State
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, fmt.Errorf("error(daemon): invalid state file %s: %w", path, err)
+	}
+	return &st, nil
+}
+
+// WriteState writes the state to a temporary file and renames it to path
+func WriteState(path string, st State) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return fmt.Errorf("error(daemon): could not encode state: %w", err)
+	}
+	file, err := sandbox.CreateTemp(filepath.Dir(path), ".state-*")
+	if err != nil {
+		return fmt.Errorf("error(daemon): could not write state: %w", err)
+	}
+	defer sandbox.Remove(file.Name())
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return fmt.Errorf("error(daemon): could not write state: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("error(daemon): could not write state: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("error(daemon): could not write state: %w", err)
+	}
+	if err := sandbox.Rename(file.Name(), path); err != nil {
+		return fmt.Errorf("error(daemon): could not write state: %w", err)
+	}
+	return nil
+}
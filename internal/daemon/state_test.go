@@ -0,0 +1,287 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package daemon
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/report"
+	"gitlab.com/allddd/opnsense-filterlog/internal/sink"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+func TestState(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "filter.log")
+	statePath := filepath.Join(dir, "state.json")
+	reports := filepath.Join(dir, "reports")
+	lines := logLine("2025-10-10T10:05:00+02:00") + logLine("2025-10-10T10:30:00+02:00")
+	if err := os.WriteFile(path, []byte(lines), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	loc := time.FixedZone("", 2*60*60)
+	opts := Options{Dir: reports, Formats: []string{report.FormatJSON}, Interval: IntervalHourly, StateFile: statePath}
+	// start runs the daemon like the cli does (resuming at the saved state if any)
+	start := func(s sink.Sink) (*Daemon, *stream.Follower) {
+		st, err := LoadState(statePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var f *stream.Follower
+		if st != nil {
+			f, err = stream.NewFollowerAt(path, st.Resume)
+		} else {
+			f, err = stream.NewFollower(path, true)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts.Sinks = []sink.Sink{s}
+		d, err := New(f, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d, f
+	}
+
+	first := &testSink{}
+	d, f := start(first)
+	d.poll(time.Date(2025, 10, 10, 10, 45, 0, 0, loc))
+	f.Close()
+	if len(first.entries) != 2 {
+		t.Fatalf("expected 2 published entries, got %d", len(first.entries))
+	}
+	st, err := LoadState(statePath)
+	if err != nil || st == nil {
+		t.Fatalf("expected state, got %v (%v)", st, err)
+	}
+	if st.Resume.Offset != 0 || st.Sent.Offset != int64(len(lines)) {
+		t.Fatalf("expected resume at start of the unwritten report and all entries sent, got %+v", st)
+	}
+
+	// after a restart the unwritten report is rebuilt without publishing entries twice
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(logLine("2025-10-10T11:10:00+02:00"))
+	file.Close()
+	second := &testSink{}
+	d, f = start(second)
+	defer f.Close()
+	d.poll(time.Date(2025, 10, 10, 11, 15, 0, 0, loc))
+	if len(second.entries) != 1 {
+		t.Fatalf("expected 1 published entry after restart, got %d", len(second.entries))
+	}
+	data, err := os.ReadFile(filepath.Join(reports, "report-2025-10-10T10.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"entries": 2`) {
+		t.Fatalf("expected rebuilt report with 2 entries, got:\n%s", data)
+	}
+}
+
+func TestStateReplaced(t *testing.T) {
+	loc := time.FixedZone("", 2*60*60)
+	now := time.Date(2025, 10, 10, 10, 45, 0, 0, loc)
+	tests := []struct {
+		name    string
+		replace func(t *testing.T, path string) // empties the file after the restart
+	}{
+		{
+			name: "rotated",
+			replace: func(t *testing.T, path string) {
+				if err := os.Rename(path, path+".0"); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, nil, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name: "truncated",
+			replace: func(t *testing.T, path string) {
+				if err := os.Truncate(path, 0); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "filter.log")
+			statePath := filepath.Join(dir, "state.json")
+			lines := logLine("2025-10-10T10:05:00+02:00") + logLine("2025-10-10T10:30:00+02:00")
+			if err := os.WriteFile(path, []byte(lines), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			opts := Options{Interval: IntervalHourly, StateFile: statePath, Sinks: []sink.Sink{&testSink{}}}
+			f, err := stream.NewFollower(path, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			d, err := New(f, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			d.poll(now)
+			f.Close()
+
+			// restart at the saved position, entries of the new content are not skipped even though they are
+			// before the sent offset of the previous file
+			st, err := LoadState(statePath)
+			if err != nil || st == nil {
+				t.Fatalf("expected state, got %v (%v)", st, err)
+			}
+			if f, err = stream.NewFollowerAt(path, st.Resume); err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			s := &testSink{}
+			opts.Sinks = []sink.Sink{s}
+			if d, err = New(f, opts); err != nil {
+				t.Fatal(err)
+			}
+			d.poll(now)
+			tc.replace(t, path)
+			d.poll(now)
+			file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			file.WriteString(logLine("2025-10-10T10:40:00+02:00"))
+			file.Close()
+			d.poll(now)
+			if len(s.entries) != 1 || s.entries[0].Time.Minute() != 40 {
+				t.Fatalf("expected the entry of the new content, got %d entries", len(s.entries))
+			}
+		})
+	}
+}
+
+// failSink fails to flush the entries it receives while down
+type failSink struct {
+	testSink
+	down bool
+}
+
+func (s *failSink) Flush() error {
+	if s.down {
+		s.entries = nil
+		return errors.New("error(sink): connection refused")
+	}
+	return nil
+}
+
+func (s *failSink) String() string { return "fail" }
+
+func TestStateSinkFailure(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "filter.log")
+	statePath := filepath.Join(dir, "state.json")
+	if err := os.WriteFile(path, []byte(logLine("2025-10-10T10:05:00+02:00")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	appendLine := func(timestamp string) {
+		t.Helper()
+		file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file.WriteString(logLine(timestamp))
+		file.Close()
+	}
+	opts := Options{Interval: IntervalHourly, StateFile: statePath}
+	now := time.Date(2025, 10, 10, 10, 15, 0, 0, time.FixedZone("", 2*60*60))
+
+	f, err := stream.NewFollower(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	failing := &failSink{down: true}
+	opts.Sinks = []sink.Sink{failing, &testSink{}}
+	d, err := New(f, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.poll(now)
+	f.Close()
+	st, err := LoadState(statePath)
+	if err != nil || st == nil || st.Resume.Offset != 0 || st.Sent != f.Checkpoint() || len(st.Pinned) != 1 ||
+		st.Pinned["fail"].Offset != 0 {
+		t.Fatalf("expected state pinned before the failed entry, got %+v (%v)", st, err)
+	}
+
+	// the entry is published again after a restart, but only to the sink that failed
+	if f, err = stream.NewFollowerAt(path, st.Resume); err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	failing, s := &failSink{}, &testSink{}
+	opts.Sinks = []sink.Sink{failing, s}
+	if d, err = New(f, opts); err != nil {
+		t.Fatal(err)
+	}
+	d.poll(now)
+	if len(failing.entries) != 1 || failing.entries[0].Time.Minute() != 5 || len(s.entries) != 0 {
+		t.Fatalf("expected the failed entry for the failed sink only, got %d and %d", len(failing.entries), len(s.entries))
+	}
+	if st, err = LoadState(statePath); err != nil || st == nil || st.Sent != f.Checkpoint() || st.Pinned != nil {
+		t.Fatalf("expected state with all entries sent, got %+v (%v)", st, err)
+	}
+
+	// the state is pinned while the sink fails and moves forward again once it recovers
+	appendLine("2025-10-10T10:10:00+02:00")
+	failing.down = true
+	d.poll(now)
+	pinned := st.Sent
+	if st, err = LoadState(statePath); err != nil || st == nil || st.Pinned["fail"] != pinned {
+		t.Fatalf("expected state pinned before the failed entry, got %+v (%v)", st, err)
+	}
+	appendLine("2025-10-10T10:12:00+02:00")
+	failing.down = false
+	d.poll(now)
+	if len(failing.entries) != 1 || failing.entries[0].Time.Minute() != 12 || len(s.entries) != 2 {
+		t.Fatalf("expected the new entry after recovery, got %d and %d", len(failing.entries), len(s.entries))
+	}
+	if st, err = LoadState(statePath); err != nil || st == nil || st.Sent != f.Checkpoint() || st.Pinned != nil {
+		t.Fatalf("expected state with all entries sent after recovery, got %+v (%v)", st, err)
+	}
+}
+
+func TestStateRequiresCheckpoint(t *testing.T) {
+	_, err := New(nil, Options{Interval: IntervalHourly, Sinks: []sink.Sink{&testSink{}}, StateFile: "state.json"})
+	if err == nil || !strings.Contains(err.Error(), "requires a log file source") {
+		t.Fatalf("expected error, got %v", err)
+	}
+}
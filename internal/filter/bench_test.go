@@ -0,0 +1,82 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package filter
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+// benchEntries returns the entries of the valid test log
+func benchEntries(b *testing.B) []*stream.LogEntry {
+	content, err := os.ReadFile("../../tests/filter_valid.log")
+	if err != nil {
+		b.Fatal(err)
+	}
+	var entries []*stream.LogEntry
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		entry, err := stream.ParseLine(line)
+		if err != nil {
+			b.Fatal(err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func BenchmarkCompile(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := Compile("(src 192.168.1.100 or dst 10.0.0.5) and not action block and dport 443"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMatches(b *testing.B) {
+	entries := benchEntries(b)
+	for _, bc := range []struct {
+		name   string
+		filter string
+	}{
+		{name: "field", filter: "action block"},
+		{name: "port", filter: "dport 22"},
+		{name: "any", filter: "192.168"},
+		{name: "complex", filter: "(src 192.168.1.100 or dst 10.0.0.5) and not action block and dport 443"},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			compiled, err := Compile(bc.filter)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ReportAllocs()
+			for i := 0; b.Loop(); i++ {
+				compiled.Matches(entries[i%len(entries)])
+			}
+		})
+	}
+}
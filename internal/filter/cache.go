@@ -0,0 +1,143 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package filter
+
+import (
+	"slices"
+	"sync"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+// cacheLines is the number of most recently used expressions whose matching lines are kept
+const cacheLines = 8
+
+// cacheEntry is a compiled expression and the lines it matched
+type cacheEntry struct {
+	err         error             // compile error (node is nil)
+	fingerprint stream.Checkpoint // end of the indexed lines of the file lines belong to
+	lines       []int             // matching line numbers (nil if not known)
+	node        FilterNode        // compiled filter
+}
+
+// Cache keeps compiled filters and the lines they matched in a file by expression (safe for concurrent use)
+type Cache struct {
+	entries map[string]*cacheEntry // entries by expression
+	hits    int                    // lookups of lines that were known
+	misses  int                    // lookups of lines that weren't known
+	mu      sync.Mutex             // guards entries, recent and the lookup counts
+	opts    Options                // options expressions are compiled with
+	recent  []string               // expressions from least to most recently used
+	size    int                    // maximum number of expressions
+}
+
+// use marks the expression as most recently used (must hold mu)
+func (c *Cache) use(expression string) {
+	if i := slices.Index(c.recent, expression); i >= 0 {
+		c.recent = slices.Delete(c.recent, i, i+1)
+	}
+	c.recent = append(c.recent, expression)
+}
+
+// timeDependent returns true if the result of the filter depends on the current time (age)
+func timeDependent(node FilterNode) bool {
+	switch f := node.(type) {
+	case *fieldFilter:
+		return f.field == fieldAge
+	case *andFilter:
+		return timeDependent(f.left) || timeDependent(f.right)
+	case *orFilter:
+		return timeDependent(f.left) || timeDependent(f.right)
+	case *notFilter:
+		return timeDependent(f.child)
+	}
+	return false
+}
+
+// public
+
+// NewCache creates a cache of at most size expressions compiled with opts (see CompileWith)
+func NewCache(size int, opts Options) *Cache {
+	return &Cache{entries: make(map[string]*cacheEntry), opts: opts, size: max(size, 1)}
+}
+
+// Compile returns the cached filter of the expression
+func (c *Cache) Compile(expression string) (FilterNode, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[expression]; ok {
+		c.use(expression)
+		return e.node, e.err
+	}
+	node, err := CompileWith(expression, c.opts)
+	c.entries[expression] = &cacheEntry{err: err, node: node}
+	c.use(expression)
+	if len(c.recent) > c.size {
+		delete(c.entries, c.recent[0])
+		c.recent = c.recent[1:]
+	}
+	return node, err
+}
+
+// Lines returns the lines the expression matched in the file with the fingerprint (see stream.Stream.CheckpointAt),
+// false if not known
+func (c *Cache) Lines(expression string, fingerprint stream.Checkpoint) ([]int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[expression]
+	if !ok || e.lines == nil || e.fingerprint != fingerprint {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	c.use(expression)
+	return e.lines, true
+}
+
+// Stats returns how many lookups of lines (see Lines) were answered from the cache and how many weren't
+func (c *Cache) Stats() (hits, misses int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
+
+// SetLines remembers the lines the compiled expression matched in the file with the fingerprint (ignored for
+// filters that depend on the current time), lines must not be modified afterwards
+func (c *Cache) SetLines(expression string, fingerprint stream.Checkpoint, lines []int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[expression]
+	if !ok || e.err != nil || timeDependent(e.node) {
+		return
+	}
+	if lines == nil {
+		lines = []int{}
+	}
+	e.fingerprint, e.lines = fingerprint, lines
+	c.use(expression)
+	// lines of large files take up a lot of memory, only keep the most recent ones
+	for _, expression := range c.recent[:max(len(c.recent)-cacheLines, 0)] {
+		c.entries[expression].lines = nil
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package filter
+
+import (
+	"slices"
+	"testing"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+func TestCache(t *testing.T) {
+	c := NewCache(2, Options{})
+	fingerprint := stream.Checkpoint{Dev: 1, Ino: 2, LineNum: 10, Offset: 1000}
+
+	node, err := c.Compile("action block")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := c.Compile("action block"); again != node {
+		t.Error("expected the cached filter")
+	}
+	if _, err := c.Compile("src and"); err == nil {
+		t.Error("expected cached compile error, got nil")
+	}
+
+	if _, ok := c.Lines("action block", fingerprint); ok {
+		t.Error("expected no lines before SetLines")
+	}
+	c.SetLines("action block", fingerprint, []int{1, 4})
+	if lines, ok := c.Lines("action block", fingerprint); !ok || !slices.Equal(lines, []int{1, 4}) {
+		t.Errorf("expected [1 4], got %v (%v)", lines, ok)
+	}
+	// lines only apply to the same indexed lines of the same file
+	if _, ok := c.Lines("action block", stream.Checkpoint{Dev: 1, Ino: 2, LineNum: 11, Offset: 1100}); ok {
+		t.Error("expected no lines for another fingerprint")
+	}
+	if hits, misses := c.Stats(); hits != 1 || misses != 2 {
+		t.Errorf("expected 1 hit and 2 misses, got %d and %d", hits, misses)
+	}
+
+	// no matches are cached as well
+	c.Compile("dport 1")
+	c.SetLines("dport 1", fingerprint, nil)
+	if lines, ok := c.Lines("dport 1", fingerprint); !ok || len(lines) != 0 {
+		t.Errorf("expected no matching lines, got %v (%v)", lines, ok)
+	}
+
+	// the least recently used expression is dropped
+	c.Compile("action block")
+	c.Compile("proto tcp")
+	if _, ok := c.Lines("dport 1", fingerprint); ok {
+		t.Error("expected dport 1 to be dropped")
+	}
+	if _, ok := c.Lines("action block", fingerprint); !ok {
+		t.Error("expected action block to be kept")
+	}
+
+	// filters that depend on the current time are compiled but their lines are not cached
+	c.Compile("age < 1m")
+	c.SetLines("age < 1m", fingerprint, []int{1})
+	if _, ok := c.Lines("age < 1m", fingerprint); ok {
+		t.Error("expected no lines for age")
+	}
+
+	// expressions are compiled with the options of the cache
+	node, _ = c.Compile("blocked wan")
+	if node.Matches(&stream.LogEntry{Action: "block", Interface: "igb0"}) {
+		t.Error("expected wan to be searched in all fields without interface names")
+	}
+	node, _ = NewCache(2, Options{Interfaces: map[string][]string{"wan": {"igb0"}}}).Compile("blocked wan")
+	if !node.Matches(&stream.LogEntry{Action: "block", Interface: "igb0"}) {
+		t.Error("expected the filter to be compiled with the interface names")
+	}
+}
@@ -25,34 +25,55 @@ package filter
 
 import (
 	"fmt"
+	"math"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
 
 	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
 )
 
 const (
-	tokenAnd    tokenTyp = iota // and operator
-	tokenEOF                    // eof
-	tokenField                  // field name
-	tokenNot                    // not operator
-	tokenOr                     // or operator
-	tokenParenL                 // left parenthesis
-	tokenParenR                 // right parenthesis
-	tokenValue                  // value
+	tokenAnd     tokenTyp = iota // and operator
+	tokenEOF                     // eof
+	tokenField                   // field name
+	tokenInvalid                 // invalid input (value is the error message)
+	tokenNot                     // not operator
+	tokenOr                      // or operator
+	tokenParenL                  // left parenthesis
+	tokenParenR                  // right parenthesis
+	tokenValue                   // value
 )
 
 const (
 	fieldAction      fieldTyp = iota // action taken
+	fieldAge                         // time since the entry was logged
+	fieldAnchor                      // anchor of the matching rule
+	fieldDataLen                     // length of the data (tcp/udp)
 	fieldDestination                 // destination ip address
+	fieldDstClass                    // class of the destination address
 	fieldDirection                   // traffic direction
 	fieldDstPort                     // destination port
+	fieldHour                        // hour of the day
+	fieldICMPType                    // icmp type
 	fieldIPVersion                   // ip version
 	fieldInterface                   // network interface
+	fieldLabel                       // label of the matching rule (tracker id)
+	fieldLength                      // total length of the packet
+	fieldOrigin                      // firewall or file the entry was read from
 	fieldPort                        // source or destination port
 	fieldProtocol                    // protocol
 	fieldReason                      // reason for action
+	fieldRuleNum                     // number of the matching rule
+	fieldSeverity                    // severity level (matches the level and above)
 	fieldSource                      // source IP address
+	fieldSrcClass                    // class of the source address
 	fieldSrcPort                     // source port
+	fieldSubRuleNum                  // number of the matching rule within its anchor
+	fieldTCPFlags                    // tcp flags
+	fieldWeekday                     // day of the week
 )
 
 var (
@@ -73,6 +94,12 @@ var (
 	fields = map[string]fieldTyp{
 		// action
 		"action": fieldAction,
+		// age
+		"age": fieldAge,
+		// anchor
+		"anchor": fieldAnchor,
+		// data length
+		"datalen": fieldDataLen,
 		// direction
 		"direction": fieldDirection,
 		"dir":       fieldDirection,
@@ -80,9 +107,15 @@ var (
 		"destination": fieldDestination,
 		"dest":        fieldDestination,
 		"dst":         fieldDestination,
+		// destination class
+		"dstclass": fieldDstClass,
 		// destination port
 		"dstport": fieldDstPort,
 		"dport":   fieldDstPort,
+		// hour
+		"hour": fieldHour,
+		// icmp type
+		"icmptype": fieldICMPType,
 		// ip version
 		"ipversion": fieldIPVersion,
 		"ip":        fieldIPVersion,
@@ -90,6 +123,15 @@ var (
 		// interface
 		"interface": fieldInterface,
 		"iface":     fieldInterface,
+		// label
+		"label":   fieldLabel,
+		"tracker": fieldLabel,
+		"rid":     fieldLabel,
+		// length
+		"length": fieldLength,
+		"len":    fieldLength,
+		// origin
+		"origin": fieldOrigin,
 		// port
 		"port": fieldPort,
 		// protocol
@@ -97,12 +139,75 @@ var (
 		"proto":    fieldProtocol,
 		// reason
 		"reason": fieldReason,
+		// rule number
+		"rulenr": fieldRuleNum,
+		"rule":   fieldRuleNum,
+		// severity
+		"severity": fieldSeverity,
+		"sev":      fieldSeverity,
 		// source
 		"source": fieldSource,
 		"src":    fieldSource,
+		// source class
+		"srcclass": fieldSrcClass,
 		// source port
 		"srcport": fieldSrcPort,
 		"sport":   fieldSrcPort,
+		// subrule number
+		"subrulenr": fieldSubRuleNum,
+		"subrule":   fieldSubRuleNum,
+		// tcp flags
+		"tcpflags": fieldTCPFlags,
+		// weekday
+		"weekday": fieldWeekday,
+	}
+
+	// examples of valid values of fields with a syntax (shown in errors)
+	examples = map[fieldTyp]string{
+		fieldAge:     "<10m or >1h",
+		fieldDataLen: ">1000 or 0-64",
+		fieldLength:  ">1000 or 0-64",
+		fieldHour:    "22-06 or 8,12-13",
+		fieldWeekday: "sat,sun or mon-fri",
+	}
+
+	// shorthands maps bare words to the field and value they stand for
+	shorthands = map[string]struct {
+		name  string // field name
+		value string // field value
+	}{
+		"blocked":  {"action", "block"},
+		"passed":   {"action", "pass"},
+		"inbound":  {"direction", "in"},
+		"outbound": {"direction", "out"},
+	}
+
+	// weekdays are the names of the days of the week (matched by their first three letters)
+	weekdays = []string{"sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"}
+
+	// searchable are the names of the fields returned by stream.LogEntry.Searchable (in the same order)
+	searchable = []string{"action", "direction", "interface", "origin", "reason", "time", "destination", "protocol",
+		"source"}
+
+	// highlighted maps field types to the fields they are highlighted in (see Highlights)
+	highlighted = map[fieldTyp][]string{
+		fieldAction:      {"action"},
+		fieldAge:         {"time"},
+		fieldDestination: {"destination"},
+		fieldDirection:   {"direction"},
+		fieldDstClass:    {"destination"},
+		fieldDstPort:     {"dstport"},
+		fieldHour:        {"time"},
+		fieldInterface:   {"interface"},
+		fieldLabel:       {"label"},
+		fieldOrigin:      {"origin"},
+		fieldProtocol:    {"protocol"},
+		fieldReason:      {"reason"},
+		fieldSeverity:    {"severity"},
+		fieldSource:      {"source"},
+		fieldSrcClass:    {"source"},
+		fieldSrcPort:     {"srcport"},
+		fieldWeekday:     {"time"},
 	}
 )
 
@@ -110,24 +215,35 @@ type tokenTyp int
 
 // token represents a single token from the filter expression
 type token struct {
-	typ   tokenTyp // type of token
-	value string   // value of the token
+	typ    tokenTyp // type of token
+	value  string   // value of the token
+	pos    int      // byte offset of the token in the expression
+	quoted bool     // whether the value was quoted
 }
 
 // lexer tokenizes filter expression input into a stream of tokens
 type lexer struct {
-	input string // input string being lexed
+	input string // input string being lexed (trailing spaces removed)
 	pos   int    // current position in the input string
 }
 
 // parser parses filter expressions into a filter node tree
 type parser struct {
-	lex     *lexer // provides tokens
-	current token  // current token being parsed
+	lex         *lexer              // provides tokens
+	current     token               // current token being parsed
+	implicitAnd bool                // whether adjacent terms are combined with and
+	interfaces  map[string][]string // interface names (e.g. wan) and the interfaces they stand for
+	previous    token               // token parsed before the current one
 }
 
 type fieldTyp int
 
+// Options changes how expressions are compiled (see CompileWith)
+type Options struct {
+	Interfaces map[string][]string // names (e.g. wan) standing for the interfaces (e.g. igb0) as bare word (wan) or interface value (iface wan)
+	Strict     bool                // adjacent terms (e.g. src 10.0.0.1 dport 443) are an error instead of being combined with and
+}
+
 // FilterNode is the interface that all filter nodes use to match log entries
 type FilterNode interface {
 	Matches(entry *stream.LogEntry) bool
@@ -135,13 +251,27 @@ type FilterNode interface {
 
 // anyFilter matches any field containing the value
 type anyFilter struct {
-	value string // value to search for in any field
+	named      bool   // whether the value is an interface name (quoted in expressions)
+	suggestion string // field the value is likely a misspelling of (see Warnings)
+	value      string // value to search for in any field
 }
 
 // fieldFilter matches a specific field against a value
 type fieldFilter struct {
-	field fieldTyp // type of field
-	value string   // value to match against
+	age   time.Duration // maximum age, or minimum age if older is set (age only)
+	field fieldTyp      // type of field
+	name  string        // field name as written (lowercase)
+	named bool          // whether the value is an interface name (quoted in expressions)
+	older bool          // whether entries older than age match instead of newer ones (age only)
+	set   uint32        // bit set of the hours or weekdays in value (hour and weekday only)
+	size  [2]uint64     // inclusive range of lengths in bytes (length and datalen only)
+	value string        // value to match against
+}
+
+// interfaceFilter matches entries of any of the interfaces a name stands for (e.g. wan)
+type interfaceFilter struct {
+	interfaces []string // interfaces the name stands for
+	name       string   // name as written (lowercase)
 }
 
 // andFilter matches only if both child filters match
@@ -161,6 +291,39 @@ type notFilter struct {
 	child FilterNode // filter expression to invert
 }
 
+// countFilter counts the entries its child filter was evaluated for and matched (see Stats)
+type countFilter struct {
+	child FilterNode  // filter being counted
+	stats ClauseStats // counts of the child
+}
+
+// ClauseStats is the number of entries a clause of a filter was evaluated for and matched
+type ClauseStats struct {
+	Expression string // value, field and value, or operator (and, or, not)
+	Depth      int    // nesting level (0 for the root clause)
+	Evaluated  int    // entries the clause was evaluated for (operands of and and or are short-circuited)
+	Matched    int    // entries the clause matched
+}
+
+// Stats counts the entries per clause of instrumented filters (not safe for concurrent use)
+type Stats struct {
+	counters []*countFilter // counters of all clauses in pre-order
+}
+
+// Error is an error in a filter expression
+type Error struct {
+	Msg string // description of the error
+	Pos int    // byte offset in the expression the error was found at
+}
+
+// Clause is the result of a node of a filter for an entry (see Trace)
+type Clause struct {
+	Expression string   `json:"expression"`        // value, field and value, or operator (and, or, not)
+	Matched    bool     `json:"matched"`           // whether the node matched
+	Reason     string   `json:"reason,omitempty"`  // value of the field in the entry (values and fields only)
+	Clauses    []Clause `json:"clauses,omitempty"` // operands (operators only)
+}
+
 // lexer
 
 // readWord reads a word token (letters, numbers, etc.) until space or parentheses
@@ -175,12 +338,41 @@ func (l *lexer) readWord() string {
 	return l.input[start:l.pos]
 }
 
+// readQuoted reads a double quoted value (the opening quote is at the current position), a backslash escapes the
+// next character (e.g. \" or \\)
+func (l *lexer) readQuoted() (string, bool) {
+	var b strings.Builder
+	for l.pos++; l.pos < len(l.input); l.pos++ {
+		switch ch := l.input[l.pos]; ch {
+		case '\\':
+			if l.pos++; l.pos >= len(l.input) {
+				return "", false
+			}
+			b.WriteByte(l.input[l.pos])
+		case '"':
+			l.pos++
+			return b.String(), true
+		default:
+			b.WriteByte(ch)
+		}
+	}
+	return "", false
+}
+
 // nextToken returns the next token
 func (l *lexer) nextToken() token {
 	// skip space(s)
 	for l.pos < len(l.input) && l.input[l.pos] == ' ' {
 		l.pos++
 	}
+	start := l.pos
+	t := l.readToken()
+	t.pos = start
+	return t
+}
+
+// readToken reads the token at the current position (spaces have been skipped)
+func (l *lexer) readToken() token {
 	// check for eof
 	if l.pos >= len(l.input) {
 		return token{typ: tokenEOF}
@@ -193,6 +385,12 @@ func (l *lexer) nextToken() token {
 	case ")":
 		l.pos++
 		return token{typ: tokenParenR, value: ch}
+	case `"`:
+		// quoted values may contain spaces, parentheses and operators or field names
+		if value, ok := l.readQuoted(); ok {
+			return token{typ: tokenValue, value: value, quoted: true}
+		}
+		return token{typ: tokenInvalid, value: "unterminated quoted value"}
 	}
 	word := l.readWord()
 	// check for eof again
@@ -214,34 +412,86 @@ func (l *lexer) nextToken() token {
 
 // newLexer creates a new lexer for the given input string
 func newLexer(input string) *lexer {
+	// leading spaces are skipped instead of trimmed, so positions refer to the original input
+	input = strings.TrimRightFunc(input, unicode.IsSpace)
 	return &lexer{
-		input: strings.TrimSpace(input),
-		pos:   0,
+		input: input,
+		pos:   len(input) - len(strings.TrimLeftFunc(input, unicode.IsSpace)),
 	}
 }
 
 // parser
 
 // newParser creates a new parser for the given input string
-func newParser(input string) *parser {
+func newParser(input string, opts Options) *parser {
 	lex := newLexer(input)
 	return &parser{
-		lex:     lex,
-		current: lex.nextToken(), // pre-load the first token
+		lex:         lex,
+		current:     lex.nextToken(), // pre-load the first token
+		implicitAnd: !opts.Strict,
+		interfaces:  lowerKeys(opts.Interfaces),
+	}
+}
+
+// lowerKeys returns the interface names in lowercase
+func lowerKeys(names map[string][]string) map[string][]string {
+	lower := make(map[string][]string, len(names))
+	for name, ifaces := range names {
+		lower[strings.ToLower(name)] = ifaces
 	}
+	return lower
 }
 
 // advance moves to the next token
 func (p *parser) advance() {
+	p.previous = p.current
 	p.current = p.lex.nextToken()
 }
 
+// describe returns the current token as shown in errors
+func (p *parser) describe() string {
+	if p.current.typ == tokenEOF {
+		return "end of expression"
+	}
+	return fmt.Sprintf("%q", p.current.value)
+}
+
+// unexpected returns an error at the current token (invalid tokens report their own message)
+func (p *parser) unexpected(format string, args ...any) error {
+	if p.current.typ == tokenInvalid {
+		return &Error{Msg: p.current.value, Pos: p.current.pos}
+	}
+	return &Error{Msg: fmt.Sprintf(format, args...), Pos: p.current.pos}
+}
+
+// startsTerm returns true if the current token starts a term (value, field, group or negation)
+func (p *parser) startsTerm() bool {
+	switch p.current.typ {
+	case tokenField, tokenNot, tokenParenL, tokenValue:
+		return true
+	}
+	return false
+}
+
 // parse parses the entire filter expression and returns the root FilterNode or nil if empty
 func (p *parser) parse() (FilterNode, error) {
 	if p.current.typ == tokenEOF {
 		return nil, nil
 	}
-	return p.parseOr()
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.current.typ == tokenEOF {
+		return node, nil
+	}
+	// a value followed by another value is likely a misspelled field name
+	if p.previous.typ == tokenValue && p.current.typ == tokenValue {
+		if field := suggestField(p.previous.value); field != "" {
+			return nil, &Error{Msg: fmt.Sprintf("unknown field %q (did you mean %q?)", p.previous.value, field), Pos: p.previous.pos}
+		}
+	}
+	return nil, p.unexpected("unexpected %s", p.describe())
 }
 
 // parseOr handles or expressions (lowest precedence)
@@ -261,14 +511,16 @@ func (p *parser) parseOr() (FilterNode, error) {
 	return left, nil
 }
 
-// parseAnd handles and expressions (medium precedence)
+// parseAnd handles and expressions, explicit or between adjacent terms (medium precedence)
 func (p *parser) parseAnd() (FilterNode, error) {
 	left, err := p.parseNot()
 	if err != nil {
 		return nil, err
 	}
-	for p.current.typ == tokenAnd {
-		p.advance()
+	for p.current.typ == tokenAnd || p.implicitAnd && p.startsTerm() {
+		if p.current.typ == tokenAnd {
+			p.advance()
+		}
 		right, err := p.parseNot()
 		if err != nil {
 			return nil, err
@@ -301,7 +553,7 @@ func (p *parser) parsePrimary() (FilterNode, error) {
 			return nil, err
 		}
 		if p.current.typ != tokenParenR {
-			return nil, fmt.Errorf("error(filter): expected \")\" but got %q", p.current.value)
+			return nil, p.unexpected("expected \")\" but got %s", p.describe())
 		}
 		p.advance()
 		return node, nil
@@ -312,21 +564,204 @@ func (p *parser) parsePrimary() (FilterNode, error) {
 		p.advance()
 
 		if p.current.typ != tokenValue {
-			return nil, fmt.Errorf("error(filter): expected value after field %q but got %q", field, p.current.value)
+			return nil, p.unexpected("expected value after field %q but got %s", field, p.describe())
+		}
+		// interface names stand for their interfaces (e.g. iface wan)
+		if names, ok := p.interfaces[strings.ToLower(p.current.value)]; ok && fields[field] == fieldInterface {
+			p.advance()
+			return &interfaceFilter{interfaces: names, name: strings.ToLower(p.previous.value)}, nil
+		}
+		f := &fieldFilter{field: fields[field], name: field, value: p.current.value}
+		_, f.named = p.interfaces[strings.ToLower(f.value)]
+		pos, valid := p.current.pos, true
+		// the comparison may be separated from the duration or length (age < 10m or length > 1000)
+		comparison := f.value == "<" || f.value == "<=" || f.value == ">" || f.value == ">="
+		if comparison && (f.field == fieldAge || f.field == fieldDataLen || f.field == fieldLength) {
+			p.advance()
+			if p.current.typ != tokenValue {
+				expected := "length"
+				if f.field == fieldAge {
+					expected = "duration"
+				}
+				return nil, p.unexpected("expected %s after %q but got %s", expected, f.value, p.describe())
+			}
+			f.value += p.current.value
+		}
+		switch f.field {
+		case fieldAge:
+			f.age, f.older, valid = parseAge(f.value)
+		case fieldDataLen, fieldLength:
+			f.size, valid = parseSize(f.value)
+		case fieldHour:
+			f.set = parseSet(f.value, 24, parseHour)
+		case fieldWeekday:
+			f.set = parseSet(f.value, len(weekdays), parseWeekday)
+		}
+		if !valid || (f.field == fieldHour || f.field == fieldWeekday) && f.set == 0 {
+			return nil, &Error{Msg: fmt.Sprintf("invalid %s %q (e.g. %s)", field, f.value, examples[f.field]), Pos: pos}
 		}
-		value := p.current.value
 		p.advance()
 
-		return &fieldFilter{field: fields[field], value: value}, nil
+		return f, nil
 	}
 	// handle bare values
 	if p.current.typ == tokenValue {
-		value := p.current.value
+		value := p.current
 		p.advance()
-		return &anyFilter{value: value}, nil
+		// unquoted shorthands and interface names stand for field filters (e.g. blocked or wan)
+		if !value.quoted {
+			word := strings.ToLower(value.value)
+			if s, ok := shorthands[word]; ok {
+				return &fieldFilter{field: fields[s.name], name: s.name, value: s.value}, nil
+			}
+			if names, ok := p.interfaces[word]; ok {
+				return &interfaceFilter{interfaces: names, name: word}, nil
+			}
+		}
+		f := &anyFilter{value: value.value}
+		_, f.named = p.interfaces[strings.ToLower(f.value)]
+		// an unquoted value followed by another value is likely a misspelled field name (e.g. sr 10.0.0.1)
+		if !value.quoted && p.current.typ == tokenValue {
+			f.suggestion = suggestField(value.value)
+		}
+		return f, nil
+	}
+	return nil, p.unexpected("expected value, field or \"(\" but got %s", p.describe())
+}
+
+// parseAge returns the duration of an age comparison (e.g. <10m or >1h, newer if the comparison is omitted) and
+// whether it matches older entries
+func parseAge(s string) (time.Duration, bool, bool) {
+	older := strings.HasPrefix(s, ">")
+	for _, comparison := range []string{"<=", ">=", "<", ">"} {
+		if rest, ok := strings.CutPrefix(s, comparison); ok {
+			s = rest
+			break
+		}
+	}
+	age, err := time.ParseDuration(s)
+	if err != nil || age <= 0 {
+		return 0, false, false
+	}
+	return age, older, true
+}
+
+// parseSize returns the inclusive range of lengths of a comparison or range (e.g. >1000 or 0-64, exact if neither)
+func parseSize(s string) ([2]uint64, bool) {
+	parse := func(s string) (uint64, bool) {
+		n, err := strconv.ParseUint(s, 10, 32)
+		return n, err == nil
 	}
-	// TODO: make this err msg more helpful
-	return nil, fmt.Errorf("error(filter): unexpected token %q", p.current.value)
+	for _, c := range []struct {
+		prefix string
+		size   func(n uint64) [2]uint64
+	}{
+		{"<=", func(n uint64) [2]uint64 { return [2]uint64{0, n} }},
+		{">=", func(n uint64) [2]uint64 { return [2]uint64{n, math.MaxUint32} }},
+		{"<", func(n uint64) [2]uint64 { return [2]uint64{0, n - 1} }},
+		{">", func(n uint64) [2]uint64 { return [2]uint64{n + 1, math.MaxUint32} }},
+	} {
+		if rest, ok := strings.CutPrefix(s, c.prefix); ok {
+			n, ok := parse(rest)
+			if !ok || c.prefix == "<" && n == 0 {
+				return [2]uint64{}, false
+			}
+			return c.size(n), true
+		}
+	}
+	first, last, isRange := strings.Cut(s, "-")
+	start, ok := parse(first)
+	if !ok {
+		return [2]uint64{}, false
+	}
+	end := start
+	if isRange {
+		if end, ok = parse(last); !ok || end < start {
+			return [2]uint64{}, false
+		}
+	}
+	return [2]uint64{start, end}, true
+}
+
+// parseHour returns the hour of s (0-23)
+func parseHour(s string) (int, bool) {
+	hour, err := strconv.Atoi(s)
+	return hour, err == nil && hour >= 0 && hour < 24
+}
+
+// parseWeekday returns the day of the week of s (at least the first three letters of its name, 0 is sunday)
+func parseWeekday(s string) (int, bool) {
+	s = strings.ToLower(s)
+	if len(s) >= 3 {
+		for day, name := range weekdays {
+			if strings.HasPrefix(name, s) {
+				return day, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// parseSet returns the bit set of a comma separated list of items and inclusive ranges of items (e.g. 22-06 or
+// sat,sun), ranges wrap around after the last of n items, 0 if the list is invalid
+func parseSet(s string, n int, parseItem func(string) (int, bool)) uint32 {
+	var set uint32
+	for item := range strings.SplitSeq(s, ",") {
+		first, last, isRange := strings.Cut(item, "-")
+		start, ok := parseItem(first)
+		if !ok {
+			return 0
+		}
+		end := start
+		if isRange {
+			if end, ok = parseItem(last); !ok {
+				return 0
+			}
+		}
+		for i := start; ; i = (i + 1) % n {
+			set |= 1 << i
+			if i == end {
+				break
+			}
+		}
+	}
+	return set
+}
+
+// suggestField returns the field name closest to the (misspelled) name, empty if none is close enough
+func suggestField(name string) string {
+	name = strings.ToLower(name)
+	// allow one edit for short names, two otherwise
+	best, bestDistance := "", 1
+	if len(name) > 4 {
+		bestDistance = 2
+	}
+	for field := range fields {
+		if d := distance(name, field); d < bestDistance || (d == bestDistance && (best == "" || field < best)) {
+			best, bestDistance = field, d
+		}
+	}
+	return best
+}
+
+// distance returns the levenshtein distance between a and b
+func distance(a, b string) int {
+	row := make([]int, len(b)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		diagonal := row[0]
+		row[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			diagonal, row[j] = row[j], min(row[j]+1, row[j-1]+1, diagonal+cost)
+		}
+	}
+	return row[len(b)]
 }
 
 // filter nodes
@@ -334,17 +769,7 @@ func (p *parser) parsePrimary() (FilterNode, error) {
 // Matches (anyFilter) returns true if any field in the log entry contains the filter value
 func (f *anyFilter) Matches(entry *stream.LogEntry) bool {
 	value := strings.ToLower(f.value)
-	searchFields := []string{
-		entry.Action,
-		entry.Direction,
-		entry.Interface,
-		entry.Reason,
-		entry.Time.Format("Jan 02 15:04:05"),
-		entry.Dst,
-		entry.ProtoName,
-		entry.Src,
-	}
-	for _, field := range searchFields {
+	for _, field := range entry.Searchable() {
 		if strings.Contains(strings.ToLower(field), value) {
 			return true
 		}
@@ -361,29 +786,173 @@ func (f *fieldFilter) Matches(entry *stream.LogEntry) bool {
 	matchStr := func(s string) bool {
 		return strings.HasPrefix(strings.ToLower(s), value)
 	}
+	matchSize := func(n uint32) bool {
+		return uint64(n) >= f.size[0] && uint64(n) <= f.size[1]
+	}
 	switch f.field {
 	case fieldAction:
 		return matchStr(entry.Action)
+	case fieldAge:
+		// wall clock time when the entry is matched
+		if f.older {
+			return time.Since(entry.Time) > f.age
+		}
+		return time.Since(entry.Time) <= f.age
 	case fieldDestination:
 		return matchStr(entry.Dst)
 	case fieldDirection:
 		return matchStr(entry.Direction)
+	case fieldDataLen:
+		// only tcp and udp entries have a data length (it may be 0)
+		return entry.Transport() && matchSize(entry.DataLen)
+	case fieldDstClass:
+		return matchStr(entry.DstClass)
 	case fieldDstPort:
 		return matchInt(entry.DstPort)
+	case fieldHour:
+		// hours and weekdays of the time as logged (time zone of the firewall)
+		return f.set&(1<<entry.Time.Hour()) != 0
+	case fieldICMPType:
+		return matchStr(entry.ICMPType)
 	case fieldIPVersion:
 		return matchInt(entry.IPVersion)
 	case fieldInterface:
 		return matchStr(entry.Interface)
+	case fieldOrigin:
+		return matchStr(entry.Origin)
+	case fieldAnchor:
+		return matchStr(entry.Anchor)
+	case fieldLabel:
+		return matchStr(entry.Label)
+	case fieldLength:
+		// 0 if the length was not logged
+		return entry.Length > 0 && matchSize(entry.Length)
+	case fieldRuleNum:
+		// numbers are matched exactly (rule 1 doesn't match rule 10)
+		return entry.RuleNum == f.value
+	case fieldSubRuleNum:
+		return entry.SubRuleNum == f.value
 	case fieldPort:
 		return matchInt(entry.SrcPort) || matchInt(entry.DstPort)
 	case fieldProtocol:
-		return matchStr(entry.ProtoName)
+		// by name or number (e.g. 112 for carp, if logged)
+		return matchStr(entry.ProtoName) || entry.ProtoNum > 0 && matchInt(entry.ProtoNum)
 	case fieldReason:
 		return matchStr(entry.Reason)
+	case fieldSeverity:
+		// known levels match the level and above (e.g. warning matches critical)
+		if level := stream.SeverityLevel(value); level >= 0 {
+			return stream.SeverityLevel(entry.Severity) >= level
+		}
+		return matchStr(entry.Severity)
 	case fieldSource:
 		return matchStr(entry.Src)
+	case fieldSrcClass:
+		return matchStr(entry.SrcClass)
 	case fieldSrcPort:
 		return matchInt(entry.SrcPort)
+	case fieldTCPFlags:
+		// all of the flags are set (in any order, e.g. S matches syn and syn-ack)
+		return entry.TCPFlags != "" && !strings.ContainsFunc(f.value, func(r rune) bool {
+			return !strings.ContainsRune(entry.TCPFlags, unicode.ToUpper(r))
+		})
+	case fieldWeekday:
+		return f.set&(1<<entry.Time.Weekday()) != 0
+	}
+	return false
+}
+
+// Matches (interfaceFilter) returns true if the entry was logged on one of the interfaces
+func (f *interfaceFilter) Matches(entry *stream.LogEntry) bool {
+	for _, iface := range f.interfaces {
+		if strings.EqualFold(entry.Interface, iface) {
+			return true
+		}
+	}
+	return false
+}
+
+// reason (anyFilter) returns the first field of the entry containing the filter value
+func (f *anyFilter) reason(entry *stream.LogEntry) string {
+	value := strings.ToLower(f.value)
+	for _, field := range entry.Searchable() {
+		if strings.Contains(strings.ToLower(field), value) {
+			return fmt.Sprintf("found in %q", field)
+		}
+	}
+	return "not found in any field"
+}
+
+// reason (fieldFilter) returns the value of the field in the entry
+func (f *fieldFilter) reason(entry *stream.LogEntry) string {
+	switch f.field {
+	case fieldAction:
+		return fmt.Sprintf("action is %q", entry.Action)
+	case fieldAge:
+		return fmt.Sprintf("age is %s", time.Since(entry.Time).Round(time.Second))
+	case fieldDestination:
+		return fmt.Sprintf("destination is %q", entry.Dst)
+	case fieldDirection:
+		return fmt.Sprintf("direction is %q", entry.Direction)
+	case fieldDataLen:
+		return fmt.Sprintf("datalen is %d", entry.DataLen)
+	case fieldDstClass:
+		return fmt.Sprintf("dstclass is %q", entry.DstClass)
+	case fieldDstPort:
+		return fmt.Sprintf("dstport is %d", entry.DstPort)
+	case fieldHour:
+		return fmt.Sprintf("hour is %d", entry.Time.Hour())
+	case fieldICMPType:
+		return fmt.Sprintf("icmptype is %q", entry.ICMPType)
+	case fieldIPVersion:
+		return fmt.Sprintf("ipversion is %d", entry.IPVersion)
+	case fieldInterface:
+		return fmt.Sprintf("interface is %q", entry.Interface)
+	case fieldOrigin:
+		return fmt.Sprintf("origin is %q", entry.Origin)
+	case fieldAnchor:
+		return fmt.Sprintf("anchor is %q", entry.Anchor)
+	case fieldLabel:
+		return fmt.Sprintf("label is %q", entry.Label)
+	case fieldLength:
+		return fmt.Sprintf("length is %d", entry.Length)
+	case fieldRuleNum:
+		return fmt.Sprintf("rulenr is %q", entry.RuleNum)
+	case fieldSubRuleNum:
+		return fmt.Sprintf("subrulenr is %q", entry.SubRuleNum)
+	case fieldPort:
+		return fmt.Sprintf("srcport is %d, dstport is %d", entry.SrcPort, entry.DstPort)
+	case fieldProtocol:
+		return fmt.Sprintf("protocol is %q", entry.ProtoName)
+	case fieldReason:
+		return fmt.Sprintf("reason is %q", entry.Reason)
+	case fieldSeverity:
+		return fmt.Sprintf("severity is %q", entry.Severity)
+	case fieldSource:
+		return fmt.Sprintf("source is %q", entry.Src)
+	case fieldSrcClass:
+		return fmt.Sprintf("srcclass is %q", entry.SrcClass)
+	case fieldSrcPort:
+		return fmt.Sprintf("srcport is %d", entry.SrcPort)
+	case fieldTCPFlags:
+		return fmt.Sprintf("tcpflags is %q", entry.TCPFlags)
+	case fieldWeekday:
+		return fmt.Sprintf("weekday is %q", weekdays[entry.Time.Weekday()][:3])
+	}
+	return ""
+}
+
+// reason (interfaceFilter) returns the interface of the entry
+func (f *interfaceFilter) reason(entry *stream.LogEntry) string {
+	return fmt.Sprintf("interface is %q", entry.Interface)
+}
+
+// Matches (countFilter) returns whether the child filter matches and counts the result
+func (f *countFilter) Matches(entry *stream.LogEntry) bool {
+	f.stats.Evaluated++
+	if f.child.Matches(entry) {
+		f.stats.Matched++
+		return true
 	}
 	return false
 }
@@ -403,13 +972,235 @@ func (f *notFilter) Matches(entry *stream.LogEntry) bool {
 	return !f.child.Matches(entry)
 }
 
+// highlights appends the fields that positive clauses of node matched in the entry to fields (nothing if node
+// doesn't match)
+func highlights(node FilterNode, entry *stream.LogEntry, fields []string) []string {
+	if !node.Matches(entry) {
+		return fields
+	}
+	switch f := node.(type) {
+	case *anyFilter:
+		value := strings.ToLower(f.value)
+		for i, field := range entry.Searchable() {
+			if strings.Contains(strings.ToLower(field), value) {
+				fields = append(fields, searchable[i])
+			}
+		}
+	case *fieldFilter:
+		if f.field == fieldPort {
+			// only the port that matched
+			if (&fieldFilter{field: fieldSrcPort, value: f.value}).Matches(entry) {
+				fields = append(fields, "srcport")
+			}
+			if (&fieldFilter{field: fieldDstPort, value: f.value}).Matches(entry) {
+				fields = append(fields, "dstport")
+			}
+			return fields
+		}
+		fields = append(fields, highlighted[f.field]...)
+	case *interfaceFilter:
+		fields = append(fields, "interface")
+	case *andFilter:
+		fields = highlights(f.right, entry, highlights(f.left, entry, fields))
+	case *orFilter:
+		fields = highlights(f.right, entry, highlights(f.left, entry, fields))
+	case *countFilter:
+		fields = highlights(f.child, entry, fields)
+	case *notFilter:
+		// matches because a field doesn't, so there is nothing to highlight
+	}
+	return fields
+}
+
+// quote returns the value as written in expressions (quoted if it contains spaces, parentheses or quotes, or if it
+// would be read as keyword or interface name)
+func quote(value string, named bool) string {
+	if value != "" && !IsKeyword(value) && !named && !strings.ContainsAny(value, " ()\"\\") {
+		return value
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// expression returns the clause of node as shown in traces and statistics
+func expression(node FilterNode) string {
+	switch f := node.(type) {
+	case *anyFilter:
+		return quote(f.value, f.named)
+	case *fieldFilter:
+		return f.name + " " + quote(f.value, f.named)
+	case *interfaceFilter:
+		return f.name
+	case *andFilter:
+		return "and"
+	case *orFilter:
+		return "or"
+	case *notFilter:
+		return "not"
+	}
+	return ""
+}
+
+// instrument wraps node and its operands in counters
+func (s *Stats) instrument(node FilterNode, depth int) FilterNode {
+	c := &countFilter{child: node, stats: ClauseStats{Expression: expression(node), Depth: depth}}
+	s.counters = append(s.counters, c)
+	switch f := node.(type) {
+	case *andFilter:
+		c.child = &andFilter{left: s.instrument(f.left, depth+1), right: s.instrument(f.right, depth+1)}
+	case *orFilter:
+		c.child = &orFilter{left: s.instrument(f.left, depth+1), right: s.instrument(f.right, depth+1)}
+	case *notFilter:
+		c.child = &notFilter{child: s.instrument(f.child, depth+1)}
+	}
+	return c
+}
+
 // public
 
-// Compile compiles a filter expression string into a FilterNode tree
+// Columnar returns true if the filter only uses fields recorded in stream.Columns (action, interface, ip version and ports)
+func Columnar(node FilterNode) bool {
+	switch f := node.(type) {
+	case *fieldFilter:
+		switch f.field {
+		case fieldAction, fieldDstPort, fieldInterface, fieldIPVersion, fieldPort, fieldSrcPort:
+			return true
+		}
+	case *interfaceFilter:
+		return true
+	case *andFilter:
+		return Columnar(f.left) && Columnar(f.right)
+	case *orFilter:
+		return Columnar(f.left) && Columnar(f.right)
+	case *notFilter:
+		return Columnar(f.child)
+	}
+	return false
+}
+
+// Terms returns the (lowercase) bare values that every entry matching the filter must contain (see stream.MayContain)
+func Terms(node FilterNode) []string {
+	switch f := node.(type) {
+	case *anyFilter:
+		return []string{strings.ToLower(f.value)}
+	case *andFilter:
+		return append(Terms(f.left), Terms(f.right)...)
+	}
+	return nil
+}
+
+// Trace evaluates every node of the filter for the entry (operands are not short-circuited, so the result of each
+// clause is shown), the root clause matches if and only if the filter matches the entry
+func Trace(node FilterNode, entry *stream.LogEntry) Clause {
+	switch f := node.(type) {
+	case *anyFilter:
+		return Clause{Expression: expression(f), Matched: f.Matches(entry), Reason: f.reason(entry)}
+	case *fieldFilter:
+		return Clause{Expression: expression(f), Matched: f.Matches(entry), Reason: f.reason(entry)}
+	case *interfaceFilter:
+		return Clause{Expression: expression(f), Matched: f.Matches(entry), Reason: f.reason(entry)}
+	case *andFilter:
+		left, right := Trace(f.left, entry), Trace(f.right, entry)
+		return Clause{Expression: expression(f), Matched: left.Matched && right.Matched, Clauses: []Clause{left, right}}
+	case *orFilter:
+		left, right := Trace(f.left, entry), Trace(f.right, entry)
+		return Clause{Expression: expression(f), Matched: left.Matched || right.Matched, Clauses: []Clause{left, right}}
+	case *notFilter:
+		child := Trace(f.child, entry)
+		return Clause{Expression: expression(f), Matched: !child.Matched, Clauses: []Clause{child}}
+	}
+	return Clause{Matched: true}
+}
+
+// Error returns the description of the error with its (1-based) position
+func (e *Error) Error() string {
+	return fmt.Sprintf("error(filter): %s at position %d", e.Msg, e.Pos+1)
+}
+
+// Highlights returns the fields (action, destination, direction, dstport, interface, origin, protocol, reason,
+// severity, source, srcport or time) that explain why the filter matches the entry, sorted and without duplicates
+// (none if it doesn't match, operands of not are never highlighted)
+func Highlights(node FilterNode, entry *stream.LogEntry) []string {
+	if node == nil {
+		return nil
+	}
+	fields := highlights(node, entry, nil)
+	slices.Sort(fields)
+	return slices.Compact(fields)
+}
+
+// Instrument returns node with every clause wrapped in a counter of s (node is returned as is if s is nil), the
+// instrumented filter matches the same entries but isn't recognized by Columnar and Terms
+func (s *Stats) Instrument(node FilterNode) FilterNode {
+	if s == nil || node == nil {
+		return node
+	}
+	return s.instrument(node, 0)
+}
+
+// Clauses returns the counts of all clauses of the instrumented filters (operators before their operands)
+func (s *Stats) Clauses() []ClauseStats {
+	clauses := make([]ClauseStats, len(s.counters))
+	for i, c := range s.counters {
+		clauses[i] = c.stats
+	}
+	return clauses
+}
+
+// Warnings returns likely mistakes that don't make the filter invalid (values that look like misspelled field names)
+func Warnings(node FilterNode) []string {
+	switch f := node.(type) {
+	case *anyFilter:
+		if f.suggestion != "" {
+			return []string{fmt.Sprintf("%q is not a field and is searched in all fields (did you mean %q? quote it to search for the value)", f.value, f.suggestion)}
+		}
+	case *andFilter:
+		return append(Warnings(f.left), Warnings(f.right)...)
+	case *orFilter:
+		return append(Warnings(f.left), Warnings(f.right)...)
+	case *notFilter:
+		return Warnings(f.child)
+	case *countFilter:
+		return Warnings(f.child)
+	}
+	return nil
+}
+
+// IsKeyword returns true if the word is read as operator, field name or shorthand (e.g. blocked) unless quoted
+func IsKeyword(word string) bool {
+	word = strings.ToLower(word)
+	_, operator := tokens[word]
+	_, field := fields[word]
+	_, shorthand := shorthands[word]
+	return operator || field || shorthand
+}
+
+// IsField returns true if the word is a field name or alias (e.g. dport)
+func IsField(word string) bool {
+	_, ok := fields[strings.ToLower(word)]
+	return ok
+}
+
+// Term returns the term matching the value in the field (quoted where needed, interface names of opts included),
+// negated with not if negate is set
+func Term(field, value string, negate bool, opts Options) string {
+	_, named := lowerKeys(opts.Interfaces)[strings.ToLower(value)]
+	term := strings.ToLower(field) + " " + quote(value, named)
+	if negate {
+		return "not " + term
+	}
+	return term
+}
+
+// Compile compiles a filter expression string into a FilterNode tree (with default options)
 func Compile(expression string) (FilterNode, error) {
+	return CompileWith(expression, Options{})
+}
+
+// CompileWith compiles a filter expression string into a FilterNode tree with the given options
+func CompileWith(expression string, opts Options) (FilterNode, error) {
 	if expression == "" {
 		return nil, nil
 	}
-	parser := newParser(expression)
+	parser := newParser(expression, opts)
 	return parser.parse()
 }
@@ -24,7 +24,12 @@
 package filter
 
 import (
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
 )
@@ -116,6 +121,108 @@ func TestAnyFilter(t *testing.T) {
 
 func TestFieldFilter(t *testing.T) {
 	tests := []test{
+		{
+			name:        "match source class",
+			filter:      "srcclass private",
+			entry:       stream.LogEntry{Src: "192.168.1.1", SrcClass: "private", DstClass: "public"},
+			expectMatch: true,
+		},
+		{
+			name:        "match destination class prefix",
+			filter:      "dstclass pub",
+			entry:       stream.LogEntry{SrcClass: "private", DstClass: "public"},
+			expectMatch: true,
+		},
+		{
+			name:        "do not match other class",
+			filter:      "srcclass public",
+			entry:       stream.LogEntry{SrcClass: "private", DstClass: "public"},
+			expectMatch: false,
+		},
+		{
+			name:        "match icmp type",
+			filter:      "icmptype 135",
+			entry:       stream.LogEntry{ProtoName: "ipv6-icmp", ICMPType: "135"},
+			expectMatch: true,
+		},
+		{
+			name:        "do not match other icmp type",
+			filter:      "icmptype 135",
+			entry:       stream.LogEntry{ProtoName: "ipv6-icmp", ICMPType: "128"},
+			expectMatch: false,
+		},
+		{
+			name:        "match tcp flags in any order",
+			filter:      "tcpflags as",
+			entry:       stream.LogEntry{ProtoName: "tcp", TCPFlags: "SA"},
+			expectMatch: true,
+		},
+		{
+			name:        "do not match missing tcp flag",
+			filter:      "tcpflags SR",
+			entry:       stream.LogEntry{ProtoName: "tcp", TCPFlags: "S"},
+			expectMatch: false,
+		},
+		{
+			name:        "match rule number",
+			filter:      "rule 12",
+			entry:       stream.LogEntry{RuleNum: "12"},
+			expectMatch: true,
+		},
+		{
+			name:        "do not match rule number prefix",
+			filter:      "rulenr 1",
+			entry:       stream.LogEntry{RuleNum: "12"},
+			expectMatch: false,
+		},
+		{
+			name:        "match anchor and subrule",
+			filter:      "anchor miniupnpd and subrule 3",
+			entry:       stream.LogEntry{Anchor: "miniupnpd", RuleNum: "12", SubRuleNum: "3"},
+			expectMatch: true,
+		},
+		{
+			name:        "match tracker",
+			filter:      "tracker 02f4bab0",
+			entry:       stream.LogEntry{Label: "02f4bab031b57d1e30553ce08e0ec131"},
+			expectMatch: true,
+		},
+		{
+			name:        "match severity level",
+			filter:      "severity warning",
+			entry:       stream.LogEntry{Severity: stream.SeverityWarning},
+			expectMatch: true,
+		},
+		{
+			name:        "match higher severity level",
+			filter:      "sev warning",
+			entry:       stream.LogEntry{Severity: stream.SeverityCritical},
+			expectMatch: true,
+		},
+		{
+			name:        "do not match lower severity level",
+			filter:      "severity warning",
+			entry:       stream.LogEntry{Severity: stream.SeverityNotice},
+			expectMatch: false,
+		},
+		{
+			name:        "do not match unclassified entry",
+			filter:      "severity info",
+			entry:       stream.LogEntry{},
+			expectMatch: false,
+		},
+		{
+			name:        "match origin prefix",
+			filter:      "origin fw",
+			entry:       stream.LogEntry{Origin: "fw2.example.com"},
+			expectMatch: true,
+		},
+		{
+			name:        "do not match wrong origin",
+			filter:      "origin fw1",
+			entry:       stream.LogEntry{Origin: "fw2.example.com"},
+			expectMatch: false,
+		},
 		{
 			name:        "match source ip exact",
 			filter:      "source 192.168.1.1",
@@ -164,6 +271,18 @@ func TestFieldFilter(t *testing.T) {
 			entry:       stream.LogEntry{ProtoName: "udp"},
 			expectMatch: true,
 		},
+		{
+			name:        "match protocol number",
+			filter:      "proto 112",
+			entry:       stream.LogEntry{ProtoName: "carp", ProtoNum: 112},
+			expectMatch: true,
+		},
+		{
+			name:        "do not match protocol number prefix",
+			filter:      "proto 11",
+			entry:       stream.LogEntry{ProtoName: "carp", ProtoNum: 112},
+			expectMatch: false,
+		},
 		{
 			name:        "match action",
 			filter:      "action block",
@@ -524,3 +643,521 @@ func TestEdge(t *testing.T) {
 	}
 	runTests(t, tests)
 }
+
+func TestTimeFields(t *testing.T) {
+	// 2025-10-11 is a saturday
+	at := func(day, hour int) stream.LogEntry {
+		return stream.LogEntry{Time: time.Date(2025, 10, day, hour, 30, 0, 0, time.FixedZone("", 2*60*60))}
+	}
+	tests := []test{
+		{name: "single hour", filter: "hour 23", entry: at(11, 23), expectMatch: true},
+		{name: "other hour", filter: "hour 22", entry: at(11, 23), expectMatch: false},
+		{name: "range", filter: "hour 9-17", entry: at(11, 17), expectMatch: true},
+		{name: "outside range", filter: "hour 9-17", entry: at(11, 18), expectMatch: false},
+		{name: "wrapping range late", filter: "hour 22-06", entry: at(11, 23), expectMatch: true},
+		{name: "wrapping range early", filter: "hour 22-06", entry: at(11, 6), expectMatch: true},
+		{name: "wrapping range outside", filter: "hour 22-06", entry: at(11, 7), expectMatch: false},
+		{name: "list", filter: "hour 8,12-13", entry: at(11, 12), expectMatch: true},
+		{name: "time as logged", filter: "hour 0", entry: at(11, 0), expectMatch: true},
+		{name: "weekday", filter: "weekday sat,sun", entry: at(11, 12), expectMatch: true},
+		{name: "other weekday", filter: "weekday sat,sun", entry: at(13, 12), expectMatch: false},
+		{name: "weekday range", filter: "weekday mon-fri", entry: at(13, 12), expectMatch: true},
+		{name: "wrapping weekday range", filter: "weekday fri-mon", entry: at(12, 12), expectMatch: true},
+		{name: "full weekday name", filter: "weekday Saturday", entry: at(11, 12), expectMatch: true},
+		{name: "outside business hours", filter: "weekday sat,sun or not hour 9-16", entry: at(13, 20), expectMatch: true},
+		{name: "invalid hour", filter: "hour 24", expectError: true},
+		{name: "invalid hour range", filter: "hour 22-", expectError: true},
+		{name: "invalid weekday", filter: "weekday sa", expectError: true},
+		{name: "invalid weekday list", filter: "weekday sat,,sun", expectError: true},
+	}
+	runTests(t, tests)
+
+	_, err := Compile("action block and hour 9-25")
+	var ferr *Error
+	if !errors.As(err, &ferr) || ferr.Pos != 22 || ferr.Msg != `invalid hour "9-25" (e.g. 22-06 or 8,12-13)` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestAge(t *testing.T) {
+	ago := func(d time.Duration) stream.LogEntry {
+		return stream.LogEntry{Time: time.Now().Add(-d)}
+	}
+	tests := []test{
+		{name: "newer", filter: "age < 10m", entry: ago(time.Minute), expectMatch: true},
+		{name: "not newer", filter: "age < 10m", entry: ago(time.Hour), expectMatch: false},
+		{name: "joined comparison", filter: "age <=10m", entry: ago(time.Minute), expectMatch: true},
+		{name: "omitted comparison", filter: "age 10m", entry: ago(time.Minute), expectMatch: true},
+		{name: "older", filter: "age > 1h", entry: ago(2 * time.Hour), expectMatch: true},
+		{name: "not older", filter: "age >= 1h", entry: ago(time.Minute), expectMatch: false},
+		{name: "combined", filter: "action block and age < 1m", entry: stream.LogEntry{Action: "block", Time: time.Now()}, expectMatch: true},
+		{name: "missing duration", filter: "age <", expectError: true},
+		{name: "invalid duration", filter: "age < 10", expectError: true},
+		{name: "double comparison", filter: "age <<10m", expectError: true},
+		{name: "negative duration", filter: "age < -1m", expectError: true},
+	}
+	runTests(t, tests)
+
+	node, err := Compile("age < 10m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := Trace(node, &stream.LogEntry{Time: time.Now().Add(-90 * time.Second)}); got.Expression != "age <10m" || got.Reason != "age is 1m30s" {
+		t.Fatalf("unexpected trace %+v", got)
+	}
+}
+
+func TestLength(t *testing.T) {
+	tcp := func(length, dataLen uint32) stream.LogEntry {
+		return stream.LogEntry{ProtoName: "tcp", Length: length, DataLen: dataLen}
+	}
+	tests := []test{
+		{name: "exact", filter: "length 1500", entry: tcp(1500, 1460), expectMatch: true},
+		{name: "greater", filter: "length > 1000", entry: tcp(1500, 1460), expectMatch: true},
+		{name: "not greater", filter: "len >1500", entry: tcp(1500, 1460), expectMatch: false},
+		{name: "at most", filter: "length <= 1500", entry: tcp(1500, 1460), expectMatch: true},
+		{name: "range", filter: "length 40-60", entry: tcp(52, 0), expectMatch: true},
+		{name: "not logged", filter: "length < 100", entry: stream.LogEntry{ProtoName: "tcp"}, expectMatch: false},
+		{name: "empty data", filter: "datalen 0", entry: tcp(40, 0), expectMatch: true},
+		{name: "no data length", filter: "datalen < 10", entry: stream.LogEntry{ProtoName: "icmp", Length: 84}, expectMatch: false},
+		{name: "missing length", filter: "length >", expectError: true},
+		{name: "invalid length", filter: "length 1k", expectError: true},
+		{name: "reversed range", filter: "datalen 60-40", expectError: true},
+		{name: "less than zero", filter: "datalen < 0", expectError: true},
+	}
+	runTests(t, tests)
+
+	node, err := Compile("datalen > 1000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := tcp(1500, 1460)
+	if got := Trace(node, &entry); got.Expression != "datalen >1000" || got.Reason != "datalen is 1460" {
+		t.Fatalf("unexpected trace %+v", got)
+	}
+}
+
+func TestQuoted(t *testing.T) {
+	tests := []test{
+		{
+			name:        "value with spaces",
+			filter:      `"Jan 02"`,
+			entry:       stream.LogEntry{Time: time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)},
+			expectMatch: true,
+		},
+		{
+			name:        "field value with spaces",
+			filter:      `origin "branch office"`,
+			entry:       stream.LogEntry{Origin: "branch office"},
+			expectMatch: true,
+		},
+		{
+			name:        "operator as value",
+			filter:      `iface "and"`,
+			entry:       stream.LogEntry{Interface: "and0"},
+			expectMatch: true,
+		},
+		{
+			name:        "field name as value",
+			filter:      `"port" or action block`,
+			entry:       stream.LogEntry{Action: "pass", Interface: "port1"},
+			expectMatch: true,
+		},
+		{
+			name:        "parentheses in value",
+			filter:      `("(lan)" and action pass)`,
+			entry:       stream.LogEntry{Action: "pass", Interface: "(lan)"},
+			expectMatch: true,
+		},
+		{
+			name:        "escaped quote",
+			filter:      `iface "a\"b"`,
+			entry:       stream.LogEntry{Interface: `a"b`},
+			expectMatch: true,
+		},
+		{
+			name:        "escaped backslash",
+			filter:      `iface "a\\"`,
+			entry:       stream.LogEntry{Interface: `a\`},
+			expectMatch: true,
+		},
+		{
+			name:        "empty value",
+			filter:      `iface ""`,
+			entry:       stream.LogEntry{Interface: "eth0"},
+			expectMatch: true,
+		},
+		{
+			name:        "unterminated quote",
+			filter:      `iface "eth0`,
+			expectError: true,
+		},
+		{
+			name:        "unterminated quote after expression",
+			filter:      `action block and "eth0`,
+			expectError: true,
+		},
+		{
+			name:        "unterminated escape",
+			filter:      `"eth0\`,
+			expectError: true,
+		},
+	}
+	runTests(t, tests)
+
+	// expressions of traces are quoted so they can be pasted back
+	for _, expression := range []string{`iface "a b"`, `"and"`, `iface "a\"b\\"`, `src ""`} {
+		node, err := Compile(expression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := Trace(node, &stream.LogEntry{}).Expression; got != expression {
+			t.Errorf("expected %s, got %s", expression, got)
+		}
+	}
+}
+
+func TestError(t *testing.T) {
+	tests := []struct {
+		filter    string
+		expectMsg string
+		expectPos int
+	}{
+		{"src and", `expected value after field "src" but got "and"`, 4},
+		{"action", `expected value after field "action" but got end of expression`, 6},
+		{"  (src 10.0.0.1", `expected ")" but got end of expression`, 15},
+		{"port 22 )", `unexpected ")"`, 8},
+		{"action block or", `expected value, field or "(" but got end of expression`, 15},
+		{`iface "eth0`, "unterminated quoted value", 6},
+		{`(iface eth0 "lan`, "unterminated quoted value", 12},
+		{"dprt 22", `unknown field "dprt" (did you mean "dport"?)`, 0},
+		{"action block and protocl tcp", `unknown field "protocl" (did you mean "protocol"?)`, 17},
+		{"blocked 22", `unexpected "22"`, 8},
+	}
+	// adjacent terms are only an error in strict mode
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			_, err := CompileWith(tt.filter, Options{Strict: true})
+			var ferr *Error
+			if !errors.As(err, &ferr) {
+				t.Fatalf("expected *Error, got %v", err)
+			}
+			if ferr.Msg != tt.expectMsg || ferr.Pos != tt.expectPos {
+				t.Errorf("expected %q at %d, got %q at %d", tt.expectMsg, tt.expectPos, ferr.Msg, ferr.Pos)
+			}
+		})
+	}
+}
+
+func TestImplicitAnd(t *testing.T) {
+	entry := stream.LogEntry{Action: "block", DstPort: 443, Interface: "igb0", ProtoName: "tcp", Src: "10.0.0.1"}
+	tests := []struct {
+		filter      string
+		expected    string
+		expectMatch bool
+	}{
+		{"src 10.0.0.1 dport 443", "and(src 10.0.0.1, dport 443)", true},
+		{"src 10.0.0.1 dport 80", "and(src 10.0.0.1, dport 80)", false},
+		{"block tcp igb0", "and(and(block, tcp), igb0)", true},
+		{"action block not proto udp", "and(action block, not(proto udp))", true},
+		{"iface igb0 (dport 80 or dport 443)", "and(iface igb0, or(dport 80, dport 443))", true},
+		{"src 10.0.0.2 dport 443 or action block", "or(and(src 10.0.0.2, dport 443), action block)", true},
+		{"src 10.0.0.1 and dport 443 tcp", "and(and(src 10.0.0.1, dport 443), tcp)", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			node, err := Compile(tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := format(Trace(node, &entry)); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+			if match := node.Matches(&entry); match != tt.expectMatch {
+				t.Errorf("expected %v, got %v", tt.expectMatch, match)
+			}
+		})
+	}
+
+	if _, err := CompileWith("src 10.0.0.1 dport 443", Options{Strict: true}); err == nil {
+		t.Fatal("expected error in strict mode, got nil")
+	}
+}
+
+func TestShorthands(t *testing.T) {
+	opts := Options{Interfaces: map[string][]string{"WAN": {"igb0", "pppoe0"}, "lan": {"igb1"}}}
+	entry := stream.LogEntry{Action: "block", Direction: "in", Interface: "pppoe0", Src: "10.0.0.1"}
+	tests := []struct {
+		filter      string
+		expected    string
+		expectMatch bool
+	}{
+		{"blocked", "action block", true},
+		{"passed", "action pass", false},
+		{"inbound", "direction in", true},
+		{"OUTBOUND", "direction out", false},
+		{"wan", "wan", true},
+		{"lan", "lan", false},
+		{"iface wan", "wan", true},
+		{"blocked and inbound and wan", "and(and(action block, direction in), wan)", true},
+		{"blocked inbound not lan", "and(and(action block, direction in), not(lan))", true},
+		{`"blocked"`, `"blocked"`, false},
+		{`"wan"`, `"wan"`, false},
+		{"iface pppoe", "iface pppoe", true},
+		{"dmz", "dmz", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			node, err := CompileWith(tt.filter, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := format(Trace(node, &entry)); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+			if match := node.Matches(&entry); match != tt.expectMatch {
+				t.Errorf("expected %v, got %v", tt.expectMatch, match)
+			}
+		})
+	}
+
+	// interface names match whole interfaces only
+	node, _ := CompileWith("wan", opts)
+	if node.Matches(&stream.LogEntry{Interface: "igb01"}) {
+		t.Error("expected igb01 not to match wan")
+	}
+}
+
+// format returns the clause as operator(operands...) to compare tree shapes
+func format(c Clause) string {
+	if len(c.Clauses) == 0 {
+		return c.Expression
+	}
+	operands := make([]string, len(c.Clauses))
+	for i, operand := range c.Clauses {
+		operands[i] = format(operand)
+	}
+	return c.Expression + "(" + strings.Join(operands, ", ") + ")"
+}
+
+func TestWarnings(t *testing.T) {
+	tests := []struct {
+		filter   string
+		expected []string
+	}{
+		{"sr 10.0.0.1", []string{`"sr" is not a field and is searched in all fields (did you mean "src"? quote it to search for the value)`}},
+		{"action block or (not dprt 22)", []string{`"dprt" is not a field and is searched in all fields (did you mean "dport"? quote it to search for the value)`}},
+		{"sr and 10.0.0.1", nil},
+		{`"sr" 10.0.0.1`, nil},
+		{"src 10.0.0.1", nil},
+		{"sr", nil},
+		{"blocked 22", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			node, err := Compile(tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := Warnings(node); !slices.Equal(got, tt.expected) {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+			if got := Warnings((&Stats{}).Instrument(node)); !slices.Equal(got, tt.expected) {
+				t.Errorf("expected %q for instrumented filter, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestColumnar(t *testing.T) {
+	opts := Options{Interfaces: map[string][]string{"wan": {"igb0"}}}
+	tests := []struct {
+		filter   string
+		expected bool
+	}{
+		{"action block", true},
+		{"dport 22 and iface igb0", true},
+		{"(sport 53 or port 123) and not ip 6", true},
+		{"action block and src 10.0.0.1", false},
+		{"not proto tcp", false},
+		{"block", false},
+		{"blocked and wan", true},
+		{"blocked and inbound", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			node, err := CompileWith(tt.filter, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := Columnar(node); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestTerms(t *testing.T) {
+	tests := []struct {
+		filter   string
+		expected []string
+	}{
+		{"10.0.0.1", []string{"10.0.0.1"}},
+		{"LAN and action block and Eth0", []string{"lan", "eth0"}},
+		{"(foo and bar) and baz", []string{"foo", "bar", "baz"}},
+		{"foo or bar", nil},
+		{"not foo", nil},
+		{"action block", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			node, err := Compile(tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := Terms(node); !slices.Equal(got, tt.expected) {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestTerm(t *testing.T) {
+	opts := Options{Interfaces: map[string][]string{"WAN": {"igb0"}}}
+	tests := []struct {
+		field    string
+		value    string
+		negate   bool
+		expected string
+	}{
+		{"dport", "443", false, "dport 443"},
+		{"Action", "block", true, "not action block"},
+		{"reason", "ip-option", false, "reason ip-option"},
+		{"src", "blocked", false, `src "blocked"`},
+		{"iface", `a "b"`, false, `iface "a \"b\""`},
+		{"src", "", false, `src ""`},
+		{"iface", "wan", false, `iface "wan"`},
+		{"iface", "igb0", false, "iface igb0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			got := Term(tt.field, tt.value, tt.negate, opts)
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+			if !IsField(tt.field) {
+				t.Errorf("expected %q to be a field", tt.field)
+			}
+			if tt.value != "" {
+				if _, err := CompileWith(got, opts); err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			}
+		})
+	}
+	if IsField("and") || IsField("blocked") {
+		t.Error("expected operators and shorthands not to be fields")
+	}
+}
+
+func TestTrace(t *testing.T) {
+	entry := stream.LogEntry{Action: "block", DstPort: 22, Interface: "igb0", Src: "10.0.0.1", SrcPort: 50000}
+	tests := []struct {
+		filter   string
+		expected Clause
+	}{
+		{"action block", Clause{Expression: "action block", Matched: true, Reason: `action is "block"`}},
+		{"10.0.0", Clause{Expression: "10.0.0", Matched: true, Reason: `found in "10.0.0.1"`}},
+		{"action block and (port 80 or not iface igb0)", Clause{Expression: "and", Clauses: []Clause{
+			{Expression: "action block", Matched: true, Reason: `action is "block"`},
+			{Expression: "or", Clauses: []Clause{
+				{Expression: "port 80", Reason: "srcport is 50000, dstport is 22"},
+				{Expression: "not", Clauses: []Clause{{Expression: "iface igb0", Matched: true, Reason: `interface is "igb0"`}}},
+			}},
+		}}},
+		{"lan or dport 22", Clause{Expression: "or", Matched: true, Clauses: []Clause{
+			{Expression: "lan", Reason: "not found in any field"},
+			{Expression: "dport 22", Matched: true, Reason: "dstport is 22"},
+		}}},
+		{"", Clause{Matched: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			node, err := Compile(tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := Trace(node, &entry)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+			if node != nil && got.Matched != node.Matches(&entry) {
+				t.Errorf("expected trace to agree with Matches")
+			}
+		})
+	}
+}
+
+func TestStats(t *testing.T) {
+	entries := []stream.LogEntry{
+		{Action: "block", DstPort: 22},
+		{Action: "block", DstPort: 443},
+		{Action: "pass", DstPort: 22},
+	}
+	node, err := Compile("action block and (port 22 or not proto tcp)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s *Stats
+	if s.Instrument(node) != node {
+		t.Fatal("expected nil stats to return the filter as is")
+	}
+	s = &Stats{}
+	instrumented := s.Instrument(node)
+	for i := range entries {
+		if got := instrumented.Matches(&entries[i]); got != node.Matches(&entries[i]) {
+			t.Fatalf("entry %d: expected instrumented filter to return %v", i, !got)
+		}
+	}
+	expected := []ClauseStats{
+		{Expression: "and", Depth: 0, Evaluated: 3, Matched: 2},
+		{Expression: "action block", Depth: 1, Evaluated: 3, Matched: 2},
+		{Expression: "or", Depth: 1, Evaluated: 2, Matched: 2},
+		{Expression: "port 22", Depth: 2, Evaluated: 2, Matched: 1},
+		{Expression: "not", Depth: 2, Evaluated: 1, Matched: 1},
+		{Expression: "proto tcp", Depth: 3, Evaluated: 1, Matched: 0},
+	}
+	if got := s.Clauses(); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestHighlights(t *testing.T) {
+	entry := stream.LogEntry{Action: "block", Direction: "in", DstPort: 22, Interface: "igb0", Src: "10.0.0.1",
+		SrcPort: 50000, Dst: "10.0.0.2", ProtoName: "tcp"}
+	tests := []struct {
+		filter   string
+		expected []string
+	}{
+		{"action block", []string{"action"}},
+		{"10.0.0", []string{"destination", "source"}},
+		{"src 10.0 and port 22", []string{"dstport", "source"}},
+		{"action pass or proto tcp", []string{"protocol"}},
+		{"blocked and not iface igb1", []string{"action"}},
+		{"action pass", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			node, err := Compile(tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := Highlights(node, &entry); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
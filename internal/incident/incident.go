@@ -0,0 +1,217 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package incident
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/bookmark"
+	"gitlab.com/allddd/opnsense-filterlog/internal/manifest"
+	"gitlab.com/allddd/opnsense-filterlog/internal/report"
+	"gitlab.com/allddd/opnsense-filterlog/internal/sandbox"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+const (
+	// files of the bundle
+	fileBookmarks = "bookmarks.json"
+	fileEntries   = "entries.ndjson"
+	fileFilter    = "filter.txt"
+	fileManifest  = "manifest.json"
+	fileRaw       = "raw.log"
+	fileSummary   = "summary.md"
+)
+
+// Bundle describes the entries written to an incident bundle
+type Bundle struct {
+	Bookmarks []bookmark.Bookmark         // bookmarks of the log file (only those of selected entries are written)
+	Filter    string                      // filter expression the entries were selected with (empty if none)
+	Manifest  bool                        // whether to write a manifest with the checksums of the other files
+	Match     func(*stream.LogEntry) bool // selects the entries (all entries if nil)
+}
+
+// archive reports whether path is a tar.gz archive (a directory otherwise)
+func archive(path string) bool {
+	return strings.HasSuffix(path, ".tar.gz") || strings.HasSuffix(path, ".tgz")
+}
+
+// writeFile creates a file in dir and writes its content using fn
+func writeFile(dir, name string, fn func(w io.Writer) error) error {
+	file, err := sandbox.Create(filepath.Join(dir, name))
+	if err != nil {
+		return fmt.Errorf("error(incident): %w", err)
+	}
+	w := bufio.NewWriter(file)
+	if err := fn(w); err != nil {
+		file.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return fmt.Errorf("error(incident): could not write %s: %w", name, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("error(incident): could not write %s: %w", name, err)
+	}
+	return nil
+}
+
+// writeDir writes the bundle into dir and returns the number of entries
+func (b Bundle) writeDir(dir string, s *stream.Stream) (int, error) {
+	if err := sandbox.MkdirAll(dir, 0o755); err != nil {
+		return 0, fmt.Errorf("error(incident): %w", err)
+	}
+	r := report.New(s.GetPathRel(), b.Filter)
+	count := 0
+	raw, err := sandbox.Create(filepath.Join(dir, fileRaw))
+	if err != nil {
+		return 0, fmt.Errorf("error(incident): %w", err)
+	}
+	defer raw.Close()
+	rawWriter := bufio.NewWriter(raw)
+	err = writeFile(dir, fileEntries, func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		for entry := s.Next(); entry != nil; entry = s.Next() {
+			if b.Match != nil && !b.Match(entry) {
+				continue
+			}
+			if err := encoder.Encode(entry); err != nil {
+				return fmt.Errorf("error(incident): could not write %s: %w", fileEntries, err)
+			}
+			if _, err := fmt.Fprintln(rawWriter, s.Raw()); err != nil {
+				return fmt.Errorf("error(incident): could not write %s: %w", fileRaw, err)
+			}
+			r.Add(entry)
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if err := rawWriter.Flush(); err != nil {
+		return 0, fmt.Errorf("error(incident): could not write %s: %w", fileRaw, err)
+	}
+	if err := raw.Close(); err != nil {
+		return 0, fmt.Errorf("error(incident): could not write %s: %w", fileRaw, err)
+	}
+	r.Errors = len(s.GetErrors())
+	r.Bookmarks = make([]bookmark.Bookmark, 0, len(b.Bookmarks))
+	for _, bm := range b.Bookmarks {
+		if b.Match == nil || b.Match(&bm.Entry) {
+			r.Bookmarks = append(r.Bookmarks, bm)
+		}
+	}
+	err = writeFile(dir, fileBookmarks, func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(r.Bookmarks); err != nil {
+			return fmt.Errorf("error(incident): could not write %s: %w", fileBookmarks, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	err = writeFile(dir, fileFilter, func(w io.Writer) error {
+		if _, err := fmt.Fprintln(w, b.Filter); err != nil {
+			return fmt.Errorf("error(incident): could not write %s: %w", fileFilter, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if err := writeFile(dir, fileSummary, r.WriteMarkdown); err != nil {
+		return 0, err
+	}
+	if b.Manifest {
+		m := manifest.New(s.GetPathRel(), b.Filter, count, r.Errors)
+		for _, name := range []string{fileBookmarks, fileEntries, fileFilter, fileRaw, fileSummary} {
+			if err := m.Add(filepath.Join(dir, name), name); err != nil {
+				return 0, err
+			}
+		}
+		if err := m.Write(filepath.Join(dir, fileManifest)); err != nil {
+			return 0, err
+		}
+	}
+	return count, nil
+}
+
+// writeArchive writes the files in dir into a tar.gz archive at path
+func writeArchive(path, dir string) error {
+	file, err := sandbox.Create(path)
+	if err != nil {
+		return fmt.Errorf("error(incident): %w", err)
+	}
+	defer file.Close()
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+	if err := tw.AddFS(sandbox.DirFS(dir)); err != nil {
+		return fmt.Errorf("error(incident): could not write archive: %w", err)
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("error(incident): could not write archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("error(incident): could not write archive: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("error(incident): could not write archive: %w", err)
+	}
+	return nil
+}
+
+// public
+
+// Write reads all entries of s and writes the selected ones with raw lines, filter expression, summary, bookmarks and
+// manifest (if enabled) into the directory at path (or a tar.gz archive if path ends with .tar.gz or .tgz), returns the number of entries
+func (b Bundle) Write(path string, s *stream.Stream) (int, error) {
+	if !archive(path) {
+		return b.writeDir(path, s)
+	}
+	tmp, err := sandbox.MkdirTemp(filepath.Dir(path), ".incident-*")
+	if err != nil {
+		return 0, fmt.Errorf("error(incident): %w", err)
+	}
+	defer sandbox.RemoveAll(tmp)
+	prefix := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(path), ".tgz"), ".tar.gz")
+	count, err := b.writeDir(filepath.Join(tmp, prefix), s)
+	if err != nil {
+		return 0, err
+	}
+	if err := writeArchive(path, tmp); err != nil {
+		sandbox.Remove(path)
+		return 0, err
+	}
+	return count, nil
+}
@@ -0,0 +1,213 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package incident
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/bookmark"
+	"gitlab.com/allddd/opnsense-filterlog/internal/manifest"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+// testBundle returns a bundle selecting the blocked entries
+func testBundle() Bundle {
+	return Bundle{
+		Bookmarks: []bookmark.Bookmark{
+			{Line: 1, Note: "first attempt", Entry: stream.LogEntry{Action: stream.ActionBlock}},
+			{Line: 2, Note: "passed", Entry: stream.LogEntry{Action: stream.ActionPass}},
+		},
+		Filter: "action block",
+		Match:  func(e *stream.LogEntry) bool { return e.Action == stream.ActionBlock },
+	}
+}
+
+// blockedLines returns the raw lines of blocked entries in the test log
+func blockedLines(t *testing.T) []string {
+	t.Helper()
+	content, err := os.ReadFile("../../tests/filter_bruteforce.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := make([]string, 0)
+	for line := range strings.Lines(string(content)) {
+		if strings.Contains(line, ",block,") {
+			lines = append(lines, strings.TrimSuffix(line, "\n"))
+		}
+	}
+	return lines
+}
+
+// checkFiles checks the content of the bundle files
+func checkFiles(t *testing.T, files map[string]string, raw []string) {
+	t.Helper()
+	if got := strings.Split(strings.TrimSuffix(files[fileRaw], "\n"), "\n"); !slices.Equal(got, raw) {
+		t.Errorf("expected raw lines %q, got %q", raw, got)
+	}
+	if got := strings.Count(files[fileEntries], "\n"); got != len(raw) {
+		t.Errorf("expected %d entries, got %d", len(raw), got)
+	}
+	if strings.Contains(files[fileEntries], `"action":"pass"`) {
+		t.Error("expected only blocked entries")
+	}
+	if files[fileFilter] != "action block\n" {
+		t.Errorf("expected filter expression, got %q", files[fileFilter])
+	}
+	if !strings.Contains(files[fileBookmarks], "first attempt") || strings.Contains(files[fileBookmarks], "passed") {
+		t.Errorf("expected bookmarks of selected entries only, got %q", files[fileBookmarks])
+	}
+	for _, s := range []string{"- Filter: `action block`", "first attempt"} {
+		if !strings.Contains(files[fileSummary], s) {
+			t.Errorf("expected summary to contain %q", s)
+		}
+	}
+}
+
+func TestWriteDir(t *testing.T) {
+	s, err := stream.NewStream("../../tests/filter_bruteforce.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	dir := filepath.Join(t.TempDir(), "incident")
+	raw := blockedLines(t)
+	count, err := testBundle().Write(dir, s)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count != len(raw) {
+		t.Errorf("expected %d entries, got %d", len(raw), count)
+	}
+	files := make(map[string]string)
+	for _, name := range []string{fileBookmarks, fileEntries, fileFilter, fileRaw, fileSummary} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[name] = string(data)
+	}
+	checkFiles(t, files, raw)
+	if _, err := os.Stat(filepath.Join(dir, fileManifest)); err == nil {
+		t.Error("expected no manifest unless enabled")
+	}
+}
+
+func TestWriteManifest(t *testing.T) {
+	s, err := stream.NewStream("../../tests/filter_bruteforce.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	dir := filepath.Join(t.TempDir(), "incident")
+	b := testBundle()
+	b.Manifest = true
+	count, err := b.Write(dir, s)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, fileManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m manifest.Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Entries != count || m.Filter != "action block" || len(m.Files) != 5 {
+		t.Errorf("expected %d entries, filter and 5 files, got %+v", count, m)
+	}
+	for _, f := range m.Files {
+		data, err := os.ReadFile(filepath.Join(dir, f.Name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != f.SHA256 || int64(len(data)) != f.Size {
+			t.Errorf("expected checksum and size of %s to match its content", f.Name)
+		}
+	}
+}
+
+func TestWriteArchive(t *testing.T) {
+	s, err := stream.NewStream("../../tests/filter_bruteforce.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "incident-42.tar.gz")
+	if _, err := testBundle().Write(path, s); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		name, ok := strings.CutPrefix(header.Name, "incident-42/")
+		if !ok {
+			t.Fatalf("expected files in incident-42/, got %s", header.Name)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[name] = string(data)
+	}
+	checkFiles(t, files, blockedLines(t))
+	// the temporary directory is removed
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected only the archive in %s, got %d files", dir, len(entries))
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package manifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/meta"
+	"gitlab.com/allddd/opnsense-filterlog/internal/sandbox"
+)
+
+// File is a file covered by a manifest
+type File struct {
+	Name   string `json:"name"`   // path relative to the manifest
+	SHA256 string `json:"sha256"` // hex encoded checksum of the content
+	Size   int64  `json:"size"`   // size in bytes
+}
+
+// Manifest lists the files of an export with their checksums and the counts of the export
+type Manifest struct {
+	Created time.Time `json:"created"`          // time the manifest was created
+	Entries int       `json:"entries"`          // number of entries exported
+	Errors  int       `json:"errors,omitempty"` // number of parse errors
+	Files   []File    `json:"files"`            // exported files
+	Filter  string    `json:"filter,omitempty"` // filter expression the entries were selected with
+	Source  string    `json:"source"`           // log file the entries were read from
+	Version string    `json:"version"`          // version of the build that exported the entries
+}
+
+// checksum returns the sha256 checksum and size of the file at path
+func checksum(path string) (string, int64, error) {
+	file, err := sandbox.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, file)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+// public
+
+// New returns the manifest of entries exported from source now
+func New(source, filter string, entries, errors int) *Manifest {
+	return &Manifest{
+		Created: time.Now().UTC().Truncate(time.Second),
+		Entries: entries,
+		Errors:  errors,
+		Files:   make([]File, 0),
+		Filter:  filter,
+		Source:  source,
+		Version: meta.Version,
+	}
+}
+
+// Add adds the file at path as name (relative to the manifest)
+func (m *Manifest) Add(path, name string) error {
+	sum, size, err := checksum(path)
+	if err != nil {
+		return fmt.Errorf("error(manifest): could not checksum %s: %w", name, err)
+	}
+	m.Files = append(m.Files, File{Name: name, SHA256: sum, Size: size})
+	return nil
+}
+
+// Write writes the manifest as indented json to the file at path
+func (m *Manifest) Write(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error(manifest): %w", err)
+	}
+	if err := sandbox.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("error(manifest): %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package netclass
+
+import (
+	"fmt"
+	"net/netip"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+const (
+	// address classes
+	ClassMine      = "mine"      // networks listed in the config
+	ClassLoopback  = "loopback"  // loopback addresses
+	ClassLinkLocal = "linklocal" // link-local addresses
+	ClassPrivate   = "private"   // private networks (rfc 1918, unique local addresses)
+	ClassCGN       = "cgn"       // carrier-grade nat (rfc 6598)
+	ClassBogon     = "bogon"     // reserved, documentation and multicast addresses that are not routed on the internet
+	ClassPublic    = "public"    // all other addresses
+)
+
+// builtin lists the built-in classes in the order they are checked (the first matching network wins)
+var builtin = []struct {
+	class    string
+	networks []netip.Prefix
+}{
+	{ClassLoopback, prefixes("127.0.0.0/8", "::1/128")},
+	{ClassLinkLocal, prefixes("169.254.0.0/16", "fe80::/10")},
+	{ClassPrivate, prefixes("10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7")},
+	{ClassCGN, prefixes("100.64.0.0/10")},
+	{ClassBogon, prefixes(
+		"0.0.0.0/8", "192.0.0.0/24", "192.0.2.0/24", "198.18.0.0/15", "198.51.100.0/24", "203.0.113.0/24",
+		"224.0.0.0/4", "240.0.0.0/4",
+		"::/128", "::ffff:0:0/96", "100::/64", "2001:db8::/32", "ff00::/8",
+	)},
+}
+
+// Classes lists all address classes
+var Classes = []string{ClassMine, ClassLoopback, ClassLinkLocal, ClassPrivate, ClassCGN, ClassBogon, ClassPublic}
+
+// Classifier assigns address classes to the source and destination of entries
+type Classifier struct {
+	networks []netip.Prefix // networks of the mine class
+}
+
+// prefixes parses the built-in networks
+func prefixes(networks ...string) []netip.Prefix {
+	p := make([]netip.Prefix, len(networks))
+	for i, network := range networks {
+		p[i] = netip.MustParsePrefix(network)
+	}
+	return p
+}
+
+// contains returns true if any of the networks contains addr
+func contains(networks []netip.Prefix, addr netip.Addr) bool {
+	for _, network := range networks {
+		if network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// public
+
+// Class returns the class of the address (empty if it is not a valid address)
+func (c *Classifier) Class(address string) string {
+	addr, err := netip.ParseAddr(address)
+	if err != nil {
+		return ""
+	}
+	addr = addr.WithZone("")
+	if c != nil && contains(c.networks, addr.Unmap()) {
+		return ClassMine
+	}
+	for _, b := range builtin {
+		if contains(b.networks, addr) {
+			return b.class
+		}
+	}
+	return ClassPublic
+}
+
+// Classify sets the address classes of the source and destination of the entry (only built-in classes if c is nil)
+func (c *Classifier) Classify(entry *stream.LogEntry) {
+	entry.SrcClass = c.Class(entry.Src)
+	entry.DstClass = c.Class(entry.Dst)
+}
+
+// New parses the networks of the mine class (in CIDR notation, e.g. 192.0.2.0/24)
+func New(networks []string) (*Classifier, error) {
+	c := &Classifier{networks: make([]netip.Prefix, 0, len(networks))}
+	for _, network := range networks {
+		p, err := netip.ParsePrefix(network)
+		if err != nil {
+			return nil, fmt.Errorf("error(netclass): invalid network %q: %w", network, err)
+		}
+		c.networks = append(c.networks, p.Masked())
+	}
+	return c, nil
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package netclass
+
+import (
+	"testing"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+func TestClass(t *testing.T) {
+	c, err := New([]string{"198.51.100.0/24", "192.168.10.1/24", "2001:db8:1::/48"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		address string
+		expect  string
+	}{
+		{"192.168.10.5", ClassMine},
+		{"198.51.100.7", ClassMine},
+		{"2001:db8:1::1", ClassMine},
+		{"192.168.1.1", ClassPrivate},
+		{"10.1.2.3", ClassPrivate},
+		{"172.31.255.255", ClassPrivate},
+		{"172.32.0.1", ClassPublic},
+		{"fd00::1", ClassPrivate},
+		{"127.0.0.1", ClassLoopback},
+		{"::1", ClassLoopback},
+		{"169.254.1.1", ClassLinkLocal},
+		{"fe80::1%igb0", ClassLinkLocal},
+		{"100.64.0.1", ClassCGN},
+		{"100.128.0.1", ClassPublic},
+		{"0.1.2.3", ClassBogon},
+		{"203.0.113.1", ClassBogon},
+		{"224.0.0.251", ClassBogon},
+		{"ff02::1", ClassBogon},
+		{"2001:db8::1", ClassBogon},
+		{"8.8.8.8", ClassPublic},
+		{"2606:4700::1111", ClassPublic},
+		{"", ""},
+		{"not an address", ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.address, func(t *testing.T) {
+			if got := c.Class(tc.address); got != tc.expect {
+				t.Fatalf("expected %q, got %q", tc.expect, got)
+			}
+		})
+	}
+}
+
+func TestClassify(t *testing.T) {
+	entry := stream.LogEntry{Src: "192.0.2.10", Dst: "10.0.0.1"}
+	// built-in classes only
+	var c *Classifier
+	c.Classify(&entry)
+	if entry.SrcClass != ClassBogon || entry.DstClass != ClassPrivate {
+		t.Fatalf("expected bogon -> private, got %s -> %s", entry.SrcClass, entry.DstClass)
+	}
+	c, err := New([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Classify(&entry)
+	if entry.SrcClass != ClassBogon || entry.DstClass != ClassMine {
+		t.Fatalf("expected bogon -> mine, got %s -> %s", entry.SrcClass, entry.DstClass)
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	for _, network := range []string{"192.0.2.1", "192.0.2.0/33", "lan"} {
+		if _, err := New([]string{network}); err == nil {
+			t.Errorf("%s: expected error, got nil", network)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package opnsense
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/config"
+	"gitlab.com/allddd/opnsense-filterlog/internal/meta"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+const (
+	logEndpoint   = "/api/diagnostics/firewall/log/"
+	clientTimeout = 30 * time.Second
+)
+
+// apiEntry represents an entry returned by the firewall log endpoint (all values are strings)
+type apiEntry struct {
+	Action    string `json:"action"`
+	Anchor    string `json:"anchorname"`
+	Class     string `json:"class"`
+	DataLen   string `json:"datalen"`
+	Digest    string `json:"__digest__"`
+	Dir       string `json:"dir"`
+	Dst       string `json:"dst"`
+	DstPort   string `json:"dstport"`
+	ECN       string `json:"ecn"`
+	FlowLabel string `json:"flowlabel"`
+	HopLimit  string `json:"hoplimit"`
+	ID        string `json:"id"`
+	Interface string `json:"interface"`
+	IPFlags   string `json:"ipflags"`
+	IPVersion string `json:"ipversion"`
+	Length    string `json:"length"`
+	Offset    string `json:"offset"`
+	ProtoName string `json:"protoname"`
+	ProtoNum  string `json:"protonum"`
+	Reason    string `json:"reason"`
+	RID       string `json:"rid"`
+	RuleNr    string `json:"rulenr"`
+	SubRuleNr string `json:"subrulenr"`
+	Src       string `json:"src"`
+	SrcPort   string `json:"srcport"`
+	Timestamp string `json:"__timestamp__"`
+	TOS       string `json:"tos"`
+	TTL       string `json:"ttl"`
+}
+
+// logEntry converts the api entry (origin is the hostname of the firewall)
+func (e *apiEntry) logEntry(origin string) (*stream.LogEntry, error) {
+	timestamp, err := time.Parse(time.RFC3339, e.Timestamp)
+	if err != nil {
+		// older versions don't include the time zone
+		if timestamp, err = time.ParseInLocation("2006-01-02T15:04:05", e.Timestamp, time.Local); err != nil {
+			return nil, fmt.Errorf("invalid timestamp %q", e.Timestamp)
+		}
+	}
+	ipVersion, err := strconv.ParseUint(e.IPVersion, 10, 8)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ipversion %q", e.IPVersion)
+	}
+	entry := &stream.LogEntry{
+		Action:     e.Action,
+		Direction:  e.Dir,
+		Interface:  e.Interface,
+		Origin:     origin,
+		Reason:     e.Reason,
+		Time:       timestamp,
+		Class:      e.Class,
+		Dst:        e.Dst,
+		ECN:        e.ECN,
+		FlowLabel:  e.FlowLabel,
+		IPFlags:    e.IPFlags,
+		IPVersion:  uint8(ipVersion),
+		ProtoName:  strings.ToLower(e.ProtoName),
+		Src:        e.Src,
+		TOS:        e.TOS,
+		Anchor:     e.Anchor,
+		Label:      e.RID,
+		RuleNum:    e.RuleNr,
+		SubRuleNum: e.SubRuleNr,
+	}
+	if e.SrcPort != "" {
+		port, err := strconv.ParseUint(e.SrcPort, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid srcport %q", e.SrcPort)
+		}
+		entry.SrcPort = uint16(port)
+	}
+	if e.DstPort != "" {
+		port, err := strconv.ParseUint(e.DstPort, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dstport %q", e.DstPort)
+		}
+		entry.DstPort = uint16(port)
+	}
+	// lengths are informational (0 if invalid, like in files)
+	if length, err := strconv.ParseUint(e.Length, 10, 32); err == nil {
+		entry.Length = uint32(length)
+	}
+	if dataLen, err := strconv.ParseUint(e.DataLen, 10, 32); err == nil && entry.Transport() {
+		entry.DataLen = uint32(dataLen)
+	}
+	// so are the header fields
+	if protoNum, err := strconv.ParseUint(e.ProtoNum, 10, 8); err == nil {
+		entry.ProtoNum = uint8(protoNum)
+	}
+	if ttl, err := strconv.ParseUint(e.TTL, 10, 8); err == nil {
+		entry.TTL = uint8(ttl)
+	}
+	if id, err := strconv.ParseUint(e.ID, 10, 16); err == nil {
+		entry.ID = uint16(id)
+	}
+	if offset, err := strconv.ParseUint(e.Offset, 10, 16); err == nil {
+		entry.Offset = uint16(offset)
+	}
+	if hopLimit, err := strconv.ParseUint(e.HopLimit, 10, 8); err == nil {
+		entry.HopLimit = uint8(hopLimit)
+	}
+	return entry, nil
+}
+
+// Client requests firewall log entries from the OPNsense API
+type Client struct {
+	client *http.Client // http client
+	host   string       // hostname of the firewall (origin of entries)
+	key    string       // api key
+	secret string       // api secret
+	url    string       // url of the log endpoint
+}
+
+// fetch requests up to limit of the newest entries (only entries newer than digest if set, oldest first)
+func (c *Client) fetch(digest string, limit int) ([]apiEntry, error) {
+	query := url.Values{"limit": {strconv.Itoa(limit)}}
+	if digest != "" {
+		query.Set("digest", digest)
+	}
+	req, err := http.NewRequest(http.MethodGet, c.url+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error(opnsense): %w", err)
+	}
+	req.SetBasicAuth(c.key, c.secret)
+	req.Header.Set("User-Agent", meta.Name+"/"+meta.Version)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error(opnsense): %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		return nil, fmt.Errorf("error(opnsense): unexpected status %s from %s", resp.Status, c)
+	}
+	var entries []apiEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("error(opnsense): could not decode response: %w", err)
+	}
+	// the api returns the newest entry first
+	slices.Reverse(entries)
+	return entries, nil
+}
+
+// public
+
+// Download writes up to limit of the newest entries as log lines to w and returns the number of entries written
+func (c *Client) Download(w io.Writer, limit int) (int, error) {
+	entries, err := c.fetch("", limit)
+	if err != nil {
+		return 0, err
+	}
+	written := 0
+	for i := range entries {
+		entry, err := entries[i].logEntry(c.host)
+		if err != nil {
+			continue
+		}
+		if _, err := fmt.Fprintln(w, stream.FormatLine(entry)); err != nil {
+			return written, fmt.Errorf("error(opnsense): %w", err)
+		}
+		written++
+	}
+	return written, nil
+}
+
+// NewClient creates a new api client
+func NewClient(cfg config.API) (*Client, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("error(opnsense): api.url is not configured")
+	}
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("error(opnsense): invalid api.url %q", cfg.URL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + logEndpoint
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.CA != "" {
+		data, err := os.ReadFile(cfg.CA)
+		if err != nil {
+			return nil, fmt.Errorf("error(opnsense): could not read api.ca: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("error(opnsense): no certificates found in %s", cfg.CA)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+	return &Client{
+		client: &http.Client{Timeout: clientTimeout, Transport: transport},
+		host:   u.Hostname(),
+		key:    cfg.Key,
+		secret: cfg.Secret,
+		url:    u.String(),
+	}, nil
+}
+
+// String returns the url of the log endpoint
+func (c *Client) String() string {
+	return c.url
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package opnsense
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/config"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+// apiServer serves the given entries (oldest first) like the firewall log endpoint
+func apiServer(t *testing.T, entries *[]apiEntry) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if key, secret, ok := r.BasicAuth(); !ok || key != "key" || secret != "secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != logEndpoint {
+			http.NotFound(w, r)
+			return
+		}
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		digest := r.URL.Query().Get("digest")
+		// newest first, stopping at the digest
+		var result []apiEntry
+		for i := len(*entries) - 1; i >= 0 && len(result) < limit; i-- {
+			if (*entries)[i].Digest == digest {
+				break
+			}
+			result = append(result, (*entries)[i])
+		}
+		json.NewEncoder(w).Encode(result)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// testAPIEntry returns a blocked tcp entry with the given digest and destination port
+func testAPIEntry(digest string, port int) apiEntry {
+	return apiEntry{
+		Action: "block", DataLen: "0", Digest: digest, Dir: "in", Dst: "198.51.100.1", DstPort: strconv.Itoa(port), Interface: "igb1",
+		IPFlags: "DF", IPVersion: "4", Length: "60", ProtoName: "TCP", Reason: "match", Src: "203.0.113.10", SrcPort: "51000", Timestamp: "2025-10-10T10:05:00+02:00",
+		ProtoNum: "6", TOS: "0x0", TTL: "52",
+	}
+}
+
+func TestDownload(t *testing.T) {
+	entries := []apiEntry{testAPIEntry("a", 22), testAPIEntry("b", 443), {Digest: "c", Timestamp: "invalid"}}
+	srv := apiServer(t, &entries)
+	c, err := NewClient(config.API{URL: srv.URL + "/", Key: "key", Secret: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	n, err := c.Download(&b, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("expected 2 entries, got %d", n)
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	entry, err := stream.ParseLine(lines[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.DstPort != 22 || entry.ProtoName != "tcp" || entry.Action != stream.ActionBlock || entry.Length != 60 || entry.TTL != 52 || entry.IPFlags != "DF" || entry.ProtoNum != 6 {
+		t.Fatalf("unexpected first entry: %+v", entry)
+	}
+
+	// invalid credentials
+	c, err = NewClient(config.API{URL: srv.URL, Key: "key", Secret: "wrong"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Download(&b, 10); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("expected unauthorized error, got %v", err)
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         config.API
+		expectError string
+	}{
+		{name: "missing url", cfg: config.API{}, expectError: "not configured"},
+		{name: "invalid scheme", cfg: config.API{URL: "ftp://192.168.1.1"}, expectError: "invalid api.url"},
+		{name: "missing ca", cfg: config.API{URL: "https://192.168.1.1", CA: "/nonexistent"}, expectError: "could not read api.ca"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewClient(tc.cfg)
+			if err == nil || !strings.Contains(err.Error(), tc.expectError) {
+				t.Fatalf("expected error containing %q, got %v", tc.expectError, err)
+			}
+		})
+	}
+}
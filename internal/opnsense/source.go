@@ -0,0 +1,118 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package opnsense
+
+import (
+	"fmt"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+// Source polls the OPNsense API for new entries (used by the daemon instead of a log file)
+type Source struct {
+	client   *Client            // api client
+	digest   string             // digest of the newest received entry (empty before the first poll)
+	errors   []string           // conversion and request errors
+	interval time.Duration      // polling interval
+	limit    int                // maximum number of entries per request
+	origin   string             // origin of all entries
+	polled   time.Time          // time of the last poll
+	queue    []*stream.LogEntry // received entries not yet returned
+}
+
+// addError adds an error (errors beyond stream.MaxErrorsInMemory are dropped)
+func (s *Source) addError(msg string) {
+	if len(s.errors) < stream.MaxErrorsInMemory {
+		s.errors = append(s.errors, msg)
+	}
+}
+
+// poll requests entries newer than the last received entry
+func (s *Source) poll() {
+	s.polled = time.Now()
+	entries, err := s.client.fetch(s.digest, s.limit)
+	if err != nil {
+		s.addError(err.Error())
+		return
+	}
+	if len(entries) == 0 {
+		return
+	}
+	if s.digest != "" && len(entries) >= s.limit {
+		s.addError(fmt.Sprintf("more than %d entries since the last poll, some entries may be missing (increase api.limit or decrease api.interval)", s.limit))
+	}
+	for i := range entries {
+		entry, err := entries[i].logEntry(s.origin)
+		if err != nil {
+			s.addError(fmt.Sprintf("invalid entry %s: %v", entries[i].Digest, err))
+			continue
+		}
+		s.queue = append(s.queue, entry)
+	}
+	s.digest = entries[len(entries)-1].Digest
+}
+
+// public
+
+// GetPathRel returns the url of the log endpoint
+func (s *Source) GetPathRel() string {
+	return s.client.String()
+}
+
+// NewSource creates a new source polling every interval (the first poll returns up to limit of the newest entries)
+func NewSource(client *Client, interval time.Duration, limit int) *Source {
+	return &Source{
+		client:   client,
+		interval: interval,
+		limit:    limit,
+		origin:   client.host,
+	}
+}
+
+// Next returns the next received entry (returns nil if no entry is available until the next poll)
+func (s *Source) Next() *stream.LogEntry {
+	if len(s.queue) == 0 && time.Since(s.polled) >= s.interval {
+		s.poll()
+	}
+	if len(s.queue) == 0 {
+		return nil
+	}
+	entry := s.queue[0]
+	s.queue[0] = nil
+	s.queue = s.queue[1:]
+	return entry
+}
+
+// SetOrigin sets the origin of all entries received afterwards (defaults to the hostname of the firewall)
+func (s *Source) SetOrigin(origin string) {
+	s.origin = origin
+}
+
+// TakeErrors returns all errors encountered since the last call and clears them
+func (s *Source) TakeErrors() []string {
+	errors := s.errors
+	s.errors = make([]string, 0)
+	return errors
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package opnsense
+
+import (
+	"strings"
+	"testing"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/config"
+)
+
+func TestSource(t *testing.T) {
+	entries := []apiEntry{testAPIEntry("a", 1), testAPIEntry("b", 2), testAPIEntry("c", 3)}
+	srv := apiServer(t, &entries)
+	c, err := NewClient(config.API{URL: srv.URL, Key: "key", Secret: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewSource(c, 0, 2)
+	ports := func() []uint16 {
+		var ports []uint16
+		for entry := s.Next(); entry != nil; entry = s.Next() {
+			ports = append(ports, entry.DstPort)
+		}
+		return ports
+	}
+
+	// first poll returns the newest entries (oldest first)
+	if got := ports(); len(got) != 2 || got[0] != 2 || got[1] != 3 {
+		t.Fatalf("expected ports [2 3], got %v", got)
+	}
+	if got := ports(); len(got) != 0 {
+		t.Fatalf("expected no new entries, got %v", got)
+	}
+
+	// later polls only return new entries and warn about gaps
+	entries = append(entries, testAPIEntry("d", 4))
+	if got := ports(); len(got) != 1 || got[0] != 4 {
+		t.Fatalf("expected ports [4], got %v", got)
+	}
+	if errors := s.TakeErrors(); len(errors) != 0 {
+		t.Fatalf("expected no errors, got %v", errors)
+	}
+	entries = append(entries, testAPIEntry("e", 5), testAPIEntry("f", 6), testAPIEntry("g", 7))
+	if got := ports(); len(got) != 2 || got[0] != 6 {
+		t.Fatalf("expected ports [6 7], got %v", got)
+	}
+	if errors := s.TakeErrors(); len(errors) != 1 || !strings.Contains(errors[0], "may be missing") {
+		t.Fatalf("expected gap warning, got %v", errors)
+	}
+
+	// entries are tagged with the hostname of the firewall unless overridden
+	entries = append(entries, testAPIEntry("h", 8))
+	if entry := s.Next(); entry == nil || entry.Origin != "127.0.0.1" {
+		t.Fatalf("expected hostname as origin, got %+v", entry)
+	}
+	s.SetOrigin("fw1")
+	entries = append(entries, testAPIEntry("i", 9))
+	if entry := s.Next(); entry == nil || entry.Origin != "fw1" {
+		t.Fatalf("expected origin %q, got %+v", "fw1", entry)
+	}
+}
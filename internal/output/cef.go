@@ -0,0 +1,98 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package output
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/meta"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+var (
+	// cefHeaderEscaper escapes values of the cef header (pipes separate the header fields)
+	cefHeaderEscaper = strings.NewReplacer(`\`, `\\`, "|", `\|`)
+	// cefValueEscaper escapes values of the cef extension (equal signs separate keys and values)
+	cefValueEscaper = strings.NewReplacer(`\`, `\\`, "=", `\=`, "\n", `\n`, "\r", `\r`)
+	// cefSeverities maps severity levels to cef severities (0-10)
+	cefSeverities = map[string]int{"info": 3, "notice": 5, "warning": 7, "critical": 10}
+)
+
+// cefLine formats an entry as an ArcSight Common Event Format (CEF) event, the signature id is the rule number (or
+// the action if the rule is unknown)
+func cefLine(entry *stream.LogEntry) (string, error) {
+	signature := entry.RuleNum
+	if signature == "" {
+		signature = entry.Action
+	}
+	severity, ok := cefSeverities[entry.Severity]
+	if !ok {
+		severity = cefSeverities["info"]
+	}
+	header := []string{"CEF:0", "OPNsense", meta.Name, meta.Version, signature, entry.Action + " " + entry.Direction}
+	for i := 1; i < len(header); i++ {
+		header[i] = cefHeaderEscaper.Replace(header[i])
+	}
+
+	var ext []string
+	add := func(key, value string) {
+		if value != "" {
+			ext = append(ext, key+"="+cefValueEscaper.Replace(value))
+		}
+	}
+	add("rt", strconv.FormatInt(entry.Time.UnixMilli(), 10))
+	add("act", entry.Action)
+	switch entry.Direction {
+	case "in":
+		add("deviceDirection", "0")
+		add("deviceInboundInterface", entry.Interface)
+	case "out":
+		add("deviceDirection", "1")
+		add("deviceOutboundInterface", entry.Interface)
+	}
+	add("dvchost", entry.Origin)
+	// ipv6 addresses have their own keys
+	src, dst := "src", "dst"
+	if entry.IPVersion == 6 {
+		src, dst = "c6a2", "c6a4"
+	}
+	add(src, entry.Src)
+	sport, _ := entry.Field("sport")
+	add("spt", sport)
+	add(dst, entry.Dst)
+	dport, _ := entry.Field("dport")
+	add("dpt", dport)
+	add("proto", entry.ProtoName)
+	if entry.Reason != "" {
+		add("cs1Label", "reason")
+		add("cs1", entry.Reason)
+	}
+	if entry.Label != "" {
+		add("cs2Label", "label")
+		add("cs2", entry.Label)
+	}
+	return fmt.Sprintf("%s|%d|%s", strings.Join(header, "|"), severity, strings.Join(ext, " ")), nil
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package output
+
+import (
+	"testing"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/meta"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+func TestCEF(t *testing.T) {
+	tests := []struct {
+		name   string
+		entry  stream.LogEntry
+		expect string
+	}{
+		{
+			name: "ipv4 inbound",
+			entry: stream.LogEntry{
+				Action: "block", Direction: "in", Interface: "igb0", Origin: "fw1", Reason: "match", RuleNum: "12",
+				Severity: "warning", Time: time.Date(2025, 10, 10, 0, 0, 0, 0, time.UTC),
+				IPVersion: 4, ProtoName: "tcp", Src: "192.0.2.1", SrcPort: 51000, Dst: "198.51.100.1", DstPort: 22,
+			},
+			expect: "CEF:0|OPNsense|" + meta.Name + "|" + meta.Version + "|12|block in|7|rt=1760054400000 act=block " +
+				"deviceDirection=0 deviceInboundInterface=igb0 dvchost=fw1 src=192.0.2.1 spt=51000 dst=198.51.100.1 " +
+				"dpt=22 proto=tcp cs1Label=reason cs1=match",
+		},
+		{
+			name: "ipv6 outbound without rule",
+			entry: stream.LogEntry{
+				Action: "pass", Direction: "out", Interface: "igb1", Label: `a=b|c\d`,
+				Time: time.Date(2025, 10, 10, 0, 0, 0, 0, time.UTC), IPVersion: 6, ProtoName: "ipv6-icmp",
+				Src: "2001:db8::1", Dst: "2001:db8::2",
+			},
+			expect: "CEF:0|OPNsense|" + meta.Name + "|" + meta.Version + "|pass|pass out|3|rt=1760054400000 act=pass " +
+				`deviceDirection=1 deviceOutboundInterface=igb1 c6a2=2001:db8::1 c6a4=2001:db8::2 proto=ipv6-icmp ` +
+				`cs2Label=label cs2=a\=b|c\\d`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got, _ := cefLine(&tc.entry); got != tc.expect {
+				t.Fatalf("expected %q, got %q", tc.expect, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package output
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// compressions
+	CompressGzip = "gzip"
+	CompressNone = "none"
+	CompressZstd = "zstd"
+)
+
+// Compressions lists the compressions supported by Open
+var Compressions = []string{CompressGzip, CompressNone, CompressZstd}
+
+// compressor compresses the data written to it (gzip.Writer, zstd.Encoder)
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// newCompressor returns a compressor writing to w (compress must not be none)
+func newCompressor(w io.Writer, compress string) (compressor, error) {
+	if compress == CompressZstd {
+		// entries are flushed in small batches, concurrent encoding wouldn't pay off
+		enc, err := zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			return nil, fmt.Errorf("error(output): %w", err)
+		}
+		return enc, nil
+	}
+	return gzip.NewWriter(w), nil
+}
+
+// compressedFile compresses writes to a destination file (appended files consist of several gzip members or zstd
+// frames, which are decompressed as a whole)
+type compressedFile struct {
+	compressor
+	dest io.WriteCloser // destination file
+}
+
+// public
+
+// Flush (compressedFile) writes the compressed data of all writes so far to the file
+func (c *compressedFile) Flush() error {
+	if err := c.compressor.Flush(); err != nil {
+		return err
+	}
+	return flush(c.dest)
+}
+
+// Close (compressedFile) completes the gzip member or zstd frame and closes the file
+func (c *compressedFile) Close() error {
+	if err := c.compressor.Close(); err != nil {
+		c.dest.Close()
+		return err
+	}
+	return c.dest.Close()
+}
+
+// Compression returns the compression of destination, detected from the extension unless given (an error if it is
+// unsupported or the destination is a udp address, datagrams can't carry a compressed stream)
+func Compression(destination, compress string) (string, error) {
+	if compress == "" {
+		switch strings.ToLower(filepath.Ext(destination)) {
+		case ".gz":
+			return CompressGzip, nil
+		case ".zst":
+			return CompressZstd, nil
+		}
+		return CompressNone, nil
+	}
+	if !slices.Contains(Compressions, compress) {
+		return "", fmt.Errorf("error(output): unknown compression %q (available: %s)", compress, strings.Join(Compressions, ", "))
+	}
+	if compress != CompressNone && strings.HasPrefix(destination, "udp://") {
+		return "", fmt.Errorf("error(output): compression %q requires a file or tcp destination", compress)
+	}
+	return compress, nil
+}
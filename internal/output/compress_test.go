@@ -0,0 +1,132 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package output
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+func TestCompression(t *testing.T) {
+	tests := []struct {
+		destination string
+		compress    string
+		expect      string
+		expectError bool
+	}{
+		{destination: "entries.ndjson", expect: CompressNone},
+		{destination: "entries.ndjson.gz", expect: CompressGzip},
+		{destination: "entries.CSV.GZ", expect: CompressGzip},
+		{destination: "entries.ndjson.gz", compress: CompressNone, expect: CompressNone},
+		{destination: "entries.ndjson", compress: CompressGzip, expect: CompressGzip},
+		{destination: "entries.ndjson.zst", expect: CompressZstd},
+		{destination: "entries.ndjson", compress: CompressZstd, expect: CompressZstd},
+		{destination: "entries.ndjson", compress: "bzip2", expectError: true},
+		{destination: "tcp://127.0.0.1:5170", compress: CompressGzip, expect: CompressGzip},
+		{destination: "tcp://127.0.0.1:5170", compress: CompressZstd, expect: CompressZstd},
+		{destination: "udp://127.0.0.1:5170", compress: CompressGzip, expectError: true},
+		{destination: "udp://127.0.0.1:5170", expect: CompressNone},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.destination+" "+tc.compress, func(t *testing.T) {
+			got, err := Compression(tc.destination, tc.compress)
+			if tc.expectError {
+				if err == nil {
+					t.Fatalf("expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.expect {
+				t.Errorf("expected %q, got %q", tc.expect, got)
+			}
+		})
+	}
+}
+
+func TestOpenCompressed(t *testing.T) {
+	for _, ext := range []string{".gz", ".zst"} {
+		t.Run(ext, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "entries.log"+ext)
+			// appended runs are separate gzip members or zstd frames
+			for range 2 {
+				w, err := Open(path, "")
+				if err != nil {
+					t.Fatal(err)
+				}
+				s, _ := New(FormatTemplate, w, Options{Template: "{action}"})
+				if err := s.Write(&stream.LogEntry{Action: "block"}); err != nil {
+					t.Fatal(err)
+				}
+				// flushed entries can be read before the file is closed
+				if err := s.Flush(); err != nil {
+					t.Fatal(err)
+				}
+				if data := readCompressed(t, path); !bytes.HasSuffix(data, []byte("block\n")) {
+					t.Errorf("expected flushed entry, got %q", data)
+				}
+				s.Close()
+				if err := w.Close(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if data := readCompressed(t, path); string(data) != "block\nblock\n" {
+				t.Errorf("expected both runs, got %q", data)
+			}
+		})
+	}
+}
+
+// readCompressed returns the decompressed content of the gzip or zstd file at path (as far as it has been written)
+func readCompressed(t *testing.T, path string) []byte {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var r io.Reader
+	if filepath.Ext(path) == ".zst" {
+		zr, err := zstd.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer zr.Close()
+		r = zr
+	} else if r, err = gzip.NewReader(f); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(r)
+	return data
+}
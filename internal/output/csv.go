@@ -0,0 +1,54 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package output
+
+import (
+	"encoding/csv"
+	"strings"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+// csvRecord formats the values as a csv record (without newline)
+func csvRecord(values []string) string {
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	w.Write(values) // writing to a strings.Builder doesn't fail
+	w.Flush()
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// csvHeader returns the header record (the field names, extra fields are not included)
+func csvHeader() string {
+	return csvRecord(stream.FieldNames)
+}
+
+// csvLine formats an entry as a csv record of its fields in the order of the header
+func csvLine(entry *stream.LogEntry) (string, error) {
+	values := make([]string, len(stream.FieldNames))
+	for i, name := range stream.FieldNames {
+		values[i], _ = entry.Field(name)
+	}
+	return csvRecord(values), nil
+}
@@ -0,0 +1,168 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package output
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// dialTimeout is the maximum time of connecting to a network destination
+const dialTimeout = 10 * time.Second
+
+// file is a destination file (writes are buffered until flushed)
+type file struct {
+	*bufio.Writer
+	f *os.File // opened file
+}
+
+// conn is a network destination that connects again on the next write after a write failed
+type conn struct {
+	addr     string     // host and port
+	c        net.Conn   // connection (nil after a failed write)
+	compress string     // compression of the stream (tcp only, a new stream per connection)
+	z        compressor // compressor of the connection (nil unless compressed)
+	network  string     // tcp or udp
+}
+
+// Close (file) flushes buffered writes and closes the file
+func (f *file) Close() error {
+	if err := f.Flush(); err != nil {
+		f.f.Close()
+		return err
+	}
+	return f.f.Close()
+}
+
+// connect connects to the destination (a new compressed stream begins if compressed)
+func (c *conn) connect() error {
+	nc, err := net.DialTimeout(c.network, c.addr, dialTimeout)
+	if err != nil {
+		return fmt.Errorf("error(output): %w", err)
+	}
+	if c.compress != CompressNone {
+		if c.z, err = newCompressor(nc, c.compress); err != nil {
+			nc.Close()
+			return err
+		}
+	}
+	c.c = nc
+	return nil
+}
+
+// disconnect closes the connection after a failed write (the next write connects again)
+func (c *conn) disconnect() {
+	c.c.Close()
+	c.c, c.z = nil, nil
+}
+
+// Write (conn) writes p to the connection (a single datagram for udp, compressed data is sent once flushed),
+// connecting first if needed
+func (c *conn) Write(p []byte) (int, error) {
+	if c.c == nil {
+		if err := c.connect(); err != nil {
+			return 0, err
+		}
+	}
+	var w io.Writer = c.c
+	if c.z != nil {
+		w = c.z
+	}
+	n, err := w.Write(p)
+	if err != nil {
+		c.disconnect()
+		return n, fmt.Errorf("error(output): %w", err)
+	}
+	return n, nil
+}
+
+// Flush (conn) sends the compressed data of all writes so far
+func (c *conn) Flush() error {
+	if c.z == nil {
+		return nil
+	}
+	if err := c.z.Flush(); err != nil {
+		c.disconnect()
+		return fmt.Errorf("error(output): %w", err)
+	}
+	return nil
+}
+
+// Close (conn) completes the compressed stream (if compressed) and closes the connection
+func (c *conn) Close() error {
+	if c.c == nil {
+		return nil
+	}
+	if c.z != nil {
+		if err := c.z.Close(); err != nil {
+			c.c.Close()
+			return fmt.Errorf("error(output): %w", err)
+		}
+	}
+	return c.c.Close()
+}
+
+// public
+
+// IsNetwork returns true if the destination is a network address (tcp:// or udp://)
+func IsNetwork(destination string) bool {
+	return strings.HasPrefix(destination, "tcp://") || strings.HasPrefix(destination, "udp://")
+}
+
+// Open opens a destination, tcp://host:port (compressed with compress, a stream per connection), udp://host:port
+// (every line is sent as a datagram) or a file that is created or appended to (compressed with compress, detected
+// from the extension if empty, e.g. .gz or .zst)
+func Open(destination, compress string) (io.WriteCloser, error) {
+	compress, err := Compression(destination, compress)
+	if err != nil {
+		return nil, err
+	}
+	if network, addr, ok := strings.Cut(destination, "://"); ok && IsNetwork(destination) {
+		c := &conn{addr: addr, compress: compress, network: network}
+		// fail early if the destination can't be reached
+		if err := c.connect(); err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+	f, err := os.OpenFile(destination, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("error(output): %w", err)
+	}
+	dest := &file{Writer: bufio.NewWriter(f), f: f}
+	if compress == CompressNone {
+		return dest, nil
+	}
+	z, err := newCompressor(dest, compress)
+	if err != nil {
+		dest.Close()
+		return nil, err
+	}
+	return &compressedFile{compressor: z, dest: dest}, nil
+}
@@ -0,0 +1,164 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package output
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+func TestOpenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "entries.log")
+	for i := range 2 {
+		w, err := Open(path, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		s, _ := New(FormatTemplate, w, Options{Template: "{action}"})
+		if err := s.Write(&stream.LogEntry{Action: "block"}); err != nil {
+			t.Fatal(err)
+		}
+		s.Close()
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		// files are appended to
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := map[int]string{0: "block\n", 1: "block\nblock\n"}[i]; string(data) != expected {
+			t.Fatalf("expected %q, got %q", expected, data)
+		}
+	}
+	if _, err := Open(filepath.Join(t.TempDir(), "missing", "entries.log"), ""); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestOpenNetwork(t *testing.T) {
+	// udp: one datagram per entry
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	w, err := Open("udp://"+pc.LocalAddr().String(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	s, _ := New(FormatLogfmt, w, Options{})
+	for _, action := range []string{"block", "pass"} {
+		if err := s.Write(&stream.LogEntry{Action: action}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	buf := make([]byte, 1024)
+	for _, expected := range []string{"time=0001-01-01T00:00:00Z action=block ipver=0\n", "time=0001-01-01T00:00:00Z action=pass ipver=0\n"} {
+		n, _, err := pc.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(buf[:n]) != expected {
+			t.Fatalf("expected datagram %q, got %q", expected, buf[:n])
+		}
+	}
+
+	// tcp
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	w, err = Open("tcp://"+ln.Addr().String(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	c, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	s, _ = New(FormatTemplate, w, Options{Template: "{action} {src}"})
+	if err := s.Write(&stream.LogEntry{Action: "block", Src: "192.0.2.1"}); err != nil {
+		t.Fatal(err)
+	}
+	line, err := bufio.NewReader(c).ReadString('\n')
+	if err != nil || line != "block 192.0.2.1\n" {
+		t.Fatalf("expected line %q, got %q (%v)", "block 192.0.2.1\n", line, err)
+	}
+
+	// tcp compressed (sent once flushed)
+	for _, compress := range []string{CompressGzip, CompressZstd} {
+		w, err = Open("tcp://"+ln.Addr().String(), compress)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err = ln.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		s, _ = New(FormatTemplate, w, Options{Template: "{action} {src}"})
+		if err := s.Write(&stream.LogEntry{Action: "pass", Src: "192.0.2.2"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		var zr io.Reader
+		if compress == CompressZstd {
+			dec, err := zstd.NewReader(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer dec.Close()
+			zr = dec
+		} else if zr, err = gzip.NewReader(c); err != nil {
+			t.Fatal(err)
+		}
+		line, err = bufio.NewReader(zr).ReadString('\n')
+		if err != nil || line != "pass 192.0.2.2\n" {
+			t.Fatalf("%s: expected line %q, got %q (%v)", compress, "pass 192.0.2.2\n", line, err)
+		}
+		w.Close()
+	}
+
+	if !IsNetwork("tcp://127.0.0.1:1") || IsNetwork("tcp.log") {
+		t.Fatal("unexpected IsNetwork result")
+	}
+	if _, err := Open("tcp://127.0.0.1:0", ""); err == nil {
+		t.Fatal("expected error for unreachable destination, got nil")
+	}
+}
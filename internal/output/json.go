@@ -0,0 +1,125 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+// JSON writes a json document of the entries array followed by the meta object (each entry is encoded as it is
+// written and ends its line, so the document has one entry per line)
+type JSON struct {
+	enc     *json.Encoder         // encodes meta (entries are encoded by Encode)
+	entries int                   // number of entries written
+	meta    func(entries int) any // returns the meta object (omitted if nil)
+	w       io.Writer             // destination
+}
+
+// ndjsonLine formats an entry as a json object (without newline)
+func ndjsonLine(entry *stream.LogEntry) (string, error) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return "", fmt.Errorf("error(output): could not encode entry: %w", err)
+	}
+	return string(data), nil
+}
+
+// public
+
+// NewJSON creates a sink that writes the json document to w (meta returns the meta object given the number of
+// entries written, the document is opened by the first entry or Close)
+func NewJSON(w io.Writer, meta func(entries int) any) *JSON {
+	return &JSON{enc: json.NewEncoder(w), meta: meta, w: w}
+}
+
+// Entries returns the number of entries written
+func (j *JSON) Entries() int {
+	return j.entries
+}
+
+// Encode (JSON) returns the element of the entries array of the entry
+func (j *JSON) Encode(entry *stream.LogEntry) ([]byte, error) {
+	line, err := ndjsonLine(entry)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(line + "\n"), nil
+}
+
+// WriteEncoded (JSON) writes an element returned by Encode as the next element of the entries array
+func (j *JSON) WriteEncoded(b []byte) error {
+	separator := ","
+	if j.entries == 0 {
+		separator = `{"entries":[`
+	}
+	if _, err := io.WriteString(j.w, separator); err != nil {
+		return err
+	}
+	if _, err := j.w.Write(b); err != nil {
+		return err
+	}
+	j.entries++
+	return nil
+}
+
+// Write (JSON) writes the next element of the entries array
+func (j *JSON) Write(entry *stream.LogEntry) error {
+	b, err := j.Encode(entry)
+	if err != nil {
+		return err
+	}
+	return j.WriteEncoded(b)
+}
+
+// Flush (JSON) flushes the destination
+func (j *JSON) Flush() error {
+	return flush(j.w)
+}
+
+// Close (JSON) closes the entries array, writes the meta object and flushes the destination
+func (j *JSON) Close() error {
+	separator := "]"
+	if j.entries == 0 {
+		separator = `{"entries":[]`
+	}
+	if _, err := io.WriteString(j.w, separator); err != nil {
+		return err
+	}
+	if j.meta != nil {
+		if _, err := io.WriteString(j.w, `,"meta":`); err != nil {
+			return err
+		}
+		if err := j.enc.Encode(j.meta(j.entries)); err != nil {
+			return fmt.Errorf("error(output): could not encode meta: %w", err)
+		}
+	}
+	if _, err := io.WriteString(j.w, "}\n"); err != nil {
+		return err
+	}
+	return j.Flush()
+}
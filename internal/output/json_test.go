@@ -0,0 +1,90 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+func TestJSON(t *testing.T) {
+	type document struct {
+		Entries []*stream.LogEntry `json:"entries"`
+		Meta    *struct {
+			Entries int `json:"entries"`
+		} `json:"meta"`
+	}
+	for _, n := range []int{0, 1, 3} {
+		for _, withMeta := range []bool{false, true} {
+			var buf bytes.Buffer
+			var meta func(int) any
+			if withMeta {
+				meta = func(entries int) any { return map[string]int{"entries": entries} }
+			}
+			j := NewJSON(&buf, meta)
+			for range n {
+				if err := j.Write(&stream.LogEntry{Action: "block", Src: `10.0.0.1"`}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := j.Close(); err != nil {
+				t.Fatal(err)
+			}
+			var doc document
+			if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+				t.Fatalf("%d entries: could not parse json: %v\n%s", n, err, buf.String())
+			}
+			if len(doc.Entries) != n || j.Entries() != n {
+				t.Fatalf("expected %d entries, got %d (counted %d)", n, len(doc.Entries), j.Entries())
+			}
+			if withMeta != (doc.Meta != nil) || withMeta && doc.Meta.Entries != n {
+				t.Fatalf("unexpected meta %+v", doc.Meta)
+			}
+			if n > 0 && doc.Entries[0].Src != `10.0.0.1"` {
+				t.Fatalf("expected escaped source to round-trip, got %q", doc.Entries[0].Src)
+			}
+			// entries are written as they are encoded, one per line (and the meta object on its own line)
+			expected := n + 1
+			if withMeta {
+				expected++
+			}
+			if lines := strings.Count(buf.String(), "\n"); lines != expected {
+				t.Fatalf("expected %d lines, got %d\n%s", expected, lines, buf.String())
+			}
+		}
+	}
+}
+
+func TestNDJSON(t *testing.T) {
+	for i, line := range strings.Split(strings.TrimSpace(writeFile(t, FormatNDJSON, Options{})), "\n") {
+		var entry stream.LogEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil || entry.Action == "" {
+			t.Fatalf("line %d: could not parse %q: %v", i, line, err)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package output
+
+import (
+	"testing"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+func TestLogfmtLine(t *testing.T) {
+	tests := []struct {
+		name   string
+		entry  stream.LogEntry
+		expect string
+	}{
+		{
+			name: "tcp entry",
+			entry: stream.LogEntry{
+				Action: "block", Direction: "in", Interface: "eth0", Reason: "match",
+				Time:      time.Date(2025, 10, 10, 0, 0, 0, 0, time.UTC),
+				IPVersion: 4, ProtoName: "tcp", Src: "192.0.2.1", SrcPort: 51000, Dst: "198.51.100.1", DstPort: 22,
+			},
+			expect: "time=2025-10-10T00:00:00Z action=block dir=in iface=eth0 reason=match ipver=4 proto=tcp src=192.0.2.1 sport=51000 dst=198.51.100.1 dport=22",
+		},
+		{
+			name: "empty fields and extra",
+			entry: stream.LogEntry{
+				Action: "pass", Time: time.Date(2025, 10, 10, 0, 0, 0, 0, time.UTC), IPVersion: 4,
+				Extra: map[string]string{"tag": "a=b", "country": "NL", "note": "two words"},
+			},
+			expect: `time=2025-10-10T00:00:00Z action=pass ipver=4 extra.country=NL extra.note="two words" extra.tag="a=b"`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got, _ := logfmtLine(&tc.entry); got != tc.expect {
+				t.Fatalf("expected %q, got %q", tc.expect, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+const (
+	// formats
+	FormatCEF      = "cef"
+	FormatCSV      = "csv"
+	FormatJSON     = "json"
+	FormatLogfmt   = "logfmt"
+	FormatNDJSON   = "ndjson"
+	FormatPlain    = "plain"
+	FormatTemplate = "template"
+)
+
+// Formats lists the formats supported by New
+var Formats = []string{FormatCEF, FormatCSV, FormatJSON, FormatLogfmt, FormatNDJSON, FormatPlain, FormatTemplate}
+
+// Sink writes entries in a format to a destination
+type Sink interface {
+	Write(entry *stream.LogEntry) error // writes a single entry (may be buffered until the next flush)
+	Flush() error                       // writes buffered entries
+	Close() error                       // flushes buffered entries and completes the output (the destination is not closed)
+}
+
+// Encoder is implemented by sinks whose entries can be formatted independently of each other, e.g. by several
+// goroutines (Encode is safe for concurrent use, WriteEncoded writes an encoded entry like Write would)
+type Encoder interface {
+	Encode(entry *stream.LogEntry) ([]byte, error)
+	WriteEncoded(b []byte) error
+}
+
+// Options configures the sinks created by New
+type Options struct {
+	Meta     func(entries int) any // returns the meta object written after the entries (json only, omitted if nil)
+	Template string                // line with {field} placeholders, e.g. {time} {src} {dport} (template only)
+}
+
+// lines writes one line per entry, each line in a single write (e.g. one datagram per entry)
+type lines struct {
+	format func(entry *stream.LogEntry) (string, error) // formats an entry as line (without newline)
+	header string                                       // line written before the first entry (none if empty)
+	w      io.Writer                                    // destination
+}
+
+// writeHeader writes the header unless it has been written already
+func (l *lines) writeHeader() error {
+	if l.header == "" {
+		return nil
+	}
+	header := l.header
+	l.header = ""
+	_, err := io.WriteString(l.w, header+"\n")
+	return err
+}
+
+// flush flushes w if it buffers writes (e.g. bufio.Writer)
+func flush(w io.Writer) error {
+	if f, ok := w.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// public
+
+// Encode (lines) returns the line of the entry
+func (l *lines) Encode(entry *stream.LogEntry) ([]byte, error) {
+	line, err := l.format(entry)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(line + "\n"), nil
+}
+
+// WriteEncoded (lines) writes a line returned by Encode
+func (l *lines) WriteEncoded(b []byte) error {
+	if err := l.writeHeader(); err != nil {
+		return err
+	}
+	_, err := l.w.Write(b)
+	return err
+}
+
+// Write (lines) writes the line of the entry
+func (l *lines) Write(entry *stream.LogEntry) error {
+	b, err := l.Encode(entry)
+	if err != nil {
+		return err
+	}
+	return l.WriteEncoded(b)
+}
+
+// Flush (lines) writes the header (if no entry has been written yet) and flushes the destination
+func (l *lines) Flush() error {
+	if err := l.writeHeader(); err != nil {
+		return err
+	}
+	return flush(l.w)
+}
+
+// Close (lines) flushes the destination
+func (l *lines) Close() error {
+	return l.Flush()
+}
+
+// New creates a sink that writes entries in format to w
+func New(format string, w io.Writer, opts Options) (Sink, error) {
+	switch format {
+	case FormatCEF:
+		return &lines{format: cefLine, w: w}, nil
+	case FormatCSV:
+		return &lines{format: csvLine, header: csvHeader(), w: w}, nil
+	case FormatJSON:
+		return NewJSON(w, opts.Meta), nil
+	case FormatLogfmt:
+		return &lines{format: logfmtLine, w: w}, nil
+	case FormatNDJSON:
+		return &lines{format: ndjsonLine, w: w}, nil
+	case FormatPlain:
+		return newPlain(w), nil
+	case FormatTemplate:
+		t, err := parseTemplate(opts.Template)
+		if err != nil {
+			return nil, err
+		}
+		return &lines{format: t.line, w: w}, nil
+	}
+	return nil, fmt.Errorf("error(output): unknown format %q (available: %s)", format, strings.Join(Formats, ", "))
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+// writeFile writes all entries of the valid test log to a new sink of format and returns the output
+func writeFile(t *testing.T, format string, opts Options) string {
+	t.Helper()
+	var buf bytes.Buffer
+	s, err := New(format, &buf, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := stream.NewStream("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	for entry := f.Next(); entry != nil; entry = f.Next() {
+		if err := s.Write(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestNew(t *testing.T) {
+	for _, format := range Formats {
+		t.Run(format, func(t *testing.T) {
+			out := writeFile(t, format, Options{Template: "{src}"})
+			// one line per entry (plus header or document lines)
+			if lines := strings.Count(out, "\n"); lines < 20 || lines > 22 {
+				t.Fatalf("expected 20 to 22 lines, got %d", lines)
+			}
+		})
+	}
+	if _, err := New("xml", &bytes.Buffer{}, Options{}); err == nil {
+		t.Fatal("expected error for unknown format, got nil")
+	}
+}
+
+func TestHeader(t *testing.T) {
+	// the header is written even if there are no entries (e.g. when flushed while following)
+	var buf bytes.Buffer
+	s, _ := New(FormatCSV, &buf, Options{})
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Write(&stream.LogEntry{Action: "block"}); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 2 || !strings.HasPrefix(lines[0], "time,origin,") {
+		t.Fatalf("expected header and one record, got %q", buf.String())
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package output
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/lipgloss"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+// plainColumns are the header and width of each column of the plain format (same as the TUI)
+var plainColumns = []struct {
+	header string
+	width  int
+}{
+	{"Time", 16}, {"Action", 10}, {"Interface", 10}, {"Dir", 5}, {"Source", 40},
+	{"SrcPort", 7}, {"Destination", 40}, {"DstPort", 7}, {"Proto", 10}, {"Reason", 20},
+}
+
+// plainCell escapes non-printable and non-ascii characters and pads or truncates the value to width
+func plainCell(value string, width int) string {
+	if strings.ContainsFunc(value, func(r rune) bool { return r >= utf8.RuneSelf || !unicode.IsPrint(r) }) {
+		value = strconv.QuoteToASCII(value)
+		value = value[1 : len(value)-1]
+	}
+	if len(value) > width {
+		value = value[:width-3] + "..."
+	}
+	return fmt.Sprintf("%-*s", width, value)
+}
+
+// plainRow joins the cells of a row
+func plainRow(values ...string) string {
+	cells := make([]string, len(values))
+	for i, value := range values {
+		cells[i] = plainCell(value, plainColumns[i].width)
+	}
+	return strings.TrimRight(strings.Join(cells, " "), " ")
+}
+
+// newPlain creates a sink that writes entries as aligned table rows below a header (colored if w is a terminal)
+func newPlain(w io.Writer) *lines {
+	r := lipgloss.NewRenderer(w)
+	block := r.NewStyle().Foreground(lipgloss.Color("202"))
+	headers := make([]string, len(plainColumns))
+	for i, c := range plainColumns {
+		headers[i] = c.header
+	}
+	format := func(entry *stream.LogEntry) (string, error) {
+		sport, _ := entry.Field("sport")
+		dport, _ := entry.Field("dport")
+		row := plainRow(entry.Time.Format("Jan 02 15:04:05"), entry.Action, entry.Interface, entry.Direction,
+			entry.Src, sport, entry.Dst, dport, entry.ProtoName, entry.Reason)
+		if entry.Action == stream.ActionBlock {
+			row = block.Render(row)
+		}
+		return row, nil
+	}
+	return &lines{
+		format: format,
+		header: r.NewStyle().Bold(true).Foreground(lipgloss.Color("46")).Render(plainRow(headers...)),
+		w:      w,
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package output
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPlainCell(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		width  int
+		expect string
+	}{
+		{name: "padded", value: "pass", width: 6, expect: "pass  "},
+		{name: "exact", value: "block", width: 5, expect: "block"},
+		{name: "truncated", value: "2001:db8::1234:5678", width: 10, expect: "2001:db..."},
+		{name: "control characters", value: "a\x1b[31mb", width: 12, expect: `a\x1b[31mb  `},
+		{name: "non-ascii", value: "wän", width: 10, expect: `w\u00e4n  `},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := plainCell(tc.value, tc.width); got != tc.expect {
+				t.Fatalf("expected %q, got %q", tc.expect, got)
+			}
+		})
+	}
+}
+
+func TestPlain(t *testing.T) {
+	lines := strings.Split(strings.TrimSpace(writeFile(t, FormatPlain, Options{})), "\n")
+	if len(lines) != 21 {
+		t.Fatalf("expected 21 lines, got %d", len(lines))
+	}
+	if !strings.HasPrefix(lines[0], "Time") || strings.Contains(lines[0], "\x1b") {
+		t.Fatalf("unexpected header %q", lines[0])
+	}
+	// columns are aligned with the header
+	proto := strings.Index(lines[0], "Proto")
+	for _, line := range lines[1:] {
+		if strings.Contains(line, "\x1b") || line[proto-1] != ' ' || line[proto] == ' ' {
+			t.Fatalf("unexpected line %q", line)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package output
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+// templatePlaceholder matches the {field} placeholders of a template
+var templatePlaceholder = regexp.MustCompile(`\{([a-z]+)\}`)
+
+// template is a line with {field} placeholders
+type template struct {
+	text string // template text
+}
+
+// parseTemplate returns the template of text (every placeholder must be a field name)
+func parseTemplate(text string) (*template, error) {
+	if text == "" {
+		return nil, fmt.Errorf("error(output): template must not be empty")
+	}
+	if strings.ContainsAny(text, "\r\n") {
+		return nil, fmt.Errorf("error(output): template must be a single line")
+	}
+	for _, match := range templatePlaceholder.FindAllStringSubmatch(text, -1) {
+		if !slices.Contains(stream.FieldNames, match[1]) {
+			return nil, fmt.Errorf("error(output): unknown field %q in template (available: %s)", match[1], strings.Join(stream.FieldNames, ", "))
+		}
+	}
+	return &template{text: text}, nil
+}
+
+// line formats an entry by substituting the placeholders with the values of the entry
+func (t *template) line(entry *stream.LogEntry) (string, error) {
+	return templatePlaceholder.ReplaceAllStringFunc(t.text, func(placeholder string) string {
+		value, _ := entry.Field(placeholder[1 : len(placeholder)-1])
+		return value
+	}), nil
+}
@@ -0,0 +1,191 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package plugin
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+const (
+	enrichTimeout = 5 * time.Second // time the plugin has to take a request and answer it, or to exit once closed
+)
+
+// Enricher runs an external program that adds extra fields to log entries
+//
+// The program is started once and receives one JSON encoded entry per line on stdin,
+// it must answer each line with exactly one line containing a JSON object of string values.
+type Enricher struct {
+	cmd     *exec.Cmd      // plugin process
+	command string         // command line of the plugin
+	done    chan struct{}  // closed once the plugin is stopped (stops forwarding lines)
+	err     error          // sticky error (plugin is unusable once set)
+	mu      sync.Mutex     // serializes requests
+	lines   chan string    // lines read from stdout
+	stdin   io.WriteCloser // plugin stdin
+	timeout time.Duration  // time the plugin has to take a request and answer it, or to exit once closed
+}
+
+// readLines forwards lines read from r to the lines channel until EOF or the plugin is stopped
+func (e *Enricher) readLines(r io.Reader) {
+	defer close(e.lines)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		select {
+		case e.lines <- scanner.Text():
+		case <-e.done:
+			return
+		}
+	}
+}
+
+// fail marks the plugin as unusable and stops it
+func (e *Enricher) fail(err error) error {
+	e.err = err
+	close(e.done)
+	e.stdin.Close()
+	e.cmd.Process.Kill()
+	e.cmd.Wait()
+	return err
+}
+
+// public
+
+// NewEnricher starts the plugin program for the given command line
+func NewEnricher(command string) (*Enricher, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("error(plugin): empty command")
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("error(plugin): %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("error(plugin): %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("error(plugin): %w", err)
+	}
+	e := &Enricher{
+		cmd:     cmd,
+		command: command,
+		done:    make(chan struct{}),
+		lines:   make(chan string),
+		stdin:   stdin,
+		timeout: enrichTimeout,
+	}
+	go e.readLines(stdout)
+	return e, nil
+}
+
+// Close closes the stdin of the plugin program and waits for it to exit (it is killed if it doesn't exit in time)
+func (e *Enricher) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.err != nil {
+		return nil
+	}
+	e.err = fmt.Errorf("error(plugin): %q is closed", e.command)
+	close(e.done)
+	e.stdin.Close()
+	exited := make(chan error, 1)
+	go func() {
+		exited <- e.cmd.Wait()
+	}()
+	select {
+	case err := <-exited:
+		return err
+	case <-time.After(e.timeout):
+		e.cmd.Process.Kill()
+		<-exited
+		return fmt.Errorf("error(plugin): %q did not exit within %s and was killed", e.command, e.timeout)
+	}
+}
+
+// Enrich sends the entry to the plugin and stores the returned fields in entry.Extra
+func (e *Enricher) Enrich(entry *stream.LogEntry) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.err != nil {
+		return e.err
+	}
+	request, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("error(plugin): could not encode entry: %w", err)
+	}
+	// a line that is already waiting would be taken as the response to this entry
+	select {
+	case line, ok := <-e.lines:
+		if ok {
+			return e.fail(fmt.Errorf("error(plugin): %q wrote a line without request: %q", e.command, line))
+		}
+		return e.fail(fmt.Errorf("error(plugin): %q exited unexpectedly", e.command))
+	default:
+	}
+	timeout := time.NewTimer(e.timeout)
+	defer timeout.Stop()
+	// the write blocks once the pipe is full if the plugin doesn't read its stdin
+	written := make(chan error, 1)
+	go func() {
+		_, err := e.stdin.Write(append(request, '\n'))
+		written <- err
+	}()
+	select {
+	case err := <-written:
+		if err != nil {
+			return e.fail(fmt.Errorf("error(plugin): %q: %w", e.command, err))
+		}
+	case <-timeout.C:
+		return e.fail(fmt.Errorf("error(plugin): %q did not read the entry within %s", e.command, e.timeout))
+	}
+	var response string
+	select {
+	case line, ok := <-e.lines:
+		if !ok {
+			return e.fail(fmt.Errorf("error(plugin): %q exited unexpectedly", e.command))
+		}
+		response = line
+	case <-timeout.C:
+		return e.fail(fmt.Errorf("error(plugin): %q did not respond within %s", e.command, e.timeout))
+	}
+	extra := make(map[string]string)
+	if err := json.Unmarshal([]byte(response), &extra); err != nil {
+		return fmt.Errorf("error(plugin): %q returned invalid response: %w", e.command, err)
+	}
+	if len(extra) > 0 {
+		entry.Extra = extra
+	}
+	return nil
+}
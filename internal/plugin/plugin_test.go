@@ -0,0 +1,182 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+func writePlugin(t *testing.T, script string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "plugin.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestEnrich(t *testing.T) {
+	tests := []struct {
+		name        string
+		script      string
+		expectExtra map[string]string
+		expectError bool
+	}{
+		{
+			name:        "extra fields",
+			script:      "while read line; do echo '{\"tenant\":\"acme\",\"owner\":\"noc\"}'; done\n",
+			expectExtra: map[string]string{"tenant": "acme", "owner": "noc"},
+		},
+		{
+			name:        "no extra fields",
+			script:      "while read line; do echo '{}'; done\n",
+			expectExtra: nil,
+		},
+		{
+			name:        "invalid response",
+			script:      "while read line; do echo 'tenant=acme'; done\n",
+			expectError: true,
+		},
+		{
+			name:        "plugin exits",
+			script:      "exit 0\n",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			e, err := NewEnricher(writePlugin(t, tc.script))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer e.Close()
+			// enrich twice to make sure the plugin keeps answering
+			for range 2 {
+				entry := stream.LogEntry{Src: "192.168.1.1"}
+				err := e.Enrich(&entry)
+				if tc.expectError {
+					if err == nil {
+						t.Fatal("expected error, got nil")
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if len(entry.Extra) != len(tc.expectExtra) {
+					t.Fatalf("expected extra %v, got %v", tc.expectExtra, entry.Extra)
+				}
+				for k, v := range tc.expectExtra {
+					if entry.Extra[k] != v {
+						t.Fatalf("expected extra %q=%q, got %q", k, v, entry.Extra[k])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestEnrichTimeout(t *testing.T) {
+	// the plugin never reads its stdin, so writing blocks once the pipe is full
+	e, err := NewEnricher(writePlugin(t, "exec sleep 30\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.timeout = 100 * time.Millisecond
+	defer e.Close()
+	entry := stream.LogEntry{Src: strings.Repeat("a", 1<<20)}
+	done := make(chan error)
+	go func() {
+		done <- e.Enrich(&entry)
+	}()
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "did not read the entry") {
+			t.Fatalf("expected timeout error, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected enrich to time out while writing")
+	}
+	if err := e.Enrich(&stream.LogEntry{}); err == nil {
+		t.Fatal("expected error of stopped plugin, got nil")
+	}
+}
+
+func TestEnrichUnsolicited(t *testing.T) {
+	// the plugin answers every entry twice
+	e, err := NewEnricher(writePlugin(t, "while read line; do echo '{}'; echo '{}'; done\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+	if err := e.Enrich(&stream.LogEntry{}); err != nil {
+		t.Fatal(err)
+	}
+	// the second answer is detected once it has been read
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+		if err := e.Enrich(&stream.LogEntry{}); err != nil {
+			if !strings.Contains(err.Error(), "without request") {
+				t.Fatalf("expected error of unsolicited line, got %v", err)
+			}
+			return
+		}
+	}
+	t.Fatal("expected error of unsolicited line, got nil")
+}
+
+func TestCloseKill(t *testing.T) {
+	// the plugin keeps running after its stdin is closed
+	e, err := NewEnricher(writePlugin(t, "while read line; do echo '{}'; done\nexec sleep 30\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.timeout = 100 * time.Millisecond
+	if err := e.Enrich(&stream.LogEntry{}); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if err := e.Close(); err == nil || !strings.Contains(err.Error(), "killed") {
+		t.Fatalf("expected plugin to be killed, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("expected close to return after the timeout, took %s", elapsed)
+	}
+}
+
+func TestNewEnricherInvalid(t *testing.T) {
+	if _, err := NewEnricher(""); err == nil {
+		t.Fatal("expected error for empty command, got nil")
+	}
+	if _, err := NewEnricher(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Fatal("expected error for missing program, got nil")
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/meta"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stats"
+)
+
+const (
+	// time chart dimensions (svg user units)
+	chartHeight = 200
+	chartWidth  = 960
+)
+
+// chartBar represents a single bar of the time chart
+type chartBar struct {
+	X        float64 // left edge
+	Width    float64 // bar width
+	Height   float64 // height of all entries
+	Blocked  float64 // height of blocked entries
+	Title    string  // tooltip
+	Interval string  // start of the interval
+}
+
+// htmlData is passed to the html template
+type htmlData struct {
+	Summary
+	Name      string
+	Version   string
+	Generated string
+	Bars      []chartBar
+	Chart     struct{ Width, Height int }
+}
+
+// htmlTemplate renders the report (styles and chart are inline so the document is self-contained)
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"endpoint": stats.Endpoint,
+	"last":     func(bars []chartBar) int { return len(bars) - 1 },
+	"subtract": func(a int, b float64) float64 { return float64(a) - b },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Name}} report: {{.Source}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em auto; max-width: 1000px; color: #222; }
+h1 { font-size: 1.5em; }
+h2 { font-size: 1.2em; margin-top: 2em; border-bottom: 1px solid #ccc; }
+table { border-collapse: collapse; margin: 0.5em 0; }
+th, td { padding: 0.2em 0.8em; text-align: left; }
+th { background: #eee; }
+td.num { text-align: right; font-variant-numeric: tabular-nums; }
+tr:nth-child(even) td { background: #f7f7f7; }
+.top { display: flex; gap: 2em; flex-wrap: wrap; }
+.legend span { display: inline-block; width: 0.8em; height: 0.8em; margin: 0 0.3em 0 1em; }
+.pass { fill: #4caf50; background: #4caf50; }
+.block { fill: #e53935; background: #e53935; }
+footer { margin-top: 2em; color: #888; font-size: 0.8em; }
+</style>
+</head>
+<body>
+<h1>Firewall log report</h1>
+
+<h2>Summary</h2>
+<table>
+<tr><th>Source</th><td>{{.Source}}</td></tr>
+<tr><th>Filter</th><td>{{if .Filter}}<code>{{.Filter}}</code>{{else}}none{{end}}</td></tr>
+{{if not .From.IsZero}}<tr><th>Period</th><td>{{.From.Format "2006-01-02 15:04:05"}} &ndash; {{.To.Format "2006-01-02 15:04:05"}}</td></tr>
+{{end}}<tr><th>Time range</th><td>{{if .Entries}}{{.First.Format "2006-01-02 15:04:05"}} &ndash; {{.Last.Format "2006-01-02 15:04:05"}}{{else}}no entries{{end}}</td></tr>
+<tr><th>Entries</th><td class="num">{{.Entries}}</td></tr>
+<tr><th>Blocked</th><td class="num">{{.Blocked}}</td></tr>
+<tr><th>Passed</th><td class="num">{{.Passed}}</td></tr>
+<tr><th>Other</th><td class="num">{{.Other}}</td></tr>
+<tr><th>Parse errors</th><td class="num">{{.Errors}}</td></tr>
+</table>
+
+<h2>Time chart</h2>
+{{if .Bars}}<div class="legend"><span class="pass"></span>passed/other<span class="block"></span>blocked</div>
+<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 {{.Chart.Width}} {{.Chart.Height}}" width="100%" role="img">
+{{range .Bars}}<g><title>{{.Title}}</title><rect class="pass" x="{{printf "%.2f" .X}}" y="{{printf "%.2f" (subtract $.Chart.Height .Height)}}" width="{{printf "%.2f" .Width}}" height="{{printf "%.2f" .Height}}"/><rect class="block" x="{{printf "%.2f" .X}}" y="{{printf "%.2f" (subtract $.Chart.Height .Blocked)}}" width="{{printf "%.2f" .Width}}" height="{{printf "%.2f" .Blocked}}"/></g>
+{{end}}</svg>
+<p>{{(index .Bars 0).Interval}} &ndash; {{(index .Bars (last .Bars)).Interval}}</p>
+{{else}}<p>no entries</p>{{end}}
+
+<h2>Top talkers</h2>
+<div class="top">
+<table>
+<tr><th>Source</th><th>Entries</th><th>Blocked</th><th>Bytes</th></tr>
+{{range .Sources}}<tr><td>{{.Addr}}</td><td class="num">{{.Entries}}</td><td class="num">{{.Blocked}}</td><td class="num">{{.Bytes}}</td></tr>
+{{end}}</table>
+<table>
+<tr><th>Destination</th><th>Entries</th><th>Blocked</th><th>Bytes</th></tr>
+{{range .Destinations}}<tr><td>{{.Addr}}</td><td class="num">{{.Entries}}</td><td class="num">{{.Blocked}}</td><td class="num">{{.Bytes}}</td></tr>
+{{end}}</table>
+</div>
+
+<h2>Destination ports</h2>
+<table>
+<tr><th>Port</th><th>Sources</th><th>Entries</th><th>Blocked</th><th>First</th><th>Last</th></tr>
+{{range .Ports}}<tr><td class="num">{{.DstPort}}</td><td class="num">{{.Sources}}</td><td class="num">{{.Entries}}</td><td class="num">{{.Blocked}}</td><td>{{.First.Format "2006-01-02 15:04:05"}}</td><td>{{.Last.Format "2006-01-02 15:04:05"}}</td></tr>
+{{end}}</table>
+{{if .Bookmarks}}
+<h2>Bookmarks</h2>
+<table>
+<tr><th>Time</th><th>Line</th><th>Action</th><th>Source</th><th>Destination</th><th>Note</th></tr>
+{{range .Bookmarks}}<tr><td>{{.Entry.Time.Format "2006-01-02 15:04:05"}}</td><td class="num">{{.Line}}</td><td>{{.Entry.Action}}</td><td>{{endpoint .Entry.Src .Entry.SrcPort}}</td><td>{{endpoint .Entry.Dst .Entry.DstPort}}</td><td>{{.Note}}</td></tr>
+{{end}}</table>
+{{end}}
+<footer>generated by {{.Name}} {{.Version}} on {{.Generated}}</footer>
+</body>
+</html>
+`))
+
+// chartBars scales timeline buckets to the chart dimensions
+func chartBars(buckets []stats.TimelineBucket) []chartBar {
+	maxEntries := 0
+	for _, b := range buckets {
+		maxEntries = max(maxEntries, b.Entries)
+	}
+	if maxEntries == 0 {
+		return nil
+	}
+	width := float64(chartWidth) / float64(len(buckets))
+	bars := make([]chartBar, len(buckets))
+	for i, b := range buckets {
+		bars[i] = chartBar{
+			X:        float64(i) * width,
+			Width:    width,
+			Height:   float64(b.Entries) / float64(maxEntries) * chartHeight,
+			Blocked:  float64(b.Blocked) / float64(maxEntries) * chartHeight,
+			Title:    fmt.Sprintf("%s: %d entries, %d blocked", b.Start.Format(time.DateTime), b.Entries, b.Blocked),
+			Interval: b.Start.Format(time.DateTime),
+		}
+	}
+	return bars
+}
+
+// WriteHTML writes the report as a standalone html document
+func (r *Report) WriteHTML(w io.Writer) error {
+	summary := r.Summary()
+	data := htmlData{
+		Summary:   summary,
+		Name:      meta.Name,
+		Version:   meta.Version,
+		Generated: time.Now().Format(time.DateTime),
+		Bars:      chartBars(summary.Timeline),
+	}
+	data.Chart.Width, data.Chart.Height = chartWidth, chartHeight
+	if err := htmlTemplate.Execute(w, data); err != nil {
+		return fmt.Errorf("error(report): could not write html: %w", err)
+	}
+	return nil
+}
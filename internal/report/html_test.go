@@ -0,0 +1,98 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package report
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+func TestWriteHTML(t *testing.T) {
+	start := time.Date(2025, 10, 10, 0, 0, 0, 0, time.UTC)
+	entries := []stream.LogEntry{
+		{Action: stream.ActionBlock, Src: "192.0.2.1", Dst: "198.51.100.1", DstPort: 22, Time: start},
+		{Action: stream.ActionBlock, Src: "192.0.2.1", Dst: "198.51.100.1", DstPort: 22, Time: start.Add(time.Hour)},
+		{Action: stream.ActionPass, Src: "192.0.2.2", Dst: "198.51.100.2", DstPort: 443, Time: start.Add(2 * time.Hour)},
+	}
+
+	tests := []struct {
+		name     string
+		filter   string
+		entries  []stream.LogEntry
+		contains []string
+	}{
+		{
+			name:    "entries",
+			filter:  "dport 22 or dport 443",
+			entries: entries,
+			contains: []string{
+				"<!DOCTYPE html>",
+				"<code>dport 22 or dport 443</code>",
+				"2025-10-10 00:00:00 &ndash; 2025-10-10 02:00:00",
+				"<td>192.0.2.1</td><td class=\"num\">2</td><td class=\"num\">2</td>",
+				"<td>198.51.100.2</td><td class=\"num\">1</td><td class=\"num\">0</td>",
+				"<td class=\"num\">22</td><td class=\"num\">1</td><td class=\"num\">2</td>",
+				"<svg",
+				"2025-10-10 01:00:00: 1 entries, 1 blocked",
+			},
+		},
+		{
+			name:    "no entries",
+			entries: nil,
+			contains: []string{
+				"<tr><th>Filter</th><td>none</td></tr>",
+				"<p>no entries</p>",
+			},
+		},
+		{
+			name:    "escaped filter",
+			filter:  "iface <script>",
+			entries: nil,
+			contains: []string{
+				"<code>iface &lt;script&gt;</code>",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := New("filter.log", tc.filter)
+			for _, entry := range tc.entries {
+				r.Add(&entry)
+			}
+			var b strings.Builder
+			if err := r.WriteHTML(&b); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, s := range tc.contains {
+				if !strings.Contains(b.String(), s) {
+					t.Fatalf("expected output to contain %q", s)
+				}
+			}
+		})
+	}
+}
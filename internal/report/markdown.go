@@ -0,0 +1,92 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stats"
+)
+
+// writeTalkers writes a markdown table of top talkers
+func writeTalkers(b *strings.Builder, header string, talkers []stats.Talker) {
+	fmt.Fprintf(b, "| %s | Entries | Blocked | Bytes |\n|---|--:|--:|--:|\n", header)
+	for _, t := range talkers {
+		fmt.Fprintf(b, "| %s | %d | %d | %d |\n", t.Addr, t.Entries, t.Blocked, t.Bytes)
+	}
+}
+
+// markdownCell escapes a value for a markdown table cell
+func markdownCell(value string) string {
+	return strings.NewReplacer("|", "\\|", "\n", " ", "\r", " ").Replace(value)
+}
+
+// WriteMarkdown writes the report as a markdown document (e.g. for mail digests or chat)
+func (r *Report) WriteMarkdown(w io.Writer) error {
+	s := r.Summary()
+	var b strings.Builder
+
+	b.WriteString("# Firewall log report\n\n## Summary\n\n")
+	fmt.Fprintf(&b, "- Source: %s\n", s.Source)
+	if s.Filter != "" {
+		fmt.Fprintf(&b, "- Filter: `%s`\n", s.Filter)
+	} else {
+		b.WriteString("- Filter: none\n")
+	}
+	if !s.From.IsZero() {
+		fmt.Fprintf(&b, "- Period: %s - %s\n", s.From.Format(time.DateTime), s.To.Format(time.DateTime))
+	}
+	if s.Entries > 0 {
+		fmt.Fprintf(&b, "- Time range: %s - %s\n", s.First.Format(time.DateTime), s.Last.Format(time.DateTime))
+	}
+	fmt.Fprintf(&b, "- Entries: %d (blocked: %d, passed: %d, other: %d)\n", s.Entries, s.Blocked, s.Passed, s.Other)
+	fmt.Fprintf(&b, "- Parse errors: %d\n", s.Errors)
+
+	b.WriteString("\n## Top sources\n\n")
+	writeTalkers(&b, "Source", s.Sources)
+	b.WriteString("\n## Top destinations\n\n")
+	writeTalkers(&b, "Destination", s.Destinations)
+
+	b.WriteString("\n## Destination ports\n\n| Port | Sources | Entries | Blocked | First | Last |\n|--:|--:|--:|--:|---|---|\n")
+	for _, p := range s.Ports {
+		fmt.Fprintf(&b, "| %d | %d | %d | %d | %s | %s |\n", p.DstPort, p.Sources, p.Entries, p.Blocked,
+			p.First.Format(time.DateTime), p.Last.Format(time.DateTime))
+	}
+
+	if len(s.Bookmarks) > 0 {
+		b.WriteString("\n## Bookmarks\n\n| Time | Line | Action | Source | Destination | Note |\n|---|--:|---|---|---|---|\n")
+		for _, bm := range s.Bookmarks {
+			fmt.Fprintf(&b, "| %s | %d | %s | %s | %s | %s |\n", bm.Entry.Time.Format(time.DateTime), bm.Line, bm.Entry.Action,
+				stats.Endpoint(bm.Entry.Src, bm.Entry.SrcPort), stats.Endpoint(bm.Entry.Dst, bm.Entry.DstPort), markdownCell(bm.Note))
+		}
+	}
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("error(report): could not write markdown: %w", err)
+	}
+	return nil
+}
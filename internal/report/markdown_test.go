@@ -0,0 +1,66 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package report
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/bookmark"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+func TestWriteMarkdown(t *testing.T) {
+	start := time.Date(2025, 10, 10, 0, 0, 0, 0, time.UTC)
+	r := New("filter.log", "")
+	r.From, r.To = start, start.Add(time.Hour)
+	for _, entry := range testEntries(start) {
+		r.Add(&entry)
+	}
+	r.Bookmarks = []bookmark.Bookmark{{
+		File:  "/var/log/filter/filter.log",
+		Line:  3,
+		Note:  "scan | started here",
+		Entry: stream.LogEntry{Action: stream.ActionBlock, Src: "192.0.2.1", SrcPort: 40000, Dst: "198.51.100.1", DstPort: 22, Time: start},
+	}}
+	var b strings.Builder
+	if err := r.WriteMarkdown(&b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, s := range []string{
+		"# Firewall log report",
+		"- Filter: none",
+		"- Period: 2025-10-10 00:00:00 - 2025-10-10 01:00:00",
+		"- Entries: 4 (blocked: 2, passed: 1, other: 1)",
+		"| 192.0.2.1 | 2 | 2 |",
+		"| 198.51.100.2 | 2 | 0 |",
+		"| 22 | 1 | 2 | 2 |",
+		"| 2025-10-10 00:00:00 | 3 | block | 192.0.2.1:40000 | 198.51.100.1:22 | scan \\| started here |",
+	} {
+		if !strings.Contains(b.String(), s) {
+			t.Fatalf("expected output to contain %q", s)
+		}
+	}
+}
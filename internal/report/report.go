@@ -0,0 +1,173 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/bookmark"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stats"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+const (
+	// formats
+	FormatHTML     = "html"
+	FormatJSON     = "json"
+	FormatMarkdown = "markdown"
+
+	topCount = 10 // number of rows in top tables
+
+	// number of timeline buckets
+	timelineBuckets = 96
+)
+
+// Formats lists all available report formats
+var Formats = []string{FormatHTML, FormatJSON, FormatMarkdown}
+
+// extensions maps report formats to file extensions
+var extensions = map[string]string{
+	FormatHTML:     ".html",
+	FormatJSON:     ".json",
+	FormatMarkdown: ".md",
+}
+
+// Report aggregates entries into a summary that can be rendered as a standalone document
+type Report struct {
+	Source string    // log file the report is generated from
+	Filter string    // filter expression (empty if none)
+	Errors int       // number of parse errors
+	From   time.Time // start of the reporting period (zero if not applicable)
+	To     time.Time // end of the reporting period (zero if not applicable)
+
+	Bookmarks []bookmark.Bookmark // bookmarked entries of the source (included as incident timeline)
+
+	entries  int             // number of entries
+	blocked  int             // number of blocked entries
+	passed   int             // number of passed entries
+	ports    *stats.Ports    // destination port summary
+	talkers  *stats.Talkers  // top sources and destinations
+	timeline *stats.Timeline // entries over time
+}
+
+// Summary represents the aggregated content of a report
+type Summary struct {
+	Source       string                 `json:"source"`
+	Filter       string                 `json:"filter"`
+	From         time.Time              `json:"from,omitzero"`
+	To           time.Time              `json:"to,omitzero"`
+	First        time.Time              `json:"first,omitzero"`
+	Last         time.Time              `json:"last,omitzero"`
+	Entries      int                    `json:"entries"`
+	Blocked      int                    `json:"blocked"`
+	Passed       int                    `json:"passed"`
+	Other        int                    `json:"other"`
+	Errors       int                    `json:"errors"`
+	Sources      []stats.Talker         `json:"sources"`
+	Destinations []stats.Talker         `json:"destinations"`
+	Ports        []stats.PortSummary    `json:"ports"`
+	Timeline     []stats.TimelineBucket `json:"timeline"`
+	Bookmarks    []bookmark.Bookmark    `json:"bookmarks,omitempty"`
+}
+
+// New creates a new report
+func New(source string, filter string) *Report {
+	return &Report{
+		Source:   source,
+		Filter:   filter,
+		ports:    stats.NewPorts(),
+		talkers:  stats.NewTalkers(),
+		timeline: stats.NewTimeline(),
+	}
+}
+
+// Add processes a single entry
+func (r *Report) Add(entry *stream.LogEntry) {
+	r.entries++
+	switch entry.Action {
+	case stream.ActionBlock:
+		r.blocked++
+	case stream.ActionPass:
+		r.passed++
+	}
+	r.ports.Add(entry)
+	r.talkers.Add(entry)
+	r.timeline.Add(entry)
+}
+
+// Summary returns the aggregated content of the report
+func (r *Report) Summary() Summary {
+	first, last := r.timeline.Range()
+	ports := r.ports.Summaries()
+	if len(ports) > topCount {
+		ports = ports[:topCount]
+	}
+	return Summary{
+		Source:       r.Source,
+		Filter:       r.Filter,
+		From:         r.From,
+		To:           r.To,
+		First:        first,
+		Last:         last,
+		Entries:      r.entries,
+		Blocked:      r.blocked,
+		Passed:       r.passed,
+		Other:        r.entries - r.blocked - r.passed,
+		Errors:       r.Errors,
+		Sources:      r.talkers.Sources(topCount),
+		Destinations: r.talkers.Destinations(topCount),
+		Ports:        ports,
+		Timeline:     r.timeline.Buckets(timelineBuckets),
+		Bookmarks:    r.Bookmarks,
+	}
+}
+
+// CheckFormat returns an error if format is not a valid report format
+func CheckFormat(format string) error {
+	if _, ok := extensions[format]; !ok {
+		return fmt.Errorf("error(report): unknown format %q (available: %s)", format, strings.Join(Formats, ", "))
+	}
+	return nil
+}
+
+// Extension returns the file extension of the given report format
+func Extension(format string) string {
+	return extensions[format]
+}
+
+// Write writes the report in the given format
+func (r *Report) Write(w io.Writer, format string) error {
+	switch format {
+	case FormatHTML:
+		return r.WriteHTML(w)
+	case FormatJSON:
+		return r.WriteJSON(w)
+	case FormatMarkdown:
+		return r.WriteMarkdown(w)
+	}
+	return CheckFormat(format)
+}
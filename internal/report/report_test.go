@@ -0,0 +1,81 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package report
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+// testEntries returns entries shared by the report tests
+func testEntries(start time.Time) []stream.LogEntry {
+	return []stream.LogEntry{
+		{Action: stream.ActionBlock, Src: "192.0.2.1", Dst: "198.51.100.1", DstPort: 22, Time: start},
+		{Action: stream.ActionBlock, Src: "192.0.2.1", Dst: "198.51.100.1", DstPort: 22, Time: start.Add(time.Hour)},
+		{Action: stream.ActionPass, Src: "192.0.2.2", Dst: "198.51.100.2", DstPort: 443, Time: start.Add(2 * time.Hour)},
+		{Action: "rdr", Src: "192.0.2.3", Dst: "198.51.100.2", Time: start.Add(2 * time.Hour)},
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	start := time.Date(2025, 10, 10, 0, 0, 0, 0, time.UTC)
+	r := New("filter.log", "dport 22")
+	r.From, r.To = start, start.Add(24*time.Hour)
+	for _, entry := range testEntries(start) {
+		r.Add(&entry)
+	}
+	var b strings.Builder
+	if err := r.WriteJSON(&b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var s Summary
+	if err := json.Unmarshal([]byte(b.String()), &s); err != nil {
+		t.Fatalf("could not parse json: %v", err)
+	}
+	if s.Entries != 4 || s.Blocked != 2 || s.Passed != 1 || s.Other != 1 {
+		t.Fatalf("unexpected counts: %+v", s)
+	}
+	if !s.From.Equal(start) || !s.First.Equal(start) || !s.Last.Equal(start.Add(2*time.Hour)) {
+		t.Fatalf("unexpected time range: %+v", s)
+	}
+	if len(s.Sources) != 3 || s.Sources[0].Addr != "192.0.2.1" {
+		t.Fatalf("unexpected sources: %+v", s.Sources)
+	}
+	if len(s.Ports) != 2 || len(s.Timeline) != 25 {
+		t.Fatalf("expected 2 ports and 25 timeline buckets, got %d and %d", len(s.Ports), len(s.Timeline))
+	}
+	// period is omitted if not set
+	r.From, r.To = time.Time{}, time.Time{}
+	b.Reset()
+	if err := r.WriteJSON(&b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(b.String(), `"from"`) {
+		t.Fatal("expected from to be omitted")
+	}
+}
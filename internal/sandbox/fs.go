@@ -0,0 +1,377 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sandbox
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// maxSymlinks is the number of symlinks to absolute paths followed when opening a path beneath the roots
+const maxSymlinks = 8
+
+// root is a directory opened before entering the sandbox, paths beneath it are opened relative to its descriptor
+type root struct {
+	dir   *os.File // open directory
+	path  string   // clean absolute path of the directory
+	file  string   // name of the only file that may be opened in the directory (empty allows everything beneath it)
+	write bool     // whether files may be created, written, renamed and removed beneath it
+}
+
+// scope returns the path that may be opened beneath r (the file if restricted to one)
+func (r root) scope() string {
+	if r.file != "" {
+		return filepath.Join(r.path, r.file)
+	}
+	return r.path
+}
+
+var (
+	// roots are the directories of the policy once the process can't open paths by itself (capability mode,
+	// chroot), nil while paths are opened as usual (set once by Enter)
+	roots []root
+	// workDir is the working directory when the roots were opened (relative paths are resolved against it)
+	workDir string
+)
+
+// within returns true if path is dir or beneath it
+func within(path, dir string) bool {
+	return path == dir || dir == string(filepath.Separator) || strings.HasPrefix(path, dir+string(filepath.Separator))
+}
+
+// resolve returns the root name is beneath and the path relative to it (the most specific root, fails if name is
+// beneath no root or would be modified beneath a read-only root)
+func resolve(op, name string, write bool) (root, string, error) {
+	abs := name
+	if !filepath.IsAbs(abs) {
+		abs = filepath.Join(workDir, abs)
+	}
+	abs = filepath.Clean(abs)
+	best, bestLen := -1, 0
+	for i, r := range roots {
+		scope := r.scope()
+		if !within(abs, scope) || (r.file != "" && abs != scope) {
+			continue
+		}
+		if best < 0 || len(scope) > bestLen || (len(scope) == bestLen && r.write) {
+			best, bestLen = i, len(scope)
+		}
+	}
+	if best < 0 || (write && !roots[best].write) {
+		return root{}, "", &fs.PathError{Op: op, Path: name, Err: fs.ErrPermission}
+	}
+	rel, err := filepath.Rel(roots[best].path, abs)
+	if err != nil {
+		return root{}, "", &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	return roots[best], rel, nil
+}
+
+// closeRoots closes the descriptors of all roots (paths are opened as usual afterwards)
+func closeRoots() {
+	for _, r := range roots {
+		r.dir.Close()
+	}
+	roots = nil
+}
+
+// writes returns true if opening a file with flag modifies it
+func writes(flag int) bool {
+	return flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0
+}
+
+// tempName returns a random name for pattern (the last * is replaced, like os.CreateTemp)
+func tempName(pattern string) string {
+	prefix, suffix := pattern, ""
+	if i := strings.LastIndexByte(pattern, '*'); i >= 0 {
+		prefix, suffix = pattern[:i], pattern[i+1:]
+	}
+	return prefix + strconv.FormatUint(uint64(rand.Uint32()), 10) + suffix
+}
+
+// dirFS is the file system of DirFS
+type dirFS string
+
+func (d dirFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	return Open(filepath.Join(string(d), filepath.FromSlash(name)))
+}
+
+func (d dirFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	return ReadDir(filepath.Join(string(d), filepath.FromSlash(name)))
+}
+
+func (d dirFS) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+	return Stat(filepath.Join(string(d), filepath.FromSlash(name)))
+}
+
+// public
+
+// The following functions work like their equivalents of the os package, once the sandbox has been entered they
+// also work where the process can't open paths by itself (beneath the directories of the policy).
+
+// OpenFile opens the named file with flag and perm (see os.OpenFile)
+func OpenFile(name string, flag int, perm fs.FileMode) (*os.File, error) {
+	if roots == nil {
+		return os.OpenFile(name, flag, perm)
+	}
+	for range maxSymlinks {
+		r, rel, err := resolve("open", name, writes(flag))
+		if err != nil {
+			return nil, err
+		}
+		file, target, err := openAt(r, rel, flag, perm)
+		if target == "" {
+			return file, err
+		}
+		// symlinks to absolute paths are resolved against the roots as well
+		name = target
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: errors.New("too many levels of symbolic links")}
+}
+
+// Open opens the named file for reading (see os.Open)
+func Open(name string) (*os.File, error) {
+	return OpenFile(name, os.O_RDONLY, 0)
+}
+
+// Create creates or truncates the named file (see os.Create)
+func Create(name string) (*os.File, error) {
+	return OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o666)
+}
+
+// CreateTemp creates a new temporary file in dir (see os.CreateTemp, dir must not be empty)
+func CreateTemp(dir, pattern string) (*os.File, error) {
+	if roots == nil {
+		return os.CreateTemp(dir, pattern)
+	}
+	for {
+		file, err := OpenFile(filepath.Join(dir, tempName(pattern)), os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o600)
+		if !errors.Is(err, fs.ErrExist) {
+			return file, err
+		}
+	}
+}
+
+// MkdirTemp creates a new temporary directory in dir (see os.MkdirTemp, dir must not be empty)
+func MkdirTemp(dir, pattern string) (string, error) {
+	if roots == nil {
+		return os.MkdirTemp(dir, pattern)
+	}
+	for {
+		name := filepath.Join(dir, tempName(pattern))
+		err := Mkdir(name, 0o700)
+		if err == nil {
+			return name, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return "", err
+		}
+	}
+}
+
+// Stat returns the file info of the named file, symlinks are followed (see os.Stat)
+func Stat(name string) (fs.FileInfo, error) {
+	if roots == nil {
+		return os.Stat(name)
+	}
+	file, err := Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return file.Stat()
+}
+
+// ReadFile reads the named file (see os.ReadFile)
+func ReadFile(name string) ([]byte, error) {
+	if roots == nil {
+		return os.ReadFile(name)
+	}
+	file, err := Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+// WriteFile writes data to the named file, creating it with perm if necessary (see os.WriteFile)
+func WriteFile(name string, data []byte, perm fs.FileMode) error {
+	file, err := OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// ReadDir returns the entries of the named directory sorted by name (see os.ReadDir)
+func ReadDir(name string) ([]fs.DirEntry, error) {
+	if roots == nil {
+		return os.ReadDir(name)
+	}
+	dir, err := Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer dir.Close()
+	names, err := dir.Readdirnames(-1)
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(names)
+	// entries of os.File.ReadDir stat their path on demand, which isn't possible in the sandbox
+	entries := make([]fs.DirEntry, 0, len(names))
+	for _, n := range names {
+		info, err := Stat(filepath.Join(name, n))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, fs.FileInfoToDirEntry(info))
+	}
+	return entries, nil
+}
+
+// DirFS returns a file system of the files beneath dir (see os.DirFS)
+func DirFS(dir string) fs.FS {
+	if roots == nil {
+		return os.DirFS(dir)
+	}
+	return dirFS(dir)
+}
+
+// Mkdir creates the named directory (see os.Mkdir)
+func Mkdir(name string, perm fs.FileMode) error {
+	if roots == nil {
+		return os.Mkdir(name, perm)
+	}
+	r, rel, err := resolve("mkdir", name, true)
+	if err != nil {
+		return err
+	}
+	if err := mkdirAt(r, rel, perm); err != nil {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: err}
+	}
+	return nil
+}
+
+// MkdirAll creates the named directory and all missing parents (see os.MkdirAll)
+func MkdirAll(name string, perm fs.FileMode) error {
+	if roots == nil {
+		return os.MkdirAll(name, perm)
+	}
+	if info, err := Stat(name); err == nil {
+		if !info.IsDir() {
+			return &fs.PathError{Op: "mkdir", Path: name, Err: fmt.Errorf("not a directory")}
+		}
+		return nil
+	}
+	if parent := filepath.Dir(name); parent != name {
+		if err := MkdirAll(parent, perm); err != nil {
+			return err
+		}
+	}
+	if err := Mkdir(name, perm); err != nil && !errors.Is(err, fs.ErrExist) {
+		return err
+	}
+	return nil
+}
+
+// Remove removes the named file or empty directory (see os.Remove)
+func Remove(name string) error {
+	if roots == nil {
+		return os.Remove(name)
+	}
+	r, rel, err := resolve("remove", name, true)
+	if err != nil {
+		return err
+	}
+	if err := removeAt(r, rel); err != nil {
+		return &fs.PathError{Op: "remove", Path: name, Err: err}
+	}
+	return nil
+}
+
+// RemoveAll removes the named file or directory and everything beneath it (see os.RemoveAll)
+func RemoveAll(name string) error {
+	if roots == nil {
+		return os.RemoveAll(name)
+	}
+	err := Remove(name)
+	if err == nil || errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	entries, rerr := ReadDir(name)
+	if rerr != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := RemoveAll(filepath.Join(name, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return Remove(name)
+}
+
+// Rename renames oldpath to newpath, replacing newpath if it exists (see os.Rename)
+func Rename(oldpath, newpath string) error {
+	if roots == nil {
+		return os.Rename(oldpath, newpath)
+	}
+	oldRoot, oldRel, err := resolve("rename", oldpath, true)
+	if err != nil {
+		return err
+	}
+	newRoot, newRel, err := resolve("rename", newpath, true)
+	if err != nil {
+		return err
+	}
+	if err := renameAt(oldRoot, oldRel, newRoot, newRel); err != nil {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+//go:build !unix
+
+package sandbox
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+)
+
+// openAt is not available, roots can't be opened on this platform
+func openAt(r root, rel string, flag int, perm fs.FileMode) (*os.File, string, error) {
+	return nil, "", errors.ErrUnsupported
+}
+
+// mkdirAt is not available, roots can't be opened on this platform
+func mkdirAt(r root, rel string, perm fs.FileMode) error {
+	return errors.ErrUnsupported
+}
+
+// removeAt is not available, roots can't be opened on this platform
+func removeAt(r root, rel string) error {
+	return errors.ErrUnsupported
+}
+
+// renameAt is not available, roots can't be opened on this platform
+func renameAt(oldRoot root, oldRel string, newRoot root, newRel string) error {
+	return errors.ErrUnsupported
+}
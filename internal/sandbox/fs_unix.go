@@ -0,0 +1,133 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+//go:build unix
+
+package sandbox
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"golang.org/x/sys/unix"
+)
+
+// openAt opens rel beneath r, if it is a symlink that can't be followed beneath r (e.g. to an absolute path) the
+// path it points to is returned instead to be resolved against the roots
+func openAt(r root, rel string, flag int, perm fs.FileMode) (*os.File, string, error) {
+	name := filepath.Join(r.path, rel)
+	fd, err := unix.Openat(int(r.dir.Fd()), rel, flag|unix.O_CLOEXEC, uint32(perm.Perm()))
+	if err == nil {
+		return os.NewFile(uintptr(fd), name), "", nil
+	}
+	if flag&unix.O_NOFOLLOW == 0 {
+		if target, lerr := readlinkAt(r, rel); lerr == nil {
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(filepath.Dir(name), target)
+			}
+			return nil, target, nil
+		}
+	}
+	return nil, "", &fs.PathError{Op: "open", Path: name, Err: err}
+}
+
+// mkdirAt creates the directory rel beneath r
+func mkdirAt(r root, rel string, perm fs.FileMode) error {
+	return unix.Mkdirat(int(r.dir.Fd()), rel, uint32(perm.Perm()))
+}
+
+// removeAt removes the file or empty directory rel beneath r
+func removeAt(r root, rel string) error {
+	err := unix.Unlinkat(int(r.dir.Fd()), rel, 0)
+	if err == nil {
+		return nil
+	}
+	rmdirErr := unix.Unlinkat(int(r.dir.Fd()), rel, unix.AT_REMOVEDIR)
+	if rmdirErr == nil {
+		return nil
+	}
+	// like os.Remove, rmdir of a file fails with ENOTDIR on all platforms (unlink of a directory doesn't)
+	if rmdirErr != unix.ENOTDIR {
+		return rmdirErr
+	}
+	return err
+}
+
+// renameAt renames oldRel beneath oldRoot to newRel beneath newRoot
+func renameAt(oldRoot root, oldRel string, newRoot root, newRel string) error {
+	return unix.Renameat(int(oldRoot.dir.Fd()), oldRel, int(newRoot.dir.Fd()), newRel)
+}
+
+// openRoots opens the directories of the policy as roots and limits their descriptors with limit (if not nil,
+// paths that don't exist are skipped, files are opened through their directory)
+func openRoots(p Policy, limit func(fd int, write bool) error) error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("error(sandbox): %w", err)
+	}
+	var opened []root
+	add := func(path string, write bool) error {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return fmt.Errorf("error(sandbox): %w", err)
+		}
+		info, err := os.Stat(abs)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error(sandbox): %w", err)
+		}
+		dir, name := abs, ""
+		if !info.IsDir() {
+			// only the file itself is opened through its directory
+			dir, name = filepath.Dir(abs), filepath.Base(abs)
+		}
+		file, err := os.Open(dir)
+		if err != nil {
+			return fmt.Errorf("error(sandbox): %w", err)
+		}
+		if limit != nil {
+			if err := limit(int(file.Fd()), write); err != nil {
+				file.Close()
+				return fmt.Errorf("error(sandbox): could not limit rights of %s: %w", abs, err)
+			}
+		}
+		opened = append(opened, root{dir: file, path: dir, file: name, write: write})
+		return nil
+	}
+	for i, path := range append(slices.Clone(p.Read), p.Write...) {
+		if err := add(path, i >= len(p.Read)); err != nil {
+			for _, r := range opened {
+				r.dir.Close()
+			}
+			return err
+		}
+	}
+	roots, workDir = opened, wd
+	return nil
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+//go:build unix
+
+package sandbox
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// TestRoots opens paths relative to the directories of a policy like in capability mode or after chroot (the
+// process isn't restricted, paths beneath no directory are refused by the package)
+func TestRoots(t *testing.T) {
+	dir := t.TempDir()
+	for _, sub := range []string{"read", "write"} {
+		if err := os.Mkdir(filepath.Join(dir, sub), 0o700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range []string{filepath.Join("read", "filter.log"), "config.json", "other"} {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte("x"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// latest.log points to an absolute path
+	if err := os.Symlink(filepath.Join(dir, "read", "filter.log"), filepath.Join(dir, "read", "latest.log")); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+	if err := openRoots(Policy{Read: []string{"read", "missing", "config.json"}, Write: []string{filepath.Join(dir, "write")}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(closeRoots)
+	if len(roots) != 3 {
+		t.Fatalf("expected 3 roots, got %d", len(roots))
+	}
+
+	for _, name := range []string{filepath.Join("read", "filter.log"), filepath.Join(dir, "read", "latest.log"), "config.json"} {
+		if data, err := ReadFile(name); err != nil || string(data) != "x" {
+			t.Errorf("read %s: got %q (%v)", name, data, err)
+		}
+	}
+	if _, err := ReadFile("other"); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("read file next to config.json: expected permission error, got %v", err)
+	}
+	if err := WriteFile(filepath.Join("read", "out"), nil, 0o600); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("write read-only dir: expected permission error, got %v", err)
+	}
+
+	// state files are written atomically
+	file, err := CreateTemp(filepath.Join(dir, "write"), ".state-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString("state")
+	file.Close()
+	state := filepath.Join(dir, "write", "state.json")
+	if err := Rename(file.Name(), state); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := Stat(state); err != nil || info.Size() != 5 {
+		t.Errorf("stat renamed file: got %v (%v)", info, err)
+	}
+
+	// incident bundles are written into new directories and archived
+	bundle := filepath.Join("write", "incident", "bundle")
+	if err := MkdirAll(bundle, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFile(filepath.Join(bundle, "raw.log"), []byte("x"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	err = fs.WalkDir(DirFS("write"), ".", func(path string, d fs.DirEntry, err error) error {
+		names = append(names, path)
+		return err
+	})
+	if expect := []string{".", "incident", "incident/bundle", "incident/bundle/raw.log", "state.json"}; err != nil || !slices.Equal(names, expect) {
+		t.Errorf("walk: expected %q, got %q (%v)", expect, names, err)
+	}
+	if err := RemoveAll(filepath.Join("write", "incident")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Stat(filepath.Join("write", "incident")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("stat removed dir: expected not exist, got %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sandbox
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// ErrUnsupported is wrapped by errors of platforms that can't restrict the process as requested
+var ErrUnsupported = errors.New("error(sandbox): not supported")
+
+// resolverFiles are read by the dns resolver whenever they change
+var resolverFiles = []string{"/etc/hosts", "/etc/nsswitch.conf", "/etc/resolv.conf", "/etc/services"}
+
+// Policy describes what the process accesses after entering the sandbox
+type Policy struct {
+	Network bool     // whether connections are made afterwards (e.g. by sinks)
+	Read    []string // files and directories read afterwards (e.g. to reopen a rotated log file)
+	User    string   // user to switch to if running as root (empty keeps root)
+	Write   []string // directories written to afterwards (e.g. reports)
+}
+
+// lookupUser returns the user and group id of name
+func lookupUser(name string) (int, int, error) {
+	u, err := user.Lookup(name)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error(sandbox): %w", err)
+	}
+	uid, err := strconv.Atoi(u.Uid)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error(sandbox): invalid id of user %s: %w", name, err)
+	}
+	gid, err := strconv.Atoi(u.Gid)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error(sandbox): invalid group of user %s: %w", name, err)
+	}
+	return uid, gid, nil
+}
+
+// public
+
+// Enter restricts the process to p, all other files have to be opened before or through the functions of this
+// package (landlock on linux, capability mode on freebsd, as root a chroot into an empty directory if neither is
+// available and nothing is connected to afterwards), as root it switches to p.User afterwards (errors wrap
+// ErrUnsupported if the platform can't restrict the process, the user is switched anyway)
+func Enter(p Policy) error {
+	// load lazily initialized data while it can still be read
+	_ = time.Local.String()
+	if p.Network {
+		x509.SystemCertPool()
+		p.Read = append(slices.Clone(p.Read), resolverFiles...)
+	}
+	root := os.Geteuid() == 0
+	uid, gid := -1, -1
+	if root && p.User != "" {
+		var err error
+		if uid, gid, err = lookupUser(p.User); err != nil {
+			return err
+		}
+	}
+	err := enter(p)
+	if errors.Is(err, ErrUnsupported) && root && !p.Network {
+		// root can still confine itself to the directories of the policy (not if it connects, the resolver files
+		// can't be read from an empty root)
+		if cerr := chroot(p); cerr == nil || !errors.Is(cerr, ErrUnsupported) {
+			err = cerr
+		}
+	}
+	if err != nil && !errors.Is(err, ErrUnsupported) {
+		return err
+	}
+	if uid >= 0 {
+		if err := setUser(uid, gid); err != nil {
+			return err
+		}
+	}
+	return err
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sandbox
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+var (
+	// readRights are the rights of directories read in capability mode (and of the files opened beneath them)
+	readRights = []uint64{unix.CAP_FCNTL, unix.CAP_FSTAT, unix.CAP_FSTATAT, unix.CAP_LOOKUP, unix.CAP_READ, unix.CAP_SEEK}
+	// writeRights are the rights of directories written to in capability mode
+	writeRights = append(readRights, unix.CAP_CREATE, unix.CAP_FSYNC, unix.CAP_FTRUNCATE, unix.CAP_MKDIRAT,
+		unix.CAP_RENAMEAT_SOURCE, unix.CAP_RENAMEAT_TARGET, unix.CAP_UNLINKAT, unix.CAP_WRITE)
+)
+
+// limitRights limits the rights of the descriptor of a directory to those needed to read or write beneath it
+func limitRights(fd int, write bool) error {
+	rights := readRights
+	if write {
+		rights = writeRights
+	}
+	r, err := unix.CapRightsInit(rights)
+	if err != nil {
+		return err
+	}
+	return unix.CapRightsLimit(uintptr(fd), r)
+}
+
+// enter opens the directories of the policy with limited rights and enters capability mode, files are opened
+// relative to them afterwards (connecting isn't possible in capability mode)
+func enter(p Policy) error {
+	if p.Network {
+		return fmt.Errorf("%w: capability mode can't connect after startup", ErrUnsupported)
+	}
+	if err := openRoots(p, limitRights); err != nil {
+		if errors.Is(err, unix.ENOSYS) {
+			return fmt.Errorf("%w: capsicum is not enabled in the kernel", ErrUnsupported)
+		}
+		return err
+	}
+	if err := unix.CapEnter(); err != nil {
+		closeRoots()
+		if errors.Is(err, unix.ENOSYS) {
+			return fmt.Errorf("%w: capsicum is not enabled in the kernel", ErrUnsupported)
+		}
+		return fmt.Errorf("error(sandbox): could not enter capability mode: %w", err)
+	}
+	return nil
+}
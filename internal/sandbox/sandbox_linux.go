@@ -0,0 +1,128 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sandbox
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	// landlock system calls (same number on all architectures)
+	sysLandlockCreateRuleset = 444
+	sysLandlockAddRule       = 445
+	sysLandlockRestrictSelf  = 446
+
+	landlockRulePathBeneath = 1
+	prSetNoNewPrivs         = 38
+
+	// filesystem access rights (landlock abi 1)
+	accessExecute    = 1 << 0
+	accessWriteFile  = 1 << 1
+	accessReadFile   = 1 << 2
+	accessReadDir    = 1 << 3
+	accessRemoveDir  = 1 << 4
+	accessRemoveFile = 1 << 5
+	accessMakeDir    = 1 << 7
+	accessMakeReg    = 1 << 8
+	accessAll        = 1<<13 - 1 // all rights of abi 1 (everything not granted by a rule is denied)
+
+	accessFile  = accessExecute | accessWriteFile | accessReadFile // rights that apply to files (not directories)
+	accessRead  = accessReadFile | accessReadDir
+	accessWrite = accessRead | accessWriteFile | accessRemoveDir | accessRemoveFile | accessMakeDir | accessMakeReg
+)
+
+// rulesetAttr is struct landlock_ruleset_attr
+type rulesetAttr struct {
+	handledAccessFS uint64
+}
+
+// pathBeneathAttr is struct landlock_path_beneath_attr (packed, the kernel reads the first 12 bytes)
+type pathBeneathAttr struct {
+	allowedAccess uint64
+	parentFd      int32
+}
+
+// addRule grants access to path and everything beneath it (paths that don't exist are skipped)
+func addRule(ruleset int, path string, access uint64) error {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error(sandbox): %w", err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("error(sandbox): %w", err)
+	}
+	if !info.IsDir() {
+		access &= accessFile
+	}
+	attr := pathBeneathAttr{allowedAccess: access, parentFd: int32(file.Fd())}
+	if _, _, errno := syscall.Syscall6(sysLandlockAddRule, uintptr(ruleset), landlockRulePathBeneath,
+		uintptr(unsafe.Pointer(&attr)), 0, 0, 0); errno != 0 {
+		return fmt.Errorf("error(sandbox): could not add landlock rule for %s: %w", path, errno)
+	}
+	return nil
+}
+
+// enter restricts filesystem access of all threads with landlock
+func enter(p Policy) error {
+	attr := rulesetAttr{handledAccessFS: accessAll}
+	fd, _, errno := syscall.Syscall(sysLandlockCreateRuleset, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr), 0)
+	if errno == syscall.ENOSYS || errno == syscall.EOPNOTSUPP {
+		return fmt.Errorf("%w: landlock is not enabled in the kernel", ErrUnsupported)
+	}
+	if errno != 0 {
+		return fmt.Errorf("error(sandbox): could not create landlock ruleset: %w", errno)
+	}
+	defer syscall.Close(int(fd))
+	for _, path := range p.Read {
+		if err := addRule(int(fd), path, accessRead); err != nil {
+			return err
+		}
+	}
+	for _, path := range p.Write {
+		if err := addRule(int(fd), path, accessWrite); err != nil {
+			return err
+		}
+	}
+	// landlock applies to the calling thread only, go runs on many
+	if _, _, errno := syscall.AllThreadsSyscall(syscall.SYS_PRCTL, prSetNoNewPrivs, 1, 0); errno != 0 {
+		if errno == syscall.ENOTSUP {
+			return fmt.Errorf("%w: binary is built with cgo", ErrUnsupported)
+		}
+		return fmt.Errorf("error(sandbox): could not set no_new_privs: %w", errno)
+	}
+	if _, _, errno := syscall.AllThreadsSyscall(sysLandlockRestrictSelf, fd, 0, 0); errno != 0 {
+		return fmt.Errorf("error(sandbox): could not enforce landlock ruleset: %w", errno)
+	}
+	return nil
+}
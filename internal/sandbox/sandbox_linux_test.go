@@ -0,0 +1,78 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sandbox
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// TestEnter runs in a child process since the sandbox can't be left again
+func TestEnter(t *testing.T) {
+	if dir := os.Getenv("SANDBOX_TEST_DIR"); dir != "" {
+		if err := Enter(Policy{Read: []string{filepath.Join(dir, "read")}, Write: []string{filepath.Join(dir, "write")}}); err != nil {
+			if errors.Is(err, ErrUnsupported) {
+				os.Exit(3)
+			}
+			t.Fatal(err)
+		}
+		if _, err := os.ReadFile(filepath.Join(dir, "read", "log")); err != nil {
+			t.Errorf("read allowed file: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "write", "out"), []byte("x"), 0o600); err != nil {
+			t.Errorf("write allowed dir: %v", err)
+		}
+		if _, err := os.ReadFile(filepath.Join(dir, "other")); err == nil {
+			t.Error("read other file: want error")
+		}
+		if err := os.WriteFile(filepath.Join(dir, "read", "out"), []byte("x"), 0o600); err == nil {
+			t.Error("write read-only dir: want error")
+		}
+		return
+	}
+
+	dir := t.TempDir()
+	for _, sub := range []string{"read", "write"} {
+		if err := os.Mkdir(filepath.Join(dir, sub), 0o700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range []string{filepath.Join("read", "log"), "other"} {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte("x"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cmd := exec.Command(os.Args[0], "-test.run=^TestEnter$")
+	cmd.Env = append(os.Environ(), "SANDBOX_TEST_DIR="+dir)
+	out, err := cmd.CombinedOutput()
+	if exit, ok := err.(*exec.ExitError); ok && exit.ExitCode() == 3 {
+		t.Skip("landlock is not available")
+	}
+	if err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+}
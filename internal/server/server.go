@@ -0,0 +1,155 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/config"
+)
+
+const shutdownTimeout = 5 * time.Second
+
+// TLS represents the tls settings of a network service
+type TLS struct {
+	Cert     string // certificate file (tls is disabled if empty)
+	ClientCA string // ca file to verify client certificates against (client certificates are not required if empty)
+	Key      string // private key file
+}
+
+// Config returns the tls configuration (nil if tls is disabled)
+func (t TLS) Config() (*tls.Config, error) {
+	if t.Cert == "" && t.Key == "" {
+		if t.ClientCA != "" {
+			return nil, fmt.Errorf("error(server): client ca requires certificate and key")
+		}
+		return nil, nil
+	}
+	if t.Cert == "" || t.Key == "" {
+		return nil, fmt.Errorf("error(server): certificate and key must be set together")
+	}
+	cert, err := tls.LoadX509KeyPair(t.Cert, t.Key)
+	if err != nil {
+		return nil, fmt.Errorf("error(server): could not load certificate: %w", err)
+	}
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if t.ClientCA != "" {
+		data, err := os.ReadFile(t.ClientCA)
+		if err != nil {
+			return nil, fmt.Errorf("error(server): could not read client ca: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("error(server): no certificates found in %s", t.ClientCA)
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return cfg, nil
+}
+
+// equal compares secrets in constant time
+func equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// authRequired returns true if any credentials are configured
+func authRequired(auth config.Auth) bool {
+	return auth.Token != "" || auth.Username != ""
+}
+
+// RequireAuth wraps h so requests must present the bearer token or the basic auth credentials (if configured)
+func RequireAuth(h http.Handler, auth config.Auth) http.Handler {
+	if !authRequired(auth) {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth.Token != "" && equal(r.Header.Get("Authorization"), "Bearer "+auth.Token) {
+			h.ServeHTTP(w, r)
+			return
+		}
+		if username, password, ok := r.BasicAuth(); ok && auth.Username != "" &&
+			equal(username, auth.Username) && equal(password, auth.Password) {
+			h.ServeHTTP(w, r)
+			return
+		}
+		if auth.Username != "" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="opnsense-filterlog"`)
+		}
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	})
+}
+
+// IsLoopback returns true if addr (host:port) only listens on a loopback interface
+func IsLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// Serve serves h on addr (using tls and authentication if configured) until ctx is done
+func Serve(ctx context.Context, addr string, h http.Handler, tlsConfig *tls.Config, auth config.Auth) error {
+	if !IsLoopback(addr) && (tlsConfig == nil || !authRequired(auth)) {
+		fmt.Fprintf(os.Stderr, "warning(server): %s is reachable from the network without tls and authentication\n", addr)
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("error(server): %w", err)
+	}
+	if tlsConfig != nil {
+		ln = tls.NewListener(ln, tlsConfig)
+	}
+	srv := &http.Server{
+		Handler:           RequireAuth(h, auth),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+	if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("error(server): %w", err)
+	}
+	return nil
+}
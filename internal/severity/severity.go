@@ -0,0 +1,73 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package severity
+
+import (
+	"gitlab.com/allddd/opnsense-filterlog/internal/config"
+	"gitlab.com/allddd/opnsense-filterlog/internal/filter"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+// rule represents a compiled classification rule
+type rule struct {
+	compiled filter.FilterNode // compiled filter expression
+	level    string            // severity level of matching entries
+}
+
+// Classifier assigns severity levels to entries (the first matching rule wins, info if none matches)
+type Classifier struct {
+	rules []rule // compiled rules in config order
+}
+
+// public
+
+// Classify sets the severity of the entry (does nothing if c is nil)
+func (c *Classifier) Classify(entry *stream.LogEntry) {
+	if c == nil {
+		return
+	}
+	for _, r := range c.rules {
+		if r.compiled.Matches(entry) {
+			entry.Severity = r.level
+			return
+		}
+	}
+	entry.Severity = stream.SeverityInfo
+}
+
+// New compiles the rules with opts (returns nil if there are no rules, entries are not classified then)
+func New(rules []config.SeverityRule, opts filter.Options) (*Classifier, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	c := &Classifier{rules: make([]rule, 0, len(rules))}
+	for _, r := range rules {
+		compiled, err := filter.CompileWith(r.Filter, opts)
+		if err != nil {
+			return nil, err
+		}
+		c.rules = append(c.rules, rule{compiled: compiled, level: r.Level})
+	}
+	return c, nil
+}
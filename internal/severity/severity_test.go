@@ -0,0 +1,82 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package severity
+
+import (
+	"testing"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/config"
+	"gitlab.com/allddd/opnsense-filterlog/internal/filter"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+func TestClassify(t *testing.T) {
+	c, err := New([]config.SeverityRule{
+		{Filter: "action block and dport 22", Level: stream.SeverityCritical},
+		{Filter: "action block", Level: stream.SeverityWarning},
+		{Filter: "dport 53", Level: stream.SeverityNotice},
+	}, filter.Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests := []struct {
+		name     string
+		entry    stream.LogEntry
+		expected string
+	}{
+		{name: "first matching rule wins", entry: stream.LogEntry{Action: stream.ActionBlock, DstPort: 22}, expected: stream.SeverityCritical},
+		{name: "second rule", entry: stream.LogEntry{Action: stream.ActionBlock, DstPort: 53}, expected: stream.SeverityWarning},
+		{name: "third rule", entry: stream.LogEntry{Action: stream.ActionPass, DstPort: 53}, expected: stream.SeverityNotice},
+		{name: "no matching rule", entry: stream.LogEntry{Action: stream.ActionPass, DstPort: 443}, expected: stream.SeverityInfo},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c.Classify(&tc.entry)
+			if tc.entry.Severity != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, tc.entry.Severity)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	c, err := New(nil, filter.Options{})
+	if err != nil || c != nil {
+		t.Fatalf("expected nil classifier without rules, got %v and %v", c, err)
+	}
+	// a nil classifier leaves entries unclassified
+	var entry stream.LogEntry
+	c.Classify(&entry)
+	if entry.Severity != "" {
+		t.Fatalf("expected no severity, got %q", entry.Severity)
+	}
+	if _, err := New([]config.SeverityRule{{Filter: "src and", Level: stream.SeverityInfo}}, filter.Options{}); err == nil {
+		t.Fatal("expected error for invalid filter, got nil")
+	}
+	// rules are compiled with the options
+	if _, err := New([]config.SeverityRule{{Filter: "blocked wan", Level: stream.SeverityInfo}}, filter.Options{Strict: true}); err == nil {
+		t.Fatal("expected error for adjacent terms in strict mode, got nil")
+	}
+}
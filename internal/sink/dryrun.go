@@ -0,0 +1,85 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sink
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// dryRunTransport describes requests instead of sending them and answers them with 200 OK
+type dryRunTransport struct {
+	name string    // description of the sink (without credentials)
+	w    io.Writer // destination of the descriptions
+}
+
+// dryRunLines describes every write (a line of a formatted sink) instead of writing it
+type dryRunLines struct {
+	name string    // description of the sink (without credentials)
+	w    io.Writer // destination of the descriptions
+}
+
+// public
+
+// RoundTrip (dryRunTransport) describes the request
+func (t dryRunTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		body, _ = io.ReadAll(req.Body)
+		req.Body.Close()
+	}
+	headers := make([]string, 0)
+	for name := range req.Header {
+		if name != "User-Agent" {
+			headers = append(headers, name+": "+req.Header.Get(name))
+		}
+	}
+	sort.Strings(headers)
+	if _, err := fmt.Fprintf(t.w, "dry-run: %s %s (%s)\n%s\n", req.Method, t.name, strings.Join(headers, ", "), bytes.TrimSpace(body)); err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		Body:       io.NopCloser(bytes.NewReader(nil)),
+		Request:    req,
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+	}, nil
+}
+
+// Write (dryRunLines) describes the line
+func (l dryRunLines) Write(p []byte) (int, error) {
+	if _, err := fmt.Fprintf(l.w, "dry-run: %s: %s\n", l.name, bytes.TrimSuffix(p, []byte("\n"))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close (dryRunLines) does nothing
+func (l dryRunLines) Close() error {
+	return nil
+}
@@ -0,0 +1,236 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sink
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/debuglog"
+	"gitlab.com/allddd/opnsense-filterlog/internal/meta"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+const (
+	// schemes
+	schemeMQTT  = "mqtt"
+	schemeMQTTS = "mqtts"
+
+	mqttPort    = "1883"
+	mqttPortTLS = "8883"
+	mqttTimeout = 10 * time.Second
+
+	// packet types (mqtt 3.1.1)
+	mqttConnect    = 0x10
+	mqttConnack    = 0x20
+	mqttPublish    = 0x30
+	mqttDisconnect = 0xe0
+)
+
+// mqtt publishes entries as json messages to an mqtt broker (mqtt 3.1.1, qos 0)
+type mqtt struct {
+	addr     string        // broker address (host:port)
+	clientID string        // client identifier
+	conn     net.Conn      // broker connection (nil if not connected)
+	dryRun   io.Writer     // destination of descriptions of messages instead of the broker (nil if not a dry run)
+	password *string       // password (nil if none)
+	retain   bool          // whether the broker should retain the last message
+	timeout  time.Duration // connect and write timeout
+	tls      *tls.Config   // tls configuration (nil if plain tcp)
+	topic    string        // topic to publish to
+	url      string        // url without password (for logs)
+	username string        // username (empty if none)
+}
+
+// mqttString encodes a length-prefixed utf-8 string
+func mqttString(s string) []byte {
+	return append(binary.BigEndian.AppendUint16(nil, uint16(len(s))), s...)
+}
+
+// mqttPacket encodes a control packet with the given fixed header byte and body
+func mqttPacket(header byte, body []byte) []byte {
+	packet := []byte{header}
+	// remaining length is encoded 7 bits at a time, least significant first
+	for n := len(body); ; {
+		b := byte(n % 128)
+		if n /= 128; n > 0 {
+			b |= 0x80
+		}
+		packet = append(packet, b)
+		if n == 0 {
+			break
+		}
+	}
+	return append(packet, body...)
+}
+
+// newMQTT creates an mqtt sink (the connection is established on first use)
+func newMQTT(u *url.URL) (*mqtt, error) {
+	m := &mqtt{
+		clientID: fmt.Sprintf("%s-%d", meta.Name, os.Getpid()),
+		retain:   u.Query().Get("retain") == "1",
+		topic:    strings.TrimPrefix(u.Path, "/"),
+		url:      u.Redacted(),
+		timeout:  mqttTimeout,
+	}
+	if m.topic == "" {
+		return nil, fmt.Errorf("error(sink): missing topic in %s", m.url)
+	}
+	if id := u.Query().Get("client_id"); id != "" {
+		m.clientID = id
+	}
+	if u.User != nil {
+		m.username = u.User.Username()
+		if password, ok := u.User.Password(); ok {
+			m.password = &password
+		}
+	}
+	port := u.Port()
+	if u.Scheme == schemeMQTTS {
+		m.tls = &tls.Config{ServerName: u.Hostname()}
+		if port == "" {
+			port = mqttPortTLS
+		}
+	} else if port == "" {
+		port = mqttPort
+	}
+	m.addr = net.JoinHostPort(u.Hostname(), port)
+	return m, nil
+}
+
+// connect connects to the broker and waits for the connection to be acknowledged
+func (m *mqtt) connect() error {
+	dialer := &net.Dialer{Timeout: m.timeout}
+	var conn net.Conn
+	var err error
+	if m.tls != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", m.addr, m.tls)
+	} else {
+		conn, err = dialer.Dial("tcp", m.addr)
+	}
+	if err != nil {
+		return fmt.Errorf("error(sink): could not connect to %s: %w", m.url, err)
+	}
+	flags := byte(0x02) // clean session
+	payload := mqttString(m.clientID)
+	if m.username != "" {
+		flags |= 0x80
+		payload = append(payload, mqttString(m.username)...)
+	}
+	if m.password != nil {
+		flags |= 0x40
+		payload = append(payload, mqttString(*m.password)...)
+	}
+	// protocol name and level (4 is mqtt 3.1.1), flags and keep alive (0 disables it)
+	body := append(mqttString("MQTT"), 4, flags, 0, 0)
+	conn.SetDeadline(time.Now().Add(m.timeout))
+	if _, err := conn.Write(mqttPacket(mqttConnect, append(body, payload...))); err != nil {
+		conn.Close()
+		return fmt.Errorf("error(sink): could not connect to %s: %w", m.url, err)
+	}
+	ack := make([]byte, 4)
+	if _, err := io.ReadFull(conn, ack); err != nil {
+		conn.Close()
+		return fmt.Errorf("error(sink): could not connect to %s: %w", m.url, err)
+	}
+	if ack[0] != mqttConnack || ack[1] != 2 {
+		conn.Close()
+		return fmt.Errorf("error(sink): could not connect to %s: unexpected response", m.url)
+	}
+	if ack[3] != 0 {
+		conn.Close()
+		return fmt.Errorf("error(sink): could not connect to %s: connection refused (code %d)", m.url, ack[3])
+	}
+	conn.SetDeadline(time.Time{})
+	m.conn = conn
+	return nil
+}
+
+// Close disconnects from the broker
+func (m *mqtt) Close() error {
+	if m.conn == nil {
+		return nil
+	}
+	m.conn.SetWriteDeadline(time.Now().Add(m.timeout))
+	m.conn.Write(mqttPacket(mqttDisconnect, nil))
+	err := m.conn.Close()
+	m.conn = nil
+	return err
+}
+
+// Flush does nothing (entries are published immediately)
+func (m *mqtt) Flush() error {
+	return nil
+}
+
+// Write publishes the entry as json (reconnecting once if the connection was lost)
+func (m *mqtt) Write(entry *stream.LogEntry) error {
+	payload, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("error(sink): could not encode entry: %w", err)
+	}
+	header := byte(mqttPublish)
+	if m.retain {
+		header |= 0x01
+	}
+	if m.dryRun != nil {
+		_, err := fmt.Fprintf(m.dryRun, "dry-run: PUBLISH %s\n%s\n", m.url, payload)
+		return err
+	}
+	packet := mqttPacket(header, append(mqttString(m.topic), payload...))
+	for attempt := 0; ; attempt++ {
+		if m.conn == nil {
+			if err := m.connect(); err != nil {
+				return &failedError{entries: []stream.LogEntry{*entry}, err: err}
+			}
+		}
+		m.conn.SetWriteDeadline(time.Now().Add(m.timeout))
+		_, err := m.conn.Write(packet)
+		if err == nil {
+			return nil
+		}
+		m.conn.Close()
+		m.conn = nil
+		if attempt > 0 {
+			return &failedError{
+				entries: []stream.LogEntry{*entry},
+				err:     fmt.Errorf("error(sink): could not publish to %s: %w", m.url, err),
+			}
+		}
+		debuglog.Warn("sink: reconnecting", "sink", m.String(), "error", err)
+	}
+}
+
+// String returns the url without password
+func (m *mqtt) String() string {
+	return m.url
+}
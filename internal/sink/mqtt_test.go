@@ -0,0 +1,182 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sink
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"testing"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/filter"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+// mqttReadPacket reads a single control packet and returns its fixed header byte and body
+func mqttReadPacket(r io.Reader) (byte, []byte, error) {
+	header := make([]byte, 1)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+	length, multiplier := 0, 1
+	for {
+		b := make([]byte, 1)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return 0, nil, err
+		}
+		length += int(b[0]&0x7f) * multiplier
+		if b[0]&0x80 == 0 {
+			break
+		}
+		multiplier *= 128
+	}
+	body := make([]byte, length)
+	_, err := io.ReadFull(r, body)
+	return header[0], body, err
+}
+
+// mqttBroker accepts a single connection, acknowledges it with code and sends received packets to the returned channel
+func mqttBroker(t *testing.T, code byte) (string, <-chan []byte) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	packets := make(chan []byte, 10)
+	go func() {
+		defer close(packets)
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			header, body, err := mqttReadPacket(conn)
+			if err != nil {
+				return
+			}
+			packets <- append([]byte{header}, body...)
+			if header == mqttConnect {
+				conn.Write([]byte{mqttConnack, 2, 0, code})
+			}
+		}
+	}()
+	return ln.Addr().String(), packets
+}
+
+func TestMQTTPacket(t *testing.T) {
+	tests := []struct {
+		name         string
+		bodyLen      int
+		expectLength []byte
+	}{
+		{name: "empty", bodyLen: 0, expectLength: []byte{0x00}},
+		{name: "one byte", bodyLen: 127, expectLength: []byte{0x7f}},
+		{name: "two bytes", bodyLen: 128, expectLength: []byte{0x80, 0x01}},
+		{name: "three bytes", bodyLen: 16384, expectLength: []byte{0x80, 0x80, 0x01}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			packet := mqttPacket(mqttPublish, make([]byte, tc.bodyLen))
+			if packet[0] != mqttPublish {
+				t.Fatalf("expected header %#x, got %#x", mqttPublish, packet[0])
+			}
+			if got := packet[1 : 1+len(tc.expectLength)]; string(got) != string(tc.expectLength) {
+				t.Fatalf("expected remaining length %v, got %v", tc.expectLength, got)
+			}
+			if len(packet) != 1+len(tc.expectLength)+tc.bodyLen {
+				t.Fatalf("unexpected packet length %d", len(packet))
+			}
+		})
+	}
+}
+
+func TestMQTT(t *testing.T) {
+	addr, packets := mqttBroker(t, 0)
+	s, err := New("mqtt://user:secret@"+addr+"/opnsense/filterlog?client_id=test&filter=action+block", filter.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.String(); got != "mqtt://user:xxxxx@"+addr+"/opnsense/filterlog?client_id=test" {
+		t.Fatalf("unexpected string %q", got)
+	}
+	for _, entry := range []stream.LogEntry{
+		{Action: stream.ActionPass, Src: "192.0.2.1"},
+		{Action: stream.ActionBlock, Src: "192.0.2.2"},
+	} {
+		if err := s.Write(&entry); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// connect
+	connect := <-packets
+	body := connect[1:]
+	if connect[0] != mqttConnect || string(body[2:6]) != "MQTT" || body[6] != 4 {
+		t.Fatalf("unexpected connect packet %v", connect)
+	}
+	if flags := body[7]; flags != 0xc2 {
+		t.Fatalf("expected connect flags 0xc2, got %#x", flags)
+	}
+	if n := binary.BigEndian.Uint16(body[10:]); string(body[12:12+n]) != "test" {
+		t.Fatalf("unexpected client id %q", body[12:12+n])
+	}
+	// publish (only the blocked entry matches the filter)
+	publish := <-packets
+	if publish[0] != mqttPublish {
+		t.Fatalf("expected publish packet, got %#x", publish[0])
+	}
+	n := binary.BigEndian.Uint16(publish[1:])
+	if topic := string(publish[3 : 3+n]); topic != "opnsense/filterlog" {
+		t.Fatalf("unexpected topic %q", topic)
+	}
+	var entry stream.LogEntry
+	if err := json.Unmarshal(publish[3+n:], &entry); err != nil {
+		t.Fatalf("could not parse payload: %v", err)
+	}
+	if entry.Src != "192.0.2.2" {
+		t.Fatalf("unexpected entry %+v", entry)
+	}
+	// disconnect
+	if disconnect := <-packets; disconnect[0] != mqttDisconnect {
+		t.Fatalf("expected disconnect packet, got %#x", disconnect[0])
+	}
+}
+
+func TestMQTTRefused(t *testing.T) {
+	addr, _ := mqttBroker(t, 5)
+	s, err := New("mqtt://"+addr+"/topic", filter.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.Write(&stream.LogEntry{}); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
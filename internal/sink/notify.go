@@ -0,0 +1,124 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sink
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+const (
+	// schemes
+	schemeDiscord  = "discord"
+	schemeSlack    = "slack"
+	schemeTelegram = "telegram"
+
+	telegramHost = "api.telegram.org"
+)
+
+// notifyTemplates are the body templates per notifier (can be replaced with template_file)
+var notifyTemplates = map[string]string{
+	schemeDiscord:  `{"content":{{json .Text}}}`,
+	schemeSlack:    `{"text":{{json .Text}}}`,
+	schemeTelegram: `{"chat_id":{{json .Chat}},"text":{{json .Text}}}`,
+}
+
+// notifyLine formats the key fields of an entry as message line
+// (e.g. [critical] block in on igb0: tcp 203.0.113.7:51234 -> 192.0.2.10:22, rule 5 (ssh), Oct 10 14:02:00)
+func notifyLine(entry *stream.LogEntry) string {
+	addr := func(ip string, port uint16) string {
+		if port == 0 {
+			return ip
+		}
+		return net.JoinHostPort(ip, strconv.FormatUint(uint64(port), 10))
+	}
+	var b strings.Builder
+	if entry.Severity != "" {
+		fmt.Fprintf(&b, "[%s] ", entry.Severity)
+	}
+	fmt.Fprintf(&b, "%s %s on %s: %s %s -> %s", entry.Action, entry.Direction, entry.Interface, entry.ProtoName,
+		addr(entry.Src, entry.SrcPort), addr(entry.Dst, entry.DstPort))
+	if entry.RuleNum != "" {
+		fmt.Fprintf(&b, ", rule %s", entry.RuleNum)
+		if entry.Label != "" {
+			fmt.Fprintf(&b, " (%s)", entry.Label)
+		}
+	}
+	b.WriteString(", " + entry.Time.Format(time.Stamp))
+	if entry.Origin != "" {
+		b.WriteString(" on " + entry.Origin)
+	}
+	return b.String()
+}
+
+// newNotifier creates a slack, discord or telegram sink, these are webhooks with built-in message templates
+// posting to https (sink parameters are removed from the query)
+func newNotifier(u *url.URL) (*webhook, error) {
+	target := *u
+	target.Scheme = schemeHTTPS
+	name := u.Scheme + "://" + u.Host
+	query := u.Query()
+	chat := ""
+	if u.Scheme == schemeTelegram {
+		// telegram://<bot token>@api.telegram.org?chat_id=<chat>
+		token := u.User.Username()
+		if password, ok := u.User.Password(); ok {
+			token += ":" + password
+		}
+		chat = query.Get("chat_id")
+		if token == "" || chat == "" {
+			return nil, fmt.Errorf("error(sink): telegram requires a bot token and chat_id in %s", u.Redacted())
+		}
+		if target.Host == "" {
+			target.Host = telegramHost
+		}
+		target.User = nil
+		target.Path = "/bot" + token + "/sendMessage"
+		query.Del("chat_id")
+		name = u.Scheme + "://" + target.Host + "?chat_id=" + chat
+	} else if u.Host == "" || u.Path == "" {
+		return nil, fmt.Errorf("error(sink): %s requires the webhook url in %s", u.Scheme, u.Redacted())
+	}
+	target.RawQuery = query.Encode()
+	w, err := newWebhook(&target)
+	if err != nil {
+		return nil, err
+	}
+	if w.template == nil {
+		if w.template, err = webhookTemplate(notifyTemplates[u.Scheme]); err != nil {
+			return nil, err
+		}
+	}
+	w.chat = chat
+	w.line = notifyLine
+	// the path of slack and discord webhooks contains the token
+	w.name = name
+	return w, nil
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sink
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/output"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+const (
+	// schemes
+	schemeFile = "file"
+	schemeTCP  = "tcp"
+	schemeUDP  = "udp"
+)
+
+// formatted writes entries in an output format to a file or a tcp/udp destination
+type formatted struct {
+	output.Sink
+	dest io.WriteCloser // file or connection
+	url  string         // url (for logs)
+}
+
+// newFormatted creates a sink from a file:///path, tcp://host:port or udp://host:port url (the format query parameter
+// selects the output format, ndjson by default, the template query parameter is the line of the template format, the
+// compress query parameter the compression of files, lines are described to dryRun instead if not nil)
+func newFormatted(u *url.URL, dryRun io.Writer) (*formatted, error) {
+	query := u.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = output.FormatNDJSON
+	}
+	if format == output.FormatJSON {
+		// the json document would only be complete once the daemon stops
+		return nil, fmt.Errorf("error(sink): format json is not supported in %s (use ndjson)", u.Redacted())
+	}
+	destination := u.Scheme + "://" + u.Host
+	if u.Scheme == schemeFile {
+		destination = u.Path
+	}
+	if destination == "" || (u.Scheme != schemeFile && u.Port() == "") {
+		return nil, fmt.Errorf("error(sink): missing destination in %s", u.Redacted())
+	}
+	opts := output.Options{Template: query.Get("template")}
+	// check the format before the file is created or the destination is connected
+	if _, err := output.New(format, io.Discard, opts); err != nil {
+		return nil, err
+	}
+	var dest io.WriteCloser = dryRunLines{name: u.Redacted(), w: dryRun}
+	var err error
+	if dryRun != nil {
+		_, err = output.Compression(destination, query.Get("compress"))
+	} else {
+		dest, err = output.Open(destination, query.Get("compress"))
+	}
+	if err != nil {
+		return nil, err
+	}
+	s, err := output.New(format, dest, opts)
+	if err != nil {
+		dest.Close()
+		return nil, err
+	}
+	return &formatted{Sink: s, dest: dest, url: u.Redacted()}, nil
+}
+
+// public
+
+// Write writes the entry (entries that could not be sent to a network destination are returned in a failedError)
+func (f *formatted) Write(entry *stream.LogEntry) error {
+	err := f.Sink.Write(entry)
+	if err != nil && output.IsNetwork(f.url) {
+		return &failedError{
+			entries: []stream.LogEntry{*entry},
+			err:     fmt.Errorf("error(sink): could not write to %s: %w", f.url, err),
+		}
+	}
+	return err
+}
+
+// Close completes the output and closes the destination
+func (f *formatted) Close() error {
+	err := f.Sink.Close()
+	if closeErr := f.dest.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// String returns the url
+func (f *formatted) String() string {
+	return f.url
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sink
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/filter"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+func TestFormattedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "export.log")
+	s, err := New("file://"+path+"?format=template&template={action}+{src}&filter=action+block", filter.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range []stream.LogEntry{{Action: "block", Src: "192.0.2.1"}, {Action: "pass", Src: "192.0.2.2"}} {
+		if err := s.Write(&entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "block 192.0.2.1\n" {
+		t.Errorf("expected single matching line, got %q", data)
+	}
+	if !strings.HasPrefix(s.String(), "file://") {
+		t.Errorf("expected url as description, got %q", s.String())
+	}
+}
+
+func TestFormattedTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	received := make(chan string)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			close(received)
+			return
+		}
+		defer conn.Close()
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		received <- line
+	}()
+	s, err := New("tcp://"+l.Addr().String(), filter.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.Write(&stream.LogEntry{Action: "block", Src: "192.0.2.1"}); err != nil {
+		t.Fatal(err)
+	}
+	if line := <-received; !strings.Contains(line, `"src":"192.0.2.1"`) {
+		t.Errorf("expected ndjson line, got %q", line)
+	}
+}
+
+func TestFormattedInvalid(t *testing.T) {
+	for _, u := range []string{
+		"file:///tmp/export.json?format=json",
+		"file:///tmp/export.log?format=yaml",
+		"file:///tmp/export.log?format=template",
+		"file:///tmp/export.log?compress=bzip2",
+		"udp://localhost:5170?compress=gzip",
+		"tcp://localhost",
+		"udp://",
+		"file://",
+	} {
+		if s, err := New(u, filter.Options{}); err == nil {
+			s.Close()
+			t.Errorf("%s: expected error, got nil", u)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sink
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/filter"
+	"gitlab.com/allddd/opnsense-filterlog/internal/output"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+// Sink publishes entries to an external system (Write may queue entries until the next Flush, which is called
+// periodically, Close flushes queued entries and releases resources)
+type Sink interface {
+	output.Sink
+	String() string // description for logs (without credentials)
+}
+
+// cooldownSources is the number of source addresses a throttled sink keeps before forgetting those whose cooldown is over
+const cooldownSources = 10000
+
+// throttled is a sink that drops entries of a source address within the cooldown after its last published entry
+type throttled struct {
+	Sink
+	cooldown time.Duration        // minimum time between entries of the same source address
+	last     map[string]time.Time // time of the last published entry per source address
+	limit    int                  // number of source addresses kept before forgetting expired ones
+}
+
+// Write publishes the entry unless its source address is cooling down
+func (t *throttled) Write(entry *stream.LogEntry) error {
+	if last, ok := t.last[entry.Src]; ok && entry.Time.Before(last.Add(t.cooldown)) {
+		return nil
+	}
+	t.last[entry.Src] = entry.Time
+	if len(t.last) > t.limit {
+		for src, last := range t.last {
+			if !entry.Time.Before(last.Add(t.cooldown)) {
+				delete(t.last, src)
+			}
+		}
+		t.limit = max(2*len(t.last), cooldownSources)
+	}
+	return t.Sink.Write(entry)
+}
+
+// filtered is a sink that only receives entries matching a filter
+type filtered struct {
+	Sink
+	compiled filter.FilterNode // compiled filter expression
+}
+
+// Write publishes the entry if it matches the filter
+func (f *filtered) Write(entry *stream.LogEntry) error {
+	if !f.compiled.Matches(entry) {
+		return nil
+	}
+	return f.Sink.Write(entry)
+}
+
+// newSink creates a sink from the given url (its filter compiled with opts), what it would send is described to dryRun
+// instead if not nil
+func newSink(rawURL string, opts filter.Options, dryRun io.Writer) (Sink, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("error(sink): invalid url: %w", err)
+	}
+	query := u.Query()
+	compiled, err := filter.CompileWith(query.Get("filter"), opts)
+	if err != nil {
+		return nil, err
+	}
+	dir, size := query.Get("spool"), spoolSize
+	if v := query.Get("spool_size"); v != "" {
+		if size, err = strconv.Atoi(v); err != nil || size < 1 {
+			return nil, fmt.Errorf("error(sink): invalid spool_size %q in %s", v, u.Redacted())
+		}
+		if dir == "" {
+			return nil, fmt.Errorf("error(sink): spool_size requires spool in %s", u.Redacted())
+		}
+	}
+	var cooldown time.Duration
+	if v := query.Get("cooldown"); v != "" {
+		if cooldown, err = time.ParseDuration(v); err != nil || cooldown <= 0 {
+			return nil, fmt.Errorf("error(sink): invalid cooldown %q in %s", v, u.Redacted())
+		}
+	}
+	for _, name := range []string{"cooldown", "filter", "spool", "spool_size"} {
+		query.Del(name)
+	}
+	u.RawQuery = query.Encode()
+
+	var s Sink
+	switch u.Scheme {
+	case schemeHTTP, schemeHTTPS, schemeDiscord, schemeSlack, schemeTelegram:
+		var w *webhook
+		if u.Scheme == schemeHTTP || u.Scheme == schemeHTTPS {
+			w, err = newWebhook(u)
+		} else {
+			w, err = newNotifier(u)
+		}
+		if err == nil && dryRun != nil {
+			w.client = &http.Client{Transport: dryRunTransport{name: w.String(), w: dryRun}}
+		}
+		// failed requests are spooled right away and retried by the spool
+		if err == nil && dir != "" {
+			w.retries = 0
+		}
+		s = w
+	case schemeMQTT, schemeMQTTS:
+		var m *mqtt
+		if m, err = newMQTT(u); err == nil {
+			m.dryRun = dryRun
+		}
+		s = m
+	case schemeFile, schemeTCP, schemeUDP:
+		s, err = newFormatted(u, dryRun)
+	default:
+		return nil, fmt.Errorf("error(sink): unknown scheme %q in %s", u.Scheme, u.Redacted())
+	}
+	if err != nil {
+		return nil, err
+	}
+	// a dry run never fails, so it has nothing to spool
+	if dir != "" && dryRun == nil {
+		sp, err := openSpool(dir, size)
+		if err != nil {
+			return nil, err
+		}
+		s = &spooled{Sink: s, backoff: spoolBackoff, spool: sp}
+	}
+	if cooldown > 0 {
+		// only matching entries start the cooldown
+		s = &throttled{Sink: s, cooldown: cooldown, last: make(map[string]time.Time), limit: cooldownSources}
+	}
+	if compiled != nil {
+		s = &filtered{Sink: s, compiled: compiled}
+	}
+	return s, nil
+}
+
+// public
+
+// New creates a sink from the given url (the scheme selects the sink, the filter query parameter restricts entries,
+// the cooldown query parameter limits entries per source address, the spool query parameter enables the disk spool,
+// file, tcp and udp urls write entries in the format query parameter, the filter is compiled with opts)
+func New(rawURL string, opts filter.Options) (Sink, error) {
+	return newSink(rawURL, opts, nil)
+}
+
+// NewDryRun creates a sink like New that writes what it would send to w instead of sending it (nothing is connected,
+// created or spooled)
+func NewDryRun(rawURL string, opts filter.Options, w io.Writer) (Sink, error) {
+	return newSink(rawURL, opts, w)
+}
+
+// Spooled returns true if err was returned by a sink that spooled the entries it couldn't publish (they are replayed
+// by the sink, so they aren't lost)
+func Spooled(err error) bool {
+	var spooled *spooledError
+	return errors.As(err, &spooled)
+}
+
+// Retrying returns true if err was returned by a sink that kept the entries it couldn't publish and publishes them again
+// on a later flush (they are neither delivered nor lost yet)
+func Retrying(err error) bool {
+	var retrying *retryingError
+	return errors.As(err, &retrying)
+}
+
+// Rejected returns true if err was returned by a sink whose endpoint rejected the entries for good (e.g. a webhook
+// responding with a client error), they are dropped since publishing them again would fail as well
+func Rejected(err error) bool {
+	var rejected *rejectedError
+	return errors.As(err, &rejected)
+}
+
+// SpoolDir returns the spool directory of the given url (empty if it has none or the url is invalid)
+func SpoolDir(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Query().Get("spool")
+}
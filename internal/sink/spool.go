@@ -0,0 +1,369 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sink
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/debuglog"
+	"gitlab.com/allddd/opnsense-filterlog/internal/sandbox"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+const (
+	spoolBackoff     = time.Second // delay before the first replay (doubled for every failed replay)
+	spoolBackoffMax  = time.Minute
+	spoolHeadFile    = "head"
+	spoolReplayBatch = 100
+	spoolSegmentExt  = ".jsonl"
+	spoolSegmentSize = 1000 // maximum number of entries per segment
+	spoolSize        = 100000
+)
+
+// failedError is returned by sinks if entries could not be published but may succeed later (e.g. the endpoint is unreachable)
+type failedError struct {
+	entries []stream.LogEntry // entries that were not published
+	err     error             // cause
+}
+
+func (e *failedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *failedError) Unwrap() error {
+	return e.err
+}
+
+// rejectedError is returned by sinks if the endpoint rejected entries for good (sending them again would be rejected
+// as well, so they are dropped instead of being retried or spooled)
+type rejectedError struct {
+	err error // cause
+}
+
+func (e *rejectedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *rejectedError) Unwrap() error {
+	return e.err
+}
+
+// retryingError is returned by sinks that keep entries they could not publish and publish them again on a later flush
+// (e.g. a webhook waiting for the backoff of a failed request)
+type retryingError struct {
+	err error // cause
+}
+
+func (e *retryingError) Error() string {
+	return e.err.Error()
+}
+
+func (e *retryingError) Unwrap() error {
+	return e.err
+}
+
+// spooledError is returned by spooled sinks if entries could not be published but were spooled (and are replayed later)
+type spooledError struct {
+	err    error // cause
+	queued int   // number of spooled entries
+}
+
+func (e *spooledError) Error() string {
+	return fmt.Sprintf("%v (%d entries spooled)", e.err, e.queued)
+}
+
+func (e *spooledError) Unwrap() error {
+	return e.err
+}
+
+// spool is a bounded on-disk queue of entries (stored as json lines in segment files)
+type spool struct {
+	counts      []int    // number of entries per segment
+	dir         string   // spool directory
+	head        int      // entries of the first segment that were already replayed
+	len         int      // number of queued entries
+	max         int      // maximum number of queued entries
+	segmentSize int      // maximum number of entries per segment
+	segments    []int    // sequence numbers of the segments (oldest first)
+	writer      *os.File // last segment (nil if not open)
+}
+
+// openSpool opens the spool in dir (entries queued by a previous run are kept)
+func openSpool(dir string, max int) (*spool, error) {
+	if err := sandbox.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("error(sink): could not create spool: %w", err)
+	}
+	s := &spool{
+		dir:         dir,
+		max:         max,
+		segmentSize: min(spoolSegmentSize, max),
+	}
+	files, err := sandbox.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error(sink): could not read spool: %w", err)
+	}
+	for _, file := range files {
+		seq, err := strconv.Atoi(strings.TrimSuffix(file.Name(), spoolSegmentExt))
+		if err != nil || !strings.HasSuffix(file.Name(), spoolSegmentExt) {
+			continue
+		}
+		s.segments = append(s.segments, seq)
+	}
+	slices.Sort(s.segments)
+	for _, seq := range s.segments {
+		count, err := s.countLines(seq)
+		if err != nil {
+			return nil, err
+		}
+		s.counts = append(s.counts, count)
+		s.len += count
+	}
+	if data, err := sandbox.ReadFile(filepath.Join(dir, spoolHeadFile)); err == nil && len(s.segments) > 0 {
+		if head, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil && head >= 0 && head <= s.counts[0] {
+			s.head = head
+			s.len -= head
+		}
+	}
+	return s, nil
+}
+
+// path returns the path of the segment
+func (s *spool) path(seq int) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%020d%s", seq, spoolSegmentExt))
+}
+
+// countLines returns the number of entries in the segment
+func (s *spool) countLines(seq int) (int, error) {
+	file, err := sandbox.Open(s.path(seq))
+	if err != nil {
+		return 0, fmt.Errorf("error(sink): could not read spool: %w", err)
+	}
+	defer file.Close()
+	count := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		count++
+	}
+	return count, scanner.Err()
+}
+
+// close closes the last segment
+func (s *spool) close() error {
+	if s.writer == nil {
+		return nil
+	}
+	err := s.writer.Close()
+	s.writer = nil
+	return err
+}
+
+// dropFirst removes the first segment
+func (s *spool) dropFirst() error {
+	if len(s.segments) == 1 {
+		s.close()
+	}
+	if err := sandbox.Remove(s.path(s.segments[0])); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("error(sink): could not remove spool segment: %w", err)
+	}
+	s.len -= s.counts[0] - s.head
+	s.segments, s.counts, s.head = s.segments[1:], s.counts[1:], 0
+	return s.writeHead()
+}
+
+// writeHead persists the number of replayed entries of the first segment
+func (s *spool) writeHead() error {
+	if err := sandbox.WriteFile(filepath.Join(s.dir, spoolHeadFile), []byte(strconv.Itoa(s.head)+"\n"), 0o600); err != nil {
+		return fmt.Errorf("error(sink): could not write spool: %w", err)
+	}
+	return nil
+}
+
+// push appends the entry (the oldest segment is dropped if the spool is full)
+func (s *spool) push(entry *stream.LogEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("error(sink): could not encode entry: %w", err)
+	}
+	last := len(s.segments) - 1
+	if last < 0 || s.counts[last] >= s.segmentSize {
+		s.close()
+		seq := 1
+		if last >= 0 {
+			seq = s.segments[last] + 1
+		}
+		s.segments = append(s.segments, seq)
+		s.counts = append(s.counts, 0)
+		last++
+	}
+	if s.writer == nil {
+		if s.writer, err = sandbox.OpenFile(s.path(s.segments[last]), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600); err != nil {
+			return fmt.Errorf("error(sink): could not write spool: %w", err)
+		}
+	}
+	if _, err := s.writer.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("error(sink): could not write spool: %w", err)
+	}
+	s.counts[last]++
+	s.len++
+	if s.len > s.max {
+		dropped := s.counts[0] - s.head
+		if err := s.dropFirst(); err != nil {
+			return err
+		}
+		return fmt.Errorf("error(sink): spool %s is full, dropped %d oldest entries", s.dir, dropped)
+	}
+	return nil
+}
+
+// peek returns up to n of the oldest entries and the number of lines they occupy (undecodable lines are skipped)
+func (s *spool) peek(n int) ([]stream.LogEntry, int, error) {
+	if s.len == 0 {
+		return nil, 0, nil
+	}
+	file, err := sandbox.Open(s.path(s.segments[0]))
+	if err != nil {
+		return nil, 0, fmt.Errorf("error(sink): could not read spool: %w", err)
+	}
+	defer file.Close()
+	var entries []stream.LogEntry
+	lines := 0
+	scanner := bufio.NewScanner(file)
+	for i := 0; scanner.Scan() && lines < n; i++ {
+		if i < s.head {
+			continue
+		}
+		lines++
+		var entry stream.LogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err == nil {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error(sink): could not read spool: %w", err)
+	}
+	return entries, lines, nil
+}
+
+// commit removes the first n entries (returned by peek)
+func (s *spool) commit(n int) error {
+	s.head += n
+	s.len -= n
+	if s.head >= s.counts[0] {
+		return s.dropFirst()
+	}
+	return s.writeHead()
+}
+
+// spooled is a sink that queues entries on disk while the wrapped sink is unreachable and replays them later
+type spooled struct {
+	Sink
+	backoff time.Duration // delay before the next replay
+	retryAt time.Time     // time of the next replay
+	spool   *spool        // queued entries
+}
+
+// keep spools the entries of a failed publish
+func (s *spooled) keep(err error) error {
+	var failed *failedError
+	if !errors.As(err, &failed) {
+		return err
+	}
+	for i := range failed.entries {
+		if err := s.spool.push(&failed.entries[i]); err != nil {
+			return err
+		}
+	}
+	s.retryAt = time.Now().Add(s.backoff)
+	debuglog.Warn("sink: spooled failed entries", "entries", len(failed.entries), "spooled", s.spool.len,
+		"backoff", s.backoff, "error", err)
+	return &spooledError{err: err, queued: s.spool.len}
+}
+
+// replay publishes spooled entries (oldest first) until the spool is empty or publishing fails
+func (s *spooled) replay() error {
+	for s.spool.len > 0 && !time.Now().Before(s.retryAt) {
+		entries, n, err := s.spool.peek(spoolReplayBatch)
+		if err != nil {
+			return err
+		}
+		var sendErr error
+		for i := range entries {
+			if sendErr = s.Sink.Write(&entries[i]); sendErr != nil {
+				break
+			}
+		}
+		if sendErr == nil {
+			sendErr = s.Sink.Flush()
+		}
+		var failed *failedError
+		if errors.As(sendErr, &failed) {
+			// entries stay spooled (entries published before the failure may be published twice)
+			s.backoff = min(s.backoff*2, spoolBackoffMax)
+			s.retryAt = time.Now().Add(s.backoff)
+			debuglog.Warn("sink: replay failed", "spooled", s.spool.len, "backoff", s.backoff, "error", sendErr)
+			return &spooledError{err: sendErr, queued: s.spool.len}
+		}
+		if err := s.spool.commit(n); err != nil {
+			return err
+		}
+		s.backoff = spoolBackoff
+		if sendErr != nil {
+			// entries rejected for good (rejectedError) are not retried
+			return sendErr
+		}
+	}
+	return nil
+}
+
+// Close flushes the wrapped sink (entries that are still spooled are replayed on the next start)
+func (s *spooled) Close() error {
+	err := s.keep(s.Sink.Close())
+	s.spool.close()
+	return err
+}
+
+// Flush flushes the wrapped sink and replays spooled entries
+func (s *spooled) Flush() error {
+	if err := s.keep(s.Sink.Flush()); err != nil {
+		return err
+	}
+	return s.replay()
+}
+
+// Write publishes the entry (spooled behind older entries while the spool is not empty)
+func (s *spooled) Write(entry *stream.LogEntry) error {
+	if s.spool.len > 0 {
+		return s.spool.push(entry)
+	}
+	return s.keep(s.Sink.Write(entry))
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sink
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+// spoolEntry returns an entry with the given destination port
+func spoolEntry(port uint16) *stream.LogEntry {
+	return &stream.LogEntry{Action: stream.ActionBlock, DstPort: port, Time: time.Date(2025, 10, 10, 10, 0, 0, 0, time.UTC)}
+}
+
+// ports returns the destination ports of the entries
+func ports(entries []stream.LogEntry) string {
+	var ports []string
+	for _, entry := range entries {
+		ports = append(ports, fmt.Sprint(entry.DstPort))
+	}
+	return strings.Join(ports, ",")
+}
+
+func TestSpool(t *testing.T) {
+	dir := t.TempDir()
+	s, err := openSpool(dir, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for port := uint16(1); port <= 3; port++ {
+		if err := s.push(spoolEntry(port)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// oldest segment is dropped once the spool is full
+	if err := s.push(spoolEntry(4)); err == nil || !strings.Contains(err.Error(), "dropped 3 oldest entries") {
+		t.Fatalf("expected spool full error, got %v", err)
+	}
+	for port := uint16(5); port <= 6; port++ {
+		if err := s.push(spoolEntry(port)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, n, err := s.peek(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ports(entries) != "4,5" || n != 2 {
+		t.Fatalf("expected entries 4,5, got %s", ports(entries))
+	}
+	if err := s.commit(1); err != nil {
+		t.Fatal(err)
+	}
+	s.close()
+
+	// replay position and queued entries survive a restart
+	s, err = openSpool(dir, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.close()
+	if s.len != 2 {
+		t.Fatalf("expected 2 queued entries, got %d", s.len)
+	}
+	entries, n, err = s.peek(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ports(entries) != "5,6" {
+		t.Fatalf("expected entries 5,6, got %s", ports(entries))
+	}
+	if err := s.commit(n); err != nil {
+		t.Fatal(err)
+	}
+	if s.len != 0 || len(s.segments) != 0 {
+		t.Fatalf("expected empty spool, got %d entries in %d segments", s.len, len(s.segments))
+	}
+}
+
+// flakySink records entries and fails while down
+type flakySink struct {
+	down    bool
+	entries []stream.LogEntry
+}
+
+func (s *flakySink) Write(entry *stream.LogEntry) error {
+	if s.down {
+		return &failedError{entries: []stream.LogEntry{*entry}, err: errors.New("unreachable")}
+	}
+	s.entries = append(s.entries, *entry)
+	return nil
+}
+
+func (s *flakySink) Flush() error   { return nil }
+func (s *flakySink) Close() error   { return nil }
+func (s *flakySink) String() string { return "flaky" }
+
+func TestSpooled(t *testing.T) {
+	sp, err := openSpool(t.TempDir(), spoolSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flaky := &flakySink{down: true}
+	s := &spooled{Sink: flaky, spool: sp}
+	defer s.Close()
+
+	if err := s.Write(spoolEntry(1)); !Spooled(err) || !strings.Contains(err.Error(), "1 entries spooled") {
+		t.Fatalf("expected spooled error, got %v", err)
+	}
+	// entries are queued behind spooled entries to keep the order
+	if err := s.Write(spoolEntry(2)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Flush(); !Spooled(err) {
+		t.Fatalf("expected replay to fail while sink is down, got %v", err)
+	}
+	if len(flaky.entries) != 0 || sp.len != 2 {
+		t.Fatalf("expected 2 spooled entries, got %d published and %d spooled", len(flaky.entries), sp.len)
+	}
+
+	flaky.down = false
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if ports(flaky.entries) != "1,2" || sp.len != 0 {
+		t.Fatalf("expected entries 1,2 to be replayed, got %s (%d spooled)", ports(flaky.entries), sp.len)
+	}
+	if err := s.Write(spoolEntry(3)); err != nil {
+		t.Fatal(err)
+	}
+	if ports(flaky.entries) != "1,2,3" {
+		t.Fatalf("expected entry 3 to be published directly, got %s", ports(flaky.entries))
+	}
+}
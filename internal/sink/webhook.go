@@ -0,0 +1,297 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sink
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/debuglog"
+	"gitlab.com/allddd/opnsense-filterlog/internal/meta"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+const (
+	// schemes
+	schemeHTTP  = "http"
+	schemeHTTPS = "https"
+
+	webhookBackoff    = time.Second // delay before the first retry (doubled for every further retry)
+	webhookBackoffMax = time.Minute
+	webhookRetries    = 3
+	webhookTimeout    = 10 * time.Second
+
+	// webhookSignatureHeader contains the hex encoded hmac-sha256 of the body (if a secret is set)
+	webhookSignatureHeader = "X-Signature-256"
+)
+
+// webhookTemplates are the built-in body templates
+var webhookTemplates = map[string]string{
+	"discord": `{"content":{{json .Text}}}`,
+	"slack":   `{"text":{{json .Text}}}`,
+}
+
+// webhookData is passed to body templates
+type webhookData struct {
+	Chat    string            // chat the message is sent to (telegram only)
+	Entries []stream.LogEntry // entries of the batch
+	Text    string            // entries of the batch as human-readable lines
+}
+
+// webhook posts entries as json to an http endpoint
+type webhook struct {
+	attempts int                           // failed posts of the first pending batch
+	backoff  time.Duration                 // delay before the first retry
+	batch    int                           // number of entries per request
+	chat     string                        // chat the message is sent to (telegram only)
+	client   *http.Client                  // http client
+	line     func(*stream.LogEntry) string // formats an entry as human-readable line
+	name     string                        // description for logs (redacted url if empty)
+	pending  []stream.LogEntry             // entries not yet posted
+	retries  int                           // number of retries of failed requests
+	retryAt  time.Time                     // time of the next post after a failed one (zero if none failed)
+	secret   []byte                        // hmac secret (nil if requests are not signed)
+	template *template.Template            // body template (nil for plain json)
+	url      string                        // endpoint url
+}
+
+// webhookLine formats an entry as a human-readable line
+func webhookLine(entry *stream.LogEntry) string {
+	addr := func(ip string, port uint16) string {
+		if port == 0 {
+			return ip
+		}
+		return net.JoinHostPort(ip, strconv.FormatUint(uint64(port), 10))
+	}
+	return fmt.Sprintf("%s %s %s %s %s %s -> %s", entry.Time.Format(time.DateTime), entry.Action, entry.Direction,
+		entry.Interface, entry.ProtoName, addr(entry.Src, entry.SrcPort), addr(entry.Dst, entry.DstPort))
+}
+
+// webhookTemplate parses a body template
+func webhookTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("webhook").Funcs(template.FuncMap{
+		"json": func(v any) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("error(sink): invalid template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// newWebhook creates a webhook sink (sink parameters are removed from the query)
+func newWebhook(u *url.URL) (*webhook, error) {
+	query := u.Query()
+	w := &webhook{
+		backoff: webhookBackoff,
+		batch:   1,
+		client:  &http.Client{Timeout: webhookTimeout},
+		line:    webhookLine,
+		retries: webhookRetries,
+	}
+	for _, param := range []struct {
+		name  string
+		value *int
+	}{
+		{"batch", &w.batch},
+		{"retries", &w.retries},
+	} {
+		if v := query.Get(param.name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 || (param.name == "batch" && n < 1) {
+				return nil, fmt.Errorf("error(sink): invalid %s %q in %s", param.name, v, u.Redacted())
+			}
+			*param.value = n
+		}
+	}
+	if secret := query.Get("secret"); secret != "" {
+		w.secret = []byte(secret)
+	}
+	text := ""
+	if name := query.Get("template"); name != "" {
+		var ok bool
+		if text, ok = webhookTemplates[name]; !ok {
+			return nil, fmt.Errorf("error(sink): unknown template %q (available: discord, slack)", name)
+		}
+	}
+	if path := query.Get("template_file"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error(sink): could not read template: %w", err)
+		}
+		text = string(data)
+	}
+	if text != "" {
+		tmpl, err := webhookTemplate(text)
+		if err != nil {
+			return nil, err
+		}
+		w.template = tmpl
+	}
+	for _, name := range []string{"batch", "retries", "secret", "template", "template_file"} {
+		query.Del(name)
+	}
+	u.RawQuery = query.Encode()
+	w.url = u.String()
+	return w, nil
+}
+
+// body encodes entries as request body
+func (w *webhook) body(entries []stream.LogEntry) ([]byte, error) {
+	if w.template != nil {
+		lines := make([]string, len(entries))
+		for i := range entries {
+			lines[i] = w.line(&entries[i])
+		}
+		var b bytes.Buffer
+		if err := w.template.Execute(&b, webhookData{Chat: w.chat, Entries: entries, Text: strings.Join(lines, "\n")}); err != nil {
+			return nil, fmt.Errorf("error(sink): could not execute template: %w", err)
+		}
+		return b.Bytes(), nil
+	}
+	if w.batch == 1 {
+		return json.Marshal(entries[0])
+	}
+	return json.Marshal(entries)
+}
+
+// post posts the body once and returns whether a failed request should be retried
+func (w *webhook) post(body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", meta.Name+"/"+meta.Version)
+	if w.secret != nil {
+		mac := hmac.New(sha256.New, w.secret)
+		mac.Write(body)
+		req.Header.Set(webhookSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("unexpected status %s", resp.Status)
+}
+
+// failed schedules the next post of the pending entries after a failed post (with exponential backoff), they are
+// returned in a failedError once all retries failed
+func (w *webhook) failed(err error) error {
+	if w.attempts >= w.retries {
+		entries := w.pending
+		w.attempts, w.pending, w.retryAt = 0, nil, time.Time{}
+		return &failedError{
+			entries: entries,
+			err:     fmt.Errorf("error(sink): could not post %d entries to %s: %w", len(entries), w, err),
+		}
+	}
+	backoff := min(w.backoff<<min(w.attempts, 16), webhookBackoffMax)
+	w.attempts++
+	w.retryAt = time.Now().Add(backoff)
+	debuglog.Warn("sink: retrying post", "sink", w.String(), "entries", len(w.pending), "attempt", w.attempts,
+		"backoff", backoff, "error", err)
+	return &retryingError{err: fmt.Errorf("error(sink): could not post %d entries to %s, retrying in %s: %w",
+		len(w.pending), w, backoff, err)}
+}
+
+// Close posts pending entries (once without waiting for the backoff, entries are returned in a failedError if it
+// fails)
+func (w *webhook) Close() error {
+	w.attempts, w.retryAt = w.retries, time.Time{}
+	return w.Flush()
+}
+
+// Flush posts pending entries in batches, a failed post is retried by a later Flush once its backoff is over (a
+// retryingError is returned until then, entries are returned in a failedError once all retries failed, entries
+// rejected with a client error other than 429 are dropped and a rejectedError is returned)
+func (w *webhook) Flush() error {
+	if len(w.pending) == 0 {
+		return nil
+	}
+	if time.Now().Before(w.retryAt) {
+		return &retryingError{err: fmt.Errorf("error(sink): %d entries for %s are posted again at %s", len(w.pending),
+			w, w.retryAt.Format(time.TimeOnly))}
+	}
+	for len(w.pending) > 0 {
+		entries := w.pending[:min(len(w.pending), w.batch)]
+		body, err := w.body(entries)
+		if err != nil {
+			w.pending = w.pending[len(entries):]
+			return err
+		}
+		retry, err := w.post(body)
+		if err != nil && retry {
+			return w.failed(err)
+		}
+		w.attempts, w.pending, w.retryAt = 0, w.pending[len(entries):], time.Time{}
+		if err != nil {
+			return &rejectedError{err: fmt.Errorf("error(sink): %s rejected %d entries: %w", w, len(entries), err)}
+		}
+	}
+	return nil
+}
+
+// Write queues the entry and posts the batch once it is full (entries are only queued while a failed post waits for
+// its retry)
+func (w *webhook) Write(entry *stream.LogEntry) error {
+	w.pending = append(w.pending, *entry)
+	if len(w.pending) < w.batch || !w.retryAt.IsZero() {
+		return nil
+	}
+	return w.Flush()
+}
+
+// String returns the url without password
+func (w *webhook) String() string {
+	if w.name != "" {
+		return w.name
+	}
+	u, err := url.Parse(w.url)
+	if err != nil {
+		return w.url
+	}
+	return u.Redacted()
+}
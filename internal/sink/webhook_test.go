@@ -0,0 +1,226 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sink
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/filter"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+// webhookRequest is a request received by the test server
+type webhookRequest struct {
+	body      string
+	query     string
+	signature string
+}
+
+// webhookServer responds with the given status codes (200 once exhausted) and records all requests
+func webhookServer(t *testing.T, statuses ...int) (*httptest.Server, *[]webhookRequest) {
+	var requests []webhookRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, webhookRequest{
+			body:      string(body),
+			query:     r.URL.RawQuery,
+			signature: r.Header.Get(webhookSignatureHeader),
+		})
+		if len(statuses) > 0 {
+			w.WriteHeader(statuses[0])
+			statuses = statuses[1:]
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+// newTestWebhook creates a webhook sink without backoff delay
+func newTestWebhook(t *testing.T, rawURL string) *webhook {
+	s, err := New(rawURL, filter.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := s.(*webhook)
+	w.backoff = time.Millisecond
+	return w
+}
+
+func TestWebhook(t *testing.T) {
+	entries := []stream.LogEntry{
+		{Action: stream.ActionBlock, Direction: "in", Interface: "eth0", ProtoName: "tcp", Src: "192.0.2.1", SrcPort: 51000, Dst: "198.51.100.1", DstPort: 22},
+		{Action: stream.ActionBlock, Direction: "in", Interface: "eth0", ProtoName: "icmp", Src: "2001:db8::1", Dst: "2001:db8::2"},
+	}
+
+	t.Run("single entries with signature", func(t *testing.T) {
+		server, requests := webhookServer(t)
+		w := newTestWebhook(t, server.URL+"/hook?token=abc&secret=key")
+		if strings.Contains(w.String(), "key") {
+			t.Fatalf("expected secret to be removed from %q", w.String())
+		}
+		for _, entry := range entries {
+			if err := w.Write(&entry); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		if len(*requests) != 2 {
+			t.Fatalf("expected 2 requests, got %d", len(*requests))
+		}
+		req := (*requests)[0]
+		if req.query != "token=abc" {
+			t.Fatalf("expected sink parameters to be removed from query, got %q", req.query)
+		}
+		var entry stream.LogEntry
+		if err := json.Unmarshal([]byte(req.body), &entry); err != nil || entry.Src != "192.0.2.1" {
+			t.Fatalf("unexpected body %q: %v", req.body, err)
+		}
+		mac := hmac.New(sha256.New, []byte("key"))
+		mac.Write([]byte(req.body))
+		if expect := "sha256=" + hex.EncodeToString(mac.Sum(nil)); req.signature != expect {
+			t.Fatalf("expected signature %q, got %q", expect, req.signature)
+		}
+	})
+
+	t.Run("batch with template", func(t *testing.T) {
+		server, requests := webhookServer(t)
+		w := newTestWebhook(t, server.URL+"?batch=10&template=slack")
+		for _, entry := range entries {
+			if err := w.Write(&entry); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		if len(*requests) != 0 {
+			t.Fatalf("expected entries to be queued, got %d requests", len(*requests))
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(*requests) != 1 {
+			t.Fatalf("expected 1 request, got %d", len(*requests))
+		}
+		var body struct{ Text string }
+		if err := json.Unmarshal([]byte((*requests)[0].body), &body); err != nil {
+			t.Fatalf("could not parse body: %v", err)
+		}
+		lines := strings.Split(body.Text, "\n")
+		if len(lines) != 2 || !strings.HasSuffix(lines[0], "tcp 192.0.2.1:51000 -> 198.51.100.1:22") || !strings.HasSuffix(lines[1], "icmp 2001:db8::1 -> 2001:db8::2") {
+			t.Fatalf("unexpected text %q", body.Text)
+		}
+	})
+
+	t.Run("retry", func(t *testing.T) {
+		server, requests := webhookServer(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+		w := newTestWebhook(t, server.URL)
+		if err := w.Write(&entries[0]); !Retrying(err) {
+			t.Fatalf("expected retrying error, got %v", err)
+		}
+		// entries are queued and not posted again until the backoff is over
+		if err := w.Write(&entries[1]); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		w.backoff = time.Hour
+		w.retryAt = time.Now().Add(time.Hour)
+		if err := w.Flush(); !Retrying(err) || len(*requests) != 1 {
+			t.Fatalf("expected retrying error without request, got %v and %d requests", err, len(*requests))
+		}
+		// the backoff is capped
+		w.retryAt = time.Time{}
+		if err := w.Flush(); !Retrying(err) || time.Until(w.retryAt) > webhookBackoffMax {
+			t.Fatalf("expected retry within %s, got %v at %s", webhookBackoffMax, err, w.retryAt)
+		}
+		w.retryAt = time.Time{}
+		if err := w.Flush(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(*requests) != 4 || len(w.pending) != 0 {
+			t.Fatalf("expected 4 requests and no pending entries, got %d and %d", len(*requests), len(w.pending))
+		}
+	})
+
+	t.Run("retries exhausted", func(t *testing.T) {
+		server, requests := webhookServer(t, 500, 500, 500)
+		w := newTestWebhook(t, server.URL+"?retries=2")
+		err := w.Write(&entries[0])
+		for Retrying(err) {
+			time.Sleep(time.Until(w.retryAt))
+			err = w.Flush()
+		}
+		var failed *failedError
+		if !errors.As(err, &failed) || len(failed.entries) != 1 {
+			t.Fatalf("expected failed error with 1 entry, got %v", err)
+		}
+		if len(*requests) != 3 {
+			t.Fatalf("expected 3 requests, got %d", len(*requests))
+		}
+	})
+
+	t.Run("close without backoff", func(t *testing.T) {
+		server, requests := webhookServer(t, 500, 500)
+		w := newTestWebhook(t, server.URL)
+		w.backoff = time.Hour
+		if err := w.Write(&entries[0]); !Retrying(err) {
+			t.Fatalf("expected retrying error, got %v", err)
+		}
+		var failed *failedError
+		if err := w.Close(); !errors.As(err, &failed) || len(failed.entries) != 1 {
+			t.Fatalf("expected failed error with 1 entry, got %v", err)
+		}
+		if len(*requests) != 2 {
+			t.Fatalf("expected 2 requests, got %d", len(*requests))
+		}
+	})
+
+	t.Run("spool retries", func(t *testing.T) {
+		server, _ := webhookServer(t)
+		s, err := New(server.URL+"?retries=5&spool="+t.TempDir(), filter.Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+		if w := s.(*spooled).Sink.(*webhook); w.retries != 0 {
+			t.Fatalf("expected failed requests to be spooled right away, got %d retries", w.retries)
+		}
+	})
+
+	t.Run("client error", func(t *testing.T) {
+		server, requests := webhookServer(t, http.StatusBadRequest)
+		w := newTestWebhook(t, server.URL)
+		if err := w.Write(&entries[0]); !Rejected(err) {
+			t.Fatalf("expected rejected error, got %v", err)
+		}
+		if len(*requests) != 1 {
+			t.Fatalf("expected 1 request, got %d", len(*requests))
+		}
+	})
+}
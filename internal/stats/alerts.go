@@ -0,0 +1,80 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stats
+
+import (
+	"slices"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+// AlertCount represents the number of recent entries of a single severity level
+type AlertCount struct {
+	Severity string `json:"severity"` // severity level
+	Entries  int    `json:"entries"`  // number of entries within the window
+}
+
+// Alerts counts entries of alerting severity levels (warning and above) within a sliding window,
+// it can be updated with new entries at any time
+type Alerts struct {
+	times  map[string][]time.Time // times of the entries per severity level
+	window time.Duration          // length of the window
+}
+
+// NewAlerts creates a new alert counter for the given window
+func NewAlerts(window time.Duration) *Alerts {
+	return &Alerts{
+		times:  make(map[string][]time.Time),
+		window: window,
+	}
+}
+
+// Add processes a single entry (entries below warning are ignored)
+func (a *Alerts) Add(entry *stream.LogEntry) {
+	if stream.SeverityLevel(entry.Severity) < stream.SeverityLevel(stream.SeverityWarning) {
+		return
+	}
+	a.times[entry.Severity] = append(a.times[entry.Severity], entry.Time)
+}
+
+// Counts drops entries older than the window before now and returns the remaining counts
+// (most severe level first, levels without entries are omitted)
+func (a *Alerts) Counts(now time.Time) []AlertCount {
+	start := now.Add(-a.window)
+	counts := make([]AlertCount, 0, len(a.times))
+	for severity, times := range a.times {
+		times = slices.DeleteFunc(times, func(t time.Time) bool { return t.Before(start) })
+		if len(times) == 0 {
+			delete(a.times, severity)
+			continue
+		}
+		a.times[severity] = times
+		counts = append(counts, AlertCount{Severity: severity, Entries: len(times)})
+	}
+	slices.SortFunc(counts, func(x, y AlertCount) int {
+		return stream.SeverityLevel(y.Severity) - stream.SeverityLevel(x.Severity)
+	})
+	return counts
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stats
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+func TestAlerts(t *testing.T) {
+	start := time.Date(2025, 10, 10, 0, 0, 0, 0, time.UTC)
+	entries := []stream.LogEntry{
+		{Severity: stream.SeverityCritical, Time: start},
+		{Severity: stream.SeverityWarning, Time: start.Add(time.Minute)},
+		{Severity: stream.SeverityNotice, Time: start.Add(time.Minute)},
+		{Severity: stream.SeverityCritical, Time: start.Add(2 * time.Minute)},
+		{Severity: stream.SeverityCritical, Time: start.Add(3 * time.Minute)},
+		{Time: start.Add(3 * time.Minute)},
+	}
+	a := NewAlerts(5 * time.Minute)
+	for i := range entries {
+		a.Add(&entries[i])
+	}
+	tests := []struct {
+		name string
+		now  time.Time
+		want []AlertCount
+	}{
+		{
+			name: "all within window",
+			now:  start.Add(4 * time.Minute),
+			want: []AlertCount{{Severity: stream.SeverityCritical, Entries: 3}, {Severity: stream.SeverityWarning, Entries: 1}},
+		},
+		{
+			name: "oldest dropped",
+			now:  start.Add(5*time.Minute + time.Second),
+			want: []AlertCount{{Severity: stream.SeverityCritical, Entries: 2}, {Severity: stream.SeverityWarning, Entries: 1}},
+		},
+		{
+			name: "level without entries omitted",
+			now:  start.Add(7*time.Minute + time.Second),
+			want: []AlertCount{{Severity: stream.SeverityCritical, Entries: 1}},
+		},
+		{
+			name: "empty",
+			now:  start.Add(time.Hour),
+			want: []AlertCount{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := a.Counts(tt.now); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
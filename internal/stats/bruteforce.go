@@ -0,0 +1,145 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stats
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+// bruteforceKey identifies a source attacking a destination/port
+type bruteforceKey struct {
+	src     string // source ip address
+	dst     string // destination ip address
+	dstPort uint16 // destination port
+}
+
+// bruteforceState tracks blocked attempts for a single key
+type bruteforceState struct {
+	attempts int         // total number of blocked attempts
+	first    time.Time   // first blocked attempt
+	last     time.Time   // last blocked attempt
+	peak     int         // highest number of attempts within the window
+	window   []time.Time // timestamps of attempts within the window
+}
+
+// Offender represents a source that exceeded the brute-force threshold
+type Offender struct {
+	Src      string    `json:"src"`             // source ip address
+	Dst      string    `json:"dst"`             // destination ip address
+	DstPort  uint16    `json:"dport,omitempty"` // destination port
+	Attempts int       `json:"attempts"`        // total number of blocked attempts
+	Peak     int       `json:"peak"`            // highest number of attempts within the window
+	First    time.Time `json:"first"`           // first blocked attempt
+	Last     time.Time `json:"last"`            // last blocked attempt
+}
+
+// Bruteforce finds sources with more than threshold blocked attempts against the same destination/port within window
+type Bruteforce struct {
+	states    map[bruteforceKey]*bruteforceState // state per source/destination/port
+	threshold int                                // attempts within window that must be exceeded
+	window    time.Duration                      // sliding window size
+}
+
+// NewBruteforce creates a new brute-force detector
+func NewBruteforce(threshold int, window time.Duration) *Bruteforce {
+	return &Bruteforce{
+		states:    make(map[bruteforceKey]*bruteforceState),
+		threshold: threshold,
+		window:    window,
+	}
+}
+
+// Add processes a single entry (entries are expected in chronological order)
+func (b *Bruteforce) Add(entry *stream.LogEntry) {
+	if entry.Action != stream.ActionBlock {
+		return
+	}
+	key := bruteforceKey{src: entry.Src, dst: entry.Dst, dstPort: entry.DstPort}
+	state, ok := b.states[key]
+	if !ok {
+		state = &bruteforceState{first: entry.Time}
+		b.states[key] = state
+	}
+	state.attempts++
+	state.last = entry.Time
+	// drop attempts that fell out of the window
+	start := entry.Time.Add(-b.window)
+	drop := 0
+	for drop < len(state.window) && state.window[drop].Before(start) {
+		drop++
+	}
+	state.window = append(state.window[drop:], entry.Time)
+	state.peak = max(state.peak, len(state.window))
+}
+
+// Offenders returns all sources that exceeded the threshold (sorted by peak and attempts)
+func (b *Bruteforce) Offenders() []Offender {
+	offenders := make([]Offender, 0)
+	for key, state := range b.states {
+		if state.peak <= b.threshold {
+			continue
+		}
+		offenders = append(offenders, Offender{
+			Src:      key.src,
+			Dst:      key.dst,
+			DstPort:  key.dstPort,
+			Attempts: state.attempts,
+			Peak:     state.peak,
+			First:    state.first,
+			Last:     state.last,
+		})
+	}
+	slices.SortFunc(offenders, func(a, b Offender) int {
+		return cmp.Or(
+			cmp.Compare(b.Peak, a.Peak),
+			cmp.Compare(b.Attempts, a.Attempts),
+			cmp.Compare(a.Src, b.Src),
+			cmp.Compare(a.Dst, b.Dst),
+			cmp.Compare(a.DstPort, b.DstPort),
+		)
+	})
+	return offenders
+}
+
+// WriteOffenders writes offenders as an aligned table
+func WriteOffenders(w io.Writer, offenders []Offender) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Source\tDestination\tDstPort\tAttempts\tPeak\tFirst\tLast")
+	for _, o := range offenders {
+		dstPort := ""
+		if o.DstPort > 0 {
+			dstPort = fmt.Sprintf("%d", o.DstPort)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%s\t%s\n", o.Src, o.Dst, dstPort, o.Attempts, o.Peak,
+			o.First.Format(time.DateTime), o.Last.Format(time.DateTime))
+	}
+	return tw.Flush()
+}
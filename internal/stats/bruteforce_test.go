@@ -0,0 +1,140 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stats
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+func TestBruteforce(t *testing.T) {
+	start := time.Date(2025, 10, 10, 0, 0, 0, 0, time.UTC)
+	attempt := func(src string, dstPort uint16, offset time.Duration, action string) stream.LogEntry {
+		return stream.LogEntry{Action: action, Src: src, Dst: "10.0.0.1", DstPort: dstPort, Time: start.Add(offset)}
+	}
+	tests := []struct {
+		name            string
+		entries         []stream.LogEntry
+		expectOffenders []Offender
+	}{
+		{
+			name: "exceeds threshold within window",
+			entries: []stream.LogEntry{
+				attempt("192.0.2.1", 22, 0, stream.ActionBlock),
+				attempt("192.0.2.1", 22, 10*time.Second, stream.ActionBlock),
+				attempt("192.0.2.1", 22, 20*time.Second, stream.ActionBlock),
+				attempt("192.0.2.1", 22, 30*time.Second, stream.ActionBlock),
+			},
+			expectOffenders: []Offender{
+				{Src: "192.0.2.1", Dst: "10.0.0.1", DstPort: 22, Attempts: 4, Peak: 4, First: start, Last: start.Add(30 * time.Second)},
+			},
+		},
+		{
+			name: "attempts spread over multiple windows",
+			entries: []stream.LogEntry{
+				attempt("192.0.2.1", 22, 0, stream.ActionBlock),
+				attempt("192.0.2.1", 22, 2*time.Minute, stream.ActionBlock),
+				attempt("192.0.2.1", 22, 4*time.Minute, stream.ActionBlock),
+				attempt("192.0.2.1", 22, 6*time.Minute, stream.ActionBlock),
+			},
+			expectOffenders: []Offender{},
+		},
+		{
+			name: "passed entries are ignored",
+			entries: []stream.LogEntry{
+				attempt("192.0.2.1", 22, 0, stream.ActionPass),
+				attempt("192.0.2.1", 22, 1*time.Second, stream.ActionPass),
+				attempt("192.0.2.1", 22, 2*time.Second, stream.ActionPass),
+				attempt("192.0.2.1", 22, 3*time.Second, stream.ActionBlock),
+			},
+			expectOffenders: []Offender{},
+		},
+		{
+			name: "different ports are counted separately",
+			entries: []stream.LogEntry{
+				attempt("192.0.2.1", 22, 0, stream.ActionBlock),
+				attempt("192.0.2.1", 23, 1*time.Second, stream.ActionBlock),
+				attempt("192.0.2.1", 24, 2*time.Second, stream.ActionBlock),
+				attempt("192.0.2.1", 25, 3*time.Second, stream.ActionBlock),
+			},
+			expectOffenders: []Offender{},
+		},
+		{
+			name: "sorted by peak",
+			entries: []stream.LogEntry{
+				attempt("192.0.2.1", 22, 0, stream.ActionBlock),
+				attempt("192.0.2.2", 3389, 0, stream.ActionBlock),
+				attempt("192.0.2.1", 22, 1*time.Second, stream.ActionBlock),
+				attempt("192.0.2.2", 3389, 1*time.Second, stream.ActionBlock),
+				attempt("192.0.2.1", 22, 2*time.Second, stream.ActionBlock),
+				attempt("192.0.2.2", 3389, 2*time.Second, stream.ActionBlock),
+				attempt("192.0.2.2", 3389, 3*time.Second, stream.ActionBlock),
+				attempt("192.0.2.2", 3389, 4*time.Second, stream.ActionBlock),
+			},
+			expectOffenders: []Offender{
+				{Src: "192.0.2.2", Dst: "10.0.0.1", DstPort: 3389, Attempts: 5, Peak: 5, First: start, Last: start.Add(4 * time.Second)},
+				{Src: "192.0.2.1", Dst: "10.0.0.1", DstPort: 22, Attempts: 3, Peak: 3, First: start, Last: start.Add(2 * time.Second)},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			b := NewBruteforce(2, time.Minute)
+			for _, entry := range tc.entries {
+				b.Add(&entry)
+			}
+			offenders := b.Offenders()
+			if len(offenders) != len(tc.expectOffenders) {
+				t.Fatalf("expected %d offenders, got %d: %+v", len(tc.expectOffenders), len(offenders), offenders)
+			}
+			for i, expect := range tc.expectOffenders {
+				if offenders[i] != expect {
+					t.Fatalf("offender %d: expected %+v, got %+v", i, expect, offenders[i])
+				}
+			}
+		})
+	}
+}
+
+func TestWriteOffenders(t *testing.T) {
+	var b strings.Builder
+	first := time.Date(2025, 10, 10, 0, 0, 0, 0, time.UTC)
+	offenders := []Offender{
+		{Src: "192.0.2.1", Dst: "10.0.0.1", DstPort: 22, Attempts: 4, Peak: 3, First: first, Last: first.Add(time.Minute)},
+	}
+	if err := WriteOffenders(&b, offenders); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+	if fields := strings.Fields(lines[1]); fields[0] != "192.0.2.1" || fields[2] != "22" || fields[3] != "4" {
+		t.Fatalf("unexpected row %q", lines[1])
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stats
+
+import (
+	"cmp"
+	"slices"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+// ValueCount represents the number of entries with a single value of a field
+type ValueCount struct {
+	Value   string  `json:"value"`   // value of the field
+	Entries int     `json:"entries"` // number of entries with the value
+	Share   float64 `json:"share"`   // share of all counted entries (0-1)
+}
+
+// Frequency counts entries per value of fields (named as by stream.LogEntry.Field)
+type Frequency struct {
+	fields []string         // names of the counted fields
+	counts []map[string]int // entries per value (indexed like fields)
+	total  int              // number of counted entries
+}
+
+// NewFrequency creates a new frequency count of the given fields
+func NewFrequency(fields ...string) *Frequency {
+	f := &Frequency{fields: fields, counts: make([]map[string]int, len(fields))}
+	for i := range fields {
+		f.counts[i] = make(map[string]int)
+	}
+	return f
+}
+
+// Add processes a single entry (fields without a value are not counted)
+func (f *Frequency) Add(entry *stream.LogEntry) {
+	f.total++
+	for i, name := range f.fields {
+		if value, ok := entry.Field(name); ok && value != "" {
+			f.counts[i][value]++
+		}
+	}
+}
+
+// Total returns the number of counted entries
+func (f *Frequency) Total() int {
+	return f.total
+}
+
+// Values returns the values of the field sorted by entries (none if the field isn't counted)
+func (f *Frequency) Values(field string) []ValueCount {
+	i := slices.Index(f.fields, field)
+	if i < 0 {
+		return nil
+	}
+	values := make([]ValueCount, 0, len(f.counts[i]))
+	for value, entries := range f.counts[i] {
+		values = append(values, ValueCount{Value: value, Entries: entries, Share: float64(entries) / float64(f.total)})
+	}
+	slices.SortFunc(values, func(a, b ValueCount) int {
+		return cmp.Or(cmp.Compare(b.Entries, a.Entries), cmp.Compare(a.Value, b.Value))
+	})
+	return values
+}
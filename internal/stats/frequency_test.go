@@ -0,0 +1,64 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stats
+
+import (
+	"testing"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+func TestFrequency(t *testing.T) {
+	entries := []stream.LogEntry{
+		{Src: "192.0.2.1", DstPort: 443, Reason: "match"},
+		{Src: "192.0.2.2", DstPort: 443, Reason: "match"},
+		{Src: "192.0.2.1", DstPort: 22, Reason: "match"},
+		{Src: "192.0.2.1", ProtoName: "icmp", Reason: "match"},
+	}
+	f := NewFrequency("src", "dport")
+	for _, entry := range entries {
+		f.Add(&entry)
+	}
+	if f.Total() != len(entries) {
+		t.Fatalf("expected %d entries, got %d", len(entries), f.Total())
+	}
+	expect := []ValueCount{
+		{Value: "192.0.2.1", Entries: 3, Share: 0.75},
+		{Value: "192.0.2.2", Entries: 1, Share: 0.25},
+	}
+	if got := f.Values("src"); len(got) != len(expect) || got[0] != expect[0] || got[1] != expect[1] {
+		t.Fatalf("expected %+v, got %+v", expect, got)
+	}
+	// entries without a port aren't counted, the share is of all entries
+	expect = []ValueCount{
+		{Value: "443", Entries: 2, Share: 0.5},
+		{Value: "22", Entries: 1, Share: 0.25},
+	}
+	if got := f.Values("dport"); len(got) != len(expect) || got[0] != expect[0] || got[1] != expect[1] {
+		t.Fatalf("expected %+v, got %+v", expect, got)
+	}
+	if got := f.Values("reason"); got != nil {
+		t.Fatalf("expected no values of a field that isn't counted, got %+v", got)
+	}
+}
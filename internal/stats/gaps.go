@@ -0,0 +1,125 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stats
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+// maxGaps is the number of largest gaps listed in the gap summary
+const maxGaps = 10
+
+// Gap represents the time between two consecutive entries
+type Gap struct {
+	Start    time.Time     `json:"start"`    // entry before the gap
+	End      time.Time     `json:"end"`      // entry after the gap
+	Duration time.Duration `json:"duration"` // time between the entries (nanoseconds)
+}
+
+// GapSummary represents the distribution of the time between consecutive entries
+type GapSummary struct {
+	Entries int           `json:"entries"` // number of entries
+	Min     time.Duration `json:"min"`     // shortest gap (nanoseconds)
+	Median  time.Duration `json:"median"`  // median gap (nanoseconds)
+	P95     time.Duration `json:"p95"`     // 95th percentile gap (nanoseconds)
+	Max     time.Duration `json:"max"`     // largest gap (nanoseconds)
+	Largest []Gap         `json:"largest"` // largest gaps (longest first)
+}
+
+// Gaps collects the times of entries to analyze the gaps between them
+type Gaps struct {
+	times []time.Time // time of every entry
+}
+
+// NewGaps creates a new gap analysis
+func NewGaps() *Gaps {
+	return &Gaps{}
+}
+
+// Add processes a single entry (entries without time are ignored)
+func (g *Gaps) Add(entry *stream.LogEntry) {
+	if entry.Time.IsZero() {
+		return
+	}
+	g.times = append(g.times, entry.Time)
+}
+
+// Summary returns the distribution of the gaps between the entries in chronological order (percentiles use the
+// nearest rank, all gaps are zero with fewer than two entries)
+func (g *Gaps) Summary() GapSummary {
+	summary := GapSummary{Entries: len(g.times)}
+	if len(g.times) < 2 {
+		return summary
+	}
+	times := slices.Clone(g.times)
+	slices.SortFunc(times, time.Time.Compare)
+	gaps := make([]Gap, len(times)-1)
+	durations := make([]time.Duration, len(gaps))
+	for i := range gaps {
+		gaps[i] = Gap{Start: times[i], End: times[i+1], Duration: times[i+1].Sub(times[i])}
+		durations[i] = gaps[i].Duration
+	}
+	slices.Sort(durations)
+	percentile := func(p float64) time.Duration {
+		return durations[max(int(math.Ceil(p*float64(len(durations))))-1, 0)]
+	}
+	summary.Min = durations[0]
+	summary.Median = percentile(0.5)
+	summary.P95 = percentile(0.95)
+	summary.Max = durations[len(durations)-1]
+	// gaps of the same duration stay in chronological order
+	slices.SortStableFunc(gaps, func(a, b Gap) int {
+		return cmp.Compare(b.Duration, a.Duration)
+	})
+	summary.Largest = gaps[:min(len(gaps), maxGaps)]
+	return summary
+}
+
+// WriteGaps writes the gap summary and the largest gaps as aligned tables
+func WriteGaps(w io.Writer, summary GapSummary) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Entries\tMin\tMedian\tP95\tMax")
+	fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", summary.Entries, summary.Min, summary.Median, summary.P95, summary.Max)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if len(summary.Largest) == 0 {
+		return nil
+	}
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Gap\tFrom\tTo")
+	for _, gap := range summary.Largest {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", gap.Duration, gap.Start.Format(time.DateTime), gap.End.Format(time.DateTime))
+	}
+	return tw.Flush()
+}
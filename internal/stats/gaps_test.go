@@ -0,0 +1,99 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stats
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+func TestGaps(t *testing.T) {
+	start := time.Date(2025, 10, 10, 0, 0, 0, 0, time.UTC)
+	// gaps of 1s (x17), 2s, 10s and 1h, added out of order
+	var entries []stream.LogEntry
+	at := start
+	for range 18 {
+		entries = append(entries, stream.LogEntry{Time: at})
+		at = at.Add(time.Second)
+	}
+	at = at.Add(time.Second)
+	entries = append(entries, stream.LogEntry{Time: at})
+	at = at.Add(10 * time.Second)
+	entries = append(entries, stream.LogEntry{Time: at})
+	entries = append(entries, stream.LogEntry{Time: at.Add(time.Hour)}, stream.LogEntry{})
+	entries[0], entries[len(entries)-2] = entries[len(entries)-2], entries[0]
+
+	g := NewGaps()
+	for _, entry := range entries {
+		g.Add(&entry)
+	}
+	summary := g.Summary()
+	if summary.Entries != 21 {
+		t.Errorf("expected 21 entries, got %d", summary.Entries)
+	}
+	if summary.Min != time.Second || summary.Median != time.Second || summary.P95 != 10*time.Second ||
+		summary.Max != time.Hour {
+		t.Errorf("expected 1s/1s/10s/1h, got %s/%s/%s/%s", summary.Min, summary.Median, summary.P95, summary.Max)
+	}
+	if len(summary.Largest) != maxGaps {
+		t.Fatalf("expected %d largest gaps, got %d", maxGaps, len(summary.Largest))
+	}
+	if gap := summary.Largest[0]; gap.Duration != time.Hour || !gap.Start.Equal(at) || !gap.End.Equal(at.Add(time.Hour)) {
+		t.Errorf("expected the 1h gap first, got %+v", gap)
+	}
+	// gaps of the same duration are listed chronologically
+	if gap := summary.Largest[3]; gap.Duration != time.Second || !gap.Start.Equal(start) {
+		t.Errorf("expected the first 1s gap, got %+v", gap)
+	}
+
+	// fewer than two entries have no gaps
+	g = NewGaps()
+	g.Add(&entries[1])
+	if summary := g.Summary(); summary.Entries != 1 || summary.Max != 0 || summary.Largest != nil {
+		t.Errorf("expected no gaps, got %+v", summary)
+	}
+}
+
+func TestWriteGaps(t *testing.T) {
+	var b strings.Builder
+	now := time.Date(2025, 10, 10, 0, 0, 0, 0, time.UTC)
+	summary := GapSummary{Entries: 3, Min: time.Second, Median: time.Second, P95: time.Minute, Max: time.Minute,
+		Largest: []Gap{{Start: now, End: now.Add(time.Minute), Duration: time.Minute}}}
+	if err := WriteGaps(&b, summary); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("expected 5 lines, got %d", len(lines))
+	}
+	if fields := strings.Fields(lines[1]); fields[0] != "3" || fields[3] != "1m0s" {
+		t.Fatalf("unexpected row %q", lines[1])
+	}
+	if fields := strings.Fields(lines[4]); fields[0] != "1m0s" || fields[2] != "00:00:00" {
+		t.Fatalf("unexpected gap %q", lines[4])
+	}
+}
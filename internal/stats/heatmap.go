@@ -0,0 +1,78 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stats
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+// HeatmapDay represents the number of entries per hour of a single day
+type HeatmapDay struct {
+	Date    string  `json:"date"`    // date (YYYY-MM-DD)
+	Entries [24]int `json:"entries"` // number of entries per hour
+	Blocked [24]int `json:"blocked"` // number of blocked entries per hour
+}
+
+// Heatmap counts entries per day and hour of day (in the time zone of the entry)
+type Heatmap struct {
+	days map[string]*HeatmapDay // counts per date
+}
+
+// NewHeatmap creates a new hour of day heatmap
+func NewHeatmap() *Heatmap {
+	return &Heatmap{
+		days: make(map[string]*HeatmapDay),
+	}
+}
+
+// Add processes a single entry
+func (h *Heatmap) Add(entry *stream.LogEntry) {
+	date := entry.Time.Format(time.DateOnly)
+	day, ok := h.days[date]
+	if !ok {
+		day = &HeatmapDay{Date: date}
+		h.days[date] = day
+	}
+	hour := entry.Time.Hour()
+	day.Entries[hour]++
+	if entry.Action == stream.ActionBlock {
+		day.Blocked[hour]++
+	}
+}
+
+// Days returns the counts of all days (sorted by date)
+func (h *Heatmap) Days() []HeatmapDay {
+	days := make([]HeatmapDay, 0, len(h.days))
+	for _, day := range h.days {
+		days = append(days, *day)
+	}
+	slices.SortFunc(days, func(a, b HeatmapDay) int {
+		return strings.Compare(a.Date, b.Date)
+	})
+	return days
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+func TestHeatmap(t *testing.T) {
+	zone := time.FixedZone("", 2*60*60)
+	entries := []stream.LogEntry{
+		{Action: stream.ActionBlock, Time: time.Date(2025, 10, 11, 22, 5, 0, 0, zone)},
+		{Action: stream.ActionPass, Time: time.Date(2025, 10, 10, 0, 0, 0, 0, zone)},
+		{Action: stream.ActionBlock, Time: time.Date(2025, 10, 10, 0, 59, 59, 0, zone)},
+		{Action: stream.ActionPass, Time: time.Date(2025, 10, 10, 23, 0, 0, 0, zone)},
+	}
+	h := NewHeatmap()
+	for _, entry := range entries {
+		h.Add(&entry)
+	}
+	days := h.Days()
+	if len(days) != 2 {
+		t.Fatalf("expected 2 days, got %d", len(days))
+	}
+	if days[0].Date != "2025-10-10" || days[1].Date != "2025-10-11" {
+		t.Fatalf("expected days sorted by date, got %s and %s", days[0].Date, days[1].Date)
+	}
+	if days[0].Entries[0] != 2 || days[0].Blocked[0] != 1 {
+		t.Fatalf("expected 2 entries and 1 blocked at 2025-10-10 00h, got %d and %d", days[0].Entries[0], days[0].Blocked[0])
+	}
+	if days[0].Entries[23] != 1 || days[0].Blocked[23] != 0 {
+		t.Fatalf("expected 1 entry and 0 blocked at 2025-10-10 23h, got %d and %d", days[0].Entries[23], days[0].Blocked[23])
+	}
+	if days[1].Entries[22] != 1 || days[1].Blocked[22] != 1 {
+		t.Fatalf("expected 1 entry and 1 blocked at 2025-10-11 22h, got %d and %d", days[1].Entries[22], days[1].Blocked[22])
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stats
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+// rateSeconds is the window (in seconds before the newest entry) used to compute the rate
+const rateSeconds = 60
+
+// interfaceState tracks entries for a single interface
+type interfaceState struct {
+	entries int                // number of entries
+	passed  int                // number of passed entries
+	blocked int                // number of blocked entries
+	counts  [rateSeconds]int   // entries per second of the rate window (ring buffer)
+	seconds [rateSeconds]int64 // unix time of each second in counts
+}
+
+// InterfaceSummary represents the entries seen on a single interface
+type InterfaceSummary struct {
+	Interface string `json:"interface"` // name of the interface
+	Entries   int    `json:"entries"`   // number of entries
+	Passed    int    `json:"passed"`    // number of passed entries
+	Blocked   int    `json:"blocked"`   // number of blocked entries
+	Rate      int    `json:"rate"`      // number of entries in the minute before the newest entry (of all interfaces)
+}
+
+// Interfaces groups entries by interface, it can be updated with new entries at any time
+type Interfaces struct {
+	states map[string]*interfaceState // state per interface
+	last   int64                      // unix time of the newest entry
+}
+
+// NewInterfaces creates a new interface summary
+func NewInterfaces() *Interfaces {
+	return &Interfaces{
+		states: make(map[string]*interfaceState),
+	}
+}
+
+// Add processes a single entry
+func (f *Interfaces) Add(entry *stream.LogEntry) {
+	state, ok := f.states[entry.Interface]
+	if !ok {
+		state = &interfaceState{}
+		f.states[entry.Interface] = state
+	}
+	state.entries++
+	switch entry.Action {
+	case stream.ActionPass:
+		state.passed++
+	case stream.ActionBlock:
+		state.blocked++
+	}
+	sec := entry.Time.Unix()
+	f.last = max(f.last, sec)
+	i := (sec%rateSeconds + rateSeconds) % rateSeconds
+	switch {
+	case state.seconds[i] == sec:
+		state.counts[i]++
+	case state.seconds[i] < sec:
+		// the slot belongs to an older second
+		state.seconds[i] = sec
+		state.counts[i] = 1
+	}
+}
+
+// Summaries returns the summary of all interfaces (sorted by entries)
+func (f *Interfaces) Summaries() []InterfaceSummary {
+	summaries := make([]InterfaceSummary, 0, len(f.states))
+	for name, state := range f.states {
+		rate := 0
+		for i, sec := range state.seconds {
+			if sec > f.last-rateSeconds && sec <= f.last {
+				rate += state.counts[i]
+			}
+		}
+		summaries = append(summaries, InterfaceSummary{
+			Interface: name,
+			Entries:   state.entries,
+			Passed:    state.passed,
+			Blocked:   state.blocked,
+			Rate:      rate,
+		})
+	}
+	slices.SortFunc(summaries, func(a, b InterfaceSummary) int {
+		return cmp.Or(
+			cmp.Compare(b.Entries, a.Entries),
+			cmp.Compare(a.Interface, b.Interface),
+		)
+	})
+	return summaries
+}
+
+// Last returns the time of the newest entry (zero if there are no entries)
+func (f *Interfaces) Last() time.Time {
+	if len(f.states) == 0 {
+		return time.Time{}
+	}
+	return time.Unix(f.last, 0)
+}
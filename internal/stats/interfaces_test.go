@@ -0,0 +1,76 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+func TestInterfaces(t *testing.T) {
+	start := time.Date(2025, 10, 10, 0, 0, 0, 0, time.UTC)
+	entries := []stream.LogEntry{
+		{Action: stream.ActionBlock, Interface: "igb0", Time: start},
+		{Action: stream.ActionBlock, Interface: "igb0", Time: start.Add(30 * time.Second)},
+		{Action: stream.ActionPass, Interface: "igb0", Time: start.Add(90 * time.Second)},
+		{Action: stream.ActionPass, Interface: "igb1", Time: start.Add(100 * time.Second)},
+		{Action: stream.ActionPass, Interface: "igb1", Time: start.Add(100 * time.Second)},
+		{Action: stream.ActionBlock, Interface: "igb0", Time: start.Add(110 * time.Second)},
+		// older than the rate window
+		{Action: stream.ActionPass, Interface: "igb1", Time: start.Add(40 * time.Second)},
+	}
+	f := NewInterfaces()
+	if !f.Last().IsZero() {
+		t.Fatalf("expected zero time without entries, got %v", f.Last())
+	}
+	for _, entry := range entries {
+		f.Add(&entry)
+	}
+	expect := []InterfaceSummary{
+		{Interface: "igb0", Entries: 4, Passed: 1, Blocked: 3, Rate: 2},
+		{Interface: "igb1", Entries: 3, Passed: 3, Blocked: 0, Rate: 2},
+	}
+	summaries := f.Summaries()
+	if len(summaries) != len(expect) {
+		t.Fatalf("expected %d summaries, got %d: %+v", len(expect), len(summaries), summaries)
+	}
+	for i := range expect {
+		if summaries[i] != expect[i] {
+			t.Fatalf("summary %d: expected %+v, got %+v", i, expect[i], summaries[i])
+		}
+	}
+	if last := start.Add(110 * time.Second); !f.Last().Equal(last) {
+		t.Fatalf("expected last %v, got %v", last, f.Last())
+	}
+
+	// entries added later move the rate window
+	f.Add(&stream.LogEntry{Action: stream.ActionPass, Interface: "igb1", Time: start.Add(165 * time.Second)})
+	for _, s := range f.Summaries() {
+		if expect := map[string]int{"igb0": 1, "igb1": 1}[s.Interface]; s.Rate != expect {
+			t.Fatalf("%s: expected rate %d, got %d", s.Interface, expect, s.Rate)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stats
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+const (
+	// NATWindow is the maximum time between a translation entry and the entry of the translated packet
+	NATWindow = 2 * time.Second
+
+	// translation status
+	NATTranslated = "translated" // the translated packet has different addresses or ports
+	NATUnchanged  = "unchanged"  // the translated packet has the original addresses and ports
+	NATUnmatched  = "unmatched"  // no entry of the translated packet was found
+)
+
+// natKey identifies a translation
+type natKey struct {
+	action     string // translation action (rdr, nat or binat)
+	rule       string // label of the translation rule (rule number if the rule has no label)
+	original   string // original address (and port)
+	translated string // translated address (and port, empty if unmatched)
+}
+
+// natState tracks entries for a single translation
+type natState struct {
+	entries int       // number of translation entries
+	first   time.Time // first entry
+	last    time.Time // last entry
+}
+
+// NATSummary represents the entries of a translation rule with the same original and translated address
+type NATSummary struct {
+	Action     string    `json:"action"`               // translation action (rdr, nat or binat)
+	Rule       string    `json:"rule"`                 // label of the translation rule (rule number if the rule has no label)
+	Original   string    `json:"original"`             // original address (and port for rdr)
+	Translated string    `json:"translated,omitempty"` // translated address (and port for rdr, empty if unmatched)
+	Status     string    `json:"status"`               // translated, unchanged or unmatched
+	Entries    int       `json:"entries"`              // number of translation entries
+	First      time.Time `json:"first"`                // first entry
+	Last       time.Time `json:"last"`                 // last entry
+}
+
+// NAT pairs translation entries with the entries of the translated packets and groups them by translation
+type NAT struct {
+	pending []stream.LogEntry    // translation entries without matching entry (within NATWindow)
+	recent  []stream.LogEntry    // other entries that haven't been matched (within NATWindow)
+	states  map[natKey]*natState // state per translation
+}
+
+// NewNAT creates a new translation summary
+func NewNAT() *NAT {
+	return &NAT{
+		states: make(map[natKey]*natState),
+	}
+}
+
+// natAddresses returns the original and translated address of a translation (destination and port for rdr,
+// source for nat, the side that doesn't match for binat, translated is empty if entry is nil)
+func natAddresses(translation, entry *stream.LogEntry) (string, string) {
+	switch {
+	case translation.Action == stream.ActionRdr:
+		if entry == nil {
+			return Endpoint(translation.Dst, translation.DstPort), ""
+		}
+		return Endpoint(translation.Dst, translation.DstPort), Endpoint(entry.Dst, entry.DstPort)
+	case translation.Action == stream.ActionNat, entry != nil && entry.Src != translation.Src:
+		if entry == nil {
+			return translation.Src, ""
+		}
+		return translation.Src, entry.Src
+	case entry == nil:
+		return translation.Dst, ""
+	}
+	return translation.Dst, entry.Dst
+}
+
+// record adds a translation entry and the entry of the translated packet (nil if unmatched)
+func (n *NAT) record(translation, entry *stream.LogEntry) {
+	key := natKey{
+		action: translation.Action,
+		rule:   cmp.Or(translation.Label, translation.RuleNum),
+	}
+	key.original, key.translated = natAddresses(translation, entry)
+	state, ok := n.states[key]
+	if !ok {
+		state = &natState{first: translation.Time, last: translation.Time}
+		n.states[key] = state
+	}
+	state.entries++
+	if translation.Time.Before(state.first) {
+		state.first = translation.Time
+	}
+	if translation.Time.After(state.last) {
+		state.last = translation.Time
+	}
+}
+
+// prune drops entries that are older than NATWindow before t (translations are recorded as unmatched)
+func (n *NAT) prune(t time.Time) {
+	start := t.Add(-NATWindow)
+	n.pending = slices.DeleteFunc(n.pending, func(e stream.LogEntry) bool {
+		if e.Time.Before(start) {
+			n.record(&e, nil)
+			return true
+		}
+		return false
+	})
+	n.recent = slices.DeleteFunc(n.recent, func(e stream.LogEntry) bool {
+		return e.Time.Before(start)
+	})
+}
+
+// public
+
+// Endpoint formats an address and port (the address only if there is no port)
+func Endpoint(addr string, port uint16) string {
+	if port == 0 {
+		return addr
+	}
+	return net.JoinHostPort(addr, strconv.Itoa(int(port)))
+}
+
+// NATRelated returns true if entry could be the translated packet of the translation entry (same protocol,
+// the side that is not translated matches and it was logged within NATWindow)
+func NATRelated(translation, entry *stream.LogEntry) bool {
+	if !translation.Translation() || entry.Translation() || entry.ProtoName != translation.ProtoName {
+		return false
+	}
+	if d := entry.Time.Sub(translation.Time); d > NATWindow || d < -NATWindow {
+		return false
+	}
+	sameSrc := entry.Src == translation.Src && entry.SrcPort == translation.SrcPort
+	sameDst := entry.Dst == translation.Dst && entry.DstPort == translation.DstPort
+	switch translation.Action {
+	case stream.ActionRdr:
+		return sameSrc
+	case stream.ActionNat:
+		return sameDst
+	}
+	return sameSrc || sameDst
+}
+
+// Add processes a single entry (entries are expected in chronological order)
+func (n *NAT) Add(entry *stream.LogEntry) {
+	n.prune(entry.Time)
+	if entry.Translation() {
+		for i := len(n.recent) - 1; i >= 0; i-- {
+			if NATRelated(entry, &n.recent[i]) {
+				n.record(entry, &n.recent[i])
+				n.recent = slices.Delete(n.recent, i, i+1)
+				return
+			}
+		}
+		n.pending = append(n.pending, *entry)
+		return
+	}
+	for i := range n.pending {
+		if NATRelated(&n.pending[i], entry) {
+			n.record(&n.pending[i], entry)
+			n.pending = slices.Delete(n.pending, i, i+1)
+			return
+		}
+	}
+	n.recent = append(n.recent, *entry)
+}
+
+// Summaries returns the summary of all translations (sorted by rule, original address and entries)
+func (n *NAT) Summaries() []NATSummary {
+	for i := range n.pending {
+		n.record(&n.pending[i], nil)
+	}
+	n.pending = n.pending[:0]
+	summaries := make([]NATSummary, 0, len(n.states))
+	for key, state := range n.states {
+		status := NATTranslated
+		switch key.translated {
+		case "":
+			status = NATUnmatched
+		case key.original:
+			status = NATUnchanged
+		}
+		summaries = append(summaries, NATSummary{
+			Action:     key.action,
+			Rule:       key.rule,
+			Original:   key.original,
+			Translated: key.translated,
+			Status:     status,
+			Entries:    state.entries,
+			First:      state.first,
+			Last:       state.last,
+		})
+	}
+	slices.SortFunc(summaries, func(a, b NATSummary) int {
+		return cmp.Or(
+			cmp.Compare(a.Rule, b.Rule),
+			cmp.Compare(a.Original, b.Original),
+			cmp.Compare(b.Entries, a.Entries),
+			cmp.Compare(a.Translated, b.Translated),
+		)
+	})
+	return summaries
+}
+
+// WriteNAT writes translation summaries as an aligned table
+func WriteNAT(w io.Writer, summaries []NATSummary) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Action\tRule\tOriginal\tTranslated\tStatus\tEntries\tFirst\tLast")
+	for _, s := range summaries {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s\n", s.Action, s.Rule, s.Original, cmp.Or(s.Translated, "-"), s.Status,
+			s.Entries, s.First.Format(time.DateTime), s.Last.Format(time.DateTime))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stats
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+func TestNAT(t *testing.T) {
+	start := time.Date(2025, 10, 10, 0, 0, 0, 0, time.UTC)
+	rdr := stream.LogEntry{Action: stream.ActionRdr, Label: "web", ProtoName: "tcp", Src: "198.51.100.7", SrcPort: 40000, Dst: "203.0.113.1", DstPort: 8443, Time: start}
+	pass := stream.LogEntry{Action: stream.ActionPass, ProtoName: "tcp", Src: "198.51.100.7", SrcPort: 40000, Dst: "192.168.1.10", DstPort: 443, Time: start}
+	entries := []stream.LogEntry{
+		rdr,
+		// different protocol
+		{Action: stream.ActionPass, ProtoName: "udp", Src: "198.51.100.7", SrcPort: 40000, Dst: "192.168.1.11", DstPort: 443, Time: start},
+		pass,
+		// translated packet logged before the translation
+		func() stream.LogEntry { e := pass; e.SrcPort = 40001; e.Time = start.Add(time.Second); return e }(),
+		func() stream.LogEntry { e := rdr; e.SrcPort = 40001; e.Time = start.Add(time.Second); return e }(),
+		// not translated
+		func() stream.LogEntry { e := rdr; e.SrcPort = 40002; e.Time = start.Add(2 * time.Second); return e }(),
+		{Action: stream.ActionBlock, ProtoName: "tcp", Src: "198.51.100.7", SrcPort: 40002, Dst: "203.0.113.1", DstPort: 8443, Time: start.Add(2 * time.Second)},
+		// no translated packet
+		func() stream.LogEntry { e := rdr; e.SrcPort = 40003; e.Time = start.Add(3 * time.Second); return e }(),
+		// source nat
+		{Action: stream.ActionNat, RuleNum: "7", ProtoName: "udp", Src: "192.168.1.10", SrcPort: 5000, Dst: "192.0.2.53", DstPort: 53, Time: start.Add(10 * time.Second)},
+		{Action: stream.ActionPass, ProtoName: "udp", Src: "203.0.113.1", SrcPort: 61000, Dst: "192.0.2.53", DstPort: 53, Time: start.Add(11 * time.Second)},
+	}
+	n := NewNAT()
+	for _, entry := range entries {
+		n.Add(&entry)
+	}
+	expect := []NATSummary{
+		{Action: "nat", Rule: "7", Original: "192.168.1.10", Translated: "203.0.113.1", Status: NATTranslated, Entries: 1, First: start.Add(10 * time.Second), Last: start.Add(10 * time.Second)},
+		{Action: "rdr", Rule: "web", Original: "203.0.113.1:8443", Translated: "192.168.1.10:443", Status: NATTranslated, Entries: 2, First: start, Last: start.Add(time.Second)},
+		{Action: "rdr", Rule: "web", Original: "203.0.113.1:8443", Status: NATUnmatched, Entries: 1, First: start.Add(3 * time.Second), Last: start.Add(3 * time.Second)},
+		{Action: "rdr", Rule: "web", Original: "203.0.113.1:8443", Translated: "203.0.113.1:8443", Status: NATUnchanged, Entries: 1, First: start.Add(2 * time.Second), Last: start.Add(2 * time.Second)},
+	}
+	summaries := n.Summaries()
+	if len(summaries) != len(expect) {
+		t.Fatalf("expected %d summaries, got %d: %+v", len(expect), len(summaries), summaries)
+	}
+	for i := range expect {
+		if summaries[i] != expect[i] {
+			t.Fatalf("summary %d: expected %+v, got %+v", i, expect[i], summaries[i])
+		}
+	}
+}
+
+func TestNATRelated(t *testing.T) {
+	start := time.Date(2025, 10, 10, 0, 0, 0, 0, time.UTC)
+	binat := stream.LogEntry{Action: stream.ActionBinat, ProtoName: "tcp", Src: "198.51.100.7", SrcPort: 40000, Dst: "203.0.113.5", DstPort: 22, Time: start}
+	tests := []struct {
+		name   string
+		entry  stream.LogEntry
+		expect bool
+	}{
+		{name: "inbound", entry: stream.LogEntry{Action: stream.ActionPass, ProtoName: "tcp", Src: "198.51.100.7", SrcPort: 40000, Dst: "192.168.1.5", DstPort: 22, Time: start}, expect: true},
+		{name: "outbound", entry: stream.LogEntry{Action: stream.ActionPass, ProtoName: "tcp", Src: "192.168.1.5", SrcPort: 51000, Dst: "203.0.113.5", DstPort: 22, Time: start.Add(-time.Second)}, expect: true},
+		{name: "too late", entry: stream.LogEntry{Action: stream.ActionPass, ProtoName: "tcp", Src: "198.51.100.7", SrcPort: 40000, Dst: "192.168.1.5", DstPort: 22, Time: start.Add(3 * time.Second)}, expect: false},
+		{name: "other source port", entry: stream.LogEntry{Action: stream.ActionPass, ProtoName: "tcp", Src: "198.51.100.7", SrcPort: 40001, Dst: "192.168.1.5", DstPort: 22, Time: start}, expect: false},
+		{name: "translation", entry: binat, expect: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := NATRelated(&binat, &tc.entry); got != tc.expect {
+				t.Fatalf("expected %v, got %v", tc.expect, got)
+			}
+		})
+	}
+	if NATRelated(&tests[0].entry, &binat) {
+		t.Fatal("expected no relation if the first entry is not a translation")
+	}
+}
+
+func TestWriteNAT(t *testing.T) {
+	var b strings.Builder
+	now := time.Date(2025, 10, 10, 0, 0, 0, 0, time.UTC)
+	if err := WriteNAT(&b, []NATSummary{{Action: "rdr", Rule: "web", Original: "203.0.113.1:8443", Status: NATUnmatched, Entries: 2, First: now, Last: now}}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+	if fields := strings.Fields(lines[1]); fields[0] != "rdr" || fields[3] != "-" || fields[4] != NATUnmatched {
+		t.Fatalf("unexpected row %q", lines[1])
+	}
+}
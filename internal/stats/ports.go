@@ -0,0 +1,127 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stats
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+// portState tracks entries for a single destination port
+type portState struct {
+	entries int                 // number of entries
+	blocked int                 // number of blocked entries
+	first   time.Time           // first entry
+	last    time.Time           // last entry
+	sources map[string]struct{} // distinct source ip addresses
+}
+
+// PortSummary represents the distinct sources seen for a destination port
+type PortSummary struct {
+	DstPort uint16    `json:"dport"`   // destination port
+	Sources int       `json:"sources"` // number of distinct source ip addresses
+	Entries int       `json:"entries"` // number of entries
+	Blocked int       `json:"blocked"` // number of blocked entries
+	First   time.Time `json:"first"`   // first entry
+	Last    time.Time `json:"last"`    // last entry
+}
+
+// Ports groups entries by destination port and counts distinct sources
+type Ports struct {
+	states map[uint16]*portState // state per destination port
+}
+
+// NewPorts creates a new destination port summary
+func NewPorts() *Ports {
+	return &Ports{
+		states: make(map[uint16]*portState),
+	}
+}
+
+// Add processes a single entry (entries without destination port are ignored)
+func (p *Ports) Add(entry *stream.LogEntry) {
+	if entry.DstPort == 0 {
+		return
+	}
+	state, ok := p.states[entry.DstPort]
+	if !ok {
+		state = &portState{
+			first:   entry.Time,
+			last:    entry.Time,
+			sources: make(map[string]struct{}),
+		}
+		p.states[entry.DstPort] = state
+	}
+	state.entries++
+	if entry.Action == stream.ActionBlock {
+		state.blocked++
+	}
+	if entry.Time.Before(state.first) {
+		state.first = entry.Time
+	}
+	if entry.Time.After(state.last) {
+		state.last = entry.Time
+	}
+	state.sources[entry.Src] = struct{}{}
+}
+
+// Summaries returns the summary of all destination ports (sorted by distinct sources and entries)
+func (p *Ports) Summaries() []PortSummary {
+	summaries := make([]PortSummary, 0, len(p.states))
+	for port, state := range p.states {
+		summaries = append(summaries, PortSummary{
+			DstPort: port,
+			Sources: len(state.sources),
+			Entries: state.entries,
+			Blocked: state.blocked,
+			First:   state.first,
+			Last:    state.last,
+		})
+	}
+	slices.SortFunc(summaries, func(a, b PortSummary) int {
+		return cmp.Or(
+			cmp.Compare(b.Sources, a.Sources),
+			cmp.Compare(b.Entries, a.Entries),
+			cmp.Compare(a.DstPort, b.DstPort),
+		)
+	})
+	return summaries
+}
+
+// WritePorts writes port summaries as an aligned table
+func WritePorts(w io.Writer, summaries []PortSummary) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DstPort\tSources\tEntries\tBlocked\tFirst\tLast")
+	for _, s := range summaries {
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%d\t%s\t%s\n", s.DstPort, s.Sources, s.Entries, s.Blocked,
+			s.First.Format(time.DateTime), s.Last.Format(time.DateTime))
+	}
+	return tw.Flush()
+}
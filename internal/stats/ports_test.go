@@ -0,0 +1,77 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stats
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+func TestPorts(t *testing.T) {
+	start := time.Date(2025, 10, 10, 0, 0, 0, 0, time.UTC)
+	entries := []stream.LogEntry{
+		{Action: stream.ActionBlock, Src: "192.0.2.1", DstPort: 3389, Time: start.Add(time.Minute)},
+		{Action: stream.ActionBlock, Src: "192.0.2.2", DstPort: 3389, Time: start},
+		{Action: stream.ActionBlock, Src: "192.0.2.1", DstPort: 3389, Time: start.Add(2 * time.Minute)},
+		{Action: stream.ActionPass, Src: "192.0.2.1", DstPort: 443, Time: start},
+		{Action: stream.ActionPass, Src: "192.0.2.1", DstPort: 443, Time: start},
+		{Action: stream.ActionPass, Src: "192.0.2.1", DstPort: 443, Time: start},
+		{Action: stream.ActionBlock, Src: "192.0.2.3", ProtoName: "icmp", Time: start},
+	}
+	p := NewPorts()
+	for _, entry := range entries {
+		p.Add(&entry)
+	}
+	expect := []PortSummary{
+		{DstPort: 3389, Sources: 2, Entries: 3, Blocked: 3, First: start, Last: start.Add(2 * time.Minute)},
+		{DstPort: 443, Sources: 1, Entries: 3, Blocked: 0, First: start, Last: start},
+	}
+	summaries := p.Summaries()
+	if len(summaries) != len(expect) {
+		t.Fatalf("expected %d summaries, got %d: %+v", len(expect), len(summaries), summaries)
+	}
+	for i := range expect {
+		if summaries[i] != expect[i] {
+			t.Fatalf("summary %d: expected %+v, got %+v", i, expect[i], summaries[i])
+		}
+	}
+}
+
+func TestWritePorts(t *testing.T) {
+	var b strings.Builder
+	now := time.Date(2025, 10, 10, 0, 0, 0, 0, time.UTC)
+	if err := WritePorts(&b, []PortSummary{{DstPort: 22, Sources: 3, Entries: 5, Blocked: 4, First: now, Last: now}}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+	if fields := strings.Fields(lines[1]); fields[0] != "22" || fields[1] != "3" || fields[3] != "4" {
+		t.Fatalf("unexpected row %q", lines[1])
+	}
+}
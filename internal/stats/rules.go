@@ -0,0 +1,132 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stats
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+// ruleState tracks entries for a single rule
+type ruleState struct {
+	ruleNum string    // number of the rule (last seen)
+	entries int       // number of entries
+	passed  int       // number of passed entries
+	blocked int       // number of blocked entries
+	first   time.Time // first entry
+	last    time.Time // last entry
+}
+
+// RuleSummary represents the usage of a single firewall rule
+type RuleSummary struct {
+	Label   string    `json:"label"`   // label of the rule (rule number if the rule has no label)
+	RuleNum string    `json:"rulenr"`  // number of the rule (last seen, changes when the ruleset is reloaded)
+	Entries int       `json:"entries"` // number of entries
+	Passed  int       `json:"passed"`  // number of passed entries
+	Blocked int       `json:"blocked"` // number of blocked entries
+	First   time.Time `json:"first"`   // first entry
+	Last    time.Time `json:"last"`    // last entry
+}
+
+// Rules groups entries by the rule that produced them
+type Rules struct {
+	states map[string]*ruleState // state per rule label
+}
+
+// NewRules creates a new rule usage summary
+func NewRules() *Rules {
+	return &Rules{
+		states: make(map[string]*ruleState),
+	}
+}
+
+// Add processes a single entry (entries without rule label and number are ignored)
+func (r *Rules) Add(entry *stream.LogEntry) {
+	label := cmp.Or(entry.Label, entry.RuleNum)
+	if label == "" {
+		return
+	}
+	state, ok := r.states[label]
+	if !ok {
+		state = &ruleState{
+			first: entry.Time,
+			last:  entry.Time,
+		}
+		r.states[label] = state
+	}
+	state.entries++
+	switch entry.Action {
+	case stream.ActionPass:
+		state.passed++
+	case stream.ActionBlock:
+		state.blocked++
+	}
+	if entry.Time.Before(state.first) {
+		state.first = entry.Time
+	}
+	if !entry.Time.Before(state.last) {
+		state.last = entry.Time
+		state.ruleNum = entry.RuleNum
+	}
+}
+
+// Summaries returns the summary of all rules (sorted by entries)
+func (r *Rules) Summaries() []RuleSummary {
+	summaries := make([]RuleSummary, 0, len(r.states))
+	for label, state := range r.states {
+		summaries = append(summaries, RuleSummary{
+			Label:   label,
+			RuleNum: state.ruleNum,
+			Entries: state.entries,
+			Passed:  state.passed,
+			Blocked: state.blocked,
+			First:   state.first,
+			Last:    state.last,
+		})
+	}
+	slices.SortFunc(summaries, func(a, b RuleSummary) int {
+		return cmp.Or(
+			cmp.Compare(b.Entries, a.Entries),
+			cmp.Compare(a.Label, b.Label),
+		)
+	})
+	return summaries
+}
+
+// WriteRules writes rule summaries as an aligned table
+func WriteRules(w io.Writer, summaries []RuleSummary) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Label\tRule\tEntries\tPassed\tBlocked\tFirst\tLast")
+	for _, s := range summaries {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%s\t%s\n", s.Label, cmp.Or(s.RuleNum, "-"), s.Entries, s.Passed, s.Blocked,
+			s.First.Format(time.DateTime), s.Last.Format(time.DateTime))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stats
+
+import (
+	"cmp"
+	"slices"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+// Talker represents the number of entries of a single ip address
+type Talker struct {
+	Addr    string `json:"addr"`    // ip address
+	Entries int    `json:"entries"` // number of entries
+	Blocked int    `json:"blocked"` // number of blocked entries
+	Bytes   uint64 `json:"bytes"`   // total length of the packets (of entries with logged length)
+}
+
+// Talkers counts entries per source and destination ip address
+type Talkers struct {
+	sources      map[string]*Talker // counts per source ip address
+	destinations map[string]*Talker // counts per destination ip address
+}
+
+// NewTalkers creates a new top talkers summary
+func NewTalkers() *Talkers {
+	return &Talkers{
+		sources:      make(map[string]*Talker),
+		destinations: make(map[string]*Talker),
+	}
+}
+
+// Add processes a single entry
+func (t *Talkers) Add(entry *stream.LogEntry) {
+	blocked := entry.Action == stream.ActionBlock
+	for _, c := range []struct {
+		talkers map[string]*Talker
+		addr    string
+	}{
+		{t.sources, entry.Src},
+		{t.destinations, entry.Dst},
+	} {
+		if c.addr == "" {
+			continue
+		}
+		talker, ok := c.talkers[c.addr]
+		if !ok {
+			talker = &Talker{Addr: c.addr}
+			c.talkers[c.addr] = talker
+		}
+		talker.Entries++
+		talker.Bytes += uint64(entry.Length)
+		if blocked {
+			talker.Blocked++
+		}
+	}
+}
+
+// Sources returns the top n source ip addresses (all if n <= 0)
+func (t *Talkers) Sources(n int) []Talker {
+	return topTalkers(t.sources, n)
+}
+
+// Destinations returns the top n destination ip addresses (all if n <= 0)
+func (t *Talkers) Destinations(n int) []Talker {
+	return topTalkers(t.destinations, n)
+}
+
+// topTalkers returns the top n talkers sorted by entries
+func topTalkers(talkers map[string]*Talker, n int) []Talker {
+	top := make([]Talker, 0, len(talkers))
+	for _, talker := range talkers {
+		top = append(top, *talker)
+	}
+	slices.SortFunc(top, func(a, b Talker) int {
+		return cmp.Or(
+			cmp.Compare(b.Entries, a.Entries),
+			cmp.Compare(b.Blocked, a.Blocked),
+			cmp.Compare(a.Addr, b.Addr),
+		)
+	})
+	if n > 0 && len(top) > n {
+		top = top[:n]
+	}
+	return top
+}
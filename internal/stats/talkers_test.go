@@ -0,0 +1,81 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stats
+
+import (
+	"testing"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+func TestTalkers(t *testing.T) {
+	entries := []stream.LogEntry{
+		{Action: stream.ActionBlock, Src: "192.0.2.1", Dst: "198.51.100.1", Length: 60},
+		{Action: stream.ActionBlock, Src: "192.0.2.1", Dst: "198.51.100.1", Length: 40},
+		{Action: stream.ActionPass, Src: "192.0.2.2", Dst: "198.51.100.1", Length: 1500},
+		{Action: stream.ActionPass, Src: "192.0.2.3", Dst: "198.51.100.2"},
+		{Action: stream.ActionBlock, Src: "192.0.2.3", Dst: "198.51.100.3"},
+	}
+	tl := NewTalkers()
+	for _, entry := range entries {
+		tl.Add(&entry)
+	}
+
+	tests := []struct {
+		name   string
+		got    []Talker
+		expect []Talker
+	}{
+		{
+			name: "sources",
+			got:  tl.Sources(0),
+			expect: []Talker{
+				{Addr: "192.0.2.1", Entries: 2, Blocked: 2, Bytes: 100},
+				{Addr: "192.0.2.3", Entries: 2, Blocked: 1},
+				{Addr: "192.0.2.2", Entries: 1, Blocked: 0, Bytes: 1500},
+			},
+		},
+		{
+			name: "top destinations",
+			got:  tl.Destinations(2),
+			expect: []Talker{
+				{Addr: "198.51.100.1", Entries: 3, Blocked: 2, Bytes: 1600},
+				{Addr: "198.51.100.3", Entries: 1, Blocked: 1},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if len(tc.got) != len(tc.expect) {
+				t.Fatalf("expected %d talkers, got %d: %+v", len(tc.expect), len(tc.got), tc.got)
+			}
+			for i := range tc.expect {
+				if tc.got[i] != tc.expect[i] {
+					t.Fatalf("talker %d: expected %+v, got %+v", i, tc.expect[i], tc.got[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stats
+
+import (
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+// timelineSteps are the bucket sizes a timeline can be grouped by
+var timelineSteps = []time.Duration{
+	time.Minute,
+	5 * time.Minute,
+	15 * time.Minute,
+	time.Hour,
+	6 * time.Hour,
+	24 * time.Hour,
+	7 * 24 * time.Hour,
+}
+
+// TimelineBucket represents the number of entries within a time interval
+type TimelineBucket struct {
+	Start   time.Time `json:"start"`   // start of the interval
+	Entries int       `json:"entries"` // number of entries
+	Blocked int       `json:"blocked"` // number of blocked entries
+}
+
+// Timeline counts entries over time (per minute)
+type Timeline struct {
+	entries map[int64]int // number of entries per minute (unix time)
+	blocked map[int64]int // number of blocked entries per minute (unix time)
+	first   time.Time     // first entry
+	last    time.Time     // last entry
+}
+
+// NewTimeline creates a new timeline
+func NewTimeline() *Timeline {
+	return &Timeline{
+		entries: make(map[int64]int),
+		blocked: make(map[int64]int),
+	}
+}
+
+// Add processes a single entry
+func (t *Timeline) Add(entry *stream.LogEntry) {
+	if len(t.entries) == 0 || entry.Time.Before(t.first) {
+		t.first = entry.Time
+	}
+	if len(t.entries) == 0 || entry.Time.After(t.last) {
+		t.last = entry.Time
+	}
+	minute := entry.Time.Truncate(time.Minute).Unix()
+	t.entries[minute]++
+	if entry.Action == stream.ActionBlock {
+		t.blocked[minute]++
+	}
+}
+
+// Range returns the time of the first and last entry (zero if empty)
+func (t *Timeline) Range() (time.Time, time.Time) {
+	return t.first, t.last
+}
+
+// Buckets groups the timeline into contiguous buckets (including empty ones) using the smallest step that fits n buckets
+func (t *Timeline) Buckets(n int) []TimelineBucket {
+	if len(t.entries) == 0 || n <= 0 {
+		return nil
+	}
+	step := timelineSteps[len(timelineSteps)-1]
+	for _, s := range timelineSteps {
+		if int(t.last.Sub(t.first.Truncate(s))/s) < n {
+			step = s
+			break
+		}
+	}
+	start := t.first.Truncate(step)
+	buckets := make([]TimelineBucket, int(t.last.Sub(start)/step)+1)
+	for i := range buckets {
+		buckets[i].Start = start.Add(time.Duration(i) * step)
+	}
+	for minute, count := range t.entries {
+		i := int(time.Unix(minute, 0).Sub(start) / step)
+		buckets[i].Entries += count
+		buckets[i].Blocked += t.blocked[minute]
+	}
+	return buckets
+}
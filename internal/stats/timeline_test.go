@@ -0,0 +1,105 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+func TestTimeline(t *testing.T) {
+	start := time.Date(2025, 10, 10, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		offsets    []time.Duration
+		n          int
+		expectStep time.Duration
+		expectLen  int
+	}{
+		{
+			name:       "minutes",
+			offsets:    []time.Duration{0, 30 * time.Second, 5 * time.Minute},
+			n:          10,
+			expectStep: time.Minute,
+			expectLen:  6,
+		},
+		{
+			name:       "hours",
+			offsets:    []time.Duration{0, 90 * time.Minute, 20 * time.Hour},
+			n:          48,
+			expectStep: time.Hour,
+			expectLen:  21,
+		},
+		{
+			name:       "days",
+			offsets:    []time.Duration{0, 72 * time.Hour},
+			n:          10,
+			expectStep: 24 * time.Hour,
+			expectLen:  4,
+		},
+		{
+			name:      "empty",
+			offsets:   nil,
+			n:         10,
+			expectLen: 0,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tl := NewTimeline()
+			for i, offset := range tc.offsets {
+				entry := stream.LogEntry{Action: stream.ActionPass, Time: start.Add(offset)}
+				if i == 0 {
+					entry.Action = stream.ActionBlock
+				}
+				tl.Add(&entry)
+			}
+			buckets := tl.Buckets(tc.n)
+			if len(buckets) != tc.expectLen {
+				t.Fatalf("expected %d buckets, got %d", tc.expectLen, len(buckets))
+			}
+			if len(buckets) == 0 {
+				return
+			}
+			if step := buckets[1].Start.Sub(buckets[0].Start); step != tc.expectStep {
+				t.Fatalf("expected step %s, got %s", tc.expectStep, step)
+			}
+			entries, blocked := 0, 0
+			for _, b := range buckets {
+				entries += b.Entries
+				blocked += b.Blocked
+			}
+			if entries != len(tc.offsets) || blocked != 1 {
+				t.Fatalf("expected %d entries and 1 blocked, got %d and %d", len(tc.offsets), entries, blocked)
+			}
+			if buckets[0].Entries == 0 || buckets[len(buckets)-1].Entries == 0 {
+				t.Fatalf("expected first and last bucket to have entries: %+v", buckets)
+			}
+		})
+	}
+}
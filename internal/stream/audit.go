@@ -0,0 +1,111 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stream
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// maxFieldSamples is the maximum number of distinct sample values kept per csv position
+const maxFieldSamples = 3
+
+// FieldPosition represents a single csv position of a line layout
+type FieldPosition struct {
+	Position int      `json:"position"` // position in the csv data
+	Seen     int      `json:"seen"`     // number of lines containing the position
+	Consumed bool     `json:"consumed"` // true if the parser read the position
+	Samples  []string `json:"samples"`  // distinct sample values (up to maxFieldSamples)
+}
+
+// FieldLayout groups the csv positions of all lines with the same format version, ip version and protocol
+type FieldLayout struct {
+	Format    string          `json:"format"`    // format version (detected)
+	Layout    string          `json:"layout"`    // ip version and protocol (e.g. ipv4/tcp, "invalid" if the line could not be parsed)
+	Lines     int             `json:"lines"`     // number of lines
+	Positions []FieldPosition `json:"positions"` // csv positions seen
+}
+
+// public
+
+// AuditFields reads the file from the start and reports every csv position seen, along with
+// sample values and whether the parser consumed it (used to validate the parser against format changes)
+func (s *Stream) AuditFields() ([]FieldLayout, error) {
+	if err := s.reset(); err != nil {
+		return nil, err
+	}
+	layouts := make(map[string]*FieldLayout)
+	s.consumed = make(map[int]bool)
+	defer func() { s.consumed = nil }()
+	scanner := bufio.NewScanner(s.file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := trimLine(scanner.Text())
+		clear(s.consumed)
+		name := "invalid"
+		if entry := s.parse(line, lineNum); entry != nil {
+			name = fmt.Sprintf("ipv%d/%s", entry.IPVersion, entry.ProtoName)
+		}
+		csv := ""
+		if csvStart := strings.Index(line, "] "); csvStart != -1 {
+			csv = line[csvStart+2:]
+		}
+		format := detectFormat(csv).version
+		layout, ok := layouts[format+"/"+name]
+		if !ok {
+			layout = &FieldLayout{Format: format, Layout: name}
+			layouts[format+"/"+name] = layout
+		}
+		layout.Lines++
+		if csv == "" {
+			continue
+		}
+		for i, value := range strings.Split(csv, ",") {
+			if i == len(layout.Positions) {
+				layout.Positions = append(layout.Positions, FieldPosition{Position: i})
+			}
+			position := &layout.Positions[i]
+			position.Seen++
+			position.Consumed = position.Consumed || s.consumed[i]
+			if value != "" && len(position.Samples) < maxFieldSamples && !slices.Contains(position.Samples, value) {
+				position.Samples = append(position.Samples, value)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error(stream): could not audit fields due to scanner error: %w", err)
+	}
+	result := make([]FieldLayout, 0, len(layouts))
+	for _, layout := range layouts {
+		result = append(result, *layout)
+	}
+	slices.SortFunc(result, func(a, b FieldLayout) int {
+		return cmp.Or(cmp.Compare(b.Lines, a.Lines), cmp.Compare(a.Format, b.Format), cmp.Compare(a.Layout, b.Layout))
+	})
+	return result, s.reset()
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stream
+
+import (
+	"testing"
+)
+
+func TestAuditFields(t *testing.T) {
+	s, err := NewStream("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	layouts, err := s.AuditFields()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectLines := map[string]int{"ipv4/tcp": 12, "ipv4/udp": 6, "ipv6/udp": 2}
+	if len(layouts) != len(expectLines) {
+		t.Fatalf("expected %d layouts, got %+v", len(expectLines), layouts)
+	}
+	for _, layout := range layouts {
+		if layout.Format != FormatCurrent {
+			t.Fatalf("expected %s format for %s, got %s", FormatCurrent, layout.Layout, layout.Format)
+		}
+		if layout.Lines != expectLines[layout.Layout] {
+			t.Fatalf("expected %d lines for %s, got %d", expectLines[layout.Layout], layout.Layout, layout.Lines)
+		}
+	}
+	// tcp4 (most lines first)
+	tcp := layouts[0]
+	if tcp.Layout != "ipv4/tcp" || len(tcp.Positions) != 29 {
+		t.Fatalf("expected 29 positions for ipv4/tcp, got %+v", tcp)
+	}
+	for _, tc := range []struct {
+		position int
+		consumed bool
+	}{
+		{position: 0, consumed: true},
+		{position: 1, consumed: true},
+		{position: 2, consumed: true},
+		{position: 4, consumed: true},
+		{position: 11, consumed: true},
+		{position: 15, consumed: true},
+		{position: 17, consumed: true},
+		{position: 21, consumed: true},
+		{position: 22, consumed: true},
+		{position: 23, consumed: true},
+		{position: 28, consumed: true},
+	} {
+		if got := tcp.Positions[tc.position]; got.Consumed != tc.consumed || got.Seen != 12 {
+			t.Fatalf("position %d: expected consumed=%v seen=12, got %+v", tc.position, tc.consumed, got)
+		}
+	}
+	if samples := tcp.Positions[16].Samples; len(samples) != 1 || samples[0] != "tcp" {
+		t.Fatalf("expected sample tcp at position 16, got %v", samples)
+	}
+	if samples := tcp.Positions[3].Samples; len(samples) != maxFieldSamples {
+		t.Fatalf("expected %d samples at position 3, got %v", maxFieldSamples, samples)
+	}
+	// the stream can be read again afterwards
+	count := 0
+	for entry := s.Next(); entry != nil; entry = s.Next() {
+		count++
+	}
+	if count != 20 {
+		t.Fatalf("expected 20 entries after audit, got %d", count)
+	}
+}
+
+func TestAuditFieldsLegacy(t *testing.T) {
+	s, err := NewStream("../../tests/filter_legacy.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	layouts, err := s.AuditFields()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, layout := range layouts {
+		if layout.Format != FormatLegacy || layout.Layout == "invalid" {
+			t.Fatalf("expected valid %s layout, got %+v", FormatLegacy, layout)
+		}
+		// every position is shifted by one
+		for _, position := range layout.Positions {
+			if !position.Consumed {
+				t.Fatalf("expected every position of %s to be consumed, got %+v", layout.Layout, layout.Positions)
+			}
+		}
+		if samples := layout.Positions[3].Samples; len(samples) != 1 || samples[0] != "eth1" && samples[0] != "eth0" {
+			t.Fatalf("expected interface at position 3, got %v", samples)
+		}
+	}
+}
+
+func TestAuditFieldsInvalid(t *testing.T) {
+	s, err := NewStream("../../tests/filter_corrupt.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	layouts, err := s.AuditFields()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, layout := range layouts {
+		if layout.Layout == "invalid" {
+			return
+		}
+	}
+	t.Fatalf("expected invalid layout, got %+v", layouts)
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stream
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Backfill returns the entries logged since a point in time from the rotated files of a log file and then follows
+// the log file (starting at its first entry since then, so no entry is returned twice or skipped at the handoff)
+type Backfill struct {
+	*Follower           // follows the log file once the rotated files have been read
+	files     []string  // rotated files not read yet (oldest first)
+	rotated   *Stream   // rotated file being read (nil if none)
+	since     time.Time // entries logged before are skipped
+}
+
+// firstSince returns the position of the first indexed entry of s logged at or after since (assuming entries are
+// in chronological order, the number of indexed entries if there is none)
+func firstSince(s *Stream, since time.Time) (int, error) {
+	var err error
+	pos := sort.Search(max(s.TotalLines(), 0), func(i int) bool {
+		if err != nil {
+			return true
+		}
+		if err = s.SeekToLine(i); err != nil {
+			return true
+		}
+		entry := s.Next()
+		return entry == nil || !entry.Time.Before(since)
+	})
+	return pos, err
+}
+
+// public
+
+// Next returns the next entry of the rotated files and then of the followed file (nil if no more entries are
+// available yet)
+func (b *Backfill) Next() *LogEntry {
+	for b.rotated != nil || len(b.files) > 0 {
+		if b.rotated == nil {
+			rotated, err := b.stream.Open(b.files[0])
+			b.files = b.files[1:]
+			if err != nil {
+				b.stream.addError(err)
+				continue
+			}
+			rotated.SetErrorHandler(b.stream.addError)
+			b.rotated = rotated
+		}
+		for entry := b.rotated.Next(); entry != nil; entry = b.rotated.Next() {
+			if !entry.Time.Before(b.since) {
+				return entry
+			}
+		}
+		b.rotated.Close()
+		b.rotated = nil
+	}
+	return b.Follower.Next()
+}
+
+// Close closes the followed file and the rotated file being read
+func (b *Backfill) Close() error {
+	if b.rotated != nil {
+		b.rotated.Close()
+	}
+	return b.Follower.Close()
+}
+
+// NewBackfill creates a follower of path that first returns the entries logged since the given time from the
+// rotated files of path (see Rotations, files last modified before are skipped) and then the entries of path
+// logged since then (located using its index)
+func NewBackfill(path string, since time.Time) (*Backfill, error) {
+	current, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return nil, fmt.Errorf("error(stream): %w", err)
+	}
+	rotations, err := Rotations(path)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]string, 0, len(rotations))
+	for _, file := range rotations {
+		if info, err := os.Stat(file); file == current || err != nil || info.ModTime().Before(since) {
+			continue
+		}
+		files = append(files, file)
+	}
+	s, err := NewStream(path)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+	if err := s.BuildIndex(); err != nil {
+		return nil, err
+	}
+	pos, err := firstSince(s, since)
+	if err != nil {
+		return nil, err
+	}
+	f, err := NewFollowerAt(path, s.CheckpointAt(pos))
+	if err != nil {
+		return nil, err
+	}
+	return &Backfill{Follower: f, files: files, since: since}, nil
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stream
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackfill(t *testing.T) {
+	data, err := os.ReadFile("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := strings.SplitAfter(string(data), "\n")[1]
+	logLine := func(ts string) string {
+		return strings.Replace(line, "2025-10-10T00:00:00+02:00", ts, 1)
+	}
+	files := []struct {
+		name  string
+		times []string
+	}{
+		{"filter_20251008.log", []string{"2025-10-08T22:00:00+02:00", "2025-10-08T23:00:00+02:00"}},
+		{"filter_20251009.log", []string{"2025-10-09T22:00:00+02:00", "2025-10-09T23:00:00+02:00"}},
+		{"filter_20251010.log", []string{"2025-10-10T00:00:00+02:00", "2025-10-10T01:00:00+02:00", "2025-10-10T02:00:00+02:00"}},
+	}
+	dir := t.TempDir()
+	for _, file := range files {
+		var b strings.Builder
+		for _, ts := range file.times {
+			b.WriteString(logLine(ts))
+		}
+		path := filepath.Join(dir, file.name)
+		if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		modTime, _ := time.Parse(time.RFC3339, file.times[len(file.times)-1])
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := filepath.Join(dir, "latest.log")
+	if err := os.Symlink("filter_20251010.log", path); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		since    string
+		expected []string
+	}{
+		{"rotated", "2025-10-09T23:00:00+02:00", []string{
+			"2025-10-09T23:00:00+02:00", "2025-10-10T00:00:00+02:00", "2025-10-10T01:00:00+02:00", "2025-10-10T02:00:00+02:00",
+		}},
+		{"current", "2025-10-10T00:30:00+02:00", []string{"2025-10-10T01:00:00+02:00", "2025-10-10T02:00:00+02:00"}},
+		{"all", "2025-10-01T00:00:00+02:00", []string{
+			"2025-10-08T22:00:00+02:00", "2025-10-08T23:00:00+02:00", "2025-10-09T22:00:00+02:00", "2025-10-09T23:00:00+02:00",
+			"2025-10-10T00:00:00+02:00", "2025-10-10T01:00:00+02:00", "2025-10-10T02:00:00+02:00",
+		}},
+		{"none", "2025-10-11T00:00:00+02:00", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			since, _ := time.Parse(time.RFC3339, tt.since)
+			b, err := NewBackfill(path, since)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer b.Close()
+			var got []string
+			for entry := b.Next(); entry != nil; entry = b.Next() {
+				got = append(got, entry.Time.Format(time.RFC3339))
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, got)
+			}
+			if errs := b.TakeErrors(); len(errs) > 0 {
+				t.Fatalf("expected no errors, got %v", errs)
+			}
+		})
+	}
+
+	// entries appended after the backfill are followed exactly once
+	since, _ := time.Parse(time.RFC3339, "2025-10-10T02:00:00+02:00")
+	b, err := NewBackfill(path, since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	if entry := b.Next(); entry == nil || entry.Time.Format(time.RFC3339) != "2025-10-10T02:00:00+02:00" {
+		t.Fatalf("expected last entry, got %+v", entry)
+	}
+	fh, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fh.WriteString(logLine("2025-10-10T03:00:00+02:00")); err != nil {
+		t.Fatal(err)
+	}
+	fh.Close()
+	if entry := b.Next(); entry == nil || entry.Time.Format(time.RFC3339) != "2025-10-10T03:00:00+02:00" {
+		t.Fatalf("expected appended entry, got %+v", entry)
+	}
+	if entry := b.Next(); entry != nil {
+		t.Fatalf("expected no more entries, got %+v", entry)
+	}
+}
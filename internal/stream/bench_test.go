@@ -0,0 +1,131 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stream
+
+import (
+	"bufio"
+	"flag"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var benchLines = flag.Int("lines", 100000, "number of lines of the synthetic log used by benchmarks")
+
+// fixtureLines returns the lines of the valid test log
+func fixtureLines(b *testing.B) []string {
+	content, err := os.ReadFile("../../tests/filter_valid.log")
+	if err != nil {
+		b.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(string(content)), "\n")
+}
+
+// syntheticLog writes a log with the given number of lines (repeating the valid test log) and returns its path
+func syntheticLog(b *testing.B, lines int) string {
+	fixture := fixtureLines(b)
+	path := filepath.Join(b.TempDir(), "filter.log")
+	file, err := os.Create(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer file.Close()
+	w := bufio.NewWriter(file)
+	for i := range lines {
+		w.WriteString(fixture[i%len(fixture)])
+		w.WriteByte('\n')
+	}
+	if err := w.Flush(); err != nil {
+		b.Fatal(err)
+	}
+	return path
+}
+
+// openSynthetic opens a synthetic log with -lines lines
+func openSynthetic(b *testing.B) *Stream {
+	s, err := NewStream(syntheticLog(b, *benchLines))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { s.Close() })
+	return s
+}
+
+func BenchmarkParseLine(b *testing.B) {
+	lines := fixtureLines(b)
+	b.ReportAllocs()
+	for i := 0; b.Loop(); i++ {
+		if _, err := ParseLine(lines[i%len(lines)]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBuildIndex(b *testing.B) {
+	s := openSynthetic(b)
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := s.BuildIndex(); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(*benchLines)*float64(b.N)/b.Elapsed().Seconds(), "lines/s")
+}
+
+func BenchmarkNext(b *testing.B) {
+	s := openSynthetic(b)
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := s.reset(); err != nil {
+			b.Fatal(err)
+		}
+		for entry := s.Next(); entry != nil; entry = s.Next() {
+		}
+	}
+	b.ReportMetric(float64(*benchLines)*float64(b.N)/b.Elapsed().Seconds(), "lines/s")
+}
+
+func BenchmarkSeekToLine(b *testing.B) {
+	for _, mmap := range []bool{false, true} {
+		b.Run(map[bool]string{false: "file", true: "mmap"}[mmap], func(b *testing.B) {
+			s := openSynthetic(b)
+			s.SetMmap(mmap)
+			if err := s.BuildIndex(); err != nil {
+				b.Fatal(err)
+			}
+			r := rand.New(rand.NewPCG(1, 2))
+			b.ReportAllocs()
+			for b.Loop() {
+				if err := s.SeekToLine(r.IntN(*benchLines)); err != nil {
+					b.Fatal(err)
+				}
+				if s.Next() == nil {
+					b.Fatal("expected entry after seek")
+				}
+			}
+		})
+	}
+}
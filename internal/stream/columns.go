@@ -0,0 +1,117 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stream
+
+import "math"
+
+// Columns holds compact metadata of every indexed line (used to answer simple filters without parsing the file)
+type Columns struct {
+	actions    dict          // distinct actions
+	interfaces dict          // distinct interfaces
+	lines      []lineColumns // metadata per indexed line
+}
+
+// dict assigns an id to each distinct string
+type dict struct {
+	ids    map[string]uint16 // id of each value
+	values []string          // values by id
+}
+
+// lineColumns represents the metadata of a single line
+type lineColumns struct {
+	action    uint16 // id in actions
+	iface     uint16 // id in interfaces
+	ipVersion uint8  // ip protocol version
+	dstPort   uint16 // destination port
+	srcPort   uint16 // source port
+}
+
+// id returns the id of the value (false if there are too many distinct values)
+func (d *dict) id(value string) (uint16, bool) {
+	if id, ok := d.ids[value]; ok {
+		return id, true
+	}
+	if len(d.values) > math.MaxUint16 {
+		return 0, false
+	}
+	if d.ids == nil {
+		d.ids = make(map[string]uint16)
+	}
+	id := uint16(len(d.values))
+	d.ids[value] = id
+	d.values = append(d.values, value)
+	return id, true
+}
+
+// add records the metadata of the next indexed line (false if it can't be represented)
+func (c *Columns) add(entry *LogEntry) bool {
+	action, ok := c.actions.id(entry.Action)
+	if !ok {
+		return false
+	}
+	iface, ok := c.interfaces.id(entry.Interface)
+	if !ok {
+		return false
+	}
+	c.lines = append(c.lines, lineColumns{
+		action:    action,
+		iface:     iface,
+		ipVersion: entry.IPVersion,
+		dstPort:   entry.DstPort,
+		srcPort:   entry.SrcPort,
+	})
+	return true
+}
+
+// public
+
+// Entry returns the entry of the given line with only action, interface, ip version and ports set
+func (c *Columns) Entry(lineNum int) LogEntry {
+	l := c.lines[lineNum]
+	return LogEntry{
+		Action:    c.actions.values[l.action],
+		Interface: c.interfaces.values[l.iface],
+		IPVersion: l.ipVersion,
+		DstPort:   l.dstPort,
+		SrcPort:   l.srcPort,
+	}
+}
+
+// Len returns the number of lines
+func (c *Columns) Len() int {
+	return len(c.lines)
+}
+
+// Columns returns the metadata recorded by BuildIndex (nil if not enabled using SetColumns)
+func (s *Stream) Columns() *Columns {
+	return s.columns
+}
+
+// SetColumns enables recording action, interface, ip version and ports of every line while indexing
+func (s *Stream) SetColumns(enabled bool) {
+	s.columnar = enabled
+	if !enabled {
+		s.columns = nil
+	}
+}
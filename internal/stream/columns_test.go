@@ -0,0 +1,96 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stream
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestColumns(t *testing.T) {
+	s, err := NewStream("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.BuildIndex(); err != nil {
+		t.Fatal(err)
+	}
+	if s.Columns() != nil {
+		t.Fatal("expected no columns unless enabled")
+	}
+	s.SetColumns(true)
+	if err := s.BuildIndex(); err != nil {
+		t.Fatal(err)
+	}
+	c := s.Columns()
+	if c == nil || c.Len() != s.TotalLines() {
+		t.Fatalf("expected columns of %d lines, got %+v", s.TotalLines(), c)
+	}
+	// columns must match the parsed entries
+	for i := range c.Len() {
+		entry := s.Next()
+		if entry == nil {
+			t.Fatalf("line %d: unexpected EOF", i)
+		}
+		expected := LogEntry{
+			Action:    entry.Action,
+			Interface: entry.Interface,
+			IPVersion: entry.IPVersion,
+			DstPort:   entry.DstPort,
+			SrcPort:   entry.SrcPort,
+		}
+		if got := c.Entry(i); !reflect.DeepEqual(got, expected) {
+			t.Errorf("line %d: expected %+v, got %+v", i, expected, got)
+		}
+	}
+	clone, err := s.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clone.Close()
+	if clone.Columns() != c {
+		t.Error("expected clone to share columns")
+	}
+	s.SetColumns(false)
+	if s.Columns() != nil {
+		t.Error("expected columns to be dropped when disabled")
+	}
+}
+
+func TestDictOverflow(t *testing.T) {
+	var d dict
+	for i := range 1 << 16 {
+		if _, ok := d.id(strconv.Itoa(i)); !ok {
+			t.Fatalf("unexpected overflow at %d values", i)
+		}
+	}
+	if _, ok := d.id("new"); ok {
+		t.Error("expected overflow")
+	}
+	if id, ok := d.id("42"); !ok || d.values[id] != "42" {
+		t.Error("expected existing value to be found")
+	}
+}
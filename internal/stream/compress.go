@@ -0,0 +1,120 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stream
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	compressBzip2 = "bzip2"
+	compressGzip  = "gzip"
+)
+
+// magic numbers of the supported compression formats
+var (
+	magicBzip2 = []byte("BZh")
+	magicGzip  = []byte{0x1f, 0x8b}
+)
+
+// compression returns the compression format of file detected from its first bytes (empty if not compressed,
+// the file is positioned at the start again)
+func compression(file *os.File) (string, error) {
+	header := make([]byte, len(magicBzip2))
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	switch {
+	case bytes.HasPrefix(header[:n], magicBzip2):
+		return compressBzip2, nil
+	case bytes.HasPrefix(header[:n], magicGzip):
+		return compressGzip, nil
+	}
+	return "", nil
+}
+
+// decompress writes the decompressed content of file to a temporary file, which is returned positioned at the
+// start (rotated logs are read and indexed like plain files this way, the caller removes the temporary file)
+func decompress(file *os.File, format string) (*os.File, error) {
+	var r io.Reader
+	switch format {
+	case compressBzip2:
+		r = bzip2.NewReader(file)
+	case compressGzip:
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	default:
+		return nil, fmt.Errorf("unknown compression format %q", format)
+	}
+	temp, err := os.CreateTemp("", "filterlog-*.log")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(temp, r); err != nil {
+		temp.Close()
+		os.Remove(temp.Name())
+		return nil, err
+	}
+	if _, err := temp.Seek(0, io.SeekStart); err != nil {
+		temp.Close()
+		os.Remove(temp.Name())
+		return nil, err
+	}
+	return temp, nil
+}
+
+// openLog opens the log file at path, compressed files are decompressed (temp is the path of the decompressed copy
+// that is returned instead, empty if the file isn't compressed)
+func openLog(path string) (file *os.File, temp string, err error) {
+	if file, err = openFile(path); err != nil {
+		return nil, "", err
+	}
+	format, err := compression(file)
+	if err != nil {
+		file.Close()
+		return nil, "", err
+	}
+	if format == "" {
+		return file, "", nil
+	}
+	defer file.Close()
+	decompressed, err := decompress(file, format)
+	if err != nil {
+		return nil, "", fmt.Errorf("could not decompress %s (%s): %w", path, format, err)
+	}
+	return decompressed, decompressed.Name(), nil
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stream
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewStreamCompressed(t *testing.T) {
+	data, err := os.ReadFile("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gzPath := filepath.Join(t.TempDir(), "filter_20251001.log.gz")
+	file, err := os.Create(gzPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// rotated files may consist of several gzip members
+	half := len(data) / 2
+	for _, part := range [][]byte{data[:half], data[half:]} {
+		gz := gzip.NewWriter(file)
+		if _, err := gz.Write(part); err != nil {
+			t.Fatal(err)
+		}
+		if err := gz.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	file.Close()
+
+	plain, err := NewStream("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plain.Close()
+	if err := plain.BuildIndex(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{name: "gzip", path: gzPath},
+		{name: "bzip2", path: "../../tests/filter_valid.log.bz2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewStream(tt.path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if s.Source() == tt.path || s.GetPathRel() != tt.path {
+				t.Errorf("expected a decompressed copy of %s, got source %s", tt.path, s.Source())
+			}
+			if err := s.BuildIndex(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if s.TotalLines() != plain.TotalLines() {
+				t.Fatalf("expected %d lines, got %d", plain.TotalLines(), s.TotalLines())
+			}
+			last := s.TotalLines() - 1
+			if err := s.SeekToLine(last); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := plain.SeekToLine(last); err != nil {
+				t.Fatal(err)
+			}
+			if got, expected := s.Next(), plain.Next(); got == nil || FormatLine(got) != FormatLine(expected) {
+				t.Errorf("expected last line %q, got %v", FormatLine(expected), got)
+			}
+			source := s.Source()
+			if err := s.Close(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := os.Stat(source); !os.IsNotExist(err) {
+				t.Errorf("expected %s to be removed, got %v", source, err)
+			}
+		})
+	}
+
+	if _, err := NewFollower(gzPath, true); err == nil {
+		t.Error("expected error when following a compressed file")
+	}
+}
+
+func TestStreamUnlink(t *testing.T) {
+	s, err := NewStream("../../tests/filter_valid.log.bz2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.Unlink(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(s.Source()); !os.IsNotExist(err) {
+		t.Errorf("expected %s to be removed, got %v", s.Source(), err)
+	}
+	// the open handle can still be read
+	if s.Next() == nil {
+		t.Error("expected an entry")
+	}
+
+	plain, err := NewStream("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plain.Close()
+	if err := plain.Unlink(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(plain.Source()); err != nil {
+		t.Errorf("expected the log file to be kept, got %v", err)
+	}
+}
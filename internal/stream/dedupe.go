@@ -0,0 +1,82 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stream
+
+import (
+	"hash/fnv"
+	"time"
+)
+
+// DedupeWindow is how long an entry is remembered after the newest entry seen (duplicates of overlapping rotated
+// files or re-shipped syslog are logged close to each other, merged files are ordered by time)
+const DedupeWindow = time.Minute
+
+// Hash returns a stable hash of the content of the entry (the fields preserved by FormatLine, identical lines have
+// the same hash regardless of the file or syslog message they were read from)
+func (e *LogEntry) Hash() uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(FormatLine(e)))
+	return h.Sum64()
+}
+
+// Deduper detects entries identical to one seen before (not safe for concurrent use)
+type Deduper struct {
+	duplicates int                  // number of duplicates detected
+	newest     time.Time            // time of the newest entry seen
+	pruned     time.Time            // newest time when seen was last pruned
+	seen       map[uint64]time.Time // hashes of the entries seen within DedupeWindow and their times
+}
+
+// NewDeduper creates a new deduper
+func NewDeduper() *Deduper {
+	return &Deduper{seen: make(map[uint64]time.Time)}
+}
+
+// Duplicate returns true if an identical entry has been seen within DedupeWindow, the entry is remembered otherwise
+func (d *Deduper) Duplicate(entry *LogEntry) bool {
+	hash := entry.Hash()
+	if _, ok := d.seen[hash]; ok {
+		d.duplicates++
+		return true
+	}
+	d.seen[hash] = entry.Time
+	if entry.Time.After(d.newest) {
+		d.newest = entry.Time
+	}
+	// forget entries too old to have duplicates logged after the newest entry
+	if d.newest.Sub(d.pruned) > DedupeWindow {
+		for hash, t := range d.seen {
+			if d.newest.Sub(t) > DedupeWindow {
+				delete(d.seen, hash)
+			}
+		}
+		d.pruned = d.newest
+	}
+	return false
+}
+
+// Duplicates returns the number of duplicates detected
+func (d *Deduper) Duplicates() int {
+	return d.duplicates
+}
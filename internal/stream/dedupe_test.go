@@ -0,0 +1,86 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stream
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHash(t *testing.T) {
+	line := mergeLine(t, 0)
+	entry, err := ParseLine(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the same entry shipped again in another syslog message
+	reshipped, err := ParseLine(strings.Replace(line, `sequenceId="1"`, `sequenceId="4711"`, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Hash() != reshipped.Hash() {
+		t.Errorf("expected identical hashes, got %x and %x", entry.Hash(), reshipped.Hash())
+	}
+	later, err := ParseLine(mergeLine(t, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Hash() == later.Hash() {
+		t.Errorf("expected different hashes for entries logged at different times, got %x", entry.Hash())
+	}
+}
+
+func TestDeduper(t *testing.T) {
+	entry := func(second int, dport uint16) *LogEntry {
+		return &LogEntry{Action: ActionBlock, Time: time.Date(2025, 10, 10, 0, 0, second, 0, time.UTC), IPVersion: ipVersion4, ProtoName: protoTCP, Src: "203.0.113.10", Dst: "198.51.100.1", DstPort: dport}
+	}
+	d := NewDeduper()
+	for i, tc := range []struct {
+		entry     *LogEntry
+		duplicate bool
+	}{
+		{entry: entry(0, 22), duplicate: false},
+		{entry: entry(0, 443), duplicate: false},
+		{entry: entry(0, 22), duplicate: true},
+		{entry: entry(1, 22), duplicate: false},
+		{entry: entry(0, 443), duplicate: true},
+		{entry: entry(1, 22), duplicate: true},
+	} {
+		if got := d.Duplicate(tc.entry); got != tc.duplicate {
+			t.Errorf("entry %d: expected duplicate=%v, got %v", i, tc.duplicate, got)
+		}
+	}
+	if d.Duplicates() != 3 {
+		t.Errorf("expected 3 duplicates, got %d", d.Duplicates())
+	}
+
+	// entries older than the window are forgotten
+	late := entry(0, 80)
+	late.Time = late.Time.Add(2 * DedupeWindow)
+	d.Duplicate(late)
+	if len(d.seen) != 1 {
+		t.Errorf("expected only the newest entry to be remembered, got %d", len(d.seen))
+	}
+}
@@ -0,0 +1,304 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stream
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/debuglog"
+	"gitlab.com/allddd/opnsense-filterlog/internal/sandbox"
+)
+
+// Checkpoint represents a position in a log file (used to resume following after a restart)
+type Checkpoint struct {
+	Dev     uint64 `json:"dev"`    // device of the file
+	Ino     uint64 `json:"ino"`    // inode of the file
+	LineNum int    `json:"line"`   // number of lines before offset
+	Offset  int64  `json:"offset"` // byte offset of the next line
+}
+
+// SameFile returns true if both checkpoints refer to the same file
+func (c Checkpoint) SameFile(other Checkpoint) bool {
+	return c.Dev == other.Dev && c.Ino == other.Ino
+}
+
+// Follower reads entries appended to a log file (similar to tail -f)
+type Follower struct {
+	dev     uint64        // device of the open file
+	file    *os.File      // file handle
+	ino     uint64        // inode of the open file
+	lineNum int           // current line number
+	offset  int64         // byte offset of the next complete line
+	partial []byte        // incomplete last line (not yet terminated by newline)
+	reader  *bufio.Reader // file reader
+	stream  *Stream       // parser (collects parsing errors)
+}
+
+// reopen reopens the file and positions the reader at offset
+func (f *Follower) reopen(offset int64) error {
+	if f.file != nil {
+		f.file.Close()
+	}
+	file, err := openFile(f.stream.path)
+	if err != nil {
+		return fmt.Errorf("error(stream): %w", err)
+	}
+	if format, _ := compression(file); format != "" {
+		file.Close()
+		return fmt.Errorf("error(stream): can't follow %s, it's compressed (%s)", f.stream.path, format)
+	}
+	if info, err := file.Stat(); err == nil {
+		f.dev, f.ino = fileIdentity(info)
+	}
+	f.file = file
+	return f.seek(offset)
+}
+
+// seek positions the reader of the open file at offset
+func (f *Follower) seek(offset int64) error {
+	if _, err := f.file.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("error(stream): could not seek to offset %d: %w", offset, err)
+	}
+	f.offset = offset
+	f.partial = nil
+	f.reader = bufio.NewReader(f.file)
+	return nil
+}
+
+// checkTruncated starts over from the beginning of the open file if it has been truncated
+func (f *Follower) checkTruncated() {
+	info, err := f.file.Stat()
+	if err != nil || info.Size() >= f.offset+int64(len(f.partial)) {
+		return
+	}
+	debuglog.Info("stream: file truncated, reading from the start", "path", f.stream.path, "size", info.Size(),
+		"offset", f.offset)
+	if err := f.seek(0); err != nil {
+		f.stream.addError(err)
+		return
+	}
+	f.lineNum = 0
+}
+
+// checkReplaced switches to the file at the path once it is another file than the open one (e.g. rotated by
+// syslogd, called once the open file has been read to the end, returns true if switched)
+func (f *Follower) checkReplaced() bool {
+	info, err := sandbox.Stat(f.stream.path)
+	if err != nil {
+		// not created yet after rotation
+		return false
+	}
+	if dev, ino := fileIdentity(info); dev == f.dev && ino == f.ino {
+		return false
+	}
+	if len(f.partial) > 0 {
+		f.stream.addError(fmt.Errorf("error(stream): incomplete last line %d of the replaced file", f.lineNum+1))
+	}
+	debuglog.Info("stream: file replaced, reopening", "path", f.stream.path, "lines", f.lineNum, "offset", f.offset)
+	if err := f.reopen(0); err != nil {
+		f.stream.addError(err)
+		return false
+	}
+	f.stream.addError(fmt.Errorf("error(stream): %w: %s, reading the new file from the start", ErrReplaced, f.stream.path))
+	f.lineNum = 0
+	return true
+}
+
+// public
+
+// Checkpoint returns the position after the last returned entry
+func (f *Follower) Checkpoint() Checkpoint {
+	return Checkpoint{Dev: f.dev, Ino: f.ino, LineNum: f.lineNum, Offset: f.offset}
+}
+
+// Close closes the log file
+func (f *Follower) Close() error {
+	if f.file != nil {
+		return f.file.Close()
+	}
+	return nil
+}
+
+// GetPathRel returns the relative path of the log file
+func (f *Follower) GetPathRel() string {
+	return f.stream.path
+}
+
+// Lag returns the number of bytes of the file that have not been returned as entries yet
+func (f *Follower) Lag() int64 {
+	info, err := f.file.Stat()
+	if err != nil {
+		return 0
+	}
+	return max(info.Size()-f.offset, 0)
+}
+
+// NewFollower creates a new follower for the given log file (starting at the end unless fromStart is set)
+func NewFollower(path string, fromStart bool) (*Follower, error) {
+	f := &Follower{
+		stream: &Stream{errors: make([]string, 0), path: path},
+	}
+	if err := f.reopen(0); err != nil {
+		return nil, err
+	}
+	if !fromStart {
+		offset, err := f.file.Seek(0, io.SeekEnd)
+		if err != nil {
+			f.file.Close()
+			return nil, fmt.Errorf("error(stream): could not seek to end: %w", err)
+		}
+		f.offset = offset
+	}
+	return f, nil
+}
+
+// NewFollowerAt creates a new follower for the given log file resuming at the checkpoint
+// (starting at the beginning if the file has been replaced or truncated since)
+func NewFollowerAt(path string, cp Checkpoint) (*Follower, error) {
+	f := &Follower{
+		stream: &Stream{errors: make([]string, 0), path: path},
+	}
+	if err := f.reopen(0); err != nil {
+		return nil, err
+	}
+	info, err := f.file.Stat()
+	if err != nil {
+		f.file.Close()
+		return nil, fmt.Errorf("error(stream): %w", err)
+	}
+	if !f.Checkpoint().SameFile(cp) || info.Size() < cp.Offset {
+		return f, nil
+	}
+	if err := f.reopen(cp.Offset); err != nil {
+		return nil, err
+	}
+	f.lineNum = cp.LineNum
+	return f, nil
+}
+
+// Next reads and parses the next complete log entry (returns nil when no more entries are available yet)
+func (f *Follower) Next() *LogEntry {
+	for {
+		line, err := f.reader.ReadBytes('\n')
+		if err != nil {
+			f.partial = append(f.partial, line...)
+			if err != io.EOF {
+				f.stream.addError(fmt.Errorf("error(stream): could not read line %d: %w", f.lineNum+1, err))
+			}
+			if f.checkReplaced() {
+				continue
+			}
+			f.checkTruncated()
+			return nil
+		}
+		if len(f.partial) > 0 {
+			line = append(f.partial, line...)
+			f.partial = nil
+		}
+		f.offset += int64(len(line))
+		f.lineNum++
+		if entry := f.stream.parse(string(bytes.TrimSuffix(line, []byte{'\n'})), f.lineNum); entry != nil {
+			return entry
+		}
+		// if nil, continue to the next line
+	}
+}
+
+// Batch is a batch of entries sent by Follower.WatchBatches
+type Batch struct {
+	Checkpoint Checkpoint // position after the last entry of the batch
+	Entries    []LogEntry // entries appended since the previous batch
+}
+
+// watch reads the entries appended to the file every interval and sends them in batches converted by conv (one per
+// check that found entries) until ctx is done, the channel is closed afterwards
+func watch[T any](ctx context.Context, f *Follower, interval time.Duration, conv func(batch Batch) T) <-chan T {
+	ch := make(chan T)
+	go func() {
+		defer close(ch)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			var batch []LogEntry
+			for entry := f.Next(); entry != nil; entry = f.Next() {
+				batch = append(batch, *entry)
+			}
+			if len(batch) > 0 {
+				debuglog.Debug("stream: appended entries", "path", f.stream.path, "entries", len(batch), "offset", f.offset)
+				select {
+				case ch <- conv(Batch{Checkpoint: f.Checkpoint(), Entries: batch}):
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return ch
+}
+
+// Watch reads the entries appended to the file every interval and sends them in batches (one per check that found
+// entries) until ctx is done, the channel is closed afterwards (the follower must not be used meanwhile)
+func (f *Follower) Watch(ctx context.Context, interval time.Duration) <-chan []LogEntry {
+	return watch(ctx, f, interval, func(batch Batch) []LogEntry { return batch.Entries })
+}
+
+// WatchBatches works like Watch, each batch carries the position after its last entry (e.g. to save it once the
+// entries have been processed)
+func (f *Follower) WatchBatches(ctx context.Context, interval time.Duration) <-chan Batch {
+	return watch(ctx, f, interval, func(batch Batch) Batch { return batch })
+}
+
+// SetErrorHandler sets a function that is called for every error encountered afterwards instead of collecting
+// it for TakeErrors (parse errors are *ParseError)
+func (f *Follower) SetErrorHandler(handler func(err error)) {
+	f.stream.SetErrorHandler(handler)
+}
+
+// SetHook sets a function that is called for every entry read afterwards (e.g. to classify it)
+func (f *Follower) SetHook(hook func(entry *LogEntry)) {
+	f.stream.SetHook(hook)
+}
+
+// SetOrigin sets the origin of all entries read afterwards (overrides the hostname of each line)
+func (f *Follower) SetOrigin(origin string) {
+	f.stream.SetOrigin(origin)
+}
+
+// TakeErrors returns all parsing errors encountered since the last call and clears them
+func (f *Follower) TakeErrors() []string {
+	errors := f.stream.errors
+	f.stream.errors = make([]string, 0)
+	return errors
+}
@@ -0,0 +1,281 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stream
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFollower(t *testing.T) {
+	data, err := os.ReadFile("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	path := filepath.Join(t.TempDir(), "filter.log")
+	if err := os.WriteFile(path, []byte(lines[0]+lines[1]), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	appendString := func(s string) {
+		file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		if _, err := file.WriteString(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	count := func(f *Follower) int {
+		n := 0
+		for entry := f.Next(); entry != nil; entry = f.Next() {
+			n++
+		}
+		return n
+	}
+
+	f, err := NewFollower(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	end, err := NewFollower(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer end.Close()
+
+	steps := []struct {
+		name          string
+		action        func()
+		expect        int
+		expectFromEnd int
+	}{
+		{
+			name:          "existing entries",
+			action:        func() {},
+			expect:        2,
+			expectFromEnd: 0,
+		},
+		{
+			name:          "partial line",
+			action:        func() { appendString(lines[2][:40]) },
+			expect:        0,
+			expectFromEnd: 0,
+		},
+		{
+			name:          "completed line",
+			action:        func() { appendString(lines[2][40:] + lines[3]) },
+			expect:        2,
+			expectFromEnd: 2,
+		},
+		{
+			name:          "invalid line",
+			action:        func() { appendString("invalid\n") },
+			expect:        0,
+			expectFromEnd: 0,
+		},
+		{
+			name: "truncated",
+			action: func() {
+				if err := os.WriteFile(path, []byte(lines[4]), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			},
+			expect:        1,
+			expectFromEnd: 1,
+		},
+		{
+			name: "rotated",
+			action: func() {
+				if err := os.Rename(path, path+".1"); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(lines[5]+lines[6]), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			},
+			expect:        2,
+			expectFromEnd: 2,
+		},
+	}
+
+	for _, step := range steps {
+		step.action()
+		// truncation is detected on the first read, entries are returned on the next one
+		got, gotFromEnd := count(f)+count(f), count(end)+count(end)
+		if got != step.expect || gotFromEnd != step.expectFromEnd {
+			t.Fatalf("%s: expected %d/%d entries, got %d/%d", step.name, step.expect, step.expectFromEnd, got, gotFromEnd)
+		}
+	}
+	// the invalid line and the replaced file
+	if errors := f.TakeErrors(); len(errors) != 2 || !strings.Contains(errors[1], "file replaced") {
+		t.Fatalf("expected 2 errors, got %d: %v", len(errors), errors)
+	}
+	if errors := f.TakeErrors(); len(errors) != 0 {
+		t.Fatalf("expected errors to be cleared, got %v", errors)
+	}
+}
+
+func TestNewFollowerAt(t *testing.T) {
+	data, err := os.ReadFile("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	dir := t.TempDir()
+	path := filepath.Join(dir, "filter.log")
+	if err := os.WriteFile(path, []byte(lines[0]+lines[1]+lines[2]), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := NewFollower(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Next()
+	cp := f.Checkpoint()
+	f.Close()
+	if cp.Offset != int64(len(lines[0])) || cp.LineNum != 1 {
+		t.Fatalf("expected checkpoint after line 1, got %+v", cp)
+	}
+
+	// same file resumes at the checkpoint
+	f, err = NewFollowerAt(path, cp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := f.Next()
+	f.Close()
+	expected, _ := ParseLine(strings.TrimSuffix(lines[1], "\n"))
+	if entry == nil || !reflect.DeepEqual(entry, expected) {
+		t.Fatalf("expected second entry, got %+v", entry)
+	}
+
+	// replaced file starts at the beginning
+	replaced := filepath.Join(dir, "replaced.log")
+	if err := os.WriteFile(replaced, []byte(lines[0]+lines[1]+lines[2]), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(replaced, path); err != nil {
+		t.Fatal(err)
+	}
+	f, err = NewFollowerAt(path, cp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := f.Checkpoint(); got.Offset != 0 || got.SameFile(cp) {
+		t.Fatalf("expected replaced file to start at the beginning, got %+v", got)
+	}
+	cp = Checkpoint{Dev: f.Checkpoint().Dev, Ino: f.Checkpoint().Ino, Offset: 1 << 20}
+	f.Close()
+
+	// truncated file starts at the beginning
+	f, err = NewFollowerAt(path, cp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if got := f.Checkpoint(); got.Offset != 0 {
+		t.Fatalf("expected truncated file to start at the beginning, got %+v", got)
+	}
+}
+
+func TestFollowerLag(t *testing.T) {
+	data, err := os.ReadFile("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	path := filepath.Join(t.TempDir(), "filter.log")
+	if err := os.WriteFile(path, []byte(lines[0]+lines[1]), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := NewFollower(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if lag := f.Lag(); lag != int64(len(lines[0])+len(lines[1])) {
+		t.Fatalf("expected lag of the whole file, got %d", lag)
+	}
+	f.Next()
+	if lag := f.Lag(); lag != int64(len(lines[1])) {
+		t.Fatalf("expected lag of the second line, got %d", lag)
+	}
+	for f.Next() != nil {
+	}
+	if lag := f.Lag(); lag != 0 {
+		t.Fatalf("expected no lag, got %d", lag)
+	}
+}
+
+func TestFollowerWatch(t *testing.T) {
+	data, err := os.ReadFile("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	path := filepath.Join(t.TempDir(), "filter.log")
+	if err := os.WriteFile(path, []byte(lines[0]), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := NewFollower(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := f.Watch(ctx, 10*time.Millisecond)
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(lines[1] + lines[2]); err != nil {
+		t.Fatal(err)
+	}
+	received := 0
+	for received < 2 {
+		select {
+		case batch := <-ch:
+			received += len(batch)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected 2 appended entries, got %d", received)
+		}
+	}
+	if received != 2 {
+		t.Fatalf("expected 2 appended entries, got %d", received)
+	}
+	cancel()
+	for range ch {
+		// drained until closed
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stream
+
+const (
+	// format versions
+	FormatCurrent = "current" // rule label at position 3 (pfSense 2.2 and later, all current OPNsense releases)
+	FormatLegacy  = "legacy"  // no rule label (pfSense before 2.2 and early OPNsense releases)
+)
+
+// ipColumns maps the csv positions of the fields that depend on the ip version
+type ipColumns struct {
+	tos       int // type of service (ipv4)
+	ecn       int // explicit congestion notification (ipv4)
+	ttl       int // time to live (ipv4) or hop limit (ipv6)
+	id        int // identification (ipv4)
+	offset    int // fragment offset (ipv4)
+	flags     int // flags (ipv4)
+	class     int // traffic class (ipv6)
+	flow      int // flow label (ipv6)
+	protoNum  int // protocol number
+	protoName int // protocol name
+	length    int // total length of the packet
+	src       int // source ip address
+	dst       int // destination ip address
+	srcPort   int // source port (tcp/udp)
+	dstPort   int // destination port (tcp/udp)
+	dataLen   int // data length (tcp/udp)
+	icmpType  int // icmp type (icmp/ipv6-icmp)
+	tcpFlags  int // tcp flags, followed by seq, ack, window, urg and options (tcp)
+	protoData int // first value following the addresses (other protocols)
+}
+
+// columns maps the csv positions of all parsed fields for a format version (-1 if not present)
+type columns struct {
+	version    string    // format version
+	ruleNum    int       // rule number
+	subRuleNum int       // subrule number
+	anchor     int       // anchor name
+	label      int       // rule label
+	iface      int       // network interface
+	reason     int       // reason for action
+	action     int       // action taken
+	direction  int       // traffic direction
+	ipVersion  int       // ip protocol version
+	ipv4       ipColumns // ipv4 header and protocol
+	ipv6       ipColumns // ipv6 header and protocol
+}
+
+var (
+	// columnsCurrent
+	// 0: rulenr, 1: subrulenr, 2: anchorname, 3: label, 4: interface, 5: reason, 6: action, 7: direction, 8: ipversion
+	// ipv4: 9:tos, 10:ecn, 11:ttl, 12:id, 13:offset, 14:flags, 15:protonum, 16:protoname, 17:length, 18:src, 19:dst
+	// ipv6: 9:class, 10:flow, 11:hoplimit, 12:protoname, 13:protonum, 14:length, 15:src, 16:dst
+	// udp: srcport, dstport, datalen
+	// icmp: type, ...
+	// carp: type, ttl, vhid, version, advskew, advbase (other protocols: as logged, if anything)
+	// tcp: srcport, dstport, datalen, flags, seq, ack, window, urg, options
+	columnsCurrent = columns{
+		version:    FormatCurrent,
+		ruleNum:    0,
+		subRuleNum: 1,
+		anchor:     2,
+		label:      3,
+		iface:      4,
+		reason:     5,
+		action:     6,
+		direction:  7,
+		ipVersion:  8,
+		ipv4:       ipColumns{tos: 9, ecn: 10, ttl: 11, id: 12, offset: 13, flags: 14, protoNum: 15, protoName: 16, length: 17, src: 18, dst: 19, srcPort: 20, dstPort: 21, dataLen: 22, icmpType: 20, tcpFlags: 23, protoData: 20},
+		ipv6:       ipColumns{class: 9, flow: 10, ttl: 11, protoName: 12, protoNum: 13, length: 14, src: 15, dst: 16, srcPort: 17, dstPort: 18, dataLen: 19, icmpType: 17, tcpFlags: 20, protoData: 17},
+	}
+
+	// columnsLegacy (same as current without the label, all following positions are shifted by one)
+	columnsLegacy = columns{
+		version:    FormatLegacy,
+		ruleNum:    0,
+		subRuleNum: 1,
+		anchor:     2,
+		label:      -1,
+		iface:      3,
+		reason:     4,
+		action:     5,
+		direction:  6,
+		ipVersion:  7,
+		ipv4:       ipColumns{tos: 8, ecn: 9, ttl: 10, id: 11, offset: 12, flags: 13, protoNum: 14, protoName: 15, length: 16, src: 17, dst: 18, srcPort: 19, dstPort: 20, dataLen: 21, icmpType: 19, tcpFlags: 22, protoData: 19},
+		ipv6:       ipColumns{class: 8, flow: 9, ttl: 10, protoName: 11, protoNum: 12, length: 13, src: 14, dst: 15, srcPort: 16, dstPort: 17, dataLen: 18, icmpType: 16, tcpFlags: 19, protoData: 16},
+	}
+)
+
+// isIPVersion returns true if the csv field is a known ip version
+func isIPVersion(csv string, field int) bool {
+	value, ok := extractCSVField(csv, field)
+	return ok && (value == "4" || value == "6")
+}
+
+// detectFormat returns the columns of the format version the csv data was written in (based on the
+// position of the ip version, defaults to the current format if it can't be detected)
+func detectFormat(csv string) *columns {
+	if !isIPVersion(csv, columnsCurrent.ipVersion) && isIPVersion(csv, columnsLegacy.ipVersion) {
+		return &columnsLegacy
+	}
+	return &columnsCurrent
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stream
+
+import (
+	"testing"
+)
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name          string
+		csv           string
+		expectVersion string
+	}{
+		{
+			name:          "current ipv4",
+			csv:           "68,,,2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e,eth1,match,pass,out,4,0x0,,64,0,0,DF,17,udp,80,192.168.1.100,192.168.1.1,12162,53,60",
+			expectVersion: FormatCurrent,
+		},
+		{
+			name:          "current ipv6",
+			csv:           "61,,,1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d,eth0,match,pass,in,6,0x00,0xfd492,128,udp,17,60,fd00::1,fd00::2,63511,53,60",
+			expectVersion: FormatCurrent,
+		},
+		{
+			name:          "legacy ipv4",
+			csv:           "68,,,eth1,match,pass,out,4,0x0,,64,0,0,DF,17,udp,80,192.168.1.100,192.168.1.1,12162,53,60",
+			expectVersion: FormatLegacy,
+		},
+		{
+			name:          "legacy ipv6",
+			csv:           "61,,,eth0,match,pass,in,6,0x00,0xfd492,128,udp,17,60,fd00::1,fd00::2,63511,53,60",
+			expectVersion: FormatLegacy,
+		},
+		{
+			name:          "unknown",
+			csv:           "68,,,eth1,match",
+			expectVersion: FormatCurrent,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if version := detectFormat(tc.csv).version; version != tc.expectVersion {
+				t.Fatalf("expected %s, got %s", tc.expectVersion, version)
+			}
+		})
+	}
+}
+
+func TestLegacyLog(t *testing.T) {
+	s, err := NewStream("../../tests/filter_legacy.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	expect := []LogEntry{
+		{Interface: "eth1", Action: ActionPass, Direction: directionOut, IPVersion: ipVersion4, ProtoName: protoTCP, Src: "192.168.1.100", Dst: "10.0.0.5", SrcPort: 46376, DstPort: 80, RuleNum: "68"},
+		{Interface: "eth1", Action: ActionPass, Direction: directionOut, IPVersion: ipVersion4, ProtoName: protoUDP, Src: "192.168.1.100", Dst: "192.168.1.1", SrcPort: 12162, DstPort: 53, RuleNum: "68"},
+		{Interface: "eth0", Action: ActionBlock, Direction: directionIn, IPVersion: ipVersion6, ProtoName: protoUDP, Src: "fd00:1234:5678:9abc::1", Dst: "fd00:1234:5678:9abc::2", SrcPort: 63511, DstPort: 53, RuleNum: "61"},
+	}
+	for i, want := range expect {
+		entry := s.Next()
+		if entry == nil {
+			t.Fatalf("entry %d: expected entry, got nil (errors: %v)", i, s.GetErrors())
+		}
+		if entry.Interface != want.Interface || entry.Action != want.Action || entry.Direction != want.Direction ||
+			entry.IPVersion != want.IPVersion || entry.ProtoName != want.ProtoName || entry.Src != want.Src ||
+			entry.Dst != want.Dst || entry.SrcPort != want.SrcPort || entry.DstPort != want.DstPort ||
+			entry.RuleNum != want.RuleNum || entry.Label != "" || entry.Reason != reasonMatch {
+			t.Fatalf("entry %d: expected %+v, got %+v", i, want, *entry)
+		}
+	}
+	if entry := s.Next(); entry != nil {
+		t.Fatalf("expected EOF, got %+v", *entry)
+	}
+	if errors := s.GetErrors(); len(errors) > 0 {
+		t.Fatalf("expected no errors, got %v", errors)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stream
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// addSeeds adds every line of the log files in the tests directory to the seed corpus
+func addSeeds(f *testing.F) {
+	paths, err := filepath.Glob("../../tests/*.log")
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			f.Add(scanner.Text())
+		}
+		file.Close()
+	}
+	f.Add("")
+	f.Add(" ")
+	f.Add("<134>1 ")
+	f.Add("<134>1 2025-10-10T00:00:00+02:00")
+	f.Add("<134>1 2025-10-10T00:00:00+02:00 ] ")
+	f.Add("<134>1 2025-10-10T00:00:00+02:00 \xff\xfe filterlog - - [meta] \xe2\x82,,,,")
+}
+
+func FuzzParseLine(f *testing.F) {
+	addSeeds(f)
+	f.Fuzz(func(t *testing.T, line string) {
+		entry, err := ParseLine(line)
+		if (entry == nil) == (err == nil) {
+			t.Fatalf("expected either entry or error, got %v and %v", entry, err)
+		}
+		if err != nil {
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) || parseErr == nil || !strings.HasPrefix(parseErr.Reason, "invalid ") {
+				t.Fatalf("expected *ParseError, got %#v", err)
+			}
+			return
+		}
+		// entries must survive a round trip
+		again, err := ParseLine(FormatLine(entry))
+		if err != nil {
+			t.Fatalf("could not parse formatted line %q: %v", FormatLine(entry), err)
+		}
+		if again.Src != entry.Src || again.Dst != entry.Dst || again.DstPort != entry.DstPort || again.Action != entry.Action {
+			t.Fatalf("round trip changed entry: %+v != %+v", *again, *entry)
+		}
+	})
+}
+
+func FuzzExtractCSVField(f *testing.F) {
+	addSeeds(f)
+	f.Fuzz(func(t *testing.T, csv string) {
+		fields := strings.Split(csv, ",")
+		for i := range len(fields) + 1 {
+			value, ok := extractCSVField(csv, i)
+			if ok != (i < len(fields)) {
+				t.Fatalf("field %d: expected ok=%v, got %v", i, i < len(fields), ok)
+			}
+			if ok && value != fields[i] {
+				t.Fatalf("field %d: expected %q, got %q", i, fields[i], value)
+			}
+		}
+	})
+}
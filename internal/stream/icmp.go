@@ -0,0 +1,54 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stream
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	// icmpv6 neighbor discovery types (rfc 4861)
+	icmpv6RouterSolicitation    = 133
+	icmpv6Redirect              = 137
+	icmpv6AllNodes              = "ff02::1"    // all-nodes multicast address
+	icmpv6AllRouters            = "ff02::2"    // all-routers multicast address
+	icmpv6SolicitedNodePrefix   = "ff02::1:ff" // solicited-node multicast prefix
+	icmpv6SolicitedNodeExpanded = "ff02:0000:0000:0000:0000:0001:ff"
+)
+
+// Housekeeping returns true if the entry is icmpv6 neighbor discovery (router and neighbor solicitation and
+// advertisement, redirect), if the type is not logged it is guessed from the multicast destination
+func (e *LogEntry) Housekeeping() bool {
+	if e.ProtoName != protoICMPv6 {
+		return false
+	}
+	if e.ICMPType != "" {
+		t, err := strconv.Atoi(e.ICMPType)
+		return err == nil && t >= icmpv6RouterSolicitation && t <= icmpv6Redirect
+	}
+	dst := strings.ToLower(e.Dst)
+	return dst == icmpv6AllNodes || dst == icmpv6AllRouters ||
+		strings.HasPrefix(dst, icmpv6SolicitedNodePrefix) || strings.HasPrefix(dst, icmpv6SolicitedNodeExpanded)
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stream
+
+import "testing"
+
+func TestHousekeeping(t *testing.T) {
+	tests := []struct {
+		name   string
+		entry  LogEntry
+		expect bool
+	}{
+		{name: "router solicitation", entry: LogEntry{ProtoName: "ipv6-icmp", ICMPType: "133", Dst: "ff02::2"}, expect: true},
+		{name: "neighbor advertisement", entry: LogEntry{ProtoName: "ipv6-icmp", ICMPType: "136", Dst: "fe80::1"}, expect: true},
+		{name: "redirect", entry: LogEntry{ProtoName: "ipv6-icmp", ICMPType: "137", Dst: "fe80::1"}, expect: true},
+		{name: "echo request", entry: LogEntry{ProtoName: "ipv6-icmp", ICMPType: "128", Dst: "ff02::1"}, expect: false},
+		{name: "packet too big", entry: LogEntry{ProtoName: "ipv6-icmp", ICMPType: "2", Dst: "2001:db8::1"}, expect: false},
+		{name: "solicited-node without type", entry: LogEntry{ProtoName: "ipv6-icmp", Dst: "ff02::1:ff00:1"}, expect: true},
+		{name: "all-nodes without type", entry: LogEntry{ProtoName: "ipv6-icmp", Dst: "FF02::1"}, expect: true},
+		{name: "unicast without type", entry: LogEntry{ProtoName: "ipv6-icmp", Dst: "2001:db8::1"}, expect: false},
+		{name: "icmp4", entry: LogEntry{ProtoName: "icmp", ICMPType: "133", Dst: "ff02::1"}, expect: false},
+		{name: "udp to all-nodes", entry: LogEntry{ProtoName: "udp", Dst: "ff02::1"}, expect: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.entry.Housekeeping(); got != tc.expect {
+				t.Fatalf("expected %v, got %v", tc.expect, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stream
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// mergeSource is a log file that is merged with others
+type mergeSource struct {
+	line   string        // next line (including the newline, empty once the file has been read)
+	reader *bufio.Reader // file reader
+	time   time.Time     // time of line (of the line before if it can't be parsed)
+}
+
+// next reads the next line of the source
+func (m *mergeSource) next() error {
+	line, err := m.reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if line != "" && !strings.HasSuffix(line, "\n") {
+		// last line without newline
+		line += "\n"
+	}
+	m.line = line
+	if entry, err := ParseLine(strings.TrimSuffix(line, "\n")); err == nil {
+		m.time = entry.Time
+	}
+	return nil
+}
+
+// merge writes the lines of the readers to w ordered by time (lines of the same time keep the order of the readers,
+// lines that can't be parsed stay behind the line before them)
+func merge(w io.Writer, readers []io.Reader) error {
+	sources := make([]*mergeSource, len(readers))
+	for i, r := range readers {
+		sources[i] = &mergeSource{reader: bufio.NewReader(r)}
+		if err := sources[i].next(); err != nil {
+			return err
+		}
+	}
+	bw := bufio.NewWriter(w)
+	for {
+		var oldest *mergeSource
+		for _, src := range sources {
+			if src.line != "" && (oldest == nil || src.time.Before(oldest.time)) {
+				oldest = src
+			}
+		}
+		if oldest == nil {
+			return bw.Flush()
+		}
+		if _, err := bw.WriteString(oldest.line); err != nil {
+			return err
+		}
+		if err := oldest.next(); err != nil {
+			return err
+		}
+	}
+}
+
+// public
+
+// NewMergedStream creates a new streaming parser for the lines of several log files ordered by time, e.g. latest.log
+// and its rotations (the files are merged into a temporary file which is indexed like a single file, line numbers
+// refer to it), a single path is opened like NewStream
+func NewMergedStream(paths []string) (*Stream, error) {
+	if len(paths) == 1 {
+		return NewStream(paths[0])
+	}
+	readers := make([]io.Reader, 0, len(paths))
+	for _, path := range paths {
+		file, temp, err := openLog(path)
+		if err != nil {
+			return nil, fmt.Errorf("error(stream): %w", err)
+		}
+		defer file.Close()
+		if temp != "" {
+			defer os.Remove(temp)
+		}
+		readers = append(readers, file)
+	}
+	merged, err := os.CreateTemp("", "filterlog-*.log")
+	if err != nil {
+		return nil, fmt.Errorf("error(stream): %w", err)
+	}
+	if err := merge(merged, readers); err == nil {
+		_, err = merged.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		merged.Close()
+		os.Remove(merged.Name())
+		return nil, fmt.Errorf("error(stream): could not merge %s: %w", strings.Join(paths, ", "), err)
+	}
+	return &Stream{
+		errors:  make([]string, 0),
+		file:    merged,
+		path:    strings.Join(paths, ", "),
+		paths:   paths,
+		scanner: bufio.NewScanner(merged),
+		temp:    merged.Name(),
+	}, nil
+}
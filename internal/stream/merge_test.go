@@ -0,0 +1,113 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stream
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// mergeLine returns the first line of filter_valid.log logged at the given second
+func mergeLine(t *testing.T, second int) string {
+	t.Helper()
+	data, err := os.ReadFile("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	line, _, _ := strings.Cut(string(data), "\n")
+	return strings.Replace(line, "2025-10-10T00:00:00+02:00", fmt.Sprintf("2025-10-10T00:00:%02d+02:00", second), 1)
+}
+
+func TestMerge(t *testing.T) {
+	rotated := strings.Join([]string{mergeLine(t, 0), mergeLine(t, 2), "garbage", mergeLine(t, 4)}, "\n") + "\n"
+	latest := strings.Join([]string{mergeLine(t, 1), mergeLine(t, 3), mergeLine(t, 5)}, "\n") // no final newline
+	var b strings.Builder
+	if err := merge(&b, []io.Reader{strings.NewReader(rotated), strings.NewReader(latest)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{mergeLine(t, 0), mergeLine(t, 1), mergeLine(t, 2), "garbage", mergeLine(t, 3), mergeLine(t, 4), mergeLine(t, 5), ""}
+	if got := strings.Split(b.String(), "\n"); !slices.Equal(got, expected) {
+		t.Errorf("expected lines\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestNewMergedStream(t *testing.T) {
+	dir := t.TempDir()
+	rotated, latest := filepath.Join(dir, "filter_20251009.log"), filepath.Join(dir, "latest.log")
+	if err := os.WriteFile(rotated, []byte(mergeLine(t, 0)+"\n"+mergeLine(t, 2)+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(latest, []byte(mergeLine(t, 1)+"\n"+mergeLine(t, 3)+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewMergedStream([]string{latest, rotated})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := latest + ", " + rotated; s.GetPathRel() != expected {
+		t.Errorf("expected path %q, got %q", expected, s.GetPathRel())
+	}
+	if err := s.BuildIndex(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.TotalLines() != 4 {
+		t.Fatalf("expected 4 lines, got %d", s.TotalLines())
+	}
+	// seeking across the boundary of the files
+	for _, line := range []int{3, 0, 2, 1} {
+		if err := s.SeekToLine(line); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if entry := s.Next(); entry == nil || entry.Time.Second() != line {
+			t.Errorf("expected entry of second %d at line %d, got %v", line, line, entry)
+		}
+	}
+	source := s.Source()
+	if err := s.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(source); !os.IsNotExist(err) {
+		t.Errorf("expected %s to be removed, got %v", source, err)
+	}
+
+	// a single path is opened as is
+	s, err = NewMergedStream([]string{latest})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer s.Close()
+	if s.Source() != latest {
+		t.Errorf("expected %s to be read, got %s", latest, s.Source())
+	}
+
+	if _, err := NewMergedStream([]string{latest, filepath.Join(dir, "missing.log")}); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
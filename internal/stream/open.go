@@ -0,0 +1,59 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stream
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/sandbox"
+)
+
+// noSymlinks makes openFile refuse symlinks
+var noSymlinks atomic.Bool
+
+// openFile opens a log file read-only (log files are never written to)
+func openFile(path string) (*os.File, error) {
+	if !noSymlinks.Load() {
+		return sandbox.OpenFile(path, os.O_RDONLY, 0)
+	}
+	file, err := sandbox.OpenFile(path, os.O_RDONLY|oNoFollow, 0)
+	if err != nil {
+		// the error for symlinks differs between platforms (ELOOP, EMLINK)
+		if info, lerr := os.Lstat(path); lerr == nil && info.Mode()&os.ModeSymlink != 0 {
+			return nil, fmt.Errorf("refusing to open symlink %s", path)
+		}
+		return nil, err
+	}
+	return file, nil
+}
+
+// public
+
+// SetNoSymlinks makes opening a log file fail if its last path element is a symlink (ignored on platforms without
+// O_NOFOLLOW)
+func SetNoSymlinks(enabled bool) {
+	noSymlinks.Store(enabled)
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stream
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestOpenFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks require privileges")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "filter.log")
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "latest.log")
+	if err := os.Symlink("filter.log", link); err != nil {
+		t.Fatal(err)
+	}
+	defer SetNoSymlinks(false)
+
+	tests := []struct {
+		name        string
+		path        string
+		noSymlinks  bool
+		expectError bool
+	}{
+		{name: "file", path: path},
+		{name: "symlink", path: link},
+		{name: "file without symlinks", path: path, noSymlinks: true},
+		{name: "symlink without symlinks", path: link, noSymlinks: true, expectError: true},
+		{name: "missing", path: filepath.Join(dir, "missing.log"), noSymlinks: true, expectError: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			SetNoSymlinks(tc.noSymlinks)
+			file, err := openFile(tc.path)
+			if tc.expectError {
+				if err == nil {
+					file.Close()
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer file.Close()
+			if _, err := file.WriteString("x"); err == nil {
+				t.Error("expected file to be read-only")
+			}
+		})
+	}
+
+	SetNoSymlinks(true)
+	if _, err := NewStream(link); err == nil || !strings.Contains(err.Error(), "symlink") {
+		t.Errorf("expected symlink error, got %v", err)
+	}
+}
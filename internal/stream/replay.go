@@ -0,0 +1,91 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stream
+
+import (
+	"context"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/debuglog"
+)
+
+// Replayer sends the entries of a stream at the pace they were logged at (scaled by speed), as a Follower would have
+// read them while they were written
+type Replayer struct {
+	speed  float64 // factor the time between entries is divided by (e.g. 10 replays an hour in 6 minutes)
+	stream *Stream // entries to replay
+}
+
+// NewReplayer creates a replayer of the remaining entries of s (speed must be positive)
+func NewReplayer(s *Stream, speed float64) *Replayer {
+	return &Replayer{speed: speed, stream: s}
+}
+
+// SetErrorHandler sets a function that is called for every error encountered afterwards instead of collecting
+// it (parse errors are *ParseError)
+func (r *Replayer) SetErrorHandler(handler func(err error)) {
+	r.stream.SetErrorHandler(handler)
+}
+
+// Watch sends the entries that are due every interval in batches (like Follower.Watch) until all entries have been
+// sent or ctx is done, the channel is closed afterwards (entries logged before the previous one are due right away,
+// the stream must not be used meanwhile)
+func (r *Replayer) Watch(ctx context.Context, interval time.Duration) <-chan []LogEntry {
+	ch := make(chan []LogEntry)
+	go func() {
+		defer close(ch)
+		next := r.stream.Next()
+		if next == nil {
+			return
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		first, start := next.Time, time.Now()
+		for {
+			// time of the log that has been replayed so far
+			replayed := first.Add(time.Duration(float64(time.Since(start)) * r.speed))
+			var batch []LogEntry
+			for ; next != nil && !next.Time.After(replayed); next = r.stream.Next() {
+				batch = append(batch, *next)
+			}
+			if len(batch) > 0 {
+				debuglog.Debug("stream: replayed entries", "path", r.stream.path, "entries", len(batch), "time", replayed)
+				select {
+				case ch <- batch:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if next == nil {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return ch
+}
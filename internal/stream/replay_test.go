@@ -0,0 +1,91 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stream
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReplayer(t *testing.T) {
+	start := time.Date(2025, 10, 10, 10, 0, 0, 0, time.UTC)
+	var lines []string
+	// 0s, 0s, 2s, 1s (out of order) and 4s after the first entry
+	for i, offset := range []time.Duration{0, 0, 2 * time.Second, time.Second, 4 * time.Second} {
+		entry := LogEntry{Action: ActionBlock, Direction: directionIn, Interface: "igb1", Reason: reasonMatch,
+			Time: start.Add(offset), Dst: "198.51.100.1", IPVersion: ipVersion4, ProtoName: protoUDP, Src: "203.0.113.10",
+			DstPort: uint16(i + 1), SrcPort: 40000}
+		lines = append(lines, FormatLine(&entry))
+	}
+	path := filepath.Join(t.TempDir(), "filter.log")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewStream(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// 4 seconds of log in 40 milliseconds
+	begin := time.Now()
+	var ports []uint16
+	batches := 0
+	for batch := range NewReplayer(s, 100).Watch(context.Background(), 5*time.Millisecond) {
+		batches++
+		for _, entry := range batch {
+			ports = append(ports, entry.DstPort)
+		}
+	}
+	elapsed := time.Since(begin)
+	if len(ports) != 5 || ports[0] != 1 || ports[4] != 5 {
+		t.Fatalf("expected 5 entries in order, got %v", ports)
+	}
+	if batches < 3 {
+		t.Errorf("expected the entries to be spread over at least 3 batches, got %d", batches)
+	}
+	if elapsed < 40*time.Millisecond || elapsed > 5*time.Second {
+		t.Errorf("expected the replay to take about 40ms, took %s", elapsed)
+	}
+
+	// stops once ctx is done
+	s, err = NewStream(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := NewReplayer(s, 0.001).Watch(ctx, time.Millisecond)
+	if batch := <-ch; len(batch) != 2 {
+		t.Fatalf("expected the 2 entries logged first, got %d", len(batch))
+	}
+	cancel()
+	for range ch {
+		// drained until closed
+	}
+}
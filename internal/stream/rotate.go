@@ -0,0 +1,122 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stream
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// rotationFile is a candidate rotated log file
+type rotationFile struct {
+	modTime time.Time // modification time
+	path    string    // resolved path
+}
+
+// rotationName returns the name of a log file without digits (the name shared by all its rotations,
+// e.g. filter_.log for filter_20251010.log and filter.log for filter.log.1)
+func rotationName(path string) string {
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return -1
+		}
+		return r
+	}, filepath.Base(path))
+	return strings.TrimRight(name, ".")
+}
+
+// public
+
+// Follow creates a follower for the log file of s (starting at the end) with the same hook and origin as s
+func (s *Stream) Follow() (*Follower, error) {
+	f, err := NewFollower(s.path, false)
+	if err != nil {
+		return nil, err
+	}
+	f.SetHook(s.hook)
+	f.SetOrigin(s.origin)
+	return f, nil
+}
+
+// Open opens another log file with the same hook, origin and error handler as s
+func (s *Stream) Open(path string) (*Stream, error) {
+	file, temp, err := openLog(path)
+	if err != nil {
+		return nil, fmt.Errorf("error(stream): %w", err)
+	}
+	return &Stream{
+		errors:  make([]string, 0),
+		file:    file,
+		hook:    s.hook,
+		onError: s.onError,
+		origin:  s.origin,
+		path:    path,
+		scanner: bufio.NewScanner(file),
+		temp:    temp,
+	}, nil
+}
+
+// Rotations returns the rotated log files of path in chronological order (path included, symlinks such as
+// latest.log are resolved), rotations are the files in the same directory whose names only differ in digits
+func Rotations(path string) ([]string, error) {
+	path, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return nil, fmt.Errorf("error(stream): %w", err)
+	}
+	dir, name := filepath.Dir(path), rotationName(path)
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error(stream): %w", err)
+	}
+	files := make([]rotationFile, 0, len(dirEntries))
+	for _, dirEntry := range dirEntries {
+		if rotationName(dirEntry.Name()) != name {
+			continue
+		}
+		resolved, err := filepath.EvalSymlinks(filepath.Join(dir, dirEntry.Name()))
+		if err != nil || slices.ContainsFunc(files, func(f rotationFile) bool { return f.path == resolved }) {
+			continue
+		}
+		info, err := os.Stat(resolved)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		files = append(files, rotationFile{modTime: info.ModTime(), path: resolved})
+	}
+	slices.SortFunc(files, func(a, b rotationFile) int {
+		return cmp.Or(a.modTime.Compare(b.modTime), cmp.Compare(a.path, b.path))
+	})
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = f.path
+	}
+	return paths, nil
+}
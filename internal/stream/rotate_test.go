@@ -0,0 +1,172 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stream
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotations(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	for i, name := range []string{
+		"filter_20251008.log",
+		"filter_20251009.log",
+		"filter_20251010.log",
+		"other_20251010.log",
+		"filter.log",
+		"filter.log.1",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		modTime := now.Add(time.Duration(i) * time.Hour)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// filter_20251009.log is modified after filter_20251010.log (e.g. by a late write before rotation)
+	late := now.Add(3 * time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "filter_20251009.log"), late, late); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("filter_20251010.log", filepath.Join(dir, "latest.log")); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		path   string
+		expect []string
+	}{
+		{
+			name:   "dated files",
+			path:   "filter_20251008.log",
+			expect: []string{"filter_20251008.log", "filter_20251010.log", "filter_20251009.log"},
+		},
+		{
+			name:   "symlink",
+			path:   "latest.log",
+			expect: []string{"filter_20251008.log", "filter_20251010.log", "filter_20251009.log"},
+		},
+		{
+			name:   "numbered files",
+			path:   "filter.log",
+			expect: []string{"filter.log", "filter.log.1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths, err := Rotations(filepath.Join(dir, tt.path))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			resolved, err := filepath.EvalSymlinks(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expect := make([]string, len(tt.expect))
+			for i, name := range tt.expect {
+				expect[i] = filepath.Join(resolved, name)
+			}
+			if !slices.Equal(paths, expect) {
+				t.Errorf("expected %v, got %v", expect, paths)
+			}
+		})
+	}
+	if _, err := Rotations(filepath.Join(dir, "missing.log")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestOpen(t *testing.T) {
+	s, err := NewStream("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.SetOrigin("fw1")
+	o, err := s.Open("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer o.Close()
+	entry := o.Next()
+	if entry == nil {
+		t.Fatal("expected entry")
+	}
+	if entry.Origin != "fw1" {
+		t.Errorf("expected origin fw1, got %q", entry.Origin)
+	}
+	if _, err := s.Open("../../tests/missing.log"); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestFollow(t *testing.T) {
+	data, err := os.ReadFile("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	path := filepath.Join(t.TempDir(), "filter.log")
+	if err := os.WriteFile(path, []byte(lines[0]), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewStream(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.SetOrigin("fw1")
+	s.SetHook(func(entry *LogEntry) { entry.Severity = SeverityCritical })
+	f, err := s.Follow()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer f.Close()
+	if entry := f.Next(); entry != nil {
+		t.Fatalf("expected no entry before append, got %v", entry.Time)
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.WriteString(lines[1]); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	entry := f.Next()
+	if entry == nil {
+		t.Fatal("expected entry after append")
+	}
+	if entry.Origin != "fw1" || entry.Severity != SeverityCritical {
+		t.Errorf("expected origin fw1 and severity critical, got %q and %q", entry.Origin, entry.Severity)
+	}
+}
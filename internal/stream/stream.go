@@ -25,23 +25,32 @@ package stream
 
 import (
 	"bufio"
+	"bytes"
+	"cmp"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/debuglog"
 )
 
 const (
 	MaxErrorsInMemory = 1000
 
 	// actions
-	actionBinat        = "binat"
+	ActionBinat        = "binat"
 	ActionBlock        = "block"
-	actionNat          = "nat"
+	ActionNat          = "nat"
 	ActionPass         = "pass"
-	actionRdr          = "rdr"
+	ActionRdr          = "rdr"
 	actionScrub        = "scrub"
 	actionSynproxyDrop = "synproxy-drop"
 
@@ -76,53 +85,168 @@ const (
 	reasonStateLimit    = "state-limit"
 	reasonStateMismatch = "state-mismatch"
 	reasonSynproxy      = "synproxy"
+
+	// severities (in ascending order)
+	SeverityInfo     = "info"
+	SeverityNotice   = "notice"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
 )
 
 // LogEntry represents a parsed filter log entry
 type LogEntry struct {
 	// common
-	Action    string    `json:"action"` // action taken
-	Direction string    `json:"dir"`    // traffic direction
-	Interface string    `json:"iface"`  // network interface
-	Reason    string    `json:"reason"` // reason for action
-	Time      time.Time `json:"time"`   // timestamp
+	Action    string    `json:"action"`             // action taken
+	Direction string    `json:"dir"`                // traffic direction
+	Interface string    `json:"iface"`              // network interface
+	Origin    string    `json:"origin,omitempty"`   // firewall or file the entry was read from
+	Reason    string    `json:"reason"`             // reason for action
+	Severity  string    `json:"severity,omitempty"` // severity level assigned by the classification rules
+	Time      time.Time `json:"time"`               // timestamp
 
 	// ip
-	Dst       string `json:"dst"`   // destination ip address
-	IPVersion uint8  `json:"ipver"` // ip protocol version
-	ProtoName string `json:"proto"` // protocol name
-	Src       string `json:"src"`   // source ip address
+	Class     string `json:"class,omitempty"`     // traffic class, e.g. 0x00 (ipv6, if logged)
+	Dst       string `json:"dst"`                 // destination ip address
+	DstClass  string `json:"dstclass,omitempty"`  // class of the destination address (e.g. private, assigned by the classification hook)
+	ECN       string `json:"ecn,omitempty"`       // explicit congestion notification (ipv4, if logged)
+	FlowLabel string `json:"flowlabel,omitempty"` // flow label, e.g. 0xfd492 (ipv6, if logged)
+	HopLimit  uint8  `json:"hoplimit,omitempty"`  // hop limit (ipv6, if logged)
+	ID        uint16 `json:"id,omitempty"`        // identification (ipv4, if logged)
+	IPFlags   string `json:"ipflags,omitempty"`   // flags, e.g. DF or none (ipv4, if logged)
+	IPVersion uint8  `json:"ipver"`               // ip protocol version
+	Length    uint32 `json:"length,omitempty"`    // total length of the packet in bytes (ipv6: payload length, if logged)
+	Offset    uint16 `json:"offset,omitempty"`    // fragment offset (ipv4, if logged)
+	ProtoName string `json:"proto"`               // protocol name
+	ProtoNum  uint8  `json:"protonum,omitempty"`  // protocol number (e.g. 112 for carp, if logged)
+	Src       string `json:"src"`                 // source ip address
+	SrcClass  string `json:"srcclass,omitempty"`  // class of the source address
+	TOS       string `json:"tos,omitempty"`       // type of service, e.g. 0x0 (ipv4, if logged)
+	TTL       uint8  `json:"ttl,omitempty"`       // time to live (ipv4, if logged)
 
 	// protocol
-	DstPort uint16 `json:"dport,omitempty"` // destination port
-	SrcPort uint16 `json:"sport,omitempty"` // source port
+	DataLen    uint32 `json:"datalen,omitempty"`   // length of the data in bytes (tcp and udp, if logged)
+	ProtoData  string `json:"protodata,omitempty"` // values following the addresses separated by commas as logged (protocols other than tcp, udp and icmp, e.g. carp: type, ttl, vhid, version, advskew, advbase)
+	DstPort    uint16 `json:"dport,omitempty"`     // destination port
+	ICMPType   string `json:"icmptype,omitempty"`  // icmp type (icmp and ipv6-icmp, if logged)
+	SrcPort    uint16 `json:"sport,omitempty"`     // source port
+	TCPAck     string `json:"ack,omitempty"`       // acknowledgment number (tcp, if logged)
+	TCPFlags   string `json:"tcpflags,omitempty"`  // flags as letters, e.g. S for syn or SA for syn-ack (tcp)
+	TCPOptions string `json:"tcpopts,omitempty"`   // options separated by semicolons, e.g. mss;nop;wscale (tcp, if logged)
+	TCPSeq     string `json:"seq,omitempty"`       // sequence number (tcp, if logged)
+	TCPUrg     string `json:"urg,omitempty"`       // urgent pointer (tcp, if logged)
+	TCPWindow  string `json:"window,omitempty"`    // window size (tcp)
+
+	// rule
+	Anchor     string `json:"anchor,omitempty"`    // anchor the matching rule belongs to (empty for rules of the main ruleset)
+	Label      string `json:"label,omitempty"`     // label of the matching rule (tracker id)
+	RuleNum    string `json:"rulenr,omitempty"`    // number of the matching rule
+	SubRuleNum string `json:"subrulenr,omitempty"` // number of the matching rule within its anchor
+
+	// plugin
+	Extra map[string]string `json:"extra,omitempty"` // extra fields added by the enrichment plugin
 }
 
+// FieldNames lists the names of all fields that can be looked up using LogEntry.Field (in display order)
+var FieldNames = []string{"time", "origin", "severity", "action", "dir", "iface", "reason", "ipver", "proto", "protonum", "length", "tos", "ecn", "ttl", "id", "offset", "ipflags", "class", "flowlabel", "hoplimit", "src", "srcclass", "sport", "dst", "dstclass", "dport", "datalen", "icmptype", "tcpflags", "seq", "ack", "window", "urg", "tcpopts", "protodata", "rulenr", "subrulenr", "anchor", "label"}
+
+// ErrReplaced is returned if the file at the path is not the indexed file anymore (e.g. rotated by syslogd,
+// the offsets of the index don't apply to it, ExtendIndex starts over)
+var ErrReplaced = errors.New("file replaced")
+
+// Severities lists all severity levels in ascending order
+var Severities = []string{SeverityInfo, SeverityNotice, SeverityWarning, SeverityCritical}
+
 // indexEntry represents an entry in the index
 type indexEntry struct {
-	lineNum    int   // line number
+	lineNum    int   // number of lines (valid or not) before the entry
 	lineOffset int64 // byte offset
 }
 
+// ParseError describes why a line could not be parsed
+type ParseError struct {
+	Line   int    // line number (0 if the line was not read from a file)
+	Reason string // what is invalid (e.g. "invalid timestamp")
+	Err    error  // underlying error (if any)
+}
+
+// message returns the error message without prefix (as collected for GetErrors)
+func (e *ParseError) message() string {
+	msg := e.Reason
+	if e.Line > 0 {
+		msg += fmt.Sprintf(" on line %d", e.Line)
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Error returns the error message
+func (e *ParseError) Error() string {
+	return "error(stream): " + e.message()
+}
+
+// Unwrap returns the underlying error
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
 // Stream represents a streaming log parser
 type Stream struct {
-	errors  []string       // parsing errors
-	file    *os.File       // file handle
-	index   []indexEntry   // index of line positions
-	lineNum int            // current line number
-	path    string         // file path
-	scanner *bufio.Scanner // file scanner
+	columnar  bool            // whether to record columns while indexing
+	columns   *Columns        // metadata of every indexed line (nil if not recorded)
+	consumed  map[int]bool    // csv positions read by the last parse (nil unless auditing fields)
+	errors    []string        // parsing errors (collected if onError is nil)
+	lastErr   *ParseError     // error of the last line that could not be parsed
+	file      *os.File        // file handle
+	hook      func(*LogEntry) // called for every parsed entry (nil if none)
+	index     []indexEntry    // index of line positions
+	indexDev  uint64          // device of the indexed file
+	indexEnd  int64           // byte offset after the last complete indexed line
+	indexIno  uint64          // inode of the indexed file
+	indexNum  int             // number of lines (valid or not) before indexEnd
+	indexSize int64           // size of the file when it was last indexed
+	mmap      bool            // whether to map the file into memory after indexing
+	mu        sync.RWMutex    // guards index, mapped and errors (indexing may run while reading)
+	offset    atomic.Int64    // byte offset after the line last read by Next (see Offset)
+	onError   func(error)     // called for every error instead of collecting it (nil if none)
+	lineNum   int             // current line number
+	mapped    *mapping        // memory-mapped file (nil if not mapped, shared with clones)
+	origin    string          // origin of all entries (hostname of each line if empty)
+	path      string          // file path (paths separated by commas if merged)
+	paths     []string        // paths of the merged files (nil unless merged, see NewMergedStream)
+	scanner   *bufio.Scanner  // file scanner
+	shared    bool            // whether the index and the decompressed copy belong to another stream (see Clone)
+	temp      string          // decompressed copy of a compressed file that is read instead (empty if none)
+	termed    bool            // whether to record terms while indexing
+	terms     []termBlock     // trigrams of every block of indexed lines (nil if not recorded)
 }
 
 // parsing
 
-// addError adds a parsing error to the errors slice
-func (s *Stream) addError(msg string) {
+// addError passes an error to the error handler or adds it to the errors slice
+func (s *Stream) addError(err error) {
+	if s.onError != nil {
+		s.onError(err)
+		return
+	}
+	msg := err.Error()
+	if perr, ok := err.(*ParseError); ok {
+		msg = perr.message()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	if len(s.errors) < MaxErrorsInMemory {
 		s.errors = append(s.errors, msg)
 	}
 }
 
+// parseError records why the line could not be parsed
+func (s *Stream) parseError(lineNum int, reason string, err error) {
+	s.lastErr = &ParseError{Line: lineNum, Reason: reason, Err: err}
+	s.addError(s.lastErr)
+}
+
 // extractCSVField extracts a csv field and returns a copy
 func extractCSVField(csv string, field int) (string, bool) {
 	start := 0
@@ -144,66 +268,146 @@ func extractCSVField(csv string, field int) (string, bool) {
 	return strings.Clone(csv[start : start+end]), true
 }
 
+// csvField extracts a csv field (and records the position as consumed if auditing fields)
+func (s *Stream) csvField(csv string, field int) (string, bool) {
+	if s.consumed != nil {
+		s.consumed[field] = true
+	}
+	return extractCSVField(csv, field)
+}
+
+// csvNumber extracts an unsigned number of bitSize bits (0 if it's not logged or invalid, for informational fields)
+func (s *Stream) csvNumber(csv string, field int, bitSize int) uint64 {
+	value, _ := s.csvField(csv, field)
+	number, err := strconv.ParseUint(value, 10, bitSize)
+	if err != nil {
+		return 0
+	}
+	return number
+}
+
+// csvRest extracts the csv fields from field to the end of the line as logged (empty if there are none)
+func (s *Stream) csvRest(csv string, field int) string {
+	start := 0
+	for range field {
+		idx := strings.IndexByte(csv[start:], ',')
+		if idx == -1 {
+			return ""
+		}
+		start += idx + 1
+	}
+	if s.consumed != nil {
+		for i := range strings.Count(csv[start:], ",") + 1 {
+			s.consumed[field+i] = true
+		}
+	}
+	return strings.Clone(csv[start:])
+}
+
+// csvLength extracts a length in bytes (0 if it's not logged or invalid, lengths are informational)
+func (s *Stream) csvLength(csv string, field int) uint32 {
+	return uint32(s.csvNumber(csv, field, 32))
+}
+
+// parseTCP extracts the tcp fields following the ports (all optional, the header is not always logged in full)
+func (s *Stream) parseTCP(csv string, flags int, entry *LogEntry) {
+	entry.TCPFlags, _ = s.csvField(csv, flags)
+	entry.TCPSeq, _ = s.csvField(csv, flags+1)
+	entry.TCPAck, _ = s.csvField(csv, flags+2)
+	entry.TCPWindow, _ = s.csvField(csv, flags+3)
+	entry.TCPUrg, _ = s.csvField(csv, flags+4)
+	entry.TCPOptions, _ = s.csvField(csv, flags+5)
+}
+
+// trimLine removes a byte order mark and carriage return (logs copied via windows tools)
+func trimLine(line string) string {
+	line = strings.TrimPrefix(line, "\ufeff")
+	return strings.TrimSuffix(line, "\r")
+}
+
 // parse parses a single line and returns a LogEntry
 func (s *Stream) parse(line string, lineNum int) *LogEntry {
+	line = trimLine(line)
 	// extract the timestamp (between 1st and 2nd space)
 	timestampStart := strings.IndexByte(line, ' ') + 1 // +1 for 1st space
 	timestampEnd := strings.IndexByte(line[timestampStart:], ' ')
 	if timestampStart <= 0 || timestampEnd == -1 {
-		s.addError(fmt.Sprintf("invalid timestamp on line %d", lineNum))
+		s.parseError(lineNum, "invalid timestamp", nil)
 		return nil
 	}
 	timestampEnd += timestampStart // make relative index absolute
 	timestamp, err := time.Parse(time.RFC3339, line[timestampStart:timestampEnd])
 	if err != nil {
-		s.addError(fmt.Sprintf("invalid timestamp on line %d: %v", lineNum, err))
+		s.parseError(lineNum, "invalid timestamp", err)
 		// TODO: maybe we should just show a random timestamp instead of failing?
 		return nil
 	}
 
+	// extract the hostname (between 2nd and 3rd space, "-" if unknown)
+	origin := s.origin
+	if hostnameEnd := strings.IndexByte(line[timestampEnd+1:], ' '); origin == "" && hostnameEnd > 0 {
+		if hostname := line[timestampEnd+1 : timestampEnd+1+hostnameEnd]; hostname != "-" {
+			origin = strings.Clone(hostname)
+		}
+	}
+
 	// extract the csv data (after "] ")
 	csvStart := strings.Index(line, "] ")
 	if csvStart == -1 {
-		s.addError(fmt.Sprintf("invalid csv on line %d", lineNum))
+		s.parseError(lineNum, "invalid csv", nil)
 		return nil
 	}
 	csv := line[csvStart+2:] // +2 for "] "
 
-	// extract CSV fields
-	// 3: label, 4: interface, 5: reason, 6: action, 7: direction, 8: ipversion
-	iface, ok := extractCSVField(csv, 4)
+	// extract CSV fields (positions depend on the format version)
+	cols := detectFormat(csv)
+	iface, ok := s.csvField(csv, cols.iface)
 	if !ok {
-		s.addError(fmt.Sprintf("invalid iface on line %d", lineNum))
+		s.parseError(lineNum, "invalid iface", nil)
 		return nil
 	}
 
-	reason, ok := extractCSVField(csv, 5)
+	// all exist if the interface does
+	ruleNum, _ := s.csvField(csv, cols.ruleNum)
+	subRuleNum, _ := s.csvField(csv, cols.subRuleNum)
+	anchor, _ := s.csvField(csv, cols.anchor)
+	var label string
+	if cols.label >= 0 {
+		label, _ = s.csvField(csv, cols.label)
+	}
+
+	reason, ok := s.csvField(csv, cols.reason)
 	if !ok {
-		s.addError(fmt.Sprintf("invalid reason on line %d", lineNum))
+		s.parseError(lineNum, "invalid reason", nil)
 		return nil
 	}
 
-	action, ok := extractCSVField(csv, 6)
+	action, ok := s.csvField(csv, cols.action)
 	if !ok {
-		s.addError(fmt.Sprintf("invalid action on line %d", lineNum))
+		s.parseError(lineNum, "invalid action", nil)
 		return nil
 	}
 
-	direction, ok := extractCSVField(csv, 7)
+	direction, ok := s.csvField(csv, cols.direction)
 	if !ok {
-		s.addError(fmt.Sprintf("invalid direction on line %d", lineNum))
+		s.parseError(lineNum, "invalid direction", nil)
 		return nil
 	}
 
-	ipVersion, ok := extractCSVField(csv, 8)
+	ipVersion, ok := s.csvField(csv, cols.ipVersion)
 	if !ok {
-		s.addError(fmt.Sprintf("invalid ipVersion on line %d", lineNum))
+		s.parseError(lineNum, "invalid ipVersion", nil)
 		return nil
 	}
 
 	entry := LogEntry{
-		Time:      timestamp,
-		Interface: iface,
+		Time:       timestamp,
+		Interface:  iface,
+		Origin:     origin,
+		Anchor:     anchor,
+		Label:      label,
+		RuleNum:    ruleNum,
+		SubRuleNum: subRuleNum,
 	}
 
 	switch reason {
@@ -246,12 +450,12 @@ func (s *Stream) parse(line string, lineNum int) *LogEntry {
 		entry.Action = ActionPass
 	case ActionBlock:
 		entry.Action = ActionBlock
-	case actionBinat:
-		entry.Action = actionBinat
-	case actionNat:
-		entry.Action = actionNat
-	case actionRdr:
-		entry.Action = actionRdr
+	case ActionBinat:
+		entry.Action = ActionBinat
+	case ActionNat:
+		entry.Action = ActionNat
+	case ActionRdr:
+		entry.Action = ActionRdr
 	case actionScrub:
 		entry.Action = actionScrub
 	case actionSynproxyDrop:
@@ -279,7 +483,7 @@ func (s *Stream) parse(line string, lineNum int) *LogEntry {
 	default:
 		ipVersion, err := strconv.ParseUint(ipVersion, 10, 8)
 		if err != nil {
-			s.addError(fmt.Sprintf("invalid ipVersion on line %d", lineNum))
+			s.parseError(lineNum, "invalid ipVersion", nil)
 			return nil
 		}
 		entry.IPVersion = uint8(ipVersion)
@@ -288,26 +492,33 @@ func (s *Stream) parse(line string, lineNum int) *LogEntry {
 	switch entry.IPVersion {
 	// ipv4
 	case ipVersion4:
-		// 9:tos, 10:ecn, 11:ttl, 12:id, 13:offset, 14:flags, 15:protonum, 16:protoname, 17:length, 18:src, 19:dst
-		protoName, ok := extractCSVField(csv, 16)
+		protoName, ok := s.csvField(csv, cols.ipv4.protoName)
 		if !ok {
-			s.addError(fmt.Sprintf("invalid v4/protoName on line %d", lineNum))
+			s.parseError(lineNum, "invalid v4/protoName", nil)
 			return nil
 		}
 
-		src, ok := extractCSVField(csv, 18)
+		src, ok := s.csvField(csv, cols.ipv4.src)
 		if !ok {
-			s.addError(fmt.Sprintf("invalid v4/src on line %d", lineNum))
+			s.parseError(lineNum, "invalid v4/src", nil)
 			return nil
 		}
 		entry.Src = src
 
-		dst, ok := extractCSVField(csv, 19)
+		dst, ok := s.csvField(csv, cols.ipv4.dst)
 		if !ok {
-			s.addError(fmt.Sprintf("invalid v4/dst on line %d", lineNum))
+			s.parseError(lineNum, "invalid v4/dst", nil)
 			return nil
 		}
 		entry.Dst = dst
+		entry.Length = s.csvLength(csv, cols.ipv4.length)
+		entry.TOS, _ = s.csvField(csv, cols.ipv4.tos)
+		entry.ECN, _ = s.csvField(csv, cols.ipv4.ecn)
+		entry.TTL = uint8(s.csvNumber(csv, cols.ipv4.ttl, 8))
+		entry.ID = uint16(s.csvNumber(csv, cols.ipv4.id, 16))
+		entry.Offset = uint16(s.csvNumber(csv, cols.ipv4.offset, 16))
+		entry.IPFlags, _ = s.csvField(csv, cols.ipv4.flags)
+		entry.ProtoNum = uint8(s.csvNumber(csv, cols.ipv4.protoNum, 8))
 
 		switch protoName {
 		case protoTCP:
@@ -323,86 +534,96 @@ func (s *Stream) parse(line string, lineNum int) *LogEntry {
 		switch entry.ProtoName {
 		// udp4
 		case protoUDP:
-			// 20: srcport, 21: dstport, 22: datalen
-			srcPortStr, ok := extractCSVField(csv, 20)
+			srcPortStr, ok := s.csvField(csv, cols.ipv4.srcPort)
 			if !ok {
-				s.addError(fmt.Sprintf("invalid udp4/srcPortStr on line %d", lineNum))
+				s.parseError(lineNum, "invalid udp4/srcPortStr", nil)
 				return nil
 			}
 			srcPort, err := strconv.ParseUint(srcPortStr, 10, 16)
 			if err != nil {
-				s.addError(fmt.Sprintf("invalid udp4/srcPort on line %d", lineNum))
+				s.parseError(lineNum, "invalid udp4/srcPort", nil)
 				return nil
 			}
 
-			dstPortStr, ok := extractCSVField(csv, 21)
+			dstPortStr, ok := s.csvField(csv, cols.ipv4.dstPort)
 			if !ok {
-				s.addError(fmt.Sprintf("invalid udp4/dstPortStr on line %d", lineNum))
+				s.parseError(lineNum, "invalid udp4/dstPortStr", nil)
 				return nil
 			}
 			dstPort, err := strconv.ParseUint(dstPortStr, 10, 16)
 			if err != nil {
-				s.addError(fmt.Sprintf("invalid udp4/dstPort on line %d", lineNum))
+				s.parseError(lineNum, "invalid udp4/dstPort", nil)
 				return nil
 			}
 
 			entry.SrcPort = uint16(srcPort)
 			entry.DstPort = uint16(dstPort)
+			entry.DataLen = s.csvLength(csv, cols.ipv4.dataLen)
 
 		// tcp4
 		case protoTCP:
-			// 20: srcport, 21: dstport, 22: datalen, 23: flags, 24: seq, 25: ack, 26: window, 27: urg, 28: options
-			srcPortStr, ok := extractCSVField(csv, 20)
+			srcPortStr, ok := s.csvField(csv, cols.ipv4.srcPort)
 			if !ok {
-				s.addError(fmt.Sprintf("invalid tcp4/srcPortStr on line %d", lineNum))
+				s.parseError(lineNum, "invalid tcp4/srcPortStr", nil)
 				return nil
 			}
 			srcPort, err := strconv.ParseUint(srcPortStr, 10, 16)
 			if err != nil {
-				s.addError(fmt.Sprintf("invalid tcp4/srcPort on line %d", lineNum))
+				s.parseError(lineNum, "invalid tcp4/srcPort", nil)
 				return nil
 			}
 
-			dstPortStr, ok := extractCSVField(csv, 21)
+			dstPortStr, ok := s.csvField(csv, cols.ipv4.dstPort)
 			if !ok {
-				s.addError(fmt.Sprintf("invalid tcp4/dstPortStr on line %d", lineNum))
+				s.parseError(lineNum, "invalid tcp4/dstPortStr", nil)
 				return nil
 			}
 			dstPort, err := strconv.ParseUint(dstPortStr, 10, 16)
 			if err != nil {
-				s.addError(fmt.Sprintf("invalid tcp4/dstPort on line %d", lineNum))
+				s.parseError(lineNum, "invalid tcp4/dstPort", nil)
 				return nil
 			}
 
 			entry.SrcPort = uint16(srcPort)
 			entry.DstPort = uint16(dstPort)
+			entry.DataLen = s.csvLength(csv, cols.ipv4.dataLen)
+			s.parseTCP(csv, cols.ipv4.tcpFlags, &entry)
 
-		// skip for any other protocol
+		// icmp4 (the type is optional)
+		case protoICMP:
+			entry.ICMPType, _ = s.csvField(csv, cols.ipv4.icmpType)
+
+		// any other protocol (e.g. esp, gre, carp, igmp, ospf, pfsync)
 		default:
+			entry.ProtoData = s.csvRest(csv, cols.ipv4.protoData)
 		}
 
 	// ipv6
 	case ipVersion6:
-		// 9:class, 10:flow, 11:hoplimit, 12:protoname, 13:protonum, 14:length, 15:src, 16:dst
-		protoName, ok := extractCSVField(csv, 12)
+		protoName, ok := s.csvField(csv, cols.ipv6.protoName)
 		if !ok {
-			s.addError(fmt.Sprintf("invalid v6/protoName on line %d", lineNum))
+			s.parseError(lineNum, "invalid v6/protoName", nil)
 			return nil
 		}
 
-		src, ok := extractCSVField(csv, 15)
+		src, ok := s.csvField(csv, cols.ipv6.src)
 		if !ok {
-			s.addError(fmt.Sprintf("invalid v6/src on line %d", lineNum))
+			s.parseError(lineNum, "invalid v6/src", nil)
 			return nil
 		}
 		entry.Src = src
 
-		dst, ok := extractCSVField(csv, 16)
+		dst, ok := s.csvField(csv, cols.ipv6.dst)
 		if !ok {
-			s.addError(fmt.Sprintf("invalid v6/dst on line %d", lineNum))
+			s.parseError(lineNum, "invalid v6/dst", nil)
 			return nil
 		}
 		entry.Dst = dst
+		entry.Length = s.csvLength(csv, cols.ipv6.length)
+		entry.Class, _ = s.csvField(csv, cols.ipv6.class)
+		entry.FlowLabel, _ = s.csvField(csv, cols.ipv6.flow)
+		entry.HopLimit = uint8(s.csvNumber(csv, cols.ipv6.ttl, 8))
+		entry.ProtoNum = uint8(s.csvNumber(csv, cols.ipv6.protoNum, 8))
 
 		switch protoName {
 		case protoTCP:
@@ -419,146 +640,462 @@ func (s *Stream) parse(line string, lineNum int) *LogEntry {
 
 		// udp6
 		case protoUDP:
-			// 17: srcport, 18: dstport, 19: datalen
-			srcPortStr, ok := extractCSVField(csv, 17)
+			srcPortStr, ok := s.csvField(csv, cols.ipv6.srcPort)
 			if !ok {
-				s.addError(fmt.Sprintf("invalid udp6/srcPortStr on line %d", lineNum))
+				s.parseError(lineNum, "invalid udp6/srcPortStr", nil)
 				return nil
 			}
 			srcPort, err := strconv.ParseUint(srcPortStr, 10, 16)
 			if err != nil {
-				s.addError(fmt.Sprintf("invalid udp6/srcPort on line %d", lineNum))
+				s.parseError(lineNum, "invalid udp6/srcPort", nil)
 				return nil
 			}
 
-			dstPortStr, ok := extractCSVField(csv, 18)
+			dstPortStr, ok := s.csvField(csv, cols.ipv6.dstPort)
 			if !ok {
-				s.addError(fmt.Sprintf("invalid udp6/dstPortStr on line %d", lineNum))
+				s.parseError(lineNum, "invalid udp6/dstPortStr", nil)
 				return nil
 			}
 			dstPort, err := strconv.ParseUint(dstPortStr, 10, 16)
 			if err != nil {
-				s.addError(fmt.Sprintf("invalid udp6/dstPort on line %d", lineNum))
+				s.parseError(lineNum, "invalid udp6/dstPort", nil)
 				return nil
 			}
 
 			entry.SrcPort = uint16(srcPort)
 			entry.DstPort = uint16(dstPort)
+			entry.DataLen = s.csvLength(csv, cols.ipv6.dataLen)
 
 		// tcp6
 		case protoTCP:
-			// 17: srcport, 18: dstport, 19: datalen, 20: flags, 21: seq, 22: ack, 23: window, 24: urg, 25: options
-			srcPortStr, ok := extractCSVField(csv, 17)
+			srcPortStr, ok := s.csvField(csv, cols.ipv6.srcPort)
 			if !ok {
-				s.addError(fmt.Sprintf("invalid tcp6/srcPortStr on line %d", lineNum))
+				s.parseError(lineNum, "invalid tcp6/srcPortStr", nil)
 				return nil
 			}
 			srcPort, err := strconv.ParseUint(srcPortStr, 10, 16)
 			if err != nil {
-				s.addError(fmt.Sprintf("invalid tcp6/srcPort on line %d", lineNum))
+				s.parseError(lineNum, "invalid tcp6/srcPort", nil)
 				return nil
 			}
 
-			dstPortStr, ok := extractCSVField(csv, 18)
+			dstPortStr, ok := s.csvField(csv, cols.ipv6.dstPort)
 			if !ok {
-				s.addError(fmt.Sprintf("invalid tcp6/dstPortStr on line %d", lineNum))
+				s.parseError(lineNum, "invalid tcp6/dstPortStr", nil)
 				return nil
 			}
 			dstPort, err := strconv.ParseUint(dstPortStr, 10, 16)
 			if err != nil {
-				s.addError(fmt.Sprintf("invalid tcp6/dstPort on line %d", lineNum))
+				s.parseError(lineNum, "invalid tcp6/dstPort", nil)
 				return nil
 			}
 
 			entry.SrcPort = uint16(srcPort)
 			entry.DstPort = uint16(dstPort)
+			entry.DataLen = s.csvLength(csv, cols.ipv6.dataLen)
+			s.parseTCP(csv, cols.ipv6.tcpFlags, &entry)
 
-		// skip for any other protocol
+		// icmp6 (the type is optional)
+		case protoICMPv6:
+			entry.ICMPType, _ = s.csvField(csv, cols.ipv6.icmpType)
+
+		// any other protocol
 		default:
+			entry.ProtoData = s.csvRest(csv, cols.ipv6.protoData)
 		}
 
 	default:
-		s.addError(fmt.Sprintf("invalid ipVersion '%d' on line %d", entry.IPVersion, lineNum))
+		s.parseError(lineNum, fmt.Sprintf("invalid ipVersion '%d'", entry.IPVersion), nil)
 		return nil
 	}
 
+	if s.hook != nil {
+		s.hook(&entry)
+	}
 	return &entry
 }
 
 // stream
 
-// reset repositions the stream to the start of the file
-func (s *Stream) reset() error {
-	if s.file != nil {
-		s.file.Close()
+// isIndexed returns true if file is the indexed file (or nothing has been indexed yet)
+func (s *Stream) isIndexed(file *os.File) bool {
+	info, err := file.Stat()
+	if err != nil {
+		return false
 	}
-	file, err := os.Open(s.path)
+	dev, ino := fileIdentity(info)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.index == nil || dev == s.indexDev && ino == s.indexIno
+}
+
+// openIndexed opens the file at the path and checks that it is the indexed file (ErrReplaced otherwise)
+func (s *Stream) openIndexed() (*os.File, error) {
+	file, err := openFile(s.Source())
 	if err != nil {
+		return nil, err
+	}
+	if !s.isIndexed(file) {
+		file.Close()
+		return nil, fmt.Errorf("%w since indexing %s", ErrReplaced, s.path)
+	}
+	return file, nil
+}
+
+// position seeks the open file to offset, it's only reopened if it's not the indexed file (reading it while
+// syslogd writes or rotates it is fine, ErrReplaced if the file at the path isn't the indexed file either)
+func (s *Stream) position(offset int64) error {
+	if s.file == nil || !s.isIndexed(s.file) {
+		file, err := s.openIndexed()
+		if err != nil {
+			return err
+		}
+		if s.file != nil {
+			s.file.Close()
+		}
+		s.file = file
+	}
+	if _, err := s.file.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	s.scanner = bufio.NewScanner(s.file)
+	s.offset.Store(offset)
+	return nil
+}
+
+// reset repositions the stream to the start of the file
+func (s *Stream) reset() error {
+	if err := s.position(0); err != nil {
 		return fmt.Errorf("error(stream): %w", err)
 	}
-	s.file = file
-	s.scanner = bufio.NewScanner(file)
 	s.lineNum = 0
 	return nil
 }
 
-// public
+// resetIndex drops the index of the file with the given identity (must hold mu)
+func (s *Stream) resetIndex(dev, ino uint64) {
+	s.index = make([]indexEntry, 0)
+	s.columns = nil
+	if s.columnar {
+		s.columns = &Columns{}
+	}
+	s.terms = nil
+	s.indexDev, s.indexIno = dev, ino
+	s.indexEnd, s.indexNum = 0, 0
+}
 
-// BuildIndex builds an index of line positions in the file
-func (s *Stream) BuildIndex() error {
-	if err := s.reset(); err != nil {
-		return err
+// extendIndex indexes up to maxLines lines (all if maxLines <= 0) following the last complete indexed line
+// (starts over if the file has been replaced or truncated, returns true once EOF is reached)
+func (s *Stream) extendIndex(maxLines int) (bool, error) {
+	file, err := openFile(s.Source())
+	if err != nil {
+		return false, fmt.Errorf("error(stream): %w", err)
 	}
-	lineIndexed := 0
-	lineNum := 0
-	lineOffset := int64(0)
-	s.index = make([]indexEntry, 0)
-	// parse the file and add positions of valid entries to the index
-	scanner := bufio.NewScanner(s.file)
-	for scanner.Scan() {
-		if entry := s.parse(scanner.Text(), lineNum); entry != nil {
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return false, fmt.Errorf("error(stream): %w", err)
+	}
+	s.mu.Lock()
+	if dev, ino := fileIdentity(info); s.index == nil || dev != s.indexDev || ino != s.indexIno || info.Size() < s.indexEnd {
+		s.resetIndex(dev, ino)
+	}
+	// drop the incomplete last line (if indexed), it's indexed again
+	n, _ := slices.BinarySearchFunc(s.index, s.indexEnd, func(e indexEntry, offset int64) int {
+		return cmp.Compare(e.lineOffset, offset)
+	})
+	s.index = s.index[:n]
+	if s.columns != nil {
+		s.columns.lines = s.columns.lines[:n]
+	}
+	s.indexSize = info.Size()
+	offset := s.indexEnd
+	s.mu.Unlock()
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return false, fmt.Errorf("error(stream): could not seek to offset %d: %w", offset, err)
+	}
+	start := time.Now()
+	done, err := s.scanIndex(file, maxLines)
+	debuglog.Debug("stream: indexed", "path", s.path, "offset", offset, "lines", s.TotalLines(), "done", done,
+		"duration", time.Since(start))
+	return done, err
+}
+
+// scanIndex parses up to maxLines lines (all if maxLines <= 0) read from r (starting at indexEnd) and adds
+// positions of valid entries to the index (returns true once EOF is reached)
+func (s *Stream) scanIndex(r io.Reader, maxLines int) (bool, error) {
+	// parse using another stream, s may be read meanwhile
+	p := &Stream{onError: s.addError, origin: s.origin}
+	s.mu.RLock()
+	lineNum := s.indexNum
+	lineOffset := s.indexEnd
+	s.mu.RUnlock()
+	scanner := bufio.NewScanner(r)
+	lineLen := 0        // length of the last line including line ending (\n or \r\n)
+	terminated := false // whether the last line ends with \n (an incomplete line is indexed again by ExtendIndex)
+	scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		advance, token, err := bufio.ScanLines(data, atEOF)
+		if token != nil {
+			lineLen = advance
+			terminated = data[advance-1] == '\n'
+		}
+		return advance, token, err
+	})
+	for lines := 0; maxLines <= 0 || lines < maxLines; lines++ {
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return false, fmt.Errorf("error(stream): could not build index due to scanner error: %w", err)
+			}
+			return true, nil
+		}
+		entry := p.parse(scanner.Text(), lineNum)
+		s.mu.Lock()
+		if entry != nil {
 			// it's valid, add to index
+			lineIndexed := len(s.index)
 			s.index = append(s.index, indexEntry{
-				lineNum:    lineIndexed,
+				lineNum:    lineNum,
 				lineOffset: lineOffset,
 			})
-			lineIndexed++
+			if s.columns != nil && !s.columns.add(entry) {
+				// too many distinct values, filter by parsing instead
+				s.columns = nil
+			}
+			if s.termed {
+				if len(s.terms) <= lineIndexed/TermBlockLines {
+					s.terms = append(s.terms, termBlock{})
+				}
+				s.terms[lineIndexed/TermBlockLines].add(entry)
+			}
 		}
-		lineOffset += int64(len(scanner.Bytes()) + 1) // +1 for newline
+		lineOffset += int64(lineLen)
 		lineNum++
+		if terminated {
+			s.indexEnd, s.indexNum = lineOffset, lineNum
+		}
+		s.mu.Unlock()
+	}
+	return false, nil
+}
+
+// mapping is a memory-mapped file that is unmapped once the last stream holding it releases it (clones keep
+// reading the previous mapping while the index is extended)
+type mapping struct {
+	data []byte       // mapped memory
+	refs atomic.Int32 // number of streams holding the mapping
+}
+
+// bytes returns the mapped memory (nil if m is nil)
+func (m *mapping) bytes() []byte {
+	if m == nil {
+		return nil
+	}
+	return m.data
+}
+
+// acquire adds a holder of the mapping (m may be nil)
+func (m *mapping) acquire() *mapping {
+	if m != nil {
+		m.refs.Add(1)
 	}
-	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("error(stream): could not build index due to scanner error: %w", err)
+	return m
+}
+
+// release removes a holder of the mapping and unmaps it once it was the last one (m may be nil)
+func (m *mapping) release() {
+	if m != nil && m.refs.Add(-1) == 0 {
+		munmapFile(m.data)
+	}
+}
+
+// remap maps the file into memory (replacing the previous mapping, falls back to reading the file on error)
+func (s *Stream) remap() {
+	s.unmap()
+	file, err := s.openIndexed()
+	if err != nil {
+		return
+	}
+	// the mapping remains valid after closing the file
+	defer file.Close()
+	if data, err := mmapFile(file); err == nil {
+		m := &mapping{data: data}
+		m.refs.Store(1)
+		s.mu.Lock()
+		s.mapped = m
+		s.mu.Unlock()
+	}
+}
+
+// unmap releases the mapping of the file (it's unmapped once no clone holds it anymore)
+func (s *Stream) unmap() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mapped.release()
+	s.mapped = nil
+}
+
+// public
+
+// BuildIndex builds an index of line positions in the file
+func (s *Stream) BuildIndex() error {
+	s.mu.Lock()
+	s.index = nil
+	s.mu.Unlock()
+	start := time.Now()
+	if err := s.ExtendIndex(); err != nil {
+		return err
+	}
+	debuglog.Info("stream: built index", "path", s.path, "lines", s.TotalLines(), "duration", time.Since(start))
+	return nil
+}
+
+// ExtendIndex adds the lines appended since the index was built (rebuilds the index if the file has been
+// replaced or truncated meanwhile, reading starts over from the beginning)
+func (s *Stream) ExtendIndex() error {
+	if _, err := s.extendIndex(0); err != nil {
+		return err
+	}
+	if err := s.reset(); err != nil {
+		return err
+	}
+	if s.mmap {
+		s.remap()
+	}
+	return nil
+}
+
+// IndexNext indexes up to the given number of lines following the indexed ones and returns true once the whole
+// file has been indexed (to read a large file while it is indexed in the background, the reading position is kept)
+func (s *Stream) IndexNext(lines int) (bool, error) {
+	done, err := s.extendIndex(lines)
+	if err == nil && done && s.mmap {
+		s.remap()
+	}
+	return done, err
+}
+
+// IndexProgress returns the share of the file that has been indexed (between 0 and 1)
+func (s *Stream) IndexProgress() float64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.index == nil {
+		return 0
+	}
+	if s.indexSize <= 0 {
+		return 1
 	}
-	return s.reset()
+	return min(float64(s.indexEnd)/float64(s.indexSize), 1)
 }
 
-// Close closes the log file
+// IndexIdentity returns the device and inode of the indexed file (zero if not available on this platform, positions
+// in the index only apply to this file)
+func (s *Stream) IndexIdentity() (uint64, uint64) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.indexDev, s.indexIno
+}
+
+// CheckpointAt returns the position before the indexed entry at pos (the end of the indexed lines if pos is out of
+// range), e.g. to follow the file from that entry on with NewFollowerAt
+func (s *Stream) CheckpointAt(pos int) Checkpoint {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	cp := Checkpoint{Dev: s.indexDev, Ino: s.indexIno, LineNum: s.indexNum, Offset: s.indexEnd}
+	if pos >= 0 && pos < len(s.index) {
+		cp.LineNum, cp.Offset = s.index[pos].lineNum, s.index[pos].lineOffset
+	}
+	return cp
+}
+
+// Clone opens another handle of the file that shares the index and the mapping (to read concurrently, must be closed
+// before s, the mapping stays valid for the clone if s is remapped meanwhile)
+func (s *Stream) Clone() (*Stream, error) {
+	file, err := s.openIndexed()
+	if err != nil {
+		return nil, fmt.Errorf("error(stream): %w", err)
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return &Stream{
+		columns:  s.columns,
+		errors:   make([]string, 0),
+		file:     file,
+		hook:     s.hook,
+		index:    s.index,
+		indexDev: s.indexDev,
+		indexIno: s.indexIno,
+		mapped:   s.mapped.acquire(),
+		onError:  s.onError,
+		origin:   s.origin,
+		path:     s.path,
+		paths:    s.paths,
+		scanner:  bufio.NewScanner(file),
+		shared:   true,
+		temp:     s.temp,
+		terms:    s.terms,
+	}, nil
+}
+
+// Close closes the log file (and removes the decompressed copy of a compressed file)
 func (s *Stream) Close() error {
+	s.unmap()
+	if s.temp != "" && !s.shared {
+		defer os.Remove(s.temp)
+	}
 	if s.file != nil {
 		return s.file.Close()
 	}
 	return nil
 }
 
-// GetPathAbs returns the absolute path of the log file
-func (s Stream) GetPathAbs() (string, error) {
-	return filepath.Abs(s.path)
+// GetPathAbs returns the absolute path of the log file (the absolute paths separated by commas if merged)
+func (s *Stream) GetPathAbs() (string, error) {
+	if s.paths == nil {
+		return filepath.Abs(s.path)
+	}
+	abs := make([]string, len(s.paths))
+	for i, path := range s.paths {
+		var err error
+		if abs[i], err = filepath.Abs(path); err != nil {
+			return "", err
+		}
+	}
+	return strings.Join(abs, ", "), nil
 }
 
-// GetPathRel returns the relative path of the log file
-func (s Stream) GetPathRel() string {
+// GetPathRel returns the relative path of the log file (the paths separated by commas if merged)
+func (s *Stream) GetPathRel() string {
 	return s.path
 }
 
+// Source returns the path of the file that is read, the decompressed copy if the log file is compressed
+func (s *Stream) Source() string {
+	return cmp.Or(s.temp, s.path)
+}
+
+// Unlink removes the decompressed copy of a compressed file while it's open, it can't be reopened afterwards (for
+// streams that are only read sequentially, nothing is left behind if they aren't closed)
+func (s *Stream) Unlink() error {
+	if s.temp == "" || s.shared {
+		return nil
+	}
+	if err := os.Remove(s.temp); err != nil {
+		return fmt.Errorf("error(stream): %w", err)
+	}
+	return nil
+}
+
 // GetErrors returns all parsing errors encountered during parsing
-func (s Stream) GetErrors() []string {
+func (s *Stream) GetErrors() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	return s.errors
 }
 
 // NewStream creates a new streaming parser for the given log file
+// (gzip and bzip2 compressed files are decompressed into a temporary file first)
 func NewStream(path string) (*Stream, error) {
-	file, err := os.Open(path)
+	file, temp, err := openLog(path)
 	if err != nil {
 		return nil, fmt.Errorf("error(stream): %w", err)
 	}
@@ -569,14 +1106,162 @@ func NewStream(path string) (*Stream, error) {
 		lineNum: 0,
 		path:    path,
 		scanner: bufio.NewScanner(file),
+		temp:    temp,
 	}, nil
 }
 
+// Translation returns true if the entry was logged by a translation rule (rdr, nat or binat)
+func (e *LogEntry) Translation() bool {
+	return e.Action == ActionRdr || e.Action == ActionNat || e.Action == ActionBinat
+}
+
+// Transport returns true if the entry is a tcp or udp packet (it has ports and a data length)
+func (e *LogEntry) Transport() bool {
+	return e.ProtoName == protoTCP || e.ProtoName == protoUDP
+}
+
+// number formats an informational number (empty if 0, i.e. not logged)
+func number(n uint32) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.FormatUint(uint64(n), 10)
+}
+
+// Field returns the string representation of the field with the given name (json tag)
+func (e *LogEntry) Field(name string) (string, bool) {
+	switch name {
+	case "action":
+		return e.Action, true
+	case "dir":
+		return e.Direction, true
+	case "iface":
+		return e.Interface, true
+	case "origin":
+		return e.Origin, true
+	case "reason":
+		return e.Reason, true
+	case "severity":
+		return e.Severity, true
+	case "time":
+		return e.Time.Format(time.RFC3339), true
+	case "dst":
+		return e.Dst, true
+	case "ipver":
+		return strconv.FormatUint(uint64(e.IPVersion), 10), true
+	case "proto":
+		return e.ProtoName, true
+	case "protonum":
+		return number(uint32(e.ProtoNum)), true
+	case "length":
+		return number(e.Length), true
+	case "tos":
+		return e.TOS, true
+	case "ecn":
+		return e.ECN, true
+	case "ttl":
+		return number(uint32(e.TTL)), true
+	case "id":
+		return number(uint32(e.ID)), true
+	case "offset":
+		return number(uint32(e.Offset)), true
+	case "ipflags":
+		return e.IPFlags, true
+	case "class":
+		return e.Class, true
+	case "flowlabel":
+		return e.FlowLabel, true
+	case "hoplimit":
+		return number(uint32(e.HopLimit)), true
+	case "src":
+		return e.Src, true
+	case "srcclass":
+		return e.SrcClass, true
+	case "dstclass":
+		return e.DstClass, true
+	case "dport":
+		return number(uint32(e.DstPort)), true
+	case "sport":
+		return number(uint32(e.SrcPort)), true
+	case "datalen":
+		return number(e.DataLen), true
+	case "icmptype":
+		return e.ICMPType, true
+	case "tcpflags":
+		return e.TCPFlags, true
+	case "seq":
+		return e.TCPSeq, true
+	case "ack":
+		return e.TCPAck, true
+	case "window":
+		return e.TCPWindow, true
+	case "urg":
+		return e.TCPUrg, true
+	case "tcpopts":
+		return e.TCPOptions, true
+	case "protodata":
+		return e.ProtoData, true
+	case "label":
+		return e.Label, true
+	case "rulenr":
+		return e.RuleNum, true
+	case "subrulenr":
+		return e.SubRuleNum, true
+	case "anchor":
+		return e.Anchor, true
+	}
+	return "", false
+}
+
+// FormatLine formats an entry as a log line that can be parsed again (only fields of LogEntry are preserved)
+func FormatLine(entry *LogEntry) string {
+	hostname := entry.Origin
+	if hostname == "" {
+		hostname = "-"
+	}
+	// 0: rulenr, 1: subrulenr, 2: anchorname, 3: label, 4: interface, 5: reason, 6: action, 7: direction, 8: ipversion
+	fields := []string{entry.RuleNum, entry.SubRuleNum, entry.Anchor, entry.Label, entry.Interface, entry.Reason, entry.Action, entry.Direction, strconv.Itoa(int(entry.IPVersion))}
+	length := number(entry.Length)
+	if entry.IPVersion == ipVersion6 {
+		// 9:class, 10:flow, 11:hoplimit, 12:protoname, 13:protonum, 14:length, 15:src, 16:dst
+		fields = append(fields, entry.Class, entry.FlowLabel, number(uint32(entry.HopLimit)), entry.ProtoName, number(uint32(entry.ProtoNum)),
+			length, entry.Src, entry.Dst)
+	} else {
+		// 9:tos, 10:ecn, 11:ttl, 12:id, 13:offset, 14:flags, 15:protonum, 16:protoname, 17:length, 18:src, 19:dst
+		fields = append(fields, entry.TOS, entry.ECN, number(uint32(entry.TTL)), number(uint32(entry.ID)), number(uint32(entry.Offset)),
+			entry.IPFlags, number(uint32(entry.ProtoNum)), entry.ProtoName, length, entry.Src, entry.Dst)
+	}
+	if entry.ProtoName == protoTCP || entry.ProtoName == protoUDP {
+		// srcport, dstport, datalen
+		fields = append(fields, strconv.Itoa(int(entry.SrcPort)), strconv.Itoa(int(entry.DstPort)),
+			strconv.FormatUint(uint64(entry.DataLen), 10))
+		if entry.ProtoName == protoTCP {
+			// flags, seq, ack, window, urg, options
+			fields = append(fields, entry.TCPFlags, entry.TCPSeq, entry.TCPAck, entry.TCPWindow, entry.TCPUrg, entry.TCPOptions)
+		}
+	} else if entry.ICMPType != "" {
+		// type, ...
+		fields = append(fields, entry.ICMPType)
+	} else if entry.ProtoData != "" {
+		// as logged
+		fields = append(fields, entry.ProtoData)
+	}
+	return fmt.Sprintf("<134>1 %s %s filterlog - - [meta] %s", entry.Time.Format(time.RFC3339Nano), hostname, strings.Join(fields, ","))
+}
+
+// ParseLine parses a single log line that was not read from a file (e.g. received via syslog, returns a *ParseError if invalid)
+func ParseLine(line string) (*LogEntry, error) {
+	var s Stream
+	if entry := s.parse(line, 0); entry != nil {
+		return entry, nil
+	}
+	return nil, s.lastErr
+}
+
 // Next reads and parses the next log entry (returns nil when EOF is reached)
 func (s *Stream) Next() *LogEntry {
-	for s.scanner.Scan() {
-		s.lineNum++
-		if entry := s.parse(s.scanner.Text(), s.lineNum); entry != nil {
+	for line, lineNum, ok := s.ReadLine(); ok; line, lineNum, ok = s.ReadLine() {
+		if entry := s.parse(line, lineNum); entry != nil {
 			return entry
 		}
 		// if nil, continue to the next line
@@ -584,34 +1269,99 @@ func (s *Stream) Next() *LogEntry {
 	return nil
 }
 
+// ReadLine reads the next line without parsing it, e.g. to parse lines in parallel (see Parser, returns false when EOF
+// is reached)
+func (s *Stream) ReadLine() (string, int, bool) {
+	if !s.scanner.Scan() {
+		return "", 0, false
+	}
+	s.lineNum++
+	s.offset.Add(int64(len(s.scanner.Bytes())) + 1)
+	return s.scanner.Text(), s.lineNum, true
+}
+
+// Parser returns a function that parses a line read by ReadLine like Next would (returns nil if the line is invalid,
+// parse errors are collected by s, each goroutine needs its own parser)
+func (s *Stream) Parser() func(line string, lineNum int) *LogEntry {
+	p := &Stream{hook: s.hook, onError: s.addError, origin: s.origin}
+	return p.parse
+}
+
+// Offset returns the approximate byte offset after the line last read by Next (safe to call while reading, e.g.
+// to display progress)
+func (s *Stream) Offset() int64 {
+	return s.offset.Load()
+}
+
+// Raw returns the unparsed line of the entry last returned by Next
+func (s *Stream) Raw() string {
+	return s.scanner.Text()
+}
+
 // SeekToLine seeks to a specific line number using the index
 func (s *Stream) SeekToLine(lineNum int) error {
-	if len(s.index) <= 0 {
+	s.mu.RLock()
+	total, data := len(s.index), s.mapped.bytes()
+	var offset int64
+	if lineNum >= 0 && lineNum < total {
+		offset = s.index[lineNum].lineOffset
+	}
+	s.mu.RUnlock()
+	if total <= 0 {
 		return fmt.Errorf("error(stream): could not seek: missing index")
 	}
-	if lineNum < 0 || lineNum >= len(s.index) {
-		return fmt.Errorf("error(stream): could not seek: line %d out of range [0, %d)", lineNum, len(s.index))
+	if lineNum < 0 || lineNum >= total {
+		return fmt.Errorf("error(stream): could not seek: line %d out of range [0, %d)", lineNum, total)
 	}
-	if s.file != nil {
-		s.file.Close()
+	debuglog.Debug("stream: seek", "path", s.path, "line", lineNum, "offset", offset, "mapped", offset < int64(len(data)))
+	if offset < int64(len(data)) {
+		// read from memory instead of reopening the file
+		s.scanner = bufio.NewScanner(bytes.NewReader(data[offset:]))
+		s.offset.Store(offset)
+		s.lineNum = lineNum
+		return nil
 	}
-	file, err := os.Open(s.path)
-	if err != nil {
+	if err := s.position(offset); err != nil {
 		return fmt.Errorf("error(stream): could not seek to line %d: %w", lineNum, err)
 	}
-	_, err = file.Seek(s.index[lineNum].lineOffset, 0)
-	if err != nil {
-		file.Close()
-		return fmt.Errorf("error(stream): could not seek to line %d: %w", lineNum, err)
-	}
-	s.file = file
-	s.scanner = bufio.NewScanner(file)
 	s.lineNum = lineNum
 	return nil
 }
 
+// SetMmap enables mapping the file into memory after indexing to speed up SeekToLine (the file is read
+// as usual if it can't be mapped, it must not be truncated while mapped)
+func (s *Stream) SetMmap(enabled bool) {
+	s.mmap = enabled
+	if !enabled {
+		s.unmap()
+	}
+}
+
+// SetErrorHandler sets a function that is called for every error encountered afterwards instead of collecting
+// it for GetErrors (parse errors are *ParseError, it may be called concurrently while indexing with IndexNext)
+func (s *Stream) SetErrorHandler(handler func(err error)) {
+	s.onError = handler
+}
+
+// SetHook sets a function that is called for every entry read afterwards (e.g. to classify it)
+func (s *Stream) SetHook(hook func(entry *LogEntry)) {
+	s.hook = hook
+}
+
+// SetOrigin sets the origin of all entries read afterwards (overrides the hostname of each line)
+func (s *Stream) SetOrigin(origin string) {
+	s.origin = origin
+}
+
+// SeverityLevel returns the rank of the severity level (-1 if unknown)
+func SeverityLevel(severity string) int {
+	return slices.Index(Severities, severity)
+}
+
 // TotalLines returns the total number of valid lines (if indexed)
-func (s Stream) TotalLines() int {
+func (s *Stream) TotalLines() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	if i := len(s.index); i > 0 {
 		return i
 	}
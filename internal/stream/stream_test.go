@@ -24,6 +24,16 @@
 package stream
 
 import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -228,6 +238,357 @@ func TestSeekToLine(t *testing.T) {
 	}
 }
 
+func TestSeekToLineMmap(t *testing.T) {
+	expect, err := NewStream("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer expect.Close()
+	s, err := NewStream("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.SetMmap(true)
+	if err := s.BuildIndex(); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.mapped.bytes()) == 0 && runtime.GOOS != "windows" {
+		t.Fatal("expected file to be mapped")
+	}
+	// scattered seeks must return the same entries as reading the file sequentially
+	var entries []*LogEntry
+	for entry := expect.Next(); entry != nil; entry = expect.Next() {
+		entries = append(entries, entry)
+	}
+	for _, lineNum := range []int{19, 0, 10, 3, 19, 7} {
+		if err := s.SeekToLine(lineNum); err != nil {
+			t.Fatal(err)
+		}
+		if entry := s.Next(); entry == nil || !reflect.DeepEqual(entry, entries[lineNum]) {
+			t.Fatalf("line %d: expected %+v, got %+v", lineNum, entries[lineNum], entry)
+		}
+	}
+	// reading continues after the seek
+	if err := s.SeekToLine(18); err != nil {
+		t.Fatal(err)
+	}
+	if s.Next() == nil || s.Next() == nil || s.Next() != nil {
+		t.Fatal("expected 2 entries until EOF")
+	}
+	s.SetMmap(false)
+	if s.mapped != nil {
+		t.Fatal("expected file to be unmapped")
+	}
+	if err := s.SeekToLine(0); err != nil {
+		t.Fatal(err)
+	}
+	if entry := s.Next(); entry == nil || !reflect.DeepEqual(entry, entries[0]) {
+		t.Fatalf("expected first entry after unmapping, got %+v", entry)
+	}
+}
+
+func TestExtendIndex(t *testing.T) {
+	data, err := os.ReadFile("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	path := filepath.Join(t.TempDir(), "filter.log")
+	if err := os.WriteFile(path, []byte(lines[0]+lines[1]), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	appendString := func(s string) {
+		file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		if _, err := file.WriteString(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s, err := NewStream(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.SetColumns(true)
+	s.SetMmap(true)
+	s.SetTerms(true)
+	if err := s.BuildIndex(); err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		name   string
+		action func()
+		expect []string // lines expected to be indexed
+	}{
+		{
+			name:   "nothing appended",
+			action: func() {},
+			expect: lines[:2],
+		},
+		{
+			name:   "incomplete line",
+			action: func() { appendString(lines[2][:len(lines[2])-10]) },
+			expect: lines[:2],
+		},
+		{
+			name:   "completed line",
+			action: func() { appendString(lines[2][len(lines[2])-10:] + lines[3]) },
+			expect: lines[:4],
+		},
+		{
+			name:   "truncated",
+			action: func() { os.WriteFile(path, []byte(lines[4]), 0o644) },
+			expect: lines[4:5],
+		},
+		{
+			name: "replaced",
+			action: func() {
+				os.Remove(path)
+				os.WriteFile(path, []byte(lines[5]+lines[6]+lines[7]), 0o644)
+			},
+			expect: lines[5:8],
+		},
+	}
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			step.action()
+			if err := s.ExtendIndex(); err != nil {
+				t.Fatal(err)
+			}
+			if got := s.TotalLines(); got != len(step.expect) {
+				t.Fatalf("expected %d lines, got %d", len(step.expect), got)
+			}
+			if got := s.Columns().Len(); got != len(step.expect) {
+				t.Errorf("expected columns of %d lines, got %d", len(step.expect), got)
+			}
+			for i, line := range step.expect {
+				expect, err := ParseLine(strings.TrimSuffix(line, "\n"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := s.SeekToLine(i); err != nil {
+					t.Fatal(err)
+				}
+				if entry := s.Next(); entry == nil || !reflect.DeepEqual(entry, expect) {
+					t.Errorf("line %d: expected %+v, got %+v", i, expect, entry)
+				}
+			}
+		})
+	}
+}
+
+func TestSeekToLineReplaced(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file identity is not available")
+	}
+	data, err := os.ReadFile("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	path := filepath.Join(t.TempDir(), "filter.log")
+	if err := os.WriteFile(path, []byte(lines[0]+lines[1]), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewStream(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.BuildIndex(); err != nil {
+		t.Fatal(err)
+	}
+	// rotated, the new file has a longer first line
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(lines[2]+lines[0]), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// the open file is still read
+	expect, err := ParseLine(strings.TrimSuffix(lines[1], "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SeekToLine(1); err != nil {
+		t.Fatal(err)
+	}
+	if entry := s.Next(); entry == nil || !reflect.DeepEqual(entry, expect) {
+		t.Fatalf("expected %+v, got %+v", expect, entry)
+	}
+	// the index doesn't apply to the file at the path
+	if _, err := s.Clone(); !errors.Is(err, ErrReplaced) {
+		t.Fatalf("expected ErrReplaced, got %v", err)
+	}
+	// until it has been indexed
+	if err := s.ExtendIndex(); err != nil {
+		t.Fatal(err)
+	}
+	c, err := s.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if expect, err = ParseLine(strings.TrimSuffix(lines[2], "\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SeekToLine(0); err != nil {
+		t.Fatal(err)
+	}
+	if entry := c.Next(); entry == nil || !reflect.DeepEqual(entry, expect) {
+		t.Fatalf("expected %+v, got %+v", expect, entry)
+	}
+}
+
+func TestIndexNext(t *testing.T) {
+	expect, err := NewStream("../../tests/filter_mixed.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer expect.Close()
+	if err := expect.BuildIndex(); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewStream("../../tests/filter_mixed.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if s.IndexProgress() != 0 {
+		t.Error("expected progress of 0 before indexing")
+	}
+	// read while indexing
+	var indexed atomic.Bool
+	defer indexed.Store(true)
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		for !indexed.Load() {
+			if s.TotalLines() > 0 {
+				if err := s.SeekToLine(0); err != nil {
+					t.Error(err)
+				} else if s.Next() == nil {
+					t.Error("expected entry while indexing")
+				}
+			}
+		}
+	})
+	chunks := 0
+	for done := false; !done; chunks++ {
+		progress := s.IndexProgress()
+		if done, err = s.IndexNext(3); err != nil {
+			t.Fatal(err)
+		}
+		if !done && s.IndexProgress() <= progress {
+			t.Fatalf("expected progress to increase, got %f after %f", s.IndexProgress(), progress)
+		}
+	}
+	indexed.Store(true)
+	wg.Wait()
+	if chunks < 2 {
+		t.Errorf("expected the file to be indexed in several chunks, got %d", chunks)
+	}
+	if s.TotalLines() != expect.TotalLines() || !reflect.DeepEqual(s.index, expect.index) {
+		t.Errorf("expected the same index as BuildIndex, got %d lines instead of %d", s.TotalLines(), expect.TotalLines())
+	}
+	if !slices.Equal(s.GetErrors(), expect.GetErrors()) {
+		t.Errorf("expected errors %q, got %q", expect.GetErrors(), s.GetErrors())
+	}
+}
+
+func TestClone(t *testing.T) {
+	s, err := NewStream("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.SetMmap(true)
+	if err := s.BuildIndex(); err != nil {
+		t.Fatal(err)
+	}
+	c, err := s.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// readers are independent of each other
+	if err := s.SeekToLine(3); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SeekToLine(10); err != nil {
+		t.Fatal(err)
+	}
+	expect := s.Next()
+	if err := s.SeekToLine(10); err != nil {
+		t.Fatal(err)
+	}
+	if entry := c.Next(); entry == nil || !reflect.DeepEqual(entry, s.Next()) {
+		t.Fatalf("expected entry of line 10, got %+v", entry)
+	}
+	// closing the clone must not release the shared mapping
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SeekToLine(3); err != nil {
+		t.Fatal(err)
+	}
+	if entry := s.Next(); entry == nil || !reflect.DeepEqual(entry, expect) {
+		t.Fatalf("expected entry of line 3 after closing clone, got %+v", entry)
+	}
+}
+
+func TestCloneRemap(t *testing.T) {
+	data, err := os.ReadFile("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "filter.log")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewStream(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.SetMmap(true)
+	if err := s.BuildIndex(); err != nil {
+		t.Fatal(err)
+	}
+	c, err := s.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := c.SeekToLine(3); err != nil {
+		t.Fatal(err)
+	}
+	expect := c.Next()
+	// extending the index remaps the file, the clone keeps reading the previous mapping
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Write(data)
+	file.Close()
+	if err := s.ExtendIndex(); err != nil {
+		t.Fatal(err)
+	}
+	if s.TotalLines() != 2*c.TotalLines() {
+		t.Fatalf("expected %d lines after extending, got %d", 2*c.TotalLines(), s.TotalLines())
+	}
+	if err := c.SeekToLine(3); err != nil {
+		t.Fatal(err)
+	}
+	if entry := c.Next(); entry == nil || !reflect.DeepEqual(entry, expect) {
+		t.Fatalf("expected entry of line 3 after remapping, got %+v", entry)
+	}
+}
+
 func TestParsedValues(t *testing.T) {
 	s, err := NewStream("../../tests/filter_valid.log")
 	if err != nil {
@@ -254,6 +615,12 @@ func TestParsedValues(t *testing.T) {
 	if entry.SrcPort != 63511 || entry.DstPort != 53 {
 		t.Fatalf("entry 1: expected ports 63511:53, got %d:%d", entry.SrcPort, entry.DstPort)
 	}
+	if entry.Length != 60 || entry.DataLen != 60 {
+		t.Fatalf("entry 1: expected length/datalen 60/60, got %d/%d", entry.Length, entry.DataLen)
+	}
+	if entry.Class != "0x00" || entry.FlowLabel != "0xfd492" || entry.HopLimit != 128 {
+		t.Fatalf("entry 1: expected class/flowlabel/hoplimit 0x00/0xfd492/128, got %s/%s/%d", entry.Class, entry.FlowLabel, entry.HopLimit)
+	}
 	expectedTime := time.Date(2025, 10, 10, 0, 0, 0, 0, time.FixedZone("", 2*60*60))
 	if !entry.Time.Equal(expectedTime) {
 		t.Fatalf("entry 1: expected time %v, got %v", expectedTime, entry.Time)
@@ -272,6 +639,12 @@ func TestParsedValues(t *testing.T) {
 	if entry.Src != "192.168.1.100" || entry.Dst != "192.168.1.1" {
 		t.Fatalf("entry 2: expected src/dst 192.168.1.100/192.168.1.1, got %s/%s", entry.Src, entry.Dst)
 	}
+	if entry.Length != 80 || entry.DataLen != 60 {
+		t.Fatalf("entry 2: expected length/datalen 80/60, got %d/%d", entry.Length, entry.DataLen)
+	}
+	if entry.TOS != "0x0" || entry.ECN != "" || entry.TTL != 64 || entry.ID != 0 || entry.Offset != 0 || entry.IPFlags != "DF" {
+		t.Fatalf("entry 2: expected tos/ttl/flags 0x0/64/DF, got %+v", entry)
+	}
 	// 7th entry
 	for range 4 {
 		s.Next()
@@ -286,6 +659,65 @@ func TestParsedValues(t *testing.T) {
 	if entry.ProtoName != protoTCP {
 		t.Fatalf("entry 7: expected %s, got %s", protoTCP, entry.ProtoName)
 	}
+	if entry.TCPFlags != "S" || entry.TCPSeq != "1548925256" || entry.TCPAck != "" || entry.TCPWindow != "1025" ||
+		entry.TCPUrg != "" || entry.TCPOptions != "mss" {
+		t.Fatalf("entry 7: expected tcp header S/1548925256//1025//mss, got %s/%s/%s/%s/%s/%s", entry.TCPFlags,
+			entry.TCPSeq, entry.TCPAck, entry.TCPWindow, entry.TCPUrg, entry.TCPOptions)
+	}
+}
+
+func TestRaw(t *testing.T) {
+	content, err := os.ReadFile("../../tests/filter_corrupt.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	s, err := NewStream("../../tests/filter_corrupt.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	for entry := s.Next(); entry != nil; entry = s.Next() {
+		if !slices.Contains(lines, s.Raw()) {
+			t.Fatalf("expected raw line of entry, got %q", s.Raw())
+		}
+		parsed, err := ParseLine(s.Raw())
+		if err != nil || !parsed.Time.Equal(entry.Time) {
+			t.Fatalf("expected raw line to parse to the same entry, got %v", err)
+		}
+	}
+}
+
+func TestOffset(t *testing.T) {
+	info, err := os.Stat("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewStream("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.BuildIndex(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SeekToLine(1); err != nil {
+		t.Fatal(err)
+	}
+	first := s.Offset()
+	if first <= 0 {
+		t.Fatalf("expected offset of the second line, got %d", first)
+	}
+	last := first
+	for entry := s.Next(); entry != nil; entry = s.Next() {
+		if s.Offset() <= last {
+			t.Fatalf("expected offset to grow, got %d after %d", s.Offset(), last)
+		}
+		last = s.Offset()
+	}
+	if last != info.Size() {
+		t.Errorf("expected offset %d at the end, got %d", info.Size(), last)
+	}
 }
 
 func TestTotalLines(t *testing.T) {
@@ -306,3 +738,355 @@ func TestTotalLines(t *testing.T) {
 		t.Fatalf("expected 20 with index, got %d", total)
 	}
 }
+
+func TestField(t *testing.T) {
+	entry := LogEntry{
+		Action:    ActionBlock,
+		Direction: directionIn,
+		Interface: "eth0",
+		Origin:    "fw1",
+		Reason:    reasonMatch,
+		Time:      time.Date(2025, 10, 10, 0, 0, 0, 0, time.UTC),
+		Dst:       "10.0.0.1",
+		IPVersion: ipVersion4,
+		ProtoName: protoTCP,
+		Src:       "192.168.1.1",
+		DstPort:   443,
+		Length:    60,
+		Label:     "1a2b3c4d",
+		RuleNum:   "61",
+	}
+	tests := []struct {
+		name        string
+		expectOk    bool
+		expectValue string
+	}{
+		{name: "action", expectOk: true, expectValue: "block"},
+		{name: "dir", expectOk: true, expectValue: "in"},
+		{name: "iface", expectOk: true, expectValue: "eth0"},
+		{name: "origin", expectOk: true, expectValue: "fw1"},
+		{name: "reason", expectOk: true, expectValue: "match"},
+		{name: "time", expectOk: true, expectValue: "2025-10-10T00:00:00Z"},
+		{name: "dst", expectOk: true, expectValue: "10.0.0.1"},
+		{name: "ipver", expectOk: true, expectValue: "4"},
+		{name: "proto", expectOk: true, expectValue: "tcp"},
+		{name: "src", expectOk: true, expectValue: "192.168.1.1"},
+		{name: "dport", expectOk: true, expectValue: "443"},
+		{name: "sport", expectOk: true, expectValue: ""},
+		{name: "length", expectOk: true, expectValue: "60"},
+		{name: "datalen", expectOk: true, expectValue: ""},
+		{name: "label", expectOk: true, expectValue: "1a2b3c4d"},
+		{name: "rulenr", expectOk: true, expectValue: "61"},
+		{name: "unknown", expectOk: false, expectValue: ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			value, ok := entry.Field(tc.name)
+			if ok != tc.expectOk {
+				t.Fatalf("expected ok=%v, got %v", tc.expectOk, ok)
+			}
+			if value != tc.expectValue {
+				t.Fatalf("expected %q, got %q", tc.expectValue, value)
+			}
+		})
+	}
+}
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		name         string
+		line         string
+		expectSrc    string
+		expectOrigin string
+		expectError  string
+	}{
+		{
+			name:         "valid line",
+			line:         `<134>1 2025-10-10T00:00:00+02:00 opnsense.filter.log filterlog 86605 - [meta sequenceId="2"] 68,,,2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e,eth1,match,pass,out,4,0x0,,64,0,0,DF,17,udp,80,192.168.1.100,192.168.1.1,12162,53,60`,
+			expectSrc:    "192.168.1.100",
+			expectOrigin: "opnsense.filter.log",
+		},
+		{
+			name:      "nil hostname",
+			line:      `<134>1 2025-10-10T00:00:00+02:00 - filterlog 86605 - [meta sequenceId="2"] 68,,,2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e,eth1,match,pass,out,4,0x0,,64,0,0,DF,17,udp,80,192.168.1.100,192.168.1.1,12162,53,60`,
+			expectSrc: "192.168.1.100",
+		},
+		{
+			name:        "invalid timestamp",
+			line:        "invalid",
+			expectError: "error(stream): invalid timestamp",
+		},
+		{
+			name:        "truncated timestamp",
+			line:        "<134>1 2025-10-10T00:00:00+02:00",
+			expectError: "error(stream): invalid timestamp",
+		},
+		{
+			name:        "missing csv",
+			line:        "<134>1 2025-10-10T00:00:00+02:00 - filterlog",
+			expectError: "error(stream): invalid csv",
+		},
+		{
+			name:        "truncated csv",
+			line:        `<134>1 2025-10-10T00:00:00+02:00 - filterlog 86605 - [meta sequenceId="2"] 68,,,2b3c,eth1,match,pass,out,4,0x0`,
+			expectError: "error(stream): invalid v4/protoName",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			entry, err := ParseLine(tc.line)
+			if tc.expectError != "" {
+				var parseErr *ParseError
+				if !errors.As(err, &parseErr) || err.Error() != tc.expectError {
+					t.Fatalf("expected error %q, got %v", tc.expectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if entry.Src != tc.expectSrc {
+				t.Fatalf("expected src %q, got %q", tc.expectSrc, entry.Src)
+			}
+			if entry.Origin != tc.expectOrigin {
+				t.Fatalf("expected origin %q, got %q", tc.expectOrigin, entry.Origin)
+			}
+		})
+	}
+}
+
+func TestParseOtherProtocols(t *testing.T) {
+	prefix := `<134>1 2025-10-10T00:00:00+02:00 fw1 filterlog 86605 - [meta sequenceId="1"] `
+	tests := []struct {
+		name            string
+		csv             string
+		expectProto     string
+		expectProtoNum  uint8
+		expectProtoData string
+	}{
+		{
+			name:            "carp",
+			csv:             "77,,,0b1c2d3e,igb0,match,pass,out,4,0x10,,255,12345,0,none,112,carp,56,192.168.1.2,224.0.0.18,advertise,255,1,2,0,1",
+			expectProto:     "carp",
+			expectProtoNum:  112,
+			expectProtoData: "advertise,255,1,2,0,1",
+		},
+		{
+			name:           "esp",
+			csv:            "61,,,0b1c2d3e,igb1,match,pass,in,4,0x0,,64,4242,0,DF,50,esp,120,203.0.113.1,198.51.100.1",
+			expectProto:    "esp",
+			expectProtoNum: 50,
+		},
+		{
+			name:           "gre6",
+			csv:            "61,,,0b1c2d3e,igb1,match,block,in,6,0x00,0x00000,64,gre,47,80,2001:db8::1,2001:db8::2",
+			expectProto:    "gre",
+			expectProtoNum: 47,
+		},
+		{
+			name:            "pfsync",
+			csv:             "12,,,0b1c2d3e,igb2,match,pass,out,4,0xc0,,255,0,0,none,240,pfsync,140,10.0.0.1,224.0.0.240,datalength=120",
+			expectProto:     "pfsync",
+			expectProtoNum:  240,
+			expectProtoData: "datalength=120",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			entry, err := ParseLine(prefix + tc.csv)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if entry.ProtoName != tc.expectProto || entry.ProtoNum != tc.expectProtoNum || entry.ProtoData != tc.expectProtoData {
+				t.Fatalf("expected %s (%d) with %q, got %s (%d) with %q", tc.expectProto, tc.expectProtoNum, tc.expectProtoData,
+					entry.ProtoName, entry.ProtoNum, entry.ProtoData)
+			}
+			if entry.SrcPort != 0 || entry.DstPort != 0 || entry.ICMPType != "" {
+				t.Fatalf("expected no ports or icmp type, got %+v", entry)
+			}
+		})
+	}
+}
+
+func TestSetHook(t *testing.T) {
+	s, err := NewStream("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.SetHook(func(entry *LogEntry) { entry.Severity = SeverityWarning })
+	if entry := s.Next(); entry == nil || entry.Severity != SeverityWarning {
+		t.Fatalf("expected hook to be called, got %+v", entry)
+	}
+}
+
+func TestSetErrorHandler(t *testing.T) {
+	collected, err := NewStream("../../tests/filter_corrupt.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer collected.Close()
+	for entry := collected.Next(); entry != nil; entry = collected.Next() {
+	}
+	s, err := NewStream("../../tests/filter_corrupt.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	var reported []string
+	s.SetErrorHandler(func(err error) {
+		var perr *ParseError
+		if !errors.As(err, &perr) || perr.Line == 0 {
+			t.Errorf("expected *ParseError with line number, got %v", err)
+		}
+		reported = append(reported, err.Error())
+	})
+	for entry := s.Next(); entry != nil; entry = s.Next() {
+	}
+	if len(s.GetErrors()) != 0 {
+		t.Errorf("expected no collected errors, got %q", s.GetErrors())
+	}
+	if len(reported) == 0 || len(reported) != len(collected.GetErrors()) {
+		t.Fatalf("expected %d reported errors, got %d", len(collected.GetErrors()), len(reported))
+	}
+	for i, msg := range collected.GetErrors() {
+		if reported[i] != "error(stream): "+msg {
+			t.Errorf("expected %q to match collected %q", reported[i], msg)
+		}
+	}
+}
+
+func TestSetOrigin(t *testing.T) {
+	s, err := NewStream("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if entry := s.Next(); entry == nil || entry.Origin != "opnsense.filter.log" {
+		t.Fatalf("expected hostname as origin, got %+v", entry)
+	}
+	s.SetOrigin("fw1")
+	if entry := s.Next(); entry == nil || entry.Origin != "fw1" {
+		t.Fatalf("expected origin %q, got %+v", "fw1", entry)
+	}
+}
+
+func TestFormatLine(t *testing.T) {
+	timestamp := time.Date(2025, 10, 10, 10, 5, 0, 0, time.FixedZone("", 2*60*60))
+	tests := []struct {
+		name  string
+		entry LogEntry
+	}{
+		{
+			name:  "tcp4",
+			entry: LogEntry{Action: ActionBlock, Direction: directionIn, Interface: "igb1", Reason: reasonMatch, Time: timestamp, Dst: "198.51.100.1", IPVersion: ipVersion4, ProtoName: protoTCP, Src: "203.0.113.10", DstPort: 22, SrcPort: 51000},
+		},
+		{
+			name:  "tcp6 with header",
+			entry: LogEntry{Action: ActionPass, Direction: directionOut, Interface: "igb0", Reason: reasonMatch, Time: timestamp, Dst: "2001:db8::1", IPVersion: ipVersion6, ProtoName: protoTCP, Src: "2001:db8::2", DstPort: 443, SrcPort: 40000, TCPFlags: "SA", TCPSeq: "1548925256", TCPAck: "2145306579", TCPWindow: "65160", TCPOptions: "mss;sackOK;TS;nop;wscale"},
+		},
+		{
+			name:  "udp6",
+			entry: LogEntry{Action: ActionPass, Direction: directionOut, Interface: "igb0", Reason: reasonMatch, Time: timestamp, Dst: "2001:db8::1", IPVersion: ipVersion6, ProtoName: protoUDP, Src: "2001:db8::2", DstPort: 53, SrcPort: 40000},
+		},
+		{
+			name:  "lengths",
+			entry: LogEntry{Action: ActionPass, Direction: directionOut, Interface: "igb0", Reason: reasonMatch, Time: timestamp, Dst: "198.51.100.1", IPVersion: ipVersion4, ProtoName: protoUDP, Src: "203.0.113.10", DstPort: 53, SrcPort: 40000, Length: 80, DataLen: 60},
+		},
+		{
+			name:  "ip4 header",
+			entry: LogEntry{Action: ActionBlock, Direction: directionIn, Interface: "igb1", Reason: reasonMatch, Time: timestamp, Dst: "198.51.100.1", IPVersion: ipVersion4, ProtoName: protoTCP, Src: "203.0.113.10", DstPort: 22, SrcPort: 51000, TOS: "0x10", ECN: "ECT(0)", TTL: 243, ID: 54321, Offset: 185, IPFlags: "+"},
+		},
+		{
+			name:  "ip6 header",
+			entry: LogEntry{Action: ActionPass, Direction: directionOut, Interface: "igb0", Reason: reasonMatch, Time: timestamp, Dst: "2001:db8::1", IPVersion: ipVersion6, ProtoName: protoUDP, Src: "2001:db8::2", DstPort: 53, SrcPort: 40000, Class: "0xb8", FlowLabel: "0xfd492", HopLimit: 64},
+		},
+		{
+			name:  "carp",
+			entry: LogEntry{Action: ActionPass, Direction: directionOut, Interface: "igb0", Reason: reasonMatch, Time: timestamp, Dst: "224.0.0.18", IPVersion: ipVersion4, ProtoName: "carp", ProtoNum: 112, Src: "192.168.1.2", TTL: 255, ProtoData: "advertise,255,1,2,0,1"},
+		},
+		{
+			name:  "gre6",
+			entry: LogEntry{Action: ActionBlock, Direction: directionIn, Interface: "igb1", Reason: reasonMatch, Time: timestamp, Dst: "2001:db8::2", IPVersion: ipVersion6, ProtoName: "gre", ProtoNum: 47, Src: "2001:db8::1", HopLimit: 64},
+		},
+		{
+			name:  "origin",
+			entry: LogEntry{Action: ActionPass, Direction: directionIn, Interface: "igb1", Origin: "fw1", Reason: reasonMatch, Time: timestamp, Dst: "198.51.100.1", IPVersion: ipVersion4, ProtoName: protoUDP, Src: "203.0.113.10", DstPort: 53, SrcPort: 40000},
+		},
+		{
+			name:  "icmp4",
+			entry: LogEntry{Action: ActionBlock, Direction: directionIn, Interface: "igb1", Reason: reasonMatch, Time: timestamp, Dst: "198.51.100.1", IPVersion: ipVersion4, ProtoName: protoICMP, Src: "203.0.113.10"},
+		},
+		{
+			name:  "icmp6 with type",
+			entry: LogEntry{Action: ActionPass, Direction: directionIn, Interface: "igb1", Reason: reasonMatch, Time: timestamp, Dst: "ff02::1:ff00:1", IPVersion: ipVersion6, ProtoName: protoICMPv6, Src: "fe80::1", ICMPType: "135"},
+		},
+		{
+			name:  "rule",
+			entry: LogEntry{Action: ActionBlock, Direction: directionIn, Interface: "igb1", Reason: reasonMatch, Time: timestamp, Dst: "198.51.100.1", IPVersion: ipVersion4, ProtoName: protoTCP, Src: "203.0.113.10", DstPort: 22, SrcPort: 51000, Label: "02f4bab031b57d1e30553ce08e0ec131", RuleNum: "5"},
+		},
+		{
+			name:  "anchor",
+			entry: LogEntry{Action: ActionPass, Direction: directionIn, Interface: "igb1", Reason: reasonMatch, Time: timestamp, Dst: "198.51.100.1", IPVersion: ipVersion4, ProtoName: protoUDP, Src: "203.0.113.10", DstPort: 53, SrcPort: 40000, Anchor: "miniupnpd", RuleNum: "12", SubRuleNum: "3"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			entry, err := ParseLine(FormatLine(&tc.entry))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(*entry, tc.entry) {
+				t.Fatalf("expected %+v, got %+v", tc.entry, *entry)
+			}
+		})
+	}
+}
+
+func TestWindowsLineEndings(t *testing.T) {
+	content, err := os.ReadFile("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content = append([]byte("\ufeff"), bytes.ReplaceAll(content, []byte("\n"), []byte("\r\n"))...)
+	path := filepath.Join(t.TempDir(), "filter.log")
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewStream(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	valid := 0
+	var last *LogEntry
+	for entry := s.Next(); entry != nil; entry = s.Next() {
+		valid++
+		last = entry
+	}
+	if valid != 20 || len(s.GetErrors()) != 0 {
+		t.Fatalf("expected 20 valid entries and no errors, got %d and %v", valid, s.GetErrors())
+	}
+	// offsets must account for \r\n
+	if err := s.BuildIndex(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SeekToLine(19); err != nil {
+		t.Fatal(err)
+	}
+	if entry := s.Next(); entry == nil || !reflect.DeepEqual(entry, last) {
+		t.Fatalf("expected last entry after seek, got %+v", entry)
+	}
+	// lines that were not read by a scanner
+	entry, err := ParseLine(strings.SplitN(string(content), "\n", 2)[0])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if entry.DstPort != 53 {
+		t.Fatalf("expected dport 53, got %d", entry.DstPort)
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stream
+
+import "strings"
+
+const (
+	// TermBlockLines is the number of lines per block of the term index (see SetTerms)
+	TermBlockLines = 1024
+
+	termBits   = 1 << 16 // size of the bloom filter of each block (in bits)
+	termHashes = 3       // number of bits set per trigram
+)
+
+// termBlock is a bloom filter of all trigrams occurring in the searchable fields of a block of lines
+type termBlock [termBits / 64]uint64
+
+// trigramHash returns the fnv-1a hash of a trigram
+func trigramHash(t string) uint64 {
+	h := uint64(14695981039346656037)
+	for i := range 3 {
+		h ^= uint64(t[i])
+		h *= 1099511628211
+	}
+	return h
+}
+
+// trigramBits returns the bits of the trigram in a block (double hashing)
+func trigramBits(t string) [termHashes]uint32 {
+	h := trigramHash(t)
+	h1, h2 := uint32(h), uint32(h>>32)|1
+	var pos [termHashes]uint32
+	for i := range termHashes {
+		pos[i] = (h1 + uint32(i)*h2) % termBits
+	}
+	return pos
+}
+
+// add adds all trigrams of the searchable fields of an entry
+func (b *termBlock) add(entry *LogEntry) {
+	for _, field := range entry.Searchable() {
+		field = strings.ToLower(field)
+		for i := 0; i+3 <= len(field); i++ {
+			for _, p := range trigramBits(field[i : i+3]) {
+				b[p/64] |= 1 << (p % 64)
+			}
+		}
+	}
+}
+
+// mayContain returns false if no field of any line of the block contains the (lowercase) term
+func (b *termBlock) mayContain(term string) bool {
+	for i := 0; i+3 <= len(term); i++ {
+		for _, p := range trigramBits(term[i : i+3]) {
+			if b[p/64]&(1<<(p%64)) == 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// public
+
+// MayContain returns false if no line of the block starting at lineNum contains all (lowercase) terms in its searchable
+// fields (always true unless enabled using SetTerms, terms shorter than 3 bytes are ignored)
+func (s *Stream) MayContain(lineNum int, terms []string) bool {
+	block := lineNum / TermBlockLines
+	if block < 0 || block >= len(s.terms) {
+		return true
+	}
+	for _, term := range terms {
+		if !s.terms[block].mayContain(term) {
+			return false
+		}
+	}
+	return true
+}
+
+// Searchable returns the values of the fields that are searched by bare values
+func (e *LogEntry) Searchable() []string {
+	return []string{
+		e.Action,
+		e.Direction,
+		e.Interface,
+		e.Origin,
+		e.Reason,
+		e.Time.Format("Jan 02 15:04:05"),
+		e.Dst,
+		e.ProtoName,
+		e.Src,
+	}
+}
+
+// SetTerms enables recording which terms occur in each block of lines while indexing (about 8 bytes per line)
+func (s *Stream) SetTerms(enabled bool) {
+	s.termed = enabled
+	if !enabled {
+		s.terms = nil
+	}
+}
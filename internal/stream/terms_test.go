@@ -0,0 +1,69 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package stream
+
+import "testing"
+
+func TestMayContain(t *testing.T) {
+	s, err := NewStream("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.BuildIndex(); err != nil {
+		t.Fatal(err)
+	}
+	if !s.MayContain(0, []string{"not-in-file"}) {
+		t.Fatal("expected true unless enabled")
+	}
+	s.SetTerms(true)
+	if err := s.BuildIndex(); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		terms    []string
+		expected bool
+	}{
+		{"no terms", nil, true},
+		{"interface", []string{"eth0"}, true},
+		{"partial address", []string{"168.1.10"}, true},
+		{"ipv6 address", []string{"fd00:1234"}, true},
+		{"timestamp", []string{"oct 10"}, true},
+		{"all terms present", []string{"pass", "udp"}, true},
+		{"absent term", []string{"not-in-file"}, false},
+		{"one absent term", []string{"pass", "not-in-file"}, false},
+		{"short term", []string{"zz"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.MayContain(0, tt.terms); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+	if !s.MayContain(TermBlockLines, []string{"not-in-file"}) {
+		t.Error("expected true for lines outside of the index")
+	}
+}
@@ -0,0 +1,366 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package syslog
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+const (
+	// schemes
+	schemeTCP = "tcp"
+	schemeTLS = "tls"
+	schemeUDP = "udp"
+
+	// policies
+	PolicyBlock      = "block"       // wait for free space (slows down tcp senders)
+	PolicyDrop       = "drop"        // drop new messages
+	PolicyDropOldest = "drop-oldest" // drop the oldest queued entry
+
+	appName        = "filterlog"
+	maxMessageSize = 64 * 1024
+	queueSize      = 10000
+)
+
+// Listener receives filter log entries via syslog (rfc 5424 messages over udp, tcp or tls)
+type Listener struct {
+	addr    net.Addr              // bound address
+	closer  io.Closer             // packet connection or listener
+	conns   map[net.Conn]struct{} // open stream connections
+	done    chan struct{}         // closed when the listener is closed
+	dropped atomic.Int64          // dropped messages
+	errors  []string              // parsing errors
+	limiter *limiter              // rate limiter (nil if unlimited)
+	mu      sync.Mutex            // guards conns and errors
+	policy  string                // behavior when the queue is full or the rate is exceeded
+	queue   chan *stream.LogEntry // received entries
+	url     string                // listen url
+	wg      sync.WaitGroup        // running goroutines
+}
+
+// addError adds a parsing error (errors beyond stream.MaxErrorsInMemory are dropped)
+func (l *Listener) addError(msg string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.errors) < stream.MaxErrorsInMemory {
+		l.errors = append(l.errors, msg)
+	}
+}
+
+// isFilterlog returns true if the app name of the message is filterlog (other messages are ignored)
+func isFilterlog(msg string) bool {
+	// <PRI>VERSION TIMESTAMP HOSTNAME APP-NAME ...
+	fields := strings.SplitN(msg, " ", 5)
+	return len(fields) >= 4 && fields[3] == appName
+}
+
+// allow applies the rate limit (returns false if the message must be dropped)
+func (l *Listener) allow() bool {
+	if l.limiter == nil {
+		return true
+	}
+	for {
+		ok, wait := l.limiter.reserve(time.Now())
+		if ok {
+			return true
+		}
+		if l.policy != PolicyBlock {
+			return false
+		}
+		select {
+		case <-time.After(wait):
+		case <-l.done:
+			return false
+		}
+	}
+}
+
+// enqueue queues the entry according to the policy
+func (l *Listener) enqueue(entry *stream.LogEntry) {
+	switch l.policy {
+	case PolicyDrop:
+		select {
+		case l.queue <- entry:
+		default:
+			l.dropped.Add(1)
+		}
+	case PolicyDropOldest:
+		for {
+			select {
+			case l.queue <- entry:
+				return
+			default:
+			}
+			select {
+			case <-l.queue:
+				l.dropped.Add(1)
+			default:
+			}
+		}
+	default:
+		select {
+		case l.queue <- entry:
+		case <-l.done:
+		}
+	}
+}
+
+// handle parses a single message and queues the entry
+func (l *Listener) handle(msg string, from net.Addr) {
+	msg = strings.TrimRight(msg, "\r\n")
+	if msg == "" || !isFilterlog(msg) {
+		return
+	}
+	if !l.allow() {
+		l.dropped.Add(1)
+		return
+	}
+	entry, err := stream.ParseLine(msg)
+	if err != nil {
+		l.addError(fmt.Sprintf("invalid message from %s: %v", from, err))
+		return
+	}
+	// senders without hostname are identified by their address
+	if entry.Origin == "" {
+		if host, _, err := net.SplitHostPort(from.String()); err == nil {
+			entry.Origin = host
+		}
+	}
+	l.enqueue(entry)
+}
+
+// readFrame reads a single message using octet counting or newline framing (rfc 6587)
+func readFrame(r *bufio.Reader) (string, error) {
+	b, err := r.Peek(1)
+	if err != nil {
+		return "", err
+	}
+	if b[0] >= '1' && b[0] <= '9' {
+		// octet counting: MSG-LEN SP SYSLOG-MSG
+		prefix, err := r.ReadSlice(' ')
+		if err != nil {
+			return "", fmt.Errorf("invalid frame: %w", err)
+		}
+		n, err := strconv.Atoi(string(prefix[:len(prefix)-1]))
+		if err != nil || n > maxMessageSize {
+			return "", fmt.Errorf("invalid frame length %q", prefix[:len(prefix)-1])
+		}
+		msg := make([]byte, n)
+		if _, err := io.ReadFull(r, msg); err != nil {
+			return "", err
+		}
+		return string(msg), nil
+	}
+	// non-transparent framing: messages are terminated by newline
+	line, err := r.ReadSlice('\n')
+	if errors.Is(err, io.EOF) && len(line) > 0 {
+		return string(line), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(line), nil
+}
+
+// serveConn reads messages from a stream connection until it is closed
+func (l *Listener) serveConn(conn net.Conn) {
+	defer l.wg.Done()
+	defer func() {
+		l.mu.Lock()
+		delete(l.conns, conn)
+		l.mu.Unlock()
+		conn.Close()
+	}()
+	r := bufio.NewReaderSize(conn, maxMessageSize)
+	for {
+		msg, err := readFrame(r)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				l.addError(fmt.Sprintf("connection from %s: %v", conn.RemoteAddr(), err))
+			}
+			return
+		}
+		l.handle(msg, conn.RemoteAddr())
+	}
+}
+
+// accept accepts stream connections until the listener is closed
+func (l *Listener) accept(ln net.Listener) {
+	defer l.wg.Done()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		l.mu.Lock()
+		l.conns[conn] = struct{}{}
+		l.mu.Unlock()
+		l.wg.Add(1)
+		go l.serveConn(conn)
+	}
+}
+
+// receive reads datagrams (one message each) until the connection is closed
+func (l *Listener) receive(pc net.PacketConn) {
+	defer l.wg.Done()
+	buf := make([]byte, maxMessageSize)
+	for {
+		n, from, err := pc.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		l.handle(string(buf[:n]), from)
+	}
+}
+
+// public
+
+// Addr returns the address the listener is bound to
+func (l *Listener) Addr() net.Addr {
+	return l.addr
+}
+
+// Close stops receiving messages
+func (l *Listener) Close() error {
+	close(l.done)
+	err := l.closer.Close()
+	l.mu.Lock()
+	for conn := range l.conns {
+		conn.Close()
+	}
+	l.mu.Unlock()
+	l.wg.Wait()
+	return err
+}
+
+// Dropped returns the number of messages dropped because the queue was full or the rate was exceeded
+func (l *Listener) Dropped() int64 {
+	return l.dropped.Load()
+}
+
+// GetPathRel returns the listen url
+func (l *Listener) GetPathRel() string {
+	return l.url
+}
+
+// Listen starts receiving messages on the given url (udp://host:port, tcp://host:port or tls://host:port),
+// the queue, policy and rate query parameters configure backpressure
+func Listen(rawURL string, tlsConfig *tls.Config) (*Listener, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("error(syslog): invalid listen url %q", rawURL)
+	}
+	query := u.Query()
+	size, rate := queueSize, 0
+	for _, param := range []struct {
+		name  string
+		value *int
+	}{
+		{"queue", &size},
+		{"rate", &rate},
+	} {
+		if v := query.Get(param.name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("error(syslog): invalid %s %q in %s", param.name, v, rawURL)
+			}
+			*param.value = n
+		}
+	}
+	l := &Listener{
+		conns:  make(map[net.Conn]struct{}),
+		done:   make(chan struct{}),
+		policy: PolicyBlock,
+		queue:  make(chan *stream.LogEntry, size),
+		url:    rawURL,
+	}
+	if policy := query.Get("policy"); policy != "" {
+		if policy != PolicyBlock && policy != PolicyDrop && policy != PolicyDropOldest {
+			return nil, fmt.Errorf("error(syslog): unknown policy %q (available: %s, %s, %s)", policy, PolicyBlock, PolicyDrop, PolicyDropOldest)
+		}
+		l.policy = policy
+	}
+	if rate > 0 {
+		l.limiter = newLimiter(rate)
+	}
+	switch u.Scheme {
+	case schemeUDP:
+		pc, err := net.ListenPacket("udp", u.Host)
+		if err != nil {
+			return nil, fmt.Errorf("error(syslog): %w", err)
+		}
+		l.addr, l.closer = pc.LocalAddr(), pc
+		l.wg.Add(1)
+		go l.receive(pc)
+	case schemeTCP, schemeTLS:
+		if u.Scheme == schemeTLS && tlsConfig == nil {
+			return nil, fmt.Errorf("error(syslog): tls requires certificate and key")
+		}
+		ln, err := net.Listen("tcp", u.Host)
+		if err != nil {
+			return nil, fmt.Errorf("error(syslog): %w", err)
+		}
+		if u.Scheme == schemeTLS {
+			ln = tls.NewListener(ln, tlsConfig)
+		}
+		l.addr, l.closer = ln.Addr(), ln
+		l.wg.Add(1)
+		go l.accept(ln)
+	default:
+		return nil, fmt.Errorf("error(syslog): unknown scheme %q (available: %s, %s, %s)", u.Scheme, schemeUDP, schemeTCP, schemeTLS)
+	}
+	return l, nil
+}
+
+// Next returns the next received entry (returns nil if no entry is available yet)
+func (l *Listener) Next() *stream.LogEntry {
+	select {
+	case entry := <-l.queue:
+		return entry
+	default:
+		return nil
+	}
+}
+
+// TakeErrors returns all parsing errors encountered since the last call and clears them
+func (l *Listener) TakeErrors() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	errors := l.errors
+	l.errors = make([]string, 0)
+	return errors
+}
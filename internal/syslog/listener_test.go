@@ -0,0 +1,250 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package syslog
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+const testMessage = `<134>1 2025-10-10T10:05:00+02:00 opnsense.filter.log filterlog 86605 - [meta sequenceId="1"] ` +
+	"68,,,2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e,eth1,match,block,in,4,0x0,,64,0,0,DF,6,tcp,60,203.0.113.10,198.51.100.1,51000,22,0"
+
+// waitEntries polls the listener until n entries have been received
+func waitEntries(t *testing.T, l *Listener, n int) []*stream.LogEntry {
+	t.Helper()
+	var entries []*stream.LogEntry
+	deadline := time.Now().Add(5 * time.Second)
+	for len(entries) < n && time.Now().Before(deadline) {
+		if entry := l.Next(); entry != nil {
+			entries = append(entries, entry)
+			continue
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(entries) != n {
+		t.Fatalf("expected %d entries, got %d", n, len(entries))
+	}
+	return entries
+}
+
+func TestListenUDP(t *testing.T) {
+	l, err := Listen("udp://127.0.0.1:0", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	conn, err := net.Dial("udp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte(testMessage))
+	// senders without hostname are identified by their address
+	conn.Write([]byte(strings.Replace(testMessage, "opnsense.filter.log", "-", 1)))
+	entries := waitEntries(t, l, 2)
+	if entries[0].Src != "203.0.113.10" || entries[0].DstPort != 22 || entries[0].Origin != "opnsense.filter.log" {
+		t.Fatalf("unexpected entry: %+v", entries[0])
+	}
+	if entries[1].Origin != "127.0.0.1" {
+		t.Fatalf("expected sender address as origin, got %q", entries[1].Origin)
+	}
+}
+
+func TestListenTCP(t *testing.T) {
+	l, err := Listen("tcp://127.0.0.1:0", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// newline framing, octet counting, other apps and invalid messages
+	other := "<134>1 2025-10-10T10:05:00+02:00 opnsense configd.py 123 - - message"
+	invalid := `<134>1 invalid opnsense.filter.log filterlog 86605 - [meta sequenceId="1"] 68,,,`
+	fmt.Fprintf(conn, "%s\n%d %s%s\r\n%s\n", testMessage, len(testMessage), testMessage, other, invalid)
+	conn.Close()
+	waitEntries(t, l, 2)
+	deadline := time.Now().Add(5 * time.Second)
+	var errors []string
+	for len(errors) == 0 && time.Now().Before(deadline) {
+		errors = l.TakeErrors()
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(errors) != 1 || !strings.Contains(errors[0], "invalid message from") {
+		t.Fatalf("expected 1 invalid message error, got %v", errors)
+	}
+	if l.GetPathRel() != "tcp://127.0.0.1:0" {
+		t.Fatalf("expected listen url as path, got %q", l.GetPathRel())
+	}
+}
+
+func TestReadFrame(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    []string
+		expectError bool
+	}{
+		{
+			name:     "newline",
+			input:    "a b\nc\n",
+			expected: []string{"a b\n", "c\n"},
+		},
+		{
+			name:     "octet counting",
+			input:    "3 a b5 c\nd e",
+			expected: []string{"a b", "c\nd e"},
+		},
+		{
+			name:     "missing trailing newline",
+			input:    "<134>1 a",
+			expected: []string{"<134>1 a"},
+		},
+		{
+			name:        "length too large",
+			input:       "99999999 a",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := bufio.NewReader(strings.NewReader(tc.input))
+			var messages []string
+			for {
+				msg, err := readFrame(r)
+				if err != nil {
+					if tc.expectError && !strings.Contains(err.Error(), "EOF") {
+						return
+					}
+					break
+				}
+				messages = append(messages, msg)
+			}
+			if tc.expectError {
+				t.Fatal("expected error, got nil")
+			}
+			if strings.Join(messages, "|") != strings.Join(tc.expected, "|") {
+				t.Fatalf("expected %q, got %q", tc.expected, messages)
+			}
+		})
+	}
+}
+
+func TestListen(t *testing.T) {
+	tests := []struct {
+		name        string
+		url         string
+		expectError string
+	}{
+		{name: "unknown scheme", url: "http://127.0.0.1:0", expectError: "unknown scheme"},
+		{name: "missing host", url: "udp://", expectError: "invalid listen url"},
+		{name: "tls without certificate", url: "tls://127.0.0.1:0", expectError: "requires certificate"},
+		{name: "invalid queue", url: "udp://127.0.0.1:0?queue=0", expectError: "invalid queue"},
+		{name: "invalid rate", url: "udp://127.0.0.1:0?rate=fast", expectError: "invalid rate"},
+		{name: "unknown policy", url: "udp://127.0.0.1:0?policy=ignore", expectError: "unknown policy"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Listen(tc.url, nil)
+			if err == nil || !strings.Contains(err.Error(), tc.expectError) {
+				t.Fatalf("expected error containing %q, got %v", tc.expectError, err)
+			}
+		})
+	}
+}
+
+func TestPolicies(t *testing.T) {
+	message := func(port int) string {
+		return strings.Replace(testMessage, ",51000,22,", fmt.Sprintf(",51000,%d,", port), 1)
+	}
+	tests := []struct {
+		name            string
+		query           string
+		expectedPorts   []uint16
+		expectedDropped int64
+	}{
+		{name: "drop", query: "queue=2&policy=drop", expectedPorts: []uint16{1, 2}, expectedDropped: 1},
+		{name: "drop oldest", query: "queue=2&policy=drop-oldest", expectedPorts: []uint16{2, 3}, expectedDropped: 1},
+		{name: "rate", query: "rate=2&policy=drop", expectedPorts: []uint16{1, 2}, expectedDropped: 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			l, err := Listen("udp://127.0.0.1:0?"+tc.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer l.Close()
+			for port := 1; port <= 3; port++ {
+				l.handle(message(port), l.Addr())
+			}
+			var ports []uint16
+			for entry := l.Next(); entry != nil; entry = l.Next() {
+				ports = append(ports, entry.DstPort)
+			}
+			if fmt.Sprint(ports) != fmt.Sprint(tc.expectedPorts) {
+				t.Fatalf("expected ports %v, got %v", tc.expectedPorts, ports)
+			}
+			if l.Dropped() != tc.expectedDropped {
+				t.Fatalf("expected %d dropped, got %d", tc.expectedDropped, l.Dropped())
+			}
+		})
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	l := newLimiter(2)
+	now := time.Date(2025, 10, 10, 10, 0, 0, 0, time.UTC)
+	for i := range 2 {
+		if ok, _ := l.reserve(now); !ok {
+			t.Fatalf("expected token %d to be available", i+1)
+		}
+	}
+	ok, wait := l.reserve(now)
+	if ok || wait != 500*time.Millisecond {
+		t.Fatalf("expected no token and 500ms wait, got %v and %v", ok, wait)
+	}
+	if ok, _ := l.reserve(now.Add(500 * time.Millisecond)); !ok {
+		t.Fatal("expected token after 500ms")
+	}
+	// tokens don't accumulate beyond one second
+	now = now.Add(time.Minute)
+	for range 2 {
+		l.reserve(now)
+	}
+	if ok, _ := l.reserve(now); ok {
+		t.Fatal("expected bucket to be limited to the rate")
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package tui
+
+import (
+	"fmt"
+	"os"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"gitlab.com/allddd/opnsense-filterlog/internal/meta"
+)
+
+const crashMessages = 20 // number of recent messages kept for the crash report
+
+// crashMsg reports a panic recovered in a command
+type crashMsg struct {
+	value any    // value passed to panic
+	stack []byte // stack trace of the panicking goroutine
+}
+
+// crash collects the recent messages of the TUI and the outcome of a panic (shared by the models of a program,
+// only used by the event loop)
+type crash struct {
+	err      error    // error writing the crash report
+	messages []string // recent messages (oldest first)
+	path     string   // path of the crash report
+	value    any      // value passed to panic (nil unless crashed)
+}
+
+// guard returns cmd recovering from panics, which are reported to the event loop as crashMsg, and counting it as
+// pending while it runs (commands of batches are guarded as well)
+func guard(cmd tea.Cmd) tea.Cmd {
+	if cmd == nil {
+		return nil
+	}
+	return func() (msg tea.Msg) {
+		pendingCmds.Add(1)
+		defer pendingCmds.Add(-1)
+		defer func() {
+			if r := recover(); r != nil {
+				msg = crashMsg{value: r, stack: debug.Stack()}
+			}
+		}()
+		msg = cmd()
+		if batch, ok := msg.(tea.BatchMsg); ok {
+			guarded := make(tea.BatchMsg, len(batch))
+			for i, c := range batch {
+				guarded[i] = guard(c)
+			}
+			return guarded
+		}
+		return msg
+	}
+}
+
+// record adds msg to the recent messages
+func (c *crash) record(msg tea.Msg) {
+	if len(c.messages) == crashMessages {
+		c.messages = c.messages[1:]
+	}
+	c.messages = append(c.messages, time.Now().Format(time.TimeOnly)+" "+describe(msg))
+}
+
+// report returns the crash report of the panic (value and stack trace) in m
+func (c *crash) report(m model, value any, stack []byte) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s crashed at %s\n\n", meta.Name, meta.Version, time.Now().Format(time.RFC3339))
+	fmt.Fprintf(&b, "panic: %v\n", value)
+	path, err := m.stream.GetPathAbs()
+	if err != nil {
+		path = m.stream.GetPathRel()
+	}
+	fmt.Fprintf(&b, "file: %s\n", path)
+	if m.opts.Profile != "" {
+		fmt.Fprintf(&b, "profile: %s\n", m.opts.Profile)
+	}
+	if m.filterApplied {
+		fmt.Fprintf(&b, "filter: %s\n", m.filterInput.Value())
+	}
+	fmt.Fprintf(&b, "entries: %d (%d available, cursor at %d)\n", m.entriesTotal, len(m.entriesAvailable), m.uiCursor)
+	fmt.Fprintf(&b, "terminal: %dx%d\n", m.uiWidth, m.uiHeight)
+	b.WriteString("\nrecent messages:\n")
+	for _, msg := range c.messages {
+		b.WriteString("  " + msg + "\n")
+	}
+	b.WriteString("\nstack:\n")
+	b.Write(stack)
+	return b.String()
+}
+
+// write writes the crash report of the panic to a temporary file (only the first panic is reported)
+func (c *crash) write(m model, value any, stack []byte) {
+	if c.value != nil {
+		return
+	}
+	c.value = value
+	file, err := os.CreateTemp("", meta.Name+"-crash-*.txt")
+	if err != nil {
+		c.err = err
+		return
+	}
+	defer file.Close()
+	if _, err := file.WriteString(c.report(m, value, stack)); err != nil {
+		c.err = err
+		return
+	}
+	c.path = file.Name()
+}
+
+// error returns the error returned by Display once the TUI crashed (nil if it didn't)
+func (c *crash) error() error {
+	switch {
+	case c.value == nil:
+		return nil
+	case c.err != nil:
+		return fmt.Errorf("error(tui): crashed (%v), could not write crash report: %w", c.value, c.err)
+	}
+	return fmt.Errorf("error(tui): crashed (%v), crash report written to %s", c.value, c.path)
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package tui
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"gitlab.com/allddd/opnsense-filterlog/internal/bookmark"
+)
+
+func TestGuard(t *testing.T) {
+	if guard(nil) != nil {
+		t.Error("expected nil command")
+	}
+	boom := func() tea.Msg { panic("boom") }
+	if msg, ok := guard(boom)().(crashMsg); !ok || msg.value != "boom" || len(msg.stack) == 0 {
+		t.Fatalf("expected crash message, got %#v", msg)
+	}
+	batch, ok := guard(tea.Batch(boom, boom))().(tea.BatchMsg)
+	if !ok || len(batch) != 2 {
+		t.Fatalf("expected batch of 2 commands, got %#v", batch)
+	}
+	for _, cmd := range batch {
+		if _, ok := cmd().(crashMsg); !ok {
+			t.Error("expected commands of batches to be guarded")
+		}
+	}
+}
+
+func TestCrash(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	m := testModel(t, 3)
+	m.filterApplied = true
+	m.filterInput.SetValue("port 22")
+	m.bookmarks = []bookmark.Bookmark{{File: "filter.log", Line: 1}}
+	m.bookmarksView = true
+
+	// removing a bookmark without a store panics
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	if cmd == nil {
+		t.Fatal("expected quit command")
+	}
+	if _, ok := cmd().(tea.QuitMsg); !ok {
+		t.Error("expected quit message")
+	}
+	if view := updated.View(); view != "" {
+		t.Errorf("expected nothing to be rendered after crashing, got %q", view)
+	}
+	err := m.crash.error()
+	if err == nil || !strings.Contains(err.Error(), m.crash.path) {
+		t.Fatalf("expected error naming the crash report, got %v", err)
+	}
+	data, err := os.ReadFile(m.crash.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"panic: runtime error", "filter: port 22", "tea.KeyMsg x", "handleBookmarksInput"} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("expected crash report to contain %q, got:\n%s", expected, data)
+		}
+	}
+
+	// only the first panic is reported
+	path := m.crash.path
+	m.Update(crashMsg{value: "again"})
+	if m.crash.path != path || m.crash.value == "again" {
+		t.Error("expected the first crash report to be kept")
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package tui
+
+import (
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// pendingCmds is the number of commands that are running (counted by guard)
+var pendingCmds atomic.Int64
+
+// hud collects the measurements of the performance overlay (shared by the models of a program, only used by the
+// event loop)
+type hud struct {
+	frame   time.Duration // render time of the last frame
+	loaded  int           // rows of the last frame whose entries were in memory
+	loading int           // rows of the last frame whose entries were still being loaded
+	shown   bool          // whether the overlay is shown (toggled with D)
+}
+
+// line returns the overlay line of m
+func (h *hud) line(m model) string {
+	hits, misses := m.filterCache.Stats()
+	rate := "-"
+	if hits+misses > 0 {
+		rate = fmt.Sprintf("%d%%", hits*100/(hits+misses))
+	}
+	return fmt.Sprintf("frame: %s | entries in memory: %d+%d filtered (max %d) | rows: %d loaded, %d loading | filter cache: %d/%d hits (%s) | pending: %d | goroutines: %d",
+		h.frame.Round(time.Microsecond), len(m.entries), len(m.entriesFiltered), m.entriesMax, h.loaded, h.loading,
+		hits, hits+misses, rate, pendingCmds.Load(), runtime.NumGoroutine())
+}
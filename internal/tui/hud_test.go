@@ -0,0 +1,68 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestHUD(t *testing.T) {
+	var m tea.Model = testModel(t, 50)
+	m, _ = m.Update(tea.WindowSizeMsg{Width: 300, Height: 12})
+	if strings.Contains(m.View(), "frame:") {
+		t.Fatal("expected the overlay to be hidden by default")
+	}
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("D")})
+	got := m.(model)
+	if got.contentHeight() != 12-4 {
+		t.Errorf("expected the overlay to take up a line, got content height %d", got.contentHeight())
+	}
+	got.View()
+	view := got.View()
+	if lines := strings.Count(view, "\n") + 1; lines != 12 {
+		t.Errorf("expected 12 lines, got %d", lines)
+	}
+	for _, expected := range []string{"frame: ", "entries in memory: 50+0 filtered", fmt.Sprintf("rows: %d loaded, 0 loading", got.contentHeight()), "filter cache: 0/0 hits (-)"} {
+		if !strings.Contains(view, expected) {
+			t.Errorf("expected overlay to contain %q, got:\n%s", expected, view)
+		}
+	}
+
+	// the cursor stays visible with the overlay
+	got.uiCursor = 49
+	got.moveCursor(49)
+	if start := got.logStart(); got.logEnd(start) != 50 {
+		t.Errorf("expected the last entry to be visible, got %d-%d", start, got.logEnd(start))
+	}
+
+	m, _ = got.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("D")})
+	if strings.Contains(m.View(), "frame:") {
+		t.Error("expected the overlay to be hidden again")
+	}
+}
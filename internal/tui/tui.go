@@ -24,50 +24,176 @@
 package tui
 
 import (
+	"cmp"
+	"context"
+	"errors"
 	"fmt"
 	"maps"
+	"net/netip"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime/debug"
+	"slices"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"gitlab.com/allddd/opnsense-filterlog/internal/annotation"
+	"gitlab.com/allddd/opnsense-filterlog/internal/bookmark"
+	"gitlab.com/allddd/opnsense-filterlog/internal/config"
+	"gitlab.com/allddd/opnsense-filterlog/internal/debuglog"
 	"gitlab.com/allddd/opnsense-filterlog/internal/filter"
+	"gitlab.com/allddd/opnsense-filterlog/internal/incident"
 	"gitlab.com/allddd/opnsense-filterlog/internal/meta"
+	"gitlab.com/allddd/opnsense-filterlog/internal/netclass"
+	"gitlab.com/allddd/opnsense-filterlog/internal/plugin"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stats"
 	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
 )
 
 const (
-	maxEntriesInMemory = 1000
-
-	// column widths (default view)
-	colWidthTime      = 16
-	colWidthAction    = 10
-	colWidthInterface = 10
-	colWidthDir       = 5
-	colWidthSource    = 40
-	colWidthSrcPort   = 7
-	colWidthDest      = 40
-	colWidthDstPort   = 7
-	colWidthProto     = 10
-	colWidthReason    = 20
-
-	// contentWidth is the total width of default view
-	contentWidth = colWidthTime + colWidthAction + colWidthInterface + colWidthDir + colWidthSource +
-		colWidthSrcPort + colWidthDest + colWidthDstPort + colWidthProto + colWidthReason
+	alertsInterval     = 2 * time.Second // interval of checking for new entries of alerting severity levels
+	alertsWindow       = 5 * time.Minute // window of the alert badge (entries older than this are not counted)
+	builderValues      = 200             // observed values offered per field in filter builder view (most frequent first)
+	commandTimeout     = 30 * time.Second
+	filterCacheSize    = 16          // filter expressions kept with their matching lines (see filter.Cache)
+	followInterval     = time.Second // interval of checking for appended lines in follow mode
+	interfacesInterval = time.Second // interval of checking for new entries in interfaces view
+	indexLines         = 100000      // lines indexed per step (entries are shown after the first step)
+	loadWorkers        = 4           // readers used to load non-contiguous entries concurrently
+	natScanLines       = 100         // lines read before and after a translation entry to find translated entries
+
+	// memory
+	entriesInMemoryDefault = 1000 // entries kept in memory if no memory limit is set
+	entriesInMemoryMin     = 100  // entries kept in memory with the lowest memory limit
+	entrySize              = 512  // approximate size of a parsed entry in memory (in bytes)
+
+	// terminal size
+	uiCompactWidth = 100 // terminals narrower than this show the log view with compact columns (see columnsCompact)
+	uiMinHeight    = 4   // terminals smaller than this show a message instead of the UI (header, status, help and an entry)
+	uiMinWidth     = 40
+
+	// column widths (default view, optional columns)
+	colWidthLabel    = 24
+	colWidthOrigin   = 20
+	colWidthSeverity = 8
+
+	// column widths (bookmarks view)
+	bookmarksWidthFile = 24
+	bookmarksWidthLine = 8
+
+	// column widths (interfaces view)
+	interfacesWidthName  = 16
+	interfacesWidthCount = 10
+
+	// column widths (filter builder view)
+	builderWidthValue = 40
+
+	// column widths (frequency view)
+	frequencyWidthValue = 40
+	frequencyWidthCount = 10
+
+	// column widths (heatmap view)
+	heatmapWidthDate  = 10
+	heatmapWidthCell  = 3
+	heatmapWidthTotal = 9
+)
+
+// filter builder steps
+const (
+	builderStepField    = iota // choosing the field of the condition
+	builderStepOperator        // choosing whether the field is or is not the value
+	builderStepValue           // choosing or typing the value
+	builderStepNext            // choosing how to continue the expression
 )
 
 var (
-	// headerLineFormat is the format string for rendering the log view header
-	headerLineFormat = fmt.Sprintf("%%-%ds %%-%ds %%-%ds %%-%ds %%-%ds %%-%ds %%-%ds %%-%ds %%-%ds %%-%ds",
-		colWidthTime, colWidthAction, colWidthInterface, colWidthDir, colWidthSource,
-		colWidthSrcPort, colWidthDest, colWidthDstPort, colWidthProto, colWidthReason,
-	)
+	// columnsDefault are the columns of the log view
+	columnsDefault = columns{
+		widths:     []int{16, 10, 10, 5, 40, 7, 40, 7, 10, 20},
+		timeFormat: "Jan 02 15:04:05",
+	}
+
+	// columnsCompact are the columns of the log view on narrow terminals (long addresses are truncated)
+	columnsCompact = columns{
+		widths:     []int{8, 6, 9, 3, 15, 7, 15, 7, 5, 10},
+		timeFormat: time.TimeOnly,
+	}
+
+	// highlightFields are the fields of the log view columns as named by filter.Highlights (indexed by column)
+	highlightFields = []string{"time", "action", "interface", "direction", "source", "srcport", "destination",
+		"dstport", "protocol", "reason"}
+
+	// heatmapCells are the heatmap cells per intensity level (level 0 means no entries)
+	heatmapCells = []string{" ·", "░░", "▒▒", "▓▓", "██"}
+
+	// heatmapCellsAccessible are the heatmap cells per intensity level in accessible mode (read out by screen readers)
+	heatmapCellsAccessible = []string{" 0", " 1", " 2", " 3", " 4"}
+
+	// builderExamples are the values offered for fields matched against the time of entries in filter builder view
+	builderExamples = map[string][]string{
+		"age":     {"<10m", "<1h", "<24h", ">24h"},
+		"hour":    {"08-17", "22-06"},
+		"weekday": {"mon-fri", "sat,sun"},
+	}
+
+	// frequencyFields are the fields the entries can be grouped by in frequency view (in the order tab cycles through)
+	frequencyFields = []string{"src", "dst", "dport", "reason"}
+
+	// sortKeys are the keys choosing the column to sort by after pressing S (see sortEntries)
+	sortKeys = map[string]int{"t": colTime, "a": colAction, "i": colInterface, "d": colDir, "s": colSource,
+		"S": colSrcPort, "D": colDest, "P": colDstPort, "p": colProto, "r": colReason}
+
+	// sortFields are the fields of the log view columns as named by LogEntry.Field (indexed by column)
+	sortFields = []string{"time", "action", "iface", "dir", "src", "sport", "dst", "dport", "proto", "reason"}
+
+	// placeholderRegexp matches {field} placeholders in command templates
+	placeholderRegexp = regexp.MustCompile(`\{([a-z]+)\}`)
 )
 
+// Opener opens the log of the profile with the given name
+type Opener func(profile string) (*stream.Stream, error)
+
+// Printer writes an entry printed after the TUI has exited
+type Printer func(entry *stream.LogEntry) error
+
+// Options represents optional settings of the TUI
+type Options struct {
+	Accessible  bool                           // render without colors and animations, with textual markers instead (screen readers)
+	Annotations []annotation.Annotation        // notes shown as separator rows between the entries they fall between
+	Bookmarks   *bookmark.Store                // bookmarks of entries (bookmarks are disabled if nil)
+	Columns     bool                           // record columns while indexing to answer simple filters without reading the file
+	Follow      bool                           // show the lines appended to the file as they are written (can be toggled in the TUI)
+	HideNDP     bool                           // hide icmpv6 neighbor discovery entries (can be toggled in the TUI)
+	Terms       bool                           // record terms of every block while indexing to skip blocks when searching
+	Inline      bool                           // render in the terminal instead of the alternate screen (the last view stays in the scrollback)
+	MemoryLimit int                            // approximate memory used for entries in MB (default if 0)
+	Mmap        bool                           // map the log file into memory to speed up loading entries
+	Open        Opener                         // opens the log of a profile (profile switcher is disabled if nil)
+	Print       Printer                        // receives the entries of the log view after exiting (the TUI is rendered on stderr if set)
+	PrintAll    bool                           // print all entries matching the filter instead of those shown in the log view
+	Profile     string                         // name of the profile of the displayed log (empty if none)
+	Reload      func() (*config.Config, error) // reloads the config file if it changed (nil config if unchanged, not watched if nil)
+}
+
 type model struct {
-	stream  *stream.Stream // log file stream
-	indexed bool           // whether file has been indexed
+	cfg      *config.Config   // user configuration
+	crash    *crash           // recent messages and panic (shared with the models of other profiles and files)
+	hud      *hud             // performance overlay (shared with the models of other profiles and files)
+	enricher *plugin.Enricher // enrichment plugin (nil if not configured)
+	opts     Options          // optional settings
+	stream   *stream.Stream   // log file stream
+	indexed  bool             // whether the first entries have been indexed
+	indexing bool             // whether the rest of the file is being indexed in the background
+	progress float64          // share of the file that has been indexed
 
 	// entries
 	entries          []stream.LogEntry       // contiguous block of entries (default view)
@@ -75,59 +201,282 @@ type model struct {
 	entriesFiltered  map[int]stream.LogEntry // non-contiguous block of entries matching current filter (filter view)
 	entriesTotal     int                     // total number of valid log entries
 	entriesAvailable []int                   // line numbers that can be displayed (all lines in default view, matching lines in filter view)
+	entriesMax       int                     // maximum number of entries kept in memory (per block)
+	entriesNext      []stream.LogEntry       // contiguous block prefetched in scroll direction (default view)
+	entriesNextStart int                     // number of first line in prefetched block
+	loading          bool                    // whether a block is being loaded from the stream (not a clone)
+	prefetching      bool                    // whether a block is being prefetched
+
+	// follow
+	follow    bool // whether appended lines are indexed and shown as they are written (follow mode)
+	followGen int  // generation of the follow ticks (ticks of a previous generation are dropped)
+	followEnd bool // whether to select the newest entry once the appended lines have been checked
 
 	// filter
-	filterApplied  bool              // whether filter is currently applied
-	filterCompiled filter.FilterNode // compiled filter expression
-	filterError    string            // error message from filter compilation
-	filterInput    textinput.Model   // filter input field
-	filterView     bool              // whether the user is currently typing filter expression
+	filterApplied    bool              // whether filter view is shown (filter is set, icmpv6 housekeeping is hidden or entries are sorted)
+	filterCache      *filter.Cache     // compiled filter expressions and their matching lines (kept across files)
+	filterCompiled   filter.FilterNode // compiled filter expression (nil if none)
+	filterError      string            // error message from filter compilation
+	filterInput      textinput.Model   // filter input field
+	filterInputError *filter.Error     // error in the filter input, shown with a caret below it while typing (nil if none)
+	filterWarning    string            // likely mistakes in the applied filter (e.g. misspelled field names)
+	filterView       bool              // whether the user is currently typing filter expression
+	hideHousekeeping bool              // whether icmpv6 neighbor discovery entries are hidden
+
+	// filter builder
+	builderChoices []builderChoice // choices of the current step (narrowed by builderInput)
+	builderCursor  int             // selected choice (index in narrowed choices)
+	builderField   string          // field of the condition being built
+	builderInput   textinput.Model // narrows the choices (typed value is offered in value step)
+	builderJoin    string          // operator joining the condition being built to builderParts (and, or)
+	builderNegate  bool            // whether the condition being built is negated
+	builderParts   []string        // conditions built so far and the operators joining them
+	builderStep    int             // step of the condition being built
+	builderView    bool            // whether showing filter builder instead of logs (builder view)
+
+	// sort
+	sortCol     int  // column the entries are sorted by (-1 if in chronological order)
+	sortDesc    bool // whether the entries are sorted in descending order
+	sortPending bool // whether waiting for the key of the column to sort by (after pressing S)
+
+	// alerts
+	alerts         *stats.Alerts      // counters of recent entries of alerting severity levels (nil if not available)
+	alertsCounts   []stats.AlertCount // recent entries per alerting severity level (shown in status bar)
+	alertsFollower *stream.Follower   // follower reading the entries appended to the file (nil if not available)
+	alertsJump     bool               // whether to select the newest entry once the alerts filter has been applied
+
+	// bookmarks
+	bookmarks       []bookmark.Bookmark // bookmarks shown in bookmarks view (all files)
+	bookmarksCursor int                 // selected bookmark (index in bookmarks)
+	bookmarksFile   string              // path the bookmarks of the displayed file are stored under (empty if unknown)
+	bookmarksView   bool                // whether showing bookmarks instead of logs (bookmarks view)
+	noteInput       textinput.Model     // note input field
+	noteLine        int                 // line number of the entry being bookmarked
+	noteEntry       stream.LogEntry     // entry being bookmarked
+	noteView        bool                // whether the user is currently typing a note
 
 	// error
 	errors     []string // parse errors
 	errorsView bool     // whether showing errors instead of logs (error view)
 
+	// frequency
+	frequency       *stats.Frequency   // entries per value of frequencyFields (entries that can be displayed)
+	frequencyCursor int                // selected value (index in frequencyValues)
+	frequencyField  int                // field the entries are grouped by (index in frequencyFields)
+	frequencyValues []stats.ValueCount // values of the field the entries are grouped by
+	frequencyView   bool               // whether showing frequency instead of logs (frequency view)
+
+	// heatmap
+	heatmap        []stats.HeatmapDay // entries per day and hour of day
+	heatmapBlocked bool               // whether heatmap shows blocked entries only
+	heatmapView    bool               // whether showing heatmap instead of logs (heatmap view)
+
+	// interfaces
+	interfaces          *stats.Interfaces        // counters per interface (updated while interfaces view is shown)
+	interfacesCursor    int                      // selected interface (index in interfacesSummary)
+	interfacesLines     int                      // number of lines counted in interfaces
+	interfacesSummaries []stats.InterfaceSummary // summary per interface
+	interfacesView      bool                     // whether showing interfaces instead of logs (interfaces view)
+
+	// profiles
+	profiles       []string // names of all profiles
+	profilesCursor int      // selected profile (index in profiles)
+	profilesView   bool     // whether showing profile switcher instead of logs (profiles view)
+
+	// output
+	output      []string // lines shown in output view (command output or entry details)
+	outputTitle string   // header of output view
+	outputView  bool     // whether showing output instead of logs (output view)
+
 	// ui
 	uiHeight         int           // terminal height (in lines)
 	uiWidth          int           // terminal width (in chars)
 	uiLoading        bool          // whether showing loading spinner (loading view)
 	uiLoadingSpinner spinner.Model // loading spinner
+	uiClasses        bool          // whether address cells are tinted by address class
+	uiLabel          bool          // whether showing the label column
+	uiOrigin         bool          // whether showing the origin column
+	uiCursor         int           // selected entry (index in entriesAvailable)
 	uiScrollH        int           // horizontal scroll position
 	uiScrollV        int           // vertical scroll position
 	uiStatusMsg      string        // status bar message
 	uiStyles         *styles       // styles for rendering
 }
 
+// builderChoice is a choice of a filter builder step
+type builderChoice struct {
+	value string // field, operator, value or next action
+	note  string // shown next to the value (e.g. number of entries)
+}
+
 type styles struct {
-	header       lipgloss.Style
-	status       lipgloss.Style
-	statusError  lipgloss.Style
-	entryBlock   lipgloss.Style
-	entryLoading lipgloss.Style
+	header        lipgloss.Style
+	status        lipgloss.Style
+	statusError   lipgloss.Style
+	statusWarning lipgloss.Style
+	annotation    lipgloss.Style
+	entryBlock    lipgloss.Style
+	entryLoading  lipgloss.Style
+	entrySelected lipgloss.Style
+	heatmapBlock  []lipgloss.Style          // heatmap cell per intensity level (blocked entries)
+	heatmapEntry  []lipgloss.Style          // heatmap cell per intensity level (all entries)
+	severity      map[string]lipgloss.Style // severity cell per severity level
+	addrClass     map[string]lipgloss.Style // address cell per address class (public addresses are not tinted)
 }
 
 // message
 // messages are processed in the Update method and represent events that update the model
 
-// indexMsg is sent when the file has been successfully indexed
+// indexMsg is sent when the next part of the file has been indexed
 type indexMsg struct {
+	stream       *stream.Stream // stream that has been indexed (may belong to the previous profile)
+	entriesTotal int            // total number of valid log entries indexed so far
+	done         bool           // whether the whole file has been indexed
+	progress     float64        // share of the file that has been indexed
+	err          error          // error that occurred (if any)
+}
+
+// reloadMsg is sent when the lines appended to the file have been indexed
+type reloadMsg struct {
 	entriesTotal int // total number of valid log entries
 }
 
+// followTickMsg is sent when the file should be checked for appended lines (follow mode)
+type followTickMsg struct {
+	stream *stream.Stream // stream that is followed (may belong to the previous profile)
+	gen    int            // generation of the follow ticks
+}
+
+// followMsg is sent when the lines appended to the file have been indexed (follow mode)
+type followMsg struct {
+	stream       *stream.Stream // stream that is followed (may belong to the previous profile)
+	entriesTotal int            // total number of valid log entries
+	replaced     bool           // whether the file has been replaced or truncated (the index has been rebuilt)
+	err          error          // error that occurred (if any)
+}
+
+// followFilteredMsg is sent when the appended lines have been filtered (follow mode)
+type followFilteredMsg struct {
+	stream *stream.Stream // stream that is followed (may belong to the previous profile)
+	lines  []int          // appended lines matching the current filter
+	err    error          // error that occurred (if any)
+}
+
 // entriesMsg is sent when contiguous block of entries has been loaded
 type entriesMsg struct {
 	entries      []stream.LogEntry // contiguous block of entries (default view)
 	entriesStart int               // number of first line in entries block
+	prefetched   bool              // whether block was prefetched in the background
 }
 
 // entriesFilteredMsg is sent when non-contiguous block of entries matching current filter has been loaded
 type entriesFilteredMsg struct {
 	entriesFiltered map[int]stream.LogEntry // non-contiguous block of entries matching current filter (filter view)
+	err             error                   // error that occurred while loading (if any)
+}
+
+// sortMsg is sent when the entries have been sorted by the selected column
+type sortMsg struct {
+	entriesAvailable []int // line numbers in sorted order
 }
 
 // filterMsg is sent when filtering has completed
 type filterMsg struct {
 	entriesAvailable []int // line numbers that can be displayed
+	hidden           int   // number of matching icmpv6 neighbor discovery entries that are hidden
+}
+
+// commandMsg is sent when an external command has finished
+type commandMsg struct {
+	command string   // command line that was run
+	output  []string // combined stdout and stderr lines
+	err     error    // error that occurred (if any)
+}
+
+// detailMsg is sent when the selected entry is ready to be shown in detail
+type detailMsg struct {
+	entry   stream.LogEntry   // selected entry (enriched if plugin is configured)
+	related []stream.LogEntry // entries of translated packets (rdr, nat and binat only)
+	err     error             // enrichment or read error (if any)
+}
+
+// bruteforceMsg is sent when brute-force detection has completed
+type bruteforceMsg struct {
+	offenders []stats.Offender // sources that exceeded the threshold
+}
+
+// incidentMsg is sent when the incident bundle has been written
+type incidentMsg struct {
+	path    string // path of the bundle
+	entries int    // number of entries in the bundle
+	err     error  // error that occurred (if any)
+}
+
+// portsMsg is sent when the destination port summary has been built
+type portsMsg struct {
+	summaries []stats.PortSummary // summary per destination port
+}
+
+// rulesMsg is sent when the rule usage summary has been built
+type rulesMsg struct {
+	summaries []stats.RuleSummary // summary per firewall rule
+}
+
+// gapsMsg is sent when the gaps between entries have been analyzed
+type gapsMsg struct {
+	summary stats.GapSummary // distribution and largest gaps
+}
+
+// frequencyMsg is sent when the entries that can be displayed have been counted per value
+type frequencyMsg struct {
+	frequency *stats.Frequency // entries per value of frequencyFields
+}
+
+// heatmapMsg is sent when the hour of day heatmap has been built
+type heatmapMsg struct {
+	days []stats.HeatmapDay // counts per day and hour of day
+}
+
+// interfacesMsg is sent when the interface counters have been built
+type interfacesMsg struct {
+	interfaces *stats.Interfaces // counters per interface
+	lines      int               // number of lines counted
+	refresh    bool              // whether the counters were rebuilt while the view is shown
+}
+
+// alertsTickMsg is sent periodically while severity rules are configured
+type alertsTickMsg struct {
+	follower *stream.Follower // follower of the file (may belong to the previous file)
+}
+
+// alertsMsg is sent when the entries appended to the file have been read
+type alertsMsg struct {
+	follower *stream.Follower  // follower of the file (may belong to the previous file)
+	entries  []stream.LogEntry // new entries of alerting severity levels
+}
+
+// interfacesTickMsg is sent periodically while the interfaces view is shown
+type interfacesTickMsg struct{}
+
+// interfacesUpdateMsg is sent when the entries appended to the file have been read
+type interfacesUpdateMsg struct {
+	entries []stream.LogEntry // new entries matching the current filter
+	lines   int               // number of lines counted (including the new entries)
+	reset   bool              // whether the file has been replaced and has to be counted again
+	err     error             // error that occurred (if any)
+}
+
+// profileMsg is sent when the log of another profile has been opened
+type profileMsg struct {
+	name   string         // name of the profile
+	stream *stream.Stream // log file stream of the profile
+	err    error          // error that occurred (if any)
+}
+
+// rotationMsg is sent when the previous or next rotated log file has been opened
+type rotationMsg struct {
+	stream *stream.Stream // log file stream of the rotated file
+	err    error          // error that occurred (if any)
 }
 
 // streamErrorMsg is sent when a stream operation fails (e.g. SeekToLine)
@@ -135,17 +484,130 @@ type streamErrorMsg struct {
 	err error // error that occurred
 }
 
+// configMsg is sent when the config file has been reloaded after it changed
+type configMsg struct {
+	cfg *config.Config // reloaded configuration
+	err error          // error that occurred (if any)
+}
+
 // bubbletea
 
-// truncateString truncates a string to a maximum length
+// sanitizeString escapes control characters and invalid utf-8 (so log lines can't inject escape sequences)
+func sanitizeString(s string) string {
+	clean := true
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x20 || s[i] >= 0x7f {
+			clean = false
+			break
+		}
+	}
+	if clean {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == '\t':
+			b.WriteByte(' ')
+		case r == utf8.RuneError && size == 1:
+			fmt.Fprintf(&b, "\\x%02x", s[i])
+		case unicode.IsControl(r) || unicode.Is(unicode.Cf, r):
+			fmt.Fprintf(&b, "\\u%04x", r)
+		default:
+			b.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	return b.String()
+}
+
+// truncateString truncates a string to a maximum number of runes (after sanitizing it, so runes are never split)
 func truncateString(s string, length int) string {
-	if len(s) <= length {
+	s = sanitizeString(s)
+	if utf8.RuneCountInString(s) <= length {
 		return s
 	}
+	runes := []rune(s)
 	if length <= 3 {
-		return s[:length]
+		return string(runes[:length])
+	}
+	return string(runes[:length-3]) + "..."
+}
+
+// styledRange is a part of a line (from start to end) that is rendered with its own style
+type styledRange struct {
+	start int
+	end   int
+	style lipgloss.Style
+}
+
+// column indexes of the log view (see columns)
+const (
+	colTime = iota
+	colAction
+	colInterface
+	colDir
+	colSource
+	colSrcPort
+	colDest
+	colDstPort
+	colProto
+	colReason
+)
+
+// columns are the columns of the log view (see columnsDefault and columnsCompact)
+type columns struct {
+	widths     []int  // width per column (in chars, separated by a space)
+	timeFormat string // layout of the time column
+}
+
+// format returns the line of the values (one per column, truncated to the width of the column)
+func (c columns) format(values ...string) string {
+	var b strings.Builder
+	for i, width := range c.widths {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		fmt.Fprintf(&b, "%-*s", width, truncateString(values[i], width))
+	}
+	return b.String()
+}
+
+// offset returns the position of the column in the line
+func (c columns) offset(col int) int {
+	pos := 0
+	for _, width := range c.widths[:col] {
+		pos += width + 1 // +1 for the separating space
+	}
+	return pos
+}
+
+// width returns the width of the line
+func (c columns) width() int {
+	return c.offset(len(c.widths)) - 1
+}
+
+// renderLine renders the visible part of line (starting at offset, up to width chars) using base style,
+// except for the ranges (sorted and not overlapping) which use their own style
+func renderLine(line string, offset int, width int, base lipgloss.Style, ranges []styledRange) string {
+	visible := sliceString(line, offset, width)
+	render := func(style lipgloss.Style, start, end int) string {
+		start, end = min(max(start-offset, 0), len(visible)), min(max(end-offset, 0), len(visible))
+		if start >= end {
+			return ""
+		}
+		return style.Render(visible[start:end])
 	}
-	return s[:length-3] + "..."
+	var b strings.Builder
+	pos := offset
+	for _, r := range ranges {
+		b.WriteString(render(base, pos, r.start))
+		b.WriteString(render(r.style, r.start, r.end))
+		pos = r.end
+	}
+	b.WriteString(render(base, pos, offset+len(visible)))
+	return b.String()
 }
 
 // sliceString returns a substring starting at offset and up to width chars
@@ -171,22 +633,106 @@ func newStyles() *styles {
 		statusError: lipgloss.NewStyle().
 			Background(lipgloss.Color("196")).
 			Foreground(lipgloss.Color("231")),
+		statusWarning: lipgloss.NewStyle().
+			Background(lipgloss.Color("214")).
+			Foreground(lipgloss.Color("16")),
+		annotation: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("45")),
 		entryBlock: lipgloss.NewStyle().
 			Foreground(lipgloss.Color("202")),
 		entryLoading: lipgloss.NewStyle().
 			Foreground(lipgloss.Color("244")),
+		entrySelected: lipgloss.NewStyle().
+			Reverse(true),
+		heatmapBlock: heatmapStyles("52", "88", "160", "196"),
+		heatmapEntry: heatmapStyles("22", "28", "34", "46"),
+		severity: map[string]lipgloss.Style{
+			stream.SeverityInfo:     lipgloss.NewStyle().Foreground(lipgloss.Color("244")),
+			stream.SeverityNotice:   lipgloss.NewStyle().Foreground(lipgloss.Color("39")),
+			stream.SeverityWarning:  lipgloss.NewStyle().Foreground(lipgloss.Color("220")),
+			stream.SeverityCritical: lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("196")),
+		},
+		addrClass: map[string]lipgloss.Style{
+			netclass.ClassMine:      lipgloss.NewStyle().Foreground(lipgloss.Color("39")),
+			netclass.ClassLoopback:  lipgloss.NewStyle().Foreground(lipgloss.Color("244")),
+			netclass.ClassLinkLocal: lipgloss.NewStyle().Foreground(lipgloss.Color("244")),
+			netclass.ClassPrivate:   lipgloss.NewStyle().Foreground(lipgloss.Color("35")),
+			netclass.ClassCGN:       lipgloss.NewStyle().Foreground(lipgloss.Color("178")),
+			netclass.ClassBogon:     lipgloss.NewStyle().Foreground(lipgloss.Color("165")),
+		},
+	}
+}
+
+// newAccessibleStyles returns styles without colors (accessible mode)
+func newAccessibleStyles() *styles {
+	plain := lipgloss.NewStyle()
+	return &styles{
+		header:        plain,
+		status:        plain,
+		statusError:   plain,
+		statusWarning: plain,
+		annotation:    plain,
+		entryBlock:    plain,
+		entryLoading:  plain,
+		entrySelected: plain,
+		heatmapBlock:  slices.Repeat([]lipgloss.Style{plain}, len(heatmapCellsAccessible)),
+		heatmapEntry:  slices.Repeat([]lipgloss.Style{plain}, len(heatmapCellsAccessible)),
+		severity:      map[string]lipgloss.Style{},
+		addrClass:     map[string]lipgloss.Style{},
+	}
+}
+
+// heatmapStyles returns a style per heatmap intensity level (level 0 is an empty cell)
+func heatmapStyles(colors ...string) []lipgloss.Style {
+	styles := []lipgloss.Style{lipgloss.NewStyle().Foreground(lipgloss.Color("240"))}
+	for _, c := range colors {
+		styles = append(styles, lipgloss.NewStyle().Foreground(lipgloss.Color(c)))
+	}
+	return styles
+}
+
+// heatmapCounts returns the hourly counts of the given day for the selected metric
+func (m model) heatmapCounts(i int) [24]int {
+	if m.heatmapBlocked {
+		return m.heatmap[i].Blocked
+	}
+	return m.heatmap[i].Entries
+}
+
+// heatmapMax returns the highest hourly count of all days for the selected metric
+func (m model) heatmapMax() int {
+	maxCount := 0
+	for i := range m.heatmap {
+		for _, count := range m.heatmapCounts(i) {
+			maxCount = max(maxCount, count)
+		}
+	}
+	return maxCount
+}
+
+// heatmapLevel scales count to an intensity level between 0 (no entries) and levels
+func heatmapLevel(count, maxCount, levels int) int {
+	if count <= 0 || maxCount <= 0 {
+		return 0
 	}
+	return (count*levels + maxCount - 1) / maxCount
 }
 
 // loadingView returns a centered loading message with an animated spinner
 func (m model) loadingView() string {
-	s := fmt.Sprintf("%s\n\n%s %s", m.uiLoadingSpinner.View(), meta.Name, meta.Version)
+	spinner := m.uiLoadingSpinner.View()
+	if m.opts.Accessible {
+		spinner = "loading..."
+	}
+	s := fmt.Sprintf("%s\n\n%s %s", spinner, meta.Name, meta.Version)
 	if m.uiWidth == 0 || m.uiHeight == 0 {
 		return s
 	}
 	style := lipgloss.NewStyle().
 		Width(m.uiWidth).
 		Height(m.uiHeight).
+		MaxWidth(m.uiWidth).
+		MaxHeight(m.uiHeight).
 		Align(lipgloss.Center, lipgloss.Center)
 	return style.Render(s)
 }
@@ -194,16 +740,56 @@ func (m model) loadingView() string {
 // withLoadingView enables loading state and batches the command with spinner tick
 func (m *model) withLoadingView(cmd tea.Cmd) tea.Cmd {
 	m.uiLoading = true
+	if m.opts.Accessible {
+		// the spinner isn't animated in accessible mode
+		return cmd
+	}
 	return tea.Batch(cmd, m.uiLoadingSpinner.Tick)
 }
 
 // Init starts the indexing process
 func (m model) Init() tea.Cmd {
-	return m.withLoadingView(index(m.stream))
+	cmd := m.withLoadingView(index(m.stream))
+	if m.follow {
+		cmd = tea.Batch(cmd, tickFollow(m.stream, m.followGen))
+	}
+	if m.alertsFollower != nil {
+		cmd = tea.Batch(cmd, tickAlerts(m.alertsFollower))
+	}
+	return guard(cmd)
+}
+
+// Update handles all messages, panics are written to a crash report before quitting
+func (m model) Update(msg tea.Msg) (updated tea.Model, cmd tea.Cmd) {
+	if msg, ok := msg.(crashMsg); ok {
+		m.crash.write(m, msg.value, msg.stack)
+		return m, tea.Quit
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			m.crash.write(m, r, debug.Stack())
+			updated, cmd = m, tea.Quit
+		}
+	}()
+	m.crash.record(msg)
+	start := time.Now()
+	updated, cmd = m.update(msg)
+	if _, tick := msg.(spinner.TickMsg); !tick && debuglog.Enabled() {
+		debuglog.Debug("tui: message", "type", describe(msg), "duration", time.Since(start))
+	}
+	return updated, guard(cmd)
+}
+
+// describe returns the type of msg and the key of key presses (the content of other messages isn't included)
+func describe(msg tea.Msg) string {
+	if key, ok := msg.(tea.KeyMsg); ok {
+		return fmt.Sprintf("%T %s", msg, key.String())
+	}
+	return fmt.Sprintf("%T", msg)
 }
 
-// Update handles all messages (and is the main event loop)
-func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+// update handles all messages (and is the main event loop)
+func (m model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 
 	case spinner.TickMsg:
@@ -215,112 +801,755 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if m.filterView {
 			return m.handleFilterInput(msg)
 		}
+		if m.noteView {
+			return m.handleNoteInput(msg)
+		}
+		if m.bookmarksView {
+			return m.handleBookmarksInput(msg)
+		}
+		if m.profilesView {
+			return m.handleProfilesInput(msg)
+		}
+		if m.builderView {
+			return m.handleBuilderInput(msg)
+		}
+		if m.interfacesView {
+			return m.handleInterfacesInput(msg)
+		}
+		if m.frequencyView {
+			return m.handleFrequencyInput(msg)
+		}
+		if m.sortPending {
+			return m.handleSortInput(msg)
+		}
 		return m.handleNormalInput(msg)
 
 	case tea.WindowSizeMsg:
 		m.filterInput.Width = msg.Width - len(m.filterInput.Prompt) - 1 // -1 for cursor
+		m.noteInput.Width = msg.Width - len(m.noteInput.Prompt) - 1
+		m.builderInput.Width = msg.Width - len(m.builderInput.Prompt) - 1
 		m.uiHeight = msg.Height
 		m.uiWidth = msg.Width
+		// the columns may have switched between compact and default, keep the scroll positions within the view
+		m.uiScrollH = min(m.uiScrollH, max(m.logWidth()-m.uiWidth, 0))
+		if m.logView() {
+			m.moveCursor(m.uiCursor)
+		}
 		return m, nil
 
 	case indexMsg:
+		if msg.stream != m.stream {
+			// the profile has been switched meanwhile
+			return m, nil
+		}
+		if msg.err != nil {
+			m.indexing = false
+			m.uiLoading = false
+			m.uiStatusMsg = m.uiStyles.statusError.Render(sanitizeString(msg.err.Error()))
+			return m, nil
+		}
+		m.entriesTotal = msg.entriesTotal
+		m.errors = m.stream.GetErrors()
+		m.indexing = !msg.done
+		m.progress = msg.progress
+		var next tea.Cmd
+		if m.indexing {
+			next = index(m.stream)
+		}
+		if m.entriesTotal <= 0 {
+			if m.indexing {
+				return m, next
+			}
+			m.indexed = true
+			m.uiLoading = false
+			m.uiStatusMsg = m.uiStyles.statusError.Render("error(tui): no valid entries found")
+			return m, nil
+		}
+		if !m.indexed {
+			m.indexed = true
+			m.uiLoading = false
+			if !m.indexing && (m.filterCompiled != nil || m.hideHousekeeping) {
+				return m, m.applyFilter()
+			}
+			m.showAllLines()
+			m.loading = true
+			return m, tea.Batch(next, loadEntries(m.stream, 0, m.entriesMax))
+		}
+		if !m.indexing && (m.filterCompiled != nil || m.hideHousekeeping) {
+			return m, m.applyFilter()
+		}
+		// filters can't be applied while indexing, all lines are shown
+		for i := len(m.entriesAvailable); i < m.entriesTotal; i++ {
+			m.entriesAvailable = append(m.entriesAvailable, i)
+		}
+		return m, next
+
+	case followTickMsg:
+		if msg.stream != m.stream || msg.gen != m.followGen || !m.follow {
+			return m, nil
+		}
+		// wait for indexing and background loads, they read the index and the stream
+		if !m.indexed || m.indexing || m.reading() {
+			return m, tickFollow(m.stream, m.followGen)
+		}
+		return m, followIndex(m.stream)
+
+	case followMsg:
+		if msg.stream != m.stream || !m.follow {
+			return m, nil
+		}
+		if msg.err != nil {
+			m.follow = false
+			m.uiStatusMsg = m.uiStyles.statusError.Render(sanitizeString("follow: " + msg.err.Error()))
+			return m, nil
+		}
+		next := tickFollow(m.stream, m.followGen)
+		if msg.replaced {
+			// the file may have been replaced, drop all loaded entries like a reload
+			updated, cmd := m.Update(reloadMsg{entriesTotal: msg.entriesTotal})
+			return updated, tea.Batch(cmd, next)
+		}
+		start := m.entriesTotal
+		if msg.entriesTotal <= start {
+			return m, tea.Batch(m.followSelect(), next)
+		}
+		m.entriesTotal = msg.entriesTotal
+		m.errors = m.stream.GetErrors()
+		if m.filterApplied {
+			return m, m.followFilter(start, m.entriesTotal)
+		}
+		m.followEnd = m.followEnd || m.uiCursor >= len(m.entriesAvailable)-1
+		m.showAllLines()
+		m.uiStatusMsg = fmt.Sprintf("follow: %d new entries", m.entriesTotal-start)
+		return m, tea.Batch(m.followSelect(), next)
+
+	case followFilteredMsg:
+		if msg.stream != m.stream || !m.follow {
+			return m, nil
+		}
+		if msg.err != nil {
+			m.follow = false
+			m.uiStatusMsg = m.uiStyles.statusError.Render(sanitizeString("follow: " + msg.err.Error()))
+			return m, nil
+		}
+		m.followEnd = m.followEnd || m.uiCursor >= len(m.entriesAvailable)-1
+		m.entriesAvailable = append(m.entriesAvailable, msg.lines...)
+		m.uiStatusMsg = fmt.Sprintf("follow: %d new matches", len(msg.lines))
+		if m.sortCol >= 0 && len(msg.lines) > 0 {
+			// the appended lines are sorted into place
+			m.followEnd = false
+			return m, tea.Batch(m.withLoadingView(m.sortEntries()), tickFollow(m.stream, m.followGen))
+		}
+		return m, tea.Batch(m.followSelect(), tickFollow(m.stream, m.followGen))
+
+	case reloadMsg:
+		added := msg.entriesTotal - m.entriesTotal
 		m.entriesTotal = msg.entriesTotal
 		m.errors = m.stream.GetErrors()
-		m.indexed = true
 		m.uiLoading = false
+		// the file may have been replaced, drop all loaded entries
+		m.entries = m.entries[:0]
+		m.entriesNext = nil
+		m.entriesFiltered = make(map[int]stream.LogEntry)
 		if m.entriesTotal <= 0 {
+			m.entriesAvailable = m.entriesAvailable[:0]
 			m.uiStatusMsg = m.uiStyles.statusError.Render("error(tui): no valid entries found")
 			return m, nil
 		}
+		if m.filterApplied {
+			return m, m.withLoadingView(m.scanAndFilter())
+		}
 		m.showAllLines()
-		return m, loadEntries(m.stream, 0, maxEntriesInMemory)
+		m.uiCursor = min(m.uiCursor, m.entriesTotal-1)
+		m.uiScrollV = min(m.uiScrollV, m.uiCursor)
+		m.uiStatusMsg = fmt.Sprintf("reload: %d new entries", max(added, 0))
+		cmd := m.checkLoadEntries()
+		return m, cmd
 
 	case entriesMsg:
+		if msg.prefetched {
+			m.prefetching = false
+			if len(msg.entries) > 0 {
+				m.entriesNext = msg.entries
+				m.entriesNextStart = msg.entriesStart
+			}
+			return m, nil
+		}
+		m.loading = false
 		m.entries = msg.entries
 		m.entriesStart = msg.entriesStart
-		return m, nil
+		cmd := m.checkLoadEntries()
+		return m, cmd
 
 	case entriesFilteredMsg:
 		m.uiLoading = false
-		// merge new entries into entriesFiltered map
+		// merge new entries into entriesFiltered map (start over once the limit is reached)
+		if len(m.entriesFiltered)+len(msg.entriesFiltered) > m.entriesMax {
+			m.entriesFiltered = make(map[int]stream.LogEntry)
+		}
 		maps.Copy(m.entriesFiltered, msg.entriesFiltered)
+		if msg.err != nil {
+			// don't retry, the missing lines would fail again
+			m.uiStatusMsg = m.uiStyles.statusError.Render(sanitizeString(msg.err.Error()))
+			return m, nil
+		}
 		return m, m.checkLoadEntriesFiltered()
 
 	case filterMsg:
 		m.entriesFiltered = make(map[int]stream.LogEntry)
 		m.entriesAvailable = msg.entriesAvailable
 		m.uiLoading = false
+		m.uiCursor = 0
 		m.uiScrollH = 0
 		m.uiScrollV = 0
 		m.uiStatusMsg = fmt.Sprintf("filter: %q (%d matches)", m.filterInput.Value(), len(m.entriesAvailable))
+		if m.filterCompiled == nil {
+			m.uiStatusMsg = fmt.Sprintf("filter: none (%d entries)", len(m.entriesAvailable))
+		}
+		if m.hideHousekeeping {
+			m.uiStatusMsg += fmt.Sprintf(" | hidden: %d neighbor discovery", msg.hidden)
+		}
+		if m.alertsJump && len(m.entriesAvailable) > 0 {
+			// select the newest alert
+			m.uiCursor = len(m.entriesAvailable) - 1
+			m.uiScrollV = max(len(m.entriesAvailable)-m.contentHeight(), 0)
+		}
+		m.alertsJump = false
+		if m.sortCol >= 0 && len(m.entriesAvailable) > 0 {
+			return m, m.withLoadingView(m.sortEntries())
+		}
 		if len(m.entriesAvailable) > 0 {
 			return m, m.withLoadingView(m.checkLoadEntriesFiltered())
 		}
 		return m, nil
 
-	case streamErrorMsg:
+	case sortMsg:
+		m.uiLoading = false
+		m.entriesAvailable = msg.entriesAvailable
+		m.moveCursor(m.uiCursor)
+		return m, m.checkLoadEntriesFiltered()
+
+	case commandMsg:
+		if msg.err != nil {
+			m.uiStatusMsg = m.uiStyles.statusError.Render(fmt.Sprintf("error(tui): command %q: %v", msg.command, msg.err))
+		} else {
+			m.uiStatusMsg = fmt.Sprintf("command: %q", msg.command)
+		}
+		if len(msg.output) > 0 {
+			m.showOutput("Output: "+msg.command, msg.output)
+		}
+		return m, nil
+
+	case bruteforceMsg:
 		m.uiLoading = false
-		m.uiStatusMsg = m.uiStyles.statusError.Render(msg.err.Error())
+		var b strings.Builder
+		stats.WriteOffenders(&b, msg.offenders)
+		title := fmt.Sprintf("Brute-force: %d offenders (more than %d blocked attempts within %s)",
+			len(msg.offenders), m.cfg.Bruteforce.Threshold, time.Duration(m.cfg.Bruteforce.Window))
+		m.showOutput(title, strings.Split(strings.TrimRight(b.String(), "\n"), "\n"))
 		return m, nil
 
-	default:
-		if m.filterView {
-			var cmd tea.Cmd
-			m.filterInput, cmd = m.filterInput.Update(msg)
-			return m, cmd
+	case incidentMsg:
+		m.uiLoading = false
+		if msg.err != nil {
+			m.uiStatusMsg = m.uiStyles.statusError.Render(sanitizeString(msg.err.Error()))
+			return m, nil
 		}
+		m.uiStatusMsg = sanitizeString(fmt.Sprintf("incident: %s (%d entries)", msg.path, msg.entries))
 		return m, nil
-	}
-}
 
-// View renders the current state of the UI (as a string)
-func (m model) View() string {
-	// show loading view during initialization or on request
-	if m.uiLoading || m.uiWidth == 0 || m.uiHeight == 0 {
-		return m.loadingView()
-	}
+	case portsMsg:
+		m.uiLoading = false
+		var b strings.Builder
+		stats.WritePorts(&b, msg.summaries)
+		title := fmt.Sprintf("Ports: %d destination ports", len(msg.summaries))
+		m.showOutput(title, strings.Split(strings.TrimRight(b.String(), "\n"), "\n"))
+		return m, nil
 
-	var b strings.Builder
-	var visibleEnd int
+	case rulesMsg:
+		m.uiLoading = false
+		var b strings.Builder
+		stats.WriteRules(&b, msg.summaries)
+		title := fmt.Sprintf("Rules: %d firewall rules", len(msg.summaries))
+		m.showOutput(title, strings.Split(strings.TrimRight(b.String(), "\n"), "\n"))
+		return m, nil
 
-	contentHeight := m.uiHeight - 3 // -3 for the header, status, and help lines
-	newLine := "\n"
-	visibleStart := m.uiScrollV
+	case gapsMsg:
+		m.uiLoading = false
+		var b strings.Builder
+		stats.WriteGaps(&b, msg.summary)
+		title := fmt.Sprintf("Gaps: %d entries", msg.summary.Entries)
+		m.showOutput(title, strings.Split(strings.TrimRight(b.String(), "\n"), "\n"))
+		return m, nil
 
-	if m.errorsView {
-		visibleEnd = min(visibleStart+contentHeight, len(m.errors))
+	case frequencyMsg:
+		m.uiLoading = false
+		m.frequency = msg.frequency
+		m.frequencyCursor = 0
+		m.frequencyValues = msg.frequency.Values(frequencyFields[m.frequencyField])
+		m.frequencyView = true
+		return m, nil
 
-		// header
-		b.WriteString(m.uiStyles.header.Render("Error") + newLine)
+	case heatmapMsg:
+		m.uiLoading = false
+		m.heatmap = msg.days
+		m.heatmapView = true
+		m.errorsView = false
+		m.outputView = false
+		m.uiScrollH = 0
+		m.uiScrollV = 0
+		return m, nil
 
-		// main
-		for i := visibleStart; i < visibleEnd; i++ {
-			line := sliceString(m.errors[i], m.uiScrollH, m.uiWidth)
-			b.WriteString(line + newLine)
+	case interfacesMsg:
+		if msg.refresh && !m.interfacesView {
+			return m, nil
 		}
-		for i := visibleEnd - visibleStart; i < contentHeight; i++ {
-			b.WriteString(newLine) // fill remaining space
+		m.uiLoading = false
+		m.interfaces = msg.interfaces
+		m.interfacesLines = msg.lines
+		m.interfacesSummaries = msg.interfaces.Summaries()
+		m.interfacesCursor = min(m.interfacesCursor, max(len(m.interfacesSummaries)-1, 0))
+		m.interfacesView = true
+		return m, tickInterfaces()
+
+	case alertsTickMsg:
+		if msg.follower != m.alertsFollower {
+			// follower of the previous file
+			msg.follower.Close()
+			return m, nil
 		}
-	} else {
-		visibleEnd = min(visibleStart+contentHeight, len(m.entriesAvailable))
+		return m, followAlerts(msg.follower)
 
-		// header
-		headerLine := fmt.Sprintf(headerLineFormat, "Time", "Action", "Interface", "Dir", "Source", "SrcPort", "Destination", "DstPort", "Proto", "Reason")
-		headerLine = sliceString(headerLine, m.uiScrollH, m.uiWidth)
-		b.WriteString(m.uiStyles.header.Render(headerLine) + newLine)
+	case alertsMsg:
+		if msg.follower != m.alertsFollower {
+			msg.follower.Close()
+			return m, nil
+		}
+		for i := range msg.entries {
+			m.alerts.Add(&msg.entries[i])
+		}
+		m.alertsCounts = m.alerts.Counts(time.Now())
+		return m, tickAlerts(msg.follower)
 
-		// main
-		for i := visibleStart; i < visibleEnd; i++ {
-			if i >= len(m.entriesAvailable) {
-				break
-			}
-			lineNum := m.entriesAvailable[i]
-			entry := m.getEntryAtLine(lineNum)
-			if entry == nil {
+	case interfacesTickMsg:
+		if !m.interfacesView {
+			return m, nil
+		}
+		if m.prefetching {
+			// wait for background loads, they read the index
+			return m, tickInterfaces()
+		}
+		return m, m.followInterfaces()
+
+	case interfacesUpdateMsg:
+		if !m.interfacesView {
+			return m, nil
+		}
+		if msg.err != nil {
+			m.interfacesView = false
+			m.uiStatusMsg = m.uiStyles.statusError.Render(sanitizeString(msg.err.Error()))
+			return m, m.reloadEntries()
+		}
+		if msg.reset {
+			return m, m.countInterfaces(true)
+		}
+		for i := range msg.entries {
+			m.interfaces.Add(&msg.entries[i])
+		}
+		m.interfacesLines = msg.lines
+		m.interfacesSummaries = m.interfaces.Summaries()
+		return m, tickInterfaces()
+
+	case detailMsg:
+		m.uiLoading = false
+		if msg.err != nil {
+			m.uiStatusMsg = m.uiStyles.statusError.Render(sanitizeString(msg.err.Error()))
+		}
+		m.showOutput("Details", append(detailLines(&msg.entry), translationLines(&msg.entry, msg.related)...))
+		return m, nil
+
+	case profileMsg:
+		if msg.err != nil {
+			m.uiLoading = false
+			m.uiStatusMsg = m.uiStyles.statusError.Render(sanitizeString(msg.err.Error()))
+			return m, nil
+		}
+		m.stream.Close()
+		opts := m.opts
+		opts.Follow = m.follow
+		opts.HideNDP = m.hideHousekeeping
+		opts.Profile = msg.name
+		nm := newModel(msg.stream, m.cfg, m.enricher, opts)
+		nm.crash = m.crash
+		nm.hud = m.hud
+		nm.filterInput.Width = m.filterInput.Width
+		nm.noteInput.Width = m.noteInput.Width
+		nm.builderInput.Width = m.builderInput.Width
+		nm.uiHeight = m.uiHeight
+		nm.uiWidth = m.uiWidth
+		nm.uiStatusMsg = "profile: " + msg.name
+		return nm, nm.Init()
+
+	case rotationMsg:
+		if msg.err != nil {
+			m.uiLoading = false
+			m.uiStatusMsg = m.uiStyles.statusError.Render(sanitizeString(msg.err.Error()))
+			return m, nil
+		}
+		m.stream.Close()
+		opts := m.opts
+		opts.Follow = m.follow
+		opts.HideNDP = m.hideHousekeeping
+		nm := newModel(msg.stream, m.cfg, m.enricher, opts)
+		nm.crash = m.crash
+		nm.hud = m.hud
+		// the filter is applied once the file has been indexed
+		nm.filterCache = m.filterCache
+		nm.filterCompiled = m.filterCompiled
+		nm.filterInput.SetValue(m.filterInput.Value())
+		nm.filterInput.Width = m.filterInput.Width
+		nm.noteInput.Width = m.noteInput.Width
+		nm.builderInput.Width = m.builderInput.Width
+		nm.uiHeight = m.uiHeight
+		nm.uiWidth = m.uiWidth
+		nm.uiStatusMsg = "file: " + sanitizeString(msg.stream.GetPathRel())
+		return nm, nm.Init()
+
+	case configMsg:
+		if msg.err != nil {
+			m.uiStatusMsg = m.uiStyles.statusError.Render(sanitizeString("config not reloaded: " + msg.err.Error()))
+			return m, nil
+		}
+		m.cfg = msg.cfg
+		m.uiStatusMsg = "config: reloaded"
+		// filters are compiled again with the interfaces and strict_filter of the config
+		m.filterCache = filter.NewCache(filterCacheSize, m.cfg.FilterOptions())
+		var cmd tea.Cmd
+		// alerts are counted while severity rules are configured
+		if len(m.cfg.Severity) == 0 {
+			// the follower is closed by its next tick
+			m.alerts, m.alertsCounts, m.alertsFollower = nil, nil, nil
+		} else if m.alertsFollower == nil {
+			if f, err := m.stream.Follow(); err == nil {
+				m.alerts, m.alertsFollower = stats.NewAlerts(alertsWindow), f
+				cmd = tickAlerts(f)
+			}
+		}
+		// loaded entries were classified by the previous rules (background loads read the index)
+		if !m.indexed || m.indexing || m.prefetching || m.entriesTotal <= 0 {
+			return m, cmd
+		}
+		m.entries = m.entries[:0]
+		m.entriesNext = nil
+		m.entriesFiltered = make(map[int]stream.LogEntry)
+		if m.filterApplied {
+			return m, tea.Batch(cmd, m.withLoadingView(m.scanAndFilter()))
+		}
+		return m, tea.Batch(cmd, m.checkLoadEntries())
+
+	case streamErrorMsg:
+		m.alertsJump = false
+		m.loading = false
+		m.uiLoading = false
+		m.uiStatusMsg = m.uiStyles.statusError.Render(sanitizeString(msg.err.Error()))
+		return m, nil
+
+	default:
+		if m.filterView {
+			var cmd tea.Cmd
+			m.filterInput, cmd = m.filterInput.Update(msg)
+			return m, cmd
+		}
+		if m.noteView {
+			var cmd tea.Cmd
+			m.noteInput, cmd = m.noteInput.Update(msg)
+			return m, cmd
+		}
+		if m.builderView {
+			var cmd tea.Cmd
+			m.builderInput, cmd = m.builderInput.Update(msg)
+			return m, cmd
+		}
+		return m, nil
+	}
+}
+
+// filterCaret returns a caret below the position of the filter input error followed by its message (only the message
+// if the input is scrolled)
+func (m model) filterCaret() string {
+	value := m.filterInput.Value()
+	msg := sanitizeString(m.filterInputError.Msg)
+	if lipgloss.Width(value) >= m.filterInput.Width {
+		// the input is scrolled, so the column isn't known
+		return m.uiStyles.statusError.Render(sanitizeString(m.filterInputError.Error()))
+	}
+	col := lipgloss.Width(m.filterInput.Prompt) + lipgloss.Width(value[:min(m.filterInputError.Pos, len(value))])
+	if col+2+lipgloss.Width(msg) > m.uiWidth && col > lipgloss.Width(msg) {
+		// message on the left of the caret if it doesn't fit on the right
+		return strings.Repeat(" ", col-lipgloss.Width(msg)-1) + m.uiStyles.statusError.Render(msg) + " ^"
+	}
+	return strings.Repeat(" ", col) + "^ " + m.uiStyles.statusError.Render(msg)
+}
+
+// View renders the current state of the UI, panics are written to a crash report and passed on to restore the
+// terminal
+func (m model) View() string {
+	if m.crash.value != nil {
+		return ""
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			m.crash.write(m, r, debug.Stack())
+			panic(r)
+		}
+	}()
+	start := time.Now()
+	view := m.view()
+	m.hud.frame = time.Since(start)
+	return view
+}
+
+// view renders the current state of the UI (as a string)
+func (m model) view() string {
+	// show loading view during initialization or on request
+	if m.uiLoading || m.uiWidth == 0 || m.uiHeight == 0 {
+		return m.loadingView()
+	}
+	if m.tooSmall() {
+		return m.tooSmallView()
+	}
+
+	var b strings.Builder
+	var visibleEnd int
+
+	contentHeight := m.contentHeight()
+	newLine := "\n"
+	visibleStart := m.uiScrollV
+
+	if m.bookmarksView {
+		// keep the cursor visible
+		visibleStart = max(m.bookmarksCursor-contentHeight+1, 0)
+		visibleEnd = min(visibleStart+contentHeight, len(m.bookmarks))
+
+		// header
+		headerLine := fmt.Sprintf("  %-19s %-*s %*s  %-40s %s", "Time", bookmarksWidthFile, "File", bookmarksWidthLine, "Line", "Entry", "Note")
+		b.WriteString(m.uiStyles.header.Render(m.markLine(headerLine, false)) + newLine)
+
+		// main
+		for i := visibleStart; i < visibleEnd; i++ {
+			bm := m.bookmarks[i]
+			marker := "  "
+			if bm.File == m.bookmarksFile {
+				marker = "* "
+			}
+			summary := fmt.Sprintf("%s %s -> %s", bm.Entry.Action, stats.Endpoint(bm.Entry.Src, bm.Entry.SrcPort), stats.Endpoint(bm.Entry.Dst, bm.Entry.DstPort))
+			line := fmt.Sprintf("%s%-19s %-*s %*d  %-40s %s", marker, bm.Entry.Time.Format(time.DateTime), bookmarksWidthFile,
+				truncateString(filepath.Base(bm.File), bookmarksWidthFile), bookmarksWidthLine, bm.Line, truncateString(summary, 40), bm.Note)
+			line = sliceString(sanitizeString(line), 0, m.uiWidth)
+			b.WriteString(m.selectLine(line, i == m.bookmarksCursor) + newLine)
+		}
+		for i := visibleEnd - visibleStart; i < contentHeight; i++ {
+			b.WriteString(newLine) // fill remaining space
+		}
+	} else if m.profilesView {
+		// keep the cursor visible
+		visibleStart = max(m.profilesCursor-contentHeight+1, 0)
+		visibleEnd = min(visibleStart+contentHeight, len(m.profiles))
+
+		// header
+		b.WriteString(m.uiStyles.header.Render(sliceString("Profiles", 0, m.uiWidth)) + newLine)
+
+		// main
+		for i := visibleStart; i < visibleEnd; i++ {
+			line := "  " + m.profiles[i]
+			if m.profiles[i] == m.opts.Profile {
+				line = "* " + m.profiles[i]
+			}
+			line = sliceString(line, 0, m.uiWidth)
+			b.WriteString(m.selectLine(line, i == m.profilesCursor) + newLine)
+		}
+		for i := visibleEnd - visibleStart; i < contentHeight; i++ {
+			b.WriteString(newLine) // fill remaining space
+		}
+	} else if m.builderView {
+		choices := m.builderNarrowed()
+		// keep the cursor visible
+		visibleStart = max(m.builderCursor-contentHeight+1, 0)
+		visibleEnd = min(visibleStart+contentHeight, len(choices))
+
+		// header
+		b.WriteString(m.uiStyles.header.Render(sliceString(sanitizeString("Filter builder: "+m.builderExpression()), 0, m.uiWidth)) + newLine)
+
+		// main
+		width := 0
+		for _, c := range choices[visibleStart:visibleEnd] {
+			width = max(width, min(lipgloss.Width(c.value), builderWidthValue))
+		}
+		for i := visibleStart; i < visibleEnd; i++ {
+			line := fmt.Sprintf("  %-*s  %s", width, truncateString(choices[i].value, builderWidthValue), choices[i].note)
+			line = sliceString(sanitizeString(line), 0, m.uiWidth)
+			b.WriteString(m.selectLine(line, i == m.builderCursor) + newLine)
+		}
+		for i := visibleEnd - visibleStart; i < contentHeight; i++ {
+			b.WriteString(newLine) // fill remaining space
+		}
+	} else if m.interfacesView {
+		// keep the cursor visible
+		visibleStart = max(m.interfacesCursor-contentHeight+1, 0)
+		visibleEnd = min(visibleStart+contentHeight, len(m.interfacesSummaries))
+
+		// header
+		headerLine := fmt.Sprintf("%-*s %*s %*s %*s %*s", interfacesWidthName, "Interface",
+			interfacesWidthCount, "Total", interfacesWidthCount, "Pass", interfacesWidthCount, "Block", interfacesWidthCount, "Rate/min")
+		b.WriteString(m.uiStyles.header.Render(m.markLine(headerLine, false)) + newLine)
+
+		// main
+		for i := visibleStart; i < visibleEnd; i++ {
+			s := m.interfacesSummaries[i]
+			line := fmt.Sprintf("%-*s %*d %*d %*d %*d", interfacesWidthName, truncateString(s.Interface, interfacesWidthName),
+				interfacesWidthCount, s.Entries, interfacesWidthCount, s.Passed, interfacesWidthCount, s.Blocked, interfacesWidthCount, s.Rate)
+			line = sliceString(line, 0, m.uiWidth)
+			b.WriteString(m.selectLine(line, i == m.interfacesCursor) + newLine)
+		}
+		for i := visibleEnd - visibleStart; i < contentHeight; i++ {
+			b.WriteString(newLine) // fill remaining space
+		}
+	} else if m.frequencyView {
+		// keep the cursor visible
+		visibleStart = max(m.frequencyCursor-contentHeight+1, 0)
+		visibleEnd = min(visibleStart+contentHeight, len(m.frequencyValues))
+
+		// header
+		headerLine := fmt.Sprintf("%-*s %*s %*s", frequencyWidthValue, "Value ("+frequencyFields[m.frequencyField]+")",
+			frequencyWidthCount, "Entries", frequencyWidthCount, "Share")
+		b.WriteString(m.uiStyles.header.Render(m.markLine(headerLine, false)) + newLine)
+
+		// main
+		for i := visibleStart; i < visibleEnd; i++ {
+			v := m.frequencyValues[i]
+			line := fmt.Sprintf("%-*s %*d %*.1f%%", frequencyWidthValue, truncateString(v.Value, frequencyWidthValue),
+				frequencyWidthCount, v.Entries, frequencyWidthCount-1, v.Share*100)
+			line = sliceString(line, 0, m.uiWidth)
+			b.WriteString(m.selectLine(line, i == m.frequencyCursor) + newLine)
+		}
+		for i := visibleEnd - visibleStart; i < contentHeight; i++ {
+			b.WriteString(newLine) // fill remaining space
+		}
+	} else if m.errorsView || m.outputView {
+		lines, title := m.errors, "Error"
+		if m.outputView {
+			lines, title = m.output, m.outputTitle
+		}
+		visibleEnd = min(visibleStart+contentHeight, len(lines))
+
+		// header
+		b.WriteString(m.uiStyles.header.Render(sliceString(sanitizeString(title), 0, m.uiWidth)) + newLine)
+
+		// main
+		for i := visibleStart; i < visibleEnd; i++ {
+			line := sliceString(sanitizeString(lines[i]), m.uiScrollH, m.uiWidth)
+			b.WriteString(line + newLine)
+		}
+		for i := visibleEnd - visibleStart; i < contentHeight; i++ {
+			b.WriteString(newLine) // fill remaining space
+		}
+	} else if m.heatmapView {
+		visibleEnd = min(visibleStart+contentHeight, len(m.heatmap))
+		cellStyles, maxCount := m.uiStyles.heatmapEntry, m.heatmapMax()
+		cells := heatmapCells
+		if m.opts.Accessible {
+			cells = heatmapCellsAccessible
+		}
+		if m.heatmapBlocked {
+			cellStyles = m.uiStyles.heatmapBlock
+		}
+		// cells are colored, so columns that don't fit are dropped instead of sliced
+		hours := min(max((m.uiWidth-heatmapWidthDate)/heatmapWidthCell, 0), 24)
+		showTotal := heatmapWidthDate+24*heatmapWidthCell+heatmapWidthTotal <= m.uiWidth
+
+		// header
+		headerLine := fmt.Sprintf("%-*s", heatmapWidthDate, "Date")
+		for hour := range hours {
+			headerLine += fmt.Sprintf(" %02d", hour)
+		}
+		if showTotal {
+			headerLine += fmt.Sprintf("%*s", heatmapWidthTotal, "Total")
+		}
+		b.WriteString(m.uiStyles.header.Render(sliceString(headerLine, 0, m.uiWidth)) + newLine)
+
+		// main
+		for i := visibleStart; i < visibleEnd; i++ {
+			counts := m.heatmapCounts(i)
+			line := sliceString(fmt.Sprintf("%-*s", heatmapWidthDate, m.heatmap[i].Date), 0, m.uiWidth)
+			for hour := range hours {
+				level := heatmapLevel(counts[hour], maxCount, len(cellStyles)-1)
+				line += " " + cellStyles[level].Render(cells[level])
+			}
+			if showTotal {
+				total := 0
+				for _, count := range counts {
+					total += count
+				}
+				line += fmt.Sprintf("%*d", heatmapWidthTotal, total)
+			}
+			b.WriteString(line + newLine)
+		}
+		for i := visibleEnd - visibleStart; i < contentHeight; i++ {
+			b.WriteString(newLine) // fill remaining space
+		}
+	} else {
+		visibleStart = m.logStart()
+		visibleEnd = visibleStart
+
+		// header
+		cols := m.logColumns()
+		names := []string{"Time", "Action", "Interface", "Dir", "Source", "SrcPort", "Destination", "DstPort", "Proto", "Reason"}
+		if m.sortCol >= 0 {
+			// the name is cut to keep the marker visible in narrow columns
+			marker := sortMarker(m.sortDesc)
+			names[m.sortCol] = sliceString(names[m.sortCol], 0, cols.widths[m.sortCol]-len(marker)) + marker
+		}
+		headerLine := cols.format(names...)
+		if m.uiLabel {
+			headerLine = fmt.Sprintf("%-*s %s", colWidthLabel, "Label", headerLine)
+		}
+		if m.uiOrigin {
+			headerLine = fmt.Sprintf("%-*s %s", colWidthOrigin, "Origin", headerLine)
+		}
+		if m.showSeverity() {
+			headerLine = fmt.Sprintf("%-*s %s", colWidthSeverity, "Severity", headerLine)
+		}
+		headerLine = m.markLine(sliceString(headerLine, m.uiScrollH, m.uiWidth-m.markerWidth()), false)
+		b.WriteString(m.uiStyles.header.Render(headerLine) + newLine)
+
+		// main
+		rows := 0
+		m.hud.loaded, m.hud.loading = 0, 0
+		for i := visibleStart; i < len(m.entriesAvailable) && rows < contentHeight; i++ {
+			for _, a := range m.annotationsBefore(i) {
+				if rows < contentHeight {
+					b.WriteString(m.renderAnnotation(a) + newLine)
+					rows++
+				}
+			}
+			if rows >= contentHeight {
+				break
+			}
+			rows++
+			visibleEnd = i + 1
+			lineNum := m.entriesAvailable[i]
+			entry := m.getEntryAtLine(lineNum)
+			if entry == nil {
 				// entry not loaded in memory
+				m.hud.loading++
+				if m.opts.Accessible {
+					b.WriteString(m.markLine(fmt.Sprintf("line %d: loading...", lineNum+1), i == m.uiCursor) + newLine)
+					continue
+				}
 				b.WriteString(m.uiStyles.entryLoading.Render("loading...") + newLine)
 				continue
 			}
+			m.hud.loaded++
 			srcPort := ""
 			if entry.SrcPort > 0 {
 				srcPort = fmt.Sprintf("%d", entry.SrcPort)
@@ -329,25 +1558,34 @@ func (m model) View() string {
 			if entry.DstPort > 0 {
 				dstPort = fmt.Sprintf("%d", entry.DstPort)
 			}
-			line := fmt.Sprintf(headerLineFormat,
-				truncateString(entry.Time.Format("Jan 02 15:04:05"), colWidthTime),
-				truncateString(entry.Action, colWidthAction),
-				truncateString(entry.Interface, colWidthInterface),
-				truncateString(entry.Direction, colWidthDir),
-				truncateString(entry.Src, colWidthSource),
-				truncateString(srcPort, colWidthSrcPort),
-				truncateString(entry.Dst, colWidthDest),
-				truncateString(dstPort, colWidthDstPort),
-				truncateString(entry.ProtoName, colWidthProto),
-				truncateString(entry.Reason, colWidthReason))
-
-			line = sliceString(line, m.uiScrollH, m.uiWidth)
+			src, dst := entry.Src, entry.Dst
+			if m.opts.Accessible && m.uiClasses {
+				// classes are written out instead of tinting the cells
+				src, dst = classifiedAddr(src, entry.SrcClass), classifiedAddr(dst, entry.DstClass)
+			}
+			line := cols.format(entry.Time.Format(cols.timeFormat), entry.Action, entry.Interface, entry.Direction,
+				src, srcPort, dst, dstPort, entry.ProtoName, entry.Reason)
+			if m.uiLabel {
+				line = fmt.Sprintf("%-*s %s", colWidthLabel, truncateString(entry.Label, colWidthLabel), line)
+			}
+			if m.uiOrigin {
+				line = fmt.Sprintf("%-*s %s", colWidthOrigin, truncateString(entry.Origin, colWidthOrigin), line)
+			}
+			if m.showSeverity() {
+				line = fmt.Sprintf("%-*s %s", colWidthSeverity, truncateString(entry.Severity, colWidthSeverity), line)
+			}
+
+			if i == m.uiCursor || m.opts.Accessible {
+				b.WriteString(m.selectLine(sliceString(line, m.uiScrollH, m.uiWidth-m.markerWidth()), i == m.uiCursor) + newLine)
+				continue
+			}
+			base := lipgloss.NewStyle()
 			if entry.Action == stream.ActionBlock {
-				line = m.uiStyles.entryBlock.Render(line)
+				base = m.uiStyles.entryBlock
 			}
-			b.WriteString(line + newLine)
+			b.WriteString(renderLine(line, m.uiScrollH, m.uiWidth, base, m.cellStyles(entry, base)) + newLine)
 		}
-		for i := visibleEnd - visibleStart; i < contentHeight; i++ {
+		for ; rows < contentHeight; rows++ {
 			b.WriteString(newLine) // fill remaining space
 		}
 	}
@@ -356,27 +1594,102 @@ func (m model) View() string {
 	statusLine := "viewing: %d-%d of %d"
 	if m.errorsView {
 		statusLine = fmt.Sprintf(statusLine+" (limit: %d)", visibleStart+1, visibleEnd, len(m.errors), stream.MaxErrorsInMemory)
+	} else if m.heatmapView {
+		metric := "all entries"
+		if m.heatmapBlocked {
+			metric = "blocked entries"
+		}
+		statusLine = fmt.Sprintf(statusLine+" days | heatmap: %s (max %d per hour)", visibleStart+1, visibleEnd, len(m.heatmap), metric, m.heatmapMax())
+	} else if m.outputView {
+		statusLine = fmt.Sprintf(statusLine, visibleStart+1, visibleEnd, len(m.output))
+	} else if m.bookmarksView {
+		statusLine = fmt.Sprintf(statusLine+" bookmarks", visibleStart+1, visibleEnd, len(m.bookmarks))
+	} else if m.profilesView {
+		statusLine = fmt.Sprintf(statusLine, visibleStart+1, visibleEnd, len(m.profiles))
+	} else if m.builderView {
+		statusLine = m.builderInput.View()
+	} else if m.interfacesView {
+		statusLine = fmt.Sprintf(statusLine+" interfaces | live: %d lines", visibleStart+1, visibleEnd, len(m.interfacesSummaries), m.interfacesLines)
+		if last := m.interfaces.Last(); !last.IsZero() {
+			statusLine += " | newest: " + last.Format(time.DateTime)
+		}
+	} else if m.frequencyView {
+		statusLine = fmt.Sprintf(statusLine+" values | group: %s (%d entries)", visibleStart+1, visibleEnd, len(m.frequencyValues),
+			frequencyFields[m.frequencyField], m.frequency.Total())
 	} else if m.filterView {
 		statusLine = m.filterInput.View()
+	} else if m.noteView {
+		statusLine = m.noteInput.View()
 	} else {
 		statusLine = fmt.Sprintf(statusLine, visibleStart+1, visibleEnd, len(m.entriesAvailable))
+		if m.indexing {
+			statusLine += fmt.Sprintf(" | indexing: %d%%", int(m.progress*100))
+		}
+		if m.follow {
+			statusLine += " | following"
+		}
+		if m.sortCol >= 0 {
+			statusLine += " | sort: " + sortFields[m.sortCol] + sortDirection(m.sortDesc)
+		}
+		if badge := m.alertsBadge(); badge != "" {
+			statusLine += " | " + badge
+		}
 		if m.filterError != "" {
 			statusLine += " | " + m.uiStyles.statusError.Render(m.filterError)
 		} else if m.uiStatusMsg != "" {
 			statusLine += " | " + m.uiStatusMsg
 		}
+		if m.filterWarning != "" && m.filterCompiled != nil {
+			statusLine += " | " + m.uiStyles.statusWarning.Render(sanitizeString(m.filterWarning))
+		}
 	}
-	b.WriteString(m.uiStyles.status.Width(m.uiWidth).Render(statusLine) + newLine)
+	// performance overlay
+	if m.hud.shown {
+		b.WriteString(m.uiStyles.header.Width(m.uiWidth).MaxHeight(1).Render(m.hud.line(m)) + newLine)
+	}
+
+	// long status lines are cut instead of wrapped to keep the help line at the bottom
+	b.WriteString(m.uiStyles.status.Width(m.uiWidth).MaxHeight(1).Render(statusLine) + newLine)
 
 	// help
 	helpLine := "q: quit | k/▲ j/▼ h/◄ l/►: scroll | u/pgup d/pgdn: page | g/home G/end 0 $: jump"
-	if m.errorsView {
+	if m.bookmarksView {
+		helpLine = "q: quit | k/▲ j/▼: select | enter: jump to entry | x: delete | esc: back to log view"
+	} else if m.profilesView {
+		helpLine = "q: quit | k/▲ j/▼: select | enter: switch profile | esc: back to log view"
+	} else if m.builderView {
+		helpLine = "type: narrow | ▲/▼: select | enter: choose | backspace: previous step | esc: cancel"
+	} else if m.interfacesView {
+		helpLine = "q: quit | k/▲ j/▼: select | enter: filter by interface | esc: back to log view"
+	} else if m.frequencyView {
+		helpLine = "q: quit | k/▲ j/▼: select | tab: next field | enter: filter by value | c/esc: back to log view"
+	} else if m.errorsView {
 		helpLine += " | e/esc: back to log view"
+	} else if m.heatmapView {
+		helpLine += " | tab: toggle blocked | esc: back to log view"
+	} else if m.outputView {
+		helpLine += " | esc: back to log view"
+	} else if m.filterView && m.filterInputError != nil {
+		helpLine = m.filterCaret()
 	} else if m.filterView {
 		helpLine = "enter: apply | esc: cancel | example: iface eth0 and (src 192.168.1.1 or dstport 80)"
+	} else if m.noteView {
+		helpLine = "enter: save bookmark | esc: cancel"
 	} else {
-		helpLine += " | /: filter"
-		if m.filterApplied {
+		helpLine += " | /: filter | F: filter builder | enter: details | b: brute-force | p: ports | R: rules | T: gaps | H: heatmap | I: interfaces | c: group | S: sort | N: neighbor discovery | r: reload | f: follow | O: origin | L: label | C: address classes | [/]: older/newer file | X: export incident"
+		if m.cfg.Open != "" {
+			helpLine += " | o: open"
+		}
+		if m.opts.Open != nil && len(m.profiles) > 0 {
+			helpLine += " | P: profiles"
+		}
+		if m.opts.Bookmarks != nil {
+			helpLine += " | m: bookmark | M: bookmarks"
+		}
+		if len(m.alertsCounts) > 0 {
+			helpLine += " | A: alerts"
+		}
+		if m.filterCompiled != nil {
 			helpLine += " | esc: clear filter"
 		}
 		if len(m.errors) > 0 {
@@ -394,145 +1707,616 @@ func (m model) View() string {
 
 // async
 
-// index builds the file index
+// index indexes the next part of the file
 func index(s *stream.Stream) tea.Cmd {
 	return func() tea.Msg {
-		if err := s.BuildIndex(); err != nil {
+		done, err := s.IndexNext(indexLines)
+		return indexMsg{
+			stream:       s,
+			entriesTotal: s.TotalLines(),
+			done:         done,
+			progress:     s.IndexProgress(),
+			err:          err,
+		}
+	}
+}
+
+// extendIndex indexes the lines appended to the file since it was indexed
+func extendIndex(s *stream.Stream) tea.Cmd {
+	return func() tea.Msg {
+		if err := s.ExtendIndex(); err != nil {
 			return streamErrorMsg{err: err}
 		}
-		return indexMsg{entriesTotal: s.TotalLines()}
+		return reloadMsg{entriesTotal: s.TotalLines()}
 	}
 }
 
-// loadEntries loads a contiguous block of log entries starting at a specific line
-func loadEntries(s *stream.Stream, startLine int, count int) tea.Cmd {
+// tickFollow waits before checking the file for appended lines (follow mode)
+func tickFollow(s *stream.Stream, gen int) tea.Cmd {
+	return tea.Tick(followInterval, func(time.Time) tea.Msg {
+		return followTickMsg{stream: s, gen: gen}
+	})
+}
+
+// followIndex indexes the lines appended to the file if it has grown (the index is rebuilt if the file has been
+// replaced or truncated)
+func followIndex(s *stream.Stream) tea.Cmd {
 	return func() tea.Msg {
-		startLine = max(startLine, 0)
-		totalLines := s.TotalLines()
-		if startLine >= totalLines {
-			startLine = max(totalLines-count, 0)
+		before := s.CheckpointAt(-1)
+		info, err := os.Stat(s.Source())
+		if err != nil {
+			// not created yet after rotation
+			return followMsg{stream: s, entriesTotal: s.TotalLines()}
 		}
-		if err := s.SeekToLine(startLine); err != nil {
-			return streamErrorMsg{err: err}
+		if info.Size() == before.Offset {
+			return followMsg{stream: s, entriesTotal: s.TotalLines()}
+		}
+		if err := s.ExtendIndex(); err != nil {
+			return followMsg{stream: s, err: err}
+		}
+		after := s.CheckpointAt(-1)
+		replaced := info.Size() < before.Offset || !after.SameFile(before)
+		return followMsg{stream: s, entriesTotal: s.TotalLines(), replaced: replaced}
+	}
+}
+
+// followFilter reads the appended lines from start to end using a separate reader and returns those matching the
+// current filter
+func (m model) followFilter(start, end int) tea.Cmd {
+	return func() tea.Msg {
+		r, err := m.stream.Clone()
+		if err != nil {
+			return followFilteredMsg{stream: m.stream, err: err}
 		}
-		entries := make([]stream.LogEntry, 0, count)
-		for i := 0; i < count && startLine+i < totalLines; i++ {
-			entry := s.Next()
+		defer r.Close()
+		lines := make([]int, 0)
+		if err := r.SeekToLine(start); err != nil {
+			return followFilteredMsg{stream: m.stream, err: err}
+		}
+		for i := start; i < end; i++ {
+			entry := r.Next()
 			if entry == nil {
-				// EOF
 				break
 			}
-			entries = append(entries, *entry)
-		}
-		return entriesMsg{
-			entries:      entries,
-			entriesStart: startLine,
+			if m.matches(entry) {
+				lines = append(lines, i)
+			}
 		}
+		return followFilteredMsg{stream: m.stream, lines: lines}
 	}
 }
 
-// loadEntriesFiltered loads non-contiguous block of entries matching current filter
-func loadEntriesFiltered(s *stream.Stream, lineNums []int) tea.Cmd {
+// followAlerts reads the entries appended to the file and returns those of alerting severity levels
+func followAlerts(f *stream.Follower) tea.Cmd {
 	return func() tea.Msg {
-		entries := make(map[int]stream.LogEntry)
-		for _, lineNum := range lineNums {
-			// TODO: handle this error
-			if err := s.SeekToLine(lineNum); err != nil {
-				continue
-			}
-			entry := s.Next()
-			if entry != nil {
-				entries[lineNum] = *entry
+		entries := make([]stream.LogEntry, 0)
+		for entry := f.Next(); entry != nil; entry = f.Next() {
+			if stream.SeverityLevel(entry.Severity) >= stream.SeverityLevel(stream.SeverityWarning) {
+				entries = append(entries, *entry)
 			}
 		}
-		return entriesFilteredMsg{entriesFiltered: entries}
+		// parse errors are shown once the lines have been reloaded
+		f.TakeErrors()
+		return alertsMsg{follower: f, entries: entries}
 	}
 }
 
-// handlers
+// tickAlerts waits before checking for new entries of alerting severity levels
+func tickAlerts(f *stream.Follower) tea.Cmd {
+	return tea.Tick(alertsInterval, func(time.Time) tea.Msg {
+		return alertsTickMsg{follower: f}
+	})
+}
 
-// handleNormalInput handles keyboard input when in default view
-func (m model) handleNormalInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	if !m.indexed {
-		return m, nil
+// tickInterfaces waits before checking for new entries in interfaces view
+func tickInterfaces() tea.Cmd {
+	return tea.Tick(interfacesInterval, func(time.Time) tea.Msg {
+		return interfacesTickMsg{}
+	})
+}
+
+// watchConfig sends a configMsg to p whenever reload returns a changed config or an error until done is closed
+func watchConfig(p *tea.Program, reload func() (*config.Config, error), done <-chan struct{}) {
+	defer func() {
+		if r := recover(); r != nil {
+			p.Send(crashMsg{value: r, stack: debug.Stack()})
+		}
+	}()
+	ticker := time.NewTicker(config.WatchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if cfg, err := reload(); cfg != nil || err != nil {
+				p.Send(configMsg{cfg: cfg, err: err})
+			}
+		}
 	}
+}
 
-	switch msg.String() {
-	case "ctrl+c", "q":
-		return m, tea.Quit
+// openProfile opens the log of the named profile
+func openProfile(open Opener, name string) tea.Cmd {
+	return func() tea.Msg {
+		s, err := open(name)
+		return profileMsg{name: name, stream: s, err: err}
+	}
+}
 
-	case "e":
-		if len(m.errors) > 0 {
-			m.errorsView = !m.errorsView
-			m.uiScrollH = 0
-			m.uiScrollV = 0
+// openRotation opens the rotated log file before (delta -1) or after (delta 1) the file of s
+func openRotation(s *stream.Stream, delta int) tea.Cmd {
+	return func() tea.Msg {
+		paths, err := stream.Rotations(s.GetPathRel())
+		if err != nil {
+			return rotationMsg{err: err}
 		}
-		return m, nil
-
-	case "j", "down":
-		m.scrollDown(1)
-		if m.filterApplied {
-			return m, m.checkLoadEntriesFiltered()
+		current, err := filepath.EvalSymlinks(s.GetPathRel())
+		if err != nil {
+			return rotationMsg{err: fmt.Errorf("error(tui): %w", err)}
 		}
-		return m, m.checkLoadEntries()
-
-	case "k", "up":
-		m.scrollUp(1)
-		if m.filterApplied {
-			return m, m.checkLoadEntriesFiltered()
+		i := slices.Index(paths, current) + delta
+		if i < 0 {
+			return rotationMsg{err: errors.New("error(tui): no older rotated log file")}
 		}
-		return m, m.checkLoadEntries()
-
-	case "d", "pgdown":
-		m.scrollDown(m.uiHeight / 2)
-		if m.filterApplied {
-			return m, m.checkLoadEntriesFiltered()
+		if i >= len(paths) {
+			return rotationMsg{err: errors.New("error(tui): no newer rotated log file")}
 		}
-		return m, m.checkLoadEntries()
+		r, err := s.Open(paths[i])
+		return rotationMsg{stream: r, err: err}
+	}
+}
+
+// loadEntries loads a contiguous block of log entries starting at a specific line
+func loadEntries(s *stream.Stream, startLine int, count int) tea.Cmd {
+	return func() tea.Msg {
+		entries, startLine, err := readEntries(s, startLine, count)
+		if err != nil {
+			return streamErrorMsg{err: err}
+		}
+		return entriesMsg{
+			entries:      entries,
+			entriesStart: startLine,
+		}
+	}
+}
+
+// prefetchEntries loads a contiguous block of log entries in the background using a separate reader of s
+// (errors are ignored, they are reported once the block is loaded regularly)
+func prefetchEntries(s *stream.Stream, startLine int, count int) tea.Cmd {
+	return func() tea.Msg {
+		r, err := s.Clone()
+		if err != nil {
+			return entriesMsg{prefetched: true}
+		}
+		defer r.Close()
+		entries, startLine, _ := readEntries(r, startLine, count)
+		return entriesMsg{
+			entries:      entries,
+			entriesStart: startLine,
+			prefetched:   true,
+		}
+	}
+}
+
+// readEntries reads up to count entries starting at a specific line (returns the actual start line)
+func readEntries(s *stream.Stream, startLine int, count int) ([]stream.LogEntry, int, error) {
+	startLine = max(startLine, 0)
+	totalLines := s.TotalLines()
+	if startLine >= totalLines {
+		startLine = max(totalLines-count, 0)
+	}
+	if err := s.SeekToLine(startLine); err != nil {
+		return nil, startLine, err
+	}
+	entries := make([]stream.LogEntry, 0, min(count, totalLines-startLine))
+	for i := 0; i < count && startLine+i < totalLines; i++ {
+		entry := s.Next()
+		if entry == nil {
+			// EOF
+			break
+		}
+		entries = append(entries, *entry)
+	}
+	return entries, startLine, nil
+}
+
+// loadEntriesFiltered loads non-contiguous block of entries matching current filter
+func loadEntriesFiltered(s *stream.Stream, lineNums []int) tea.Cmd {
+	return func() tea.Msg {
+		// split lines into chunks, each loaded by its own reader
+		workers := min(loadWorkers, len(lineNums))
+		chunkSize := (len(lineNums) + workers - 1) / max(workers, 1)
+		chunks := slices.Collect(slices.Chunk(lineNums, max(chunkSize, 1)))
+		results := make([]map[int]stream.LogEntry, len(chunks))
+		errs := make([]error, len(chunks))
+		var wg sync.WaitGroup
+		for i, chunk := range chunks {
+			wg.Go(func() {
+				results[i], errs[i] = loadLines(s, chunk)
+			})
+		}
+		wg.Wait()
+		entries := make(map[int]stream.LogEntry, len(lineNums))
+		for _, result := range results {
+			maps.Copy(entries, result)
+		}
+		return entriesFilteredMsg{entriesFiltered: entries, err: errors.Join(errs...)}
+	}
+}
+
+// loadLines loads the given lines using a separate reader of s (stops at the first error)
+func loadLines(s *stream.Stream, lineNums []int) (map[int]stream.LogEntry, error) {
+	entries := make(map[int]stream.LogEntry, len(lineNums))
+	r, err := s.Clone()
+	if err != nil {
+		return entries, err
+	}
+	defer r.Close()
+	for _, lineNum := range lineNums {
+		if err := r.SeekToLine(lineNum); err != nil {
+			return entries, err
+		}
+		entry := r.Next()
+		if entry == nil {
+			return entries, fmt.Errorf("error(tui): could not read line %d", lineNum)
+		}
+		entries[lineNum] = *entry
+	}
+	return entries, nil
+}
+
+// runCommand runs an external command and collects its output
+func runCommand(args []string) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+		defer cancel()
+		command := strings.Join(args, " ")
+		out, err := exec.CommandContext(ctx, args[0], args[1:]...).CombinedOutput()
+		output := strings.Split(strings.TrimRight(string(out), "\n"), "\n")
+		if len(output) == 1 && output[0] == "" {
+			output = nil
+		}
+		return commandMsg{command: command, output: output, err: err}
+	}
+}
+
+// loadDetail collects entries of translated packets (rdr, nat and binat only) and runs the enrichment plugin
+// (if configured) for a single entry
+func loadDetail(s *stream.Stream, e *plugin.Enricher, entry stream.LogEntry, line int) tea.Cmd {
+	return func() tea.Msg {
+		msg := detailMsg{entry: entry}
+		if entry.Translation() {
+			msg.related, msg.err = relatedEntries(s, &entry, line)
+		}
+		if e != nil {
+			if err := e.Enrich(&msg.entry); err != nil {
+				msg.err = err
+			}
+		}
+		return msg
+	}
+}
+
+// relatedEntries returns entries near the translation entry that could be its translated packet
+func relatedEntries(s *stream.Stream, translation *stream.LogEntry, line int) ([]stream.LogEntry, error) {
+	r, err := s.Clone()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	entries, _, err := readEntries(r, line-natScanLines, 2*natScanLines+1)
+	if err != nil {
+		return nil, err
+	}
+	related := make([]stream.LogEntry, 0)
+	for i := range entries {
+		if stats.NATRelated(translation, &entries[i]) {
+			related = append(related, entries[i])
+		}
+	}
+	return related, nil
+}
+
+// handlers
+
+// handleNormalInput handles keyboard input when in default view
+func (m model) handleNormalInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if !m.indexed {
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m, tea.Quit
+
+	case "e":
+		if len(m.errors) > 0 && !m.outputView && !m.heatmapView {
+			m.errorsView = !m.errorsView
+			m.uiScrollH = 0
+			m.uiScrollV = 0
+			if !m.errorsView {
+				m.moveCursor(m.uiCursor)
+			}
+		}
+		return m, nil
+
+	case "enter":
+		if !m.logView() {
+			return m, nil
+		}
+		entry := m.getSelectedEntry()
+		if entry == nil {
+			return m, nil
+		}
+		if m.enricher != nil || entry.Translation() {
+			line := m.entriesAvailable[m.uiCursor]
+			return m, m.withLoadingView(loadDetail(m.stream, m.enricher, *entry, line))
+		}
+		m.showOutput("Details", detailLines(entry))
+		return m, nil
+
+	case "b":
+		if !m.logView() || m.waitIndexed() {
+			return m, nil
+		}
+		return m, m.withLoadingView(m.detectBruteforce())
+
+	case "X":
+		if !m.logView() || m.waitIndexed() {
+			return m, nil
+		}
+		return m, m.withLoadingView(m.exportIncident())
+
+	case "p":
+		if !m.logView() || m.waitIndexed() {
+			return m, nil
+		}
+		return m, m.withLoadingView(m.summarizePorts())
+
+	case "R":
+		if !m.logView() || m.waitIndexed() {
+			return m, nil
+		}
+		return m, m.withLoadingView(m.summarizeRules())
+
+	case "T":
+		if !m.logView() || m.waitIndexed() {
+			return m, nil
+		}
+		return m, m.withLoadingView(m.analyzeGaps())
+
+	case "H":
+		if !m.logView() || m.waitIndexed() {
+			return m, nil
+		}
+		return m, m.withLoadingView(m.buildHeatmap())
+
+	case "I":
+		if !m.logView() || m.waitIndexed() {
+			return m, nil
+		}
+		m.interfacesCursor = 0
+		return m, m.withLoadingView(m.countInterfaces(false))
+
+	case "c":
+		// wait for background loads, they read the index and the stream
+		if !m.logView() || m.reading() || m.waitIndexed() {
+			return m, nil
+		}
+		return m, m.withLoadingView(m.countFrequency())
+
+	case "A":
+		// wait for background loads, they read the index and the stream
+		if !m.logView() || len(m.alertsCounts) == 0 || m.reading() || m.waitIndexed() {
+			return m, nil
+		}
+		// the least severe alerting level matches all levels above
+		filterValue := "severity " + m.alertsCounts[len(m.alertsCounts)-1].Severity
+		compiled, err := m.filterCache.Compile(filterValue)
+		if err != nil {
+			m.filterError = err.Error()
+			return m, nil
+		}
+		m.filterApplied = true
+		m.filterCompiled = compiled
+		m.filterError = ""
+		m.filterWarning = ""
+		m.filterInput.SetValue(filterValue)
+		m.alertsJump = true
+		// the matching lines are collected once the new lines have been indexed
+		return m, m.withLoadingView(extendIndex(m.stream))
+
+	case "r":
+		// wait for background loads, they read the index and the stream
+		if !m.logView() || m.reading() || m.waitIndexed() {
+			return m, nil
+		}
+		return m, m.withLoadingView(extendIndex(m.stream))
+
+	case "D":
+		// performance overlay (not listed in the help line)
+		m.hud.shown = !m.hud.shown
+		if m.logView() {
+			m.moveCursor(m.uiCursor)
+		}
+		return m, nil
+
+	case "S":
+		// wait for background loads, they read the index and the stream
+		if !m.logView() || m.reading() || m.waitIndexed() {
+			return m, nil
+		}
+		m.sortPending = true
+		m.uiStatusMsg = "sort by: t time | a action | i iface | d dir | s src | S sport | D dst | P dport | p proto | r reason"
+		return m, nil
+
+	case "f":
+		if !m.logView() {
+			return m, nil
+		}
+		m.follow = !m.follow
+		m.uiStatusMsg = "follow: off"
+		if !m.follow {
+			return m, nil
+		}
+		m.uiStatusMsg = "follow: on"
+		return m, m.startFollow()
+
+	case "C":
+		if m.logView() {
+			m.uiClasses = !m.uiClasses
+		}
+		return m, nil
+
+	case "O":
+		if m.logView() {
+			m.uiOrigin = !m.uiOrigin
+			m.uiScrollH = 0
+		}
+		return m, nil
+
+	case "L":
+		if m.logView() {
+			m.uiLabel = !m.uiLabel
+			m.uiScrollH = 0
+		}
+		return m, nil
+
+	case "m":
+		if !m.logView() || m.opts.Bookmarks == nil {
+			return m, nil
+		}
+		entry := m.getSelectedEntry()
+		if entry == nil {
+			return m, nil
+		}
+		if m.bookmarksFile == "" {
+			m.uiStatusMsg = m.uiStyles.statusError.Render("error(tui): bookmarks are not available for this file")
+			return m, nil
+		}
+		m.noteLine = m.entriesAvailable[m.uiCursor] + 1
+		m.noteEntry = *entry
+		bm, _ := m.opts.Bookmarks.Get(m.bookmarksFile, m.noteLine)
+		m.noteInput.SetValue(bm.Note)
+		m.noteInput.CursorEnd()
+		m.noteView = true
+		return m, m.noteInput.Focus()
+
+	case "M":
+		if !m.logView() || m.opts.Bookmarks == nil {
+			return m, nil
+		}
+		m.bookmarks = m.opts.Bookmarks.All()
+		m.bookmarksCursor = 0
+		m.bookmarksView = true
+		return m, nil
+
+	case "F":
+		if !m.logView() {
+			return m, nil
+		}
+		m.builderParts, m.builderJoin = nil, ""
+		m.builderView = true
+		m = m.builderGoto(builderStepField)
+		return m, m.builderInput.Focus()
+
+	case "P":
+		if !m.logView() || m.opts.Open == nil || len(m.profiles) == 0 {
+			return m, nil
+		}
+		m.profilesView = true
+		m.profilesCursor = max(slices.Index(m.profiles, m.opts.Profile), 0)
+		return m, nil
+
+	case "tab":
+		if m.heatmapView {
+			m.heatmapBlocked = !m.heatmapBlocked
+		}
+		return m, nil
+
+	case "o":
+		if !m.logView() || m.cfg.Open == "" {
+			return m, nil
+		}
+		entry := m.getSelectedEntry()
+		if entry == nil {
+			return m, nil
+		}
+		args := expandCommand(m.cfg.Open, entry)
+		if len(args) == 0 {
+			return m, nil
+		}
+		m.uiStatusMsg = fmt.Sprintf("running: %q", strings.Join(args, " "))
+		return m, runCommand(args)
+
+	case "j", "down":
+		m.scrollDown(1)
+		if m.filterApplied {
+			return m, m.checkLoadEntriesFiltered()
+		}
+		cmd := m.checkLoadEntries()
+		return m, cmd
+
+	case "k", "up":
+		m.scrollUp(1)
+		if m.filterApplied {
+			return m, m.checkLoadEntriesFiltered()
+		}
+		cmd := m.checkLoadEntries()
+		return m, cmd
+
+	case "d", "pgdown":
+		m.scrollDown(m.uiHeight / 2)
+		if m.filterApplied {
+			return m, m.checkLoadEntriesFiltered()
+		}
+		cmd := m.checkLoadEntries()
+		return m, cmd
 
 	case "u", "pgup":
 		m.scrollUp(m.uiHeight / 2)
 		if m.filterApplied {
 			return m, m.checkLoadEntriesFiltered()
 		}
-		return m, m.checkLoadEntries()
+		cmd := m.checkLoadEntries()
+		return m, cmd
 
 	case "g", "home":
 		m.uiScrollV = 0
-		if m.errorsView {
+		if !m.logView() {
 			return m, nil
 		}
+		m.uiCursor = 0
 		if m.filterApplied {
 			return m, m.checkLoadEntriesFiltered()
 		}
-		return m, m.checkLoadEntries()
+		cmd := m.checkLoadEntries()
+		return m, cmd
 
 	case "G", "end":
-		var lines int
-		if m.errorsView {
-			lines = len(m.errors)
-		} else {
-			lines = len(m.entriesAvailable)
-		}
-		contentHeight := m.uiHeight - 3 // -3 for header, status, and help line
+		lines := m.lineCount()
+		contentHeight := m.contentHeight()
 		m.uiScrollV = max(lines-contentHeight, 0)
-		if m.errorsView {
+		if !m.logView() {
 			return m, nil
 		}
+		m.uiCursor = max(lines-1, 0)
 		if m.filterApplied {
 			return m, m.checkLoadEntriesFiltered()
 		}
-		return m, m.checkLoadEntries()
+		cmd := m.checkLoadEntries()
+		return m, cmd
 
 	case "h", "left":
-		if contentWidth > m.uiWidth {
+		if m.logWidth() > m.uiWidth {
 			m.uiScrollH = max(m.uiScrollH-1, 0)
 		}
 		return m, nil
 
 	case "l", "right":
-		if contentWidth > m.uiWidth {
-			m.uiScrollH = min(m.uiScrollH+1, contentWidth-m.uiWidth)
+		if m.logWidth() > m.uiWidth {
+			m.uiScrollH = min(m.uiScrollH+1, m.logWidth()-m.uiWidth)
 		}
 		return m, nil
 
@@ -541,112 +2325,883 @@ func (m model) handleNormalInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case "$":
-		if contentWidth > m.uiWidth {
-			m.uiScrollH = contentWidth - m.uiWidth
+		if m.logWidth() > m.uiWidth {
+			m.uiScrollH = m.logWidth() - m.uiWidth
 		}
 		return m, nil
 
 	case "/":
-		if !m.errorsView {
+		if m.logView() && !m.waitIndexed() {
 			m.filterView = true
 			return m, m.filterInput.Focus()
 		}
-		return m, nil
-
-	case "esc":
-		if m.errorsView {
-			m.errorsView = false
-			return m, nil
+		return m, nil
+
+	case "esc":
+		if !m.logView() {
+			m.errorsView = false
+			m.heatmapView = false
+			m.outputView = false
+			m.uiScrollH = 0
+			m.moveCursor(m.uiCursor)
+			return m, nil
+		}
+		if m.filterCompiled != nil {
+			m.filterCompiled = nil
+			m.filterInput.SetValue("")
+			cmd := m.applyFilter()
+			return m, cmd
+		}
+		return m, nil
+
+	case "[", "]":
+		if !m.logView() {
+			return m, nil
+		}
+		delta := 1
+		if msg.String() == "[" {
+			delta = -1
+		}
+		return m, m.withLoadingView(openRotation(m.stream, delta))
+
+	case "N":
+		if !m.logView() || m.waitIndexed() {
+			return m, nil
+		}
+		m.hideHousekeeping = !m.hideHousekeeping
+		cmd := m.applyFilter()
+		return m, cmd
+	}
+
+	return m, nil
+}
+
+// handleSortInput handles the key choosing the column to sort by (the same column again reverses the order, then
+// restores the chronological order, other keys cancel)
+func (m model) handleSortInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.sortPending = false
+	m.uiStatusMsg = ""
+	col, ok := sortKeys[msg.String()]
+	if !ok {
+		return m, nil
+	}
+	switch {
+	case col != m.sortCol:
+		m.sortCol, m.sortDesc = col, false
+	case !m.sortDesc:
+		m.sortDesc = true
+	default:
+		m.sortCol, m.sortDesc = -1, false
+		return m, m.applyFilter()
+	}
+	// the sorted entries are loaded by line like the entries matching a filter
+	m.filterApplied = true
+	m.uiCursor = 0
+	m.uiScrollV = 0
+	return m, m.withLoadingView(m.sortEntries())
+}
+
+// handleFilterInput handles keyboard input when in filter view
+func (m model) handleFilterInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter":
+		return m.submitFilter()
+
+	case "esc":
+		m.filterInput.Blur()
+		m.filterInput.SetValue("")
+		m.filterInputError = nil
+		m.filterView = false
+		m.uiStatusMsg = ""
+		return m, nil
+
+	default:
+		// let textinput handle all other keys (the caret is hidden once the expression is edited)
+		var cmd tea.Cmd
+		value := m.filterInput.Value()
+		m.filterInput, cmd = m.filterInput.Update(msg)
+		if m.filterInput.Value() != value {
+			m.filterInputError = nil
+		}
+		return m, cmd
+	}
+}
+
+// submitFilter compiles and applies the expression in the filter input
+func (m model) submitFilter() (tea.Model, tea.Cmd) {
+	m.filterInput.Blur()
+	m.filterView = false
+	// compile the filter, errors with a position keep the input open to fix them
+	compiled, err := m.filterCache.Compile(m.filterInput.Value())
+	if ferr := (*filter.Error)(nil); errors.As(err, &ferr) {
+		m.filterInputError = ferr
+		m.filterView = true
+		return m, m.filterInput.Focus()
+	}
+	m.filterInputError = nil
+	if err != nil {
+		m.filterError = err.Error()
+		m.filterCompiled = nil
+	} else {
+		m.filterCompiled = compiled
+		m.filterError = ""
+	}
+	m.filterWarning = strings.Join(filter.Warnings(m.filterCompiled), ", ")
+	cmd := m.applyFilter()
+	return m, cmd
+}
+
+// handleNoteInput handles keyboard input when typing the note of a bookmark
+func (m model) handleNoteInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter":
+		m.noteInput.Blur()
+		m.noteView = false
+		err := m.opts.Bookmarks.Set(bookmark.Bookmark{
+			File:    m.bookmarksFile,
+			Line:    m.noteLine,
+			Note:    m.noteInput.Value(),
+			Created: time.Now(),
+			Entry:   m.noteEntry,
+		})
+		if err != nil {
+			m.uiStatusMsg = m.uiStyles.statusError.Render(sanitizeString(err.Error()))
+			return m, nil
+		}
+		m.uiStatusMsg = fmt.Sprintf("bookmark: line %d", m.noteLine)
+		return m, nil
+
+	case "esc":
+		m.noteInput.Blur()
+		m.noteView = false
+		return m, nil
+
+	default:
+		// let textinput handle all other keys
+		var cmd tea.Cmd
+		m.noteInput, cmd = m.noteInput.Update(msg)
+		return m, cmd
+	}
+}
+
+// handleBookmarksInput handles keyboard input when in bookmarks view
+func (m model) handleBookmarksInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m, tea.Quit
+
+	case "j", "down":
+		m.bookmarksCursor = min(m.bookmarksCursor+1, max(len(m.bookmarks)-1, 0))
+
+	case "k", "up":
+		m.bookmarksCursor = max(m.bookmarksCursor-1, 0)
+
+	case "x":
+		if len(m.bookmarks) == 0 {
+			return m, nil
+		}
+		bm := m.bookmarks[m.bookmarksCursor]
+		if err := m.opts.Bookmarks.Remove(bm.File, bm.Line); err != nil {
+			m.uiStatusMsg = m.uiStyles.statusError.Render(sanitizeString(err.Error()))
+			return m, nil
+		}
+		m.bookmarks = m.opts.Bookmarks.All()
+		m.bookmarksCursor = min(m.bookmarksCursor, max(len(m.bookmarks)-1, 0))
+
+	case "enter":
+		if len(m.bookmarks) == 0 {
+			return m, nil
+		}
+		bm := m.bookmarks[m.bookmarksCursor]
+		if bm.File != m.bookmarksFile {
+			m.uiStatusMsg = m.uiStyles.statusError.Render(sanitizeString("error(tui): bookmark is in another file: " + bm.File))
+			return m, nil
+		}
+		i, found := slices.BinarySearch(m.entriesAvailable, bm.Line-1)
+		if m.sortCol >= 0 {
+			i = slices.Index(m.entriesAvailable, bm.Line-1)
+			found = i >= 0
+		}
+		if !found {
+			m.uiStatusMsg = m.uiStyles.statusError.Render(fmt.Sprintf("error(tui): line %d is hidden by the filter", bm.Line))
+			return m, nil
+		}
+		m.bookmarksView = false
+		m.moveCursor(i)
+		if m.filterApplied {
+			return m, m.checkLoadEntriesFiltered()
+		}
+		return m, m.checkLoadEntries()
+
+	case "esc":
+		m.bookmarksView = false
+	}
+	return m, nil
+}
+
+// handleProfilesInput handles keyboard input when in profiles view
+func (m model) handleProfilesInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m, tea.Quit
+
+	case "j", "down":
+		m.profilesCursor = min(m.profilesCursor+1, len(m.profiles)-1)
+
+	case "k", "up":
+		m.profilesCursor = max(m.profilesCursor-1, 0)
+
+	case "enter":
+		m.profilesView = false
+		return m, m.withLoadingView(openProfile(m.opts.Open, m.profiles[m.profilesCursor]))
+
+	case "esc":
+		m.profilesView = false
+	}
+	return m, nil
+}
+
+// handleBuilderInput handles keyboard input when in filter builder view (letters narrow the choices)
+func (m model) handleBuilderInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+
+	case "down":
+		m.builderCursor = min(m.builderCursor+1, max(len(m.builderNarrowed())-1, 0))
+
+	case "up":
+		m.builderCursor = max(m.builderCursor-1, 0)
+
+	case "enter":
+		choices := m.builderNarrowed()
+		if len(choices) == 0 {
+			return m, nil
+		}
+		return m.builderChoose(choices[min(m.builderCursor, len(choices)-1)].value)
+
+	case "esc":
+		m.builderInput.Blur()
+		m.builderView = false
+
+	default:
+		if msg.Type == tea.KeyBackspace && m.builderInput.Value() == "" {
+			return m.builderBack(), nil
+		}
+		// let textinput handle all other keys (the first choice is selected once the input changes)
+		var cmd tea.Cmd
+		value := m.builderInput.Value()
+		m.builderInput, cmd = m.builderInput.Update(msg)
+		if m.builderInput.Value() != value {
+			m.builderCursor = 0
+		}
+		return m, cmd
+	}
+	return m, nil
+}
+
+// builderChoose continues the filter builder with the chosen field, operator, value or next action
+func (m model) builderChoose(value string) (tea.Model, tea.Cmd) {
+	switch m.builderStep {
+	case builderStepField:
+		m.builderField = value
+		return m.builderGoto(builderStepOperator), nil
+	case builderStepOperator:
+		m.builderNegate = value == "is not"
+		return m.builderGoto(builderStepValue), nil
+	case builderStepValue:
+		if len(m.builderParts) > 0 {
+			m.builderParts = append(m.builderParts, m.builderJoin)
+		}
+		m.builderParts = append(m.builderParts, filter.Term(m.builderField, value, m.builderNegate, m.cfg.FilterOptions()))
+		return m.builderGoto(builderStepNext), nil
+	}
+	if value == "and" || value == "or" {
+		m.builderJoin = value
+		return m.builderGoto(builderStepField), nil
+	}
+	// the expression is applied or edited like a typed one
+	m.builderInput.Blur()
+	m.builderView = false
+	m.filterInput.SetValue(strings.Join(m.builderParts, " "))
+	m.filterInput.CursorEnd()
+	m.filterInputError = nil
+	if value == "edit" {
+		m.filterView = true
+		return m, m.filterInput.Focus()
+	}
+	return m.submitFilter()
+}
+
+// builderBack returns to the previous step of the filter builder (the last condition is removed when going back from
+// choosing the next action)
+func (m model) builderBack() model {
+	switch m.builderStep {
+	case builderStepOperator:
+		return m.builderGoto(builderStepField)
+	case builderStepValue:
+		return m.builderGoto(builderStepOperator)
+	case builderStepNext:
+		m.builderParts = m.builderParts[:len(m.builderParts)-1]
+		if len(m.builderParts) > 0 {
+			m.builderJoin = m.builderParts[len(m.builderParts)-1]
+			m.builderParts = m.builderParts[:len(m.builderParts)-1]
+		}
+		return m.builderGoto(builderStepField)
+	}
+	if len(m.builderParts) > 0 {
+		// drop the operator of the condition that was about to be added
+		return m.builderGoto(builderStepNext)
+	}
+	return m
+}
+
+// builderGoto shows the choices of the step of the filter builder
+func (m model) builderGoto(step int) model {
+	m.builderStep = step
+	m.builderCursor = 0
+	m.builderInput.SetValue("")
+	switch step {
+	case builderStepField:
+		m.builderChoices = nil
+		for _, name := range builderFields() {
+			note := ""
+			if examples, ok := builderExamples[name]; ok {
+				note = "e.g. " + strings.Join(examples, ", ")
+			} else {
+				values := m.builderObserved(name)
+				for i := range min(len(values), 3) {
+					note += values[i].value + ", "
+				}
+				note = strings.TrimSuffix(note, ", ")
+			}
+			m.builderChoices = append(m.builderChoices, builderChoice{name, note})
+		}
+	case builderStepOperator:
+		m.builderChoices = []builderChoice{
+			{"is", "entries with the value"},
+			{"is not", "entries without the value"},
+		}
+	case builderStepValue:
+		m.builderChoices = m.builderObserved(m.builderField)
+		for _, example := range builderExamples[m.builderField] {
+			m.builderChoices = append(m.builderChoices, builderChoice{example, "example"})
+		}
+	case builderStepNext:
+		m.builderChoices = []builderChoice{
+			{"apply", "filter by the expression"},
+			{"and", "add a condition the entries must match too"},
+			{"or", "add a condition the entries may match instead"},
+			{"edit", "edit the expression as text before applying it"},
+		}
+	}
+	return m
+}
+
+// builderNarrowed returns the choices of the current step of the filter builder containing the typed text (the typed
+// text itself is offered first when choosing a value)
+func (m model) builderNarrowed() []builderChoice {
+	typed := strings.TrimSpace(m.builderInput.Value())
+	if typed == "" {
+		return m.builderChoices
+	}
+	var choices []builderChoice
+	exact := false
+	for _, c := range m.builderChoices {
+		if strings.Contains(strings.ToLower(c.value), strings.ToLower(typed)) {
+			choices = append(choices, c)
+			exact = exact || c.value == typed
+		}
+	}
+	if m.builderStep == builderStepValue && !exact {
+		choices = append([]builderChoice{{typed, "typed value"}}, choices...)
+	}
+	return choices
+}
+
+// builderExpression returns the expression of the filter builder including the condition being built
+func (m model) builderExpression() string {
+	parts := slices.Clone(m.builderParts)
+	if m.builderStep != builderStepNext && len(parts) > 0 {
+		parts = append(parts, m.builderJoin)
+	}
+	if m.builderStep == builderStepValue && m.builderNegate {
+		parts = append(parts, "not")
+	}
+	if m.builderStep == builderStepOperator || m.builderStep == builderStepValue {
+		parts = append(parts, m.builderField)
+	}
+	return strings.Join(parts, " ")
+}
+
+// builderObserved returns the values of the field in the loaded entries (most frequent first)
+func (m model) builderObserved(field string) []builderChoice {
+	names := []string{field}
+	if field == "port" {
+		names = []string{"sport", "dport"}
+	}
+	counts := make(map[string]int)
+	count := func(entry *stream.LogEntry) {
+		for _, name := range names {
+			if value, ok := entry.Field(name); ok && value != "" {
+				counts[value]++
+			}
+		}
+	}
+	if m.filterApplied {
+		for _, entry := range m.entriesFiltered {
+			count(&entry)
+		}
+	} else {
+		for i := range m.entries {
+			count(&m.entries[i])
+		}
+	}
+	values := slices.Collect(maps.Keys(counts))
+	slices.SortFunc(values, func(a, b string) int {
+		return cmp.Or(cmp.Compare(counts[b], counts[a]), cmp.Compare(a, b))
+	})
+	choices := make([]builderChoice, 0, min(len(values), builderValues))
+	for _, value := range values[:min(len(values), builderValues)] {
+		choices = append(choices, builderChoice{value, fmt.Sprintf("entries: %d", counts[value])})
+	}
+	return choices
+}
+
+// builderFields returns the fields offered by the filter builder (fields of entries in display order, then fields
+// matched against the time of entries)
+func builderFields() []string {
+	var names []string
+	for _, name := range stream.FieldNames {
+		if filter.IsField(name) {
+			names = append(names, name)
+		}
+	}
+	return append(names, "port", "age", "hour", "weekday")
+}
+
+// handleInterfacesInput handles keyboard input when in interfaces view
+func (m model) handleInterfacesInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m, tea.Quit
+
+	case "j", "down":
+		m.interfacesCursor = min(m.interfacesCursor+1, max(len(m.interfacesSummaries)-1, 0))
+
+	case "k", "up":
+		m.interfacesCursor = max(m.interfacesCursor-1, 0)
+
+	case "enter":
+		if len(m.interfacesSummaries) == 0 {
+			return m, nil
+		}
+		m.interfacesView = false
+		return m.narrowFilter("iface " + m.interfacesSummaries[m.interfacesCursor].Interface)
+
+	case "esc":
+		m.interfacesView = false
+		return m, m.reloadEntries()
+	}
+	return m, nil
+}
+
+// handleFrequencyInput handles keyboard input when in frequency view
+func (m model) handleFrequencyInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m, tea.Quit
+
+	case "j", "down":
+		m.frequencyCursor = min(m.frequencyCursor+1, max(len(m.frequencyValues)-1, 0))
+
+	case "k", "up":
+		m.frequencyCursor = max(m.frequencyCursor-1, 0)
+
+	case "tab":
+		// all fields were counted at once
+		m.frequencyField = (m.frequencyField + 1) % len(frequencyFields)
+		m.frequencyValues = m.frequency.Values(frequencyFields[m.frequencyField])
+		m.frequencyCursor = 0
+
+	case "enter":
+		if len(m.frequencyValues) == 0 {
+			return m, nil
+		}
+		m.frequencyView = false
+		return m.narrowFilter(filter.Term(frequencyFields[m.frequencyField], m.frequencyValues[m.frequencyCursor].Value, false, m.cfg.FilterOptions()))
+
+	case "c", "esc":
+		m.frequencyView = false
+	}
+	return m, nil
+}
+
+// narrowFilter combines the current filter with the term and applies it (entries that have been appended to the file
+// while another view was shown are loaded first)
+func (m model) narrowFilter(term string) (tea.Model, tea.Cmd) {
+	filterValue := term
+	if m.filterCompiled != nil {
+		filterValue = fmt.Sprintf("(%s) and %s", m.filterInput.Value(), term)
+	}
+	compiled, err := m.filterCache.Compile(filterValue)
+	if err != nil {
+		m.filterError = err.Error()
+		return m, m.reloadEntries()
+	}
+	m.filterApplied = true
+	m.filterCompiled = compiled
+	m.filterError = ""
+	m.filterInput.SetValue(filterValue)
+	m.uiCursor = 0
+	m.uiScrollH = 0
+	m.uiScrollV = 0
+	if cmd := m.reloadEntries(); cmd != nil {
+		// the matching lines are collected once the new lines have been loaded
+		return m, cmd
+	}
+	return m, m.withLoadingView(m.scanAndFilter())
+}
+
+// applyFilter switches to filter view if a filter is set, icmpv6 housekeeping is hidden or entries are sorted (to
+// default view otherwise)
+func (m *model) applyFilter() tea.Cmd {
+	m.filterApplied = m.filterCompiled != nil || m.hideHousekeeping || m.sortCol >= 0
+	m.uiCursor = 0
+	m.uiScrollH = 0
+	m.uiScrollV = 0
+	if m.filterApplied {
+		return m.withLoadingView(m.scanAndFilter())
+	}
+	m.uiStatusMsg = ""
+	m.showAllLines()
+	return m.checkLoadEntries()
+}
+
+// scrolling
+
+// reading returns true while a background command reads the stream or a clone of it (extending the index would
+// reset the reader of the stream and remap the file)
+func (m model) reading() bool {
+	return m.loading || m.prefetching || m.uiLoading
+}
+
+// waitIndexed returns true (and tells the user to wait) while the file is being indexed
+func (m *model) waitIndexed() bool {
+	if m.indexing {
+		m.uiStatusMsg = "indexing: available once the whole file has been indexed"
+	}
+	return m.indexing
+}
+
+// logView returns true if log entries are shown (no other view is active)
+func (m model) logView() bool {
+	return !m.bookmarksView && !m.builderView && !m.errorsView && !m.frequencyView && !m.heatmapView && !m.interfacesView &&
+		!m.outputView && !m.profilesView
+}
+
+// contentHeight returns the number of lines between the header and the status line (at least one)
+func (m model) contentHeight() int {
+	if m.hud.shown {
+		return max(m.uiHeight-4, 1) // -4 for the header, overlay, status, and help lines
+	}
+	return max(m.uiHeight-3, 1) // -3 for the header, status, and help lines
+}
+
+// tooSmall returns true if the terminal is too small to show the UI
+func (m model) tooSmall() bool {
+	return m.uiWidth < uiMinWidth || m.uiHeight < uiMinHeight
+}
+
+// tooSmallView returns a centered message asking to enlarge the terminal
+func (m model) tooSmallView() string {
+	s := fmt.Sprintf("terminal too small\n%dx%d (min %dx%d)", m.uiWidth, m.uiHeight, uiMinWidth, uiMinHeight)
+	return lipgloss.NewStyle().
+		Width(m.uiWidth).
+		Height(m.uiHeight).
+		MaxWidth(m.uiWidth).
+		MaxHeight(m.uiHeight).
+		Align(lipgloss.Center, lipgloss.Center).
+		Render(s)
+}
+
+// markerWidth returns the width of the selection marker in front of lines (accessible mode)
+func (m model) markerWidth() int {
+	if m.opts.Accessible {
+		return 2
+	}
+	return 0
+}
+
+// markLine returns the line cut to the terminal width, prefixed with a selection marker in accessible mode
+func (m model) markLine(line string, selected bool) string {
+	if m.opts.Accessible {
+		marker := "  "
+		if selected {
+			marker = "> "
+		}
+		line = marker + line
+	}
+	return sliceString(line, 0, m.uiWidth)
+}
+
+// selectLine returns the line of a list cut to the terminal width, the selected line is reversed (marked in accessible
+// mode)
+func (m model) selectLine(line string, selected bool) string {
+	if selected && !m.opts.Accessible {
+		return m.uiStyles.entrySelected.Render(fmt.Sprintf("%-*s", m.uiWidth, sliceString(line, 0, m.uiWidth)))
+	}
+	return m.markLine(line, selected)
+}
+
+// classifiedAddr returns the address followed by its class (public addresses are not tinted, so not annotated)
+func classifiedAddr(addr, class string) string {
+	if addr == "" || class == "" || class == netclass.ClassPublic {
+		return addr
+	}
+	return addr + " (" + class + ")"
+}
+
+// logColumns returns the columns of the log view (compact on narrow terminals)
+func (m model) logColumns() columns {
+	if m.uiWidth < uiCompactWidth {
+		return columnsCompact
+	}
+	return columnsDefault
+}
+
+// logWidth returns the total width of the log view (including optional columns)
+func (m model) logWidth() int {
+	width := m.logColumns().width() + m.markerWidth()
+	if m.uiOrigin {
+		width += colWidthOrigin + 1 // +1 for the separating space
+	}
+	if m.uiLabel {
+		width += colWidthLabel + 1
+	}
+	if m.showSeverity() {
+		width += colWidthSeverity + 1
+	}
+	return width
+}
+
+// cellStyles returns the ranges of the log view line of entry that are styled separately from base (severity cell,
+// if enabled address cells tinted by class, and cells of the fields that matched the filter in bold)
+func (m model) cellStyles(entry *stream.LogEntry, base lipgloss.Style) []styledRange {
+	matched := make(map[string]bool)
+	for _, field := range filter.Highlights(m.filterCompiled, entry) {
+		matched[field] = true
+	}
+	var ranges []styledRange
+	cell := func(start, width int, style lipgloss.Style, styled bool, field string) {
+		if matched[field] {
+			style, styled = style.Bold(true).Underline(true), true
+		}
+		if styled {
+			ranges = append(ranges, styledRange{start, start + width, style})
+		}
+	}
+	pos := 0
+	if m.showSeverity() {
+		cell(pos, colWidthSeverity, m.uiStyles.severity[entry.Severity], true, "severity")
+		pos += colWidthSeverity + 1
+	}
+	if m.uiOrigin {
+		cell(pos, colWidthOrigin, base, false, "origin")
+		pos += colWidthOrigin + 1
+	}
+	if m.uiLabel {
+		cell(pos, colWidthLabel, base, false, "label")
+		pos += colWidthLabel + 1
+	}
+	cols := m.logColumns()
+	for col, field := range highlightFields {
+		style, styled := base, false
+		if m.uiClasses && col == colSource {
+			style, styled = m.uiStyles.addrClass[entry.SrcClass]
+		}
+		if m.uiClasses && col == colDest {
+			style, styled = m.uiStyles.addrClass[entry.DstClass]
 		}
-		if m.filterApplied {
-			m.filterApplied = false
-			m.filterCompiled = nil
-			m.filterInput.SetValue("")
-			m.uiScrollH = 0
-			m.uiScrollV = 0
-			m.uiStatusMsg = ""
-			m.showAllLines()
-			return m, m.checkLoadEntries()
+		if !styled {
+			style = base
 		}
-		return m, nil
+		cell(pos+cols.offset(col), cols.widths[col], style, styled, field)
 	}
+	return ranges
+}
 
-	return m, nil
+// annotationsBefore returns the annotations between the entry at index i (in entriesAvailable) and the one before
+// (none if either is not loaded or the entries are not in chronological order)
+func (m model) annotationsBefore(i int) []annotation.Annotation {
+	if len(m.opts.Annotations) == 0 || i <= 0 || i >= len(m.entriesAvailable) || m.sortCol >= 0 {
+		return nil
+	}
+	prev, entry := m.getEntryAtLine(m.entriesAvailable[i-1]), m.getEntryAtLine(m.entriesAvailable[i])
+	if prev == nil || entry == nil {
+		return nil
+	}
+	return annotation.Between(m.opts.Annotations, prev.Time, entry.Time)
 }
 
-// handleFilterInput handles keyboard input when in filter view
-func (m model) handleFilterInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	switch msg.String() {
-	case "enter":
-		filterValue := m.filterInput.Value()
-		m.filterApplied = len(filterValue) > 0
-		m.filterInput.Blur()
-		m.filterView = false
-		m.uiScrollH = 0
-		m.uiScrollV = 0
-		// compile the filter
-		if m.filterApplied {
-			compiled, err := filter.Compile(filterValue)
-			if err != nil {
-				m.filterError = err.Error()
-				m.filterApplied = false
-				m.filterCompiled = nil
-			} else {
-				m.filterCompiled = compiled
-				m.filterError = ""
-				return m, m.withLoadingView(m.scanAndFilter())
+// logRows returns the number of rows the entries from start to end (inclusive) take up in the log view
+// (including separator rows of annotations)
+func (m model) logRows(start, end int) int {
+	rows := 0
+	for i := start; i <= end; i++ {
+		rows += len(m.annotationsBefore(i)) + 1
+	}
+	return rows
+}
+
+// logStart returns the index (in entriesAvailable) of the first entry shown in the log view
+func (m model) logStart() int {
+	// separator rows push the entries down, keep the cursor visible
+	start := m.uiScrollV
+	for start < m.uiCursor && m.logRows(start, m.uiCursor) > m.contentHeight() {
+		start++
+	}
+	return start
+}
+
+// logEnd returns the index (in entriesAvailable) after the last entry shown in the log view
+func (m model) logEnd(start int) int {
+	end, rows := start, 0
+	for i := start; i < len(m.entriesAvailable); i++ {
+		rows += len(m.annotationsBefore(i)) + 1
+		if rows > m.contentHeight() {
+			break
+		}
+		end = i + 1
+	}
+	return end
+}
+
+// print passes the entries shown in the log view (or all entries matching the filter) to the printer
+func (m model) print() error {
+	if !m.opts.PrintAll {
+		start := m.logStart()
+		for _, lineNum := range m.entriesAvailable[start:m.logEnd(start)] {
+			entry := m.getEntryAtLine(lineNum)
+			if entry == nil {
+				continue
+			}
+			if err := m.opts.Print(entry); err != nil {
+				return err
 			}
-		} else {
-			m.filterCompiled = nil
-			m.filterError = ""
 		}
-		if !m.filterApplied {
-			m.uiStatusMsg = ""
-			m.showAllLines()
+		return nil
+	}
+	if !m.indexed || m.indexing || m.prefetching {
+		// the stream is still being read in the background
+		return fmt.Errorf("error(tui): nothing printed, the file was still being indexed")
+	}
+	var printErr error
+	err := m.scanMatching(func(entry *stream.LogEntry) {
+		if printErr == nil {
+			printErr = m.opts.Print(entry)
 		}
-		return m, m.checkLoadEntries()
+	})
+	return cmp.Or(err, printErr)
+}
 
-	case "esc":
-		m.filterInput.Blur()
-		m.filterInput.SetValue("")
-		m.filterView = false
-		m.uiStatusMsg = ""
-		return m, nil
+// renderAnnotation renders an annotation as separator row
+func (m model) renderAnnotation(a annotation.Annotation) string {
+	line := sliceString(sanitizeString(fmt.Sprintf("-- %s %s ", a.Time.Format("Jan 02 15:04:05"), a.Text)), 0, m.uiWidth)
+	if fill := m.uiWidth - len(line); fill > 0 {
+		line += strings.Repeat("-", fill)
+	}
+	return m.uiStyles.annotation.Render(line)
+}
 
-	default:
-		// let textinput handle all other keys
-		var cmd tea.Cmd
-		m.filterInput, cmd = m.filterInput.Update(msg)
-		return m, cmd
+// alertsBadge returns the number of recent entries per alerting severity level (empty if there are none)
+func (m model) alertsBadge() string {
+	if len(m.alertsCounts) == 0 {
+		return ""
+	}
+	counts := make([]string, len(m.alertsCounts))
+	for i, c := range m.alertsCounts {
+		counts[i] = fmt.Sprintf("%d %s", c.Entries, c.Severity)
 	}
+	badge := fmt.Sprintf("%s in last %dm", strings.Join(counts, ", "), int(alertsWindow.Minutes()))
+	return m.uiStyles.severity[m.alertsCounts[0].Severity].Render(badge)
 }
 
-// scrolling
+// sortMarker returns the marker appended to the header of the column the entries are sorted by
+func sortMarker(desc bool) string {
+	if desc {
+		return " v"
+	}
+	return " ^"
+}
 
-func (m *model) scrollDown(n int) {
-	var lines int
+// sortDirection returns the direction shown in the status bar after the sorted field
+func sortDirection(desc bool) string {
+	if desc {
+		return " desc"
+	}
+	return " asc"
+}
+
+// showSeverity returns true if the severity column is shown (classification rules are configured)
+func (m model) showSeverity() bool {
+	return len(m.cfg.Severity) > 0
+}
+
+// lineCount returns the number of lines in the current view
+func (m model) lineCount() int {
 	if m.errorsView {
-		lines = len(m.errors)
-	} else {
-		lines = len(m.entriesAvailable)
+		return len(m.errors)
+	}
+	if m.heatmapView {
+		return len(m.heatmap)
+	}
+	if m.outputView {
+		return len(m.output)
+	}
+	return len(m.entriesAvailable)
+}
+
+// scrollDown scrolls down n lines (moves the cursor in log view)
+func (m *model) scrollDown(n int) {
+	if m.logView() {
+		m.moveCursor(m.uiCursor + n)
+		return
 	}
-	contentHeight := m.uiHeight - 3 // -3 for header, status, and help line
-	maxScroll := max(lines-contentHeight, 0)
+	contentHeight := m.contentHeight()
+	maxScroll := max(m.lineCount()-contentHeight, 0)
 	m.uiScrollV = min(m.uiScrollV+n, maxScroll)
 }
 
+// scrollUp scrolls up n lines (moves the cursor in log view)
 func (m *model) scrollUp(n int) {
+	if m.logView() {
+		m.moveCursor(m.uiCursor - n)
+		return
+	}
 	m.uiScrollV = max(m.uiScrollV-n, 0)
 }
 
+// moveCursor moves the cursor to the given entry and scrolls it into view
+func (m *model) moveCursor(i int) {
+	m.uiCursor = max(min(i, len(m.entriesAvailable)-1), 0)
+	contentHeight := m.contentHeight()
+	if m.uiCursor < m.uiScrollV {
+		m.uiScrollV = m.uiCursor
+	} else if m.uiCursor >= m.uiScrollV+contentHeight {
+		m.uiScrollV = max(m.uiCursor-contentHeight+1, 0)
+	}
+}
+
 // view management
 
 // checkLoadEntries checks if the currently loaded contiguous block needs reloading and returns a command to load it if needed
-func (m model) checkLoadEntries() tea.Cmd {
-	if !m.indexed || m.uiLoading || len(m.entriesAvailable) == 0 {
+// (swaps in the prefetched block if it covers the visible range)
+func (m *model) checkLoadEntries() tea.Cmd {
+	if !m.indexed || m.uiLoading || m.loading || len(m.entriesAvailable) == 0 {
 		return nil
 	}
-	contentHeight := m.uiHeight - 3 // -3 for header, status, and help line
+	contentHeight := m.contentHeight()
 	visibleStart := m.uiScrollV
 	visibleEnd := min(visibleStart+contentHeight, len(m.entriesAvailable))
 	minLine := m.entriesTotal
@@ -657,12 +3212,41 @@ func (m model) checkLoadEntries() tea.Cmd {
 		maxLine = max(maxLine, lineNum)
 	}
 	if minLine < m.entriesStart || maxLine >= m.entriesStart+len(m.entries) {
-		// center around the middle of visible range
-		centerLine := (minLine + maxLine) / 2
-		newStart := max(centerLine-maxEntriesInMemory/2, 0)
-		return loadEntries(m.stream, newStart, maxEntriesInMemory)
+		if minLine < m.entriesNextStart || maxLine >= m.entriesNextStart+len(m.entriesNext) {
+			// center around the middle of visible range
+			centerLine := (minLine + maxLine) / 2
+			newStart := max(centerLine-m.entriesMax/2, 0)
+			m.loading = true
+			return loadEntries(m.stream, newStart, m.entriesMax)
+		}
+		m.entries, m.entriesNext = m.entriesNext, nil
+		m.entriesStart = m.entriesNextStart
 	}
-	return nil
+	return m.checkPrefetchEntries(minLine, maxLine)
+}
+
+// checkPrefetchEntries returns a command to prefetch the adjacent block if the visible range is near the edge of the loaded block
+func (m *model) checkPrefetchEntries(minLine, maxLine int) tea.Cmd {
+	if m.prefetching {
+		return nil
+	}
+	margin := m.entriesMax / 4
+	blockEnd := m.entriesStart + len(m.entries)
+	// the prefetched block is centered around the edge, so the visible range ends up in its middle
+	var newStart int
+	switch {
+	case maxLine >= blockEnd-margin && blockEnd < m.entriesTotal:
+		newStart = blockEnd - m.entriesMax/2
+	case minLine < m.entriesStart+margin && m.entriesStart > 0:
+		newStart = max(m.entriesStart-m.entriesMax/2, 0)
+	default:
+		return nil
+	}
+	if len(m.entriesNext) > 0 && m.entriesNextStart == newStart {
+		return nil
+	}
+	m.prefetching = true
+	return prefetchEntries(m.stream, newStart, m.entriesMax)
 }
 
 // checkLoadEntriesFiltered checks if any visible filtered entries are missing and returns a command to load them if needed
@@ -670,7 +3254,7 @@ func (m model) checkLoadEntriesFiltered() tea.Cmd {
 	if !m.filterApplied || len(m.entriesAvailable) == 0 {
 		return nil
 	}
-	contentHeight := m.uiHeight - 3 // -3 for header, status, and help line
+	contentHeight := m.contentHeight()
 	visibleStart := m.uiScrollV
 	visibleEnd := min(visibleStart+contentHeight, len(m.entriesAvailable))
 	linesToLoad := make([]int, 0, visibleEnd-visibleStart)
@@ -690,6 +3274,15 @@ func (m model) checkLoadEntriesFiltered() tea.Cmd {
 	return nil
 }
 
+// entriesLimit returns the number of entries per block that fit into the memory limit (in MB)
+// (the loaded and the prefetched block share the limit)
+func entriesLimit(memoryLimit int) int {
+	if memoryLimit <= 0 {
+		return entriesInMemoryDefault
+	}
+	return max(memoryLimit*1024*1024/entrySize/2, entriesInMemoryMin)
+}
+
 // getEntryAtLine returns the log entry for a specific line number
 func (m model) getEntryAtLine(lineNum int) *stream.LogEntry {
 	if m.filterApplied && len(m.entriesFiltered) > 0 {
@@ -708,8 +3301,107 @@ func (m model) getEntryAtLine(lineNum int) *stream.LogEntry {
 	return &m.entries[idx]
 }
 
+// reloadEntries returns a command to reload the entries if lines have been indexed while another view was shown
+func (m *model) reloadEntries() tea.Cmd {
+	entriesTotal := m.stream.TotalLines()
+	if entriesTotal == m.entriesTotal {
+		return nil
+	}
+	return m.withLoadingView(func() tea.Msg {
+		return reloadMsg{entriesTotal: entriesTotal}
+	})
+}
+
+// showOutput switches to output view with the given header and lines
+func (m *model) showOutput(title string, lines []string) {
+	m.output = lines
+	m.outputTitle = title
+	m.outputView = true
+	m.errorsView = false
+	m.heatmapView = false
+	m.uiScrollH = 0
+	m.uiScrollV = 0
+}
+
+// detailLines returns all fields of an entry (including extra fields) as name/value lines
+func detailLines(entry *stream.LogEntry) []string {
+	lines := make([]string, 0, len(stream.FieldNames)+len(entry.Extra))
+	for _, name := range stream.FieldNames {
+		value, _ := entry.Field(name)
+		lines = append(lines, fmt.Sprintf("%-10s %s", name, value))
+	}
+	for _, name := range slices.Sorted(maps.Keys(entry.Extra)) {
+		lines = append(lines, fmt.Sprintf("%-10s %s", name, entry.Extra[name]))
+	}
+	return lines
+}
+
+// translationLines returns the translation section of the details (empty if the entry is not a translation)
+func translationLines(entry *stream.LogEntry, related []stream.LogEntry) []string {
+	if !entry.Translation() {
+		return nil
+	}
+	lines := []string{"", "Translation"}
+	if len(related) == 0 {
+		return append(lines, fmt.Sprintf("no translated entries within %s (not translated or not logged)", stats.NATWindow))
+	}
+	for i := range related {
+		e := &related[i]
+		status := "translated"
+		if e.Src == entry.Src && e.SrcPort == entry.SrcPort && e.Dst == entry.Dst && e.DstPort == entry.DstPort {
+			status = "unchanged"
+		}
+		lines = append(lines, fmt.Sprintf("%s  %-6s %-10s %-4s %s -> %s  %s  %s",
+			e.Time.Format(time.DateTime), e.Action, e.Interface, e.Direction,
+			stats.Endpoint(e.Src, e.SrcPort), stats.Endpoint(e.Dst, e.DstPort), e.ProtoName, status))
+	}
+	return lines
+}
+
+// getSelectedEntry returns the log entry under the cursor
+func (m model) getSelectedEntry() *stream.LogEntry {
+	if m.uiCursor < 0 || m.uiCursor >= len(m.entriesAvailable) {
+		return nil
+	}
+	return m.getEntryAtLine(m.entriesAvailable[m.uiCursor])
+}
+
+// expandCommand splits a command template into arguments and substitutes {field} placeholders
+func expandCommand(template string, entry *stream.LogEntry) []string {
+	args := strings.Fields(template)
+	for i, arg := range args {
+		args[i] = placeholderRegexp.ReplaceAllStringFunc(arg, func(placeholder string) string {
+			value, ok := entry.Field(placeholder[1 : len(placeholder)-1])
+			if !ok {
+				return placeholder
+			}
+			return value
+		})
+	}
+	return args
+}
+
 // filtering
 
+// startFollow selects the newest entry and starts checking the file for appended lines (follow mode)
+func (m *model) startFollow() tea.Cmd {
+	m.followGen++
+	m.followEnd = true
+	return tickFollow(m.stream, m.followGen)
+}
+
+// followSelect selects the newest entry if requested and loads the visible entries (follow mode)
+func (m *model) followSelect() tea.Cmd {
+	if m.followEnd && m.indexed && len(m.entriesAvailable) > 0 {
+		m.moveCursor(len(m.entriesAvailable) - 1)
+		m.followEnd = false
+	}
+	if m.filterApplied {
+		return m.checkLoadEntriesFiltered()
+	}
+	return m.checkLoadEntries()
+}
+
 // showAllLines populates visibleLines with all line numbers and is used when initializing or when clearing a filter
 func (m *model) showAllLines() {
 	m.entriesAvailable = m.entriesAvailable[:0]
@@ -720,31 +3412,74 @@ func (m *model) showAllLines() {
 
 // scanAndFilter scans the entire file and builds the list of matching line numbers
 func (m model) scanAndFilter() tea.Cmd {
+	// the matching lines are cached unless entries are hidden (the hidden count isn't kept)
+	cached := m.filterCompiled != nil && !m.hideHousekeeping
+	expression, fingerprint := m.filterInput.Value(), m.stream.CheckpointAt(-1)
 	return func() tea.Msg {
+		if cached {
+			// the same filter was applied to the same lines of the file before
+			if lines, ok := m.filterCache.Lines(expression, fingerprint); ok {
+				return filterMsg{entriesAvailable: slices.Clone(lines)}
+			}
+		}
 		entries := make([]int, 0)
-		if err := m.stream.SeekToLine(0); err != nil {
-			return streamErrorMsg{err: err}
+		hidden := 0
+		// columns don't record what's needed to detect neighbor discovery
+		if c := m.stream.Columns(); c != nil && filter.Columnar(m.filterCompiled) && !m.hideHousekeeping {
+			// answer the filter from the index without reading the file
+			for i := range c.Len() {
+				entry := c.Entry(i)
+				if m.filterCompiled.Matches(&entry) {
+					entries = append(entries, i)
+				}
+			}
+			m.filterCache.SetLines(expression, fingerprint, slices.Clone(entries))
+			return filterMsg{entriesAvailable: entries}
 		}
+		terms := filter.Terms(m.filterCompiled)
+		seek := true
 		for i := 0; i < m.entriesTotal; i++ {
+			if i%stream.TermBlockLines == 0 && !m.stream.MayContain(i, terms) {
+				// skip blocks that can't contain the searched values
+				i += stream.TermBlockLines - 1
+				seek = true
+				continue
+			}
+			if seek {
+				if err := m.stream.SeekToLine(i); err != nil {
+					return streamErrorMsg{err: err}
+				}
+				seek = false
+			}
 			entry := m.stream.Next()
 			if entry == nil {
 				break
 			}
-			if m.filterCompiled.Matches(entry) {
-				entries = append(entries, i)
+			if m.filterCompiled != nil && !m.filterCompiled.Matches(entry) {
+				continue
+			}
+			if m.hideHousekeeping && entry.Housekeeping() {
+				hidden++
+				continue
 			}
+			entries = append(entries, i)
+		}
+		if cached {
+			m.filterCache.SetLines(expression, fingerprint, slices.Clone(entries))
 		}
-		return filterMsg{entriesAvailable: entries}
+		return filterMsg{entriesAvailable: entries, hidden: hidden}
 	}
 }
 
-// public
-
-// Display starts the TUI and displays the log file from the given stream
-func Display(s *stream.Stream) error {
-	defer s.Close()
-
+// newModel creates the initial model for the given stream
+func newModel(s *stream.Stream, cfg *config.Config, e *plugin.Enricher, opts Options) model {
+	s.SetColumns(opts.Columns)
+	s.SetTerms(opts.Terms)
+	s.SetMmap(opts.Mmap)
 	st := newStyles()
+	if opts.Accessible {
+		st = newAccessibleStyles()
+	}
 
 	sp := spinner.New()
 	sp.Spinner = spinner.Dot
@@ -755,22 +3490,348 @@ func Display(s *stream.Stream) error {
 	ti.Cursor.Style = st.status
 	ti.Cursor.TextStyle = st.status
 
-	m := model{
+	ni := textinput.New()
+	ni.Prompt = "note: "
+	ni.TextStyle = st.status
+	ni.Cursor.Style = st.status
+	ni.Cursor.TextStyle = st.status
+
+	bi := textinput.New()
+	bi.Prompt = "narrow: "
+	bi.TextStyle = st.status
+	bi.Cursor.Style = st.status
+	bi.Cursor.TextStyle = st.status
+
+	// bookmarks are stored under the resolved path (not available if it can't be determined)
+	bookmarksFile, _ := bookmark.FilePath(s.GetPathRel())
+
+	// alerts are counted while severity rules are configured (not available if the file can't be followed)
+	var alerts *stats.Alerts
+	var follower *stream.Follower
+	if len(cfg.Severity) > 0 {
+		if f, err := s.Follow(); err == nil {
+			alerts = stats.NewAlerts(alertsWindow)
+			follower = f
+		}
+	}
+
+	return model{
+		alerts:           alerts,
+		alertsFollower:   follower,
+		bookmarksFile:    bookmarksFile,
+		builderInput:     bi,
+		cfg:              cfg,
+		crash:            &crash{},
+		enricher:         e,
+		hud:              &hud{},
+		opts:             opts,
 		stream:           s,
 		indexed:          false,
-		entries:          make([]stream.LogEntry, 0, maxEntriesInMemory),
+		entries:          make([]stream.LogEntry, 0),
 		entriesFiltered:  make(map[int]stream.LogEntry),
 		entriesAvailable: make([]int, 0),
+		entriesMax:       entriesLimit(opts.MemoryLimit),
 		filterApplied:    false,
+		filterCache:      filter.NewCache(filterCacheSize, cfg.FilterOptions()),
 		filterInput:      ti,
+		follow:           opts.Follow,
+		followEnd:        opts.Follow,
+		hideHousekeeping: opts.HideNDP,
+		noteInput:        ni,
+		profiles:         cfg.ProfileNames(),
+		sortCol:          -1,
 		uiLoading:        true,
 		uiLoadingSpinner: sp,
 		uiStyles:         st,
 	}
+}
+
+// analysis
 
-	p := tea.NewProgram(m, tea.WithAltScreen())
-	if _, err := p.Run(); err != nil {
+// scanMatching scans the entire file and calls fn for every entry matching the current filter (see readMatching)
+func (m model) scanMatching(fn func(entry *stream.LogEntry)) error {
+	return m.readMatching(0, m.entriesTotal, fn)
+}
+
+// readMatching reads the lines from start to end and calls fn for every entry matching the current filter
+// (hidden entries are skipped)
+func (m model) readMatching(start, end int, fn func(entry *stream.LogEntry)) error {
+	if start >= end {
+		return nil
+	}
+	if err := m.stream.SeekToLine(start); err != nil {
 		return err
 	}
+	for i := start; i < end; i++ {
+		entry := m.stream.Next()
+		if entry == nil {
+			break
+		}
+		if !m.matches(entry) {
+			continue
+		}
+		fn(entry)
+	}
 	return nil
 }
+
+// matches returns true if the entry matches the current filter and is not hidden
+func (m model) matches(entry *stream.LogEntry) bool {
+	if m.filterCompiled != nil && !m.filterCompiled.Matches(entry) {
+		return false
+	}
+	return !m.hideHousekeeping || !entry.Housekeeping()
+}
+
+// sortKey is the value of the sorted column of the entry at line
+type sortKey struct {
+	line int
+	addr netip.Addr // address columns
+	num  int64      // time and port columns
+	str  string     // other columns (and addresses that can't be parsed)
+}
+
+// newSortKey returns the value of the column of the entry at line
+func newSortKey(line int, entry *stream.LogEntry, col int) sortKey {
+	key := sortKey{line: line}
+	switch col {
+	case colTime:
+		key.num = entry.Time.UnixNano()
+	case colSrcPort:
+		key.num = int64(entry.SrcPort)
+	case colDstPort:
+		key.num = int64(entry.DstPort)
+	case colSource, colDest:
+		key.str, _ = entry.Field(sortFields[col])
+		// addresses are compared numerically (10.0.0.2 before 10.0.0.10)
+		key.addr, _ = netip.ParseAddr(key.str)
+	default:
+		key.str, _ = entry.Field(sortFields[col])
+	}
+	return key
+}
+
+// sortEntries sorts the entries that can be displayed by the selected column (entries with the same value stay in
+// chronological order)
+func (m model) sortEntries() tea.Cmd {
+	lines, col, desc := slices.Clone(m.entriesAvailable), m.sortCol, m.sortDesc
+	return func() tea.Msg {
+		keys := make([]sortKey, 0, len(lines))
+		if err := readLines(m.stream, lines, func(line int, entry *stream.LogEntry) {
+			keys = append(keys, newSortKey(line, entry, col))
+		}); err != nil {
+			return streamErrorMsg{err: err}
+		}
+		slices.SortFunc(keys, func(a, b sortKey) int {
+			c := cmp.Or(a.addr.Compare(b.addr), cmp.Compare(a.num, b.num), cmp.Compare(a.str, b.str))
+			if desc {
+				c = -c
+			}
+			return cmp.Or(c, cmp.Compare(a.line, b.line))
+		})
+		for i := range keys {
+			lines[i] = keys[i].line
+		}
+		return sortMsg{entriesAvailable: lines}
+	}
+}
+
+// readLines reads the given lines in file order and calls fn for every entry (lines are sorted in place, only the
+// lines in between are skipped by seeking, so the matching lines of a filter are read without scanning the file)
+func readLines(s *stream.Stream, lines []int, fn func(line int, entry *stream.LogEntry)) error {
+	slices.Sort(lines)
+	next := -1
+	for _, line := range lines {
+		if line != next {
+			if err := s.SeekToLine(line); err != nil {
+				return err
+			}
+		}
+		entry := s.Next()
+		if entry == nil {
+			return fmt.Errorf("error(tui): could not read line %d", line)
+		}
+		fn(line, entry)
+		next = line + 1
+	}
+	return nil
+}
+
+// countFrequency counts the entries that can be displayed per value of frequencyFields (the lines of the current
+// filter are read, the file isn't scanned again)
+func (m model) countFrequency() tea.Cmd {
+	lines := slices.Clone(m.entriesAvailable)
+	return func() tea.Msg {
+		f := stats.NewFrequency(frequencyFields...)
+		if err := readLines(m.stream, lines, func(_ int, entry *stream.LogEntry) {
+			f.Add(entry)
+		}); err != nil {
+			return streamErrorMsg{err: err}
+		}
+		return frequencyMsg{frequency: f}
+	}
+}
+
+// exportIncident writes the entries matching the current filter with raw lines, summary and bookmarks into
+// an incident bundle (tar.gz) in the working directory
+func (m model) exportIncident() tea.Cmd {
+	b := incident.Bundle{Match: m.matches}
+	if m.filterCompiled != nil {
+		b.Filter = m.filterInput.Value()
+	}
+	if m.opts.Bookmarks != nil && m.bookmarksFile != "" {
+		b.Bookmarks = m.opts.Bookmarks.File(m.bookmarksFile)
+	}
+	path := fmt.Sprintf("incident-%s.tar.gz", time.Now().Format("20060102-150405"))
+	s := m.stream
+	return func() tea.Msg {
+		r, err := s.Clone()
+		if err != nil {
+			return incidentMsg{err: err}
+		}
+		defer r.Close()
+		entries, err := b.Write(path, r)
+		return incidentMsg{path: path, entries: entries, err: err}
+	}
+}
+
+// countInterfaces counts entries per interface over entries matching the current filter
+// (refresh is set if the view is already shown)
+func (m model) countInterfaces(refresh bool) tea.Cmd {
+	return func() tea.Msg {
+		f := stats.NewInterfaces()
+		lines := m.stream.TotalLines()
+		if err := m.readMatching(0, lines, f.Add); err != nil {
+			if !refresh {
+				return streamErrorMsg{err: err}
+			}
+			return interfacesUpdateMsg{err: err}
+		}
+		return interfacesMsg{interfaces: f, lines: lines, refresh: refresh}
+	}
+}
+
+// followInterfaces indexes the lines appended to the file and reads the new entries matching the current filter
+func (m model) followInterfaces() tea.Cmd {
+	from := m.interfacesLines
+	return func() tea.Msg {
+		if err := m.stream.ExtendIndex(); err != nil {
+			return interfacesUpdateMsg{err: err}
+		}
+		lines := m.stream.TotalLines()
+		if lines < from {
+			return interfacesUpdateMsg{reset: true}
+		}
+		var entries []stream.LogEntry
+		if err := m.readMatching(from, lines, func(entry *stream.LogEntry) {
+			entries = append(entries, *entry)
+		}); err != nil {
+			return interfacesUpdateMsg{err: err}
+		}
+		return interfacesUpdateMsg{entries: entries, lines: lines}
+	}
+}
+
+// detectBruteforce runs brute-force detection over entries matching the current filter
+func (m model) detectBruteforce() tea.Cmd {
+	return func() tea.Msg {
+		b := stats.NewBruteforce(m.cfg.Bruteforce.Threshold, time.Duration(m.cfg.Bruteforce.Window))
+		if err := m.scanMatching(b.Add); err != nil {
+			return streamErrorMsg{err: err}
+		}
+		return bruteforceMsg{offenders: b.Offenders()}
+	}
+}
+
+// summarizePorts counts distinct sources per destination port over entries matching the current filter
+func (m model) summarizePorts() tea.Cmd {
+	return func() tea.Msg {
+		p := stats.NewPorts()
+		if err := m.scanMatching(p.Add); err != nil {
+			return streamErrorMsg{err: err}
+		}
+		return portsMsg{summaries: p.Summaries()}
+	}
+}
+
+// summarizeRules counts entries per firewall rule over entries matching the current filter
+func (m model) summarizeRules() tea.Cmd {
+	return func() tea.Msg {
+		r := stats.NewRules()
+		if err := m.scanMatching(r.Add); err != nil {
+			return streamErrorMsg{err: err}
+		}
+		return rulesMsg{summaries: r.Summaries()}
+	}
+}
+
+// analyzeGaps measures the time between consecutive entries matching the current filter
+func (m model) analyzeGaps() tea.Cmd {
+	return func() tea.Msg {
+		g := stats.NewGaps()
+		if err := m.scanMatching(g.Add); err != nil {
+			return streamErrorMsg{err: err}
+		}
+		return gapsMsg{summary: g.Summary()}
+	}
+}
+
+// buildHeatmap counts entries per day and hour of day over entries matching the current filter
+func (m model) buildHeatmap() tea.Cmd {
+	return func() tea.Msg {
+		h := stats.NewHeatmap()
+		if err := m.scanMatching(h.Add); err != nil {
+			return streamErrorMsg{err: err}
+		}
+		return heatmapMsg{days: h.Days()}
+	}
+}
+
+// public
+
+// Display starts the TUI and displays the log file from the given stream
+func Display(s *stream.Stream, cfg *config.Config, opts Options) error {
+
+	var e *plugin.Enricher
+	if cfg.Enrich != "" {
+		var err error
+		if e, err = plugin.NewEnricher(cfg.Enrich); err != nil {
+			return err
+		}
+		defer e.Close()
+	}
+
+	var programOpts []tea.ProgramOption
+	if !opts.Inline {
+		programOpts = append(programOpts, tea.WithAltScreen())
+	}
+	if opts.Print != nil {
+		// stdout is reserved for the printed entries, e.g. to be piped
+		lipgloss.SetDefaultRenderer(lipgloss.NewRenderer(os.Stderr))
+		programOpts = append(programOpts, tea.WithOutput(os.Stderr))
+	}
+	m := newModel(s, cfg, e, opts)
+	p := tea.NewProgram(m, programOpts...)
+	if opts.Reload != nil {
+		done := make(chan struct{})
+		defer close(done)
+		go watchConfig(p, opts.Reload, done)
+	}
+	final, err := p.Run()
+	// the stream is replaced when switching profiles
+	last, ok := final.(model)
+	if ok {
+		s = last.stream
+	}
+	if crashErr := m.crash.error(); crashErr != nil {
+		// commands that are still running may use the stream, only the decompressed copy of a compressed file is
+		// removed
+		s.Unlink()
+		return crashErr
+	}
+	if ok && err == nil && opts.Print != nil {
+		err = last.print()
+	}
+	s.Close()
+	return err
+}
@@ -0,0 +1,576 @@
+// Copyright (c) 2025 allddd <me@allddd.onl>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice, this
+//    list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package tui
+
+import (
+	"cmp"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"gitlab.com/allddd/opnsense-filterlog/internal/config"
+	"gitlab.com/allddd/opnsense-filterlog/internal/filter"
+	"gitlab.com/allddd/opnsense-filterlog/internal/stream"
+)
+
+// testModel returns a model showing n loaded entries
+func testModel(t *testing.T, n int) model {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "filter.log")
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s, err := stream.NewStream(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	m := newModel(s, config.New(), nil, Options{})
+	m.indexed = true
+	m.uiLoading = false
+	base := time.Date(2025, 10, 10, 0, 0, 0, 0, time.UTC)
+	for i := range n {
+		m.entries = append(m.entries, stream.LogEntry{
+			Time: base.Add(time.Duration(i) * time.Second), Action: stream.ActionBlock, Interface: "igb0", Direction: "in",
+			Src: "2001:db8::1", SrcPort: 51234, Dst: "192.0.2.1", DstPort: 443, ProtoName: "tcp", Reason: "match",
+		})
+		m.entriesAvailable = append(m.entriesAvailable, i)
+	}
+	m.entriesTotal = n
+	return m
+}
+
+func TestSanitizeString(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "plain", input: "igb0", expected: "igb0"},
+		{name: "multi-byte", input: "münchen", expected: "münchen"},
+		{name: "csi sequence", input: "\x1b[31mred\x1b[0m", expected: `\u001b[31mred\u001b[0m`},
+		{name: "osc sequence", input: "\x1b]0;title\x07", expected: `\u001b]0;title\u0007`},
+		{name: "c1 controls", input: "a\u0085b\u009bc\u0080", expected: `a\u0085b\u009bc\u0080`},
+		{name: "invalid utf-8", input: "a\xffb\xc3", expected: `a\xffb\xc3`},
+		{name: "tab", input: "a\tb", expected: "a b"},
+		{name: "newlines", input: "a\nb\r", expected: `a\u000ab\u000d`},
+		{name: "bidi and format", input: "a\u202eb\u200bc\ufeff", expected: `a\u202eb\u200bc\ufeff`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := sanitizeString(tc.input); got != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestTruncateString(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		length   int
+		expected string
+	}{
+		{name: "fits", input: "igb0", length: 5, expected: "igb0"},
+		{name: "ascii", input: "abcdefgh", length: 6, expected: "abc..."},
+		{name: "multi-byte", input: "äöüäöüä", length: 6, expected: "äöü..."},
+		{name: "multi-byte fits", input: "äöüäöü", length: 6, expected: "äöüäöü"},
+		{name: "shorter than ellipsis", input: "äöüä", length: 2, expected: "äö"},
+		{name: "sanitized", input: "\x1b[31m", length: 6, expected: `\u0...`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := truncateString(tc.input, tc.length)
+			if got != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("expected valid utf-8, got %q", got)
+			}
+		})
+	}
+}
+
+func TestColumns(t *testing.T) {
+	names := []string{"Time", "Action", "Interface", "Dir", "Source", "SrcPort", "Destination", "DstPort", "Proto", "Reason"}
+	for _, cols := range []columns{columnsDefault, columnsCompact} {
+		if len(cols.widths) != len(names) {
+			t.Fatalf("expected %d columns, got %d", len(names), len(cols.widths))
+		}
+		line := cols.format(names...)
+		if len(line) != cols.width() {
+			t.Errorf("expected line of width %d, got %d", cols.width(), len(line))
+		}
+		if strings.Contains(line, "...") {
+			t.Errorf("expected header names to fit, got %q", line)
+		}
+		if got := line[cols.offset(colSource):]; !strings.HasPrefix(got, "Source ") {
+			t.Errorf("expected source column at offset %d, got %q", cols.offset(colSource), got)
+		}
+	}
+	if columnsCompact.width() >= uiCompactWidth {
+		t.Errorf("expected compact columns narrower than %d, got %d", uiCompactWidth, columnsCompact.width())
+	}
+}
+
+func TestWindowSize(t *testing.T) {
+	tests := []struct {
+		name     string
+		width    int
+		height   int
+		tooSmall bool
+		compact  bool
+	}{
+		{"tiny", 1, 1, true, true},
+		{"narrow", uiMinWidth - 1, 24, true, true},
+		{"low", 80, uiMinHeight - 1, true, true},
+		{"minimum", uiMinWidth, uiMinHeight, false, true},
+		{"compact", uiCompactWidth - 1, 24, false, true},
+		{"default", uiCompactWidth, 24, false, false},
+		{"wide", 300, 80, false, false},
+	}
+	views := map[string]func(m *model){
+		"log":     func(m *model) {},
+		"builder": func(m *model) { *m = m.builderGoto(builderStepField); m.builderView = true },
+		"output":  func(m *model) { m.output, m.outputTitle, m.outputView = []string{"a", "b"}, "Output", true },
+		"profiles": func(m *model) {
+			m.profiles, m.profilesCursor, m.profilesView = []string{"a", "b", "c", "d", "e", "f"}, 5, true
+		},
+	}
+	for _, tt := range tests {
+		for name, setup := range views {
+			t.Run(tt.name+"/"+name, func(t *testing.T) {
+				m := testModel(t, 50)
+				m.uiStatusMsg = strings.Repeat("status ", 50)
+				setup(&m)
+				m.uiCursor = 49
+				updated, _ := m.Update(tea.WindowSizeMsg{Width: tt.width, Height: tt.height})
+				m = updated.(model)
+				view := m.View()
+				if got := m.tooSmall(); got != tt.tooSmall {
+					t.Fatalf("expected too small %v, got %v", tt.tooSmall, got)
+				}
+				// the message is cut on tiny terminals
+				if tt.tooSmall && tt.width > 20 && !strings.Contains(view, "terminal too small") {
+					t.Fatalf("expected too small message, got:\n%s", view)
+				}
+				lines := strings.Split(view, "\n")
+				if len(lines) != tt.height {
+					t.Fatalf("expected %d lines, got %d:\n%s", tt.height, len(lines), view)
+				}
+				// the help line is cut by the terminal
+				for i, line := range lines[:len(lines)-1] {
+					if w := lipgloss.Width(line); w > tt.width {
+						t.Errorf("expected line %d of width %d at most, got %d: %q", i, tt.width, w, line)
+					}
+				}
+				if got := m.logColumns().width() == columnsCompact.width(); got != tt.compact {
+					t.Errorf("expected compact columns %v, got %v", tt.compact, got)
+				}
+				if name == "log" && !tt.tooSmall && !strings.Contains(view, time.Date(2025, 10, 10, 0, 0, 49, 0, time.UTC).Format(m.logColumns().timeFormat)) {
+					t.Errorf("expected the selected entry to be visible:\n%s", view)
+				}
+			})
+		}
+	}
+}
+
+func TestWindowSizeScroll(t *testing.T) {
+	m := testModel(t, 50)
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: uiCompactWidth, Height: 24})
+	m = updated.(model)
+	m.uiScrollH = m.logWidth() - m.uiWidth
+	// the compact columns fit the terminal
+	updated, _ = m.Update(tea.WindowSizeMsg{Width: uiCompactWidth - 1, Height: 24})
+	if got := updated.(model).uiScrollH; got != 0 {
+		t.Errorf("expected horizontal scroll 0, got %d", got)
+	}
+	updated, _ = m.Update(tea.WindowSizeMsg{Width: 0, Height: 0})
+	if got := updated.(model).View(); got == "" {
+		t.Error("expected loading view")
+	}
+}
+
+func TestHighlights(t *testing.T) {
+	m := testModel(t, 1)
+	m.uiWidth = 200
+	compiled, err := filter.Compile("src 2001:db8 and not port 80")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.filterCompiled = compiled
+	cols := m.logColumns()
+	ranges := m.cellStyles(&m.entries[0], lipgloss.NewStyle())
+	if len(ranges) != 1 {
+		t.Fatalf("expected only the source cell to be highlighted, got %d ranges", len(ranges))
+	}
+	if r := ranges[0]; r.start != cols.offset(colSource) || r.end != r.start+cols.widths[colSource] || !r.style.GetBold() {
+		t.Errorf("expected bold source cell, got %d-%d", r.start, r.end)
+	}
+	m.filterCompiled = nil
+	if ranges := m.cellStyles(&m.entries[0], lipgloss.NewStyle()); len(ranges) != 0 {
+		t.Errorf("expected no highlights without a filter, got %d ranges", len(ranges))
+	}
+}
+
+func TestLabel(t *testing.T) {
+	m := testModel(t, 1)
+	m.entries[0].Label = "02f4bab031b57d1e30553ce08e0ec131"
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 200, Height: 24})
+	updated, _ = updated.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("L")})
+	m = updated.(model)
+	if view := m.View(); !strings.Contains(view, "Label") || !strings.Contains(view, "02f4bab031b57d1e30553...") {
+		t.Fatalf("expected label column:\n%s", view)
+	}
+	if got := m.logWidth(); got != m.logColumns().width()+m.markerWidth()+colWidthLabel+1 {
+		t.Errorf("expected log width to include the label column, got %d", got)
+	}
+	compiled, err := filter.Compile("label 02f4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.filterCompiled = compiled
+	ranges := m.cellStyles(&m.entries[0], lipgloss.NewStyle())
+	if len(ranges) != 1 || ranges[0].start != 0 || ranges[0].end != colWidthLabel {
+		t.Errorf("expected the label cell to be highlighted, got %v", ranges)
+	}
+}
+
+func TestAccessible(t *testing.T) {
+	m := testModel(t, 3)
+	m.opts.Accessible = true
+	m.uiClasses = true
+	m.entries[1].SrcClass = "bogon"
+	m.uiCursor = 1
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 200, Height: 10})
+	m = updated.(model)
+	lines := strings.Split(m.View(), "\n")
+	if !strings.HasPrefix(lines[0], "  Time") {
+		t.Errorf("expected header indented by the marker, got %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "  ") || !strings.HasPrefix(lines[2], "> ") {
+		t.Errorf("expected the selected line to be marked, got %q and %q", lines[1], lines[2])
+	}
+	if !strings.Contains(lines[2], "2001:db8::1 (bogon)") {
+		t.Errorf("expected the address class to be written out, got %q", lines[2])
+	}
+	if m.withLoadingView(nil) != nil {
+		t.Error("expected no spinner animation")
+	}
+	if got := m.loadingView(); !strings.Contains(got, "loading...") {
+		t.Errorf("expected static loading message, got %q", got)
+	}
+}
+
+func TestFollow(t *testing.T) {
+	data, err := os.ReadFile("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	path := filepath.Join(t.TempDir(), "filter.log")
+	if err := os.WriteFile(path, []byte(lines[0]+lines[1]), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s, err := stream.NewStream(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	var m tea.Model = newModel(s, config.New(), nil, Options{Follow: true})
+	m, _ = m.Update(tea.WindowSizeMsg{Width: 200, Height: 20})
+	m, _ = m.Update(index(s)())
+	if got := m.(model).entriesTotal; got != 2 {
+		t.Fatalf("expected 2 entries, got %d", got)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(lines[2] + lines[3]); err != nil {
+		t.Fatal(err)
+	}
+	m, _ = m.Update(followIndex(s)())
+	got := m.(model)
+	if got.entriesTotal != 4 || len(got.entriesAvailable) != 4 {
+		t.Fatalf("expected 4 entries, got %d (%d available)", got.entriesTotal, len(got.entriesAvailable))
+	}
+	if got.uiCursor != 3 {
+		t.Errorf("expected the newest entry to be selected, got %d", got.uiCursor)
+	}
+
+	// another entry has been selected
+	got.uiCursor = 0
+	if _, err := file.WriteString(lines[4]); err != nil {
+		t.Fatal(err)
+	}
+	m, _ = got.Update(followIndex(s)())
+	if got := m.(model); got.entriesTotal != 5 || got.uiCursor != 0 {
+		t.Errorf("expected 5 entries with the first selected, got %d with %d selected", got.entriesTotal, got.uiCursor)
+	}
+
+	// ticks of a previous generation are dropped
+	if _, cmd := m.Update(followTickMsg{stream: s, gen: m.(model).followGen - 1}); cmd != nil {
+		t.Error("expected stale tick to be dropped")
+	}
+
+	// the index isn't extended while a block is loaded from the stream (it would reset its reader)
+	got = m.(model)
+	got.loading = true
+	m, _ = got.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+	if m.(model).uiLoading {
+		t.Error("expected reload to wait for the block being loaded")
+	}
+}
+
+func TestPrefetch(t *testing.T) {
+	data, err := os.ReadFile("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "filter.log")
+	if err := os.WriteFile(path, []byte(strings.Repeat(string(data), 15)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s, err := stream.NewStream(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	m := newModel(s, config.New(), nil, Options{})
+	m.entriesMax = 100
+	var updated tea.Model = m
+	updated, _ = updated.Update(tea.WindowSizeMsg{Width: 200, Height: 20})
+	for msg := index(s)(); ; msg = index(s)() {
+		updated, _ = updated.Update(msg)
+		if msg.(indexMsg).done {
+			break
+		}
+	}
+	updated, _ = updated.Update(loadEntries(s, 0, m.entriesMax)())
+	m = updated.(model)
+	if m.entriesTotal != 300 || len(m.entries) != 100 {
+		t.Fatalf("expected the first block of 300 entries, got %d of %d", len(m.entries), m.entriesTotal)
+	}
+
+	// scroll past the end of the first block
+	prefetched := false
+	for range 150 {
+		var cmd tea.Cmd
+		updated, cmd = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("j")})
+		m = updated.(model)
+		if m.loading {
+			t.Fatalf("expected no blocking load at scroll position %d", m.uiScrollV)
+		}
+		if m.prefetching {
+			if !prefetched && m.uiScrollV+m.contentHeight() >= m.entriesStart+len(m.entries) {
+				t.Fatalf("expected the next block to be prefetched before the edge, got scroll position %d", m.uiScrollV)
+			}
+			prefetched = true
+			updated, _ = m.Update(cmd())
+			m = updated.(model)
+		}
+		if view := m.View(); strings.Contains(view, "loading...") {
+			t.Fatalf("expected no loading rows at scroll position %d, got:\n%s", m.uiScrollV, view)
+		}
+	}
+	if !prefetched || m.entriesStart == 0 {
+		t.Errorf("expected the prefetched block to be swapped in, got block at %d", m.entriesStart)
+	}
+}
+
+func TestPrint(t *testing.T) {
+	m := testModel(t, 50)
+	m.uiWidth, m.uiHeight = 200, 10
+	m.uiScrollV, m.uiCursor = 20, 22
+	var printed []*stream.LogEntry
+	m.opts.Print = func(entry *stream.LogEntry) error {
+		printed = append(printed, entry)
+		return nil
+	}
+	if err := m.print(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(printed) != m.contentHeight() {
+		t.Fatalf("expected %d visible entries, got %d", m.contentHeight(), len(printed))
+	}
+	if !printed[0].Time.Equal(m.entries[20].Time) {
+		t.Errorf("expected the first visible entry to be printed first, got %v", printed[0].Time)
+	}
+
+	// all entries matching the filter
+	s, err := stream.NewStream("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	var tm tea.Model = newModel(s, config.New(), nil, Options{PrintAll: true})
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 200, Height: 10})
+	tm, _ = tm.Update(index(s)())
+	m = tm.(model)
+	if m.filterCompiled, err = filter.Compile("action block"); err != nil {
+		t.Fatal(err)
+	}
+	printed = nil
+	m.opts.Print = func(entry *stream.LogEntry) error {
+		if entry.Action != stream.ActionBlock {
+			t.Errorf("expected only blocked entries, got %q", entry.Action)
+		}
+		printed = append(printed, entry)
+		return nil
+	}
+	if err := m.print(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(printed) != 1 {
+		t.Errorf("expected 1 matching entry, got %d", len(printed))
+	}
+
+	// the stream can't be read while indexing
+	m.indexing = true
+	if err := m.print(); err == nil {
+		t.Error("expected error while indexing")
+	}
+}
+
+func TestSort(t *testing.T) {
+	s, err := stream.NewStream("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	var tm tea.Model = newModel(s, config.New(), nil, Options{})
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 200, Height: 30})
+	tm, _ = tm.Update(index(s)())
+	// sorting waits for the first block
+	tm, _ = tm.Update(loadEntries(s, 0, tm.(model).entriesMax)())
+	key := func(k string) {
+		t.Helper()
+		tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)})
+	}
+	sorted := func() []uint16 {
+		t.Helper()
+		m := tm.(model)
+		tm, _ = m.Update(m.sortEntries()())
+		m = tm.(model)
+		tm, _ = m.Update(loadEntriesFiltered(s, m.entriesAvailable)())
+		m = tm.(model)
+		ports := make([]uint16, 0, len(m.entriesAvailable))
+		for _, line := range m.entriesAvailable {
+			ports = append(ports, m.getEntryAtLine(line).DstPort)
+		}
+		return ports
+	}
+
+	key("S")
+	key("P")
+	m := tm.(model)
+	if m.sortCol != colDstPort || m.sortDesc || !m.filterApplied {
+		t.Fatalf("expected ascending sort by destination port, got column %d (desc %v)", m.sortCol, m.sortDesc)
+	}
+	ports := sorted()
+	if len(ports) != m.entriesTotal || !slices.IsSorted(ports) {
+		t.Errorf("expected %d entries in ascending order, got %v", m.entriesTotal, ports)
+	}
+	view := tm.(model).View()
+	if !strings.Contains(view, "DstPo ^") || !strings.Contains(view, "sort: dport asc") {
+		t.Errorf("expected sort indicator in header and status bar:\n%s", view)
+	}
+
+	// the same column again reverses the order
+	key("S")
+	key("P")
+	ports = sorted()
+	if !slices.IsSortedFunc(ports, func(a, b uint16) int { return cmp.Compare(b, a) }) {
+		t.Errorf("expected descending order, got %v", ports)
+	}
+
+	// and then restores the chronological order
+	key("S")
+	key("P")
+	if m := tm.(model); m.sortCol != -1 || m.filterApplied || !slices.IsSorted(m.entriesAvailable) {
+		t.Errorf("expected chronological order, got column %d", m.sortCol)
+	}
+
+	// other keys cancel
+	key("S")
+	key("x")
+	if m := tm.(model); m.sortPending || m.sortCol != -1 {
+		t.Errorf("expected sort to be cancelled, got column %d", m.sortCol)
+	}
+}
+
+func TestFrequency(t *testing.T) {
+	s, err := stream.NewStream("../../tests/filter_valid.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	var tm tea.Model = newModel(s, config.New(), nil, Options{})
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 200, Height: 30})
+	tm, _ = tm.Update(index(s)())
+	key := func(k tea.KeyMsg) {
+		t.Helper()
+		tm, _ = tm.Update(k)
+	}
+
+	tm, _ = tm.Update(tm.(model).countFrequency()())
+	m := tm.(model)
+	if !m.frequencyView || m.frequency.Total() != m.entriesTotal {
+		t.Fatalf("expected frequency view counting %d entries, got %d", m.entriesTotal, m.frequency.Total())
+	}
+	if len(m.frequencyValues) == 0 || !strings.Contains(m.View(), "group: src") {
+		t.Fatalf("expected values grouped by source:\n%s", m.View())
+	}
+
+	// tab groups by the next field without counting again
+	key(tea.KeyMsg{Type: tea.KeyTab})
+	m = tm.(model)
+	if frequencyFields[m.frequencyField] != "dst" || !strings.Contains(m.View(), "group: dst") {
+		t.Errorf("expected values grouped by destination, got %q", frequencyFields[m.frequencyField])
+	}
+
+	// enter narrows the filter down to the selected value
+	value := m.frequencyValues[0]
+	key(tea.KeyMsg{Type: tea.KeyEnter})
+	m = tm.(model)
+	if m.frequencyView || !m.filterApplied || m.filterInput.Value() != filter.Term("dst", value.Value, false, filter.Options{}) {
+		t.Fatalf("expected filter by destination %q, got %q", value.Value, m.filterInput.Value())
+	}
+	tm, _ = m.Update(m.scanAndFilter()())
+	if m := tm.(model); len(m.entriesAvailable) != value.Entries {
+		t.Errorf("expected %d matching entries, got %d", value.Entries, len(m.entriesAvailable))
+	}
+}